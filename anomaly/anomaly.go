@@ -0,0 +1,269 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DirectionSpike is an ingest rate well above its baseline
+	DirectionSpike = "spike"
+	// DirectionDrop is an ingest rate well below its baseline
+	DirectionDrop = "drop"
+
+	// DefaultWindow is how much ingest each check counts
+	DefaultWindow = time.Hour
+	// DefaultBaselineDays is how many previous days the baseline is taken from, the same window of each
+	// day so the baseline follows the daily cycle
+	DefaultBaselineDays = 7
+	// cadence is how often the rates are checked
+	cadence = 5 * time.Minute
+)
+
+var (
+	rateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ingest_rows",
+			Help: "Rows inserted into the topic over the latest window",
+		},
+		[]string{"topic"},
+	)
+	baselineGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ingest_baseline_rows",
+			Help: "Median rows inserted into the topic over the same window of the previous days",
+		},
+		[]string{"topic"},
+	)
+	anomalyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ingest_anomaly",
+			Help: "Whether the ingest of the topic deviates from its baseline in the direction, 1, or not, 0",
+		},
+		[]string{"topic", "direction"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(rateGauge, baselineGauge, anomalyGauge)
+}
+
+// Topic is a table whose insert rate is watched
+type Topic struct {
+	Name string
+	// Table the topic's rows are inserted into
+	Table string
+	// TimeColumn is when each row was inserted
+	TimeColumn string
+}
+
+// Topics are the ingest rates watched by default, votes and the suggestions and payout drains
+// queued by clients
+var Topics = []Topic{
+	{Name: "votes", Table: "vote_drain", TimeColumn: "created_at"},
+	{Name: "suggestions", Table: "suggestion_drain", TimeColumn: "created_at"},
+	{Name: "drains", Table: "claim_drain", TimeColumn: "created_at"},
+}
+
+// Thresholds are the deviations from the baseline which are anomalies
+type Thresholds struct {
+	// Spike is the ratio of the rate to its baseline at or above which the rate is a spike
+	Spike float64 `json:"spike"`
+	// Drop is the ratio at or below which the rate is a drop
+	Drop float64 `json:"drop"`
+	// MinBaseline is the baseline below which rates are too low to be compared
+	MinBaseline float64 `json:"minBaseline"`
+}
+
+// DefaultThresholds flag rates three times their baseline, or a fifth of it
+var DefaultThresholds = Thresholds{Spike: 3, Drop: 0.2, MinBaseline: 100}
+
+// Store counts the rows inserted into a topic
+type Store interface {
+	// Count returns the rows inserted into the topic within the window ending at the time, and within the
+	// same window of each of the previous days
+	Count(ctx context.Context, topic Topic, end time.Time, window time.Duration, days int) (int64, []int64, error)
+}
+
+// Pauser pauses the jobs paying out to users, such as the payout drains
+type Pauser interface {
+	PausePayouts(until time.Time)
+}
+
+// Rate is the latest check of a topic
+type Rate struct {
+	Topic     string    `json:"topic"`
+	Rows      int64     `json:"rows"`
+	Baseline  float64   `json:"baseline"`
+	Ratio     float64   `json:"ratio"`
+	Direction string    `json:"direction,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Detector compares the ingest rate of each topic with its rolling baseline
+type Detector struct {
+	store      Store
+	topics     []Topic
+	thresholds Thresholds
+	window     time.Duration
+	days       int
+	pauser     Pauser
+	mu         sync.Mutex
+	rates      map[string]Rate
+	now        func() time.Time
+}
+
+// NewDetector creates a detector of the topics' anomalies
+func NewDetector(store Store, thresholds Thresholds, topics ...Topic) *Detector {
+	return &Detector{
+		store:      store,
+		topics:     topics,
+		thresholds: thresholds,
+		window:     DefaultWindow,
+		days:       DefaultBaselineDays,
+		rates:      map[string]Rate{},
+		now:        time.Now,
+	}
+}
+
+// PausePayouts pauses payouts while the ingest of any topic spikes, until a check finds it back to normal
+func (d *Detector) PausePayouts(pauser Pauser) {
+	d.pauser = pauser
+}
+
+// median of the counts, which a single unusual day does not skew
+func median(counts []int64) float64 {
+	if len(counts) == 0 {
+		return 0
+	}
+	sorted := append([]int64{}, counts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return float64(sorted[mid])
+}
+
+// direction of the rate's deviation from its baseline, empty when it is not an anomaly
+func (t Thresholds) direction(rows int64, baseline float64) (float64, string) {
+	if baseline <= 0 || baseline < t.MinBaseline {
+		return 0, ""
+	}
+	ratio := float64(rows) / baseline
+	switch {
+	case t.Spike > 0 && ratio >= t.Spike:
+		return ratio, DirectionSpike
+	case t.Drop > 0 && ratio <= t.Drop:
+		return ratio, DirectionDrop
+	}
+	return ratio, ""
+}
+
+// Check counts the ingest of every topic, flagging those deviating from their baseline. Payouts are
+// paused until the next check while any topic spikes
+func (d *Detector) Check(ctx context.Context) error {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	now := d.now()
+	spiking := false
+	for _, topic := range d.topics {
+		rows, previous, err := d.store.Count(ctx, topic, now, d.window, d.days)
+		if err != nil {
+			return fmt.Errorf("failed to count ingest of %s: %w", topic.Name, err)
+		}
+		baseline := median(previous)
+		ratio, direction := d.thresholds.direction(rows, baseline)
+		rate := Rate{
+			Topic:     topic.Name,
+			Rows:      rows,
+			Baseline:  baseline,
+			Ratio:     ratio,
+			Direction: direction,
+			CheckedAt: now,
+		}
+
+		rateGauge.WithLabelValues(topic.Name).Set(float64(rows))
+		baselineGauge.WithLabelValues(topic.Name).Set(baseline)
+		for _, dir := range []string{DirectionSpike, DirectionDrop} {
+			value := 0.0
+			if dir == direction {
+				value = 1
+			}
+			anomalyGauge.WithLabelValues(topic.Name, dir).Set(value)
+		}
+
+		d.mu.Lock()
+		last := d.rates[topic.Name]
+		d.rates[topic.Name] = rate
+		d.mu.Unlock()
+
+		if direction == DirectionSpike {
+			spiking = true
+		}
+		if direction == last.Direction {
+			continue
+		}
+		if direction == "" {
+			logger.Info().Str("topic", topic.Name).Int64("rows", rows).Float64("baseline", baseline).
+				Msg("ingest rate is back within its baseline")
+			continue
+		}
+		logger.Warn().
+			Str("topic", topic.Name).
+			Str("direction", direction).
+			Int64("rows", rows).
+			Float64("baseline", baseline).
+			Float64("ratio", ratio).
+			Msg("ingest rate deviates from its baseline")
+		sentry.CaptureMessage(fmt.Sprintf("%s ingest %s: %d rows against a baseline of %.0f", topic.Name, direction, rows, baseline))
+	}
+
+	if spiking && d.pauser != nil {
+		// renewed by every check while the spike lasts, so payouts resume shortly after it ends
+		d.pauser.PausePayouts(now.Add(2 * cadence))
+		logger.Warn().Msg("payouts are paused while ingest spikes")
+	}
+	return nil
+}
+
+// Rates returns the latest check of each topic, in topic order
+func (d *Detector) Rates() []Rate {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	rates := []Rate{}
+	for _, topic := range d.topics {
+		if rate, ok := d.rates[topic.Name]; ok {
+			rates = append(rates, rate)
+		}
+	}
+	return rates
+}
+
+// Jobs checks the ingest rates every five minutes. Each instance checks, as payouts are paused by
+// each instance
+func (d *Detector) Jobs() []srv.Job {
+	return []srv.Job{
+		{
+			Name:    "ingest_anomaly",
+			Service: "grant",
+			Func: func(ctx context.Context) (bool, error) {
+				return true, d.Check(ctx)
+			},
+			Cadence: cadence,
+			Workers: 1,
+		},
+	}
+}
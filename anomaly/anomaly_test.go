@@ -0,0 +1,62 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counts map[string][]int64
+
+func (c counts) Count(ctx context.Context, topic Topic, end time.Time, window time.Duration, days int) (int64, []int64, error) {
+	rows := c[topic.Name]
+	return rows[0], rows[1:], nil
+}
+
+type pauser struct {
+	until time.Time
+}
+
+func (p *pauser) PausePayouts(until time.Time) {
+	p.until = until
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 0.0, median(nil))
+	assert.Equal(t, 3.0, median([]int64{5, 1, 3}))
+	assert.Equal(t, 2.5, median([]int64{4, 1, 3, 2}))
+	assert.Equal(t, 100.0, median([]int64{100, 100, 100, 100, 100, 100, 5000}), "a single unusual day does not skew the baseline")
+}
+
+func TestDetectorCheck(t *testing.T) {
+	now := time.Date(2021, time.April, 1, 12, 0, 0, 0, time.UTC)
+	store := counts{
+		"votes":       {3500, 1000, 1100, 900, 1000, 1050, 950, 1000},
+		"suggestions": {100, 1000, 1100, 900, 1000, 1050, 950, 1000},
+		"drains":      {40, 10, 12, 8, 10, 11, 9, 10},
+	}
+	p := &pauser{}
+	d := NewDetector(store, DefaultThresholds, Topics...)
+	d.now = func() time.Time { return now }
+	d.PausePayouts(p)
+
+	require.NoError(t, d.Check(context.Background()))
+	rates := d.Rates()
+	require.Len(t, rates, 3)
+	assert.Equal(t, "votes", rates[0].Topic)
+	assert.Equal(t, DirectionSpike, rates[0].Direction)
+	assert.Equal(t, 3.5, rates[0].Ratio)
+	assert.Equal(t, DirectionDrop, rates[1].Direction)
+	assert.Empty(t, rates[2].Direction, "rates below the minimum baseline are not compared")
+	assert.Equal(t, now.Add(2*cadence), p.until, "spikes pause payouts until the next check")
+
+	// payouts are left to resume once the spike ends
+	store["votes"][0] = 1200
+	now = now.Add(cadence)
+	require.NoError(t, d.Check(context.Background()))
+	assert.Empty(t, d.Rates()[0].Direction)
+	assert.Equal(t, now.Add(cadence), p.until)
+}
@@ -0,0 +1,22 @@
+package anomaly
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// Router exposes the latest check of each topic's ingest rate
+func Router(d *Detector) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/", GetRates(d))
+	return r
+}
+
+// GetRates is the handler for listing the ingest rates with their baselines and anomalies
+func GetRates(d *Detector) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return handlers.RenderContent(r.Context(), d.Rates(), w, http.StatusOK)
+	})
+}
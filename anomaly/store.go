@@ -0,0 +1,51 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore counts the rows of the topics' tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Count returns the rows inserted within the window ending at the time, and within the same window of
+// each of the previous days, using the index on the topic's time column
+func (s *PostgresStore) Count(ctx context.Context, topic Topic, end time.Time, window time.Duration, days int) (int64, []int64, error) {
+	type count struct {
+		Day  int   `db:"day"`
+		Rows int64 `db:"rows"`
+	}
+	counts := []count{}
+	// topics are fixed at startup, their table and column are never taken from input
+	err := s.db.SelectContext(ctx, &counts, `
+			SELECT day, (
+				SELECT count(*) FROM `+topic.Table+`
+				WHERE `+topic.TimeColumn+` >= $1 - day * interval '1 day'
+					AND `+topic.TimeColumn+` < $2 - day * interval '1 day'
+			) AS rows
+			FROM generate_series(0, $3::int) AS day
+			ORDER BY day
+		`, end.Add(-window), end, days)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count %s rows: %w", topic.Table, err)
+	}
+	if len(counts) == 0 {
+		return 0, nil, nil
+	}
+
+	previous := make([]int64, 0, len(counts)-1)
+	for _, c := range counts[1:] {
+		previous = append(previous, c.Rows)
+	}
+	return counts[0].Rows, previous, nil
+}
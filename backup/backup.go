@@ -0,0 +1,241 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/scheduler"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrNoBackup is returned when there is no backup to verify
+var ErrNoBackup = errors.New("no backup found")
+
+var (
+	verificationGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "backup_verification_success",
+			Help: "Whether the latest backup restore verification passed, 1, or failed, 0",
+		})
+	verificationTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "backup_verification_timestamp_seconds",
+			Help: "When the latest backup restore verification finished",
+		})
+	backupAgeGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "backup_age_seconds",
+			Help: "How old the latest backup was when it was verified",
+		})
+	restoreDurationGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "backup_restore_duration_seconds",
+			Help: "How long restoring the latest backup took",
+		})
+	checkGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "backup_check_success",
+			Help: "Whether each check of the latest restored backup passed, 1, or failed, 0",
+		},
+		[]string{"check"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(verificationGauge, verificationTimestampGauge, backupAgeGauge, restoreDurationGauge, checkGauge)
+}
+
+// Backup is a database dump
+type Backup struct {
+	Key     string    `json:"key"`
+	TakenAt time.Time `json:"takenAt"`
+	Size    int64     `json:"size"`
+}
+
+// Source is where backups are kept
+type Source interface {
+	// Latest returns the most recent backup, or ErrNoBackup if there is none
+	Latest(ctx context.Context) (*Backup, error)
+	// Download writes the backup to the file at the path
+	Download(ctx context.Context, backup *Backup, path string) error
+}
+
+// Restorer restores a downloaded backup into a database
+type Restorer interface {
+	Restore(ctx context.Context, path string, databaseURL string) error
+}
+
+// CheckResult is the outcome of a check of the restored backup
+type CheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of verifying a backup
+type Report struct {
+	Backup          Backup        `json:"backup"`
+	RestoreDuration time.Duration `json:"restoreDuration"`
+	Checks          []CheckResult `json:"checks"`
+	Passed          bool          `json:"passed"`
+}
+
+// Verifier restores the latest backup into a scratch database and checks it is complete and consistent
+type Verifier struct {
+	source     Source
+	restorer   Restorer
+	scratchURL string
+	live       *sqlx.DB
+	checks     []Check
+	now        func() time.Time
+}
+
+// NewVerifier creates a verifier restoring into the scratch database, live is the database backed up,
+// which restored row counts are compared against, and may be nil
+func NewVerifier(source Source, restorer Restorer, scratchURL string, live *sqlx.DB) *Verifier {
+	return &Verifier{
+		source:     source,
+		restorer:   restorer,
+		scratchURL: scratchURL,
+		live:       live,
+		checks:     DefaultChecks,
+		now:        time.Now,
+	}
+}
+
+// Verify restores the latest backup and runs the checks against it. A report is returned whenever the
+// backup was restored, with an error if any check failed
+func (v *Verifier) Verify(ctx context.Context) (*Report, error) {
+	report, err := v.verify(ctx)
+	verificationTimestampGauge.Set(float64(v.now().Unix()))
+	if err != nil {
+		verificationGauge.Set(0)
+	} else {
+		verificationGauge.Set(1)
+	}
+	return report, err
+}
+
+func (v *Verifier) verify(ctx context.Context) (*Report, error) {
+	backup, err := v.source.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backupAgeGauge.Set(v.now().Sub(backup.TakenAt).Seconds())
+
+	dir, err := ioutil.TempDir("", "backup-verify")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := dir + "/backup.dump"
+	if err := v.source.Download(ctx, backup, path); err != nil {
+		return nil, fmt.Errorf("failed to download backup %s: %w", backup.Key, err)
+	}
+
+	start := v.now()
+	if err := v.restorer.Restore(ctx, path, v.scratchURL); err != nil {
+		return nil, fmt.Errorf("failed to restore backup %s: %w", backup.Key, err)
+	}
+	report := &Report{Backup: *backup, RestoreDuration: v.now().Sub(start), Checks: []CheckResult{}, Passed: true}
+	restoreDurationGauge.Set(report.RestoreDuration.Seconds())
+
+	scratch, err := sqlx.Open("postgres", v.scratchURL)
+	if err != nil {
+		return report, err
+	}
+	defer func() { _ = scratch.Close() }()
+
+	env := CheckEnv{Scratch: scratch, Live: v.live, Backup: *backup}
+	for _, check := range v.checks {
+		result := CheckResult{Name: check.Name, Passed: true}
+		if err := check.Run(ctx, env); err != nil {
+			result.Passed = false
+			result.Error = err.Error()
+			report.Passed = false
+			checkGauge.With(prometheus.Labels{"check": check.Name}).Set(0)
+		} else {
+			checkGauge.With(prometheus.Labels{"check": check.Name}).Set(1)
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		logger.Info().
+			Str("backup", backup.Key).
+			Dur("restoreDuration", report.RestoreDuration).
+			Bool("passed", report.Passed).
+			Msg("backup restore verified")
+	}
+	if !report.Passed {
+		return report, fmt.Errorf("backup %s failed verification", backup.Key)
+	}
+	return report, nil
+}
+
+// Config is where backups are and where they are restored to
+type Config struct {
+	// Bucket and Prefix are where backups are kept in s3, the latest under the prefix is verified
+	Bucket string
+	Prefix string
+	// ScratchDatabaseURL is the database backups are restored into, it is overwritten by each restore
+	ScratchDatabaseURL string
+	// RestoreCommand is the pg_restore binary
+	RestoreCommand string
+}
+
+// ConfigFromEnv returns the configuration of BACKUP_BUCKET, BACKUP_PREFIX, BACKUP_SCRATCH_DATABASE_URL and
+// BACKUP_RESTORE_COMMAND
+func ConfigFromEnv() Config {
+	return Config{
+		Bucket:             os.Getenv("BACKUP_BUCKET"),
+		Prefix:             os.Getenv("BACKUP_PREFIX"),
+		ScratchDatabaseURL: os.Getenv("BACKUP_SCRATCH_DATABASE_URL"),
+		RestoreCommand:     os.Getenv("BACKUP_RESTORE_COMMAND"),
+	}
+}
+
+// NewVerifierFromConfig creates a verifier of the s3 backups of the config
+func NewVerifierFromConfig(config Config, live *sqlx.DB) (*Verifier, error) {
+	if config.Bucket == "" || config.ScratchDatabaseURL == "" {
+		return nil, errors.New("backup bucket and scratch database url must be set")
+	}
+	source, err := NewS3Source(config.Bucket, config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewVerifier(source, &PGRestore{Command: config.RestoreCommand}, config.ScratchDatabaseURL, live), nil
+}
+
+// ScheduledJobs returns the backup verification job, none unless BACKUP_SCRATCH_DATABASE_URL is set. It runs
+// weekly unless BACKUP_VERIFY_SCHEDULE is set
+func ScheduledJobs(live *sqlx.DB) ([]scheduler.Job, error) {
+	config := ConfigFromEnv()
+	if config.ScratchDatabaseURL == "" {
+		return nil, nil
+	}
+	verifier, err := NewVerifierFromConfig(config, live)
+	if err != nil {
+		return nil, err
+	}
+	schedule := os.Getenv("BACKUP_VERIFY_SCHEDULE")
+	if schedule == "" {
+		schedule = "0 6 * * 0"
+	}
+	return []scheduler.Job{
+		{
+			Name:     "backup-verify",
+			Schedule: schedule,
+			Func: func(ctx context.Context) error {
+				_, err := verifier.Verify(ctx)
+				return err
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySource struct {
+	backup *Backup
+}
+
+func (s *memorySource) Latest(ctx context.Context) (*Backup, error) {
+	if s.backup == nil {
+		return nil, ErrNoBackup
+	}
+	return s.backup, nil
+}
+
+func (s *memorySource) Download(ctx context.Context, backup *Backup, path string) error {
+	return ioutil.WriteFile(path, []byte("dump"), 0600)
+}
+
+type recordingRestorer struct {
+	dump string
+	err  error
+}
+
+func (r *recordingRestorer) Restore(ctx context.Context, path string, databaseURL string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r.dump = string(data)
+	return r.err
+}
+
+func TestVerify(t *testing.T) {
+	source := &memorySource{backup: &Backup{Key: "grant/1.dump", TakenAt: time.Now().Add(-time.Hour)}}
+	restorer := &recordingRestorer{}
+	verifier := NewVerifier(source, restorer, "postgres://localhost/scratch?sslmode=disable", nil)
+	verifier.checks = []Check{
+		{Name: "passes", Run: func(ctx context.Context, env CheckEnv) error { return nil }},
+		{Name: "fails", Run: func(ctx context.Context, env CheckEnv) error { return errors.New("1 broken order") }},
+	}
+
+	report, err := verifier.Verify(context.Background())
+	assert.Error(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, "dump", restorer.dump)
+	assert.False(t, report.Passed)
+	assert.Equal(t, []CheckResult{
+		{Name: "passes", Passed: true},
+		{Name: "fails", Passed: false, Error: "1 broken order"},
+	}, report.Checks)
+
+	verifier.checks = verifier.checks[:1]
+	report, err = verifier.Verify(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Passed)
+
+	restorer.err = errors.New("pg_restore failed")
+	_, err = verifier.Verify(context.Background())
+	assert.Error(t, err)
+
+	source.backup = nil
+	_, err = verifier.Verify(context.Background())
+	assert.True(t, errors.Is(err, ErrNoBackup))
+}
+
+func TestS3SourceLatest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<ListBucketResult><IsTruncated>false</IsTruncated>` +
+			`<Contents><Key>grant/</Key><LastModified>2021-06-03T00:00:00.000Z</LastModified><Size>0</Size></Contents>` +
+			`<Contents><Key>grant/2.dump</Key><LastModified>2021-06-02T00:00:00.000Z</LastModified><Size>20</Size></Contents>` +
+			`<Contents><Key>grant/1.dump</Key><LastModified>2021-06-01T00:00:00.000Z</LastModified><Size>10</Size></Contents>` +
+			`</ListBucketResult>`))
+	}))
+	defer ts.Close()
+
+	env := map[string]string{
+		"AWS_REGION":            "us-west-2",
+		"AWS_ACCESS_KEY_ID":     "access",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_S3_ENDPOINT":       ts.URL,
+	}
+	for k, v := range env {
+		require.NoError(t, os.Setenv(k, v))
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+
+	source, err := NewS3Source("backups", "grant/")
+	require.NoError(t, err)
+	latest, err := source.Latest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "grant/2.dump", latest.Key)
+	assert.Equal(t, int64(20), latest.Size)
+}
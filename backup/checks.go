@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/jmoiron/sqlx"
+)
+
+// countSlack allows for rows written while the backup was being taken, which it may or may not include
+const countSlack = time.Hour
+
+// CheckEnv is what checks are run against
+type CheckEnv struct {
+	// Scratch is the database the backup was restored into
+	Scratch *sqlx.DB
+	// Live is the database that was backed up, nil when it is not available
+	Live   *sqlx.DB
+	Backup Backup
+}
+
+// Check verifies something about a restored backup
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, env CheckEnv) error
+}
+
+// DefaultChecks are run against every restored backup
+var DefaultChecks = []Check{
+	{Name: "schema", Run: checkSchema},
+	{Name: "row_counts", Run: checkRowCounts},
+	{Name: "order_totals", Run: invariant(`
+		SELECT count(*) FROM orders o
+		JOIN (SELECT order_id, sum(subtotal) AS total FROM order_items GROUP BY order_id) i ON i.order_id = o.id
+		WHERE o.total_price <> i.total
+	`, "orders whose total differs from their items")},
+	{Name: "order_creds", Run: invariant(`
+		SELECT count(*) FROM order_creds c
+		JOIN order_items i ON i.id = c.item_id
+		WHERE i.order_id <> c.order_id
+	`, "order credentials of items of another order")},
+	{Name: "dual_write_transactions", Run: invariant(`
+		SELECT count(*) FROM transactions_v2 v
+		LEFT JOIN transactions t ON t.id = v.id
+		WHERE t.id IS NULL
+	`, "transactions_v2 rows missing from transactions")},
+}
+
+// countedTables are compared between the restored and live databases
+var countedTables = []string{"orders", "order_items", "transactions", "wallets", "claims"}
+
+type migrationVersion struct {
+	Version uint `db:"version"`
+	Dirty   bool `db:"dirty"`
+}
+
+func getMigrationVersion(ctx context.Context, db *sqlx.DB) (*migrationVersion, error) {
+	var version migrationVersion
+	if err := db.GetContext(ctx, &version, `SELECT version, dirty FROM schema_migrations LIMIT 1`); err != nil {
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return &version, nil
+}
+
+// checkSchema checks the backup was taken of a cleanly migrated database at the live database's version,
+// or at the version this build migrates to when the live database is not available
+func checkSchema(ctx context.Context, env CheckEnv) error {
+	restored, err := getMigrationVersion(ctx, env.Scratch)
+	if err != nil {
+		return err
+	}
+	if restored.Dirty {
+		return fmt.Errorf("restored migration %d is dirty", restored.Version)
+	}
+	expected := grantserver.CurrentMigrationVersion
+	if env.Live != nil {
+		live, err := getMigrationVersion(ctx, env.Live)
+		if err != nil {
+			return err
+		}
+		expected = live.Version
+	}
+	if restored.Version != expected {
+		return fmt.Errorf("restored migration version %d, expected %d", restored.Version, expected)
+	}
+	return nil
+}
+
+// checkRowCounts checks the backup holds every row the live database had when it was taken. Rows may be
+// written after, so only those created well before the backup are counted
+func checkRowCounts(ctx context.Context, env CheckEnv) error {
+	before := env.Backup.TakenAt.Add(-countSlack)
+	missing := []string{}
+	for _, table := range countedTables {
+		statement := fmt.Sprintf(`SELECT count(*) FROM %s WHERE created_at < $1`, table)
+		var restored int64
+		if err := env.Scratch.GetContext(ctx, &restored, statement, before); err != nil {
+			return fmt.Errorf("failed to count restored %s: %w", table, err)
+		}
+		if env.Live == nil {
+			if restored == 0 {
+				missing = append(missing, table+" is empty")
+			}
+			continue
+		}
+		var live int64
+		if err := env.Live.GetContext(ctx, &live, statement, before); err != nil {
+			return fmt.Errorf("failed to count live %s: %w", table, err)
+		}
+		if restored < live {
+			missing = append(missing, fmt.Sprintf("%s has %d rows, %d live", table, restored, live))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("restored rows are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// invariant checks the query, which counts the rows breaking an invariant, counts none
+func invariant(query string, description string) func(context.Context, CheckEnv) error {
+	return func(ctx context.Context, env CheckEnv) error {
+		var n int64
+		if err := env.Scratch.GetContext(ctx, &n, query); err != nil {
+			return fmt.Errorf("failed to count %s: %w", description, err)
+		}
+		if n > 0 {
+			return fmt.Errorf("%d %s", n, description)
+		}
+		return nil
+	}
+}
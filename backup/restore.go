@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/brave-intl/bat-go/utils/s3"
+)
+
+// S3Source finds backups under a prefix of an s3 bucket, the most recently modified is the latest
+type S3Source struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Source creates a source of the backups under the prefix of the bucket, configured from the
+// standard AWS_* environment variables
+func NewS3Source(bucket, prefix string) (*S3Source, error) {
+	client, err := s3.New()
+	if err != nil {
+		return nil, err
+	}
+	return &S3Source{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// Latest returns the most recently modified backup, or ErrNoBackup if there is none
+func (s *S3Source) Latest(ctx context.Context) (*Backup, error) {
+	objects, err := s.client.ListObjects(ctx, s.bucket, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Backup
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		if latest == nil || object.LastModified.After(latest.TakenAt) {
+			latest = &Backup{Key: object.Key, TakenAt: object.LastModified, Size: object.Size}
+		}
+	}
+	if latest == nil {
+		return nil, ErrNoBackup
+	}
+	return latest, nil
+}
+
+// Download writes the backup to the file at the path
+func (s *S3Source) Download(ctx context.Context, backup *Backup, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := s.client.DownloadObject(ctx, s.bucket, backup.Key, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// PGRestore restores pg_dump custom format backups with pg_restore, replacing what the database held
+type PGRestore struct {
+	// Command is the pg_restore binary, found on the path when empty
+	Command string
+}
+
+// Restore the backup at the path into the database
+func (r *PGRestore) Restore(ctx context.Context, path string, databaseURL string) error {
+	command := r.Command
+	if command == "" {
+		command = "pg_restore"
+	}
+	cmd := exec.CommandContext(ctx, command,
+		"--clean", "--if-exists", "--no-owner", "--no-privileges", "--exit-on-error",
+		"--dbname", databaseURL, path)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
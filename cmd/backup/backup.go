@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/backup"
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// BackupCmd is a subcommand for database backups
+	BackupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "provides database backup utilities",
+	}
+	// VerifyBackupCmd restores and checks the latest backup
+	VerifyBackupCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "restores the latest backup into a scratch database and checks it, printing the report",
+		Run:   cmd.Perform("backup verify", RunVerifyBackup),
+	}
+)
+
+func init() {
+	BackupCmd.AddCommand(VerifyBackupCmd)
+	cmd.RootCmd.AddCommand(BackupCmd)
+
+	verifyBuilder := cmd.NewFlagBuilder(VerifyBackupCmd)
+
+	verifyBuilder.Flag().String("bucket", "",
+		"the s3 bucket backups are kept in").
+		Bind("bucket").
+		Env("BACKUP_BUCKET")
+
+	verifyBuilder.Flag().String("prefix", "",
+		"the prefix of the backups in the bucket, the most recent is verified").
+		Bind("prefix").
+		Env("BACKUP_PREFIX")
+
+	verifyBuilder.Flag().String("scratch-database-url", "",
+		"the database the backup is restored into, everything in it is overwritten").
+		Bind("scratch-database-url").
+		Env("BACKUP_SCRATCH_DATABASE_URL")
+
+	verifyBuilder.Flag().String("database-url", "",
+		"the database that was backed up, restored row counts are compared against it when set")
+
+	verifyBuilder.Flag().String("restore-command", "",
+		"the pg_restore binary, found on the path when unset").
+		Bind("restore-command").
+		Env("BACKUP_RESTORE_COMMAND")
+}
+
+// RunVerifyBackup restores and checks the latest backup, failing unless every check passes
+func RunVerifyBackup(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+
+	config := backup.Config{
+		Bucket:             viper.GetString("bucket"),
+		Prefix:             viper.GetString("prefix"),
+		ScratchDatabaseURL: viper.GetString("scratch-database-url"),
+		RestoreCommand:     viper.GetString("restore-command"),
+	}
+	databaseURL, err := command.Flags().GetString("database-url")
+	if err != nil {
+		return err
+	}
+
+	var live *sqlx.DB
+	if databaseURL != "" {
+		if live, err = sqlx.Open("postgres", databaseURL); err != nil {
+			return fmt.Errorf("unable to connect to the backed up database: %w", err)
+		}
+		defer func() { _ = live.Close() }()
+	}
+
+	verifier, err := backup.NewVerifierFromConfig(config, live)
+	if err != nil {
+		return err
+	}
+	report, verifyErr := verifier.Verify(ctx)
+	if report != nil {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(out))
+	}
+	return verifyErr
+}
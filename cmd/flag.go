@@ -85,6 +85,14 @@ func (fb *FlagBuilder) Int(key string, defaultValue int, description string) *Fl
 		})
 }
 
+// Int64 attaches an int64 flag to the command
+func (fb *FlagBuilder) Int64(key string, defaultValue int64, description string) *FlagBuilder {
+	return fb.SetKey(key).
+		loopCommands(func(command *cobra.Command) {
+			command.Flags().Int64(key, defaultValue, description)
+		})
+}
+
 // Float64 attaches a float64 type flag to the command
 func (fb *FlagBuilder) Float64(key string, defaultValue float64, description string) *FlagBuilder {
 	return fb.SetKey(key).
@@ -0,0 +1,308 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brave-intl/bat-go/cmd"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/logging"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	// register the schemas of the topics produced by payment and promotion
+	_ "github.com/brave-intl/bat-go/payment"
+	_ "github.com/brave-intl/bat-go/promotion"
+)
+
+var (
+	// DLQCmd groups the dead letter queue commands
+	DLQCmd = &cobra.Command{
+		Use:   "dlq",
+		Short: "inspects and replays messages quarantined to dead letter topics",
+	}
+
+	// ListDLQCmd lists the dead letters of a topic
+	ListDLQCmd = &cobra.Command{
+		Use:   "list",
+		Short: "lists the dead letters of a topic",
+		Run:   cmd.Perform("list dead letters", RunListDLQ),
+	}
+
+	// ShowDLQCmd shows a dead letter decoded with its topic's schema
+	ShowDLQCmd = &cobra.Command{
+		Use:   "show",
+		Short: "shows a dead letter decoded with its topic's schema",
+		Run:   cmd.Perform("show dead letter", RunShowDLQ),
+	}
+
+	// ReplayDLQCmd republishes dead letters to their original topic
+	ReplayDLQCmd = &cobra.Command{
+		Use:   "replay",
+		Short: "republishes dead letters to their original topic to be handled again",
+		Run:   cmd.Perform("replay dead letters", RunReplayDLQ),
+	}
+)
+
+func init() {
+	DLQCmd.AddCommand(ListDLQCmd, ShowDLQCmd, ReplayDLQCmd)
+	KafkaCmd.AddCommand(DLQCmd)
+
+	listBuilder := cmd.NewFlagBuilder(ListDLQCmd)
+	showBuilder := cmd.NewFlagBuilder(ShowDLQCmd)
+	replayBuilder := cmd.NewFlagBuilder(ReplayDLQCmd)
+	allBuilder := listBuilder.Concat(showBuilder, replayBuilder)
+
+	allBuilder.Flag().String("kafka-brokers", "",
+		"the comma delimited list of kafka brokers").
+		Bind("kafka-brokers").
+		Env("KAFKA_BROKERS")
+
+	allBuilder.Flag().String("topic", "",
+		"the topic whose dead letters to read, without the "+kafkautils.DeadLetterSuffix+" suffix").
+		Require()
+
+	listBuilder.Flag().Int("partition", -1,
+		"the dead letter partition to read, all partitions are read when -1")
+
+	showBuilder.Concat(replayBuilder).Flag().Int("partition", 0,
+		"the dead letter partition to read")
+
+	listBuilder.Flag().Int64("offset", -1,
+		"the offset to start listing at, the first retained dead letter when -1")
+
+	listBuilder.Flag().Int("limit", 100,
+		"the maximum number of dead letters to list per partition")
+
+	showBuilder.Flag().Int64("offset", 0,
+		"the offset of the dead letter to show").
+		Require()
+
+	replayBuilder.Flag().StringSlice("offsets", []string{},
+		"the offsets of the dead letters to replay").
+		Require()
+
+	replayBuilder.Flag().Bool("dry-run", false,
+		"print the messages which would be replayed without publishing them")
+}
+
+// dlqSource is the kafka connection dead letters are read with
+type dlqSource struct {
+	dialer *kafka.Dialer
+	broker string
+	topic  string
+}
+
+func newDLQSource(command *cobra.Command) (*dlqSource, error) {
+	topic, err := command.Flags().GetString("topic")
+	if err != nil {
+		return nil, err
+	}
+	brokers := viper.GetString("kafka-brokers")
+	if brokers == "" {
+		return nil, errors.New("kafka brokers must be set")
+	}
+	dialer, _, err := kafkautils.TLSDialer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka dialer: %w", err)
+	}
+	return &dlqSource{
+		dialer: dialer,
+		broker: strings.Split(brokers, ",")[0],
+		topic:  topic,
+	}, nil
+}
+
+// read reads the dead letter at the partition and offset
+func (s *dlqSource) read(ctx context.Context, partition int, offset int64) (*kafkautils.DeadLetter, error) {
+	letters, err := kafkautils.ReadDeadLetters(ctx, s.dialer, s.broker, s.topic, partition, offset, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(letters) == 0 || letters[0].Offset != offset {
+		return nil, fmt.Errorf("no dead letter at %d/%d", partition, offset)
+	}
+	return &letters[0], nil
+}
+
+// RunListDLQ lists dead letters, one json object per line
+func RunListDLQ(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+	source, err := newDLQSource(command)
+	if err != nil {
+		return err
+	}
+	partition, err := command.Flags().GetInt("partition")
+	if err != nil {
+		return err
+	}
+	offset, err := command.Flags().GetInt64("offset")
+	if err != nil {
+		return err
+	}
+	limit, err := command.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+
+	partitions := []int{partition}
+	if partition < 0 {
+		if partitions, err = kafkautils.DeadLetterPartitions(ctx, source.dialer, source.broker, source.topic); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, p := range partitions {
+		letters, err := kafkautils.ReadDeadLetters(ctx, source.dialer, source.broker, source.topic, p, offset, limit)
+		if err != nil {
+			return err
+		}
+		for _, letter := range letters {
+			if err := encoder.Encode(letter); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunShowDLQ prints a dead letter with its headers and decoded message
+func RunShowDLQ(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+	source, err := newDLQSource(command)
+	if err != nil {
+		return err
+	}
+	partition, err := command.Flags().GetInt("partition")
+	if err != nil {
+		return err
+	}
+	offset, err := command.Flags().GetInt64("offset")
+	if err != nil {
+		return err
+	}
+
+	letter, err := source.read(ctx, partition, offset)
+	if err != nil {
+		return err
+	}
+	return printDeadLetter(letter)
+}
+
+// RunReplayDLQ republishes the selected dead letters to their original topic
+func RunReplayDLQ(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		ctx, logger = logging.SetupLogger(ctx)
+	}
+
+	source, err := newDLQSource(command)
+	if err != nil {
+		return err
+	}
+	partition, err := command.Flags().GetInt("partition")
+	if err != nil {
+		return err
+	}
+	offsets, err := command.Flags().GetStringSlice("offsets")
+	if err != nil {
+		return err
+	}
+	dryRun, err := command.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	// read every selected dead letter before replaying any of them
+	var letters []*kafkautils.DeadLetter
+	for _, o := range offsets {
+		var offset int64
+		if _, err := fmt.Sscan(o, &offset); err != nil {
+			return fmt.Errorf("invalid offset %q: %w", o, err)
+		}
+		letter, err := source.read(ctx, partition, offset)
+		if err != nil {
+			return err
+		}
+		letters = append(letters, letter)
+	}
+
+	if dryRun {
+		for _, letter := range letters {
+			logger.Info().
+				Int("partition", letter.Partition).
+				Int64("offset", letter.Offset).
+				Str("topic", letter.OriginalTopic).
+				Msg("dry run, would replay dead letter")
+			if err := printDeadLetter(letter); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writers := map[string]*kafka.Writer{}
+	defer func() {
+		for _, w := range writers {
+			_ = w.Close()
+		}
+	}()
+	ctx = context.WithValue(ctx, appctx.KafkaBrokersCTXKey, viper.GetString("kafka-brokers"))
+	for _, letter := range letters {
+		writer, ok := writers[letter.OriginalTopic]
+		if !ok {
+			if writer, _, err = kafkautils.InitKafkaWriter(ctx, letter.OriginalTopic); err != nil {
+				return fmt.Errorf("failed to initialize kafka writer: %w", err)
+			}
+			writers[letter.OriginalTopic] = writer
+		}
+		// the original headers are kept so the replay continues the trace of the failed message
+		if err := writer.WriteMessages(ctx, letter.Replay()); err != nil {
+			return fmt.Errorf("failed to replay dead letter %d/%d: %w", letter.Partition, letter.Offset, err)
+		}
+		logger.Info().
+			Int("partition", letter.Partition).
+			Int64("offset", letter.Offset).
+			Str("topic", letter.OriginalTopic).
+			Msg("replayed dead letter")
+	}
+	return nil
+}
+
+// printDeadLetter prints the dead letter with its headers and message decoded to json. Messages of
+// topics without a registered schema are printed raw
+func printDeadLetter(letter *kafkautils.DeadLetter) error {
+	headers := map[string]string{}
+	for _, h := range letter.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	out := struct {
+		*kafkautils.DeadLetter
+		Headers map[string]string `json:"headers"`
+		Message json.RawMessage   `json:"message,omitempty"`
+		Raw     []byte            `json:"raw,omitempty"`
+	}{
+		DeadLetter: letter,
+		Headers:    headers,
+	}
+	if decoded, err := letter.Decode(); err == nil {
+		out.Message = json.RawMessage(decoded)
+	} else {
+		out.Raw = letter.Value
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
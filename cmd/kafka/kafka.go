@@ -0,0 +1,16 @@
+package kafka
+
+import (
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/spf13/cobra"
+)
+
+// KafkaCmd is the kafka command
+var KafkaCmd = &cobra.Command{
+	Use:   "kafka",
+	Short: "provides kafka utilities",
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(KafkaCmd)
+}
@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/cmd"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/logging"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ReplayCmd re-consumes a topic from an offset or time and republishes its messages
+var ReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "republishes the messages of a topic from an offset or time to be handled again",
+	Run:   cmd.Perform("replay topic", RunReplay),
+}
+
+func init() {
+	KafkaCmd.AddCommand(ReplayCmd)
+
+	builder := cmd.NewFlagBuilder(ReplayCmd)
+
+	builder.Flag().String("kafka-brokers", "",
+		"the comma delimited list of kafka brokers").
+		Bind("kafka-brokers").
+		Env("KAFKA_BROKERS")
+
+	builder.Flag().String("topic", "",
+		"the topic to replay").
+		Require()
+
+	builder.Flag().Int("partition", -1,
+		"the partition to replay, all partitions are replayed when -1")
+
+	builder.Flag().Int64("offset", -1,
+		"the offset to start replaying at, the first retained message when -1")
+
+	builder.Flag().String("since", "",
+		"the time to start replaying at in RFC3339, instead of an offset")
+
+	builder.Flag().Int("limit", 1000,
+		"the maximum number of messages to replay per partition")
+
+	builder.Flag().String("destination", "",
+		"the topic to publish the replayed messages to, the replayed topic when empty")
+
+	builder.Flag().Bool("dry-run", false,
+		"print the decoded messages which would be replayed without publishing them")
+}
+
+// replayedMessage is a message read for replay, decoded with its topic's schema
+type replayedMessage struct {
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Time      time.Time       `json:"time"`
+	Key       []byte          `json:"key"`
+	Message   json.RawMessage `json:"message,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// RunReplay reads the messages of a topic from the offset or time, decodes them with the topic's schema
+// and republishes those which decode. Messages which do not decode would be rejected by the consumers
+// again and are reported instead
+func RunReplay(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		ctx, logger = logging.SetupLogger(ctx)
+	}
+
+	topic, err := command.Flags().GetString("topic")
+	if err != nil {
+		return err
+	}
+	partition, err := command.Flags().GetInt("partition")
+	if err != nil {
+		return err
+	}
+	offset, err := command.Flags().GetInt64("offset")
+	if err != nil {
+		return err
+	}
+	sinceFlag, err := command.Flags().GetString("since")
+	if err != nil {
+		return err
+	}
+	limit, err := command.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+	destination, err := command.Flags().GetString("destination")
+	if err != nil {
+		return err
+	}
+	dryRun, err := command.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if sinceFlag != "" {
+		if offset >= 0 {
+			return errors.New("only one of offset and since can be set")
+		}
+		if since, err = time.Parse(time.RFC3339, sinceFlag); err != nil {
+			return fmt.Errorf("invalid since %q: %w", sinceFlag, err)
+		}
+	}
+	if destination == "" {
+		destination = topic
+	}
+	registered := false
+	for _, t := range kafkautils.Topics() {
+		registered = registered || t == topic
+	}
+	if !registered {
+		return fmt.Errorf("cannot replay %s: %w", topic, kafkautils.ErrUnknownTopic)
+	}
+
+	brokers := viper.GetString("kafka-brokers")
+	if brokers == "" {
+		return errors.New("kafka brokers must be set")
+	}
+	broker := strings.Split(brokers, ",")[0]
+	dialer, _, err := kafkautils.TLSDialer()
+	if err != nil {
+		return fmt.Errorf("failed to create kafka dialer: %w", err)
+	}
+
+	partitions := []int{partition}
+	if partition < 0 {
+		if partitions, err = kafkautils.Partitions(ctx, dialer, broker, topic); err != nil {
+			return err
+		}
+	}
+
+	// read every selected message before replaying any of them
+	var replays []kafka.Message
+	skipped := 0
+	encoder := json.NewEncoder(os.Stdout)
+	for _, p := range partitions {
+		msgs, err := kafkautils.ReadTopic(ctx, dialer, broker, topic, p, offset, since, limit)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			replayed := replayedMessage{
+				Partition: msg.Partition,
+				Offset:    msg.Offset,
+				Time:      msg.Time,
+				Key:       msg.Key,
+			}
+			decoded, err := kafkautils.DecodeMessage(topic, msg.Value)
+			if err != nil {
+				replayed.Error = err.Error()
+				skipped++
+			} else {
+				replayed.Message = json.RawMessage(decoded)
+				replays = append(replays, kafkautils.ReplayMessage(msg))
+			}
+			if dryRun || replayed.Error != "" {
+				if err := encoder.Encode(replayed); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if dryRun {
+		logger.Info().
+			Str("topic", topic).
+			Str("destination", destination).
+			Int("messages", len(replays)).
+			Int("skipped", skipped).
+			Msg("dry run, would replay messages")
+		return nil
+	}
+	if len(replays) == 0 {
+		logger.Info().Str("topic", topic).Int("skipped", skipped).Msg("no messages to replay")
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, appctx.KafkaBrokersCTXKey, brokers)
+	writer, _, err := kafkautils.InitKafkaWriter(ctx, destination)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kafka writer: %w", err)
+	}
+	defer func() { _ = writer.Close() }()
+	if err := writer.WriteMessages(ctx, replays...); err != nil {
+		return fmt.Errorf("failed to replay messages: %w", err)
+	}
+	logger.Info().
+		Str("topic", topic).
+		Str("destination", destination).
+		Int("messages", len(replays)).
+		Int("skipped", skipped).
+		Msg("replayed messages")
+	return nil
+}
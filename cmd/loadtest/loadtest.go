@@ -0,0 +1,160 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/loadtest"
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/spf13/cobra"
+)
+
+// LoadTestCmd generates traffic against an environment, reporting latency percentiles
+var LoadTestCmd = &cobra.Command{
+	Use:   "loadtest",
+	Short: "generates order creation, credential signing and vote traffic against an environment",
+	Long: "generates order creation, credential signing and vote traffic against an environment at " +
+		"the given rate and mix, using the payment service's request types, and reports latency percentiles",
+	Run: cmd.Perform("load test", RunLoadTest),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(LoadTestCmd)
+
+	builder := cmd.NewFlagBuilder(LoadTestCmd)
+
+	builder.Flag().String("target", "",
+		"the base url of the environment to generate traffic against").
+		Require()
+
+	builder.Flag().String("token", "",
+		"the bearer token sent with each request").
+		Env("LOADTEST_TOKEN")
+
+	builder.Flag().Float64("rate", 10,
+		"the number of requests started per second")
+
+	builder.Flag().Duration("duration", time.Minute,
+		"how long to generate traffic for")
+
+	builder.Flag().Int("concurrency", 50,
+		"the most requests in flight, requests beyond it are dropped and reported")
+
+	builder.Flag().String("mix", "orders=5,credentials=3,votes=2",
+		"the relative weight of each scenario")
+
+	builder.Flag().String("sku", "",
+		"the sku token orders are created for")
+
+	builder.Flag().String("order-id", "",
+		"a paid order credentials are signed for, otherwise an order is created for each signing")
+
+	builder.Flag().Int("credentials", 10,
+		"the number of blinded credentials in each signing request")
+
+	builder.Flag().String("vote-public-key", "",
+		"the public key of the issuer vote credentials are bound to")
+
+	builder.Flag().Int("vote-batch", 5,
+		"the number of credentials in each vote")
+
+	builder.Flag().String("channel", "loadtest.example.com",
+		"the publisher voted for, which should never be paid out")
+
+	builder.Flag().String("out", "",
+		"the file the report is written to, otherwise it is printed")
+}
+
+// RunLoadTest generates the configured traffic and reports on it
+func RunLoadTest(command *cobra.Command, args []string) error {
+	flags := command.Flags()
+	target, err := flags.GetString("target")
+	if err != nil {
+		return err
+	}
+	token, err := flags.GetString("token")
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		token = os.Getenv("LOADTEST_TOKEN")
+	}
+	rate, err := flags.GetFloat64("rate")
+	if err != nil {
+		return err
+	}
+	duration, err := flags.GetDuration("duration")
+	if err != nil {
+		return err
+	}
+	concurrency, err := flags.GetInt("concurrency")
+	if err != nil {
+		return err
+	}
+	mixFlag, err := flags.GetString("mix")
+	if err != nil {
+		return err
+	}
+	mix, err := loadtest.ParseMix(mixFlag)
+	if err != nil {
+		return err
+	}
+
+	payloads := &loadtest.Payloads{}
+	if payloads.SKU, err = flags.GetString("sku"); err != nil {
+		return err
+	}
+	if payloads.OrderID, err = flags.GetString("order-id"); err != nil {
+		return err
+	}
+	if payloads.Credentials, err = flags.GetInt("credentials"); err != nil {
+		return err
+	}
+	if payloads.VotePublicKey, err = flags.GetString("vote-public-key"); err != nil {
+		return err
+	}
+	if payloads.VoteBatch, err = flags.GetInt("vote-batch"); err != nil {
+		return err
+	}
+	if payloads.Channel, err = flags.GetString("channel"); err != nil {
+		return err
+	}
+	if (mix["orders"] > 0 || (mix["credentials"] > 0 && payloads.OrderID == "")) && payloads.SKU == "" {
+		return fmt.Errorf("--sku is required to create orders")
+	}
+	if mix["votes"] > 0 && payloads.VotePublicKey == "" {
+		return fmt.Errorf("--vote-public-key is required to vote")
+	}
+
+	client, err := clients.New(target, token)
+	if err != nil {
+		return err
+	}
+	report, err := loadtest.Run(command.Context(), client, loadtest.Config{
+		Rate:        rate,
+		Duration:    duration,
+		Concurrency: concurrency,
+		Mix:         mix,
+	}, payloads.Scenarios())
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := flags.GetString("out")
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		return ioutil.WriteFile(path, out, 0600)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
@@ -0,0 +1,78 @@
+package merchant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/payment/paymenttest"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/spf13/cobra"
+)
+
+// TestCredentialsCmd generates signed credentials for testing redemption
+var TestCredentialsCmd = &cobra.Command{
+	Use:   "test-credentials",
+	Short: "signs new credentials with a merchant's issuer, printing their redemption payloads",
+	Long: "signs new credentials with a merchant's issuer on the challenge bypass server at " +
+		"CHALLENGE_BYPASS_SERVER, so partners can test redemption before implementing the client",
+	Run: cmd.Perform("merchant test credentials", RunTestCredentials),
+}
+
+func init() {
+	MerchantCmd.AddCommand(TestCredentialsCmd)
+
+	credentialsBuilder := cmd.NewFlagBuilder(TestCredentialsCmd)
+
+	credentialsBuilder.Flag().String("merchant-id", "",
+		"the merchant whose issuer signs the credentials").
+		Require()
+
+	credentialsBuilder.Flag().String("sku", "",
+		"the sku whose issuer signs the credentials").
+		Require()
+
+	credentialsBuilder.Flag().Int("count", 1,
+		"the number of credentials to generate")
+
+	credentialsBuilder.Flag().String("payload", "",
+		"the payload the credentials are bound to, such as a base64 encoded vote")
+}
+
+// RunTestCredentials signs new credentials and prints them with their redemption payloads
+func RunTestCredentials(command *cobra.Command, args []string) error {
+	flags := command.Flags()
+	merchantID, err := flags.GetString("merchant-id")
+	if err != nil {
+		return err
+	}
+	sku, err := flags.GetString("sku")
+	if err != nil {
+		return err
+	}
+	count, err := flags.GetInt("count")
+	if err != nil {
+		return err
+	}
+	payload, err := flags.GetString("payload")
+	if err != nil {
+		return err
+	}
+
+	client, err := cbr.New()
+	if err != nil {
+		return err
+	}
+	creds, err := paymenttest.GenerateTestCredentials(command.Context(), client, merchantID, sku, count, payload)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
@@ -0,0 +1,116 @@
+package merchant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// MerchantCmd is a subcommand for merchants
+	MerchantCmd = &cobra.Command{
+		Use:   "merchant",
+		Short: "provides merchant onboarding",
+	}
+	// BootstrapMerchantCmd onboards a merchant
+	BootstrapMerchantCmd = &cobra.Command{
+		Use:   "bootstrap",
+		Short: "creates a merchant, its credential issuer and api key, printing the configuration bundle",
+		Run:   cmd.Perform("merchant bootstrap", RunBootstrapMerchant),
+	}
+)
+
+func init() {
+	MerchantCmd.AddCommand(BootstrapMerchantCmd)
+	cmd.RootCmd.AddCommand(MerchantCmd)
+
+	bootstrapBuilder := cmd.NewFlagBuilder(BootstrapMerchantCmd)
+
+	bootstrapBuilder.Flag().String("id", "",
+		"the merchant id, referenced by orders, issuers and api keys").
+		Require()
+
+	bootstrapBuilder.Flag().String("name", "",
+		"the display name of the merchant").
+		Require()
+
+	bootstrapBuilder.Flag().StringSlice("skus", []string{},
+		"the skus the merchant may sell")
+
+	bootstrapBuilder.Flag().StringSlice("webhook-urls", []string{},
+		"the urls order events are delivered to, a webhook secret is created when set")
+
+	bootstrapBuilder.Flag().String("key-name", "default",
+		"the name of the api key")
+
+	bootstrapBuilder.Flag().StringSlice("scopes", []string{payment.KeyScopeTransactionsRead},
+		"the scopes granted to the api key")
+
+	bootstrapBuilder.Flag().String("datastore", "",
+		"the datastore for the payment system, DATABASE_URL when unset")
+}
+
+// RunBootstrapMerchant onboards a merchant and prints its configuration bundle
+func RunBootstrapMerchant(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+
+	flags := command.Flags()
+	id, err := flags.GetString("id")
+	if err != nil {
+		return err
+	}
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	skus, err := flags.GetStringSlice("skus")
+	if err != nil {
+		return err
+	}
+	webhookURLs, err := flags.GetStringSlice("webhook-urls")
+	if err != nil {
+		return err
+	}
+	keyName, err := flags.GetString("key-name")
+	if err != nil {
+		return err
+	}
+	scopes, err := flags.GetStringSlice("scopes")
+	if err != nil {
+		return err
+	}
+	databaseURL, err := flags.GetString("datastore")
+	if err != nil {
+		return err
+	}
+
+	datastore, err := payment.NewPostgres(databaseURL, false, "payment_db")
+	if err != nil {
+		return fmt.Errorf("unable to connect to payment db: %w", err)
+	}
+	service, err := payment.InitOnboardingService(datastore)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := service.BootstrapMerchant(ctx, &payment.Merchant{
+		ID:          id,
+		Name:        name,
+		AllowedSKUs: skus,
+		WebhookURLs: webhookURLs,
+	}, keyName, scopes)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// MigrateCmd is a subcommand for managing database schemas
+	MigrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "provides database schema migration utilities",
+	}
+	// UpCmd migrates a schema up
+	UpCmd = &cobra.Command{
+		Use:   "up",
+		Short: "migrates the schema up to the version of the code, or to --version",
+		Run:   cmd.Perform("migrate up", RunUp),
+	}
+	// DownCmd migrates a schema down
+	DownCmd = &cobra.Command{
+		Use:   "down",
+		Short: "migrates the schema down to --version",
+		Run:   cmd.Perform("migrate down", RunDown),
+	}
+	// StatusCmd prints the version of a schema
+	StatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "prints the version of the schema and of the code",
+		Run:   cmd.Perform("migrate status", RunStatus),
+	}
+)
+
+func init() {
+	MigrateCmd.AddCommand(UpCmd, DownCmd, StatusCmd)
+	cmd.RootCmd.AddCommand(MigrateCmd)
+
+	migrateBuilder := cmd.NewFlagBuilder(UpCmd).AddCommand(DownCmd).AddCommand(StatusCmd)
+
+	migrateBuilder.Flag().String("database-url", "",
+		"the database whose schema is migrated, DATABASE_URL when unset")
+
+	migrateBuilder.Flag().String("track", "",
+		"the migration track of the database, such as eyeshade, whose code version is used")
+
+	cmd.NewFlagBuilder(UpCmd).Flag().Uint("version", 0,
+		"the version to migrate up to, the version of the code when unset")
+
+	cmd.NewFlagBuilder(DownCmd).Flag().Uint("version", 0,
+		"the version to migrate down to").
+		Require()
+}
+
+// codeVersion is the schema version the code expects for the track
+func codeVersion(track string) (uint, error) {
+	if track == "" {
+		return grantserver.CurrentMigrationVersion, nil
+	}
+	version, ok := grantserver.MigrationTracks[track]
+	if !ok {
+		return 0, fmt.Errorf("unknown migration track %s", track)
+	}
+	return version, nil
+}
+
+// connect opens the database without migrating it, returning its status and the code version of its track
+func connect(command *cobra.Command) (*grantserver.Postgres, *grantserver.MigrationStatus, uint, error) {
+	track, err := command.Flags().GetString("track")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	version, err := codeVersion(track)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	databaseURL, err := command.Flags().GetString("database-url")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	pg, err := grantserver.NewPostgres(databaseURL, false, "")
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("unable to connect to the database: %w", err)
+	}
+	status, err := pg.MigrationStatus(command.Context())
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return pg, status, version, nil
+}
+
+// RunUp migrates the schema up, failing if the target is below the schema's version
+func RunUp(command *cobra.Command, args []string) error {
+	pg, status, target, err := connect(command)
+	if err != nil {
+		return err
+	}
+	version, err := command.Flags().GetUint("version")
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		target = version
+	}
+	if target < status.Version {
+		return fmt.Errorf("the schema is at version %d, migrate down to reach version %d", status.Version, target)
+	}
+	return pg.Migrate(command.Context(), target)
+}
+
+// RunDown migrates the schema down, failing if the target is not below the schema's version
+func RunDown(command *cobra.Command, args []string) error {
+	pg, status, _, err := connect(command)
+	if err != nil {
+		return err
+	}
+	target, err := command.Flags().GetUint("version")
+	if err != nil {
+		return err
+	}
+	if status.Version < 2 {
+		return fmt.Errorf("the schema is at version %d, there is no version to migrate down to", status.Version)
+	}
+	if target == 0 || target >= status.Version {
+		return fmt.Errorf("the schema is at version %d, the version to migrate down to must be between 1 and %d",
+			status.Version, status.Version-1)
+	}
+	return pg.Migrate(command.Context(), target)
+}
+
+// RunStatus prints the version of the schema and of the code as json
+func RunStatus(command *cobra.Command, args []string) error {
+	_, status, version, err := connect(command)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(struct {
+		*grantserver.MigrationStatus
+		CodeVersion uint `json:"codeVersion"`
+		Pending     bool `json:"pending"`
+	}{status, version, status.Version < version}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(out))
+	return nil
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/rewards"
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/metrics"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	sentry "github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi"
 	"github.com/spf13/cobra"
@@ -72,14 +75,24 @@ func RestRun(command *cobra.Command, args []string) {
 
 	// do rest endpoints
 	r := cmd.SetupRouter(command.Context())
-	r.Get("/v1/parameters", middleware.InstrumentHandler(
-		"GetParametersHandler", rewards.GetParametersHandler(s)).ServeHTTP)
+	// public configuration, cached here and by the CDN
+	parametersCache := middleware.NewResponseCache(time.Minute)
+	r.With(metrics.HTTPServer("rewards")).Get("/v1/parameters", middleware.InstrumentHandler(
+		"GetParametersHandler", middleware.ETag("public, max-age=60")(
+			parametersCache.Middleware(rewards.GetParametersHandler(s)))).ServeHTTP)
 
 	// make sure exceptions go to sentry
-	defer sentry.Flush(time.Second * 2)
+	flushReporting, err := reporting.Init(ctx, "rewards")
+	if err != nil {
+		logger.Panic().Err(err).Msg("unable to setup reporting!")
+	}
+	defer flushReporting()
+
+	shutdownTracing := tracing.Init("rewards")
+	defer func() { _ = shutdownTracing(context.Background()) }()
 
 	go func() {
-		err := http.ListenAndServe(":9090", middleware.Metrics())
+		err := metrics.NewServer().ListenAndServe()
 		if err != nil {
 			sentry.CaptureException(err)
 			logger.Panic().Err(err).Msg("metrics HTTP server start failed!")
@@ -8,6 +8,7 @@ import (
 	"github.com/brave-intl/bat-go/middleware"
 	appctx "github.com/brave-intl/bat-go/utils/context"
 	"github.com/brave-intl/bat-go/utils/handlers"
+	srv "github.com/brave-intl/bat-go/utils/service"
 	"github.com/go-chi/chi"
 	chiware "github.com/go-chi/chi/middleware"
 	"github.com/rs/zerolog/hlog"
@@ -27,6 +28,12 @@ func init() {
 		"the default address to bind to")
 	Must(viper.BindPFlag("address", ServeCmd.PersistentFlags().Lookup("address")))
 	Must(viper.BindEnv("address", "ADDR"))
+
+	// shutdown-timeout - how long in flight work is given to drain once the server is asked to stop
+	ServeCmd.PersistentFlags().Duration("shutdown-timeout", srv.ShutdownTimeout,
+		"how long in flight requests, jobs and messages are given to drain on shutdown")
+	Must(viper.BindPFlag("shutdown-timeout", ServeCmd.PersistentFlags().Lookup("shutdown-timeout")))
+	Must(viper.BindEnv("shutdown-timeout", "SHUTDOWN_TIMEOUT"))
 }
 
 // ServeCmd the serve command
@@ -35,6 +42,14 @@ var ServeCmd = &cobra.Command{
 	Short: "entrypoint to serve a micro-service",
 }
 
+// ShutdownTimeout is how long in flight work is given to drain on shutdown
+func ShutdownTimeout() time.Duration {
+	if timeout := viper.GetDuration("shutdown-timeout"); timeout > 0 {
+		return timeout
+	}
+	return srv.ShutdownTimeout
+}
+
 // SetupRouter sets up a router
 func SetupRouter(ctx context.Context) *chi.Mux {
 	logger, err := appctx.GetLogger(ctx)
@@ -48,7 +63,8 @@ func SetupRouter(ctx context.Context) *chi.Mux {
 		chiware.Timeout(timeout),
 		middleware.BearerToken,
 
-		middleware.RequestIDTransfer)
+		middleware.RequestIDTransfer,
+		middleware.Tracing)
 
 	if os.Getenv("ENV") == "production" {
 		r.Use(middleware.RateLimiter(ctx, 180))
@@ -58,7 +74,6 @@ func SetupRouter(ctx context.Context) *chi.Mux {
 		r.Use(
 			hlog.NewHandler(*logger),
 			hlog.UserAgentHandler("user_agent"),
-			hlog.RequestIDHandler("req_id", "Request-Id"),
 			middleware.RequestLogger(logger))
 
 		logger.Info().
@@ -70,6 +85,8 @@ func SetupRouter(ctx context.Context) *chi.Mux {
 			Str("environment", viper.GetString("environment")).
 			Msg("server starting")
 	}
+	// the request id and logger are in the context of every request
+	r.Use(middleware.NewAppCtx(nil, logger))
 	r.Get("/health-check", handlers.HealthCheckHandler(
 		ctx.Value(appctx.VersionCTXKey).(string),
 		ctx.Value(appctx.VersionCTXKey).(string),
@@ -0,0 +1,150 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/config"
+	"github.com/brave-intl/bat-go/utils/handlers"
+)
+
+// DatabaseConfig is the database every service shares
+type DatabaseConfig struct {
+	URL         string `env:"DATABASE_URL" required:"true" validate:"url" secret:"true"`
+	ReadOnlyURL string `env:"RO_DATABASE_URL" validate:"url" secret:"true"`
+}
+
+// DependencyConfig is the services the grant server calls
+type DependencyConfig struct {
+	ReputationServer      string   `env:"REPUTATION_SERVER" validate:"url"`
+	ReputationToken       string   `env:"REPUTATION_TOKEN" secret:"true"`
+	ChallengeBypassServer string   `env:"CHALLENGE_BYPASS_SERVER" validate:"url"`
+	ChallengeBypassToken  string   `env:"CHALLENGE_BYPASS_TOKEN" secret:"true"`
+	KafkaBrokers          []string `env:"KAFKA_BROKERS" validate:"hostport"`
+	RedisURL              string   `env:"REDIS_URL" validate:"url" secret:"true"`
+	SignerAddress         string   `env:"SIGNER_ADDRESS" validate:"hostport"`
+	SignerToken           string   `env:"SIGNER_TOKEN" secret:"true"`
+	SignerWorkers         int      `env:"SIGNER_WORKERS" default:"4"`
+	// calls to the challenge bypass server are retried and pass through a circuit breaker, see cbr.New
+	CBRRetryPolicies   string        `env:"CBR_RETRY_POLICIES"`
+	CBRBreakerFailures int           `env:"CBR_BREAKER_FAILURES" default:"5"`
+	CBRBreakerCooldown time.Duration `env:"CBR_BREAKER_COOLDOWN" default:"30s"`
+	// and are made over pooled connections tuned by these, see cbr.OptionsFromEnv
+	CBRTimeout             time.Duration `env:"CBR_TIMEOUT" default:"10s"`
+	CBRCallTimeouts        string        `env:"CBR_CALL_TIMEOUTS"`
+	CBRMaxIdleConns        int           `env:"CBR_MAX_IDLE_CONNS" default:"100"`
+	CBRMaxIdleConnsPerHost int           `env:"CBR_MAX_IDLE_CONNS_PER_HOST" default:"32"`
+	CBRIdleConnTimeout     time.Duration `env:"CBR_IDLE_CONN_TIMEOUT" default:"90s"`
+	CBRKeepAlive           time.Duration `env:"CBR_KEEP_ALIVE" default:"30s"`
+	CBRHTTP2               bool          `env:"CBR_HTTP2"`
+}
+
+// PaymentConfig configures the payment service
+type PaymentConfig struct {
+	FeatureMerchant bool   `env:"FEATURE_MERCHANT"`
+	EncryptionKey   string `env:"ENCRYPTION_KEY" secret:"true"`
+	SupportTokens   string `env:"SUPPORT_TOKENS" secret:"true"`
+	// credentials of votes are redeemed through the bulk redemption endpoint in batches of this many
+	RedemptionBatchSize int `env:"REDEMPTION_BATCH_SIZE" default:"100"`
+	// orders, issuers and merchant settings are cached for this long when set, in redis when there is one
+	CacheTTL time.Duration `env:"PAYMENT_CACHE_TTL"`
+}
+
+// NotificationConfig configures the notification service, which is disabled without a provider
+type NotificationConfig struct {
+	Provider       string `env:"NOTIFICATION_PROVIDER" validate:"oneof=sendgrid|ses|log"`
+	From           string `env:"NOTIFICATION_FROM"`
+	SendGridAPIKey string `env:"SENDGRID_API_KEY" secret:"true"`
+}
+
+// JobsConfig configures the optional scheduled jobs
+type JobsConfig struct {
+	RetentionEnabled         bool          `env:"RETENTION_ENABLED"`
+	BackupScratchDatabaseURL string        `env:"BACKUP_SCRATCH_DATABASE_URL" validate:"url" secret:"true"`
+	BackupBucket             string        `env:"BACKUP_BUCKET"`
+	ProbeCheckoutSKU         string        `env:"PROBE_CHECKOUT_SKU" secret:"true"`
+	ProbeTarget              string        `env:"PROBE_TARGET" validate:"url"`
+	LeaderLeaseTTL           time.Duration `env:"LEADER_LEASE_TTL" default:"30s"`
+	WorkerStaleAfter         time.Duration `env:"WORKER_STALE_AFTER" default:"2m"`
+	AnomalyPausePayouts      bool          `env:"ANOMALY_PAUSE_PAYOUTS"`
+}
+
+// DebugConfig are the options which must not be turned on in production
+type DebugConfig struct {
+	PProfEnabled   bool `env:"PPROF_ENABLED"`
+	FaultInjection bool `env:"FAULT_INJECTION"`
+}
+
+// Config is the configuration of the grant server, loaded and validated at startup
+type Config struct {
+	Env            string `env:"ENV" default:"local"`
+	MetricsAddress string `env:"METRICS_ADDRESS"`
+	SentryDSN      string `env:"SENTRY_DSN" validate:"url" secret:"true"`
+	TokenList      string `env:"TOKEN_LIST" secret:"true"`
+	// scoped tokens carrying a role and scopes are signed with this, see middleware.ScopedTokens
+	ScopedTokenSecret string `env:"SCOPED_TOKEN_SECRET" secret:"true"`
+	MessagesPath      string `env:"MESSAGES_PATH"`
+	Database          DatabaseConfig
+	Dependencies      DependencyConfig
+	Payment           PaymentConfig
+	Notification      NotificationConfig
+	Jobs              JobsConfig
+	Debug             DebugConfig
+}
+
+// Validate the rules spanning several options
+func (c *Config) Validate() []error {
+	errs := []error{}
+	if c.Env != "local" && c.Dependencies.ReputationServer == "" {
+		errs = append(errs, errors.New("REPUTATION_SERVER is required outside of local"))
+	}
+	if _, err := cbr.ParseRetryPolicies(c.Dependencies.CBRRetryPolicies); err != nil {
+		errs = append(errs, fmt.Errorf("CBR_RETRY_POLICIES: %w", err))
+	}
+	if c.Dependencies.CBRBreakerFailures < 1 {
+		errs = append(errs, errors.New("CBR_BREAKER_FAILURES must be at least 1"))
+	}
+	if c.Notification.Provider != "" && c.Notification.From == "" {
+		errs = append(errs, errors.New("NOTIFICATION_FROM is required with NOTIFICATION_PROVIDER"))
+	}
+	if c.Notification.Provider == "sendgrid" && c.Notification.SendGridAPIKey == "" {
+		errs = append(errs, errors.New("SENDGRID_API_KEY is required with the sendgrid NOTIFICATION_PROVIDER"))
+	}
+	if c.Jobs.BackupScratchDatabaseURL != "" {
+		if c.Jobs.BackupScratchDatabaseURL == c.Database.URL {
+			errs = append(errs, errors.New("BACKUP_SCRATCH_DATABASE_URL must not be DATABASE_URL, restores overwrite it"))
+		}
+		if c.Jobs.BackupBucket == "" {
+			errs = append(errs, errors.New("BACKUP_BUCKET is required with BACKUP_SCRATCH_DATABASE_URL"))
+		}
+	}
+	if c.Env == "production" {
+		if err := config.Exclusive(map[string]bool{
+			"ENV=production":  true,
+			"PPROF_ENABLED":   c.Debug.PProfEnabled,
+			"FAULT_INJECTION": c.Debug.FaultInjection,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("debug options must be off in production: %w", err))
+		}
+	}
+	return errs
+}
+
+// LoadConfig loads the grant server's configuration from the environment, reporting every invalid option
+func LoadConfig() (*Config, error) {
+	var cfg Config
+	if err := config.Load(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ConfigHandler dumps the effective configuration, with secrets redacted
+func ConfigHandler(cfg *Config) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return handlers.RenderContent(r.Context(), config.Redacted(cfg), w, http.StatusOK)
+	})
+}
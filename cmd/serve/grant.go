@@ -2,9 +2,8 @@ package serve
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	// needed for profiling
@@ -13,18 +12,34 @@ import (
 	_ "github.com/brave-intl/bat-go/cmd/wallets"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/anomaly"
+	"github.com/brave-intl/bat-go/backup"
 	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/grant"
 	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/notification"
 	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/probe"
 	"github.com/brave-intl/bat-go/promotion"
+	"github.com/brave-intl/bat-go/retention"
+	"github.com/brave-intl/bat-go/support"
 	"github.com/brave-intl/bat-go/utils/clients"
 	"github.com/brave-intl/bat-go/utils/clients/reputation"
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/dbhealth"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/faults"
 	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/i18n"
+	"github.com/brave-intl/bat-go/utils/leader"
 	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/metrics"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/brave-intl/bat-go/utils/scheduler"
 	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/brave-intl/bat-go/utils/workers"
 	"github.com/brave-intl/bat-go/wallet"
 	sentry "github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi"
@@ -92,24 +107,33 @@ func init() {
 		Env("BITFLYER_SERVER")
 }
 
-func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context, *chi.Mux, *promotion.Service, []srv.Job) {
+func setupRouter(ctx context.Context, logger *zerolog.Logger, cfg *Config) (context.Context, *chi.Mux, *promotion.Service, []srv.Job, *workers.Registry) {
 	buildTime := ctx.Value(appctx.BuildTimeCTXKey).(string)
 	commit := ctx.Value(appctx.CommitCTXKey).(string)
 	version := ctx.Value(appctx.VersionCTXKey).(string)
-	env := ctx.Value(appctx.EnvironmentCTXKey).(string)
 
 	// runnable jobs for the services created
 	jobs := []srv.Job{}
 
 	govalidator.SetFieldsRequiredByDefault(true)
 
+	// error responses are localized with the built in messages unless a directory extends them
+	if cfg.MessagesPath != "" {
+		catalog, err := i18n.Load(cfg.MessagesPath)
+		if err != nil {
+			logger.Panic().Err(err).Msg("failed to load the error message catalog")
+		}
+		i18n.SetDefault(catalog)
+	}
+
 	r := chi.NewRouter()
 
 	// chain should be:
-	// id / transfer -> ip -> heartbeat -> request logger / recovery -> token check -> rate limit
+	// id / transfer -> trace -> ip -> heartbeat -> request logger / recovery -> token check -> rate limit
 	// -> instrumentation -> handler
 	r.Use(chiware.RequestID)
 	r.Use(middleware.RequestIDTransfer)
+	r.Use(middleware.Tracing)
 
 	// NOTE: This uses standard fowarding headers, note that this puts implicit trust in the header values
 	// provided to us. In particular it uses the first element.
@@ -122,13 +146,14 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 		// Also handles panic recovery
 		r.Use(hlog.NewHandler(*logger))
 		r.Use(hlog.UserAgentHandler("user_agent"))
-		r.Use(hlog.RequestIDHandler("req_id", "Request-Id"))
 		r.Use(middleware.RequestLogger(logger))
 	}
+	// the request id and logger are in the context of every request
+	r.Use(middleware.NewAppCtx(nil, logger))
 	// now we have middlewares we want included in logging
 	r.Use(chiware.Timeout(15 * time.Second))
 	r.Use(middleware.BearerToken)
-	if os.Getenv("ENV") == "production" {
+	if cfg.Env == "production" {
 		r.Use(middleware.RateLimiter(ctx, 180))
 	}
 
@@ -177,25 +202,27 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 	// add runnable jobs:
 	jobs = append(jobs, promotionService.Jobs()...)
 
-	r.Mount("/v1/promotions", promotion.Router(promotionService))
-	r.Mount("/v2/promotions", promotion.RouterV2(promotionService))
+	// request latencies are labelled with the service handling the route
+	promotionRoutes := r.With(metrics.HTTPServer("promotion"))
+	promotionRoutes.Mount("/v1/promotions", promotion.Router(promotionService))
+	promotionRoutes.Mount("/v2/promotions", promotion.RouterV2(promotionService))
 
 	sRouter, err := promotion.SuggestionsRouter(promotionService)
 	if err != nil {
 		logger.Panic().Err(err).Msg("failed to initialize the suggestions router")
 	}
 
-	r.Mount("/v1/suggestions", sRouter)
+	promotionRoutes.Mount("/v1/suggestions", sRouter)
 
 	sV2Router, err := promotion.SuggestionsV2Router(promotionService)
 	if err != nil {
 		logger.Panic().Err(err).Msg("failed to initialize the suggestions router")
 	}
 
-	r.Mount("/v2/suggestions", sV2Router)
+	promotionRoutes.Mount("/v2/suggestions", sV2Router)
 
 	// temporarily house batloss events in promotion to avoid widespread conflicts later
-	r.Mount("/v1/wallets", promotion.WalletEventRouter(promotionService))
+	promotionRoutes.Mount("/v1/wallets", promotion.WalletEventRouter(promotionService))
 
 	paymentPG, err := payment.NewPostgres("", true, "payment_db")
 	if err != nil {
@@ -208,14 +235,150 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 		logger.Panic().Err(err).Msg("Payment service initialization failed")
 	}
 
+	// orders, credentials and issuers are read from the read replica when there is one
+	if cfg.Database.ReadOnlyURL != "" {
+		paymentRODB, err := payment.NewReadOnlyPostgres(cfg.Database.ReadOnlyURL, false, "payment_db", "payment_read_only_db")
+		if err != nil {
+			sentry.CaptureException(err)
+			logger.Error().Err(err).Msg("Could not start payment reader postgres connection")
+		} else {
+			paymentService.UseReadReplica(paymentRODB)
+		}
+	}
+
+	// hot reads are served from the cache, ahead of the read replica
+	if ttl := cfg.Payment.CacheTTL; ttl > 0 {
+		paymentService.UseCache(payment.NewCache("payment_cache:"), ttl)
+	}
+
+	// webhooks and emails are delivered from a shared queue, retried until they are accepted
+	deliveryQueue := notification.NewQueue(notification.NewPostgresStore(paymentPG.RawDB()))
+	paymentService.UseDeliveryQueue(deliveryQueue)
+
+	// credentials are signed by a dedicated signer deployment when there is one
+	if signerAddress := cfg.Dependencies.SignerAddress; signerAddress != "" {
+		signer, err := payment.DialSigner(ctx, signerAddress, cfg.Dependencies.SignerToken)
+		if err != nil {
+			logger.Panic().Err(err).Msg("failed to connect to the signer")
+		}
+		paymentService.UseSigner(signer, cfg.Dependencies.SignerWorkers)
+	}
+
 	// add runnable jobs:
 	jobs = append(jobs, paymentService.Jobs()...)
+	jobs = append(jobs, deliveryQueue.Jobs()...)
+
+	// singleton job families are run by the instance holding their lease, which a standby takes over
+	// once the leader stops renewing it
+	elector := leader.NewElector(leader.NewPostgresStore(paymentPG.RawDB()), leader.Holder(), cfg.Jobs.LeaderLeaseTTL,
+		leader.FamilySweeps, leader.FamilyExports)
+	jobs = append(jobs, elector.Jobs()...)
+
+	// job workers heartbeat with the job they are on, a watchdog on the sweeps leader requeues the
+	// jobs of workers whose heartbeat went stale
+	workerRegistry := workers.New(workers.NewPostgresStore(paymentPG.RawDB()), leader.Holder(), cfg.Jobs.WorkerStaleAfter)
+	for _, job := range workerRegistry.Jobs() {
+		job.Func = elector.Guard(leader.FamilySweeps, job.Func)
+		jobs = append(jobs, job)
+	}
 
-	r.Mount("/v1/credentials", payment.CredentialRouter(paymentService))
-	r.Mount("/v1/orders", payment.Router(paymentService))
-	r.Mount("/v1/votes", payment.VoteRouter(paymentService))
+	// scheduled jobs run on a cron schedule, each is locked so only one instance runs it
+	jobScheduler := scheduler.New(scheduler.NewPostgresStore(paymentPG.RawDB()))
+	jobScheduler.UseLeadership(elector)
+	if err := jobScheduler.Register(paymentService.ScheduledJobs()...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	if err := jobScheduler.Register(walletService.ScheduledJobs()...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	if err := jobScheduler.Register(promotionService.ScheduledJobs()...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	probeJobs, err := probe.ScheduledJobs()
+	if err != nil {
+		logger.Panic().Err(err).Msg("failed to initialize synthetic probes")
+	}
+	if err := jobScheduler.Register(probeJobs...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	retentionStore := retention.NewPostgresStore(paymentPG.RawDB())
+	retentionJobs, err := retention.ScheduledJobs(retentionStore)
+	if err != nil {
+		logger.Panic().Err(err).Msg("failed to initialize retention policies")
+	}
+	if err := jobScheduler.Register(retentionJobs...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	backupJobs, err := backup.ScheduledJobs(paymentPG.RawDB())
+	if err != nil {
+		logger.Panic().Err(err).Msg("failed to initialize backup verification")
+	}
+	if err := jobScheduler.Register(backupJobs...); err != nil {
+		logger.Panic().Err(err).Msg("failed to register scheduled jobs")
+	}
+	jobs = append(jobs, srv.Job{
+		Name:    "scheduler",
+		Service: "grant",
+		Func:    jobScheduler.RunDue,
+		Cadence: 5 * time.Second,
+		Workers: 1,
+	})
+	// ingest rates are compared with the same hour of the previous week, spikes can pause payouts
+	ingestDetector := anomaly.NewDetector(anomaly.NewPostgresStore(promotionRODB.RawDB()), anomaly.DefaultThresholds, anomaly.Topics...)
+	if cfg.Jobs.AnomalyPausePayouts {
+		ingestDetector.PausePayouts(promotionService)
+	}
+	jobs = append(jobs, ingestDetector.Jobs()...)
+	// pool saturation, connection waits and long transactions of every database pool
+	dbMonitor := dbhealth.NewMonitor(dbhealth.NewPostgresActivity(paymentPG.RawDB()), dbhealth.DefaultThresholds)
+	for name, db := range grantserver.Pools() {
+		dbMonitor.Add(name, db)
+	}
+	jobs = append(jobs, dbMonitor.Jobs()...)
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/db-health", dbhealth.Router(dbMonitor))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/ingest-rates", anomaly.Router(ingestDetector))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/jobs", scheduler.Router(jobScheduler))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/admin/workers", workers.Router(workerRegistry))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Method("GET", "/v1/config", ConfigHandler(cfg))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/exports", payment.ExportRouter(paymentService))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/order-events", payment.OrderEventRouter(paymentService))
+	r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/deliveries", notification.DeliveryRouter(deliveryQueue))
+	if cfg.Notification.Provider != "" {
+		notificationService, err := notification.InitService(paymentPG.RawDB(), deliveryQueue)
+		if err != nil {
+			logger.Panic().Err(err).Msg("Notification service initialization failed")
+		}
+		r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/notifications", notification.Router(notificationService))
+	}
+	supportService, err := support.InitService(paymentPG, walletService, paymentPG.RawDB())
+	if err != nil {
+		logger.Panic().Err(err).Msg("Support service initialization failed")
+	}
+	r.Mount("/v1/support", support.Router(supportService))
+	if faults.Enabled() {
+		logger.Warn().Msg("fault injection is enabled")
+		r.With(middleware.SimpleTokenAuthorizedOnly).Mount("/v1/faults", faults.Router())
+	}
 
-	if os.Getenv("FEATURE_MERCHANT") != "" {
+	paymentRoutes := r.With(metrics.HTTPServer("payment"))
+	// readiness of the payment service alone, so its pods are gated on the payment database
+	paymentRoutes.Mount("/v1/payment", payment.HealthRouter(paymentService, version, buildTime, commit))
+	paymentRoutes.Mount("/.well-known", payment.WellKnownRouter(paymentService))
+	paymentRoutes.Mount("/v1/credentials", payment.CredentialRouter(paymentService))
+	paymentRoutes.Mount("/v1/orders", payment.Router(paymentService))
+	paymentRoutes.Mount("/v1/votes", payment.VoteRouter(paymentService))
+	paymentRoutes.Mount("/v1/skus", payment.SKURouter(paymentService))
+	paymentRoutes.Mount("/v1/webhooks", payment.WebhookRouter(paymentService))
+	paymentRoutes.Mount("/v1/admin/issuers", payment.IssuerRouter(paymentService))
+	paymentRoutes.Mount("/v1/admin/vote-tallies", payment.VoteTallyRouter(paymentService))
+	paymentRoutes.Mount("/v1/admin/vouchers", payment.VoucherRouter(paymentService))
+	retentionRouter, err := retention.Router(retentionStore, paymentService.Datastore)
+	if err != nil {
+		logger.Panic().Err(err).Msg("failed to initialize retention policies")
+	}
+	paymentRoutes.Mount("/v1/admin/retention", retentionRouter)
+
+	if cfg.Payment.FeatureMerchant {
 		payment.InitEncryptionKeys()
 		paymentDB, err := payment.NewPostgres("", true, "merch_payment_db")
 		if err != nil {
@@ -227,11 +390,13 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 			sentry.CaptureException(err)
 			logger.Panic().Err(err).Msg("Payment service initialization failed")
 		}
-		r.Mount("/v1/merchants", payment.MerchantRouter(paymentService))
+		paymentService.UseDeliveryQueue(deliveryQueue)
+		paymentRoutes.Mount("/v1/merchants", payment.MerchantRouter(paymentService))
+		paymentRoutes.Mount("/v1/audit", payment.AuditRouter(paymentService))
 	}
 
 	// add profiling flag to enable profiling routes
-	if os.Getenv("PPROF_ENABLED") != "" {
+	if cfg.Debug.PProfEnabled {
 		// pprof attaches routes to default serve mux
 		// host:6061/debug/pprof/
 		go func() {
@@ -245,16 +410,18 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 		Str("buildTime", buildTime).
 		Msg("server starting up")
 
-	r.Get("/health-check", handlers.HealthCheckHandler(version, buildTime, commit))
+	// composite client operations, each operation is routed through the full middleware chain
+	r.Method("POST", "/v1/batch", middleware.InstrumentHandler("Batch", handlers.BatchHandler(r, "/v1/batch", 10)))
 
-	reputationServer := os.Getenv("REPUTATION_SERVER")
-	reputationToken := os.Getenv("REPUTATION_TOKEN")
-	if len(reputationServer) == 0 {
-		if env != "local" {
-			logger.Panic().Msg("REPUTATION_SERVER is missing in production environment")
-		}
-	} else {
-		proxyRouter := reputation.ProxyRouter(reputationServer, reputationToken)
+	r.Get("/health-check", handlers.HealthCheckHandler(version, buildTime, commit))
+	// liveness only reports the process is serving, readiness checks the dependencies
+	r.Get("/health", handlers.HealthCheckHandler(version, buildTime, commit))
+	r.Get("/ready", handlers.ReadinessHandler(5*time.Second,
+		readinessChecks(cfg, promotionDB.RawDB(), promotionRODB.RawDB())...))
+
+	// a reputation server is required outside of local, which the config validates
+	if reputationServer := cfg.Dependencies.ReputationServer; reputationServer != "" {
+		proxyRouter := reputation.ProxyRouter(reputationServer, cfg.Dependencies.ReputationToken)
 		r.Mount("/v1/devicecheck", proxyRouter)
 		r.Mount("/v1/captchas", proxyRouter)
 		r.Mount("/v2/attestations/safetynet", proxyRouter)
@@ -262,16 +429,36 @@ func setupRouter(ctx context.Context, logger *zerolog.Logger) (context.Context,
 		r.Mount("/v3/captcha", proxyRouter)
 	}
 
-	return ctx, r, promotionService, jobs
+	return ctx, r, promotionService, jobs, workerRegistry
 }
 
-func jobWorker(ctx context.Context, job func(context.Context) (bool, error), duration time.Duration) {
-	logger, err := appctx.GetLogger(ctx)
+// jobWorker runs the job at its cadence until ctx is done. Runs are given runCtx, which outlives ctx, so a
+// run in flight when the server is asked to stop can finish while no new runs are started
+func jobWorker(ctx context.Context, runCtx context.Context, registry *workers.Registry, job srv.Job) {
+	logger, err := appctx.GetLogger(runCtx)
 	if err != nil {
-		ctx, logger = logging.SetupLogger(ctx)
+		runCtx, logger = logging.SetupLogger(runCtx)
 	}
+	worker := registry.Register(job.Service, job.Name)
+	runCtx = workers.WithWorker(runCtx, worker)
+	defer func() {
+		if err := worker.Stop(context.Background()); err != nil {
+			logger.Warn().Err(err).Str("job", job.Name).Msg("failed to remove worker from the registry")
+		}
+	}()
 	for {
-		_, err := job(ctx)
+		if err := worker.Beat(runCtx); err != nil {
+			logger.Warn().Err(err).Str("job", job.Name).Msg("failed to record worker heartbeat")
+		}
+		started := time.Now()
+		attempted, err := job.Func(runCtx)
+		if rerr := worker.Release(runCtx); rerr != nil {
+			logger.Warn().Err(rerr).Str("job", job.Name).Msg("failed to record worker heartbeat")
+		}
+		// idle polls would swamp the latencies of actual runs
+		if attempted || err != nil {
+			metrics.ObserveJob(job.Service, job.Name, started, err)
+		}
 		if err != nil {
 			log := logger.Error().Err(err)
 			httpError, ok := err.(*errorutils.ErrorBundle)
@@ -286,8 +473,13 @@ func jobWorker(ctx context.Context, job func(context.Context) (bool, error), dur
 			log.Msg("error encountered in job run")
 			sentry.CaptureException(err)
 		}
-		// regardless if attempted or not, wait for the duration until retrying
-		<-time.After(duration)
+		// regardless if attempted or not, wait for the duration until retrying,
+		// stopping once the server is shutting down
+		select {
+		case <-time.After(job.Cadence):
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
@@ -304,6 +496,27 @@ func RunGrantServer(cmd *cobra.Command, args []string) error {
 	)
 }
 
+// readinessChecks are the dependencies the grant server needs to serve requests, the
+// database primary is critical while the remaining dependencies degrade the service
+func readinessChecks(cfg *Config, primary, replica handlers.Pinger) []handlers.DependencyCheck {
+	checks := []handlers.DependencyCheck{
+		handlers.PingCheck("db", true, primary),
+	}
+	if replica != primary {
+		checks = append(checks, handlers.PingCheck("db_replica", false, replica))
+	}
+	if brokers := cfg.Dependencies.KafkaBrokers; len(brokers) > 0 {
+		checks = append(checks, handlers.DialCheck("kafka", false, brokers...))
+	}
+	if cbrServer := cfg.Dependencies.ChallengeBypassServer; cbrServer != "" {
+		checks = append(checks, handlers.HTTPCheck("challenge_bypass", false, cbrServer))
+	}
+	if redisURL := cfg.Dependencies.RedisURL; redisURL != "" {
+		checks = append(checks, handlers.RedisCheck("redis", false, redisURL))
+	}
+	return checks
+}
+
 // GrantServer runs the grant server
 func GrantServer(
 	ctx context.Context,
@@ -314,19 +527,19 @@ func GrantServer(
 		ctx, logger = logging.SetupLogger(ctx)
 	}
 
-	sentryDsn := os.Getenv("SENTRY_DSN")
-	if sentryDsn != "" {
-		buildTime := ctx.Value(appctx.BuildTimeCTXKey).(string)
-		commit := ctx.Value(appctx.CommitCTXKey).(string)
-		err := sentry.Init(sentry.ClientOptions{
-			Dsn:     sentryDsn,
-			Release: fmt.Sprintf("bat-go@%s-%s", commit, buildTime),
-		})
-		defer sentry.Flush(2 * time.Second)
-		if err != nil {
-			logger.Panic().Err(err).Msg("unable to setup reporting!")
-		}
+	// every invalid option is reported at once, before anything is started
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
 	}
+
+	flushReporting, err := reporting.Init(ctx, "grant")
+	if err != nil {
+		logger.Panic().Err(err).Msg("unable to setup reporting!")
+	}
+	defer flushReporting()
+	shutdownTracing := tracing.Init("grant")
+
 	logger.Info().
 		Str("prefix", "main").
 		Msg("Starting server")
@@ -341,40 +554,83 @@ func GrantServer(
 	ctx = context.WithValue(ctx, appctx.BitflyerClientSecretCTXKey, viper.GetString("bitflyer-client-secret"))
 	ctx = context.WithValue(ctx, appctx.BitflyerClientIDCTXKey, viper.GetString("bitflyer-client-id"))
 
-	ctx, r, _, jobs := setupRouter(ctx, logger)
+	ctx, r, _, jobs, workerRegistry := setupRouter(ctx, logger, cfg)
 
-	ctx, cancel := context.WithCancel(ctx)
+	// requests are served with the base context, which is not cancelled on shutdown so
+	// in flight requests can finish while the job workers are stopped
+	baseCtx := ctx
+	ctx, cancel := srv.WithShutdownSignals(ctx)
 	defer cancel()
+	// job runs are only cancelled once the shutdown deadline passes, the signals stop new runs being started
+	runCtx, cancelRuns := context.WithCancel(baseCtx)
+	defer cancelRuns()
 
+	var workers sync.WaitGroup
 	if enableJobWorkers {
 		for _, job := range jobs {
 			// iterate over jobs
 			for i := 0; i < job.Workers; i++ {
 				// spin up a job worker for each worker
 				logger.Debug().Msg("starting job worker")
-				go jobWorker(ctx, job.Func, job.Cadence)
+				workers.Add(1)
+				go func(job srv.Job) {
+					defer workers.Done()
+					jobWorker(ctx, runCtx, workerRegistry, job)
+				}(job)
 			}
 		}
 	}
 
+	metricsSrv := metrics.NewServer()
 	go func() {
-		err := http.ListenAndServe(":9090", middleware.Metrics())
+		err := srv.ListenAndServe(metricsSrv)
 		if err != nil {
 			sentry.CaptureException(err)
 			logger.Panic().Err(err).Msg("metrics HTTP server start failed!")
 		}
 	}()
 
-	srv := http.Server{
+	server := &http.Server{
 		Addr:         ":3333",
-		Handler:      chi.ServerBaseContext(ctx, r),
+		Handler:      chi.ServerBaseContext(baseCtx, r),
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 20 * time.Second,
 	}
-	err = srv.ListenAndServe()
-	if err != nil {
-		sentry.CaptureException(err)
-		logger.Panic().Err(err).Msg("HTTP server start failed!")
+	go func() {
+		err := srv.ListenAndServe(server)
+		if err != nil {
+			sentry.CaptureException(err)
+			logger.Panic().Err(err).Msg("HTTP server start failed!")
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info().Msg("shutting down, draining in flight requests and jobs")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(baseCtx, cmd.ShutdownTimeout())
+	defer shutdownCancel()
+
+	// stop taking checkout requests first, the metrics server stays up until the
+	// remaining work has drained so it can still be scraped
+	if err := srv.Shutdown(shutdownCtx, server); err != nil {
+		logger.Error().Err(err).Msg("failed to drain in flight requests")
+	}
+	if !srv.Wait(shutdownCtx, &workers) {
+		logger.Error().Msg("job workers did not finish before the shutdown deadline")
+		cancelRuns()
+	}
+	// the pools are closed once the requests and jobs using them have drained
+	for name, db := range grantserver.Pools() {
+		if err := db.Close(); err != nil {
+			logger.Error().Err(err).Str("pool", name).Msg("failed to close database pool")
+		}
+	}
+	if err := srv.Shutdown(shutdownCtx, metricsSrv); err != nil {
+		logger.Error().Err(err).Msg("failed to shut down metrics server")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("failed to flush traces")
 	}
+	logger.Info().Msg("shutdown complete")
 	return nil
 }
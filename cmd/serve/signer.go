@@ -0,0 +1,125 @@
+package serve
+
+import (
+	"context"
+	"net"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/payment"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/metrics"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	sentry "github.com/getsentry/sentry-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+var (
+	// SignerServerCmd starts up the credential signer
+	SignerServerCmd = &cobra.Command{
+		Use:   "signer",
+		Short: "subcommand to start up the credential signer, serving the signing stream over gRPC",
+		Run:   cmd.Perform("signer", RunSignerServer),
+	}
+)
+
+func init() {
+	cmd.ServeCmd.AddCommand(SignerServerCmd)
+
+	flagBuilder := cmd.NewFlagBuilder(SignerServerCmd)
+
+	flagBuilder.Flag().String("signer-listen-address", ":50051",
+		"the address the signing stream is served on").
+		Bind("signer-listen-address").
+		Env("SIGNER_LISTEN_ADDRESS")
+
+	flagBuilder.Flag().String("signer-token", "",
+		"the bearer token signing streams must carry, any stream is accepted when empty").
+		Bind("signer-token").
+		Env("SIGNER_TOKEN")
+
+	flagBuilder.Flag().Int("signer-concurrency", 10,
+		"how many jobs of each signing stream are signed at once").
+		Bind("signer-concurrency").
+		Env("SIGNER_CONCURRENCY")
+}
+
+// RunSignerServer is the runner for starting up the credential signer
+func RunSignerServer(command *cobra.Command, args []string) error {
+	return SignerServer(
+		command.Context(),
+		viper.GetString("signer-listen-address"),
+		viper.GetString("signer-token"),
+		viper.GetInt("signer-concurrency"),
+	)
+}
+
+// SignerServer signs the credentials streamed to it by grant servers with the challenge bypass server,
+// until it is shut down
+func SignerServer(ctx context.Context, address string, token string, concurrency int) error {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		ctx, logger = logging.SetupLogger(ctx)
+	}
+
+	shutdownTracing := tracing.Init("signer")
+
+	signer, err := payment.NewCBRSigner()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	signerServer := payment.NewSignerServer(signer, token, concurrency)
+	signerServer.Register(server)
+
+	ctx, cancel := srv.WithShutdownSignals(ctx)
+	defer cancel()
+
+	metricsSrv := metrics.NewServer()
+	go func() {
+		err := srv.ListenAndServe(metricsSrv)
+		if err != nil {
+			sentry.CaptureException(err)
+			logger.Panic().Err(err).Msg("metrics HTTP server start failed!")
+		}
+	}()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	logger.Info().Str("address", address).Msg("signer starting up")
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Info().Msg("shutting down, finishing in flight signing jobs")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cmd.ShutdownTimeout())
+	defer shutdownCancel()
+
+	// jobs being signed are sent back before the streams are closed, later jobs are failed and retried
+	if !signerServer.Drain(shutdownCtx) {
+		logger.Error().Msg("signing jobs did not finish before the shutdown deadline")
+	}
+	server.Stop()
+
+	if err := srv.Shutdown(shutdownCtx, metricsSrv); err != nil {
+		logger.Error().Err(err).Msg("failed to shut down metrics server")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("failed to flush traces")
+	}
+	logger.Info().Msg("shutdown complete")
+	return nil
+}
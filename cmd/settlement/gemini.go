@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
@@ -58,12 +59,21 @@ func UploadGeminiSettlement(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	dryRun, err := IsDryRun(cmd)
+	if err != nil {
+		return err
+	}
+
 	if out == "" {
 		out = strings.TrimSuffix(input, filepath.Ext(input)) + "-finished.json"
 	}
+	action := "upload"
+	if dryRun {
+		action = "dryrun"
+	}
 	return GeminiUploadSettlement(
 		cmd.Context(),
-		"upload",
+		action,
 		input,
 		sig,
 		allTransactionsFile,
@@ -133,6 +143,9 @@ func init() {
 	uploadCheckStatusBuilder.Flag().Int("sig", 0,
 		"signature to choose when uploading transactions (for bulk endpoint usage)").
 		Bind("sig")
+
+	cmd.NewFlagBuilder(UploadGeminiSettlementCmd).Flag().Bool("dry-run", false,
+		"write the payouts which would be uploaded to a report instead of uploading them, or SETTLEMENT_DRY_RUN")
 }
 
 // GeminiUploadSettlement marks the settlement file as complete
@@ -148,10 +161,14 @@ func GeminiUploadSettlement(ctx context.Context, action string, inPath string, s
 	}
 
 	bulkPayoutFiles := strings.Split(inPath, ",")
-	geminiClient, err := gemini.New()
-	if err != nil {
-		logger.Error().Err(err).Msg("failed to create new gemini client")
-		return err
+	var geminiClient gemini.Client
+	// dry runs never reach gemini
+	if action != "dryrun" {
+		geminiClient, err = gemini.New()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create new gemini client")
+			return err
+		}
 	}
 
 	if allTransactionsFile == "" {
@@ -182,6 +199,22 @@ func GeminiUploadSettlement(ctx context.Context, action string, inPath string, s
 		bulkPayoutFiles,
 		transactionsMap,
 	)
+	if action == "dryrun" {
+		if submitErr != nil {
+			return submitErr
+		}
+		reportPath := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + "-dry-run.json"
+		report := settlement.NewDryRunReport("gemini", submittedTransactions["pending"], submittedTransactions["unmatched"])
+		if err := report.Write(reportPath); err != nil {
+			return fmt.Errorf("failed to write dry run report: %w", err)
+		}
+		logger.Info().
+			Int("transactions", len(report.Transactions)).
+			Int("unmatched", len(report.Skipped)).
+			Str("report", reportPath).
+			Msg("dry run, no payouts were uploaded")
+		return nil
+	}
 	// write file for upload to eyeshade
 	logger.Info().
 		Str("files", outPath).
@@ -0,0 +1,198 @@
+package settlement
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/settlement"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ed25519"
+)
+
+func init() {
+	SettlementCmd.AddCommand(PrepareSettlementCmd)
+
+	prepareBuilder := cmd.NewFlagBuilder(PrepareSettlementCmd)
+
+	// input and out are read from the command's flags, or INPUT and OUT, rather than bound to viper, where
+	// the upload commands bind the same keys
+	PrepareSettlementCmd.Flags().String("input", "",
+		"the owed balances report exported from eyeshade, or INPUT")
+
+	PrepareSettlementCmd.Flags().String("out", "./payouts.json",
+		"the location of the payout files, a file is written per custodian, or OUT")
+
+	prepareBuilder.Flag().String("threshold", settlement.DefaultPayoutThreshold.String(),
+		"the minimum owed balance, in BAT, which is paid out").
+		Bind("threshold").
+		Env("PAYOUT_THRESHOLD")
+
+	prepareBuilder.Flag().String("fee-rate", settlement.DefaultFeeRate.String(),
+		"the fraction of contributions withheld as the platform fee").
+		Bind("fee-rate").
+		Env("PAYOUT_FEE_RATE")
+
+	prepareBuilder.Flag().String("signing-key", "",
+		"the hex encoded ed25519 seed the payout files are signed with").
+		Bind("signing-key").
+		Env("SETTLEMENT_SIGNING_KEY")
+}
+
+// PrepareSettlementCmd prepares payout files from owed balances
+var PrepareSettlementCmd = &cobra.Command{
+	Use:   "prepare",
+	Short: "prepares signed payout files, split by custodian, from owed balances",
+	Run:   cmd.Perform("prepare", RunPrepareSettlement),
+}
+
+// RunPrepareSettlement prepares payout files from an owed balances report
+func RunPrepareSettlement(command *cobra.Command, args []string) error {
+	input, err := flagOrEnv(command, "input", "INPUT")
+	if err != nil {
+		return err
+	}
+	if input == "" {
+		return errors.New(`required flag(s) "input" not set, or set INPUT`)
+	}
+	out, err := flagOrEnv(command, "out", "OUT")
+	if err != nil {
+		return err
+	}
+	threshold, err := decimal.NewFromString(viper.GetString("threshold"))
+	if err != nil {
+		return fmt.Errorf("failed to parse threshold: %w", err)
+	}
+	feeRate, err := decimal.NewFromString(viper.GetString("fee-rate"))
+	if err != nil {
+		return fmt.Errorf("failed to parse fee rate: %w", err)
+	}
+	signingKey := viper.GetString("signing-key")
+	if signingKey == "" {
+		return errors.New("a signing key is required to sign payout files")
+	}
+	seed, err := hex.DecodeString(signingKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return errors.New("signing key must be a hex encoded ed25519 seed")
+	}
+
+	return PrepareSettlement(
+		command.Context(),
+		input,
+		out,
+		settlement.PayoutOptions{
+			Threshold: threshold,
+			FeeRate:   feeRate,
+		},
+		ed25519.NewKeyFromSeed(seed),
+	)
+}
+
+// flagOrEnv reads a string flag of the command, or the environment variable when the flag is not set
+func flagOrEnv(command *cobra.Command, name, env string) (string, error) {
+	value, err := command.Flags().GetString(name)
+	if err != nil || command.Flags().Changed(name) {
+		return value, err
+	}
+	if fromEnv, ok := os.LookupEnv(env); ok {
+		return fromEnv, nil
+	}
+	return value, nil
+}
+
+// PrepareSettlement reads owed balances, applies the threshold and fees and writes a signed payout
+// file per custodian. Each file is signed in a detached "<file>.sig" holding the hex encoded signature
+func PrepareSettlement(
+	ctx context.Context,
+	inPath string,
+	outPath string,
+	options settlement.PayoutOptions,
+	key ed25519.PrivateKey,
+) error {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	data, err := ioutil.ReadFile(inPath)
+	if err != nil {
+		return fmt.Errorf("failed to read owed balances: %w", err)
+	}
+	var owed []settlement.AntifraudTransaction
+	if err := json.Unmarshal(data, &owed); err != nil {
+		return fmt.Errorf("failed to parse owed balances: %w", err)
+	}
+
+	prepared, err := settlement.PreparePayouts(owed, options)
+	if err != nil {
+		return err
+	}
+
+	custodians := make([]string, 0, len(prepared.Payouts))
+	for custodian := range prepared.Payouts {
+		custodians = append(custodians, custodian)
+	}
+	sort.Strings(custodians)
+
+	for _, custodian := range custodians {
+		payouts := prepared.Payouts[custodian]
+		total := decimal.Zero
+		for _, payout := range payouts {
+			total = total.Add(payout.BAT)
+		}
+		path := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + "-" + custodian + ".json"
+		if err := writeSignedPayouts(path, payouts, key); err != nil {
+			return err
+		}
+		logger.Info().
+			Str("custodian", custodian).
+			Str("file", path).
+			Int("payouts", len(payouts)).
+			Str("total", total.String()).
+			Msg("wrote payout file")
+	}
+
+	if len(prepared.Skipped) > 0 {
+		path := strings.TrimSuffix(outPath, filepath.Ext(outPath)) + "-skipped.json"
+		data, err := json.MarshalIndent(prepared.Skipped, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal skipped balances: %w", err)
+		}
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("failed to write skipped balances: %w", err)
+		}
+		logger.Info().
+			Str("file", path).
+			Int("balances", len(prepared.Skipped)).
+			Msg("skipped balances below the threshold or without a custodian")
+	}
+	return nil
+}
+
+// writeSignedPayouts writes the payouts and a detached signature of the file contents
+func writeSignedPayouts(path string, payouts []settlement.AntifraudTransaction, key ed25519.PrivateKey) error {
+	data, err := json.MarshalIndent(payouts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal payouts: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write payouts: %w", err)
+	}
+	signature := hex.EncodeToString(ed25519.Sign(key, data))
+	if err := ioutil.WriteFile(path+".sig", []byte(signature), 0600); err != nil {
+		return fmt.Errorf("failed to write payouts signature: %w", err)
+	}
+	return nil
+}
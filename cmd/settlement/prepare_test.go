@@ -0,0 +1,66 @@
+package settlement
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/settlement"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestPrepareSettlementCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prepare")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	owed, err := json.Marshal([]settlement.AntifraudTransaction{
+		{Publisher: "brave.com", BAT: decimal.New(20, 0), Type: "contribution", WalletProvider: "uphold"},
+	})
+	require.NoError(t, err)
+	input := filepath.Join(dir, "owed.json")
+	require.NoError(t, ioutil.WriteFile(input, owed, 0600))
+	seed := make([]byte, ed25519.SeedSize)
+
+	// the upload commands bind the same flag names, which must not hide the flags given to prepare
+	require.NoError(t, os.Setenv("INPUT", filepath.Join(dir, "missing.json")))
+	defer func() { _ = os.Unsetenv("INPUT") }()
+	cmd.RootCmd.SetArgs([]string{"settlement", "prepare", "--input", input, "--out", filepath.Join(dir, "payouts.json"),
+		"--signing-key", hex.EncodeToString(seed)})
+	require.NoError(t, cmd.RootCmd.ExecuteContext(context.Background()))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "payouts-uphold.json"))
+	require.NoError(t, err)
+	var payouts []settlement.AntifraudTransaction
+	require.NoError(t, json.Unmarshal(data, &payouts))
+	require.Len(t, payouts, 1)
+	assert.Equal(t, "brave.com", payouts[0].Publisher)
+	_, err = os.Stat(filepath.Join(dir, "payouts-uphold.json.sig"))
+	assert.NoError(t, err, "each payout file is signed")
+}
+
+func TestFlagOrEnv(t *testing.T) {
+	require.NoError(t, os.Setenv("OUT", "from-env.json"))
+	defer func() { _ = os.Unsetenv("OUT") }()
+
+	command := &cobra.Command{}
+	command.Flags().String("out", "./payouts.json", "")
+
+	out, err := flagOrEnv(command, "out", "OUT")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env.json", out, "the environment applies when the flag is not set")
+
+	require.NoError(t, command.Flags().Set("out", "from-flag.json"))
+	out, err = flagOrEnv(command, "out", "OUT")
+	require.NoError(t, err)
+	assert.Equal(t, "from-flag.json", out, "the flag takes precedence")
+}
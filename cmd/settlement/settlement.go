@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/brave-intl/bat-go/cmd"
@@ -24,6 +26,19 @@ var SettlementCmd = &cobra.Command{
 	Short: "provides settlement utilities",
 }
 
+// IsDryRun reports whether the dry-run flag or SETTLEMENT_DRY_RUN is set, in which case transfers
+// are written to a report rather than submitted to the custodian
+func IsDryRun(command *cobra.Command) (bool, error) {
+	dryRun, err := command.Flags().GetBool("dry-run")
+	if err != nil || dryRun {
+		return dryRun, err
+	}
+	if env := os.Getenv("SETTLEMENT_DRY_RUN"); env != "" {
+		return strconv.ParseBool(env)
+	}
+	return false, nil
+}
+
 // WriteCategorizedTransactions write out transactions categorized under a key
 func WriteCategorizedTransactions(
 	ctx context.Context,
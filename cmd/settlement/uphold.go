@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/brave-intl/bat-go/cmd"
 	"github.com/brave-intl/bat-go/settlement"
+	"github.com/brave-intl/bat-go/utils/closers"
 	appctx "github.com/brave-intl/bat-go/utils/context"
 	"github.com/brave-intl/bat-go/utils/logging"
 	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
@@ -55,6 +57,9 @@ func init() {
 	uploadBuilder.Flag().String("progress", "1s",
 		"how often progress should be printed out").
 		Bind("progress")
+
+	uploadBuilder.Flag().Bool("dry-run", false,
+		"write the transactions which would be submitted to a report instead of submitting them, or SETTLEMENT_DRY_RUN")
 }
 
 // RunUpholdUpload the runner that the uphold upload command calls
@@ -72,6 +77,10 @@ func RunUpholdUpload(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	dryRun, err := IsDryRun(cmd)
+	if err != nil {
+		return err
+	}
 	// setup context for logging, debug and progress
 	ctx = context.WithValue(ctx, appctx.DebugLoggingCTXKey, verbose)
 
@@ -86,6 +95,9 @@ func RunUpholdUpload(cmd *cobra.Command, args []string) error {
 	logFile := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "-log.json"
 	outputFilePrefix := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
 
+	if dryRun {
+		return UpholdDryRun(ctx, inputFile, logFile, outputFilePrefix+"-dry-run.json")
+	}
 	return UpholdUpload(
 		ctx,
 		inputFile,
@@ -94,6 +106,75 @@ func RunUpholdUpload(cmd *cobra.Command, args []string) error {
 	)
 }
 
+// UpholdDryRun reads the settlement and its transaction log as UpholdUpload does, writing the
+// transactions which would be submitted to a report. Nothing is submitted and the log is not written
+func UpholdDryRun(
+	ctx context.Context,
+	inputFile string,
+	logFile string,
+	reportFile string,
+) error {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	settlementJSON, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	var settlementState settlement.State
+	if err := json.Unmarshal(settlementJSON, &settlementState); err != nil {
+		return fmt.Errorf("failed to unmarshal input file: %w", err)
+	}
+	if err := settlement.CheckForDuplicates(settlementState.Transactions); err != nil {
+		return fmt.Errorf("failed duplicate transaction check: %w", err)
+	}
+	if _, err := uphold.FromWalletInfo(ctx, settlementState.WalletInfo); err != nil {
+		return fmt.Errorf("failed to make settlement wallet: %w", err)
+	}
+
+	// progress of an earlier, interrupted, upload is taken into account
+	if f, err := os.Open(logFile); err == nil {
+		defer closers.Panic(f)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var tmp settlement.Transaction
+			if err := json.Unmarshal(scanner.Bytes(), &tmp); err != nil {
+				return fmt.Errorf("failed to scan the transaction log: %w", err)
+			}
+			for i := 0; i < len(settlementState.Transactions); i++ {
+				if settlementState.Transactions[i].Channel == tmp.Channel {
+					settlementState.Transactions[i] = tmp
+				}
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open the transaction log: %w", err)
+	}
+
+	var pending, skipped []settlement.Transaction
+	for _, tx := range settlementState.Transactions {
+		tx.SignedTx = ""
+		if tx.IsComplete() || tx.IsFailed() {
+			skipped = append(skipped, tx)
+			continue
+		}
+		pending = append(pending, tx)
+	}
+
+	report := settlement.NewDryRunReport("uphold", pending, skipped)
+	if err := report.Write(reportFile); err != nil {
+		return fmt.Errorf("failed to write dry run report: %w", err)
+	}
+	logger.Info().
+		Int("transactions", len(pending)).
+		Int("skipped", len(skipped)).
+		Str("report", reportFile).
+		Msg("dry run, no transactions were submitted")
+	return nil
+}
+
 // UpholdUpload uploads transactions to uphold
 func UpholdUpload(
 	ctx context.Context,
@@ -0,0 +1,108 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// TokensCmd is a subcommand for scoped tokens
+	TokensCmd = &cobra.Command{
+		Use:   "tokens",
+		Short: "provides scoped token management",
+	}
+	// IssueTokenCmd issues a scoped token
+	IssueTokenCmd = &cobra.Command{
+		Use:   "issue",
+		Short: "issues a scoped token signed with SCOPED_TOKEN_SECRET, printing it",
+		Run:   cmd.Perform("tokens issue", RunIssueToken),
+	}
+)
+
+func init() {
+	TokensCmd.AddCommand(IssueTokenCmd)
+	cmd.RootCmd.AddCommand(TokensCmd)
+
+	issueBuilder := cmd.NewFlagBuilder(IssueTokenCmd)
+
+	issueBuilder.Flag().String("subject", "",
+		"who the token is issued to, recorded as the actor of the requests made with it").
+		Require()
+
+	issueBuilder.Flag().String("role", appctx.RoleService,
+		"the role of the token, one of admin, service or merchant")
+
+	issueBuilder.Flag().String("merchant", "",
+		"the merchant a token of the merchant role acts for")
+
+	issueBuilder.Flag().StringSlice("scopes", []string{},
+		"the scopes granted to the token")
+
+	issueBuilder.Flag().Duration("ttl", time.Hour,
+		"how long the token is valid for")
+}
+
+// RunIssueToken issues a scoped token and prints it
+func RunIssueToken(command *cobra.Command, args []string) error {
+	flags := command.Flags()
+	subject, err := flags.GetString("subject")
+	if err != nil {
+		return err
+	}
+	role, err := flags.GetString("role")
+	if err != nil {
+		return err
+	}
+	merchant, err := flags.GetString("merchant")
+	if err != nil {
+		return err
+	}
+	scopes, err := flags.GetStringSlice("scopes")
+	if err != nil {
+		return err
+	}
+	ttl, err := flags.GetDuration("ttl")
+	if err != nil {
+		return err
+	}
+
+	switch role {
+	case appctx.RoleAdmin, appctx.RoleService:
+	case appctx.RoleMerchant:
+		if merchant == "" {
+			return errors.New("tokens of the merchant role must be issued for a merchant")
+		}
+	default:
+		return fmt.Errorf("unknown role: %s", role)
+	}
+	if ttl <= 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	tokens, err := middleware.LoadScopedTokens()
+	if err != nil {
+		return err
+	}
+	if tokens == nil {
+		return errors.New("SCOPED_TOKEN_SECRET must be set to issue scoped tokens")
+	}
+	token, err := tokens.Issue(&appctx.Principal{
+		Subject:  subject,
+		Role:     role,
+		Merchant: merchant,
+		Scopes:   scopes,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, token)
+	return err
+}
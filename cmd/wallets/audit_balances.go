@@ -0,0 +1,102 @@
+package wallets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/cmd"
+	"github.com/brave-intl/bat-go/utils/s3"
+	"github.com/brave-intl/bat-go/wallet"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// AuditBalancesCmd compares custodian balances of wallets against the ledger
+	AuditBalancesCmd = &cobra.Command{
+		Use:   "audit-balances",
+		Short: "compares custodian balances of wallets against the ledger and reports discrepancies",
+		Run:   cmd.Perform("audit balances", RunAuditBalances),
+	}
+)
+
+func init() {
+	WalletsCmd.AddCommand(AuditBalancesCmd)
+
+	auditBalancesBuilder := cmd.NewFlagBuilder(AuditBalancesCmd)
+
+	auditBalancesBuilder.Flag().String("ledger", "",
+		"the ledger balance export to compare against, a path or s3 uri").
+		Bind("ledger").
+		Env("WALLET_BALANCE_AUDIT_LEDGER")
+
+	auditBalancesBuilder.Flag().String("out", "-",
+		"where to write the discrepancy report, a path, s3 uri or - for stdout").
+		Bind("out")
+
+	auditBalancesBuilder.Flag().Int("sample", 0,
+		"audit a random sample of this many wallets, all wallets are audited when 0").
+		Bind("sample").
+		Env("WALLET_BALANCE_AUDIT_SAMPLE")
+
+	auditBalancesBuilder.Flag().String("tolerance", "0",
+		"the difference, in BAT, below which balances are considered matching").
+		Bind("tolerance")
+}
+
+// RunAuditBalances runs the audit balances command
+func RunAuditBalances(command *cobra.Command, args []string) error {
+	ctx := command.Context()
+
+	out, err := command.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	ledgerLocation := viper.GetString("ledger")
+	if ledgerLocation == "" {
+		return errors.New("a ledger balance export is required")
+	}
+	tolerance, err := decimal.NewFromString(viper.GetString("tolerance"))
+	if err != nil {
+		return fmt.Errorf("failed to parse tolerance: %w", err)
+	}
+	data, err := s3.ReadLocation(ctx, ledgerLocation)
+	if err != nil {
+		return fmt.Errorf("failed to read ledger balances: %w", err)
+	}
+	ledger, err := wallet.ParseLedgerBalances(data)
+	if err != nil {
+		return err
+	}
+
+	roDB, err := wallet.NewReadOnlyPostgres(viper.GetString("ro-datastore"), false, "wallet_ro_db")
+	if err != nil {
+		return fmt.Errorf("unable to connect to wallet db: %w", err)
+	}
+	service, err := wallet.InitService(ctx, nil, roDB)
+	if err != nil {
+		return err
+	}
+
+	report, err := service.AuditBalances(ctx, wallet.BalanceAuditOptions{
+		Sample:    viper.GetInt("sample"),
+		Tolerance: tolerance,
+		Ledger:    ledger,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if out != "-" {
+		return s3.WriteLocation(ctx, out, data, "application/json")
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
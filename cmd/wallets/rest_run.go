@@ -1,6 +1,7 @@
 package wallets
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -9,6 +10,9 @@ import (
 
 	"github.com/brave-intl/bat-go/cmd"
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/metrics"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/brave-intl/bat-go/wallet"
 	sentry "github.com/getsentry/sentry-go"
 	"github.com/go-chi/chi"
@@ -35,6 +39,14 @@ func WalletRestRun(command *cobra.Command, args []string) {
 		}()
 	}
 
+	go func() {
+		err := metrics.NewServer().ListenAndServe()
+		if err != nil {
+			sentry.CaptureException(err)
+			logger.Panic().Err(err).Msg("metrics HTTP server start failed!")
+		}
+	}()
+
 	// setup server, and run
 	srv := http.Server{
 		Addr:         viper.GetString("address"),
@@ -44,7 +56,14 @@ func WalletRestRun(command *cobra.Command, args []string) {
 	}
 
 	// make sure exceptions go to sentry
-	defer sentry.Flush(time.Second * 2)
+	flushReporting, err := reporting.Init(ctx, "wallets")
+	if err != nil {
+		logger.Panic().Err(err).Msg("unable to setup reporting!")
+	}
+	defer flushReporting()
+
+	shutdownTracing := tracing.Init("wallets")
+	defer func() { _ = shutdownTracing(context.Background()) }()
 
 	if err = srv.ListenAndServe(); err != nil {
 		sentry.CaptureException(err)
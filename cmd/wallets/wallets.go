@@ -51,6 +51,11 @@ func init() {
 		Bind("datastore").
 		Require()
 
+	walletsCmdBuilder.Flag().Bool("verify-schema", false,
+		"refuse to start unless the database schema is at the version of the code").
+		Env("VERIFY_SCHEMA").
+		Bind("verify-schema")
+
 	walletsCmdBuilder.Flag().Bool("enable-link-drain-flag", false,
 		"the in-migration flag disabling the wallets link feature").
 		Env("ENABLE_LINKING_DRAINING").
@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/brave-intl/bat-go/migrations"
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/faults"
 	"github.com/brave-intl/bat-go/utils/logging"
 	"github.com/brave-intl/bat-go/utils/metrics"
 	sentry "github.com/getsentry/sentry-go"
@@ -18,6 +21,7 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 
 	// needed for magic migration
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -39,9 +43,17 @@ var (
 		"db.r5.12xlarge": 5000,
 		"db.r5.24xlarge": 5000,
 	}
+	// ErrMigrationDirty is returned when a previous migration of the schema failed part way, which has to be
+	// fixed by hand before the schema is migrated again
+	ErrMigrationDirty = errors.New("the schema is dirty from a failed migration")
+	// ErrSchemaVersion is returned when the schema is not at the version the code expects
+	ErrSchemaVersion = errors.New("the schema is not at the expected version")
+
 	dbs = map[string]*sqlx.DB{}
+	// pools are the connection pools opened with a stats prefix, keyed by it
+	pools = map[string]*sqlx.DB{}
 	// CurrentMigrationVersion holds the default migration version
-	CurrentMigrationVersion = uint(34)
+	CurrentMigrationVersion = uint(77)
 	// MigrationTracks holds the migration version for a given track (eyeshade, promotion, wallet)
 	MigrationTracks = map[string]uint{
 		"eyeshade": 20,
@@ -52,7 +64,8 @@ var (
 type Datastore interface {
 	RawDB() *sqlx.DB
 	NewMigrate() (*migrate.Migrate, error)
-	Migrate(...uint) error
+	Migrate(ctx context.Context, targetVersion uint) error
+	MigrationStatus(ctx context.Context) (*MigrationStatus, error)
 	RollbackTxAndHandle(tx *sqlx.Tx) error
 	RollbackTx(tx *sqlx.Tx)
 }
@@ -67,79 +80,152 @@ func (pg *Postgres) RawDB() *sqlx.DB {
 	return pg.DB
 }
 
-// NewMigrate creates a Migrate instance given a Postgres instance with an active database connection
+// NewMigrate creates a Migrate instance given a Postgres instance with an active database connection. The
+// migrations are those embedded in the binary, unless DATABASE_MIGRATIONS_URL points elsewhere
 func (pg *Postgres) NewMigrate() (*migrate.Migrate, error) {
 	driver, err := postgres.WithInstance(pg.RawDB().DB, &postgres.Config{})
 	if err != nil {
 		return nil, err
 	}
 
-	dbMigrationsURL := os.Getenv("DATABASE_MIGRATIONS_URL")
-	m, err := migrate.NewWithDatabaseInstance(
-		dbMigrationsURL,
-		"postgres",
-		driver,
-	)
+	if dbMigrationsURL := os.Getenv("DATABASE_MIGRATIONS_URL"); dbMigrationsURL != "" {
+		return migrate.NewWithDatabaseInstance(dbMigrationsURL, "postgres", driver)
+	}
+	src, err := migrations.Source()
 	if err != nil {
 		return nil, err
 	}
+	return migrate.NewWithInstance("go-bindata", src, "postgres", driver)
+}
 
-	return m, err
+// MigrationStatus is the version of a schema and whether its last migration failed part way
+type MigrationStatus struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
 }
 
-// Migrate the Postgres instance
-func (pg *Postgres) Migrate(currentMigrationVersions ...uint) error {
-	ctx := context.WithValue(context.Background(), appctx.EnvironmentCTXKey, os.Getenv("ENV"))
-	_, logger := logging.SetupLogger(ctx)
+// MigrationStatus returns the version of the schema, zero if it was never migrated
+func (pg *Postgres) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	m, err := pg.NewMigrate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a new migration: %w", err)
+	}
+	v, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return &MigrationStatus{Version: v, Dirty: dirty}, nil
+}
 
-	logger.Info().Msg("attempting database migration")
+// Migrate migrates the schema up or down to the target version. A dirty schema is not migrated
+func (pg *Postgres) Migrate(ctx context.Context, targetVersion uint) error {
+	logger := migrationLogger(ctx)
 
 	m, err := pg.NewMigrate()
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create a new migration")
 		return err
 	}
-
 	v, dirty, err := m.Version()
-
-	currentMigrationVersion := CurrentMigrationVersion
-	if len(currentMigrationVersions) > 0 {
-		currentMigrationVersion = currentMigrationVersions[0]
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		logger.Error().Err(err).Msg("failed to get migration version")
+		return fmt.Errorf("failed to get migration version: %w", err)
 	}
 	subLogger := logger.With().
 		Bool("dirty", dirty).
 		Int("db_version", int(v)).
-		Uint("code_version", currentMigrationVersion).
+		Uint("target_version", targetVersion).
 		Logger()
+	if dirty {
+		subLogger.Error().Msg("migration not attempted")
+		return ErrMigrationDirty
+	}
 
-	subLogger.Info().Msg("database status")
+	subLogger.Info().Msg("attempting database migration")
+	err = m.Migrate(targetVersion)
+	if err != migrate.ErrNoChange && err != nil {
+		subLogger.Error().Err(err).Msg("migration failed")
+		return err
+	}
+	subLogger.Info().Msg("database migration finished")
+	return nil
+}
+
+// VerifySchemaVersion returns ErrSchemaVersion unless the schema of the datastore is cleanly at the version
+func VerifySchemaVersion(ctx context.Context, datastore Datastore, version uint) error {
+	status, err := datastore.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Dirty || status.Version != version {
+		return fmt.Errorf("%w: database version %d, dirty %t, code version %d",
+			ErrSchemaVersion, status.Version, status.Dirty, version)
+	}
+	return nil
+}
+
+// migrateOnBoot migrates the schema up to the code version as the service starts. A schema which is ahead,
+// as it is while a newer version is being deployed, or dirty is left as it is
+func (pg *Postgres) migrateOnBoot(ctx context.Context, codeVersion uint) error {
+	logger := migrationLogger(ctx)
 
-	if !errors.Is(err, migrate.ErrNilVersion) && err != nil {
-		subLogger.Error().Err(err).Msg("failed to get migration version")
+	status, err := pg.MigrationStatus(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get migration version")
 		sentry.CaptureMessage(err.Error())
-		return fmt.Errorf("failed to get migration version: %w", err)
+		return err
 	}
+	subLogger := logger.With().
+		Bool("dirty", status.Dirty).
+		Int("db_version", int(status.Version)).
+		Uint("code_version", codeVersion).
+		Logger()
+
+	subLogger.Info().Msg("database status")
 
-	if v > currentMigrationVersion || dirty {
-		// dont attempt to migrate if our number is less than what is on the db
-		// or if the migration is in dirty state
+	if status.Version > codeVersion || status.Dirty {
 		subLogger.Error().Msg("migration not attempted")
 
 		sentry.CaptureMessage(
 			fmt.Sprintf("migration not attempted, dirty: %t; code version: %d; db version: %d",
-				dirty, currentMigrationVersion, v))
+				status.Dirty, codeVersion, status.Version))
 		return nil
 	}
+	return pg.Migrate(ctx, codeVersion)
+}
 
-	err = m.Migrate(currentMigrationVersion)
-	if err != migrate.ErrNoChange && err != nil {
-		subLogger.Error().Err(err).Msg("migration failed")
-		return err
+// migrationLogger is the logger of the context, or a new one
+func migrationLogger(ctx context.Context) *zerolog.Logger {
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		return logger
 	}
+	ctx = context.WithValue(ctx, appctx.EnvironmentCTXKey, os.Getenv("ENV"))
+	_, logger := logging.SetupLogger(ctx)
+	return logger
+}
 
-	subLogger.Info().Msg("database migration finished")
+// Pools returns the connection pools opened with a stats prefix, keyed by it
+func Pools() map[string]*sqlx.DB {
+	out := make(map[string]*sqlx.DB, len(pools))
+	for name, db := range pools {
+		out[name] = db
+	}
+	return out
+}
 
-	return nil
+// withApplicationName sets the application_name of a connection url or key/value connection string,
+// unless it already has one
+func withApplicationName(databaseURL, name string) string {
+	if strings.Contains(databaseURL, "application_name") {
+		return databaseURL
+	}
+	if u, err := url.Parse(databaseURL); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("application_name", name)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(databaseURL + " application_name=" + name)
 }
 
 // NewPostgres creates a new Postgres Datastore
@@ -160,12 +246,28 @@ func NewPostgres(
 		return &Postgres{dbs[key]}, nil
 	}
 
-	db, err := sqlx.Open("postgres", databaseURL)
+	// name the connections after their pool, so database side activity can be attributed to it
+	if dbStatsPref != "" {
+		databaseURL = withApplicationName(databaseURL, dbStatsPref)
+	}
+
+	var (
+		db  *sqlx.DB
+		err error
+	)
+	if faults.Enabled() {
+		db, err = faults.OpenPostgres(databaseURL)
+	} else {
+		db, err = sqlx.Open("postgres", databaseURL)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	dbs[key] = db
+	if dbStatsPref != "" {
+		pools[dbStatsPref] = db
+	}
 
 	// setup instrumentation using sqlstats
 	if len(dbStatsPrefix) > 0 {
@@ -211,7 +313,7 @@ func NewPostgres(
 		if migrationVersion == 0 {
 			migrationVersion = CurrentMigrationVersion
 		}
-		err = pg.Migrate(migrationVersion)
+		err = pg.migrateOnBoot(context.Background(), migrationVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,99 @@
+package grantserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// DefaultTxRetryPolicy retries a transaction three times, after up to 25ms, 50ms and 100ms
+	DefaultTxRetryPolicy = TxRetryPolicy{Attempts: 4, Backoff: 25 * time.Millisecond, MaxBackoff: time.Second}
+
+	txRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "datastore_tx_retries_total",
+			Help: "Transactions retried after a serialization failure or deadlock, by transaction",
+		},
+		[]string{"tx"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(txRetries)
+}
+
+// IsRetriableTxError is whether a transaction failed with a serialization failure or deadlock, after which
+// it was rolled back and can be run again
+func IsRetriableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
+
+// TxRetryPolicy is how a transaction is retried. Attempts includes the first, and Backoff is the delay
+// before the first retry, doubling with each retry after up to MaxBackoff, with jitter
+type TxRetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// delay before the retry following the attempt, jittered between half and all of the backoff so
+// transactions which conflicted do not conflict again
+func (p TxRetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.Backoff << uint(attempt-1)
+	if backoff > p.MaxBackoff || backoff <= 0 {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// WithTx runs fn within a transaction of the database, which fn commits and which is rolled back otherwise,
+// running it again in a new transaction while it fails with a serialization failure or deadlock. The name
+// labels the retries of the transaction
+func (p TxRetryPolicy) WithTx(ctx context.Context, pg *Postgres, name string, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := pg.runTx(ctx, opts, fn)
+		if err == nil || !IsRetriableTxError(err) || attempt >= p.Attempts {
+			return err
+		}
+
+		txRetries.WithLabelValues(name).Inc()
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.delay(attempt)):
+		}
+	}
+}
+
+func (pg *Postgres) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	tx, err := pg.RawDB().BeginTxx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer pg.RollbackTx(tx)
+	return fn(tx)
+}
+
+// WithTxRetry runs fn within a transaction with the default retry policy, see TxRetryPolicy.WithTx. fn may
+// run several times, so it must have no effects outside the transaction
+func (pg *Postgres) WithTxRetry(ctx context.Context, name string, fn func(tx *sqlx.Tx) error) error {
+	return DefaultTxRetryPolicy.WithTx(ctx, pg, name, nil, fn)
+}
@@ -0,0 +1,70 @@
+package grantserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTxRetry(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}
+	policy := TxRetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	update := func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`UPDATE orders SET status = 'paid'`); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// a serialization failure, at an update or at commit, runs the transaction again
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, policy.WithTx(context.Background(), pg, "test", nil, update))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// until it runs out of attempts
+	for i := 0; i < policy.Attempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders").WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+	}
+	err = policy.WithTx(context.Background(), pg, "test", nil, update)
+	assert.True(t, IsRetriableTxError(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// other errors are returned as they are
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE orders").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+	err = policy.WithTx(context.Background(), pg, "test", nil, update)
+	assert.Error(t, err)
+	assert.False(t, IsRetriableTxError(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsRetriableTxError(t *testing.T) {
+	assert.True(t, IsRetriableTxError(&pq.Error{Code: "40001"}))
+	assert.True(t, IsRetriableTxError(&pq.Error{Code: "40P01"}))
+	assert.True(t, IsRetriableTxError(fmt.Errorf("failed to update order: %w", &pq.Error{Code: "40001"})))
+	assert.False(t, IsRetriableTxError(&pq.Error{Code: "23505"}))
+	assert.False(t, IsRetriableTxError(errors.New("connection refused")))
+}
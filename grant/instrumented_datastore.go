@@ -7,8 +7,10 @@ package grant
 //go:generate gowrap gen -p github.com/brave-intl/bat-go/grant -i Datastore -t ../.prom-gowrap.tmpl -o instrumented_datastore.go
 
 import (
+	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
@@ -55,7 +57,7 @@ func (_d DatastoreWithPrometheus) GetGrantsOrderedByExpiry(wallet walletutils.In
 }
 
 // Migrate implements Datastore
-func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d DatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
 	_since := time.Now()
 	defer func() {
 		result := "ok"
@@ -65,7 +67,21 @@ func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements Datastore
+func (_d DatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements Datastore
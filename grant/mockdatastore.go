@@ -5,6 +5,8 @@
 package grant
 
 import (
+	context "context"
+	grantserver "github.com/brave-intl/bat-go/datastore/grantserver"
 	wallet "github.com/brave-intl/bat-go/utils/wallet"
 	v4 "github.com/golang-migrate/migrate/v4"
 	gomock "github.com/golang/mock/gomock"
@@ -65,21 +67,32 @@ func (mr *MockDatastoreMockRecorder) NewMigrate() *gomock.Call {
 }
 
 // Migrate mocks base method
-func (m *MockDatastore) Migrate(arg0 ...uint) error {
+func (m *MockDatastore) Migrate(ctx context.Context, targetVersion uint) error {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
-	for _, a := range arg0 {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "Migrate", varargs...)
+	ret := m.ctrl.Call(m, "Migrate", ctx, targetVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Migrate indicates an expected call of Migrate
-func (mr *MockDatastoreMockRecorder) Migrate(arg0 ...interface{}) *gomock.Call {
+func (mr *MockDatastoreMockRecorder) Migrate(ctx, targetVersion interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockDatastore)(nil).Migrate), arg0...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockDatastore)(nil).Migrate), ctx, targetVersion)
+}
+
+// MigrationStatus mocks base method
+func (m *MockDatastore) MigrationStatus(ctx context.Context) (*grantserver.MigrationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus", ctx)
+	ret0, _ := ret[0].(*grantserver.MigrationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus
+func (mr *MockDatastoreMockRecorder) MigrationStatus(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockDatastore)(nil).MigrationStatus), ctx)
 }
 
 // RollbackTxAndHandle mocks base method
@@ -176,21 +189,32 @@ func (mr *MockReadOnlyDatastoreMockRecorder) NewMigrate() *gomock.Call {
 }
 
 // Migrate mocks base method
-func (m *MockReadOnlyDatastore) Migrate(arg0 ...uint) error {
+func (m *MockReadOnlyDatastore) Migrate(ctx context.Context, targetVersion uint) error {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
-	for _, a := range arg0 {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "Migrate", varargs...)
+	ret := m.ctrl.Call(m, "Migrate", ctx, targetVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Migrate indicates an expected call of Migrate
-func (mr *MockReadOnlyDatastoreMockRecorder) Migrate(arg0 ...interface{}) *gomock.Call {
+func (mr *MockReadOnlyDatastoreMockRecorder) Migrate(ctx, targetVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockReadOnlyDatastore)(nil).Migrate), ctx, targetVersion)
+}
+
+// MigrationStatus mocks base method
+func (m *MockReadOnlyDatastore) MigrationStatus(ctx context.Context) (*grantserver.MigrationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus", ctx)
+	ret0, _ := ret[0].(*grantserver.MigrationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus
+func (mr *MockReadOnlyDatastoreMockRecorder) MigrationStatus(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockReadOnlyDatastore)(nil).Migrate), arg0...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockReadOnlyDatastore)(nil).MigrationStatus), ctx)
 }
 
 // RollbackTxAndHandle mocks base method
@@ -0,0 +1,228 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+)
+
+var (
+	// ErrUnknownScenario is the error when a mix weights a scenario which does not exist
+	ErrUnknownScenario = errors.New("unknown scenario")
+	// ErrEmptyMix is the error when no scenario has a weight
+	ErrEmptyMix = errors.New("mix has no weighted scenario")
+)
+
+// Scenario is a kind of traffic, a single run of which is timed as one request
+type Scenario struct {
+	Name string
+	Run  func(ctx context.Context, client *clients.SimpleHTTPClient) error
+}
+
+// Config of a load test
+type Config struct {
+	// Rate is the number of scenarios started per second
+	Rate float64
+	// Duration is how long scenarios are started for
+	Duration time.Duration
+	// Concurrency is the most scenarios running at once, further starts are dropped
+	Concurrency int
+	// Mix is the relative weight each scenario is started with, keyed by name
+	Mix map[string]int
+}
+
+// ParseMix parses a mix of the form orders=5,credentials=3,votes=2
+func ParseMix(mix string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, part := range strings.Split(mix, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid mix entry %q, expected name=weight", part)
+		}
+		weight, err := strconv.Atoi(kv[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", kv[0], kv[1])
+		}
+		weights[kv[0]] = weight
+	}
+	return weights, nil
+}
+
+// ScenarioReport is the outcome of the runs of a scenario
+type ScenarioReport struct {
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+	Dropped  int    `json:"dropped"`
+	P50      string `json:"p50"`
+	P90      string `json:"p90"`
+	P99      string `json:"p99"`
+	Max      string `json:"max"`
+	// LastError is the most recent error, to tell failures apart from load
+	LastError string `json:"lastError,omitempty"`
+
+	latencies []time.Duration
+}
+
+// Report is the outcome of a load test, keyed by scenario
+type Report struct {
+	Started   time.Time                  `json:"started"`
+	Elapsed   string                     `json:"elapsed"`
+	Scenarios map[string]*ScenarioReport `json:"scenarios"`
+}
+
+// Run starts scenarios against the client at the configured rate and mix, waiting for those
+// running to finish once the duration has passed
+func Run(ctx context.Context, client *clients.SimpleHTTPClient, config Config, scenarios []Scenario) (*Report, error) {
+	weighted, total, err := weigh(config.Mix, scenarios)
+	if err != nil {
+		return nil, err
+	}
+	if config.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+
+	report := &Report{
+		Started:   time.Now().UTC(),
+		Scenarios: map[string]*ScenarioReport{},
+	}
+	for _, s := range weighted {
+		report.Scenarios[s.Name] = &ScenarioReport{}
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, config.Concurrency)
+		ticker  = time.NewTicker(time.Duration(float64(time.Second) / config.Rate))
+		timeout = time.After(config.Duration)
+	)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-timeout:
+			break loop
+		case <-ticker.C:
+		}
+
+		scenario := pick(weighted, total)
+		select {
+		case slots <- struct{}{}:
+		default:
+			// the target is slower than the rate allows for, which the report should show
+			mu.Lock()
+			report.Scenarios[scenario.Name].Dropped++
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			err := scenario.Run(ctx, client)
+			latency := time.Since(start)
+
+			mu.Lock()
+			defer mu.Unlock()
+			sr := report.Scenarios[scenario.Name]
+			sr.Requests++
+			sr.latencies = append(sr.latencies, latency)
+			if err != nil {
+				sr.Errors++
+				sr.LastError = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(report.Started).String()
+	for _, sr := range report.Scenarios {
+		sr.summarize()
+	}
+	return report, nil
+}
+
+type weightedScenario struct {
+	Scenario
+	weight int
+}
+
+func weigh(mix map[string]int, scenarios []Scenario) ([]weightedScenario, int, error) {
+	byName := map[string]Scenario{}
+	for _, s := range scenarios {
+		byName[s.Name] = s
+	}
+
+	var (
+		weighted []weightedScenario
+		total    int
+	)
+	for name, weight := range mix {
+		s, ok := byName[name]
+		if !ok {
+			return nil, 0, fmt.Errorf("%s: %w", name, ErrUnknownScenario)
+		}
+		if weight == 0 {
+			continue
+		}
+		weighted = append(weighted, weightedScenario{Scenario: s, weight: weight})
+		total += weight
+	}
+	if total == 0 {
+		return nil, 0, ErrEmptyMix
+	}
+	// a fixed order so the same seed picks the same scenarios
+	sort.Slice(weighted, func(i, j int) bool { return weighted[i].Name < weighted[j].Name })
+	return weighted, total, nil
+}
+
+func pick(weighted []weightedScenario, total int) Scenario {
+	n := rand.Intn(total)
+	for _, s := range weighted {
+		if n < s.weight {
+			return s.Scenario
+		}
+		n -= s.weight
+	}
+	return weighted[len(weighted)-1].Scenario
+}
+
+func (sr *ScenarioReport) summarize() {
+	if len(sr.latencies) == 0 {
+		return
+	}
+	sort.Slice(sr.latencies, func(i, j int) bool { return sr.latencies[i] < sr.latencies[j] })
+	sr.P50 = percentile(sr.latencies, 50).String()
+	sr.P90 = percentile(sr.latencies, 90).String()
+	sr.P99 = percentile(sr.latencies, 99).String()
+	sr.Max = sr.latencies[len(sr.latencies)-1].String()
+}
+
+// percentile of sorted latencies, by the nearest rank
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/utils/clients"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("orders=5, votes=2,credentials=0")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"orders": 5, "votes": 2, "credentials": 0}, mix)
+
+	_, err = ParseMix("orders")
+	assert.Error(t, err)
+	_, err = ParseMix("orders=-1")
+	assert.Error(t, err)
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, time.Millisecond, percentile(latencies[:1], 50))
+}
+
+func TestRun(t *testing.T) {
+	client, err := clients.New("http://localhost", "")
+	require.NoError(t, err)
+
+	scenarios := []Scenario{
+		{Name: "ok", Run: func(ctx context.Context, client *clients.SimpleHTTPClient) error { return nil }},
+		{Name: "fail", Run: func(ctx context.Context, client *clients.SimpleHTTPClient) error { return errors.New("failed") }},
+	}
+
+	_, err = Run(context.Background(), client, Config{Rate: 1, Mix: map[string]int{"other": 1}}, scenarios)
+	assert.True(t, errors.Is(err, ErrUnknownScenario))
+	_, err = Run(context.Background(), client, Config{Rate: 1, Mix: map[string]int{"ok": 0}}, scenarios)
+	assert.Equal(t, ErrEmptyMix, err)
+
+	report, err := Run(context.Background(), client, Config{
+		Rate:        200,
+		Duration:    100 * time.Millisecond,
+		Concurrency: 10,
+		Mix:         map[string]int{"ok": 1, "fail": 1},
+	}, scenarios)
+	require.NoError(t, err)
+
+	ok, fail := report.Scenarios["ok"], report.Scenarios["fail"]
+	assert.True(t, ok.Requests+fail.Requests > 0)
+	assert.Equal(t, 0, ok.Errors)
+	assert.Equal(t, fail.Requests, fail.Errors)
+	if ok.Requests > 0 {
+		assert.NotEmpty(t, ok.P99)
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		orderID  = uuid.NewV4()
+		itemID   = uuid.NewV4()
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/v1/orders":
+			var req payment.CreateOrderRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "sku-token", req.Items[0].SKU)
+			assert.NoError(t, json.NewEncoder(w).Encode(payment.Order{
+				ID:    orderID,
+				Items: []payment.OrderItem{{ID: itemID, OrderID: orderID}},
+			}))
+		case strings.HasSuffix(r.URL.Path, "/credentials"):
+			var req payment.CreateOrderCredsRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, itemID, req.ItemID)
+			assert.Len(t, req.BlindedCreds, 2)
+		case r.URL.Path == "/v1/votes":
+			var req payment.VoteRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Len(t, req.Credentials, 3)
+		}
+	}))
+	defer server.Close()
+
+	client, err := clients.New(server.URL, "")
+	require.NoError(t, err)
+	payloads := &Payloads{SKU: "sku-token", Credentials: 2, VotePublicKey: "key", VoteBatch: 3, Channel: "brave.com"}
+	ctx := context.Background()
+	for _, s := range payloads.Scenarios() {
+		assert.NoError(t, s.Run(ctx, client), s.Name)
+	}
+
+	assert.Equal(t, 2, requests["POST /v1/orders"], "an order is created for signing when none is configured")
+	assert.Equal(t, 1, requests["POST /v1/orders/"+orderID.String()+"/credentials"])
+	assert.Equal(t, 1, requests["POST /v1/votes"])
+}
@@ -0,0 +1,164 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+)
+
+// Payloads configures the requests the scenarios make, which use the payment service's own
+// request types so they cannot drift from what clients send
+type Payloads struct {
+	// SKU is the sku token orders are created for
+	SKU string
+	// OrderID is a paid order credentials are signed for. When empty an order is created for
+	// each signing, which only succeeds when the sku is free
+	OrderID string
+	// Credentials is the number of blinded credentials in each signing request
+	Credentials int
+	// VotePublicKey is the public key of the issuer vote credentials are bound to
+	VotePublicKey string
+	// VoteBatch is the number of credentials in each vote
+	VoteBatch int
+	// Channel is the publisher voted for, which should never be paid out
+	Channel string
+
+	mu   sync.Mutex
+	item *payment.OrderItem
+}
+
+// Scenarios returns the order creation, credential signing and vote scenarios
+func (p *Payloads) Scenarios() []Scenario {
+	return []Scenario{
+		{Name: "orders", Run: p.createOrder},
+		{Name: "credentials", Run: p.signCredentials},
+		{Name: "votes", Run: p.vote},
+	}
+}
+
+func (p *Payloads) postOrder(ctx context.Context, client *clients.SimpleHTTPClient) (*payment.Order, error) {
+	body := payment.CreateOrderRequest{
+		Items: []payment.OrderItemRequest{{SKU: p.SKU, Quantity: 1}},
+	}
+	req, err := client.NewRequest(ctx, "POST", "/v1/orders", body, nil)
+	if err != nil {
+		return nil, err
+	}
+	var order payment.Order
+	if _, err := client.Do(ctx, req, &order); err != nil {
+		return nil, err
+	}
+	if len(order.Items) == 0 {
+		return nil, fmt.Errorf("order %s has no items", order.ID)
+	}
+	return &order, nil
+}
+
+func (p *Payloads) createOrder(ctx context.Context, client *clients.SimpleHTTPClient) error {
+	_, err := p.postOrder(ctx, client)
+	return err
+}
+
+// orderItem returns the configured order's item, fetching it once, or that of a new order
+func (p *Payloads) orderItem(ctx context.Context, client *clients.SimpleHTTPClient) (*payment.OrderItem, error) {
+	if p.OrderID == "" {
+		order, err := p.postOrder(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		return &order.Items[0], nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.item != nil {
+		return p.item, nil
+	}
+	req, err := client.NewRequest(ctx, "GET", "/v1/orders/"+p.OrderID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var order payment.Order
+	if _, err := client.Do(ctx, req, &order); err != nil {
+		return nil, err
+	}
+	if len(order.Items) == 0 {
+		return nil, fmt.Errorf("order %s has no items", order.ID)
+	}
+	p.item = &order.Items[0]
+	return p.item, nil
+}
+
+func (p *Payloads) signCredentials(ctx context.Context, client *clients.SimpleHTTPClient) error {
+	item, err := p.orderItem(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	body := payment.CreateOrderCredsRequest{ItemID: item.ID}
+	for i := 0; i < p.Credentials; i++ {
+		token, err := ristretto.NewToken()
+		if err != nil {
+			return err
+		}
+		blinded, err := token.Blind()
+		if err != nil {
+			return err
+		}
+		body.BlindedCreds = append(body.BlindedCreds, blinded)
+	}
+
+	req, err := client.NewRequest(ctx, "POST", "/v1/orders/"+item.OrderID.String()+"/credentials", body, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, nil)
+	return err
+}
+
+func (p *Payloads) vote(ctx context.Context, client *clients.SimpleHTTPClient) error {
+	vote, err := json.Marshal(payment.Vote{Type: "auto-contribute", Channel: p.Channel})
+	if err != nil {
+		return err
+	}
+
+	body := payment.VoteRequest{Vote: base64.StdEncoding.EncodeToString(vote)}
+	for i := 0; i < p.VoteBatch; i++ {
+		// credentials are only redeemed when the vote is drained, so well formed ones are accepted
+		preimage, err := randomBase64(ristretto.TokenPreimageLength)
+		if err != nil {
+			return err
+		}
+		signature, err := randomBase64(64)
+		if err != nil {
+			return err
+		}
+		body.Credentials = append(body.Credentials, payment.CredentialBinding{
+			PublicKey:     p.VotePublicKey,
+			TokenPreimage: preimage,
+			Signature:     signature,
+		})
+	}
+
+	req, err := client.NewRequest(ctx, "POST", "/v1/votes", body, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, nil)
+	return err
+}
+
+func randomBase64(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -16,6 +16,18 @@ import (
 	_ "github.com/brave-intl/bat-go/cmd/serve"
 	// pull in macaroon module. setup code is in init
 	_ "github.com/brave-intl/bat-go/cmd/macaroon"
+	// pull in kafka module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/kafka"
+	// pull in merchant module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/merchant"
+	// pull in tokens module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/tokens"
+	// pull in loadtest module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/loadtest"
+	// pull in backup module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/backup"
+	// pull in migrate module. setup code is in init
+	_ "github.com/brave-intl/bat-go/cmd/migrate"
 )
 
 var (
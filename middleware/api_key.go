@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+)
+
+type apiKeyCTXKey struct{}
+
+// APIKey is an authenticated merchant api key
+type APIKey struct {
+	ID       string
+	Merchant string
+	Scopes   []string
+}
+
+// HasScope checks if the api key was granted a scope
+func (key *APIKey) HasScope(scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyAuthenticator provides a way to authenticate the token of an api key
+type APIKeyAuthenticator interface {
+	// AuthenticateAPIKey returns the api key the token belongs to, or nil if the token is not valid
+	AuthenticateAPIKey(ctx context.Context, token string) (*APIKey, error)
+}
+
+// Principal is the merchant principal an api key authenticates as
+func (key *APIKey) Principal() *appctx.Principal {
+	return &appctx.Principal{
+		Subject:  key.ID,
+		Role:     appctx.RoleMerchant,
+		Merchant: key.Merchant,
+		Scopes:   key.Scopes,
+	}
+}
+
+// AddAPIKey - Helpful for test cases. The key's principal is added to the context as well
+func AddAPIKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = context.WithValue(ctx, apiKeyCTXKey{}, key)
+	ctx = appctx.WithPrincipal(ctx, key.Principal())
+	logging.AddMerchantIDToContext(ctx, key.Merchant)
+	return AddKeyID(ctx, key.ID)
+}
+
+// GetAPIKey retrieves the authenticated api key from the context
+func GetAPIKey(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCTXKey{}).(*APIKey)
+	return key, ok
+}
+
+// APIKeyAuthorized is a middleware that restricts access to requests with a bearer token which is
+// either one of the simple tokens or an api key granted the scope. The api key, or the principal of the
+// simple token, is added to the context. Requests whose principal was already authenticated, such as by
+// Authenticate, are only checked for the scope
+// NOTE the token is populated via BearerToken
+func APIKeyAuthorized(auth APIKeyAuthenticator, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if principal, err := appctx.GetPrincipal(ctx); err == nil {
+				if !principal.HasScope(scope) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if isSimpleTokenInContext(ctx) {
+				next.ServeHTTP(w, r.WithContext(appctx.WithPrincipal(ctx, simpleTokenPrincipal(ctx))))
+				return
+			}
+
+			token, _ := ctx.Value(bearerTokenKey{}).(string)
+			if token == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			key, err := auth.AuthenticateAPIKey(ctx, token)
+			if err != nil {
+				logger, lerr := appctx.GetLogger(ctx)
+				if lerr != nil {
+					_, logger = logging.SetupLogger(ctx)
+				}
+				logger.Error().Err(err).Msg("failed to authenticate api key")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if key == nil || !key.HasScope(scope) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			setAuditActor(ctx, "api_key:"+key.ID)
+			next.ServeHTTP(w, r.WithContext(AddAPIKey(ctx, key)))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAPIKeyAuthenticator map[string]*APIKey
+
+func (m mockAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, token string) (*APIKey, error) {
+	return m[token], nil
+}
+
+func TestAPIKeyAuthorized(t *testing.T) {
+	oldTokenList := TokenList
+	defer func() {
+		TokenList = oldTokenList
+	}()
+	TokenList = []string{"simple"}
+
+	auth := mockAPIKeyAuthenticator{
+		"reader": {ID: "1", Merchant: "brave.com", Scopes: []string{"transactions:read"}},
+	}
+	var seen *APIKey
+	handler := BearerToken(APIKeyAuthorized(auth, "transactions:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = GetAPIKey(r.Context())
+	})))
+	noScope := BearerToken(APIKeyAuthorized(auth, "keys:manage")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	serve := func(h http.Handler, token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(handler, "simple"), "simple tokens should be accepted")
+	assert.Nil(t, seen)
+	assert.Equal(t, http.StatusOK, serve(handler, "reader"), "keys granted the scope should be accepted")
+	assert.Equal(t, "1", seen.ID, "the api key should be added to the context")
+	assert.Equal(t, http.StatusForbidden, serve(noScope, "reader"), "keys without the scope should be rejected")
+	assert.Equal(t, http.StatusForbidden, serve(handler, "unknown"), "unknown tokens should be rejected")
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, ""), "requests without a token should be rejected")
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	chiware "github.com/go-chi/chi/middleware"
+)
+
+type auditEventCTXKey struct{}
+
+// AuditEvent is the record of an authenticated mutating request
+type AuditEvent struct {
+	ID        string            `json:"id" db:"id"`
+	CreatedAt time.Time         `json:"createdAt" db:"created_at"`
+	Actor     string            `json:"actor" db:"actor"`
+	Method    string            `json:"method" db:"method"`
+	Route     string            `json:"route" db:"route"`
+	Path      string            `json:"path" db:"path"`
+	Entities  map[string]string `json:"entities" db:"-"`
+	Status    int               `json:"status" db:"status"`
+	RequestID string            `json:"requestId" db:"request_id"`
+}
+
+// auditRecord holds the event of an in flight request while handlers add to it
+type auditRecord struct {
+	mu    sync.Mutex
+	event AuditEvent
+}
+
+// AuditStore records audit events
+type AuditStore interface {
+	// InsertAuditEvent appends the event to the audit log
+	InsertAuditEvent(ctx context.Context, event *AuditEvent) error
+}
+
+// AuditEntity records the id of an entity a request acted upon, handlers call this once
+// they know which order, key, etc. was touched. It is a no-op outside of audited requests
+func AuditEntity(ctx context.Context, kind string, id string) {
+	record, ok := ctx.Value(auditEventCTXKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	record.event.Entities[kind] = id
+}
+
+// setAuditActor records who made an audited request once they are authenticated
+func setAuditActor(ctx context.Context, actor string) {
+	record, ok := ctx.Value(auditEventCTXKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	record.event.Actor = actor
+}
+
+// AuditActor returns who made the audited request, empty outside of audited requests or before they are authenticated
+func AuditActor(ctx context.Context) string {
+	record, ok := ctx.Value(auditEventCTXKey{}).(*auditRecord)
+	if !ok {
+		return ""
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	return record.event.Actor
+}
+
+// simpleTokenActor identifies a simple token without recording the token itself
+func simpleTokenActor(ctx context.Context) string {
+	token, _ := ctx.Value(bearerTokenKey{}).(string)
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])[:12]
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// AuditLog is a middleware that records every authenticated mutating request, with the actor,
+// route, entities touched and response status, to the audit store. Unauthenticated requests
+// are not recorded
+func AuditLog(store AuditStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			record := &auditRecord{event: AuditEvent{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Entities:  map[string]string{},
+				RequestID: requestutils.GetRequestID(ctx),
+			}}
+			if isSimpleTokenInContext(ctx) || isAdminTokenInContext(ctx) {
+				record.event.Actor = simpleTokenActor(ctx)
+			}
+
+			ww := chiware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(ctx, auditEventCTXKey{}, record)))
+
+			record.mu.Lock()
+			defer record.mu.Unlock()
+			event := &record.event
+			if event.Actor == "" {
+				return
+			}
+			event.Status = ww.Status()
+			if event.Status == 0 {
+				event.Status = http.StatusOK
+			}
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				event.Route = rctx.RoutePattern()
+			}
+
+			if err := store.InsertAuditEvent(ctx, event); err != nil {
+				logger, lerr := appctx.GetLogger(ctx)
+				if lerr != nil {
+					_, logger = logging.SetupLogger(ctx)
+				}
+				logger.Error().Err(err).Str("actor", event.Actor).Str("path", event.Path).
+					Msg("failed to record audit event")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAuditStore struct {
+	events []*AuditEvent
+}
+
+func (m *mockAuditStore) InsertAuditEvent(ctx context.Context, event *AuditEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestAuditLog(t *testing.T) {
+	oldTokenList := TokenList
+	defer func() {
+		TokenList = oldTokenList
+	}()
+	TokenList = []string{"simple"}
+
+	store := &mockAuditStore{}
+	r := chi.NewRouter()
+	r.Use(BearerToken)
+	r.Use(AuditLog(store))
+	r.Method("DELETE", "/keys/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AuditEntity(r.Context(), "key", chi.URLParam(r, "id"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	r.Method("GET", "/keys/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, path, token string) {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("DELETE", "/keys/1", "")
+	assert.Len(t, store.events, 0, "unauthenticated requests should not be audited")
+	serve("GET", "/keys/1", "simple")
+	assert.Len(t, store.events, 0, "read only requests should not be audited")
+
+	serve("DELETE", "/keys/1", "simple")
+	if assert.Len(t, store.events, 1) {
+		event := store.events[0]
+		assert.Contains(t, event.Actor, "token:")
+		assert.NotContains(t, event.Actor, "simple", "the token itself should not be recorded")
+		assert.Equal(t, "/keys/{id}", event.Route)
+		assert.Equal(t, "/keys/1", event.Path)
+		assert.Equal(t, map[string]string{"key": "1"}, event.Entities)
+		assert.Equal(t, http.StatusAccepted, event.Status)
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// scopedTokenIssuer is the issuer of scoped tokens, tokens of other issuers are not accepted
+const scopedTokenIssuer = "bat-go"
+
+// minScopedTokenSecretLength is the shortest secret scoped tokens are signed with
+const minScopedTokenSecretLength = 32
+
+// PrincipalAuthenticator authenticates bearer tokens as principals
+type PrincipalAuthenticator interface {
+	// AuthenticatePrincipal returns the principal the token belongs to, or nil if the token is not valid
+	AuthenticatePrincipal(ctx context.Context, token string) (*appctx.Principal, error)
+}
+
+// scopedTokenClaims are the claims of a scoped token, the subject being the principal's
+type scopedTokenClaims struct {
+	jwt.Claims
+	Role     string   `json:"role"`
+	Merchant string   `json:"merchant,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// ScopedTokens issues and authenticates scoped tokens, short lived JWTs signed with a shared secret which
+// carry the role and scopes of their principal
+type ScopedTokens struct {
+	secret []byte
+}
+
+// NewScopedTokens creates scoped tokens signed with the secret
+func NewScopedTokens(secret []byte) (*ScopedTokens, error) {
+	if len(secret) < minScopedTokenSecretLength {
+		return nil, fmt.Errorf("scoped token secret must be at least %d bytes", minScopedTokenSecretLength)
+	}
+	return &ScopedTokens{secret: secret}, nil
+}
+
+// LoadScopedTokens creates scoped tokens signed with the SCOPED_TOKEN_SECRET environment variable, nil when it
+// is not set
+func LoadScopedTokens() (*ScopedTokens, error) {
+	secret := os.Getenv("SCOPED_TOKEN_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	return NewScopedTokens([]byte(secret))
+}
+
+// Issue a scoped token granting the principal its role and scopes until the ttl has passed
+func (t *ScopedTokens) Issue(principal *appctx.Principal, ttl time.Duration) (string, error) {
+	if principal.AllScopes {
+		return "", errors.New("scoped tokens are only issued for explicit scopes")
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: t.secret}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create signer: %w", err)
+	}
+	now := time.Now()
+	claims := scopedTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   scopedTokenIssuer,
+			Subject:  principal.Subject,
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Role:     principal.Role,
+		Merchant: principal.Merchant,
+		Scopes:   principal.Scopes,
+	}
+	return jwt.Signed(signer).Claims(claims).CompactSerialize()
+}
+
+// AuthenticatePrincipal returns the principal of a scoped token, nil if the token is not a scoped token, was
+// not signed with the secret or has expired. Tokens without an expiry are not accepted
+func (t *ScopedTokens) AuthenticatePrincipal(ctx context.Context, token string) (*appctx.Principal, error) {
+	if t == nil {
+		return nil, nil
+	}
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		// not a jwt, such as one of the simple tokens
+		return nil, nil
+	}
+	var claims scopedTokenClaims
+	if err := parsed.Claims(t.secret, &claims); err != nil {
+		return nil, nil
+	}
+	if claims.Expiry == nil || claims.Subject == "" {
+		return nil, nil
+	}
+	if err := claims.Validate(jwt.Expected{Issuer: scopedTokenIssuer, Time: time.Now()}); err != nil {
+		return nil, nil
+	}
+	switch claims.Role {
+	case appctx.RoleAdmin, appctx.RoleService:
+	case appctx.RoleMerchant:
+		if claims.Merchant == "" {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	return &appctx.Principal{
+		Subject:  claims.Subject,
+		Role:     claims.Role,
+		Merchant: claims.Merchant,
+		Scopes:   claims.Scopes,
+	}, nil
+}
+
+// simpleTokenPrincipal is the principal of the admin or simple token of the context, nil if it has neither.
+// Their holders are granted every scope
+func simpleTokenPrincipal(ctx context.Context) *appctx.Principal {
+	switch {
+	case isAdminTokenInContext(ctx):
+		return &appctx.Principal{Subject: simpleTokenActor(ctx), Role: appctx.RoleAdmin, AllScopes: true}
+	case isSimpleTokenInContext(ctx):
+		return &appctx.Principal{Subject: simpleTokenActor(ctx), Role: appctx.RoleService, AllScopes: true}
+	}
+	return nil
+}
+
+// Authenticate is a middleware that adds the principal the bearer token belongs to to the context. Admin tokens
+// are authenticated as admins and simple tokens as services, other tokens by the first of the authenticators
+// they belong to. Requests which are not authenticated are passed on without a principal, for RequireScopes
+// and RequireRoles to reject
+// NOTE the token is populated via BearerToken
+func Authenticate(authenticators ...PrincipalAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			token := GetBearerToken(ctx)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal := simpleTokenPrincipal(ctx)
+			for i := 0; principal == nil && i < len(authenticators); i++ {
+				var err error
+				principal, err = authenticators[i].AuthenticatePrincipal(ctx, token)
+				if err != nil {
+					logger, lerr := appctx.GetLogger(ctx)
+					if lerr != nil {
+						_, logger = logging.SetupLogger(ctx)
+					}
+					logger.Error().Err(err).Msg("failed to authenticate principal")
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				if principal != nil {
+					setAuditActor(ctx, principal.Role+":"+principal.Subject)
+				}
+			}
+			if principal == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(appctx.WithPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// rejectUnauthenticated responds to a request without a principal, unauthorized when it has no bearer token
+// and forbidden when its token was not recognized
+func rejectUnauthenticated(w http.ResponseWriter, r *http.Request) {
+	if GetBearerToken(r.Context()) == "" {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+// RequireScopes is a middleware that restricts access to principals granted every one of the scopes
+// NOTE the principal is populated via Authenticate
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := appctx.GetPrincipal(r.Context())
+			if err != nil {
+				rejectUnauthenticated(w, r)
+				return
+			}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRoles is a middleware that restricts access to principals with one of the roles
+// NOTE the principal is populated via Authenticate
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := appctx.GetPrincipal(r.Context())
+			if err != nil {
+				rejectUnauthenticated(w, r)
+				return
+			}
+			if !principal.HasRole(roles...) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopedTokens(t *testing.T) {
+	_, err := NewScopedTokens([]byte("short"))
+	assert.Error(t, err, "secrets must be long enough")
+
+	tokens, err := NewScopedTokens([]byte(strings.Repeat("s", 32)))
+	require.NoError(t, err)
+	other, err := NewScopedTokens([]byte(strings.Repeat("o", 32)))
+	require.NoError(t, err)
+
+	issued, err := tokens.Issue(&appctx.Principal{Subject: "payouts", Role: appctx.RoleService, Scopes: []string{"audit:read"}}, time.Minute)
+	require.NoError(t, err)
+	principal, err := tokens.AuthenticatePrincipal(context.Background(), issued)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+	assert.Equal(t, "payouts", principal.Subject)
+	assert.Equal(t, appctx.RoleService, principal.Role)
+	assert.True(t, principal.HasScope("audit:read"))
+	assert.False(t, principal.HasScope("keys:manage"))
+
+	principal, err = other.AuthenticatePrincipal(context.Background(), issued)
+	require.NoError(t, err)
+	assert.Nil(t, principal, "tokens signed with another secret are not accepted")
+
+	expired, err := tokens.Issue(&appctx.Principal{Subject: "payouts", Role: appctx.RoleService}, -time.Minute)
+	require.NoError(t, err)
+	principal, err = tokens.AuthenticatePrincipal(context.Background(), expired)
+	require.NoError(t, err)
+	assert.Nil(t, principal, "expired tokens are not accepted")
+
+	merchant, err := tokens.Issue(&appctx.Principal{Subject: "dashboard", Role: appctx.RoleMerchant}, time.Minute)
+	require.NoError(t, err)
+	principal, err = tokens.AuthenticatePrincipal(context.Background(), merchant)
+	require.NoError(t, err)
+	assert.Nil(t, principal, "merchant tokens must name their merchant")
+
+	_, err = tokens.Issue(&appctx.Principal{Subject: "all", Role: appctx.RoleAdmin, AllScopes: true}, time.Minute)
+	assert.Error(t, err)
+
+	var unset *ScopedTokens
+	principal, err = unset.AuthenticatePrincipal(context.Background(), issued)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+}
+
+func TestRequireScopes(t *testing.T) {
+	oldTokenList, oldAdminTokenList := TokenList, AdminTokenList
+	defer func() {
+		TokenList, AdminTokenList = oldTokenList, oldAdminTokenList
+	}()
+	TokenList = []string{"simple"}
+	AdminTokenList = []string{"admin"}
+
+	tokens, err := NewScopedTokens([]byte(strings.Repeat("s", 32)))
+	require.NoError(t, err)
+	reader, err := tokens.Issue(&appctx.Principal{Subject: "auditor", Role: appctx.RoleService, Scopes: []string{"audit:read"}}, time.Minute)
+	require.NoError(t, err)
+	writer, err := tokens.Issue(&appctx.Principal{Subject: "dashboard", Role: appctx.RoleMerchant, Merchant: "brave.com",
+		Scopes: []string{"orders:read"}}, time.Minute)
+	require.NoError(t, err)
+
+	var seen *appctx.Principal
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = appctx.GetPrincipal(r.Context())
+	})
+	scoped := BearerToken(Authenticate(tokens)(RequireScopes("audit:read")(ok)))
+	admins := BearerToken(Authenticate(tokens)(RequireRoles(appctx.RoleAdmin)(ok)))
+
+	serve := func(h http.Handler, token string) int {
+		seen = nil
+		req := httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(scoped, reader), "tokens granted the scope should be accepted")
+	assert.Equal(t, "auditor", seen.Subject, "the principal should be added to the context")
+	assert.Equal(t, http.StatusForbidden, serve(scoped, writer), "tokens without the scope should be rejected")
+	assert.Equal(t, http.StatusOK, serve(scoped, "simple"), "simple tokens are granted every scope")
+	assert.Equal(t, appctx.RoleService, seen.Role)
+	assert.Equal(t, http.StatusForbidden, serve(scoped, "unknown"), "unknown tokens should be rejected")
+	assert.Equal(t, http.StatusUnauthorized, serve(scoped, ""), "requests without a token should be rejected")
+
+	assert.Equal(t, http.StatusOK, serve(admins, "admin"))
+	assert.Equal(t, appctx.RoleAdmin, seen.Role)
+	assert.Equal(t, http.StatusForbidden, serve(admins, "simple"), "simple tokens are not admin tokens")
+	assert.Equal(t, http.StatusForbidden, serve(admins, reader))
+}
+
+func TestAPIKeyPrincipal(t *testing.T) {
+	ctx := AddAPIKey(context.Background(), &APIKey{ID: "1", Merchant: "brave.com", Scopes: []string{"orders:read"}})
+	principal, err := appctx.GetPrincipal(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, appctx.RoleMerchant, principal.Role)
+	assert.Equal(t, "brave.com", principal.Merchant)
+	assert.True(t, principal.HasScope("orders:read"))
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 // NewServiceCtx passes a service into the context
@@ -16,3 +19,30 @@ func NewServiceCtx(service interface{}) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// NewAppCtx guarantees the appctx accessors succeed for every request: requests without an id are given
+// one, and requests without a logger are given the logger, the global logger when nil, with their id.
+// A non nil datastore replaces the one of the context, so each service's routes see their own
+func NewAppCtx(datastore interface{}, logger *zerolog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = &log.Logger
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			reqID, err := appctx.RequestID(ctx)
+			if err != nil {
+				reqID = newRequestID()
+				ctx = requestutils.WithRequestID(ctx, reqID)
+			}
+			if _, err := appctx.Logger(ctx); err != nil {
+				l := logger.With().Str("req_id", reqID).Logger()
+				ctx = l.WithContext(ctx)
+			}
+			if datastore != nil {
+				ctx = appctx.WithDatastore(ctx, datastore)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAppCtx(t *testing.T) {
+	var b bytes.Buffer
+	logger := zerolog.New(&b)
+	datastore := &bytes.Buffer{}
+
+	_, err := appctx.RequestID(context.Background())
+	assert.True(t, errors.Is(err, appctx.ErrNotInContext), "there is no request id outside of requests")
+	_, err = appctx.Logger(context.Background())
+	assert.True(t, errors.Is(err, appctx.ErrNotInContext))
+
+	handler := NewAppCtx(datastore, &logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := appctx.RequestID(r.Context())
+		require.NoError(t, err)
+		assert.NotEmpty(t, reqID, "requests without an id are given one")
+
+		l, err := appctx.Logger(r.Context())
+		require.NoError(t, err)
+		l.Info().Msg("handled")
+
+		var writer io.Writer
+		require.NoError(t, appctx.Datastore(r.Context(), &writer))
+		assert.Equal(t, datastore, writer)
+
+		var reader io.ByteScanner
+		require.NoError(t, appctx.Datastore(r.Context(), &reader), "the datastore is set as any interface it implements")
+		var closer io.Closer
+		err = appctx.Datastore(r.Context(), &closer)
+		assert.True(t, errors.Is(err, appctx.ErrValueWrongType), "the datastore is not set as an interface it does not implement")
+		assert.Contains(t, err.Error(), "*bytes.Buffer")
+		err = appctx.ReadOnlyDatastore(r.Context(), &writer)
+		assert.True(t, errors.Is(err, appctx.ErrNotInContext))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	assert.Contains(t, b.String(), `"req_id"`, "the logger is scoped to the request")
+	assert.Contains(t, b.String(), "handled")
+}
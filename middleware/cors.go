@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/cors"
+)
+
+var (
+	// defaultCORSOrigins holds the allowed origins used when ALLOWED_ORIGINS is not set
+	defaultCORSOrigins = map[string][]string{
+		"local": {"*"},
+	}
+	// defaultCORSMaxAge is the maximum value not ignored by any of major browsers
+	defaultCORSMaxAge = 300
+)
+
+// CORSConfig holds the cross origin policy applied to a group of routes
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is the number of seconds a preflight response can be cached
+	MaxAge int
+	Debug  bool
+}
+
+// corsEnvKey - generate the route specific environment variable name for a setting,
+// e.g. corsEnvKey("claim-summary", "ALLOWED_ORIGINS") is CORS_CLAIM_SUMMARY_ALLOWED_ORIGINS
+func corsEnvKey(route, setting string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(route))
+	return "CORS_" + name + "_" + setting
+}
+
+// lookupCORSEnv - get a route specific setting, falling back to the global one
+func lookupCORSEnv(route, setting, global string) string {
+	if route != "" {
+		if v := os.Getenv(corsEnvKey(route, setting)); v != "" {
+			return v
+		}
+	}
+	return os.Getenv(global)
+}
+
+func splitCORSList(v string) []string {
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// NewCORSConfig creates the cors configuration for a route from the environment.
+// Route specific overrides (CORS_<ROUTE>_ALLOWED_ORIGINS, CORS_<ROUTE>_MAX_AGE) take precedence
+// over the global ALLOWED_ORIGINS and CORS_MAX_AGE, which in turn fall back to per environment defaults.
+// Environments without default origins allow none unless they are configured
+func NewCORSConfig(route string, allowedMethods ...string) CORSConfig {
+	debug, err := strconv.ParseBool(os.Getenv("DEBUG"))
+	if err != nil {
+		debug = false
+	}
+
+	origins := splitCORSList(lookupCORSEnv(route, "ALLOWED_ORIGINS", "ALLOWED_ORIGINS"))
+	if len(origins) == 0 {
+		origins = defaultCORSOrigins[os.Getenv("ENV")]
+	}
+
+	maxAge, err := strconv.Atoi(lookupCORSEnv(route, "MAX_AGE", "CORS_MAX_AGE"))
+	if err != nil || maxAge < 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	return CORSConfig{
+		Debug:            debug,
+		AllowedOrigins:   origins,
+		AllowedMethods:   allowedMethods,
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", IdempotencyKeyHeader},
+		ExposedHeaders:   splitCORSList(lookupCORSEnv(route, "EXPOSED_HEADERS", "CORS_EXPOSED_HEADERS")),
+		AllowCredentials: false,
+		MaxAge:           maxAge,
+	}
+}
+
+// NewPublicCORSConfig creates the cors configuration for public read only endpoints such as stats,
+// any origin is allowed unless the route is overridden in the environment
+func NewPublicCORSConfig(route string) CORSConfig {
+	config := NewCORSConfig(route, "GET")
+	if os.Getenv(corsEnvKey(route, "ALLOWED_ORIGINS")) == "" {
+		config.AllowedOrigins = []string{"*"}
+	}
+	return config
+}
+
+// denyAllOrigins rejects every origin, for configurations which allow none
+func denyAllOrigins(r *http.Request, origin string) bool {
+	return false
+}
+
+// CORS is a middleware that applies the passed cors configuration, answering preflight requests. No origin is
+// allowed when the configuration allows none, rather than any as go-chi/cors treats an empty list
+func CORS(config CORSConfig) func(next http.Handler) http.Handler {
+	var allowOriginFunc func(r *http.Request, origin string) bool
+	if len(config.AllowedOrigins) == 0 {
+		allowOriginFunc = denyAllOrigins
+	}
+	return cors.Handler(cors.Options{
+		AllowOriginFunc:  allowOriginFunc,
+		Debug:            config.Debug,
+		AllowedOrigins:   config.AllowedOrigins,
+		AllowedMethods:   config.AllowedMethods,
+		AllowedHeaders:   config.AllowedHeaders,
+		ExposedHeaders:   config.ExposedHeaders,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           config.MaxAge,
+	})
+}
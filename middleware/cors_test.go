@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCORSConfig(t *testing.T) {
+	defer os.Unsetenv("ALLOWED_ORIGINS")
+	defer os.Unsetenv("CORS_ORDERS_ALLOWED_ORIGINS")
+	defer os.Unsetenv("CORS_MAX_AGE")
+	defer os.Setenv("ENV", os.Getenv("ENV"))
+
+	os.Setenv("ENV", "local")
+	config := NewCORSConfig("orders", "GET")
+	assert.Equal(t, []string{"*"}, config.AllowedOrigins, "local environment should default to any origin")
+	assert.Equal(t, defaultCORSMaxAge, config.MaxAge)
+
+	os.Setenv("ENV", "production")
+	config = NewCORSConfig("orders", "GET")
+	assert.Empty(t, config.AllowedOrigins, "production should not default to any origin")
+	assertCORSOrigin(t, CORS(config), "https://a.example.com", "", "origins are denied when none are configured")
+
+	os.Setenv("ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+	os.Setenv("CORS_MAX_AGE", "60")
+	config = NewCORSConfig("orders", "GET")
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, config.AllowedOrigins)
+	assert.Equal(t, 60, config.MaxAge)
+
+	os.Setenv("CORS_ORDERS_ALLOWED_ORIGINS", "https://orders.example.com")
+	config = NewCORSConfig("orders", "GET")
+	assert.Equal(t, []string{"https://orders.example.com"}, config.AllowedOrigins, "route override should win")
+
+	config = NewPublicCORSConfig("claim-summary")
+	assert.Equal(t, []string{"*"}, config.AllowedOrigins, "public routes allow any origin")
+}
+
+// assertCORSOrigin checks the origin a preflight request from origin is allowed for
+func assertCORSOrigin(t *testing.T, middleware func(http.Handler) http.Handler, origin, allowed, msg string) {
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, allowed, rr.Header().Get("Access-Control-Allow-Origin"), msg)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := CORS(CORSConfig{
+		AllowedOrigins: []string{"https://a.example.com"},
+		AllowedMethods: []string{"GET"},
+		MaxAge:         120,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://a.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "https://a.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "120", rr.Header().Get("Access-Control-Max-Age"))
+
+	req = httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Origin"))
+}
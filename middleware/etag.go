@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+)
+
+// etagMatches checks an If-None-Match header against the etag of the current representation,
+// using the weak comparison required for If-None-Match
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ETag is a middleware which adds a strong etag, derived from the response body, and the cache
+// control to successful GET responses. Requests whose If-None-Match matches the current etag get
+// a 304 without a body. Since the etag covers the whole body it changes with any field, for orders
+// this is their status, items and updated at time
+func ETag(cacheControl string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffered := &bufferedResponseWriter{header: http.Header{}}
+			next.ServeHTTP(buffered, r)
+
+			for k, v := range buffered.header {
+				w.Header()[k] = v
+			}
+			if buffered.status == 0 {
+				buffered.status = http.StatusOK
+			}
+
+			if buffered.status == http.StatusOK {
+				sum := sha256.Sum256(buffered.body.Bytes())
+				etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Cache-Control", cacheControl)
+				w.Header().Add("Vary", "Accept")
+
+				if etagMatches(r.Header.Get("If-None-Match"), etag) {
+					w.Header().Del("Content-Type")
+					w.Header().Del("Content-Length")
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+
+			w.WriteHeader(buffered.status)
+			if _, err := w.Write(buffered.body.Bytes()); err != nil {
+				logger, lerr := appctx.GetLogger(r.Context())
+				if lerr != nil {
+					_, logger = logging.SetupLogger(r.Context())
+				}
+				logger.Error().Err(err).Msg("failed to write response")
+			}
+		})
+	}
+}
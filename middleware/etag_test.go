@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	status := "pending"
+	handler := ETag("private, no-cache")(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return handlers.RenderContent(r.Context(), map[string]string{"status": status}, w, http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "private, no-cache", rr.Header().Get("Cache-Control"))
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// unchanged responses are not resent
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+
+	// changed responses get a new etag
+	status = "paid"
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"paid"}`, rr.Body.String())
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+}
+
+func TestETagSkipsErrors(t *testing.T) {
+	handler := ETag("private, no-cache")(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return &handlers.AppError{Message: "not found", Code: http.StatusNotFound}
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Header().Get("ETag"))
+}
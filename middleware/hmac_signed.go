@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+)
+
+const (
+	// HMACSignatureHeader carries the hex encoded HMAC-SHA256 signature of a request
+	HMACSignatureHeader = "X-Signature"
+	// HMACTimestampHeader carries the unix time in seconds a request was signed at
+	HMACTimestampHeader = "X-Signature-Timestamp"
+)
+
+// HMACKeystore provides a way to lookup the secret an api key signs its requests with
+type HMACKeystore interface {
+	// LookupHMACSecret returns the signing secret of the api key, or nil if it has none
+	LookupHMACSecret(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// HMACSigningString is what a request is signed over, its method, path with query, timestamp and the
+// hex encoded sha256 of its body, separated by newlines
+func HMACSigningString(method string, path string, timestamp string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{method, path, timestamp, hex.EncodeToString(digest[:])}, "\n")
+}
+
+// SignHMAC returns the hex encoded signature of a request made with the secret
+func SignHMAC(secret []byte, method string, path string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(HMACSigningString(method, path, timestamp, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HMACSignedOnly is a middleware that requires requests authenticated by an api key to also be signed with
+// the key's secret, rejecting requests signed more than the ttl from now. When nonces is set a signature can
+// only be used once, so signed requests cannot be replayed. Requests authorized by a simple token, or by a
+// token whose principal was verified such as a scoped token, have no secret to sign with and are passed
+// through, otherwise the api key must already be in the context
+// NOTE the api key is populated via APIKeyAuthorized, and the principal via Authenticate
+func HMACSignedOnly(ks HMACKeystore, ttl time.Duration, nonces NonceStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if isSimpleTokenInContext(ctx) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key, ok := GetAPIKey(ctx)
+			if !ok {
+				if _, err := appctx.GetPrincipal(ctx); err == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			timestamp := r.Header.Get(HMACTimestampHeader)
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			if skew := time.Since(time.Unix(seconds, 0)); skew > ttl || skew < -ttl {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			signature, err := hex.DecodeString(r.Header.Get(HMACSignatureHeader))
+			if err != nil || len(signature) != sha256.Size {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			body, err := requestutils.Read(r.Body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			secret, err := ks.LookupHMACSecret(ctx, key.ID)
+			if err != nil {
+				logger, lerr := appctx.GetLogger(ctx)
+				if lerr != nil {
+					_, logger = logging.SetupLogger(ctx)
+				}
+				logger.Error().Err(err).Str("key_id", key.ID).Msg("failed to lookup api key secret")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			expected, _ := hex.DecodeString(SignHMAC(secret, r.Method, r.URL.RequestURI(), timestamp, body))
+			if secret == nil || !hmac.Equal(signature, expected) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			if nonces != nil {
+				// requests are accepted while signed within the ttl either side of now, so signatures are kept for twice as long
+				fresh, err := nonces.UseNonce(ctx, "hmac:"+key.ID+":"+hex.EncodeToString(signature), 2*ttl)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				if !fresh {
+					http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
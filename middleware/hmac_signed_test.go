@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockHMACKeystore map[string][]byte
+
+func (m mockHMACKeystore) LookupHMACSecret(ctx context.Context, keyID string) ([]byte, error) {
+	return m[keyID], nil
+}
+
+func TestHMACSignedOnly(t *testing.T) {
+	secret := []byte("secret")
+	var body string
+	handler := HMACSignedOnly(mockHMACKeystore{"1": secret}, time.Minute, NewMemoryNonceStore())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+		}))
+
+	serve := func(keyID string, timestamp time.Time, signature func(timestamp string) string) int {
+		req := httptest.NewRequest("POST", "/v1/merchants/brave.com/orders/1/cancel?reason=dup", strings.NewReader(`{"a":1}`))
+		if keyID != "" {
+			req = req.WithContext(AddAPIKey(req.Context(), &APIKey{ID: keyID, Merchant: "brave.com"}))
+		}
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req.Header.Set(HMACTimestampHeader, ts)
+		req.Header.Set(HMACSignatureHeader, signature(ts))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	signedWith := func(secret []byte, path string, body string) func(string) string {
+		return func(ts string) string {
+			return SignHMAC(secret, "POST", path, ts, []byte(body))
+		}
+	}
+	path := "/v1/merchants/brave.com/orders/1/cancel?reason=dup"
+
+	assert.Equal(t, http.StatusUnauthorized, serve("", time.Now(), signedWith(secret, path, `{"a":1}`)), "requests need an api key")
+	assert.Equal(t, http.StatusBadRequest, serve("1", time.Now(), func(string) string { return "nothex" }))
+	assert.Equal(t, http.StatusForbidden, serve("1", time.Now().Add(-2*time.Minute), signedWith(secret, path, `{"a":1}`)),
+		"requests signed outside the ttl are rejected")
+	assert.Equal(t, http.StatusForbidden, serve("1", time.Now(), signedWith([]byte("wrong"), path, `{"a":1}`)))
+	assert.Equal(t, http.StatusForbidden, serve("1", time.Now(), signedWith(secret, "/v1/merchants/brave.com/orders/1/cancel", `{"a":1}`)),
+		"the query is signed")
+	assert.Equal(t, http.StatusForbidden, serve("1", time.Now(), signedWith(secret, path, `{"a":2}`)), "the body is signed")
+	assert.Equal(t, http.StatusForbidden, serve("2", time.Now(), signedWith(secret, path, `{"a":1}`)), "keys without a secret are rejected")
+
+	signedAt := time.Now()
+	assert.Equal(t, http.StatusOK, serve("1", signedAt, signedWith(secret, path, `{"a":1}`)))
+	assert.Equal(t, `{"a":1}`, body, "the body is passed on")
+	assert.Equal(t, http.StatusConflict, serve("1", signedAt, signedWith(secret, path, `{"a":1}`)), "signed requests cannot be replayed")
+
+	// scoped tokens are verified on their own, their principal has no secret to sign with
+	req := httptest.NewRequest("POST", path, strings.NewReader(`{"a":3}`))
+	req = req.WithContext(appctx.WithPrincipal(req.Context(), &appctx.Principal{Subject: "ops", Role: appctx.RoleMerchant, Merchant: "brave.com"}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"a":3}`, body)
+}
@@ -5,6 +5,7 @@ import (
 	"crypto"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/brave-intl/bat-go/utils/httpsignature"
 )
@@ -31,8 +32,55 @@ func GetKeyID(ctx context.Context) (string, error) {
 	return keyID, nil
 }
 
+// HTTPSignedOption configures how signed requests to a route are verified
+type HTTPSignedOption func(*httpSignedConfig)
+
+type httpSignedConfig struct {
+	nonces   NonceStore
+	nonceTTL time.Duration
+}
+
+// RequireNonce requires requests to also sign their date and a nonce, rejecting requests dated more than
+// the ttl from now and nonces the key has already used, so signed requests cannot be replayed
+func RequireNonce(store NonceStore, ttl time.Duration) HTTPSignedOption {
+	return func(c *httpSignedConfig) {
+		c.nonces = store
+		c.nonceTTL = ttl
+	}
+}
+
+// RequireNonceOn requires nonces on the route once it is rolled out to, see NonceRequired, until then
+// signed requests to it are accepted without one
+func RequireNonceOn(route string, store NonceStore, ttl time.Duration) HTTPSignedOption {
+	if !NonceRequired(route) {
+		return func(c *httpSignedConfig) {}
+	}
+	return RequireNonce(store, ttl)
+}
+
+// checkFreshness validates the date and nonce headers of a request which must carry a nonce
+func (c *httpSignedConfig) checkFreshness(r *http.Request) int {
+	nonce := r.Header.Get(NonceHeader)
+	if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return http.StatusBadRequest
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	if skew := time.Since(date); skew > c.nonceTTL || skew < -c.nonceTTL {
+		return http.StatusForbidden
+	}
+	return 0
+}
+
 // HTTPSignedOnly is a middleware that requires an HTTP request to be signed
-func HTTPSignedOnly(ks Keystore) func(http.Handler) http.Handler {
+func HTTPSignedOnly(ks Keystore, opts ...HTTPSignedOption) func(http.Handler) http.Handler {
+	var config httpSignedConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var s httpsignature.Signature
@@ -45,6 +93,13 @@ func HTTPSignedOnly(ks Keystore) func(http.Handler) http.Handler {
 			// Override algorithm and headers to those we want to enforce
 			s.Algorithm = httpsignature.ED25519
 			s.Headers = []string{"digest", "(request-target)"}
+			if config.nonces != nil {
+				if status := config.checkFreshness(r); status != 0 {
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+				s.Headers = append(s.Headers, "date", "nonce")
+			}
 
 			ctx := context.WithValue(r.Context(), httpSignedKeyID{}, s.KeyID)
 			pubKey, err := ks.LookupPublicKey(ctx, s.KeyID)
@@ -69,6 +124,19 @@ func HTTPSignedOnly(ks Keystore) func(http.Handler) http.Handler {
 				return
 			}
 
+			if config.nonces != nil {
+				// requests are accepted while dated within the ttl either side of now, so nonces are kept for twice as long
+				fresh, err := config.nonces.UseNonce(ctx, s.KeyID+":"+r.Header.Get(NonceHeader), 2*config.nonceTTL)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				if !fresh {
+					http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+					return
+				}
+			}
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/brave-intl/bat-go/utils/httpsignature"
 	"github.com/stretchr/testify/assert"
@@ -104,3 +106,60 @@ func TestHTTPSignedOnly(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code, "request with signature from right key should succeed")
 }
+
+func TestHTTPSignedOnlyRequireNonce(t *testing.T) {
+	publicKey, privKey, err := httpsignature.GenerateEd25519Key(nil)
+	assert.NoError(t, err)
+
+	keystore := mockKeystore{publicKey}
+	handler := HTTPSignedOnly(&keystore, RequireNonce(NewMemoryNonceStore(), time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var s httpsignature.Signature
+	s.Algorithm = httpsignature.ED25519
+	s.KeyID = "primary"
+
+	serve := func(date time.Time, nonce string, headers []string) int {
+		req, err := http.NewRequest("POST", "/claim", bytes.NewBufferString("{}"))
+		assert.NoError(t, err)
+		req.Header.Set("Date", date.UTC().Format(http.TimeFormat))
+		req.Header.Set(NonceHeader, nonce)
+		s.Headers = headers
+		assert.NoError(t, s.Sign(privKey, crypto.Hash(0), req))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	headers := []string{"digest", "(request-target)", "date", "nonce"}
+
+	assert.Equal(t, http.StatusOK, serve(time.Now(), "0123456789abcdef", headers))
+	assert.Equal(t, http.StatusConflict, serve(time.Now(), "0123456789abcdef", headers), "replayed requests should fail")
+	assert.Equal(t, http.StatusOK, serve(time.Now(), "fedcba9876543210", headers))
+	assert.Equal(t, http.StatusBadRequest, serve(time.Now(), "short", headers), "short nonces should fail")
+	assert.Equal(t, http.StatusForbidden, serve(time.Now().Add(-2*time.Minute), "0000111122223333", headers), "stale requests should fail")
+	assert.Equal(t, http.StatusForbidden, serve(time.Now(), "4444555566667777", []string{"digest", "(request-target)"}),
+		"requests which do not sign the nonce should fail")
+}
+
+func TestHTTPSignedOnlyRequireNonceOn(t *testing.T) {
+	publicKey, privKey, err := httpsignature.GenerateEd25519Key(nil)
+	assert.NoError(t, err)
+	keystore := mockKeystore{publicKey}
+	serve := func(route string) int {
+		handler := HTTPSignedOnly(&keystore, RequireNonceOn(route, NewMemoryNonceStore(), time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req, err := http.NewRequest("POST", "/claim", bytes.NewBufferString("{}"))
+		assert.NoError(t, err)
+		s := httpsignature.Signature{}
+		s.Algorithm = httpsignature.ED25519
+		s.KeyID = "primary"
+		s.Headers = []string{"digest", "(request-target)"}
+		assert.NoError(t, s.Sign(privKey, crypto.Hash(0), req))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.NoError(t, os.Setenv("REQUIRE_NONCE_ROUTES", "ClaimPromotion"))
+	defer func() { _ = os.Unsetenv("REQUIRE_NONCE_ROUTES") }()
+	assert.Equal(t, http.StatusOK, serve("DrainSuggestion"), "routes not rolled out to accept requests without nonces")
+	assert.Equal(t, http.StatusBadRequest, serve("ClaimPromotion"))
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key a client retries a request with, so it only takes effect once
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a key which was already used
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotentRequest is a request made with an idempotency key, and its response once there is one
+type IdempotentRequest struct {
+	// Scope is the path the key was used on, keys are unique per path
+	Scope string `db:"scope"`
+	Key   string `db:"key"`
+	// RequestHash identifies the request's method, path and body, a key can't be reused for another request
+	RequestHash string `db:"request_hash"`
+	// Status is nil until the response is stored
+	Status    *int        `db:"status"`
+	Header    http.Header `db:"-"`
+	Body      []byte      `db:"body"`
+	CreatedAt time.Time   `db:"created_at"`
+}
+
+// IdempotencyStore persists idempotency keys along with the responses to their requests
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey records the request under its key unless the key was used within the ttl, returning
+	// the request the key was first used with and whether it was reserved for this request
+	ReserveIdempotencyKey(ctx context.Context, request *IdempotentRequest, ttl time.Duration) (*IdempotentRequest, bool, error)
+	// CompleteIdempotencyKey stores the response to the request the key was reserved for
+	CompleteIdempotencyKey(ctx context.Context, request *IdempotentRequest) error
+	// ReleaseIdempotencyKey forgets a key whose request failed, so it can be retried
+	ReleaseIdempotencyKey(ctx context.Context, scope string, key string) error
+}
+
+// idempotentRequestHash identifies a request by its method, path and body
+func idempotentRequestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Idempotent is a middleware which lets clients safely retry requests made with an Idempotency-Key header.
+// The first response to a key, unless it is a server error, is stored for the ttl and replayed to every
+// retry. Reusing a key for a different request, or while its first request is in flight, is a conflict.
+// Requests without the header are passed through
+func Idempotent(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			logger, err := appctx.GetLogger(r.Context())
+			if err != nil {
+				_, logger = logging.SetupLogger(r.Context())
+			}
+
+			body, err := requestutils.Read(r.Body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			request := &IdempotentRequest{
+				Scope:       r.URL.Path,
+				Key:         key,
+				RequestHash: idempotentRequestHash(r, body),
+			}
+			existing, reserved, err := store.ReserveIdempotencyKey(r.Context(), request, ttl)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to reserve idempotency key")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if !reserved {
+				// the key was used for another request, or its request has not finished
+				if existing.RequestHash != request.RequestHash || existing.Status == nil {
+					http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+					return
+				}
+				for k, v := range existing.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(*existing.Status)
+				_, _ = w.Write(existing.Body)
+				return
+			}
+
+			buffered := &bufferedResponseWriter{header: http.Header{}}
+			next.ServeHTTP(buffered, r)
+			if buffered.status == 0 {
+				buffered.status = http.StatusOK
+			}
+
+			// server errors may not happen again, so the key is released for the client to retry
+			if buffered.status >= http.StatusInternalServerError {
+				if err := store.ReleaseIdempotencyKey(r.Context(), request.Scope, request.Key); err != nil {
+					logger.Error().Err(err).Msg("failed to release idempotency key")
+				}
+			} else {
+				request.Status = &buffered.status
+				request.Header = buffered.header
+				request.Body = buffered.body.Bytes()
+				if err := store.CompleteIdempotencyKey(r.Context(), request); err != nil {
+					logger.Error().Err(err).Msg("failed to store idempotent response")
+				}
+			}
+
+			for k, v := range buffered.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(buffered.status)
+			if _, err := w.Write(buffered.body.Bytes()); err != nil {
+				logger.Error().Err(err).Msg("failed to write response")
+			}
+		})
+	}
+}
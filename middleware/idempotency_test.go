@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyStore keeps idempotency keys in memory, ignoring their ttl
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]IdempotentRequest
+}
+
+func (s *memoryIdempotencyStore) ReserveIdempotencyKey(ctx context.Context, request *IdempotentRequest, ttl time.Duration) (*IdempotentRequest, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.requests[request.Scope+request.Key]; ok {
+		return &existing, false, nil
+	}
+	s.requests[request.Scope+request.Key] = *request
+	return request, true, nil
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, request *IdempotentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[request.Scope+request.Key] = *request
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, scope string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.requests, scope+key)
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	store := &memoryIdempotencyStore{requests: map[string]IdempotentRequest{}}
+	calls := 0
+	status := http.StatusCreated
+	handler := Idempotent(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `,"body":` + string(body) + `}`))
+	}))
+
+	request := func(key string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/v1/orders", strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	first := request("order-1", `{"sku":"vpn"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, `{"call":1,"body":{"sku":"vpn"}}`, first.Body.String())
+
+	// retries are answered with the first response without running the handler again
+	retry := request("order-1", `{"sku":"vpn"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, http.StatusConflict, request("order-1", `{"sku":"brave-talk"}`).Code,
+		"a key can't be reused for a different request")
+
+	// a request without a key is not idempotent
+	assert.Equal(t, http.StatusCreated, request("", `{"sku":"vpn"}`).Code)
+	assert.Equal(t, 2, calls)
+
+	// server errors release the key so the request can be retried
+	status = http.StatusInternalServerError
+	assert.Equal(t, http.StatusInternalServerError, request("order-2", `{}`).Code)
+	status = http.StatusCreated
+	assert.Equal(t, http.StatusCreated, request("order-2", `{}`).Code)
+	assert.Equal(t, 4, calls)
+
+	// a key whose request has not finished can't be used
+	store.requests["/v1/ordersorder-3"] = IdempotentRequest{
+		Scope:       "/v1/orders",
+		Key:         "order-3",
+		RequestHash: idempotentRequestHash(httptest.NewRequest("POST", "/v1/orders", nil), []byte(`{}`)),
+	}
+	assert.Equal(t, http.StatusConflict, request("order-3", `{}`).Code)
+	assert.Equal(t, 4, calls)
+
+	assert.Equal(t, http.StatusBadRequest, request(strings.Repeat("k", 256), `{}`).Code)
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// JOSEContentType is the content type of JWE encrypted payloads
+const JOSEContentType = "application/jose"
+
+var (
+	// ErrUnsupportedEncryptionKey is returned when a recipient key cannot be used for encryption
+	ErrUnsupportedEncryptionKey = errors.New("unsupported encryption key type")
+)
+
+// RecipientKeyLookup finds the public key responses to the request should be encrypted with,
+// returning nil if no key has been registered
+type RecipientKeyLookup func(r *http.Request) (*jose.JSONWebKey, error)
+
+// LoadJWEDecryptionKey loads the private key used to decrypt request payloads from the
+// JWE_PRIVATE_KEY environment variable, a JSON web key
+func LoadJWEDecryptionKey() (*jose.JSONWebKey, error) {
+	raw := os.Getenv("JWE_PRIVATE_KEY")
+	if raw == "" {
+		return nil, nil
+	}
+	var jwk jose.JSONWebKey
+	if err := jwk.UnmarshalJSON([]byte(raw)); err != nil {
+		return nil, fmt.Errorf("failed to parse jwe private key: %w", err)
+	}
+	if jwk.IsPublic() {
+		return nil, errors.New("jwe private key must be a private key")
+	}
+	return &jwk, nil
+}
+
+// keyAlgorithm picks the key management algorithm for a recipient key
+func keyAlgorithm(jwk *jose.JSONWebKey) (jose.KeyAlgorithm, error) {
+	if jwk.Algorithm != "" {
+		return jose.KeyAlgorithm(jwk.Algorithm), nil
+	}
+	switch jwk.Key.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return jose.RSA_OAEP_256, nil
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		return jose.ECDH_ES_A256KW, nil
+	}
+	return "", ErrUnsupportedEncryptionKey
+}
+
+// EncryptJWE encrypts a payload to the recipient key in compact serialization
+func EncryptJWE(jwk *jose.JSONWebKey, payload []byte, contentType string) ([]byte, error) {
+	alg, err := keyAlgorithm(jwk)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm: alg,
+		Key:       jwk.Public().Key,
+		KeyID:     jwk.KeyID,
+	}, (&jose.EncrypterOptions{}).WithContentType(jose.ContentType(contentType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encrypter: %w", err)
+	}
+	object, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+	serialized, err := object.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize payload: %w", err)
+	}
+	return []byte(serialized), nil
+}
+
+// accepts checks if the client listed the media type in its Accept header
+func accepts(r *http.Request, mediaType string) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(accept, ";")[0]) == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponseWriter buffers the response so it can be transformed once complete
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// JWE is a middleware which negotiates payload encryption via content type. Requests sent with
+// a Content-Type of application/jose are decrypted with the decryption key before being passed on as
+// json, and requests sent with an Accept of application/jose have their response encrypted to the
+// recipient's registered public key. Plaintext requests are passed through unchanged
+func JWE(decryptionKey *jose.JSONWebKey, lookup RecipientKeyLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger, err := appctx.GetLogger(r.Context())
+			if err != nil {
+				_, logger = logging.SetupLogger(r.Context())
+			}
+
+			if strings.HasPrefix(r.Header.Get("Content-Type"), JOSEContentType) {
+				if decryptionKey == nil {
+					http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+					return
+				}
+				body, err := requestutils.Read(r.Body)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				object, err := jose.ParseEncrypted(string(bytes.TrimSpace(body)))
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				plaintext, err := object.Decrypt(decryptionKey)
+				if err != nil {
+					logger.Warn().Err(err).Msg("failed to decrypt jwe request payload")
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				r.Body = ioutil.NopCloser(bytes.NewReader(plaintext))
+				r.ContentLength = int64(len(plaintext))
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			if !accepts(r, JOSEContentType) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recipient, err := lookup(r)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to look up jwe recipient key")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if recipient == nil {
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+
+			// handlers render json, the result is then encrypted
+			r.Header.Set("Accept", "application/json")
+			buffered := &bufferedResponseWriter{header: http.Header{}}
+			next.ServeHTTP(buffered, r)
+
+			// conditional requests which matched have no payload to encrypt
+			if buffered.status == http.StatusNotModified {
+				for k, v := range buffered.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			contentType := buffered.header.Get("Content-Type")
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			encrypted, err := EncryptJWE(recipient, buffered.body.Bytes(), contentType)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to encrypt jwe response payload")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			for k, v := range buffered.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", JOSEContentType)
+			w.Header().Del("Content-Length")
+			if buffered.status == 0 {
+				buffered.status = http.StatusOK
+			}
+			w.WriteHeader(buffered.status)
+			if _, err := w.Write(encrypted); err != nil {
+				logger.Error().Err(err).Msg("failed to write jwe response")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func TestJWE(t *testing.T) {
+	serverPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	merchantPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serverKey := &jose.JSONWebKey{Key: serverPriv}
+	merchantKey := &jose.JSONWebKey{Key: &merchantPriv.PublicKey}
+
+	var received []byte
+	handler := JWE(serverKey, func(r *http.Request) (*jose.JSONWebKey, error) {
+		return merchantKey, nil
+	})(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		received, _ = ioutil.ReadAll(r.Body)
+		return handlers.RenderContent(r.Context(), map[string]string{"receipt": "secret"}, w, http.StatusCreated)
+	}))
+
+	// plaintext requests are untouched
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"a":"b"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"receipt":"secret"}`, rr.Body.String())
+	assert.Equal(t, `{"a":"b"}`, string(received))
+
+	// encrypted requests are decrypted and responses encrypted to the merchant key
+	encrypted, err := EncryptJWE(&jose.JSONWebKey{Key: &serverPriv.PublicKey}, []byte(`{"a":"c"}`), "application/json")
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(encrypted))
+	req.Header.Set("Content-Type", JOSEContentType)
+	req.Header.Set("Accept", JOSEContentType)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, JOSEContentType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, `{"a":"c"}`, string(received))
+	assert.NotContains(t, rr.Body.String(), "secret")
+
+	object, err := jose.ParseEncrypted(rr.Body.String())
+	require.NoError(t, err)
+	plaintext, err := object.Decrypt(merchantPriv)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"receipt":"secret"}`, string(plaintext))
+}
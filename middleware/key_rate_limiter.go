@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/gomodule/redigo/redis"
+	"github.com/throttled/throttled"
+	"github.com/throttled/throttled/store/memstore"
+	"github.com/throttled/throttled/store/redigostore"
+)
+
+// KeyRateLimit holds the limits applied to a single api key, a zero value means unlimited
+type KeyRateLimit struct {
+	// PerMinute is the sustained number of requests allowed each minute
+	PerMinute int
+	// Burst is the number of requests allowed in excess of PerMinute
+	Burst int
+	// DailyQuota is the number of requests allowed in a rolling day
+	DailyQuota int
+}
+
+// KeyRateLimitLookup gets the limits configured for an api key, returning nil if the key has none
+type KeyRateLimitLookup func(ctx context.Context, keyID string) (*KeyRateLimit, error)
+
+// KeyFunc extracts the api key id a request was made with, an empty id means the request is not key scoped
+type KeyFunc func(r *http.Request) string
+
+// NewRateLimitStore creates the store rate limits are tracked in, when REDIS_URL is set
+// the counts are shared between instances using redis
+func NewRateLimitStore(ctx context.Context, keyPrefix string) (throttled.GCRAStore, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return memstore.New(65536)
+	}
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(redisURL)
+		},
+	}
+	return redigostore.New(pool, keyPrefix, 0)
+}
+
+// keyRateLimiters lazily creates one limiter per distinct quota, as keys can be configured differently
+type keyRateLimiters struct {
+	store    throttled.GCRAStore
+	mu       sync.Mutex
+	limiters map[throttled.RateQuota]*throttled.GCRARateLimiter
+}
+
+func (krl *keyRateLimiters) get(quota throttled.RateQuota) (*throttled.GCRARateLimiter, error) {
+	krl.mu.Lock()
+	defer krl.mu.Unlock()
+	if limiter, ok := krl.limiters[quota]; ok {
+		return limiter, nil
+	}
+	limiter, err := throttled.NewGCRARateLimiter(krl.store, quota)
+	if err != nil {
+		return nil, err
+	}
+	krl.limiters[quota] = limiter
+	return limiter, nil
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, prefix string, result throttled.RateLimitResult) {
+	w.Header().Set(prefix+"-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set(prefix+"-Remaining", strconv.Itoa(result.Remaining))
+	if result.ResetAfter >= 0 {
+		w.Header().Set(prefix+"-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+	}
+}
+
+func denyRateLimited(w http.ResponseWriter, result throttled.RateLimitResult) {
+	if result.RetryAfter >= 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	}
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// KeyRateLimiter rate limits and enforces daily quotas per api key, using the
+// limits configured for the key. Requests without a key, or for keys without limits,
+// are passed through so other limiters can apply. The remaining allowance is returned
+// in the X-RateLimit-* and X-Quota-* headers.
+func KeyRateLimiter(
+	ctx context.Context,
+	store throttled.GCRAStore,
+	keyFunc KeyFunc,
+	lookup KeyRateLimitLookup,
+) func(next http.Handler) http.Handler {
+	limiters := &keyRateLimiters{
+		store:    store,
+		limiters: make(map[throttled.RateQuota]*throttled.GCRARateLimiter),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger, err := appctx.GetLogger(r.Context())
+			if err != nil {
+				_, logger = logging.SetupLogger(r.Context())
+			}
+
+			keyID := keyFunc(r)
+			if keyID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit, err := lookup(r.Context(), keyID)
+			if err != nil {
+				logger.Error().Err(err).Str("key_id", keyID).Msg("failed to look up key rate limit")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if limit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if limit.PerMinute > 0 {
+				limiter, err := limiters.get(throttled.RateQuota{
+					MaxRate:  throttled.PerMin(limit.PerMinute),
+					MaxBurst: limit.Burst,
+				})
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to create key rate limiter")
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				limited, result, err := limiter.RateLimit(fmt.Sprintf("rate:%s", keyID), 1)
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to apply key rate limit")
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				setRateLimitHeaders(w, "X-RateLimit", result)
+				if limited {
+					denyRateLimited(w, result)
+					return
+				}
+			}
+
+			if limit.DailyQuota > 0 {
+				// the whole quota may be used at once, then it refills over the day
+				limiter, err := limiters.get(throttled.RateQuota{
+					MaxRate:  throttled.PerDay(limit.DailyQuota),
+					MaxBurst: limit.DailyQuota - 1,
+				})
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to create key quota limiter")
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				limited, result, err := limiter.RateLimit(fmt.Sprintf("quota:%s", keyID), 1)
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to apply key quota")
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				setRateLimitHeaders(w, "X-Quota", result)
+				if limited {
+					denyRateLimited(w, result)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/throttled/throttled/store/memstore"
+)
+
+func TestKeyRateLimiter(t *testing.T) {
+	store, err := memstore.New(65536)
+	assert.NoError(t, err)
+
+	limits := map[string]*KeyRateLimit{
+		"limited": {PerMinute: 1, Burst: 1},
+		"quota":   {DailyQuota: 2},
+	}
+	handler := KeyRateLimiter(
+		context.Background(),
+		store,
+		func(r *http.Request) string { return r.Header.Get("key") },
+		func(ctx context.Context, keyID string) (*KeyRateLimit, error) { return limits[keyID], nil },
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		rr := serve("limited")
+		assert.Equal(t, http.StatusOK, rr.Code, "requests within the burst should pass")
+		assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Limit"))
+	}
+	rr := serve("limited")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "requests over the rate limit should be rejected")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	for i := 0; i < 2; i++ {
+		rr := serve("quota")
+		assert.Equal(t, http.StatusOK, rr.Code, "requests within the quota should pass")
+	}
+	rr = serve("quota")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "requests over the daily quota should be rejected")
+	assert.Equal(t, "0", rr.Header().Get("X-Quota-Remaining"))
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve("unlimited").Code, "keys without limits should not be limited")
+		assert.Equal(t, http.StatusOK, serve("").Code, "requests without a key should not be limited")
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/msgpack"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+)
+
+// MessagePack is a middleware which negotiates MessagePack payloads via content type. Requests
+// sent with a Content-Type of application/msgpack are passed on as json, and json responses to
+// requests sent with an Accept of application/msgpack are converted. Other requests are passed
+// through unchanged
+func MessagePack(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger, err := appctx.GetLogger(r.Context())
+		if err != nil {
+			_, logger = logging.SetupLogger(r.Context())
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), msgpack.ContentType) {
+			body, err := requestutils.Read(r.Body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			converted, err := msgpack.ToJSON(body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(converted))
+			r.ContentLength = int64(len(converted))
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		if !accepts(r, msgpack.ContentType) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// handlers render json, the result is then converted
+		r.Header.Set("Accept", "application/json")
+		buffered := &bufferedResponseWriter{header: http.Header{}}
+		next.ServeHTTP(buffered, r)
+
+		body := buffered.body.Bytes()
+		if strings.HasPrefix(buffered.header.Get("Content-Type"), "application/json") && len(bytes.TrimSpace(body)) > 0 {
+			converted, err := msgpack.FromJSON(body)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to convert response to msgpack")
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			body = converted
+			buffered.header.Set("Content-Type", msgpack.ContentType)
+		}
+
+		for k, v := range buffered.header {
+			w.Header()[k] = v
+		}
+		w.Header().Del("Content-Length")
+		if buffered.status == 0 {
+			buffered.status = http.StatusOK
+		}
+		w.WriteHeader(buffered.status)
+		if _, err := w.Write(body); err != nil {
+			logger.Error().Err(err).Msg("failed to write msgpack response")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/msgpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagePack(t *testing.T) {
+	var received []byte
+	handler := MessagePack(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		received, _ = ioutil.ReadAll(r.Body)
+		return handlers.RenderContent(r.Context(), map[string]interface{}{"signedCreds": []string{"a", "b"}}, w, http.StatusOK)
+	}))
+
+	// json requests are untouched
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"a":"b"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"signedCreds":["a","b"]}`, rr.Body.String())
+	assert.Equal(t, `{"a":"b"}`, string(received))
+
+	// msgpack requests are passed on as json and responses converted
+	body, err := msgpack.Marshal(map[string]string{"a": "c"})
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", msgpack.ContentType)
+	req.Header.Set("Accept", msgpack.ContentType)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, msgpack.ContentType, rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"a":"c"}`, string(received))
+
+	var resp struct {
+		SignedCreds []string `json:"signedCreds"`
+	}
+	require.NoError(t, msgpack.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"a", "b"}, resp.SignedCreds)
+
+	// malformed payloads are rejected
+	req = httptest.NewRequest("POST", "/", bytes.NewReader([]byte{0xc1}))
+	req.Header.Set("Content-Type", msgpack.ContentType)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
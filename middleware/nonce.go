@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/gomodule/redigo/redis"
+)
+
+// NonceHeader carries the single use nonce of a signed request
+const NonceHeader = "Nonce"
+
+const (
+	minNonceLength = 16
+	maxNonceLength = 128
+)
+
+// NonceStore remembers the nonces signed requests were made with
+type NonceStore interface {
+	// UseNonce records the nonce for the ttl, returning false if it was already used
+	UseNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// NonceRequired tells whether signed requests to the route must carry a nonce, from the comma separated
+// route names of REQUIRE_NONCE_ROUTES, or * for every route, so clients move over to signing nonces one
+// route at a time
+func NonceRequired(route string) bool {
+	for _, r := range strings.Split(os.Getenv("REQUIRE_NONCE_ROUTES"), ",") {
+		if r = strings.TrimSpace(r); r == "*" || r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// NewNonceStore creates the store nonces are tracked in, when REDIS_URL is set they are
+// shared between instances using redis. Outside of local environments REDIS_URL must be set once
+// REQUIRE_NONCE_ROUTES is, nonces tracked by one instance would be replayed against the others
+func NewNonceStore(ctx context.Context, keyPrefix string) (NonceStore, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		if os.Getenv("ENV") != "local" {
+			if strings.TrimSpace(os.Getenv("REQUIRE_NONCE_ROUTES")) != "" {
+				return nil, errors.New("REDIS_URL must be set to share nonces between instances")
+			}
+			logger, err := appctx.GetLogger(ctx)
+			if err != nil {
+				_, logger = logging.SetupLogger(ctx)
+			}
+			logger.Warn().Str("prefix", keyPrefix).
+				Msg("REDIS_URL is not set, nonces are not shared between instances")
+		}
+		return NewMemoryNonceStore(), nil
+	}
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(redisURL)
+		},
+	}
+	return NewRedisNonceStore(pool, keyPrefix), nil
+}
+
+type redisNonceStore struct {
+	pool      *redis.Pool
+	keyPrefix string
+}
+
+// NewRedisNonceStore creates a nonce store backed by redis, nonces expire with their keys
+func NewRedisNonceStore(pool *redis.Pool, keyPrefix string) NonceStore {
+	return &redisNonceStore{pool: pool, keyPrefix: keyPrefix}
+}
+
+// UseNonce sets the nonce key only if it does not exist, so concurrent replays cannot both succeed
+func (s *redisNonceStore) UseNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	conn := s.pool.Get()
+	defer func() { _ = conn.Close() }()
+
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := redis.String(conn.Do("SET", s.keyPrefix+nonce, 1, "PX", ms, "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// nonceExpiry is when a nonce is forgotten
+type nonceExpiry struct {
+	nonce   string
+	expires time.Time
+}
+
+// nonceExpiries is a heap of nonces, the first to expire on top
+type nonceExpiries []nonceExpiry
+
+func (h nonceExpiries) Len() int            { return len(h) }
+func (h nonceExpiries) Less(i, j int) bool  { return h[i].expires.Before(h[j].expires) }
+func (h nonceExpiries) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nonceExpiries) Push(x interface{}) { *h = append(*h, x.(nonceExpiry)) }
+func (h *nonceExpiries) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type memoryNonceStore struct {
+	mu       sync.Mutex
+	expires  map[string]time.Time
+	expiries nonceExpiries
+}
+
+// NewMemoryNonceStore creates a nonce store which does not synchronize across instances
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{expires: map[string]time.Time{}}
+}
+
+// UseNonce records the nonce, first dropping the nonces which expired, in the order they expire
+func (s *memoryNonceStore) UseNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.expiries) > 0 && now.After(s.expiries[0].expires) {
+		expired := heap.Pop(&s.expiries).(nonceExpiry)
+		delete(s.expires, expired.nonce)
+	}
+	if _, ok := s.expires[nonce]; ok {
+		return false, nil
+	}
+	s.expires[nonce] = now.Add(ttl)
+	heap.Push(&s.expiries, nonceExpiry{nonce: nonce, expires: now.Add(ttl)})
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNonceStore(t *testing.T, store NonceStore, expire func()) {
+	ctx := context.Background()
+
+	fresh, err := store.UseNonce(ctx, "key:nonce", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.UseNonce(ctx, "key:nonce", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, fresh, "nonces may only be used once")
+
+	fresh, err = store.UseNonce(ctx, "other:nonce", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, fresh, "nonces are tracked per key")
+
+	expire()
+	fresh, err = store.UseNonce(ctx, "key:nonce", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, fresh, "expired nonces are forgotten")
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	testNonceStore(t, store, func() { time.Sleep(60 * time.Millisecond) })
+	assert.Len(t, store.(*memoryNonceStore).expires, 1, "expired nonces are dropped")
+	assert.Len(t, store.(*memoryNonceStore).expiries, 1)
+}
+
+func TestNewNonceStore(t *testing.T) {
+	env := os.Getenv("ENV")
+	defer func() { _ = os.Setenv("ENV", env) }()
+	require.NoError(t, os.Unsetenv("REDIS_URL"))
+	defer func() { _ = os.Unsetenv("REQUIRE_NONCE_ROUTES") }()
+	ctx := context.Background()
+
+	require.NoError(t, os.Setenv("ENV", "production"))
+	require.NoError(t, os.Setenv("REQUIRE_NONCE_ROUTES", "ClaimPromotion"))
+	_, err := NewNonceStore(ctx, "nonce:")
+	assert.Error(t, err, "required nonces must be shared between instances outside local")
+
+	require.NoError(t, os.Unsetenv("REQUIRE_NONCE_ROUTES"))
+	store, err := NewNonceStore(ctx, "nonce:")
+	require.NoError(t, err, "nonces are optional until some route requires them")
+	assert.IsType(t, &memoryNonceStore{}, store)
+
+	require.NoError(t, os.Setenv("ENV", "local"))
+	require.NoError(t, os.Setenv("REQUIRE_NONCE_ROUTES", "*"))
+	store, err = NewNonceStore(ctx, "nonce:")
+	require.NoError(t, err)
+	assert.IsType(t, &memoryNonceStore{}, store)
+}
+
+func TestNonceRequired(t *testing.T) {
+	require.NoError(t, os.Setenv("REQUIRE_NONCE_ROUTES", "ClaimPromotion, DrainSuggestion"))
+	defer func() { _ = os.Unsetenv("REQUIRE_NONCE_ROUTES") }()
+	assert.True(t, NonceRequired("DrainSuggestion"))
+	assert.False(t, NonceRequired("LinkBraveDepositAccount"))
+
+	require.NoError(t, os.Setenv("REQUIRE_NONCE_ROUTES", "*"))
+	assert.True(t, NonceRequired("LinkBraveDepositAccount"))
+	require.NoError(t, os.Unsetenv("REQUIRE_NONCE_ROUTES"))
+	assert.False(t, NonceRequired("ClaimPromotion"))
+}
+
+func TestRedisNonceStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	pool := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	testNonceStore(t, NewRedisNonceStore(pool, "nonce:"), func() { mr.FastForward(time.Second) })
+	assert.True(t, mr.Exists("nonce:key:nonce"))
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"crypto/sha256"
 	"net/http"
 
@@ -10,17 +9,22 @@ import (
 	"github.com/shengdoushi/base58"
 )
 
-// RequestIDTransfer transfers the request id from header to context
+// RequestIDTransfer transfers the request id from header to context, generating one if the
+// caller did not supply it. The id is echoed back on the response so it can be quoted in reports
 func RequestIDTransfer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		reqID := r.Header.Get(requestutils.RequestIDHeaderKey)
 		if reqID == "" {
 			// generate one if one does not yet exist
-			bytes := sha256.Sum256(uuid.NewV4().Bytes())
-			reqID = base58.Encode(bytes[:], base58.BitcoinAlphabet)[:16]
+			reqID = newRequestID()
 		}
 		w.Header().Set(requestutils.RequestIDHeaderKey, reqID)
-		ctx := context.WithValue(r.Context(), requestutils.RequestID, reqID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(requestutils.WithRequestID(r.Context(), reqID)))
 	})
 }
+
+// newRequestID generates a short random request id
+func newRequestID() string {
+	bytes := sha256.Sum256(uuid.NewV4().Bytes())
+	return base58.Encode(bytes[:], base58.BitcoinAlphabet)[:16]
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDTransfer(t *testing.T) {
+	var seen string
+	handler := RequestIDTransfer(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		seen = requestutils.GetRequestID(r.Context())
+		return &handlers.AppError{Message: "failed", Code: http.StatusBadRequest}
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(requestutils.RequestIDHeaderKey, "abc123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "abc123", seen, "the supplied request id should be attached to the context")
+	assert.Equal(t, "abc123", rr.Header().Get(requestutils.RequestIDHeaderKey))
+	assert.JSONEq(t, `{"message":"failed","code":400,"requestId":"abc123","errorCode":"bad_request","localizedMessage":"The request is invalid.","retriable":false}`, rr.Body.String())
+
+	req = httptest.NewRequest("GET", "/", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Len(t, seen, 16, "a request id should be generated when none is supplied")
+	assert.Equal(t, seen, rr.Header().Get(requestutils.RequestIDHeaderKey))
+}
@@ -34,16 +34,24 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/brave-intl/bat-go/utils/handlers"
-	"github.com/getsentry/sentry-go"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 )
 
-var ipPortRE = regexp.MustCompile(`[0-9]+(?:\.[0-9]+){3}(:[0-9]+)?`)
+var (
+	ipPortRE = regexp.MustCompile(`[0-9]+(?:\.[0-9]+){3}(:[0-9]+)?`)
+	uuidRE   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+)
 
 // RequestLogger logs at the start and stop of incoming HTTP requests as well as recovers from panics
 // Modified version of RequestLogger from github.com/rs/zerolog
@@ -60,6 +68,21 @@ func RequestLogger(logger *zerolog.Logger) func(next http.Handler) http.Handler
 			t1 := time.Now().UTC()
 			// only need to get logger from context once per request
 			logger := hlog.FromRequest(r)
+			if reqID := requestutils.GetRequestID(r.Context()); reqID != "" {
+				// every log line written with the request logger carries the request id
+				logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+					return c.Str("req_id", reqID)
+				})
+			}
+			if sc := tracing.SpanFromContext(r.Context()).Context(); sc.IsValid() {
+				logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+					return c.Str("trace_id", sc.TraceID.String())
+				})
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				hooked := logger.Hook(routeHook{rctx})
+				logger = &hooked
+			}
 			createSubLog(logger, r, 0).
 				Msg("request started")
 
@@ -68,16 +91,15 @@ func RequestLogger(logger *zerolog.Logger) func(next http.Handler) http.Handler
 
 				// Recover and record stack traces in case of a panic
 				if rec := recover(); rec != nil {
-					logger.Panic().Stack().Msg("panic")
+					// logged at error level, a panic level log would panic again before the failure is reported
+					logger.Error().Stack().Str("panic", fmt.Sprint(rec)).Msg("panic")
 					// consolodate these: `http: proxy error: read tcp x.x.x.x:xxxx->x.x.x.x:xxxx: i/o timeout`
 					// any panic that has an ipaddress/port in it
 					m := string(ipPortRE.ReplaceAll(
 						[]byte(fmt.Sprint(rec)), []byte("x.x.x.x:xxxx")))
 
 					// Send panic info to Sentry
-					event := sentry.NewEvent()
-					event.Message = m
-					sentry.CaptureEvent(event)
+					reporting.CapturePanic(r, m)
 
 					handlers.AppError{
 						Message: http.StatusText(http.StatusInternalServerError),
@@ -101,6 +123,33 @@ func RequestLogger(logger *zerolog.Logger) func(next http.Handler) http.Handler
 	}
 }
 
+// loggedURLParams are the route parameters identifying a wallet or merchant, which are logged hashed
+var loggedURLParams = []struct {
+	param string
+	field string
+}{
+	{"paymentID", logging.WalletHashField},
+	{"walletId", logging.WalletHashField},
+	{"merchantID", logging.MerchantHashField},
+}
+
+// routeHook adds the route pattern and the hashed identifiers in the route parameters to each log line.
+// They are only known once the request has been routed, so they are read as each line is written
+type routeHook struct {
+	rctx *chi.Context
+}
+
+func (h routeHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if pattern := h.rctx.RoutePattern(); pattern != "" {
+		e.Str("route", pattern)
+	}
+	for _, p := range loggedURLParams {
+		if v := h.rctx.URLParam(p.param); v != "" {
+			e.Str(p.field, logging.HashIdentifier(v))
+		}
+	}
+}
+
 func createSubLog(logger *zerolog.Logger, r *http.Request, status int) (subLog *zerolog.Event) {
 	if status >= 400 && status <= 499 {
 		subLog = logger.Warn()
@@ -113,5 +162,19 @@ func createSubLog(logger *zerolog.Logger, r *http.Request, status int) (subLog *
 		Str("host", r.Host).
 		Str("http_proto", r.Proto).
 		Str("http_method", r.Method).
-		Str("uri", r.URL.EscapedPath())
+		Str("uri", redactPath(r))
+}
+
+// redactPath hashes the wallet and merchant identifiers in the request path. Before the request is routed
+// only ids which look like uuids can be found
+func redactPath(r *http.Request) string {
+	path := r.URL.EscapedPath()
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for _, p := range loggedURLParams {
+			if v := rctx.URLParam(p.param); v != "" {
+				path = strings.Replace(path, "/"+v, "/"+logging.HashIdentifier(v), -1)
+			}
+		}
+	}
+	return uuidRE.ReplaceAllStringFunc(path, logging.HashIdentifier)
 }
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLoggerScopesLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := zerolog.New(&b)
+
+	walletID := "7b5da4e0-a6c1-4b52-8e4b-c7a8e0b2cb9c"
+	r := chi.NewRouter()
+	r.Use(RequestIDTransfer)
+	r.Use(Tracing)
+	r.Use(hlog.NewHandler(logger))
+	r.Use(RequestLogger(&logger))
+	r.Post("/v3/wallet/{paymentID}/claim", func(w http.ResponseWriter, r *http.Request) {
+		log, err := appctx.GetLogger(r.Context())
+		require.NoError(t, err)
+		AddAPIKey(r.Context(), &APIKey{ID: "key", Merchant: "brave.com"})
+		log.Info().Msg("claiming")
+	})
+
+	req := httptest.NewRequest("POST", "/v3/wallet/"+walletID+"/claim", nil)
+	req.Header.Set(requestutils.RequestIDHeaderKey, "abc123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.NotContains(t, b.String(), walletID, "wallet ids must only be logged hashed")
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&b)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 3, "expected the request started, handler and request complete lines")
+
+	for _, line := range lines[1:] {
+		assert.Equal(t, "abc123", line["req_id"])
+		assert.NotEmpty(t, line["trace_id"])
+		assert.Equal(t, "/v3/wallet/{paymentID}/claim", line["route"])
+		assert.Equal(t, logging.HashIdentifier(walletID), line[logging.WalletHashField])
+	}
+	assert.Equal(t, "claiming", lines[1]["message"])
+	assert.Equal(t, logging.HashIdentifier("brave.com"), lines[2][logging.MerchantHashField],
+		"identifiers added by handlers should be logged for the rest of the request")
+}
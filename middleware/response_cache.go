@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+)
+
+// SurrogateKeyHeader lists the surrogate keys of a response, a CDN purges cached
+// responses by these keys just as the response cache does
+const SurrogateKeyHeader = "Surrogate-Key"
+
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	expires time.Time
+	keys    []string
+}
+
+// ResponseCache caches successful GET responses in memory, indexed by their surrogate keys so
+// every response built from some data can be purged when that data changes
+type ResponseCache struct {
+	mu        sync.RWMutex
+	ttl       time.Duration
+	responses map[string]*cachedResponse
+	keys      map[string]map[string]bool
+}
+
+// NewResponseCache creates a response cache which holds responses for at most the ttl
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:       ttl,
+		responses: map[string]*cachedResponse{},
+		keys:      map[string]map[string]bool{},
+	}
+}
+
+// AddSurrogateKeys tags the response with surrogate keys, handlers call this for each piece of
+// data the response is built from
+func AddSurrogateKeys(w http.ResponseWriter, keys ...string) {
+	existing := strings.Fields(w.Header().Get(SurrogateKeyHeader))
+	w.Header().Set(SurrogateKeyHeader, strings.Join(append(existing, keys...), " "))
+}
+
+// Purge drops every cached response tagged with any of the surrogate keys
+func (c *ResponseCache) Purge(keys ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		for cacheKey := range c.keys[key] {
+			c.remove(cacheKey)
+		}
+	}
+}
+
+// remove drops a cached response and its surrogate key index entries, the lock must be held
+func (c *ResponseCache) remove(cacheKey string) {
+	resp, ok := c.responses[cacheKey]
+	if !ok {
+		return
+	}
+	delete(c.responses, cacheKey)
+	for _, key := range resp.keys {
+		delete(c.keys[key], cacheKey)
+		if len(c.keys[key]) == 0 {
+			delete(c.keys, key)
+		}
+	}
+}
+
+func (c *ResponseCache) get(cacheKey string) (*cachedResponse, bool) {
+	c.mu.RLock()
+	resp, ok := c.responses[cacheKey]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(resp.expires) {
+		c.mu.Lock()
+		c.remove(cacheKey)
+		c.mu.Unlock()
+		return nil, false
+	}
+	return resp, true
+}
+
+func (c *ResponseCache) set(cacheKey string, resp *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(cacheKey)
+	c.responses[cacheKey] = resp
+	for _, key := range resp.keys {
+		if c.keys[key] == nil {
+			c.keys[key] = map[string]bool{}
+		}
+		c.keys[key][cacheKey] = true
+	}
+}
+
+// Middleware serves GET requests from the cache, caching successful responses on a miss
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// responses vary by representation
+		cacheKey := r.URL.RequestURI() + "\n" + r.Header.Get("Accept")
+		if resp, ok := c.get(cacheKey); ok {
+			for k, v := range resp.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(resp.body)
+			return
+		}
+
+		buffered := &bufferedResponseWriter{header: http.Header{}}
+		next.ServeHTTP(buffered, r)
+		if buffered.status == 0 {
+			buffered.status = http.StatusOK
+		}
+
+		if buffered.status == http.StatusOK {
+			header := http.Header{}
+			for k, v := range buffered.header {
+				header[k] = v
+			}
+			c.set(cacheKey, &cachedResponse{
+				header:  header,
+				body:    append([]byte{}, buffered.body.Bytes()...),
+				expires: time.Now().Add(c.ttl),
+				keys:    strings.Fields(header.Get(SurrogateKeyHeader)),
+			})
+		}
+
+		for k, v := range buffered.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-Cache", "MISS")
+		w.WriteHeader(buffered.status)
+		if _, err := w.Write(buffered.body.Bytes()); err != nil {
+			logger, lerr := appctx.GetLogger(r.Context())
+			if lerr != nil {
+				_, logger = logging.SetupLogger(r.Context())
+			}
+			logger.Error().Err(err).Msg("failed to write response")
+		}
+	})
+}
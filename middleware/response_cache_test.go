@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	cache := NewResponseCache(time.Minute)
+	handler := cache.Middleware(handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		calls++
+		AddSurrogateKeys(w, "skus", "merchant:brave.com")
+		return handlers.RenderContent(r.Context(), map[string]int{"calls": calls}, w, http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/skus", nil))
+		return rr
+	}
+
+	rr := serve()
+	assert.Equal(t, "MISS", rr.Header().Get("X-Cache"))
+	assert.Equal(t, "skus merchant:brave.com", rr.Header().Get(SurrogateKeyHeader))
+
+	rr = serve()
+	assert.Equal(t, "HIT", rr.Header().Get("X-Cache"))
+	assert.JSONEq(t, `{"calls":1}`, rr.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// purging an unrelated key keeps the response
+	cache.Purge("merchant:other")
+	assert.Equal(t, "HIT", serve().Header().Get("X-Cache"))
+
+	cache.Purge("merchant:brave.com")
+	rr = serve()
+	assert.Equal(t, "MISS", rr.Header().Get("X-Cache"))
+	assert.JSONEq(t, `{"calls":2}`, rr.Body.String())
+	assert.Empty(t, cache.keys["merchant:other"])
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	calls := 0
+	cache := NewResponseCache(-time.Second)
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 2, calls, "expired responses are not served")
+}
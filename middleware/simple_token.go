@@ -13,6 +13,8 @@ type bearerTokenKey struct{}
 var (
 	// TokenList is the list of tokens that are accepted as valid
 	TokenList = strings.Split(os.Getenv("TOKEN_LIST"), ",")
+	// AdminTokenList is the list of tokens that are accepted as valid for admin routes
+	AdminTokenList = strings.Split(os.Getenv("ADMIN_TOKEN_LIST"), ",")
 )
 
 // BearerToken is a middleware that adds the bearer token included in a request's headers to context
@@ -30,6 +32,17 @@ func BearerToken(next http.Handler) http.Handler {
 	})
 }
 
+// GetBearerToken returns the bearer token BearerToken added to the context, if any
+func GetBearerToken(ctx context.Context) string {
+	token, _ := ctx.Value(bearerTokenKey{}).(string)
+	return token
+}
+
+// IsSimpleToken reports whether the token is one of the simple tokens
+func IsSimpleToken(token string) bool {
+	return isSimpleTokenValid(TokenList, token)
+}
+
 func isSimpleTokenValid(list []string, token string) bool {
 	if token == "" {
 		return false
@@ -51,6 +64,11 @@ func isSimpleTokenInContext(ctx context.Context) bool {
 	return true
 }
 
+func isAdminTokenInContext(ctx context.Context) bool {
+	token, ok := ctx.Value(bearerTokenKey{}).(string)
+	return ok && isSimpleTokenValid(AdminTokenList, token)
+}
+
 // SimpleTokenAuthorizedOnly is a middleware that restricts access to requests with a valid bearer token via context
 // NOTE the valid token is populated via BearerToken
 func SimpleTokenAuthorizedOnly(next http.Handler) http.Handler {
@@ -62,3 +80,16 @@ func SimpleTokenAuthorizedOnly(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminTokenAuthorizedOnly is a middleware that restricts access to requests with a valid admin bearer token
+// via context, the simple tokens are not admin tokens unless they are also in the admin list
+// NOTE the valid token is populated via BearerToken
+func AdminTokenAuthorizedOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminTokenInContext(r.Context()) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_isSimpleTokenValid(t *testing.T) {
@@ -39,3 +43,26 @@ func Test_isSimpleTokenValid(t *testing.T) {
 		t.Error("Expected wrong tokens to be invalid")
 	}
 }
+
+func TestAdminTokenAuthorizedOnly(t *testing.T) {
+	oldTokenList, oldAdminTokenList := TokenList, AdminTokenList
+	defer func() {
+		TokenList, AdminTokenList = oldTokenList, oldAdminTokenList
+	}()
+	TokenList, AdminTokenList = []string{"simple"}, []string{"admin"}
+
+	handler := BearerToken(AdminTokenAuthorizedOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	serve := func(token string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("admin"))
+	assert.Equal(t, http.StatusForbidden, serve("simple"), "simple tokens are not admin tokens")
+	assert.Equal(t, http.StatusForbidden, serve(""))
+}
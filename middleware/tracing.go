@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/go-chi/chi"
+	chiware "github.com/go-chi/chi/middleware"
+)
+
+// Tracing starts a server span for each request, continuing the caller's trace if it sent a traceparent.
+// The span is named after the chi route pattern once the request has been routed
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.StartSpan(ctx, "HTTP "+r.Method, tracing.WithKind(tracing.SpanKindServer))
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		if reqID := requestutils.GetRequestID(ctx); reqID != "" {
+			span.SetAttribute("http.request_id", reqID)
+		}
+
+		ww := chiware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttribute("http.route", rctx.RoutePattern())
+		}
+		span.SetAttribute("http.status_code", status)
+
+		var err error
+		if status >= http.StatusInternalServerError {
+			err = &statusError{status}
+		}
+		span.End(err)
+	})
+}
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return http.StatusText(e.status)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	var span *tracing.Span
+	r := chi.NewRouter()
+	r.Use(Tracing)
+	r.Get("/v1/orders/{orderID}", func(w http.ResponseWriter, r *http.Request) {
+		span = tracing.SpanFromContext(r.Context())
+
+		// outbound requests continue the trace
+		header := http.Header{}
+		tracing.Inject(r.Context(), header)
+		assert.Equal(t, span.Context().Traceparent(), header.Get(tracing.TraceparentHeader))
+	})
+
+	req := httptest.NewRequest("GET", "/v1/orders/abc", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if assert.NotNil(t, span) {
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.Context().TraceID.String(),
+			"the span should continue the caller's trace")
+	}
+
+	// without a traceparent a new trace is started
+	span = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/orders/abc", nil))
+	if assert.NotNil(t, span) {
+		assert.True(t, span.Context().IsValid())
+		assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", span.Context().TraceID.String())
+	}
+
+	// the span is only in the request context
+	assert.Nil(t, tracing.SpanFromContext(context.Background()))
+}
@@ -0,0 +1 @@
+alter table vote_drain drop column request_id;
@@ -0,0 +1,2 @@
+--- request_id - the request which queued the vote, so the drain can be traced back to it
+alter table vote_drain add column request_id text;
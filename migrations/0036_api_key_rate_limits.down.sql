@@ -0,0 +1,3 @@
+alter table api_keys drop column rate_limit_per_minute;
+alter table api_keys drop column rate_limit_burst;
+alter table api_keys drop column daily_quota;
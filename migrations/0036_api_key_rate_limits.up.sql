@@ -0,0 +1,4 @@
+--- per api key rate limits and daily quotas, null means the key is not limited
+alter table api_keys add column rate_limit_per_minute integer check (rate_limit_per_minute > 0);
+alter table api_keys add column rate_limit_burst integer not null default 0 check (rate_limit_burst >= 0);
+alter table api_keys add column daily_quota integer check (daily_quota > 0);
@@ -0,0 +1,4 @@
+drop index if exists api_keys_token_hash_idx;
+alter table api_keys drop column scopes;
+alter table api_keys drop column token_hash;
+alter table api_keys drop column last_used_at;
@@ -0,0 +1,5 @@
+--- api keys can authenticate merchant requests with a token, only a hash of the token is stored
+alter table api_keys add column scopes text[] not null default '{}';
+alter table api_keys add column token_hash text;
+alter table api_keys add column last_used_at timestamp with time zone;
+create unique index api_keys_token_hash_idx on api_keys(token_hash);
@@ -0,0 +1,19 @@
+--- audit_events - append only record of authenticated mutating requests
+create table audit_events (
+    id uuid primary key not null default uuid_generate_v4(),
+    created_at timestamp with time zone not null default current_timestamp,
+    actor text not null,
+    method text not null,
+    route text not null,
+    path text not null,
+    entities jsonb not null default '{}',
+    status integer not null,
+    request_id text
+);
+
+create index audit_events_actor_idx on audit_events(actor, created_at);
+create index audit_events_created_at_idx on audit_events(created_at);
+
+--- the audit log can only be appended to
+create rule audit_events_no_update as on update to audit_events do instead nothing;
+create rule audit_events_no_delete as on delete to audit_events do instead nothing;
@@ -0,0 +1,7 @@
+--- merchant_encryption_keys - public keys responses to a merchant can be encrypted to
+create table merchant_encryption_keys (
+    merchant_id text primary key not null,
+    jwk text not null,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp
+);
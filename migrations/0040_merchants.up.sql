@@ -0,0 +1,10 @@
+--- merchants - the integrations referenced by merchant_id across orders, issuers and api keys
+create table merchants (
+    id text primary key not null,
+    name text not null,
+    allowed_skus text[] not null default '{}',
+    webhook_urls text[] not null default '{}',
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp,
+    deleted_at timestamp with time zone
+);
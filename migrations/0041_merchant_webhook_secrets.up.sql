@@ -0,0 +1,26 @@
+--- merchant_webhook_secrets - versioned secrets webhook deliveries are signed with, several are active during a rotation
+create table merchant_webhook_secrets (
+    id uuid primary key default uuid_generate_v4(),
+    merchant_id text not null,
+    version integer not null,
+    encrypted_secret text not null,
+    nonce text not null,
+    created_at timestamp with time zone not null default current_timestamp,
+    expires_at timestamp with time zone,
+    unique (merchant_id, version)
+);
+
+--- webhook_deliveries - the log of webhook deliveries, including how each was signed so merchants can debug verification
+create table webhook_deliveries (
+    id uuid primary key default uuid_generate_v4(),
+    merchant_id text not null,
+    url text not null,
+    event text not null,
+    secret_version integer not null,
+    signature text not null,
+    status integer,
+    error text,
+    created_at timestamp with time zone not null default current_timestamp
+);
+
+create index webhook_deliveries_merchant_id_created_at_idx on webhook_deliveries (merchant_id, created_at desc);
@@ -0,0 +1 @@
+drop table if exists merchant_signing_keys;
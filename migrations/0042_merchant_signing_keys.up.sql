@@ -0,0 +1,13 @@
+--- merchant_signing_keys - ed25519 keys merchant backends sign order creation requests with, scoped to skus and amounts
+create table merchant_signing_keys (
+    id uuid primary key default uuid_generate_v4(),
+    merchant_id text not null,
+    name text not null,
+    public_key text not null,
+    allowed_skus text[] not null default '{}',
+    max_amount numeric(28, 18),
+    created_at timestamp with time zone not null default current_timestamp,
+    revoked_at timestamp with time zone
+);
+
+create index merchant_signing_keys_merchant_id_idx on merchant_signing_keys (merchant_id);
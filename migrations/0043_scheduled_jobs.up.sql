@@ -0,0 +1,10 @@
+--- scheduled_jobs - the last and next runs of each scheduled job, shared by every instance
+create table scheduled_jobs (
+    name text primary key not null,
+    schedule text not null,
+    last_run_at timestamp with time zone,
+    last_duration_ms bigint,
+    last_error text,
+    next_run_at timestamp with time zone,
+    updated_at timestamp with time zone not null default current_timestamp
+);
@@ -0,0 +1,2 @@
+drop table if exists vote_drain_v2;
+drop table if exists transactions_v2;
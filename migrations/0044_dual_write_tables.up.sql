@@ -0,0 +1,29 @@
+--- transactions_v2 - transactions carrying their merchant, so merchant listings need no join with orders
+create table transactions_v2 (
+    id uuid primary key not null,
+    order_id uuid references orders(id),
+    merchant_id text not null,
+    created_at timestamp with time zone not null,
+    updated_at timestamp with time zone not null,
+    external_transaction_id text not null unique,
+    status text not null,
+    currency text not null,
+    kind text not null,
+    amount numeric(28, 18) not null
+);
+create index transactions_v2_order_id_idx on transactions_v2 (order_id);
+create index transactions_v2_merchant_id_idx on transactions_v2 (merchant_id, created_at);
+
+--- vote_drain_v2 - the vote queue, indexed on the votes still to be processed
+create table vote_drain_v2 (
+    id uuid primary key not null,
+    credentials json not null,
+    vote_text text not null,
+    vote_event bytea not null,
+    erred boolean not null default false,
+    errcode text default null,
+    processed boolean not null default false,
+    request_id text,
+    created_at timestamp with time zone not null default current_timestamp
+);
+create index vote_drain_v2_pending_idx on vote_drain_v2 (created_at) where not processed and not erred;
@@ -0,0 +1,3 @@
+drop index if exists transactions_updated_at_idx;
+drop index if exists vote_drain_created_at_idx;
+alter table vote_drain drop column created_at;
@@ -0,0 +1,4 @@
+--- created_at - when the vote was queued, so votes can be exported incrementally
+alter table vote_drain add column created_at timestamp with time zone default current_timestamp;
+create index vote_drain_created_at_idx on vote_drain (created_at);
+create index transactions_updated_at_idx on transactions (updated_at);
@@ -0,0 +1,2 @@
+drop table if exists notification_opt_outs;
+drop table if exists notifications;
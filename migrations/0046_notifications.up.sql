@@ -0,0 +1,22 @@
+--- notifications - emails sent to users and their delivery status
+create table notifications (
+    id uuid primary key not null default uuid_generate_v4(),
+    kind text not null,
+    recipient text not null,
+    subject text not null,
+    provider text not null,
+    provider_message_id text,
+    status text not null default 'pending',
+    error text,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp
+);
+create index notifications_recipient_idx on notifications (recipient, created_at);
+
+--- notification_opt_outs - the kinds of notification each recipient no longer wants, 'all' opting out of every kind
+create table notification_opt_outs (
+    recipient text not null,
+    kind text not null,
+    created_at timestamp with time zone not null default current_timestamp,
+    primary key (recipient, kind)
+);
@@ -0,0 +1,2 @@
+drop table if exists notification_delivery_attempts;
+drop table if exists notification_deliveries;
@@ -0,0 +1,29 @@
+--- notification_deliveries - the queue of emails and webhooks to deliver, retried with backoff until delivered or out of attempts
+create table notification_deliveries (
+    id uuid primary key not null default uuid_generate_v4(),
+    channel text not null,
+    destination text not null,
+    owner text,
+    payload json not null,
+    status text not null default 'pending',
+    attempts integer not null default 0,
+    max_attempts integer not null,
+    next_attempt_at timestamp with time zone,
+    last_error text,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp
+);
+create index notification_deliveries_due_idx on notification_deliveries (next_attempt_at) where status = 'pending';
+
+--- notification_delivery_attempts - the history of each attempt at a delivery
+create table notification_delivery_attempts (
+    id uuid primary key not null default uuid_generate_v4(),
+    delivery_id uuid not null references notification_deliveries(id) on delete cascade,
+    attempt integer not null,
+    succeeded boolean not null,
+    response_status integer,
+    error text,
+    duration_ms bigint not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
+create index notification_delivery_attempts_delivery_id_idx on notification_delivery_attempts (delivery_id, attempt);
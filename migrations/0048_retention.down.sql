@@ -0,0 +1,7 @@
+drop index if exists notification_deliveries_updated_at_idx;
+drop index if exists notifications_created_at_idx;
+drop index if exists webhook_deliveries_created_at_idx;
+drop index if exists vote_drain_v2_created_at_idx;
+
+drop rule audit_events_no_delete on audit_events;
+create rule audit_events_no_delete as on delete to audit_events do instead nothing;
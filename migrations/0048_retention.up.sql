@@ -0,0 +1,11 @@
+--- the retention purge may delete expired audit events, it turns retention.purge on for its transaction
+drop rule audit_events_no_delete on audit_events;
+create rule audit_events_no_delete as on delete to audit_events
+    where current_setting('retention.purge', true) is distinct from 'on'
+    do instead nothing;
+
+--- indexes for finding the rows past their retention in batches
+create index vote_drain_v2_created_at_idx on vote_drain_v2 (created_at) where processed;
+create index webhook_deliveries_created_at_idx on webhook_deliveries (created_at);
+create index notifications_created_at_idx on notifications (created_at);
+create index notification_deliveries_updated_at_idx on notification_deliveries (updated_at) where status <> 'pending';
@@ -0,0 +1,8 @@
+drop table if exists order_events;
+alter table orders drop column if exists event_sequence;
+
+update orders set status = 'canceled' where status = 'refunded';
+alter table orders drop constraint status_check;
+alter table orders add constraint status_check check (
+    status in ('pending', 'paid', 'fulfilled', 'canceled')
+);
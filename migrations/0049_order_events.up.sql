@@ -0,0 +1,40 @@
+--- order_events - the append only log of every change to an order, orders is maintained as its projection
+create table order_events (
+    id uuid primary key not null default uuid_generate_v4(),
+    order_id uuid not null references orders(id),
+    sequence integer not null,
+    type text not null,
+    payload jsonb not null default '{}',
+    created_at timestamp with time zone not null default current_timestamp,
+    dispatched_at timestamp with time zone,
+    unique (order_id, sequence)
+);
+create index order_events_undispatched_idx on order_events (created_at) where dispatched_at is null;
+create index order_events_type_created_at_idx on order_events (type, created_at);
+
+--- event_sequence is the last event applied to the order's row
+alter table orders add column event_sequence integer not null default 0;
+
+alter table orders drop constraint status_check;
+alter table orders add constraint status_check check (
+    status in ('pending', 'paid', 'fulfilled', 'canceled', 'refunded')
+);
+
+--- orders placed before the log get a created event carrying their current state, so every order can be replayed
+insert into order_events (order_id, sequence, type, payload, created_at, dispatched_at)
+select o.id, 1, 'created', jsonb_build_object(
+        'merchantId', o.merchant_id,
+        'currency', o.currency,
+        'location', o.location,
+        'status', o.status,
+        'totalPrice', o.total_price,
+        'backfilled', true,
+        'items', coalesce((
+            select jsonb_agg(jsonb_build_object(
+                'id', i.id, 'orderId', i.order_id, 'sku', i.sku, 'currency', i.currency,
+                'quantity', i.quantity, 'price', i.price, 'subtotal', i.quantity * i.price,
+                'location', i.location, 'description', i.description, 'credentialType', i.credential_type))
+            from order_items i where i.order_id = o.id), '[]'::jsonb)
+    ), o.created_at, current_timestamp
+from orders o;
+update orders set event_sequence = 1;
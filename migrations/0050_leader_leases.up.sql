@@ -0,0 +1,7 @@
+--- leader_leases - the instance leading each singleton job family, until its lease expires unrenewed
+create table leader_leases (
+    family text primary key not null,
+    holder text not null,
+    acquired_at timestamp with time zone not null default current_timestamp,
+    expires_at timestamp with time zone not null
+);
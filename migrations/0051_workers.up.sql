@@ -0,0 +1,16 @@
+--- workers - the heartbeat of every instance's job workers and the job each is working on
+create table workers (
+    id text primary key not null,
+    service text not null,
+    job text not null,
+    host text not null,
+    current_job text,
+    progress text,
+    claimed_at timestamp with time zone,
+    --- the session holding the lock of the current job, ended to requeue the job of a stuck worker
+    backend_pid integer,
+    started_at timestamp with time zone not null,
+    heartbeat_at timestamp with time zone not null
+);
+
+create index workers_heartbeat_at_idx on workers (heartbeat_at);
@@ -0,0 +1,3 @@
+drop index if exists suggestion_drain_created_at_idx;
+drop index if exists claim_drain_created_at_idx;
+alter table claim_drain drop column if exists created_at;
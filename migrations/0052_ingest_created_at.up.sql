@@ -0,0 +1,4 @@
+--- created_at - when the drain was queued, so its ingest rate can be compared with its baseline
+alter table claim_drain add column created_at timestamp with time zone default current_timestamp;
+create index claim_drain_created_at_idx on claim_drain (created_at);
+create index suggestion_drain_created_at_idx on suggestion_drain (created_at);
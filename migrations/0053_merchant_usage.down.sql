@@ -0,0 +1,2 @@
+drop table if exists merchant_usage_monthly;
+drop table if exists merchant_usage;
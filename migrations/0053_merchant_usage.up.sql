@@ -0,0 +1,20 @@
+--- merchant_usage - the upstream calls attributed to each merchant by day
+create table merchant_usage (
+    merchant_id text not null,
+    day date not null,
+    signing_calls bigint not null default 0,
+    tokens_issued bigint not null default 0,
+    redemptions bigint not null default 0,
+    primary key (merchant_id, day)
+);
+
+--- merchant_usage_monthly - merchant_usage rolled up by month once the month is over
+create table merchant_usage_monthly (
+    merchant_id text not null,
+    month date not null,
+    signing_calls bigint not null,
+    tokens_issued bigint not null,
+    redemptions bigint not null,
+    rolled_up_at timestamp with time zone not null default current_timestamp,
+    primary key (merchant_id, month)
+);
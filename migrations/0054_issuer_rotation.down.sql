@@ -0,0 +1,6 @@
+drop index if exists order_cred_issuers_public_key_idx;
+drop index if exists order_cred_issuers_merchant_id_idx;
+drop index if exists order_cred_issuers_merchant_version_idx;
+alter table order_cred_issuers drop column valid_to;
+alter table order_cred_issuers drop column valid_from;
+alter table order_cred_issuers drop column version;
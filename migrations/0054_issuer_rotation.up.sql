@@ -0,0 +1,10 @@
+--- order_cred_issuers - a merchant may hold several versions of its issuer, each signing within its window
+alter table order_cred_issuers add column version integer not null default 1;
+alter table order_cred_issuers add column valid_from timestamp with time zone not null default current_timestamp;
+alter table order_cred_issuers add column valid_to timestamp with time zone;
+update order_cred_issuers set valid_from = created_at;
+
+-- issuers created before rotation are all the first version, so only rotated versions are unique
+create unique index order_cred_issuers_merchant_version_idx on order_cred_issuers (merchant_id, version) where version > 1;
+create index order_cred_issuers_merchant_id_idx on order_cred_issuers (merchant_id);
+create index order_cred_issuers_public_key_idx on order_cred_issuers (public_key);
@@ -0,0 +1,18 @@
+--- order_signing_jobs - the durable queue of order credentials to sign, retried with backoff until signed
+create table order_signing_jobs (
+    item_id uuid primary key not null references order_creds(item_id) on delete cascade,
+    order_id uuid not null references orders(id),
+    status text not null default 'pending',
+    attempts integer not null default 0,
+    visible_at timestamp with time zone not null default current_timestamp,
+    last_error text,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp,
+    constraint order_signing_jobs_status_check check (status in ('pending', 'running', 'signed', 'failed'))
+);
+
+create index order_signing_jobs_visible_at_idx on order_signing_jobs (visible_at) where status in ('pending', 'running');
+
+--- credentials waiting to be signed before the queue existed are queued
+insert into order_signing_jobs (item_id, order_id)
+select item_id, order_id from order_creds where batch_proof is null;
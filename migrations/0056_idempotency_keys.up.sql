@@ -0,0 +1,14 @@
+--- idempotency_keys - keys clients retry requests with, along with the response to replay
+create table idempotency_keys (
+    id uuid primary key not null default uuid_generate_v4(),
+    scope text not null,
+    key text not null,
+    request_hash text not null,
+    status integer,
+    header jsonb,
+    body bytea,
+    created_at timestamp with time zone not null default current_timestamp,
+    unique (scope, key)
+);
+
+create index idempotency_keys_created_at_idx on idempotency_keys (created_at);
@@ -0,0 +1,8 @@
+--- merchant_settings - operator configured limits of a merchant, null columns take the defaults
+create table merchant_settings (
+    merchant_id text primary key not null references merchants(id),
+    max_tokens_per_issuer integer,
+    cred_buffer_size integer,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp
+);
@@ -0,0 +1,18 @@
+--- order_cred_windows - the dated batches of time-limited order credentials, each signed by the issuer of its window
+create table order_cred_windows (
+    item_id uuid not null references order_creds(item_id) on delete cascade,
+    order_id uuid not null references orders(id),
+    issuer_id uuid not null references order_cred_issuers(id),
+    valid_from timestamp with time zone not null,
+    valid_to timestamp with time zone not null,
+    signed_creds json,
+    batch_proof text,
+    public_key text,
+    attempts integer not null default 0,
+    visible_at timestamp with time zone not null default current_timestamp,
+    created_at timestamp with time zone not null default current_timestamp,
+    primary key (item_id, valid_from)
+);
+
+create index order_cred_windows_order_id_idx on order_cred_windows (order_id);
+create index order_cred_windows_unsigned_idx on order_cred_windows (visible_at) where signed_creds is null;
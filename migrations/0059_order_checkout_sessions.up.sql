@@ -0,0 +1,8 @@
+--- order_checkout_sessions - the hosted checkout session an order is paid through, such as a Stripe checkout session
+create table order_checkout_sessions (
+    order_id uuid primary key not null references orders(id),
+    provider text not null,
+    session_id text not null unique,
+    url text not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
@@ -0,0 +1 @@
+drop table if exists credential_redemptions;
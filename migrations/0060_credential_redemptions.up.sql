@@ -0,0 +1,8 @@
+--- credential_redemptions - the credentials redeemed with the challenge bypass server, so repeat submissions
+--- are rejected without calling it
+create table credential_redemptions (
+    token_preimage text primary key not null,
+    issuer_id text not null,
+    payload text not null,
+    redeemed_at timestamp with time zone not null default current_timestamp
+);
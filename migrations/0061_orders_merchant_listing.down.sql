@@ -0,0 +1,2 @@
+drop index if exists orders_merchant_updated_at_idx;
+drop index if exists orders_merchant_created_at_idx;
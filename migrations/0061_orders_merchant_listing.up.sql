@@ -0,0 +1,3 @@
+--- orders are listed by merchant, paged by creation or update time
+create index orders_merchant_created_at_idx on orders(merchant_id, created_at, id);
+create index orders_merchant_updated_at_idx on orders(merchant_id, updated_at, id);
@@ -0,0 +1,6 @@
+drop trigger if exists order_events_record_history on order_events;
+drop trigger if exists order_history_append_only on order_history;
+drop function if exists record_order_history();
+drop function if exists reject_order_history_change();
+drop function if exists order_snapshot(uuid);
+drop table if exists order_history;
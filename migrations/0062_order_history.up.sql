@@ -0,0 +1,51 @@
+--- order_history - the append only audit trail of order transitions, with who made them and the order before and after
+create table order_history (
+    id uuid primary key not null default uuid_generate_v4(),
+    order_id uuid not null references orders(id),
+    sequence integer not null,
+    transition text not null,
+    actor text not null,
+    before jsonb,
+    after jsonb not null,
+    created_at timestamp with time zone not null default current_timestamp,
+    unique (order_id, sequence)
+);
+
+--- order_snapshot is the state of an order recorded in its history, its row and the state of its credentials by item
+create or replace function order_snapshot(id uuid) returns jsonb as $$
+    select to_jsonb(o) - 'event_sequence' || jsonb_build_object('credentials', coalesce((
+        select jsonb_object_agg(c.item_id, case
+            when c.signed_creds is not null or exists (
+                select 1 from order_cred_windows w where w.item_id = c.item_id and w.signed_creds is not null
+            ) then 'signed' else 'requested' end)
+        from order_creds c where c.order_id = o.id), '{}'::jsonb))
+    from orders o where o.id = $1;
+$$ language sql stable;
+
+--- every event appended to the log of an order is a transition, the actor is set per transaction with
+--- set_config('payment.actor', ...) and transitions outside of a request are made by the system
+create or replace function record_order_history() returns trigger as $$
+begin
+    insert into order_history (order_id, sequence, transition, actor, before, after, created_at)
+    values (
+        new.order_id, new.sequence, new.type,
+        coalesce(nullif(current_setting('payment.actor', true), ''), 'system'),
+        (select h.after from order_history h where h.order_id = new.order_id order by h.sequence desc limit 1),
+        order_snapshot(new.order_id),
+        new.created_at
+    );
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger order_events_record_history after insert on order_events
+    for each row execute procedure record_order_history();
+
+create or replace function reject_order_history_change() returns trigger as $$
+begin
+    raise exception 'order_history is append only';
+end;
+$$ language plpgsql;
+
+create trigger order_history_append_only before update or delete on order_history
+    for each row execute procedure reject_order_history_change();
@@ -0,0 +1 @@
+alter table order_cred_issuers drop column if exists disabled_at;
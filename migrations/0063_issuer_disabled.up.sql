@@ -0,0 +1,2 @@
+--- disabled issuers no longer sign credentials, those they signed still redeem
+alter table order_cred_issuers add column disabled_at timestamp with time zone;
@@ -0,0 +1,5 @@
+alter table order_cred_windows
+    alter column signed_creds type json using array_to_json(signed_creds);
+alter table order_creds
+    alter column blinded_creds type json using array_to_json(blinded_creds),
+    alter column signed_creds type json using array_to_json(signed_creds);
@@ -0,0 +1,13 @@
+--- blinded and signed credentials are stored as native text arrays rather than json text
+--- json_text_array converts a json array, a json null is null as there is no array
+create function json_text_array(j json) returns text[] as $$
+    select case when json_typeof(j) = 'array' then array(select json_array_elements_text(j)) end
+$$ language sql immutable strict;
+
+alter table order_creds
+    alter column blinded_creds type text[] using coalesce(json_text_array(blinded_creds), '{}'),
+    alter column signed_creds type text[] using json_text_array(signed_creds);
+alter table order_cred_windows
+    alter column signed_creds type text[] using json_text_array(signed_creds);
+
+drop function json_text_array(json);
@@ -0,0 +1,3 @@
+alter table orders drop column rated_at;
+alter table orders drop column bat_total_price;
+alter table orders drop column exchange_rate;
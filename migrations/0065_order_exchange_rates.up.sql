@@ -0,0 +1,3 @@
+alter table orders add column exchange_rate numeric(28, 18);
+alter table orders add column bat_total_price numeric(28, 18);
+alter table orders add column rated_at timestamp with time zone;
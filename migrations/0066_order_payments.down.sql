@@ -0,0 +1,3 @@
+drop trigger if exists order_payments_append_only on order_payments;
+drop function if exists reject_order_payment_change();
+drop table if exists order_payments;
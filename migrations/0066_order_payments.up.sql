@@ -0,0 +1,27 @@
+--- order_payments is the ledger of the completed payments made towards an order, which may be paid in
+--- installments across several transactions and currencies
+create table order_payments (
+    id uuid primary key not null default uuid_generate_v4(),
+    order_id uuid not null references orders(id),
+    transaction_id uuid not null unique,
+    kind text not null,
+    currency text not null,
+    amount numeric(28, 18) not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
+
+create index order_payments_order_id_idx on order_payments (order_id, created_at);
+
+insert into order_payments (order_id, transaction_id, kind, currency, amount, created_at)
+select order_id, id, kind, currency, amount, created_at
+from transactions
+where status = 'completed' and order_id is not null;
+
+create or replace function reject_order_payment_change() returns trigger as $$
+begin
+    raise exception 'order_payments is append only';
+end;
+$$ language plpgsql;
+
+create trigger order_payments_append_only before update or delete on order_payments
+    for each row execute procedure reject_order_payment_change();
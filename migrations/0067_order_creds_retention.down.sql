@@ -0,0 +1,7 @@
+drop trigger if exists retention_runs_append_only on retention_runs;
+drop function if exists reject_retention_run_change();
+drop table if exists retention_runs;
+alter table order_creds drop column if exists purged_at;
+drop trigger if exists orders_completed_at on orders;
+drop function if exists set_order_completed_at();
+alter table orders drop column if exists completed_at;
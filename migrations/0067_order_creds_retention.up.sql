@@ -0,0 +1,50 @@
+--- completed_at is when an order was first paid, the credentials of an order expire by it
+alter table orders add column completed_at timestamp with time zone;
+
+update orders set completed_at = coalesce((
+    select min(h.created_at) from order_history h
+    where h.order_id = orders.id and h.after->>'status' in ('paid', 'fulfilled')
+), updated_at)
+where status in ('paid', 'fulfilled');
+
+create or replace function set_order_completed_at() returns trigger as $$
+begin
+    new.completed_at := current_timestamp;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger orders_completed_at before insert or update of status on orders
+    for each row when (new.status in ('paid', 'fulfilled') and new.completed_at is null)
+    execute procedure set_order_completed_at();
+
+--- purged_at is when the blinded and signed credentials of an item were purged by retention policy
+alter table order_creds add column purged_at timestamp with time zone;
+
+--- retention_runs is the append only record of retention purges, scheduled or started by an admin
+create table retention_runs (
+    id uuid primary key not null default uuid_generate_v4(),
+    policy text not null,
+    action text not null,
+    trigger text not null,
+    actor text not null,
+    cutoff timestamp with time zone not null,
+    purged_rows bigint not null default 0,
+    batches integer not null default 0,
+    caught_up boolean not null default false,
+    error text,
+    started_at timestamp with time zone not null,
+    finished_at timestamp with time zone not null default current_timestamp,
+    constraint retention_runs_trigger_check check (trigger in ('scheduled', 'manual'))
+);
+
+create index retention_runs_policy_idx on retention_runs (policy, started_at);
+
+create or replace function reject_retention_run_change() returns trigger as $$
+begin
+    raise exception 'retention_runs is append only';
+end;
+$$ language plpgsql;
+
+create trigger retention_runs_append_only before update or delete on retention_runs
+    for each row execute procedure reject_retention_run_change();
@@ -0,0 +1,4 @@
+drop table if exists vote_tally_rollups;
+drop table if exists vote_tallies;
+drop index if exists vote_drain_untallied_idx;
+alter table vote_drain drop column if exists tallied;
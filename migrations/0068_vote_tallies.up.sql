@@ -0,0 +1,33 @@
+--- tallied - whether a processed vote has been rolled up into vote_tallies, votes are only purged once they are
+alter table vote_drain add column tallied boolean not null default false;
+create index vote_drain_untallied_idx on vote_drain (created_at) where processed and not tallied;
+
+--- vote_tallies - processed votes rolled up by channel, day, vote type and funding source
+create table vote_tallies (
+    channel text not null,
+    day date not null,
+    type text not null,
+    funding_source text not null,
+    votes bigint not null,
+    tally bigint not null,
+    updated_at timestamp with time zone not null default current_timestamp,
+    primary key (channel, day, type, funding_source)
+);
+
+create index vote_tallies_day_idx on vote_tallies (day);
+
+--- vote_tally_rollups - the runs rolling votes up into vote_tallies, scheduled or started by an admin
+create table vote_tally_rollups (
+    id uuid primary key not null default uuid_generate_v4(),
+    trigger text not null,
+    actor text not null,
+    votes bigint not null default 0,
+    tallies bigint not null default 0,
+    batches integer not null default 0,
+    error text,
+    started_at timestamp with time zone not null,
+    finished_at timestamp with time zone not null default current_timestamp,
+    constraint vote_tally_rollups_trigger_check check (trigger in ('scheduled', 'manual'))
+);
+
+create index vote_tally_rollups_started_at_idx on vote_tally_rollups (started_at);
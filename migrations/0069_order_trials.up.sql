@@ -0,0 +1,10 @@
+--- order_trials - the zero-price orders whose credentials were issued to a wallet as a trial, counted
+--- against the wallet's trial limit with the merchant
+create table order_trials (
+    order_id uuid primary key references orders(id),
+    wallet_id uuid not null,
+    merchant_id text not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
+
+create index order_trials_wallet_idx on order_trials (wallet_id, merchant_id);
@@ -0,0 +1,7 @@
+--- order_receipts - the signed receipt of a paid order, a JWS of the order, its items and payments
+create table order_receipts (
+    order_id uuid primary key not null references orders(id),
+    key_id text not null,
+    receipt text not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
@@ -0,0 +1,2 @@
+drop table if exists chain_cursors;
+drop table if exists order_deposits;
@@ -0,0 +1,17 @@
+--- order_deposits - the on-chain deposit address of an order paid in BAT, and what it received
+create table order_deposits (
+    order_id uuid primary key not null references orders(id),
+    address text not null unique,
+    status text not null default 'awaiting',
+    received numeric(28, 18) not null default 0,
+    excess numeric(28, 18) not null default 0,
+    created_at timestamp with time zone not null default current_timestamp,
+    updated_at timestamp with time zone not null default current_timestamp
+);
+
+--- chain_cursors - the last block of each chain scanned for deposits
+create table chain_cursors (
+    chain text primary key not null,
+    block bigint not null,
+    updated_at timestamp with time zone not null default current_timestamp
+);
@@ -0,0 +1,4 @@
+alter table merchant_settings drop column if exists rate_limit_per_minute;
+alter table merchant_settings drop column if exists rate_limit_burst;
+alter table merchant_settings drop column if exists ip_rate_limit_per_minute;
+alter table merchant_settings drop column if exists ip_rate_limit_burst;
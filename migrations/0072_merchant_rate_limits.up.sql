@@ -0,0 +1,5 @@
+--- merchant_settings - rate limits of a merchant's credential signing and redemption, null columns take the defaults
+alter table merchant_settings add column rate_limit_per_minute integer check (rate_limit_per_minute > 0);
+alter table merchant_settings add column rate_limit_burst integer check (rate_limit_burst >= 0);
+alter table merchant_settings add column ip_rate_limit_per_minute integer check (ip_rate_limit_per_minute > 0);
+alter table merchant_settings add column ip_rate_limit_burst integer check (ip_rate_limit_burst >= 0);
@@ -0,0 +1,7 @@
+--- order_event_outbox - order events to publish to the message bus, added in the transaction appending the event
+create table order_event_outbox (
+    event_id uuid primary key not null references order_events(id),
+    created_at timestamp with time zone not null default current_timestamp,
+    published_at timestamp with time zone
+);
+create index order_event_outbox_unpublished_idx on order_event_outbox (created_at) where published_at is null;
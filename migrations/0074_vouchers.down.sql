@@ -0,0 +1,2 @@
+drop table if exists voucher_redemptions;
+drop table if exists vouchers;
@@ -0,0 +1,25 @@
+--- vouchers - promo codes discounting the orders of a merchant by a percentage or a fixed amount, until they
+--- are used up or expire
+create table vouchers (
+    id uuid primary key not null default uuid_generate_v4(),
+    code text not null unique,
+    merchant_id text not null,
+    kind text not null check (kind in ('percent', 'fixed')),
+    amount numeric(28, 18) not null check (amount > 0),
+    currency text,
+    max_uses integer check (max_uses > 0),
+    uses integer not null default 0,
+    expires_at timestamp with time zone,
+    created_at timestamp with time zone not null default current_timestamp,
+    check ((kind = 'percent' and amount < 100) or (kind = 'fixed' and currency is not null))
+);
+
+--- voucher_redemptions - the orders discounted by a voucher, and by how much, the order's total being discounted
+create table voucher_redemptions (
+    order_id uuid primary key references orders(id),
+    voucher_id uuid not null references vouchers(id),
+    discount numeric(28, 18) not null,
+    created_at timestamp with time zone not null default current_timestamp
+);
+
+create index voucher_redemptions_voucher_idx on voucher_redemptions (voucher_id);
@@ -0,0 +1 @@
+alter table merchant_settings drop column if exists sku_max_tokens_per_issuer;
@@ -0,0 +1,2 @@
+--- merchant_settings - token caps of the issuers of a merchant's skus, by sku, overriding max_tokens_per_issuer
+alter table merchant_settings add column sku_max_tokens_per_issuer jsonb;
@@ -0,0 +1,8 @@
+alter table merchant_settings drop column if exists order_expiry_minutes;
+drop index if exists orders_pending_updated_at_idx;
+
+update orders set status = 'canceled' where status = 'expired';
+alter table orders drop constraint status_check;
+alter table orders add constraint status_check check (
+    status in ('pending', 'paid', 'fulfilled', 'canceled', 'refunded')
+);
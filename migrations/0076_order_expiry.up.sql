@@ -0,0 +1,9 @@
+--- expired - unpaid orders are expired once they have not changed for their merchant's order expiry
+alter table orders drop constraint status_check;
+alter table orders add constraint status_check check (
+    status in ('pending', 'paid', 'fulfilled', 'canceled', 'refunded', 'expired')
+);
+create index orders_pending_updated_at_idx on orders (updated_at) where status = 'pending';
+
+--- merchant_settings - how long a merchant's unpaid orders last, null columns take the default
+alter table merchant_settings add column order_expiry_minutes integer check (order_expiry_minutes > 0);
@@ -0,0 +1,4 @@
+alter table order_payments disable trigger order_payments_append_only;
+delete from order_payments where transaction_id is null;
+alter table order_payments enable trigger order_payments_append_only;
+alter table order_payments alter column transaction_id set not null;
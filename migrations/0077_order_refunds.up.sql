@@ -0,0 +1,2 @@
+--- order_payments - refunds reverse the payments made towards an order, they are not made by a transaction
+alter table order_payments alter column transaction_id drop not null;
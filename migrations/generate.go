@@ -0,0 +1,52 @@
+// +build ignore
+
+// generate writes the migration files of this directory into bindata.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	names, err := filepath.Glob("*.sql")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by generate.go from the migration files; DO NOT EDIT.\n\n")
+	b.WriteString("package migrations\n\n")
+	b.WriteString("// files are the contents of the migration files by name\n")
+	b.WriteString("var files = map[string]string{\n")
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := string(contents)
+		// raw strings keep the migrations readable, unless they could not hold them
+		if strings.ContainsAny(s, "`\r") {
+			fmt.Fprintf(&b, "%q: %s,\n", name, strconv.Quote(s))
+		} else {
+			fmt.Fprintf(&b, "%q: `%s`,\n", name, s)
+		}
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("bindata.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
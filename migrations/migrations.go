@@ -0,0 +1,37 @@
+// Package migrations embeds the database migrations, so a schema can be migrated without the migration
+// files alongside the binary. Run go generate after adding or changing a migration
+package migrations
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	bindata "github.com/golang-migrate/migrate/v4/source/go_bindata"
+)
+
+//go:generate go run generate.go
+
+// Names returns the names of the embedded migration files, sorted
+func Names() []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Asset returns the contents of an embedded migration file
+func Asset(name string) ([]byte, error) {
+	contents, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("no migration file named %s", name)
+	}
+	return []byte(contents), nil
+}
+
+// Source returns a source of the embedded migrations
+func Source() (source.Driver, error) {
+	return bindata.WithInstance(bindata.Resource(Names(), Asset))
+}
@@ -0,0 +1,47 @@
+package migrations_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrationsAreGenerated(t *testing.T) {
+	names, err := filepath.Glob("*.sql")
+	require.NoError(t, err)
+	require.Equal(t, names, migrations.Names(), "go generate embeds the migrations after they are added")
+
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(name)
+		require.NoError(t, err)
+		embedded, err := migrations.Asset(name)
+		require.NoError(t, err)
+		assert.Equal(t, string(contents), string(embedded), "go generate embeds the migrations after they are changed")
+	}
+}
+
+func TestSource(t *testing.T) {
+	source, err := migrations.Source()
+	require.NoError(t, err)
+	defer func() { _ = source.Close() }()
+
+	version, err := source.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+	for {
+		next, err := source.Next(version)
+		if err != nil {
+			break
+		}
+		assert.Equal(t, version+1, next, "migrations are numbered without gaps")
+		_, _, err = source.ReadDown(next)
+		assert.NoError(t, err, "migration %d can be reverted", next)
+		version = next
+	}
+	assert.Equal(t, grantserver.CurrentMigrationVersion, version, "the code version is the latest migration")
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Router for sending notifications, checking their delivery and managing recipients' opt-outs
+func Router(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("POST", "/", SendNotification(service))
+	r.Method("GET", "/{notificationID}", GetNotification(service))
+	r.Method("GET", "/opt-outs/{recipient}", GetOptOuts(service))
+	r.Method("PUT", "/opt-outs/{recipient}/{kind}", OptOutHandler(service))
+	r.Method("DELETE", "/opt-outs/{recipient}/{kind}", OptInHandler(service))
+	return r
+}
+
+// DeliveryRouter for the status and history of queued deliveries, of webhooks as well as emails
+func DeliveryRouter(queue *Queue) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/{deliveryID}", GetDeliveryStatus(queue))
+	return r
+}
+
+// GetDeliveryStatus is the handler for the status and history of a delivery
+func GetDeliveryStatus(queue *Queue) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"deliveryID": "must be a uuid",
+			})
+		}
+
+		status, err := queue.GetStatus(r.Context(), id)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting delivery", http.StatusInternalServerError)
+		}
+		if status == nil {
+			return &handlers.AppError{
+				Message: "Delivery not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), status, w, http.StatusOK)
+	})
+}
+
+// SendNotificationRequest is a notification to render and send
+type SendNotificationRequest struct {
+	Kind      Kind                   `json:"kind"`
+	Recipient string                 `json:"recipient"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// validationError converts the errors caused by the request into validation errors
+func validationError(err error) *handlers.AppError {
+	switch {
+	case errors.Is(err, ErrUnknownKind):
+		return handlers.ValidationError("request", map[string]interface{}{"kind": err.Error()})
+	case errors.Is(err, ErrInvalidRecipient):
+		return handlers.ValidationError("request", map[string]interface{}{"recipient": err.Error()})
+	case errors.Is(err, ErrInvalidData):
+		return handlers.ValidationError("request", map[string]interface{}{"data": err.Error()})
+	}
+	return nil
+}
+
+// SendNotification is the handler for queueing a notification, which is recorded even when it is not sent
+func SendNotification(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req SendNotificationRequest
+		if err := requestutils.ReadJSON(r.Body, &req); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		notification, err := service.Send(r.Context(), req.Kind, req.Recipient, req.Data)
+		if err != nil {
+			if appErr := validationError(err); appErr != nil {
+				return appErr
+			}
+			return handlers.WrapError(err, "Error sending notification", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), notification, w, http.StatusAccepted)
+	})
+}
+
+// GetNotification is the handler for the delivery status and history of a notification
+func GetNotification(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "notificationID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"notificationID": "must be a uuid",
+			})
+		}
+
+		notification, err := service.GetNotification(r.Context(), id)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting notification", http.StatusInternalServerError)
+		}
+		if notification == nil {
+			return &handlers.AppError{
+				Message: "Notification not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), notification, w, http.StatusOK)
+	})
+}
+
+// recipientParam is the recipient of the request path, which may be escaped
+func recipientParam(r *http.Request) string {
+	recipient := chi.URLParam(r, "recipient")
+	if unescaped, err := url.PathUnescape(recipient); err == nil {
+		return unescaped
+	}
+	return recipient
+}
+
+// GetOptOuts is the handler for listing the kinds of notification a recipient opted out of
+func GetOptOuts(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		optOuts, err := service.GetOptOuts(r.Context(), recipientParam(r))
+		if err != nil {
+			if appErr := validationError(err); appErr != nil {
+				return appErr
+			}
+			return handlers.WrapError(err, "Error getting opt-outs", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), optOuts, w, http.StatusOK)
+	})
+}
+
+// OptOutHandler is the handler for a recipient opting out of a kind of notification, or of all of them
+func OptOutHandler(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		optOut, err := service.OptOut(r.Context(), recipientParam(r), Kind(chi.URLParam(r, "kind")))
+		if err != nil {
+			if appErr := validationError(err); appErr != nil {
+				return appErr
+			}
+			return handlers.WrapError(err, "Error opting out", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), optOut, w, http.StatusOK)
+	})
+}
+
+// OptInHandler is the handler for a recipient resuming a kind of notification they opted out of
+func OptInHandler(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		if err := service.OptIn(r.Context(), recipientParam(r), Kind(chi.URLParam(r, "kind"))); err != nil {
+			if appErr := validationError(err); appErr != nil {
+				return appErr
+			}
+			return handlers.WrapError(err, "Error opting in", http.StatusInternalServerError)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
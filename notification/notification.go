@@ -0,0 +1,263 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Kind is the kind of a notification, each with its own template
+type Kind string
+
+const (
+	// KindOrderReceipt is the receipt sent when an order is paid
+	KindOrderReceipt Kind = "order_receipt"
+	// KindRefundConfirmation confirms an order was refunded
+	KindRefundConfirmation Kind = "refund_confirmation"
+	// KindPayoutSent tells a creator their payout was sent
+	KindPayoutSent Kind = "payout_sent"
+	// KindAll is the opt-out of every kind of notification
+	KindAll Kind = "all"
+)
+
+const (
+	// StatusPending is the status of a notification queued for delivery
+	StatusPending = "pending"
+	// StatusSent is the status of a notification the provider accepted
+	StatusSent = "sent"
+	// StatusFailed is the status of a notification the provider did not accept before its delivery ran out of attempts
+	StatusFailed = "failed"
+	// StatusSuppressed is the status of a notification not sent because the recipient opted out
+	StatusSuppressed = "suppressed"
+)
+
+var (
+	// ErrNotConfigured is the error when no notification provider is configured
+	ErrNotConfigured = errors.New("NOTIFICATION_PROVIDER is not set")
+	// ErrUnknownKind is the error for a kind of notification without a template
+	ErrUnknownKind = errors.New("unknown notification kind")
+	// ErrInvalidData is the error for data a notification's template cannot be rendered with
+	ErrInvalidData = errors.New("invalid notification data")
+	// ErrInvalidRecipient is the error for a recipient which is not an email address
+	ErrInvalidRecipient = errors.New("recipient must be an email address")
+)
+
+var notificationsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "notifications_total",
+		Help: "Notifications by kind and status",
+	},
+	[]string{"kind", "status"},
+)
+
+func init() {
+	prometheus.MustRegister(notificationsCounter)
+}
+
+// Notification is an email sent to a user and its delivery status
+type Notification struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	Kind              Kind      `json:"kind" db:"kind"`
+	Recipient         string    `json:"recipient" db:"recipient"`
+	Subject           string    `json:"subject" db:"subject"`
+	Provider          string    `json:"provider" db:"provider"`
+	ProviderMessageID *string   `json:"providerMessageId" db:"provider_message_id"`
+	Status            string    `json:"status" db:"status"`
+	Error             *string   `json:"error" db:"error"`
+	CreatedAt         time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt         time.Time `json:"updatedAt" db:"updated_at"`
+	// History is the attempts at delivering the notification
+	History []Attempt `json:"history" db:"-"`
+}
+
+// OptOut is a kind of notification a recipient no longer wants
+type OptOut struct {
+	Recipient string    `json:"recipient" db:"recipient"`
+	Kind      Kind      `json:"kind" db:"kind"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Service renders notifications and queues them for delivery with the provider
+type Service struct {
+	store    Store
+	queue    *Queue
+	provider Provider
+	from     string
+}
+
+// New creates a service queueing notifications from the address for delivery with the provider
+func New(store Store, queue *Queue, provider Provider, from string) *Service {
+	service := &Service{store: store, queue: queue, provider: provider, from: from}
+	queue.Register(ChannelEmail, service, DefaultRetryPolicy)
+	return service
+}
+
+// InitService creates a service from the environment, keeping notifications in the database. Notifications
+// are sent with NOTIFICATION_PROVIDER from NOTIFICATION_FROM
+func InitService(db *sqlx.DB, queue *Queue) (*Service, error) {
+	provider, err := NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	from := os.Getenv("NOTIFICATION_FROM")
+	if from == "" {
+		return nil, errors.New("NOTIFICATION_FROM must be set")
+	}
+	return New(NewPostgresStore(db), queue, provider, from), nil
+}
+
+// NormalizeRecipient trims and lowercases an email address, so opt-outs match however it is written
+func NormalizeRecipient(recipient string) (string, error) {
+	recipient = strings.ToLower(strings.TrimSpace(recipient))
+	at := strings.LastIndex(recipient, "@")
+	if at < 1 || at == len(recipient)-1 || strings.ContainsAny(recipient, " \r\n<>") {
+		return "", ErrInvalidRecipient
+	}
+	return recipient, nil
+}
+
+// Send renders the kind of notification with the data and queues it for delivery to the recipient, unless
+// they opted out. The notification is recorded either way, its delivery shares its id
+func (s *Service) Send(ctx context.Context, kind Kind, recipient string, data map[string]interface{}) (*Notification, error) {
+	recipient, err := NormalizeRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	email, err := Render(kind, data)
+	if err != nil {
+		return nil, err
+	}
+	email.From = s.from
+	email.To = recipient
+
+	notification := &Notification{
+		Kind:      kind,
+		Recipient: recipient,
+		Subject:   email.Subject,
+		Provider:  s.provider.Name(),
+		Status:    StatusPending,
+	}
+
+	optedOut, err := s.store.IsOptedOut(ctx, recipient, kind)
+	if err != nil {
+		return nil, err
+	}
+	if optedOut {
+		notification.Status = StatusSuppressed
+	}
+	if err := s.store.InsertNotification(ctx, notification); err != nil {
+		return nil, err
+	}
+	if optedOut {
+		notificationsCounter.With(prometheus.Labels{"kind": string(kind), "status": StatusSuppressed}).Inc()
+		return notification, nil
+	}
+
+	delivery, err := NewDelivery(ChannelEmail, recipient, email)
+	if err != nil {
+		return nil, err
+	}
+	delivery.ID = notification.ID
+	if err := s.queue.Enqueue(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// Deliver sends the email of a queued notification, recording it as sent
+func (s *Service) Deliver(ctx context.Context, delivery *Delivery) (*int, error) {
+	var email Email
+	if err := json.Unmarshal(delivery.Payload, &email); err != nil {
+		return nil, &PermanentError{Err: fmt.Errorf("failed to decode email: %w", err)}
+	}
+	notification, err := s.store.GetNotification(ctx, delivery.ID)
+	if err != nil {
+		return nil, err
+	}
+	if notification == nil {
+		return nil, &PermanentError{Err: fmt.Errorf("notification %s not found", delivery.ID)}
+	}
+
+	messageID, err := s.provider.Send(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	notification.Status = StatusSent
+	notification.Error = nil
+	if messageID != "" {
+		notification.ProviderMessageID = &messageID
+	}
+	notificationsCounter.With(prometheus.Labels{"kind": string(notification.Kind), "status": StatusSent}).Inc()
+
+	if err := s.store.UpdateNotificationStatus(ctx, notification); err != nil {
+		// the email is already sent, so the failure to record it is only logged rather than retried
+		if logger, lerr := appctx.GetLogger(ctx); lerr == nil {
+			logger.Error().Err(err).Str("notification", notification.ID.String()).Msg("failed to record notification status")
+		}
+	}
+	return nil, nil
+}
+
+// DeliveryFailed records a notification as failed once its delivery runs out of attempts
+func (s *Service) DeliveryFailed(ctx context.Context, delivery *Delivery) error {
+	notification, err := s.store.GetNotification(ctx, delivery.ID)
+	if err != nil || notification == nil {
+		return err
+	}
+	notification.Status = StatusFailed
+	notification.Error = delivery.LastError
+	notificationsCounter.With(prometheus.Labels{"kind": string(notification.Kind), "status": StatusFailed}).Inc()
+	return s.store.UpdateNotificationStatus(ctx, notification)
+}
+
+// GetNotification returns a notification by id with the history of its delivery, or nil if there is none
+func (s *Service) GetNotification(ctx context.Context, id uuid.UUID) (*Notification, error) {
+	notification, err := s.store.GetNotification(ctx, id)
+	if err != nil || notification == nil {
+		return nil, err
+	}
+	notification.History, err = s.queue.store.GetAttempts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// OptOut stops the kind of notification, or every kind, being sent to the recipient
+func (s *Service) OptOut(ctx context.Context, recipient string, kind Kind) (*OptOut, error) {
+	recipient, err := NormalizeRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if kind != KindAll && !IsKind(kind) {
+		return nil, ErrUnknownKind
+	}
+	return s.store.InsertOptOut(ctx, recipient, kind)
+}
+
+// OptIn resumes sending the kind of notification the recipient opted out of
+func (s *Service) OptIn(ctx context.Context, recipient string, kind Kind) error {
+	recipient, err := NormalizeRecipient(recipient)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteOptOut(ctx, recipient, kind)
+}
+
+// GetOptOuts returns the kinds of notification the recipient opted out of
+func (s *Service) GetOptOuts(ctx context.Context, recipient string) ([]OptOut, error) {
+	recipient, err := NormalizeRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetOptOuts(ctx, recipient)
+}
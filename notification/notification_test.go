@@ -0,0 +1,362 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu            sync.Mutex
+	notifications map[uuid.UUID]Notification
+	optOuts       map[string]OptOut
+	deliveries    []*Delivery
+	attempts      []Attempt
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{notifications: map[uuid.UUID]Notification{}, optOuts: map[string]OptOut{}}
+}
+
+func (s *memoryStore) InsertDelivery(ctx context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if uuid.Equal(delivery.ID, uuid.Nil) {
+		delivery.ID = uuid.NewV4()
+	}
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = delivery.CreatedAt
+	d := *delivery
+	s.deliveries = append(s.deliveries, &d)
+	return nil
+}
+
+func (s *memoryStore) RunNextDelivery(ctx context.Context, attempt func(context.Context, *Delivery) Attempt) (bool, error) {
+	s.mu.Lock()
+	var due *Delivery
+	for _, d := range s.deliveries {
+		if d.Status == DeliveryPending && !d.NextAttemptAt.After(time.Now()) {
+			due = d
+			break
+		}
+	}
+	s.mu.Unlock()
+	if due == nil {
+		return false, nil
+	}
+	result := attempt(ctx, due)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.ID = uuid.NewV4()
+	result.CreatedAt = time.Now()
+	s.attempts = append(s.attempts, result)
+	return true, nil
+}
+
+func (s *memoryStore) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if uuid.Equal(d.ID, id) {
+			delivery := *d
+			return &delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts := []Attempt{}
+	for _, a := range s.attempts {
+		if uuid.Equal(a.DeliveryID, deliveryID) {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *memoryStore) InsertNotification(ctx context.Context, notification *Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notification.ID = uuid.NewV4()
+	notification.CreatedAt = time.Now()
+	notification.UpdatedAt = notification.CreatedAt
+	s.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (s *memoryStore) UpdateNotificationStatus(ctx context.Context, notification *Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notification.UpdatedAt = time.Now()
+	s.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (s *memoryStore) GetNotification(ctx context.Context, id uuid.UUID) (*Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notification, ok := s.notifications[id]
+	if !ok {
+		return nil, nil
+	}
+	return &notification, nil
+}
+
+func (s *memoryStore) IsOptedOut(ctx context.Context, recipient string, kind Kind) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, kindOptOut := s.optOuts[recipient+"/"+string(kind)]
+	_, allOptOut := s.optOuts[recipient+"/"+string(KindAll)]
+	return kindOptOut || allOptOut, nil
+}
+
+func (s *memoryStore) InsertOptOut(ctx context.Context, recipient string, kind Kind) (*OptOut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	optOut := OptOut{Recipient: recipient, Kind: kind, CreatedAt: time.Now()}
+	s.optOuts[recipient+"/"+string(kind)] = optOut
+	return &optOut, nil
+}
+
+func (s *memoryStore) DeleteOptOut(ctx context.Context, recipient string, kind Kind) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.optOuts, recipient+"/"+string(kind))
+	return nil
+}
+
+func (s *memoryStore) GetOptOuts(ctx context.Context, recipient string) ([]OptOut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	optOuts := []OptOut{}
+	for _, optOut := range s.optOuts {
+		if optOut.Recipient == recipient {
+			optOuts = append(optOuts, optOut)
+		}
+	}
+	return optOuts, nil
+}
+
+type recordingProvider struct {
+	sent  []Email
+	err   error
+	fails int
+}
+
+func (p *recordingProvider) Name() string {
+	return "recording"
+}
+
+func (p *recordingProvider) Send(ctx context.Context, email Email) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.fails > 0 {
+		p.fails--
+		return "", errors.New("unavailable")
+	}
+	p.sent = append(p.sent, email)
+	return "message-id", nil
+}
+
+var receiptData = map[string]interface{}{"orderId": "1234", "total": "5.00", "currency": "BAT"}
+
+func TestRender(t *testing.T) {
+	email, err := Render(KindOrderReceipt, receiptData)
+	require.NoError(t, err)
+	assert.Equal(t, "Your Brave receipt for order 1234", email.Subject)
+	assert.Contains(t, email.Text, "Total: 5.00 BAT")
+	assert.Contains(t, email.HTML, "Total: 5.00 BAT")
+
+	_, err = Render(KindOrderReceipt, map[string]interface{}{"orderId": "1234"})
+	assert.True(t, errors.Is(err, ErrInvalidData), "templates do not render with missing data")
+
+	_, err = Render("newsletter", nil)
+	assert.True(t, errors.Is(err, ErrUnknownKind))
+
+	email, err = Render(KindPayoutSent, map[string]interface{}{"amount": "1", "currency": "BAT", "custodian": "<b>uphold</b>"})
+	require.NoError(t, err)
+	assert.Contains(t, email.HTML, "&lt;b&gt;uphold&lt;/b&gt;", "html emails are escaped")
+}
+
+func TestNormalizeRecipient(t *testing.T) {
+	recipient, err := NormalizeRecipient(" User@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", recipient)
+
+	for _, invalid := range []string{"", "user", "@example.com", "user@", "user@example.com\r\nBcc: other@example.com"} {
+		_, err := NormalizeRecipient(invalid)
+		assert.True(t, errors.Is(err, ErrInvalidRecipient), invalid)
+	}
+}
+
+// deliverAll runs the queue until no delivery is due
+func deliverAll(t *testing.T, queue *Queue) {
+	for {
+		attempted, err := queue.RunNext(context.Background())
+		require.NoError(t, err)
+		if !attempted {
+			return
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	store := newMemoryStore()
+	queue := NewQueue(store)
+	provider := &recordingProvider{}
+	service := New(store, queue, provider, "noreply@brave.com")
+	ctx := context.Background()
+
+	notification, err := service.Send(ctx, KindOrderReceipt, "User@Example.com", receiptData)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, notification.Status, "notifications are queued for delivery")
+	deliverAll(t, queue)
+	require.Len(t, provider.sent, 1)
+	assert.Equal(t, "noreply@brave.com", provider.sent[0].From)
+	assert.Equal(t, "user@example.com", provider.sent[0].To)
+
+	stored, err := service.GetNotification(ctx, notification.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, stored.Status)
+	assert.Equal(t, "message-id", *stored.ProviderMessageID)
+	assert.Len(t, stored.History, 1)
+
+	// opting out of one kind suppresses only that kind
+	_, err = service.OptOut(ctx, "user@example.com", KindOrderReceipt)
+	require.NoError(t, err)
+	notification, err = service.Send(ctx, KindOrderReceipt, "user@example.com", receiptData)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuppressed, notification.Status)
+	_, err = service.Send(ctx, KindPayoutSent, "user@example.com", map[string]interface{}{"amount": "1", "currency": "BAT", "custodian": "uphold"})
+	require.NoError(t, err)
+	deliverAll(t, queue)
+	assert.Len(t, provider.sent, 2)
+
+	// opting out of all suppresses every kind
+	_, err = service.OptOut(ctx, "user@example.com", KindAll)
+	require.NoError(t, err)
+	notification, err = service.Send(ctx, KindPayoutSent, "user@example.com", map[string]interface{}{"amount": "1", "currency": "BAT", "custodian": "uphold"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuppressed, notification.Status)
+	assert.Len(t, provider.sent, 2)
+
+	require.NoError(t, service.OptIn(ctx, "user@example.com", KindAll))
+	require.NoError(t, service.OptIn(ctx, "user@example.com", KindOrderReceipt))
+	notification, err = service.Send(ctx, KindOrderReceipt, "user@example.com", receiptData)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, notification.Status)
+}
+
+func TestSendRetried(t *testing.T) {
+	store := newMemoryStore()
+	queue := NewQueue(store)
+	provider := &recordingProvider{fails: 1}
+	service := New(store, queue, provider, "noreply@brave.com")
+	ctx := context.Background()
+
+	notification, err := service.Send(ctx, KindOrderReceipt, "user@example.com", receiptData)
+	require.NoError(t, err)
+	deliverAll(t, queue)
+	assert.Len(t, provider.sent, 0, "the retry is not due yet")
+
+	status, err := queue.GetStatus(ctx, notification.ID)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryPending, status.Status)
+	assert.Equal(t, "unavailable", *status.LastError)
+	require.NotNil(t, status.NextAttemptAt)
+
+	// make the retry due
+	now := time.Now()
+	store.deliveries[0].NextAttemptAt = &now
+	deliverAll(t, queue)
+	assert.Len(t, provider.sent, 1)
+
+	stored, err := service.GetNotification(ctx, notification.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, stored.Status)
+	require.Len(t, stored.History, 2)
+	assert.False(t, stored.History[0].Succeeded)
+	assert.True(t, stored.History[1].Succeeded)
+}
+
+func TestSendFailed(t *testing.T) {
+	store := newMemoryStore()
+	queue := NewQueue(store)
+	service := New(store, queue, &recordingProvider{err: errors.New("rejected")}, "noreply@brave.com")
+	queue.Register(ChannelEmail, service, RetryPolicy{MaxAttempts: 1})
+
+	notification, err := service.Send(context.Background(), KindOrderReceipt, "user@example.com", receiptData)
+	require.NoError(t, err)
+	deliverAll(t, queue)
+
+	stored, err := service.GetNotification(context.Background(), notification.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, stored.Status, "notifications fail once their delivery runs out of attempts")
+	assert.Equal(t, "rejected", *stored.Error)
+}
+
+func TestRouter(t *testing.T) {
+	store := newMemoryStore()
+	queue := NewQueue(store)
+	service := New(store, queue, &recordingProvider{}, "noreply@brave.com")
+	router := Router(service)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/", `{"kind": "newsletter", "recipient": "user@example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/", `{"kind": "order_receipt", "recipient": "user@example.com", "data": {}}`).Code)
+
+	body, err := json.Marshal(SendNotificationRequest{Kind: KindOrderReceipt, Recipient: "user@example.com", Data: receiptData})
+	require.NoError(t, err)
+	rr := do("POST", "/", string(body))
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	var notification Notification
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &notification))
+	deliverAll(t, queue)
+
+	rr = do("GET", "/"+notification.ID.String(), "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.Contains(rr.Body.String(), `"status":"sent"`))
+	assert.Equal(t, http.StatusNotFound, do("GET", "/"+uuid.NewV4().String(), "").Code)
+
+	assert.Equal(t, http.StatusOK, do("PUT", "/opt-outs/user@example.com/payout_sent", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/opt-outs/user@example.com/newsletter", "").Code)
+	rr = do("GET", "/opt-outs/user%40example.com", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var optOuts []OptOut
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &optOuts))
+	require.Len(t, optOuts, 1)
+	assert.Equal(t, KindPayoutSent, optOuts[0].Kind)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/opt-outs/user@example.com/payout_sent", "").Code)
+
+	deliveries := DeliveryRouter(queue)
+	req := httptest.NewRequest("GET", "/"+notification.ID.String(), nil)
+	rr = httptest.NewRecorder()
+	deliveries.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var status DeliveryStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, DeliveryDelivered, status.Status)
+	assert.Len(t, status.History, 1)
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Email is a rendered notification
+type Email struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Provider sends emails
+type Provider interface {
+	// Name of the provider, recorded with the notifications it sends
+	Name() string
+	// Send the email, returning the id the provider gave it
+	Send(ctx context.Context, email Email) (string, error)
+}
+
+// NewProvider creates the provider named by NOTIFICATION_PROVIDER, one of sendgrid, ses or log
+func NewProvider() (Provider, error) {
+	switch name := os.Getenv("NOTIFICATION_PROVIDER"); name {
+	case "":
+		return nil, ErrNotConfigured
+	case "sendgrid":
+		return NewSendGridProvider()
+	case "ses":
+		return NewSESProvider()
+	case "log":
+		return LogProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification provider %q", name)
+	}
+}
+
+// LogProvider logs emails instead of sending them, for local development
+type LogProvider struct{}
+
+// Name of the provider
+func (LogProvider) Name() string {
+	return "log"
+}
+
+// Send logs the email
+func (LogProvider) Send(ctx context.Context, email Email) (string, error) {
+	id := uuid.NewV4().String()
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		logger.Info().
+			Str("id", id).
+			Str("to", email.To).
+			Str("subject", email.Subject).
+			Msg(email.Text)
+	}
+	return id, nil
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEmail = Email{From: "noreply@brave.com", To: "user@example.com", Subject: "subject", Text: "text", HTML: "<p>html</p>"}
+
+func TestSendGridProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mail/send", r.URL.Path)
+		assert.Equal(t, "Bearer key", r.Header.Get("authorization"))
+		var mail sendGridMail
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&mail))
+		assert.Equal(t, "user@example.com", mail.Personalizations[0].To[0].Email)
+		assert.Equal(t, "text/plain", mail.Content[0].Type)
+		w.Header().Set("X-Message-Id", "sendgrid-id")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	require.NoError(t, os.Setenv("SENDGRID_API_KEY", "key"))
+	require.NoError(t, os.Setenv("SENDGRID_SERVER", server.URL))
+	defer func() {
+		_ = os.Unsetenv("SENDGRID_API_KEY")
+		_ = os.Unsetenv("SENDGRID_SERVER")
+	}()
+	provider, err := NewSendGridProvider()
+	require.NoError(t, err)
+
+	id, err := provider.Send(context.Background(), testEmail)
+	require.NoError(t, err)
+	assert.Equal(t, "sendgrid-id", id)
+}
+
+func TestSESProvider(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/email/outbound-emails", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=access/20210601/us-west-2/ses/aws4_request"))
+		var req sesSendEmailRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []string{"user@example.com"}, req.Destination.ToAddresses)
+		assert.Equal(t, "<p>html</p>", req.Content.Simple.Body.HTML.Data)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"MessageId": "ses-id"}`))
+	}))
+	defer server.Close()
+
+	provider := &SESProvider{
+		Endpoint:    server.URL,
+		Region:      "us-west-2",
+		Credentials: s3.Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+		client:      server.Client(),
+		now:         func() time.Time { return time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	id, err := provider.Send(context.Background(), testEmail)
+	require.NoError(t, err)
+	assert.Equal(t, "ses-id", id)
+
+	status = http.StatusBadRequest
+	_, err = provider.Send(context.Background(), testEmail)
+	assert.Error(t, err)
+}
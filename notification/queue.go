@@ -0,0 +1,274 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// ChannelEmail delivers notification emails
+	ChannelEmail = "email"
+	// ChannelWebhook delivers merchant webhooks
+	ChannelWebhook = "webhook"
+)
+
+const (
+	// DeliveryPending is the status of a delivery still being attempted
+	DeliveryPending = "pending"
+	// DeliveryDelivered is the status of a delivery which succeeded
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed is the status of a delivery which ran out of attempts or cannot succeed
+	DeliveryFailed = "failed"
+)
+
+// DefaultRetryPolicy retries a delivery for about a day
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     6 * time.Hour,
+}
+
+var deliveriesCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "notification_delivery_attempts_total",
+		Help: "Attempts at notification deliveries by channel and result",
+	},
+	[]string{"channel", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(deliveriesCounter)
+}
+
+// Delivery is a queued email or webhook and the state of its delivery
+type Delivery struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Channel       string          `json:"channel" db:"channel"`
+	Destination   string          `json:"destination" db:"destination"`
+	Owner         *string         `json:"owner" db:"owner"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	MaxAttempts   int             `json:"maxAttempts" db:"max_attempts"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastError     *string         `json:"lastError" db:"last_error"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// Attempt is one attempt at a delivery
+type Attempt struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	DeliveryID     uuid.UUID `json:"deliveryId" db:"delivery_id"`
+	Attempt        int       `json:"attempt" db:"attempt"`
+	Succeeded      bool      `json:"succeeded" db:"succeeded"`
+	ResponseStatus *int      `json:"responseStatus" db:"response_status"`
+	Error          *string   `json:"error" db:"error"`
+	DurationMS     int64     `json:"durationMs" db:"duration_ms"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+// DeliveryStatus is a delivery along with the history of its attempts
+type DeliveryStatus struct {
+	Delivery
+	History []Attempt `json:"history"`
+}
+
+// NewDelivery creates a delivery of the payload over the channel to the destination
+func NewDelivery(channel, destination string, payload interface{}) (*Delivery, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", channel, err)
+	}
+	return &Delivery{Channel: channel, Destination: destination, Payload: data}, nil
+}
+
+// RetryPolicy is how often and how long after each failure a delivery is retried
+type RetryPolicy struct {
+	// MaxAttempts is how many times a delivery is attempted before it fails
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled for each retry after
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+}
+
+// Backoff is the delay before retrying a delivery after the attempt failed
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// Deliverer makes the deliveries of a channel
+type Deliverer interface {
+	// Deliver the payload of the delivery, returning the status the destination responded with when there is one
+	Deliver(ctx context.Context, delivery *Delivery) (*int, error)
+}
+
+// FailureHandler is implemented by deliverers which are told when a delivery fails for good
+type FailureHandler interface {
+	DeliveryFailed(ctx context.Context, delivery *Delivery) error
+}
+
+// PermanentError is returned by deliverers when retrying a delivery cannot make it succeed
+type PermanentError struct {
+	Err error
+}
+
+func (pe *PermanentError) Error() string {
+	return pe.Err.Error()
+}
+
+func (pe *PermanentError) Unwrap() error {
+	return pe.Err
+}
+
+// QueueStore keeps queued deliveries and their attempts
+type QueueStore interface {
+	// InsertDelivery queues a delivery, setting its id when unset and its timestamps
+	InsertDelivery(ctx context.Context, delivery *Delivery) error
+	// RunNextDelivery locks the next due delivery and attempts it, recording the attempt and the delivery's
+	// new state. It reports whether there was a delivery due
+	RunNextDelivery(ctx context.Context, attempt func(context.Context, *Delivery) Attempt) (bool, error)
+	// GetDelivery returns a delivery by id, or nil if there is none
+	GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	// GetAttempts returns the attempts at a delivery, oldest first
+	GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error)
+}
+
+type channel struct {
+	deliverer Deliverer
+	policy    RetryPolicy
+}
+
+// Queue delivers emails and webhooks, retrying failed deliveries with exponential backoff
+type Queue struct {
+	store    QueueStore
+	channels map[string]channel
+	now      func() time.Time
+}
+
+// NewQueue creates a queue backed by the store
+func NewQueue(store QueueStore) *Queue {
+	return &Queue{store: store, channels: map[string]channel{}, now: time.Now}
+}
+
+// Register the deliverer of a channel and how its deliveries are retried
+func (q *Queue) Register(name string, deliverer Deliverer, policy RetryPolicy) {
+	q.channels[name] = channel{deliverer: deliverer, policy: policy}
+}
+
+// Jobs delivers queued deliveries as they become due
+func (q *Queue) Jobs() []srv.Job {
+	return []srv.Job{
+		{
+			Name:    "notification_delivery",
+			Service: "notification",
+			Func:    q.RunNext,
+			Cadence: time.Second,
+			Workers: 1,
+		},
+	}
+}
+
+// Enqueue a delivery to be attempted straight away
+func (q *Queue) Enqueue(ctx context.Context, delivery *Delivery) error {
+	ch, ok := q.channels[delivery.Channel]
+	if !ok {
+		return fmt.Errorf("no deliverer registered for channel %s", delivery.Channel)
+	}
+	now := q.now()
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.MaxAttempts = ch.policy.MaxAttempts
+	delivery.NextAttemptAt = &now
+	return q.store.InsertDelivery(ctx, delivery)
+}
+
+// RunNext attempts the next due delivery, reporting whether there was one
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	return q.store.RunNextDelivery(ctx, q.attempt)
+}
+
+// attempt a delivery, updating it with the outcome and scheduling its retry if it failed
+func (q *Queue) attempt(ctx context.Context, delivery *Delivery) Attempt {
+	start := q.now()
+	delivery.Attempts++
+
+	var (
+		status *int
+		err    error
+	)
+	ch, ok := q.channels[delivery.Channel]
+	if ok {
+		status, err = ch.deliverer.Deliver(ctx, delivery)
+	} else {
+		err = &PermanentError{Err: fmt.Errorf("no deliverer registered for channel %s", delivery.Channel)}
+	}
+
+	attempt := Attempt{
+		DeliveryID:     delivery.ID,
+		Attempt:        delivery.Attempts,
+		Succeeded:      err == nil,
+		ResponseStatus: status,
+		DurationMS:     q.now().Sub(start).Milliseconds(),
+	}
+	if err == nil {
+		delivery.Status = DeliveryDelivered
+		delivery.NextAttemptAt = nil
+		delivery.LastError = nil
+		deliveriesCounter.With(prometheus.Labels{"channel": delivery.Channel, "result": "delivered"}).Inc()
+		return attempt
+	}
+
+	msg := err.Error()
+	attempt.Error = &msg
+	delivery.LastError = &msg
+
+	var permanentErr *PermanentError
+	if errors.As(err, &permanentErr) || delivery.Attempts >= delivery.MaxAttempts {
+		delivery.Status = DeliveryFailed
+		delivery.NextAttemptAt = nil
+		deliveriesCounter.With(prometheus.Labels{"channel": delivery.Channel, "result": "failed"}).Inc()
+		if handler, ok := ch.deliverer.(FailureHandler); ok {
+			if err := handler.DeliveryFailed(ctx, delivery); err != nil {
+				if logger, lerr := appctx.GetLogger(ctx); lerr == nil {
+					logger.Error().Err(err).Str("delivery", delivery.ID.String()).Msg("failed to handle failed delivery")
+				}
+			}
+		}
+		return attempt
+	}
+
+	next := q.now().Add(ch.policy.Backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+	deliveriesCounter.With(prometheus.Labels{"channel": delivery.Channel, "result": "retry"}).Inc()
+	return attempt
+}
+
+// GetStatus returns a delivery and the history of its attempts, or nil if there is no such delivery
+func (q *Queue) GetStatus(ctx context.Context, id uuid.UUID) (*DeliveryStatus, error) {
+	delivery, err := q.store.GetDelivery(ctx, id)
+	if err != nil || delivery == nil {
+		return nil, err
+	}
+	attempts, err := q.store.GetAttempts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &DeliveryStatus{Delivery: *delivery, History: attempts}, nil
+}
@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type funcDeliverer struct {
+	deliver func(attempt int) (*int, error)
+	failed  []*Delivery
+}
+
+func (d *funcDeliverer) Deliver(ctx context.Context, delivery *Delivery) (*int, error) {
+	return d.deliver(delivery.Attempts)
+}
+
+func (d *funcDeliverer) DeliveryFailed(ctx context.Context, delivery *Delivery) error {
+	d.failed = append(d.failed, delivery)
+	return nil
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Minute, MaxBackoff: 10 * time.Minute}
+	assert.Equal(t, time.Minute, policy.Backoff(1))
+	assert.Equal(t, 2*time.Minute, policy.Backoff(2))
+	assert.Equal(t, 8*time.Minute, policy.Backoff(4))
+	assert.Equal(t, 10*time.Minute, policy.Backoff(5), "backoff is capped")
+	assert.Equal(t, 10*time.Minute, policy.Backoff(50))
+}
+
+func TestQueueRetriesUntilMaxAttempts(t *testing.T) {
+	queue := NewQueue(newMemoryStore())
+	status := 503
+	deliverer := &funcDeliverer{deliver: func(attempt int) (*int, error) {
+		return &status, errors.New("unavailable")
+	}}
+	queue.Register(ChannelWebhook, deliverer, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	delivery, err := NewDelivery(ChannelWebhook, "https://brave.com/hook", map[string]string{"event": "order.paid"})
+	require.NoError(t, err)
+	require.NoError(t, queue.Enqueue(context.Background(), delivery))
+	assert.Equal(t, 3, delivery.MaxAttempts)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		d := queue.store.(*memoryStore).deliveries[0]
+		queue.attempt(context.Background(), d)
+		if attempt < 3 {
+			assert.Equal(t, DeliveryPending, d.Status)
+			require.NotNil(t, d.NextAttemptAt)
+			assert.True(t, d.NextAttemptAt.After(before.Add(time.Duration(attempt)*time.Minute-time.Second)), "retries back off")
+		} else {
+			assert.Equal(t, DeliveryFailed, d.Status)
+			assert.Nil(t, d.NextAttemptAt)
+		}
+	}
+	assert.Len(t, deliverer.failed, 1, "deliverers are told when a delivery fails for good")
+}
+
+func TestQueuePermanentError(t *testing.T) {
+	queue := NewQueue(newMemoryStore())
+	deliverer := &funcDeliverer{deliver: func(attempt int) (*int, error) {
+		return nil, &PermanentError{Err: errors.New("no secret")}
+	}}
+	queue.Register(ChannelWebhook, deliverer, DefaultRetryPolicy)
+
+	delivery, err := NewDelivery(ChannelWebhook, "https://brave.com/hook", nil)
+	require.NoError(t, err)
+	require.NoError(t, queue.Enqueue(context.Background(), delivery))
+
+	attempted, err := queue.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.True(t, attempted)
+
+	status, err := queue.GetStatus(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryFailed, status.Status, "permanent errors are not retried")
+	require.Len(t, status.History, 1)
+	assert.Equal(t, "no secret", *status.History[0].Error)
+}
+
+func TestQueueUnknownChannel(t *testing.T) {
+	queue := NewQueue(newMemoryStore())
+	delivery, err := NewDelivery("pager", "someone", nil)
+	require.NoError(t, err)
+	assert.Error(t, queue.Enqueue(context.Background(), delivery))
+}
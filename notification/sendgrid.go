@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+)
+
+const defaultSendGridServer = "https://api.sendgrid.com"
+
+// SendGridProvider sends emails with the SendGrid v3 mail send api
+type SendGridProvider struct {
+	client *clients.SimpleHTTPClient
+}
+
+// NewSendGridProvider creates a provider authorized with SENDGRID_API_KEY. SENDGRID_SERVER optionally
+// points it at another server
+func NewSendGridProvider() (*SendGridProvider, error) {
+	apiKey := os.Getenv("SENDGRID_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("SENDGRID_API_KEY must be set")
+	}
+	serverURL := os.Getenv("SENDGRID_SERVER")
+	if serverURL == "" {
+		serverURL = defaultSendGridServer
+	}
+	client, err := clients.New(serverURL, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SendGridProvider{client: client}, nil
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// Name of the provider
+func (p *SendGridProvider) Name() string {
+	return "sendgrid"
+}
+
+// Send the email, returning the message id SendGrid gave it
+func (p *SendGridProvider) Send(ctx context.Context, email Email) (string, error) {
+	mail := sendGridMail{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: email.To}}}},
+		From:             sendGridAddress{Email: email.From},
+		Subject:          email.Subject,
+		// sendgrid requires the plain text content first
+		Content: []sendGridContent{
+			{Type: "text/plain", Value: email.Text},
+			{Type: "text/html", Value: email.HTML},
+		},
+	}
+	req, err := p.client.NewRequest(ctx, "POST", "/v3/mail/send", mail, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.client.Do(ctx, req, nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
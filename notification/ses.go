@@ -0,0 +1,115 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/closers"
+	"github.com/brave-intl/bat-go/utils/s3"
+)
+
+// SESProvider sends emails with the Amazon SES v2 api
+type SESProvider struct {
+	// Endpoint overrides the regional endpoint
+	Endpoint    string
+	Region      string
+	Credentials s3.Credentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewSESProvider creates a provider configured from the standard AWS_* environment variables. SES_ENDPOINT
+// optionally overrides the regional endpoint
+func NewSESProvider() (*SESProvider, error) {
+	region, creds, err := s3.EnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return &SESProvider{
+		Endpoint:    os.Getenv("SES_ENDPOINT"),
+		Region:      region,
+		Credentials: creds,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+	}, nil
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent `json:"Text"`
+				HTML sesContent `json:"Html"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Name of the provider
+func (p *SESProvider) Name() string {
+	return "ses"
+}
+
+// Send the email, returning the message id SES gave it
+func (p *SESProvider) Send(ctx context.Context, email Email) (string, error) {
+	var sendEmail sesSendEmailRequest
+	sendEmail.FromEmailAddress = email.From
+	sendEmail.Destination.ToAddresses = []string{email.To}
+	sendEmail.Content.Simple.Subject = sesContent{Data: email.Subject, Charset: "UTF-8"}
+	sendEmail.Content.Simple.Body.Text = sesContent{Data: email.Text, Charset: "UTF-8"}
+	sendEmail.Content.Simple.Body.HTML = sesContent{Data: email.HTML, Charset: "UTF-8"}
+	body, err := json.Marshal(sendEmail)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", p.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(endpoint, "/")+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s3.Sign(req, body, p.Credentials, p.Region, "ses", p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send email with ses: %w", err)
+	}
+	defer closers.Panic(resp.Body)
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to send email with ses: unexpected status %d: %s", resp.StatusCode, string(data))
+	}
+	var sent struct {
+		MessageID string `json:"MessageId"`
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return "", fmt.Errorf("failed to decode ses response: %w", err)
+	}
+	return sent.MessageID, nil
+}
@@ -0,0 +1,237 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Store keeps notifications and opt-outs
+type Store interface {
+	// InsertNotification records a notification, setting its id and timestamps
+	InsertNotification(ctx context.Context, notification *Notification) error
+	// UpdateNotificationStatus records the status, provider message id and error of a notification
+	UpdateNotificationStatus(ctx context.Context, notification *Notification) error
+	// GetNotification returns a notification by id, or nil if there is none
+	GetNotification(ctx context.Context, id uuid.UUID) (*Notification, error)
+	// IsOptedOut reports whether the recipient opted out of the kind of notification, or of every kind
+	IsOptedOut(ctx context.Context, recipient string, kind Kind) (bool, error)
+	// InsertOptOut records the recipient opting out of the kind of notification
+	InsertOptOut(ctx context.Context, recipient string, kind Kind) (*OptOut, error)
+	// DeleteOptOut removes the recipient's opt-out of the kind of notification
+	DeleteOptOut(ctx context.Context, recipient string, kind Kind) error
+	// GetOptOuts returns the opt-outs of the recipient
+	GetOptOuts(ctx context.Context, recipient string) ([]OptOut, error)
+}
+
+// PostgresStore keeps notifications in the notifications and notification_opt_outs tables
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// InsertNotification records a notification, setting its id and timestamps
+func (s *PostgresStore) InsertNotification(ctx context.Context, notification *Notification) error {
+	err := s.db.QueryRowxContext(ctx, `
+			INSERT INTO notifications (kind, recipient, subject, provider, status)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, updated_at
+		`, notification.Kind, notification.Recipient, notification.Subject, notification.Provider, notification.Status).
+		Scan(&notification.ID, &notification.CreatedAt, &notification.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
+
+// UpdateNotificationStatus records the status, provider message id and error of a notification
+func (s *PostgresStore) UpdateNotificationStatus(ctx context.Context, notification *Notification) error {
+	err := s.db.QueryRowxContext(ctx, `
+			UPDATE notifications
+			SET status = $2, provider_message_id = $3, error = $4, updated_at = current_timestamp
+			WHERE id = $1
+			RETURNING updated_at
+		`, notification.ID, notification.Status, notification.ProviderMessageID, notification.Error).
+		Scan(&notification.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update notification status: %w", err)
+	}
+	return nil
+}
+
+// GetNotification returns a notification by id, or nil if there is none
+func (s *PostgresStore) GetNotification(ctx context.Context, id uuid.UUID) (*Notification, error) {
+	var notification Notification
+	err := s.db.GetContext(ctx, &notification, `
+			SELECT id, kind, recipient, subject, provider, provider_message_id, status, error, created_at, updated_at
+			FROM notifications
+			WHERE id = $1
+		`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return &notification, nil
+}
+
+// IsOptedOut reports whether the recipient opted out of the kind of notification, or of every kind
+func (s *PostgresStore) IsOptedOut(ctx context.Context, recipient string, kind Kind) (bool, error) {
+	var optedOut bool
+	err := s.db.GetContext(ctx, &optedOut, `
+			SELECT exists(
+				SELECT 1 FROM notification_opt_outs
+				WHERE recipient = $1 AND kind IN ($2, $3)
+			)
+		`, recipient, kind, KindAll)
+	if err != nil {
+		return false, fmt.Errorf("failed to check notification opt-outs: %w", err)
+	}
+	return optedOut, nil
+}
+
+// InsertOptOut records the recipient opting out of the kind of notification
+func (s *PostgresStore) InsertOptOut(ctx context.Context, recipient string, kind Kind) (*OptOut, error) {
+	var optOut OptOut
+	err := s.db.GetContext(ctx, &optOut, `
+			INSERT INTO notification_opt_outs (recipient, kind)
+			VALUES ($1, $2)
+			ON CONFLICT (recipient, kind) DO UPDATE SET recipient = excluded.recipient
+			RETURNING recipient, kind, created_at
+		`, recipient, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert notification opt-out: %w", err)
+	}
+	return &optOut, nil
+}
+
+// DeleteOptOut removes the recipient's opt-out of the kind of notification
+func (s *PostgresStore) DeleteOptOut(ctx context.Context, recipient string, kind Kind) error {
+	_, err := s.db.ExecContext(ctx, `
+			DELETE FROM notification_opt_outs
+			WHERE recipient = $1 AND kind = $2
+		`, recipient, kind)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification opt-out: %w", err)
+	}
+	return nil
+}
+
+// GetOptOuts returns the opt-outs of the recipient
+func (s *PostgresStore) GetOptOuts(ctx context.Context, recipient string) ([]OptOut, error) {
+	optOuts := []OptOut{}
+	err := s.db.SelectContext(ctx, &optOuts, `
+			SELECT recipient, kind, created_at
+			FROM notification_opt_outs
+			WHERE recipient = $1
+			ORDER BY kind
+		`, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification opt-outs: %w", err)
+	}
+	return optOuts, nil
+}
+
+// InsertDelivery queues a delivery, setting its id when unset and its timestamps
+func (s *PostgresStore) InsertDelivery(ctx context.Context, delivery *Delivery) error {
+	if uuid.Equal(delivery.ID, uuid.Nil) {
+		delivery.ID = uuid.NewV4()
+	}
+	err := s.db.QueryRowxContext(ctx, `
+			INSERT INTO notification_deliveries
+				(id, channel, destination, owner, payload, status, attempts, max_attempts, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING created_at, updated_at
+		`, delivery.ID, delivery.Channel, delivery.Destination, delivery.Owner, []byte(delivery.Payload),
+		delivery.Status, delivery.Attempts, delivery.MaxAttempts, delivery.NextAttemptAt).
+		Scan(&delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+	return nil
+}
+
+// RunNextDelivery locks the next due delivery and attempts it, recording the attempt and the delivery's
+// new state. It reports whether there was a delivery due
+func (s *PostgresStore) RunNextDelivery(ctx context.Context, attempt func(context.Context, *Delivery) Attempt) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var delivery Delivery
+	err = tx.GetContext(ctx, &delivery, `
+			SELECT id, channel, destination, owner, payload, status, attempts, max_attempts, next_attempt_at,
+				last_error, created_at, updated_at
+			FROM notification_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= current_timestamp
+			ORDER BY next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		`)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get next delivery: %w", err)
+	}
+
+	result := attempt(ctx, &delivery)
+
+	_, err = tx.ExecContext(ctx, `
+			INSERT INTO notification_delivery_attempts
+				(delivery_id, attempt, succeeded, response_status, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, delivery.ID, result.Attempt, result.Succeeded, result.ResponseStatus, result.Error, result.DurationMS)
+	if err != nil {
+		return true, fmt.Errorf("failed to insert delivery attempt: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+			UPDATE notification_deliveries
+			SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, updated_at = current_timestamp
+			WHERE id = $1
+		`, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError)
+	if err != nil {
+		return true, fmt.Errorf("failed to update delivery: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// GetDelivery returns a delivery by id, or nil if there is none
+func (s *PostgresStore) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	var delivery Delivery
+	err := s.db.GetContext(ctx, &delivery, `
+			SELECT id, channel, destination, owner, payload, status, attempts, max_attempts, next_attempt_at,
+				last_error, created_at, updated_at
+			FROM notification_deliveries
+			WHERE id = $1
+		`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// GetAttempts returns the attempts at a delivery, oldest first
+func (s *PostgresStore) GetAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error) {
+	attempts := []Attempt{}
+	err := s.db.SelectContext(ctx, &attempts, `
+			SELECT id, delivery_id, attempt, succeeded, response_status, error, duration_ms, created_at
+			FROM notification_delivery_attempts
+			WHERE delivery_id = $1
+			ORDER BY attempt
+		`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery attempts: %w", err)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+)
+
+// templates of the email for each kind of notification. Templates fail to render when data they use is missing
+var templates = map[Kind]emailTemplate{
+	KindOrderReceipt: newEmailTemplate(KindOrderReceipt,
+		`Your Brave receipt for order {{.orderId}}`,
+		`Thank you for your purchase.
+
+Order: {{.orderId}}
+Total: {{.total}} {{.currency}}
+
+Keep this email for your records.
+`,
+		`<p>Thank you for your purchase.</p>
+<p>Order: {{.orderId}}<br>Total: {{.total}} {{.currency}}</p>
+<p>Keep this email for your records.</p>
+`),
+	KindRefundConfirmation: newEmailTemplate(KindRefundConfirmation,
+		`Your refund for order {{.orderId}}`,
+		`Your order {{.orderId}} was refunded.
+
+Amount: {{.amount}} {{.currency}}
+
+It may take a few days for the refund to reach your account.
+`,
+		`<p>Your order {{.orderId}} was refunded.</p>
+<p>Amount: {{.amount}} {{.currency}}</p>
+<p>It may take a few days for the refund to reach your account.</p>
+`),
+	KindPayoutSent: newEmailTemplate(KindPayoutSent,
+		`Your Brave Rewards payout was sent`,
+		`Your payout of {{.amount}} {{.currency}} was sent to your {{.custodian}} account.
+`,
+		`<p>Your payout of {{.amount}} {{.currency}} was sent to your {{.custodian}} account.</p>
+`),
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+func newEmailTemplate(kind Kind, subject, text, html string) emailTemplate {
+	name := string(kind)
+	return emailTemplate{
+		subject: template.Must(template.New(name + ".subject").Option("missingkey=error").Parse(subject)),
+		text:    template.Must(template.New(name + ".text").Option("missingkey=error").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New(name + ".html").Option("missingkey=error").Parse(html)),
+	}
+}
+
+// IsKind reports whether there is a template for the kind of notification
+func IsKind(kind Kind) bool {
+	_, ok := templates[kind]
+	return ok
+}
+
+// Kinds lists the kinds of notification there are templates for
+func Kinds() []Kind {
+	kinds := make([]Kind, 0, len(templates))
+	for kind := range templates {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// Render the email for the kind of notification with the data, leaving the sender and recipient unset
+func Render(kind Kind, data map[string]interface{}) (Email, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Email{}, ErrUnknownKind
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Email{}, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Email{}, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Email{}, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return Email{
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -1,5 +1,19 @@
 package payment
 
+import (
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+)
+
+func init() {
+	// quarantined votes are decoded with the schema they were produced with
+	if err := kafkautils.RegisterTopic(voteTopic, voteSchema); err != nil {
+		panic(err)
+	}
+	if err := kafkautils.RegisterTopic(orderEventTopic, orderEventSchema); err != nil {
+		panic(err)
+	}
+}
+
 const voteSchema = `{
   "namespace": "brave.payments",
   "type": "record",
@@ -15,3 +29,23 @@ const voteSchema = `{
     { "name": "fundingSource", "type": "string", "default": "uphold" }
   ]
 }`
+
+const orderEventSchema = `{
+  "namespace": "brave.payments",
+  "type": "record",
+  "name": "orderEvent",
+  "doc": "This message is sent when an order is created, paid, has credentials signed or is refunded",
+  "fields": [
+    { "name": "id", "type": "string" },
+    { "name": "type", "type": "string" },
+    { "name": "orderId", "type": "string" },
+    { "name": "sequence", "type": "long" },
+    { "name": "merchantId", "type": "string" },
+    { "name": "status", "type": "string" },
+    { "name": "currency", "type": "string" },
+    { "name": "totalPrice", "type": "string" },
+    { "name": "createdAt", "type": "string" },
+    { "name": "itemId", "type": ["null", "string"], "default": null },
+    { "name": "count", "type": ["null", "long"], "default": null }
+  ]
+}`
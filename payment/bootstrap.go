@@ -0,0 +1,71 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+)
+
+// ErrMerchantExists is returned when bootstrapping a merchant which has already been onboarded
+var ErrMerchantExists = errors.New("merchant already exists")
+
+// MerchantBundle is the configuration a newly onboarded merchant integrates with. It holds the key
+// secret, token and webhook secret, which are not retrievable again
+type MerchantBundle struct {
+	Merchant      *Merchant      `json:"merchant"`
+	Issuer        *Issuer        `json:"issuer"`
+	Key           *Key           `json:"key"`
+	WebhookSecret *WebhookSecret `json:"webhookSecret,omitempty"`
+}
+
+// InitOnboardingService creates a service able to onboard merchants, without the kafka writer and
+// jobs InitService sets up for serving orders
+func InitOnboardingService(datastore Datastore) (*Service, error) {
+	cbClient, err := cbr.New()
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		Datastore: datastore,
+		cbClient:  cbClient,
+	}, nil
+}
+
+// BootstrapMerchant onboards a merchant, creating its record, provisioning its credential issuer and
+// creating an api key granted the scopes. A webhook secret is created when the merchant has webhooks
+func (s *Service) BootstrapMerchant(ctx context.Context, merchant *Merchant, keyName string, scopes []string) (*MerchantBundle, error) {
+	if errs := merchant.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid merchant: %v", errs)
+	}
+	if err := ValidateKeyScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Datastore.GetMerchant(ctx, merchant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrMerchantExists
+	}
+
+	bundle := &MerchantBundle{}
+	if bundle.Merchant, err = s.Datastore.CreateMerchant(ctx, merchant); err != nil {
+		return nil, fmt.Errorf("failed to create merchant: %w", err)
+	}
+	// an issuer left behind by an earlier, failed, bootstrap is reused
+	if bundle.Issuer, err = s.GetOrCreateIssuer(ctx, merchant.ID); err != nil {
+		return nil, fmt.Errorf("failed to provision issuer: %w", err)
+	}
+	if bundle.Key, err = s.CreateKey(ctx, merchant.ID, keyName, scopes); err != nil {
+		return nil, fmt.Errorf("failed to create key: %w", err)
+	}
+	if len(merchant.WebhookURLs) > 0 {
+		if bundle.WebhookSecret, err = s.RotateWebhookSecret(ctx, merchant.ID, 0); err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issuerClient provisions issuers without a challenge bypass server
+type issuerClient struct {
+	cbr.Client
+	created []string
+}
+
+func (c *issuerClient) CreateIssuer(ctx context.Context, issuer string, maxTokens int) error {
+	c.created = append(c.created, issuer)
+	return nil
+}
+
+func (c *issuerClient) GetIssuer(ctx context.Context, issuer string) (*cbr.IssuerResponse, error) {
+	return &cbr.IssuerResponse{Name: issuer, PublicKey: "public-" + issuer}, nil
+}
+
+func TestBootstrapMerchant(t *testing.T) {
+	oldEncryptionKey := EncryptionKey
+	defer func() {
+		EncryptionKey = oldEncryptionKey
+		InitEncryptionKeys()
+	}()
+	EncryptionKey = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0"
+	InitEncryptionKeys()
+
+	ctx := context.Background()
+	datastore := newFakeDatastore()
+	client := &issuerClient{}
+	service := &Service{Datastore: datastore, cbClient: client}
+
+	merchant := &Merchant{
+		ID:          "brave.com",
+		Name:        "Brave",
+		WebhookURLs: []string{"https://brave.com/hook"},
+	}
+	bundle, err := service.BootstrapMerchant(ctx, merchant, "default", []string{KeyScopeTransactionsRead})
+	require.NoError(t, err)
+
+	assert.Equal(t, merchant.ID, bundle.Merchant.ID)
+	assert.Equal(t, merchant.WebhookURLs, bundle.Merchant.WebhookURLs)
+	assert.Equal(t, []string{"brave.com"}, client.created)
+	assert.Equal(t, "public-brave.com", bundle.Issuer.PublicKey)
+	assert.Equal(t, "brave.com", bundle.Key.Merchant)
+	assert.NotEmpty(t, bundle.Key.SecretKey)
+	assert.NotEmpty(t, bundle.Key.Token)
+	require.NotNil(t, bundle.WebhookSecret)
+	assert.NotEmpty(t, bundle.WebhookSecret.Secret)
+
+	// bootstrapping again must not create a second key for the merchant
+	_, err = service.BootstrapMerchant(ctx, merchant, "default", []string{KeyScopeTransactionsRead})
+	assert.Equal(t, ErrMerchantExists, err)
+	assert.Len(t, datastore.keys, 1)
+
+	_, err = service.BootstrapMerchant(ctx, &Merchant{ID: "other.com", Name: "Other"}, "default", []string{"unknown"})
+	assert.Error(t, err)
+	assert.Nil(t, datastore.merchants["other.com"], "invalid scopes should be rejected before onboarding")
+}
@@ -0,0 +1,178 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+)
+
+// defaultMaxBulkOrders is the most orders created by one bulk request, unless set by MAX_BULK_ORDERS
+const defaultMaxBulkOrders = 500
+
+// errBulkOrdersRejected is returned when orders of an atomic bulk request cannot be created, so none are
+var errBulkOrdersRejected = errors.New("orders of the bulk request were rejected")
+
+// maxBulkOrders is the most orders created by one bulk request, from MAX_BULK_ORDERS
+func maxBulkOrders() int {
+	if max, err := strconv.Atoi(os.Getenv("MAX_BULK_ORDERS")); err == nil && max > 0 {
+		return max
+	}
+	return defaultMaxBulkOrders
+}
+
+// CreateBulkOrdersRequest includes the orders created together by a merchant
+type CreateBulkOrdersRequest struct {
+	Orders []CreateOrderRequest `json:"orders" valid:"-"`
+	// AllowPartial creates the orders which are valid when some are not, otherwise none are created
+	AllowPartial bool `json:"allowPartial" valid:"-"`
+}
+
+// BulkOrderResult is the result of one of the orders of a bulk request, the order created or why it was not
+type BulkOrderResult struct {
+	Index int    `json:"index"`
+	Order *Order `json:"order,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// CreateBulkOrdersResponse includes the results of a bulk request, in the order of its orders
+type CreateBulkOrdersResponse struct {
+	Results []BulkOrderResult `json:"results"`
+}
+
+// validateBulkOrder checks an order of a bulk request can be created without a checkout or voucher, which are
+// only supported for orders created one at a time
+func validateBulkOrder(req CreateOrderRequest) error {
+	if len(req.Items) == 0 {
+		return errors.New("array must contain at least one item")
+	}
+	for _, item := range req.Items {
+		if !IsValidSKU(item.SKU) {
+			return fmt.Errorf("invalid SKU token %q", item.SKU)
+		}
+	}
+	if req.PaymentMethod == PaymentMethodStripe {
+		return errors.New("orders paid through stripe cannot be created in bulk")
+	}
+	if req.VoucherCode != "" {
+		return errors.New("vouchers cannot be applied to orders created in bulk")
+	}
+	return nil
+}
+
+// CreateBulkOrders creates the orders of the merchant together, which must be of skus it sells. Orders which
+// are invalid reject the whole request with errBulkOrdersRejected, unless it allows partial failure, when only the valid orders are created
+func (s *Service) CreateBulkOrders(ctx context.Context, merchantID string, req CreateBulkOrdersRequest) ([]BulkOrderResult, error) {
+	merchant, err := s.Datastore.GetMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkOrderResult, len(req.Orders))
+	newOrders := []NewOrder{}
+	indexes := []int{}
+	rejected := false
+	for i, orderReq := range req.Orders {
+		results[i].Index = i
+		err := validateBulkOrder(orderReq)
+		var priced *NewOrder
+		if err == nil {
+			priced, err = s.priceOrder(merchantID, merchant, orderReq)
+		}
+		if err == nil && priced.Location != merchantID {
+			// skus of other merchants are sold through their own bulk requests
+			err = fmt.Errorf("%s is sold by %s: %w", priced.Items[0].SKU, priced.Location, ErrSKUNotAllowed)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			rejected = true
+			continue
+		}
+		newOrders = append(newOrders, *priced)
+		indexes = append(indexes, i)
+	}
+	if rejected && !req.AllowPartial {
+		return results, errBulkOrdersRejected
+	}
+	if len(newOrders) == 0 {
+		return results, nil
+	}
+
+	// the orders share a rate snapshotted once per currency
+	rates := map[string]*ExchangeRate{}
+	for i := range newOrders {
+		currency := newOrders[i].Currency
+		rate, ok := rates[currency]
+		if !ok {
+			if rate, err = s.snapshotExchangeRate(ctx, currency); err != nil {
+				return nil, err
+			}
+			rates[currency] = rate
+		}
+		newOrders[i].Rate = rate
+	}
+
+	orders, err := s.Datastore.CreateOrders(ctx, newOrders)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		results[indexes[i]].Order = &orders[i]
+	}
+	return results, nil
+}
+
+// CreateMerchantOrders is the handler for a merchant creating orders in bulk
+func CreateMerchantOrders(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req CreateBulkOrdersRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		_, err = govalidator.ValidateStruct(req)
+		if err != nil {
+			return handlers.WrapValidationError(err)
+		}
+		if len(req.Orders) == 0 {
+			return handlers.ValidationError(
+				"Error validating request body",
+				map[string]interface{}{
+					"orders": "array must contain at least one order",
+				},
+			)
+		}
+		if max := maxBulkOrders(); len(req.Orders) > max {
+			return handlers.ValidationError(
+				"Error validating request body",
+				map[string]interface{}{
+					"orders": fmt.Sprintf("must have at most %d orders", max),
+				},
+			)
+		}
+
+		results, err := service.CreateBulkOrders(r.Context(), chi.URLParam(r, "merchantID"), req)
+		if errors.Is(err, errBulkOrdersRejected) {
+			errs := map[string]interface{}{}
+			for _, result := range results {
+				if result.Error != "" {
+					errs[fmt.Sprintf("orders[%d]", result.Index)] = result.Error
+				}
+			}
+			return handlers.ValidationError("Error validating request body", errs)
+		}
+		if err != nil {
+			return handlers.WrapError(err, "Error creating the orders in the database", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), CreateBulkOrdersResponse{Results: results}, w, http.StatusCreated)
+	})
+}
@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMerchantOrders(t *testing.T) {
+	ds := newFakeDatastore()
+	r := chi.NewRouter()
+	r.Method("POST", "/{merchantID}/orders/bulk", CreateMerchantOrders(&Service{Datastore: ds}))
+
+	vote := CreateOrderRequest{Items: []OrderItemRequest{{SKU: developmentSKUs[0], Quantity: 2}}}
+	post := func(merchantID string, req CreateBulkOrdersRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("POST", "/"+merchantID+"/orders/bulk", bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := post("brave.com", CreateBulkOrdersRequest{Orders: []CreateOrderRequest{vote, {}, {Items: vote.Items, PaymentMethod: PaymentMethodStripe}}})
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	var appErr handlers.AppError
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &appErr))
+	assert.Equal(t, map[string]interface{}{
+		"validationErrors": map[string]interface{}{
+			"orders[1]": "array must contain at least one item",
+			"orders[2]": "orders paid through stripe cannot be created in bulk",
+		},
+	}, appErr.Data)
+	assert.Empty(t, ds.created, "no orders are created when any are rejected")
+
+	rr = post("brave.com", CreateBulkOrdersRequest{Orders: []CreateOrderRequest{vote, {}, vote}, AllowPartial: true})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var resp CreateBulkOrdersResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+	assert.NotNil(t, resp.Results[0].Order)
+	assert.Equal(t, "brave.com", resp.Results[0].Order.MerchantID)
+	assert.Nil(t, resp.Results[1].Order)
+	assert.Equal(t, "array must contain at least one item", resp.Results[1].Error)
+	assert.Equal(t, 2, resp.Results[2].Index)
+	assert.NotNil(t, resp.Results[2].Order)
+	require.Len(t, ds.created, 1, "the valid orders are created together")
+	assert.Len(t, ds.created[0], 2)
+
+	ds.merchants["brave.com"] = &Merchant{ID: "brave.com", AllowedSKUs: []string{"other"}}
+	rr = post("brave.com", CreateBulkOrdersRequest{Orders: []CreateOrderRequest{vote}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "orders must be of skus the merchant sells")
+	delete(ds.merchants, "brave.com")
+
+	rr = post("other.com", CreateBulkOrdersRequest{Orders: []CreateOrderRequest{vote}, AllowPartial: true})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var other CreateBulkOrdersResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &other))
+	require.Len(t, other.Results, 1)
+	assert.Nil(t, other.Results[0].Order, "orders must be of skus sold by the merchant")
+	assert.Contains(t, other.Results[0].Error, "is sold by brave.com")
+	assert.Len(t, ds.created, 1)
+
+	require.NoError(t, os.Setenv("MAX_BULK_ORDERS", "1"))
+	defer func() { _ = os.Unsetenv("MAX_BULK_ORDERS") }()
+	rr = post("brave.com", CreateBulkOrdersRequest{Orders: []CreateOrderRequest{vote, vote}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Len(t, ds.created, 1)
+}
+
+func TestValuesPlaceholders(t *testing.T) {
+	rows := [][]interface{}{{1, "a"}, {2, "b"}}
+	assert.Equal(t, "($1, $2), ($3, $4)", valuesPlaceholders(rows))
+	assert.Equal(t, []interface{}{1, "a", 2, "b"}, flattenRows(rows))
+
+	many := make([][]interface{}, maxRowsPerInsert+1)
+	chunks := chunkRows(many)
+	require.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], maxRowsPerInsert)
+	assert.Len(t, chunks[1], 1)
+	assert.Empty(t, chunkRows(nil))
+}
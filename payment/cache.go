@@ -0,0 +1,355 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"os"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	cache "github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+var cacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "payment_cache_requests_total",
+		Help: "Reads of cached orders, issuers and merchant settings, by entity and whether they were cached",
+	},
+	[]string{"entity", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequests)
+}
+
+// Cache holds encoded values for a time
+type Cache interface {
+	// Get returns the value of the key, false when it is not cached
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set caches the value of the key for the ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete drops the keys from the cache
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NewCache creates the cache hot reads are served from, when REDIS_URL is set it is shared between instances
+// using redis
+func NewCache(keyPrefix string) Cache {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		return NewMemoryCache()
+	}
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(redisURL)
+		},
+	}
+	return NewRedisCache(pool, keyPrefix)
+}
+
+type redisCache struct {
+	pool      *redis.Pool
+	keyPrefix string
+}
+
+// NewRedisCache creates a cache backed by redis, values expire with their keys
+func NewRedisCache(pool *redis.Pool, keyPrefix string) Cache {
+	return &redisCache{pool: pool, keyPrefix: keyPrefix}
+}
+
+// Get returns the value of the key, false when it is not cached
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	conn := c.pool.Get()
+	defer func() { _ = conn.Close() }()
+
+	value, err := redis.Bytes(conn.Do("GET", c.keyPrefix+key))
+	if err == redis.ErrNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set caches the value of the key for the ttl
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	conn := c.pool.Get()
+	defer func() { _ = conn.Close() }()
+
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := conn.Do("SET", c.keyPrefix+key, value, "PX", ms)
+	return err
+}
+
+// Delete drops the keys from the cache
+func (c *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	conn := c.pool.Get()
+	defer func() { _ = conn.Close() }()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = c.keyPrefix + key
+	}
+	_, err := conn.Do("DEL", args...)
+	return err
+}
+
+type memoryCache struct {
+	values *cache.Cache
+}
+
+// NewMemoryCache creates a cache which is not shared across instances, so their writes do not invalidate it
+func NewMemoryCache() Cache {
+	return &memoryCache{values: cache.New(cache.NoExpiration, 10*time.Minute)}
+}
+
+// Get returns the value of the key, false when it is not cached
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := c.values.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return value.([]byte), true, nil
+}
+
+// Set caches the value of the key for the ttl
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.values.Set(key, value, ttl)
+	return nil
+}
+
+// Delete drops the keys from the cache
+func (c *memoryCache) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.values.Delete(key)
+	}
+	return nil
+}
+
+// cachedSettings is how merchant settings are cached, so merchants without settings are cached too
+type cachedSettings struct {
+	Settings *MerchantSettings
+}
+
+// cachedDatastore serves orders, issuers by public key and merchant settings from the cache, reading them
+// from the datastore it wraps on a miss. The writes made through it refresh or drop what they change, a
+// failure to read or write the cache falls back to the datastore
+type cachedDatastore struct {
+	Datastore
+	cache Cache
+	ttl   time.Duration
+}
+
+// newCachedDatastore returns a datastore caching the hot reads of the datastore for the ttl
+func newCachedDatastore(datastore Datastore, cache Cache, ttl time.Duration) *cachedDatastore {
+	return &cachedDatastore{Datastore: datastore, cache: cache, ttl: ttl}
+}
+
+func orderCacheKey(orderID uuid.UUID) string {
+	return "order:" + orderID.String()
+}
+
+func issuerCacheKey(publicKey string) string {
+	return "issuer:" + publicKey
+}
+
+func merchantSettingsCacheKey(merchantID string) string {
+	return "merchant_settings:" + merchantID
+}
+
+// get decodes the cached value of the key into v, returning whether it was cached
+func (ds *cachedDatastore) get(ctx context.Context, entity, key string, v interface{}) bool {
+	value, ok, err := ds.cache.Get(ctx, key)
+	if err == nil && ok {
+		err = gob.NewDecoder(bytes.NewReader(value)).Decode(v)
+	}
+	switch {
+	case err != nil:
+		cacheRequests.WithLabelValues(entity, "error").Inc()
+		return false
+	case !ok:
+		cacheRequests.WithLabelValues(entity, "miss").Inc()
+		return false
+	}
+	cacheRequests.WithLabelValues(entity, "hit").Inc()
+	return true
+}
+
+// set caches v under the key
+func (ds *cachedDatastore) set(ctx context.Context, entity, key string, v interface{}) {
+	var value bytes.Buffer
+	err := gob.NewEncoder(&value).Encode(v)
+	if err == nil {
+		err = ds.cache.Set(ctx, key, value.Bytes(), ds.ttl)
+	}
+	if err != nil {
+		cacheRequests.WithLabelValues(entity, "error").Inc()
+	}
+}
+
+// invalidate drops the keys, which are read from the datastore again until the ttl has passed if it fails
+func (ds *cachedDatastore) invalidate(ctx context.Context, entity string, keys ...string) {
+	if err := ds.cache.Delete(ctx, keys...); err != nil {
+		cacheRequests.WithLabelValues(entity, "error").Inc()
+	}
+}
+
+// GetOrder from the cache, or from the datastore on a miss. Orders which do not exist are not cached
+func (ds *cachedDatastore) GetOrder(orderID uuid.UUID) (*Order, error) {
+	ctx := context.Background()
+	var order Order
+	if ds.get(ctx, "order", orderCacheKey(orderID), &order) {
+		if order.Items == nil {
+			order.Items = []OrderItem{}
+		}
+		return &order, nil
+	}
+
+	found, err := ds.Datastore.GetOrder(orderID)
+	if err != nil || found == nil {
+		return found, err
+	}
+	ds.set(ctx, "order", orderCacheKey(orderID), found)
+	return found, nil
+}
+
+// refreshOrder caches the order as it is after a write, dropping it if it cannot be read
+func (ds *cachedDatastore) refreshOrder(ctx context.Context, orderID uuid.UUID) {
+	order, err := ds.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		ds.invalidate(ctx, "order", orderCacheKey(orderID))
+		return
+	}
+	ds.set(ctx, "order", orderCacheKey(orderID), order)
+}
+
+// UpdateOrder updates the order and refreshes it in the cache
+func (ds *cachedDatastore) UpdateOrder(ctx context.Context, orderID uuid.UUID, status string) error {
+	if err := ds.Datastore.UpdateOrder(ctx, orderID, status); err != nil {
+		return err
+	}
+	ds.refreshOrder(ctx, orderID)
+	return nil
+}
+
+// RebuildOrderProjection rebuilds the order and refreshes it in the cache
+func (ds *cachedDatastore) RebuildOrderProjection(ctx context.Context, projection *OrderProjection) error {
+	if err := ds.Datastore.RebuildOrderProjection(ctx, projection); err != nil {
+		return err
+	}
+	ds.refreshOrder(ctx, projection.Order.ID)
+	return nil
+}
+
+// GetIssuerByPublicKey from the cache, or from the datastore on a miss. Issuers which do not exist are not
+// cached
+func (ds *cachedDatastore) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
+	ctx := context.Background()
+	var issuer Issuer
+	if ds.get(ctx, "issuer", issuerCacheKey(publicKey), &issuer) {
+		return &issuer, nil
+	}
+
+	found, err := ds.Datastore.GetIssuerByPublicKey(publicKey)
+	if err != nil || found == nil {
+		return found, err
+	}
+	ds.set(ctx, "issuer", issuerCacheKey(publicKey), found)
+	return found, nil
+}
+
+// RotateIssuer rotates the issuer and drops every version of it from the cache, their validity having ended
+func (ds *cachedDatastore) RotateIssuer(ctx context.Context, issuer *Issuer) (*Issuer, error) {
+	rotated, err := ds.Datastore.RotateIssuer(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	issuers, err := ds.Datastore.GetIssuers(ctx, issuer.MerchantID)
+	if err != nil {
+		// the rotation went through, the versions it ended are seen once they expire from the cache
+		cacheRequests.WithLabelValues("issuer", "error").Inc()
+		return rotated, nil
+	}
+	keys := make([]string, len(issuers))
+	for i := range issuers {
+		keys[i] = issuerCacheKey(issuers[i].PublicKey)
+	}
+	ds.invalidate(ctx, "issuer", keys...)
+	return rotated, nil
+}
+
+// DisableIssuer disables the issuer and drops it from the cache
+func (ds *cachedDatastore) DisableIssuer(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	issuer, err := ds.Datastore.DisableIssuer(ctx, id)
+	if err != nil || issuer == nil {
+		return issuer, err
+	}
+	ds.invalidate(ctx, "issuer", issuerCacheKey(issuer.PublicKey))
+	return issuer, nil
+}
+
+// GetMerchantSettings from the cache, or from the datastore on a miss. Merchants without settings are cached
+// as such
+func (ds *cachedDatastore) GetMerchantSettings(ctx context.Context, merchantID string) (*MerchantSettings, error) {
+	var cached cachedSettings
+	if ds.get(ctx, "merchant_settings", merchantSettingsCacheKey(merchantID), &cached) {
+		return cached.Settings, nil
+	}
+
+	settings, err := ds.Datastore.GetMerchantSettings(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	ds.set(ctx, "merchant_settings", merchantSettingsCacheKey(merchantID), cachedSettings{Settings: settings})
+	return settings, nil
+}
+
+// UpsertMerchantSettings sets the settings of the merchant and caches them
+func (ds *cachedDatastore) UpsertMerchantSettings(ctx context.Context, settings *MerchantSettings) (*MerchantSettings, error) {
+	upserted, err := ds.Datastore.UpsertMerchantSettings(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	ds.set(ctx, "merchant_settings", merchantSettingsCacheKey(upserted.MerchantID), cachedSettings{Settings: upserted})
+	return upserted, nil
+}
+
+// cachedReadOnlyDatastore serves orders and issuers of a read only datastore from the cache. Misses are read
+// from the primary, so the cache never holds what the replica has not replicated yet
+type cachedReadOnlyDatastore struct {
+	ReadOnlyDatastore
+	primary *cachedDatastore
+}
+
+// GetOrder from the cache, or from the primary on a miss
+func (ds *cachedReadOnlyDatastore) GetOrder(orderID uuid.UUID) (*Order, error) {
+	return ds.primary.GetOrder(orderID)
+}
+
+// GetIssuerByPublicKey from the cache, or from the primary on a miss
+func (ds *cachedReadOnlyDatastore) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
+	return ds.primary.GetIssuerByPublicKey(publicKey)
+}
+
+// UseCache serves orders, issuers looked up by public key and merchant settings from the cache for the ttl.
+// Writes made through the service refresh what they change, those made elsewhere are seen once the ttl has
+// passed. Call it after UseReadReplica
+func (s *Service) UseCache(cache Cache, ttl time.Duration) {
+	cached := newCachedDatastore(s.Datastore, cache, ttl)
+	s.Datastore = cached
+	if s.RoDatastore != nil {
+		s.RoDatastore = &cachedReadOnlyDatastore{ReadOnlyDatastore: s.RoDatastore, primary: cached}
+	}
+}
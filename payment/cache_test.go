@@ -0,0 +1,141 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/brave-intl/bat-go/utils/datastore"
+	"github.com/gomodule/redigo/redis"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCache(t *testing.T, cache Cache, expire func()) {
+	ctx := context.Background()
+
+	_, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value"), 50*time.Millisecond))
+	require.NoError(t, cache.Set(ctx, "other", []byte("other"), 50*time.Millisecond))
+	value, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	require.NoError(t, cache.Delete(ctx, "key"))
+	_, ok, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted values are dropped")
+
+	expire()
+	_, ok, err = cache.Get(ctx, "other")
+	require.NoError(t, err)
+	assert.False(t, ok, "values expire after their ttl")
+}
+
+func TestMemoryCache(t *testing.T) {
+	testCache(t, NewMemoryCache(), func() { time.Sleep(60 * time.Millisecond) })
+}
+
+func TestRedisCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	pool := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	cache := NewRedisCache(pool, "payment_cache:")
+	require.NoError(t, cache.Set(context.Background(), "kept", []byte("value"), time.Minute))
+	assert.True(t, mr.Exists("payment_cache:kept"))
+	testCache(t, cache, func() { mr.FastForward(time.Second) })
+}
+
+func TestCachedDatastore(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{
+		MerchantID: "brave.com",
+		Status:     "pending",
+		TotalPrice: decimal.NewFromFloat(0.25),
+		Location:   datastore.NullString{},
+		Items:      []OrderItem{},
+	})
+	ds.issuers = []Issuer{{ID: uuid.NewV4(), MerchantID: "brave.com", PublicKey: "key", Version: 1}}
+	service := &Service{Datastore: ds}
+	service.UseCache(NewMemoryCache(), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		cached, err := service.Datastore.GetOrder(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Status, cached.Status)
+		assert.True(t, order.TotalPrice.Equal(cached.TotalPrice))
+		assert.False(t, cached.Location.Valid)
+		assert.Equal(t, []OrderItem{}, cached.Items)
+	}
+	assert.Equal(t, 1, ds.calls["GetOrder"], "orders are read once")
+
+	missing := uuid.NewV4()
+	_, _ = service.Datastore.GetOrder(missing)
+	found, err := service.Datastore.GetOrder(missing)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	assert.Equal(t, 3, ds.calls["GetOrder"], "orders which do not exist are not cached")
+
+	require.NoError(t, service.Datastore.UpdateOrder(ctx, order.ID, "paid"))
+	reads := ds.calls["GetOrder"]
+	updated, err := service.Datastore.GetOrder(order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "paid", updated.Status, "updated orders are refreshed")
+	assert.Equal(t, reads, ds.calls["GetOrder"])
+
+	for i := 0; i < 2; i++ {
+		issuer, err := service.Datastore.GetIssuerByPublicKey("key")
+		require.NoError(t, err)
+		assert.Nil(t, issuer.ValidTo)
+	}
+	assert.Equal(t, 1, ds.calls["GetIssuerByPublicKey"])
+	_, err = service.Datastore.RotateIssuer(ctx, &Issuer{MerchantID: "brave.com", PublicKey: "next", Version: 2})
+	require.NoError(t, err)
+	issuer, err := service.Datastore.GetIssuerByPublicKey("key")
+	require.NoError(t, err)
+	assert.NotNil(t, issuer.ValidTo, "rotated issuers are dropped")
+
+	for i := 0; i < 2; i++ {
+		settings, err := service.Datastore.GetMerchantSettings(ctx, "brave.com")
+		require.NoError(t, err)
+		assert.Nil(t, settings)
+	}
+	assert.Equal(t, 1, ds.calls["GetMerchantSettings"], "merchants without settings are cached")
+	expiry := 60
+	_, err = service.Datastore.UpsertMerchantSettings(ctx, &MerchantSettings{MerchantID: "brave.com", OrderExpiryMinutes: &expiry})
+	require.NoError(t, err)
+	settings, err := service.Datastore.GetMerchantSettings(ctx, "brave.com")
+	require.NoError(t, err)
+	assert.Equal(t, 60, *settings.OrderExpiryMinutes)
+	assert.Equal(t, 1, ds.calls["GetMerchantSettings"])
+}
+
+func TestCachedReadReplica(t *testing.T) {
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "pending"})
+	service := &Service{Datastore: ds}
+	service.UseReadReplica(newFakeDatastore())
+	service.UseCache(NewMemoryCache(), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		found, err := service.ReadableDatastore().GetOrder(order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.ID, found.ID)
+	}
+	assert.Equal(t, 1, ds.calls["GetOrder"], "misses are read from the primary")
+}
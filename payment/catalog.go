@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// skuCatalogSurrogateKey tags every catalog response
+	skuCatalogSurrogateKey = "skus"
+	// skuCatalogCacheControl lets a CDN serve the catalog, it is purged by surrogate key on updates
+	skuCatalogCacheControl = "public, max-age=300, stale-while-revalidate=60"
+)
+
+// skuCatalogCache is shared by every service instance, since merchant updates are made through
+// a different instance than the one serving the catalog
+var skuCatalogCache = middleware.NewResponseCache(5 * time.Minute)
+
+// merchantSurrogateKey tags responses built from the settings of a merchant
+func merchantSurrogateKey(merchantID string) string {
+	return "merchant:" + merchantID
+}
+
+// CatalogSKU is a sku which may be ordered, along with the details encoded in its token
+type CatalogSKU struct {
+	Token          string          `json:"token"`
+	SKU            string          `json:"sku"`
+	MerchantID     string          `json:"merchantId"`
+	Price          decimal.Decimal `json:"price"`
+	Currency       string          `json:"currency"`
+	Description    string          `json:"description,omitempty"`
+	CredentialType string          `json:"credentialType"`
+}
+
+// GetSKUCatalog returns the skus which may be ordered, excluding any their merchant may not sell,
+// along with the merchants whose settings the catalog was built from
+func (s *Service) GetSKUCatalog(ctx context.Context) ([]CatalogSKU, []string, error) {
+	merchants := map[string]*Merchant{}
+	merchantIDs := []string{}
+	catalog := []CatalogSKU{}
+	for _, token := range ValidSKUs() {
+		item, err := CreateOrderItemFromMacaroon(token, 1)
+		if err != nil {
+			// whitelisted skus are not necessarily well formed, they are left out of the catalog
+			continue
+		}
+
+		merchantID := item.Location.String
+		merchant, ok := merchants[merchantID]
+		if !ok {
+			merchant, err = s.Datastore.GetMerchant(ctx, merchantID)
+			if err != nil {
+				return nil, nil, err
+			}
+			merchants[merchantID] = merchant
+			merchantIDs = append(merchantIDs, merchantID)
+		}
+		if merchant != nil && !merchant.AllowsSKU(item.SKU) {
+			continue
+		}
+
+		catalog = append(catalog, CatalogSKU{
+			Token:          token,
+			SKU:            item.SKU,
+			MerchantID:     merchantID,
+			Price:          item.Price,
+			Currency:       item.Currency,
+			Description:    item.Description.String,
+			CredentialType: item.CredentialType,
+		})
+	}
+	return catalog, merchantIDs, nil
+}
+
+// purgeSKUCatalog drops cached catalogs built from the settings of a merchant
+func purgeSKUCatalog(merchantID string) {
+	skuCatalogCache.Purge(merchantSurrogateKey(merchantID))
+}
+
+// SKURouter handles calls for the public sku catalog
+func SKURouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/", middleware.InstrumentHandler("GetSKUCatalog",
+		middleware.ETag(skuCatalogCacheControl)(skuCatalogCache.Middleware(GetSKUCatalog(service)))))
+	return r
+}
+
+// GetSKUCatalog is the handler for listing the skus which may be ordered
+func GetSKUCatalog(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		catalog, merchantIDs, err := service.GetSKUCatalog(r.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the sku catalog", http.StatusInternalServerError)
+		}
+
+		keys := []string{skuCatalogSurrogateKey}
+		for _, merchantID := range merchantIDs {
+			keys = append(keys, merchantSurrogateKey(merchantID))
+		}
+		middleware.AddSurrogateKeys(w, keys...)
+
+		return handlers.RenderContent(r.Context(), catalog, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,33 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSKUCatalog(t *testing.T) {
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds}
+
+	catalog, merchantIDs, err := service.GetSKUCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, catalog, len(developmentSKUs))
+	assert.Equal(t, []string{"brave.com"}, merchantIDs)
+	for _, sku := range catalog {
+		assert.Equal(t, "brave.com", sku.MerchantID)
+		assert.NotEmpty(t, sku.SKU)
+		assert.Equal(t, "BAT", sku.Currency)
+	}
+
+	// merchants may restrict the skus they sell
+	ds.merchants["brave.com"] = &Merchant{ID: "brave.com", AllowedSKUs: []string{"free-trial"}}
+	catalog, _, err = service.GetSKUCatalog(context.Background())
+	require.NoError(t, err)
+	for _, sku := range catalog {
+		assert.Equal(t, "free-trial", sku.SKU)
+	}
+	assert.NotEmpty(t, catalog)
+}
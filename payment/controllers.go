@@ -2,6 +2,7 @@ package payment
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -9,7 +10,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/brave-intl/bat-go/middleware"
@@ -21,85 +22,156 @@ import (
 	"github.com/brave-intl/bat-go/utils/requestutils"
 	"github.com/brave-intl/bat-go/utils/responses"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/cors"
 	uuid "github.com/satori/go.uuid"
 )
 
-func corsMiddleware(allowedMethods []string) func(next http.Handler) http.Handler {
-	debug, err := strconv.ParseBool(os.Getenv("DEBUG"))
-	if err != nil {
-		debug = false
-	}
-	return cors.Handler(cors.Options{
-		Debug:            debug,
-		AllowedOrigins:   strings.Split(os.Getenv("ALLOWED_ORIGINS"), ","),
-		AllowedMethods:   allowedMethods,
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{""},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	})
-}
+// idempotencyKeyTTL is how long the response to a request made with an idempotency key is replayed
+const idempotencyKeyTTL = 24 * time.Hour
 
 // Router for order endpoints
 func Router(service *Service) chi.Router {
 	r := chi.NewRouter()
 
+	// clients retrying orders and credentials with the same Idempotency-Key get the first response
+	idempotent := middleware.Idempotent(service.Datastore, idempotencyKeyTTL)
+
 	if os.Getenv("ENV") == "local" {
-		r.Method("OPTIONS", "/", middleware.InstrumentHandler("CreateOrderOptions", corsMiddleware([]string{"POST"})(nil)))
-		r.Method("POST", "/", middleware.InstrumentHandler("CreateOrder", corsMiddleware([]string{"POST"})(CreateOrder(service))))
+		createOrderCORS := middleware.CORS(middleware.NewCORSConfig("orders", "POST"))
+		r.Method("OPTIONS", "/", middleware.InstrumentHandler("CreateOrderOptions", createOrderCORS(nil)))
+		r.Method("POST", "/", middleware.InstrumentHandler("CreateOrder", createOrderCORS(idempotent(CreateOrder(service)))))
 	} else {
-		r.Method("POST", "/", middleware.InstrumentHandler("CreateOrder", CreateOrder(service)))
+		r.Method("POST", "/", middleware.InstrumentHandler("CreateOrder", idempotent(CreateOrder(service))))
 	}
 
-	r.Method("OPTIONS", "/{orderID}", middleware.InstrumentHandler("GetOrderOptions", corsMiddleware([]string{"GET"})(nil)))
-	r.Method("GET", "/{orderID}", middleware.InstrumentHandler("GetOrder", corsMiddleware([]string{"GET"})(GetOrder(service))))
+	// merchant backends create orders with requests signed by one of their signing keys
+	r.Method("POST", "/signed", middleware.InstrumentHandler("CreateSignedOrder",
+		middleware.HTTPSignedOnly(service, middleware.RequireNonce(service.nonces, signedOrderTTL))(idempotent(CreateSignedOrder(service)))))
+
+	// receipts can be encrypted to the merchant of the order
+	orderJWE := middleware.JWE(service.jweKey, service.orderRecipientKey)
+
+	// clients poll orders and their credentials, cached copies are revalidated on each use
+	orderETag := middleware.ETag("private, no-cache")
 
-	r.Method("GET", "/{orderID}/transactions", middleware.InstrumentHandler("GetTransactions", GetTransactions(service)))
-	r.Method("POST", "/{orderID}/transactions/uphold", middleware.InstrumentHandler("CreateUpholdTransaction", CreateUpholdTransaction(service)))
-	r.Method("POST", "/{orderID}/transactions/anonymousCard", middleware.InstrumentHandler("CreateAnonCardTransaction", CreateAnonCardTransaction(service)))
+	getOrderCORS := middleware.CORS(middleware.NewCORSConfig("orders", "GET"))
+	r.Method("OPTIONS", "/{orderID}", middleware.InstrumentHandler("GetOrderOptions", getOrderCORS(nil)))
+	r.Method("GET", "/{orderID}", middleware.InstrumentHandler("GetOrder", getOrderCORS(orderJWE(orderETag(GetOrder(service))))))
+
+	r.Method("OPTIONS", "/{orderID}/events", middleware.InstrumentHandler("GetOrderEventsOptions", getOrderCORS(nil)))
+	r.Method("GET", "/{orderID}/events", middleware.InstrumentHandler("GetOrderEvents", getOrderCORS(GetOrderEvents(service))))
+
+	// TODO authorization should be merchant specific, as with deleting credentials
+	r.Method("POST", "/{orderID}/cancel", middleware.InstrumentHandler("CancelOrder", middleware.SimpleTokenAuthorizedOnly(CancelOrder(service))))
+	r.Method("POST", "/{orderID}/refund", middleware.InstrumentHandler("RefundOrder", middleware.SimpleTokenAuthorizedOnly(RefundOrder(service))))
+	// clients reactivate their expired orders to pay for them
+	r.Method("POST", "/{orderID}/reactivate", middleware.InstrumentHandler("ReactivateOrder", ReactivateOrder(service)))
+
+	r.Method("GET", "/{orderID}/transactions", middleware.InstrumentHandler("GetTransactions", orderJWE(GetTransactions(service))))
+	r.Method("OPTIONS", "/{orderID}/payments", middleware.InstrumentHandler("GetOrderPaymentsOptions", getOrderCORS(nil)))
+	r.Method("GET", "/{orderID}/payments", middleware.InstrumentHandler("GetOrderPayments", getOrderCORS(orderJWE(GetOrderPayments(service)))))
+	r.Method("OPTIONS", "/{orderID}/receipt", middleware.InstrumentHandler("GetOrderReceiptOptions", getOrderCORS(nil)))
+	r.Method("GET", "/{orderID}/receipt", middleware.InstrumentHandler("GetOrderReceipt", getOrderCORS(GetOrderReceipt(service))))
+	r.Method("POST", "/{orderID}/transactions/uphold", middleware.InstrumentHandler("CreateUpholdTransaction", orderJWE(CreateUpholdTransaction(service))))
+	r.Method("POST", "/{orderID}/transactions/anonymousCard", middleware.InstrumentHandler("CreateAnonCardTransaction", orderJWE(CreateAnonCardTransaction(service))))
+	r.Method("POST", "/{orderID}/transactions/custodian", middleware.InstrumentHandler("CreateCustodianTransaction", walletSigned(service)(orderJWE(CreateCustodianTransaction(service)))))
+	r.Method("POST", "/{orderID}/deposit", middleware.InstrumentHandler("CreateOrderDeposit", orderJWE(CreateOrderDeposit(service))))
+	r.Method("GET", "/{orderID}/deposit", middleware.InstrumentHandler("GetOrderDeposit", orderJWE(GetOrderDeposit(service))))
+
+	// credential signing is rate limited per merchant and per IP address, see the merchant settings
+	credsRateLimited := credentialRateLimited(service, orderMerchant(service))
 
 	r.Route("/{orderID}/credentials", func(cr chi.Router) {
-		cr.Use(corsMiddleware([]string{"GET", "POST"}))
-		cr.Method("POST", "/", middleware.InstrumentHandler("CreateOrderCreds", CreateOrderCreds(service)))
-		cr.Method("GET", "/", middleware.InstrumentHandler("GetOrderCreds", GetOrderCreds(service)))
+		cr.Use(middleware.CORS(middleware.NewCORSConfig("credentials", "GET", "POST")))
+		cr.Method("POST", "/", middleware.InstrumentHandler("CreateOrderCreds", credsRateLimited(walletSigned(service)(idempotent(CreateOrderCreds(service))))))
+		cr.Method("GET", "/", middleware.InstrumentHandler("GetOrderCreds", orderETag(middleware.MessagePack(GetOrderCreds(service)))))
 		// TODO authorization should be merchant specific, however currently this is only used internally
 		cr.Method("DELETE", "/", middleware.InstrumentHandler("DeleteOrderCreds", middleware.SimpleTokenAuthorizedOnly(DeleteOrderCreds(service))))
 
-		cr.Method("GET", "/{itemID}", middleware.InstrumentHandler("GetOrderCredsByID", GetOrderCredsByID(service)))
+		cr.Method("GET", "/ws", middleware.InstrumentHandler("GetOrderCredsSocket", GetOrderCredsSocket(service)))
+		cr.Method("GET", "/events", middleware.InstrumentHandler("GetOrderCredsEvents", GetOrderCredsEvents(service)))
+		cr.Method("GET", "/{itemID}", middleware.InstrumentHandler("GetOrderCredsByID", orderETag(middleware.MessagePack(GetOrderCredsByID(service)))))
 	})
 
 	return r
 }
 
+// WebhookRouter handles webhooks sent by payment providers
+func WebhookRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("POST", "/stripe", middleware.InstrumentHandler("StripeWebhook", StripeWebhook(service)))
+	return r
+}
+
 // CredentialRouter handles calls relating to credentials
 func CredentialRouter(service *Service) chi.Router {
 	r := chi.NewRouter()
-	r.Method("POST", "/subscription/verifications", middleware.InstrumentHandler("VerifyCredential", middleware.SimpleTokenAuthorizedOnly(VerifyCredential(service))))
+	r.Method("POST", "/subscription/verifications", middleware.InstrumentHandler("VerifyCredential",
+		middleware.SimpleTokenAuthorizedOnly(credentialRateLimited(service, bodyMerchant)(VerifyCredential(service)))))
 	return r
 }
 
 // MerchantRouter handles calls made for the merchant
 func MerchantRouter(service *Service) chi.Router {
 	r := chi.NewRouter()
-	if os.Getenv("ENV") != "local" {
-		r.Use(middleware.SimpleTokenAuthorizedOnly)
-	}
 
 	// Once instrument handler is refactored https://github.com/brave-intl/bat-go/issues/291
 	// We can use this service context instead of having
 	r.Use(middleware.NewServiceCtx(service))
+	r.Use(middleware.AuditLog(service.Datastore))
 
 	// RESTy routes for "merchant" resource
 	r.Route("/", func(r chi.Router) {
+		r.Method("GET", "/", operatorAuthorized(service, middleware.InstrumentHandler("GetMerchants", GetMerchants(service))))
+		r.Method("POST", "/", operatorAuthorized(service, middleware.InstrumentHandler("CreateMerchant", CreateMerchant(service))))
 		r.Route("/{merchantID}", func(mr chi.Router) {
+			mr.Method("GET", "/", operatorAuthorized(service, middleware.InstrumentHandler("GetMerchant", GetMerchant(service))))
+			mr.Method("PUT", "/", operatorAuthorized(service, middleware.InstrumentHandler("UpdateMerchant", UpdateMerchant(service))))
+			mr.Method("DELETE", "/", operatorAuthorized(service, middleware.InstrumentHandler("DeleteMerchant", DeleteMerchant(service))))
+			mr.Method("GET", "/settings", operatorAuthorized(service, middleware.InstrumentHandler("GetMerchantSettings", GetMerchantSettings(service))))
+			mr.Method("PUT", "/settings", operatorAuthorized(service, middleware.InstrumentHandler("UpdateMerchantSettings", UpdateMerchantSettings(service))))
 			mr.Route("/keys", func(kr chi.Router) {
-				kr.Method("GET", "/", middleware.InstrumentHandler("GetKeys", GetKeys(service)))
-				kr.Method("POST", "/", middleware.InstrumentHandler("CreateKey", CreateKey(service)))
-				kr.Method("DELETE", "/{id}", middleware.InstrumentHandler("DeleteKey", DeleteKey(service)))
+				kr.Method("GET", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("GetKeys", GetKeys(service))))
+				kr.Method("POST", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("CreateKey", CreateKey(service))))
+				kr.Method("DELETE", "/{id}", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("DeleteKey", DeleteKey(service))))
+				kr.Method("POST", "/{id}/rotate", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("RotateKey", RotateKey(service))))
+				// limits are set by operators, not by the merchant's own keys
+				kr.Method("PUT", "/{id}/rate-limit", operatorAuthorized(service, middleware.InstrumentHandler("UpdateKeyRateLimit", UpdateKeyRateLimit(service))))
 			})
 			mr.Route("/transactions", func(kr chi.Router) {
-				kr.Method("GET", "/", middleware.InstrumentHandler("MerchantTransactions", MerchantTransactions(service)))
+				kr.Method("GET", "/", merchantAuthorized(service, KeyScopeTransactionsRead, middleware.InstrumentHandler("MerchantTransactions",
+					middleware.JWE(service.jweKey, service.merchantRecipientKey)(MerchantTransactions(service)))))
+			})
+			mr.Route("/signing-keys", func(kr chi.Router) {
+				kr.Method("GET", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("GetMerchantSigningKeys", GetMerchantSigningKeys(service))))
+				kr.Method("POST", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("CreateMerchantSigningKey", CreateMerchantSigningKey(service))))
+				kr.Method("DELETE", "/{id}", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("RevokeMerchantSigningKey", RevokeMerchantSigningKey(service))))
+			})
+			mr.Route("/webhooks", func(kr chi.Router) {
+				kr.Method("GET", "/secrets", merchantAuthorized(service, KeyScopeWebhooksManage, middleware.InstrumentHandler("GetWebhookSecrets", GetWebhookSecrets(service))))
+				kr.Method("POST", "/secrets/rotate", merchantAuthorized(service, KeyScopeWebhooksManage, middleware.InstrumentHandler("RotateWebhookSecret", RotateWebhookSecret(service))))
+				kr.Method("GET", "/deliveries", merchantAuthorized(service, KeyScopeWebhooksManage, middleware.InstrumentHandler("GetWebhookDeliveries", GetWebhookDeliveries(service))))
+				kr.Method("GET", "/deliveries/{deliveryID}", merchantAuthorized(service, KeyScopeWebhooksManage, middleware.InstrumentHandler("GetWebhookDeliveryStatus", GetWebhookDeliveryStatus(service))))
+			})
+			// credential issuers are rotated by operators, as clients fetch their keys
+			mr.Method("POST", "/issuers/rotate", operatorAuthorized(service, middleware.InstrumentHandler("RotateIssuer", RotateIssuer(service))))
+			mr.Method("GET", "/usage", merchantAuthorized(service, KeyScopeUsageRead, middleware.InstrumentHandler("GetMerchantUsage", GetMerchantUsage(service))))
+			mr.Route("/orders", func(or chi.Router) {
+				or.Method("GET", "/", merchantAuthorized(service, KeyScopeOrdersRead, middleware.InstrumentHandler("ListMerchantOrders", ListMerchantOrders(service))))
+				or.Method("GET", "/{orderID}/history", merchantAuthorized(service, KeyScopeOrdersRead, middleware.InstrumentHandler("GetMerchantOrderHistory",
+					merchantOrder(service, GetOrderHistory(service)))))
+				// order management requests made with an api key are signed with its secret
+				or.Method("POST", "/bulk", merchantSigned(service, KeyScopeOrdersManage, middleware.InstrumentHandler("CreateMerchantOrders",
+					CreateMerchantOrders(service))))
+				or.Method("POST", "/{orderID}/cancel", merchantSigned(service, KeyScopeOrdersManage, middleware.InstrumentHandler("CancelMerchantOrder",
+					merchantOrder(service, CancelOrder(service)))))
+				or.Method("POST", "/{orderID}/refund", merchantSigned(service, KeyScopeOrdersManage, middleware.InstrumentHandler("RefundMerchantOrder",
+					merchantOrder(service, RefundOrder(service)))))
+			})
+			mr.Method("POST", "/credentials/verify", merchantAuthorized(service, KeyScopeCredentialsVerify, middleware.InstrumentHandler("VerifyMerchantCredentials",
+				credentialRateLimited(service, urlMerchant)(VerifyMerchantCredentials(service)))))
+			mr.Route("/encryption-key", func(kr chi.Router) {
+				kr.Method("GET", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("GetMerchantEncryptionKey", GetMerchantEncryptionKey(service))))
+				kr.Method("PUT", "/", merchantAuthorized(service, KeyScopeKeysManage, middleware.InstrumentHandler("SetMerchantEncryptionKey", SetMerchantEncryptionKey(service))))
 			})
 		})
 	})
@@ -107,6 +179,151 @@ func MerchantRouter(service *Service) chi.Router {
 	return r
 }
 
+const (
+	// ScopeAuditRead is the scope scoped tokens are granted to read the audit log with
+	ScopeAuditRead = "audit:read"
+	// ScopeMerchantsManage is the scope scoped tokens of operators are granted to manage merchants, their
+	// settings, issuers and key rate limits with
+	ScopeMerchantsManage = "merchants:manage"
+)
+
+// AuditRouter handles queries of the audit log, made with the simple and admin tokens or a scoped token
+// granted ScopeAuditRead
+func AuditRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	if os.Getenv("ENV") != "local" {
+		r.Use(middleware.Authenticate(service.scopedTokens))
+		r.Use(middleware.RequireScopes(ScopeAuditRead))
+	}
+	r.Method("GET", "/", middleware.InstrumentHandler("GetAuditEvents", GetAuditEvents(service)))
+	return r
+}
+
+// GetAuditEvents is the handler for listing audit events, filtered with the actor, since and limit query parameters
+func GetAuditEvents(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var (
+			query  = r.URL.Query()
+			actor  = query.Get("actor")
+			since  = time.Time{}
+			limit  = 100
+			errs   = map[string]interface{}{}
+			err    error
+			events []middleware.AuditEvent
+		)
+
+		if v := query.Get("since"); v != "" {
+			if since, err = time.Parse(time.RFC3339, v); err != nil {
+				errs["since"] = "must be an RFC3339 timestamp"
+			}
+		}
+		if v := query.Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > 1000 {
+				errs["limit"] = "must be between 1 and 1000"
+			}
+		}
+		if len(errs) > 0 {
+			return handlers.ValidationError("request query parameters", errs)
+		}
+
+		events, err = service.Datastore.GetAuditEvents(r.Context(), actor, since, limit)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting audit events", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), events, w, http.StatusOK)
+	})
+}
+
+// operatorAuthorized restricts a route to the simple and admin tokens, or to scoped tokens of operators and
+// services granted ScopeMerchantsManage
+func operatorAuthorized(service *Service, next http.Handler) http.Handler {
+	if os.Getenv("ENV") == "local" {
+		return next
+	}
+	return middleware.Authenticate(service.scopedTokens)(
+		middleware.RequireRoles(appctx.RoleAdmin, appctx.RoleService)(
+			middleware.RequireScopes(ScopeMerchantsManage)(next)))
+}
+
+// adminAuthorized restricts a route to the admin tokens, or to scoped tokens of admins
+func adminAuthorized(service *Service, next http.Handler) http.Handler {
+	if os.Getenv("ENV") == "local" {
+		return next
+	}
+	return middleware.Authenticate(service.scopedTokens)(middleware.RequireRoles(appctx.RoleAdmin)(next))
+}
+
+// merchantAuthorized restricts a merchant route to the simple tokens, or to api keys and scoped tokens of that
+// merchant granted the scope, requests made with an api key are subject to the key's rate limits
+func merchantAuthorized(service *Service, scope string, next http.Handler) http.Handler {
+	if os.Getenv("ENV") == "local" {
+		return next
+	}
+	limited := middleware.KeyRateLimiter(context.Background(), service.rateLimitStore, apiKeyID, service.LookupKeyRateLimit)(next)
+	return middleware.Authenticate(service.scopedTokens)(middleware.APIKeyAuthorized(service, scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, err := appctx.GetPrincipal(r.Context()); err == nil && principal.Role == appctx.RoleMerchant &&
+			principal.Merchant != chi.URLParam(r, "merchantID") {
+			handlers.RenderError(w, r, &handlers.AppError{Message: http.StatusText(http.StatusForbidden), Code: http.StatusForbidden})
+			return
+		}
+		limited.ServeHTTP(w, r)
+	})))
+}
+
+// merchantSigned restricts a merchant route like merchantAuthorized, and additionally requires requests made
+// with an api key to be signed with the key's secret
+func merchantSigned(service *Service, scope string, next http.Handler) http.Handler {
+	if os.Getenv("ENV") == "local" {
+		return next
+	}
+	return merchantAuthorized(service, scope, middleware.HMACSignedOnly(service, signedOrderTTL, service.nonces)(next))
+}
+
+// merchantOrder restricts an order route to orders of the merchant, other orders are not found
+func merchantOrder(service *Service, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orderID, err := uuid.FromString(chi.URLParam(r, "orderID"))
+		if err != nil {
+			handlers.RenderError(w, r, ErrOrderNotFound)
+			return
+		}
+		order, err := service.Datastore.GetOrder(orderID)
+		if err != nil {
+			handlers.RenderError(w, r, err)
+			return
+		}
+		if order == nil || order.MerchantID != chi.URLParam(r, "merchantID") {
+			handlers.RenderError(w, r, ErrOrderNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// keyAccessible checks a key belongs to the merchant the request was made for, when it was made by a merchant
+func keyAccessible(r *http.Request, key *Key) bool {
+	principal, err := appctx.GetPrincipal(r.Context())
+	return err != nil || principal.Role != appctx.RoleMerchant || principal.Merchant == key.Merchant
+}
+
+// apiKeyID is the merchant api key a request was authenticated with, requests made with the simple tokens
+// have none
+func apiKeyID(r *http.Request) string {
+	key, ok := middleware.GetAPIKey(r.Context())
+	if !ok {
+		return ""
+	}
+	return key.ID
+}
+
+// UpdateKeyRateLimitRequest includes the limits to apply to a key, omitted limits are removed
+type UpdateKeyRateLimitRequest struct {
+	PerMinute  *int `json:"perMinute" valid:"-"`
+	Burst      int  `json:"burst" valid:"-"`
+	DailyQuota *int `json:"dailyQuota" valid:"-"`
+}
+
 // DeleteKeyRequest includes information needed to delete a key
 type DeleteKeyRequest struct {
 	DelaySeconds int `json:"delaySeconds" valid:"-"`
@@ -114,7 +331,14 @@ type DeleteKeyRequest struct {
 
 // CreateKeyRequest includes information needed to create a key
 type CreateKeyRequest struct {
-	Name string `json:"name" valid:"required"`
+	Name   string   `json:"name" valid:"required"`
+	Scopes []string `json:"scopes" valid:"-"`
+}
+
+// RotateKeyRequest includes information needed to rotate a key
+type RotateKeyRequest struct {
+	// OverlapSeconds is how long the rotated key remains valid
+	OverlapSeconds int `json:"overlapSeconds" valid:"-"`
 }
 
 // CreateKey is the handler for creating keys for a merchant
@@ -128,15 +352,20 @@ func CreateKey(service *Service) handlers.AppHandler {
 			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
 		}
 
-		encrypted, nonce, err := GenerateSecret()
-		if err != nil {
-			return handlers.WrapError(err, "Could not generate a secret key ", http.StatusInternalServerError)
+		if err := ValidateKeyScopes(req.Scopes); err != nil {
+			return handlers.ValidationError("request body", map[string]interface{}{
+				"scopes": err.Error(),
+			})
+		}
+		if req.Scopes == nil {
+			req.Scopes = []string{}
 		}
 
-		key, err := service.Datastore.CreateKey(reqMerchant, req.Name, encrypted, nonce)
+		key, err := service.CreateKey(r.Context(), reqMerchant, req.Name, req.Scopes)
 		if err != nil {
 			return handlers.WrapError(err, "Error create api keys", http.StatusInternalServerError)
 		}
+		middleware.AuditEntity(r.Context(), "key", key.ID)
 
 		return handlers.RenderContent(r.Context(), key, w, http.StatusOK)
 	})
@@ -166,6 +395,19 @@ func DeleteKey(service *Service) handlers.AppHandler {
 			return handlers.WrapValidationError(err)
 		}
 
+		middleware.AuditEntity(r.Context(), "key", id.String())
+
+		existing, err := service.Datastore.GetKey(*id.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting key", http.StatusInternalServerError)
+		}
+		if existing != nil && !keyAccessible(r, existing) {
+			return &handlers.AppError{
+				Message: "Key not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+
 		key, err := service.Datastore.DeleteKey(*id.UUID(), req.DelaySeconds)
 		if err != nil {
 			return handlers.WrapError(err, "Error updating keys for the merchant", http.StatusInternalServerError)
@@ -179,6 +421,353 @@ func DeleteKey(service *Service) handlers.AppHandler {
 	})
 }
 
+// UpdateKeyRateLimit is the handler for configuring the rate limit and daily quota of a key
+func UpdateKeyRateLimit(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var id = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), id, chi.URLParam(r, "id")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"id": err.Error(),
+				},
+			)
+		}
+
+		middleware.AuditEntity(r.Context(), "key", id.String())
+
+		var req UpdateKeyRateLimitRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		validationErrors := map[string]interface{}{}
+		if req.PerMinute != nil && *req.PerMinute <= 0 {
+			validationErrors["perMinute"] = "must be greater than zero"
+		}
+		if req.Burst < 0 {
+			validationErrors["burst"] = "must not be negative"
+		}
+		if req.DailyQuota != nil && *req.DailyQuota <= 0 {
+			validationErrors["dailyQuota"] = "must be greater than zero"
+		}
+		if len(validationErrors) > 0 {
+			return handlers.ValidationError("request body", validationErrors)
+		}
+
+		key, err := service.Datastore.UpdateKeyRateLimit(*id.UUID(), req.PerMinute, req.Burst, req.DailyQuota)
+		if err != nil {
+			return handlers.WrapError(err, "Error updating key rate limit", http.StatusInternalServerError)
+		}
+		status := http.StatusOK
+		if key == nil {
+			status = http.StatusNotFound
+		}
+
+		return handlers.RenderContent(r.Context(), key, w, status)
+	})
+}
+
+// RotateKey is the handler for replacing a key with a new one granted the same scopes
+func RotateKey(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var id = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), id, chi.URLParam(r, "id")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"id": err.Error(),
+				},
+			)
+		}
+
+		var req RotateKeyRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		middleware.AuditEntity(r.Context(), "key", id.String())
+
+		if req.OverlapSeconds < 0 {
+			return handlers.ValidationError("request body", map[string]interface{}{
+				"overlapSeconds": "must not be negative",
+			})
+		}
+
+		existing, err := service.Datastore.GetKey(*id.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting key", http.StatusInternalServerError)
+		}
+		if existing == nil || existing.Merchant != chi.URLParam(r, "merchantID") {
+			return &handlers.AppError{
+				Message: "Key not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+
+		key, err := service.RotateKey(r.Context(), *id.UUID(), req.OverlapSeconds)
+		if err != nil {
+			return handlers.WrapError(err, "Error rotating key", http.StatusInternalServerError)
+		}
+		if key == nil {
+			return &handlers.AppError{
+				Message: "Key not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "rotated_key", key.ID)
+
+		return handlers.RenderContent(r.Context(), key, w, http.StatusOK)
+	})
+}
+
+// RotateIssuerRequest names the issuer of the merchant to rotate
+type RotateIssuerRequest struct {
+	// SKU of the issuer, the merchant's own issuer when empty
+	SKU string `json:"sku" valid:"-"`
+}
+
+// RotateIssuer is the handler for replacing a credential issuer of a merchant with its next version
+func RotateIssuer(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req RotateIssuerRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		issuerID := chi.URLParam(r, "merchantID")
+		if req.SKU != "" {
+			if issuerID, err = encodeIssuerID(issuerID, req.SKU); err != nil {
+				return handlers.WrapError(err, "Error in merchantId or sku", http.StatusBadRequest)
+			}
+		}
+
+		issuer, err := service.RotateIssuer(r.Context(), issuerID)
+		if err != nil {
+			return handlers.WrapError(err, "Error rotating issuer", http.StatusInternalServerError)
+		}
+		middleware.AuditEntity(r.Context(), "issuer", issuer.ID.String())
+
+		return handlers.RenderContent(r.Context(), issuer, w, http.StatusCreated)
+	})
+}
+
+// MerchantRequest includes the settings of a merchant
+type MerchantRequest struct {
+	ID          string   `json:"id" valid:"-"`
+	Name        string   `json:"name" valid:"-"`
+	AllowedSKUs []string `json:"allowedSkus" valid:"-"`
+	WebhookURLs []string `json:"webhookUrls" valid:"-"`
+}
+
+// merchant creates the merchant described by the request
+func (req *MerchantRequest) merchant() *Merchant {
+	merchant := &Merchant{
+		ID:          req.ID,
+		Name:        req.Name,
+		AllowedSKUs: req.AllowedSKUs,
+		WebhookURLs: req.WebhookURLs,
+	}
+	if merchant.AllowedSKUs == nil {
+		merchant.AllowedSKUs = []string{}
+	}
+	if merchant.WebhookURLs == nil {
+		merchant.WebhookURLs = []string{}
+	}
+	return merchant
+}
+
+// CreateMerchant is the handler for onboarding a merchant
+func CreateMerchant(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req MerchantRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		merchant := req.merchant()
+		if errs := merchant.Validate(); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
+
+		existing, err := service.Datastore.GetMerchant(r.Context(), merchant.ID)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant", http.StatusInternalServerError)
+		}
+		if existing != nil {
+			return &handlers.AppError{
+				Message: "Merchant already exists",
+				Code:    http.StatusConflict,
+			}
+		}
+
+		created, err := service.Datastore.CreateMerchant(r.Context(), merchant)
+		if err != nil {
+			return handlers.WrapError(err, "Error creating merchant", http.StatusInternalServerError)
+		}
+		middleware.AuditEntity(r.Context(), "merchant", created.ID)
+		purgeSKUCatalog(created.ID)
+
+		return handlers.RenderContent(r.Context(), created, w, http.StatusCreated)
+	})
+}
+
+// GetMerchants is the handler for listing merchants
+func GetMerchants(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		merchants, err := service.Datastore.GetMerchants(r.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchants", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), merchants, w, http.StatusOK)
+	})
+}
+
+// GetMerchant is the handler for getting a merchant along with its keys and issuer
+func GetMerchant(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		merchantID := chi.URLParam(r, "merchantID")
+
+		merchant, err := service.Datastore.GetMerchant(r.Context(), merchantID)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant", http.StatusInternalServerError)
+		}
+		if merchant == nil {
+			return &handlers.AppError{
+				Message: "Merchant not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+
+		keys, err := service.Datastore.GetKeys(merchantID, false)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting keys for merchant", http.StatusInternalServerError)
+		}
+		issuer, err := service.Datastore.GetIssuer(merchantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the merchant has not issued credentials yet
+			issuer, err = nil, nil
+		}
+		if err != nil {
+			return handlers.WrapError(err, "Error getting issuer for merchant", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), MerchantDetails{
+			Merchant: merchant,
+			Keys:     *keys,
+			Issuer:   issuer,
+		}, w, http.StatusOK)
+	})
+}
+
+// UpdateMerchant is the handler for updating the settings of a merchant
+func UpdateMerchant(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req MerchantRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		req.ID = chi.URLParam(r, "merchantID")
+		merchant := req.merchant()
+		if errs := merchant.Validate(); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
+		middleware.AuditEntity(r.Context(), "merchant", merchant.ID)
+
+		updated, err := service.Datastore.UpdateMerchant(r.Context(), merchant)
+		if err != nil {
+			return handlers.WrapError(err, "Error updating merchant", http.StatusInternalServerError)
+		}
+		if updated == nil {
+			return &handlers.AppError{
+				Message: "Merchant not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		purgeSKUCatalog(updated.ID)
+
+		return handlers.RenderContent(r.Context(), updated, w, http.StatusOK)
+	})
+}
+
+// DeleteMerchant is the handler for removing a merchant
+func DeleteMerchant(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		merchantID := chi.URLParam(r, "merchantID")
+		middleware.AuditEntity(r.Context(), "merchant", merchantID)
+
+		deleted, err := service.Datastore.DeleteMerchant(r.Context(), merchantID)
+		if err != nil {
+			return handlers.WrapError(err, "Error deleting merchant", http.StatusInternalServerError)
+		}
+		if deleted == nil {
+			return &handlers.AppError{
+				Message: "Merchant not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		purgeSKUCatalog(deleted.ID)
+
+		return handlers.RenderContent(r.Context(), deleted, w, http.StatusOK)
+	})
+}
+
+// SetMerchantEncryptionKeyRequest includes the public JSON web key payloads are encrypted to
+type SetMerchantEncryptionKeyRequest struct {
+	JWK json.RawMessage `json:"jwk" valid:"-"`
+}
+
+// SetMerchantEncryptionKey is the handler for registering the key encrypted payloads are sent to the merchant with
+func SetMerchantEncryptionKey(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		merchantID := chi.URLParam(r, "merchantID")
+
+		var req SetMerchantEncryptionKeyRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		if _, err := ParseEncryptionKey(string(req.JWK)); err != nil {
+			return handlers.ValidationError("request body", map[string]interface{}{
+				"jwk": err.Error(),
+			})
+		}
+
+		key, err := service.Datastore.SetMerchantEncryptionKey(merchantID, string(req.JWK))
+		if err != nil {
+			return handlers.WrapError(err, "Error setting merchant encryption key", http.StatusInternalServerError)
+		}
+		middleware.AuditEntity(r.Context(), "merchant", merchantID)
+
+		return handlers.RenderContent(r.Context(), key, w, http.StatusOK)
+	})
+}
+
+// GetMerchantEncryptionKey is the handler for getting the key encrypted payloads are sent to the merchant with
+func GetMerchantEncryptionKey(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		key, err := service.Datastore.GetMerchantEncryptionKey(chi.URLParam(r, "merchantID"))
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant encryption key", http.StatusInternalServerError)
+		}
+		if key == nil {
+			return &handlers.AppError{
+				Message: "Encryption key not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+
+		return handlers.RenderContent(r.Context(), key, w, http.StatusOK)
+	})
+}
+
 // GetKeys returns all keys for a specified merchant
 func GetKeys(service *Service) handlers.AppHandler {
 	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
@@ -212,6 +801,11 @@ type OrderItemRequest struct {
 // CreateOrderRequest includes information needed to create an order
 type CreateOrderRequest struct {
 	Items []OrderItemRequest `json:"items" valid:"-"`
+	// PaymentMethod is stripe for orders paid through a Stripe checkout session, orders are otherwise paid
+	// with BAT transactions
+	PaymentMethod string `json:"paymentMethod" valid:"-"`
+	// VoucherCode is a promo code of the merchant discounting the order's total price
+	VoucherCode string `json:"voucherCode" valid:"-"`
 }
 
 // CreateOrder is the handler for creating a new order
@@ -243,8 +837,11 @@ func CreateOrder(service *Service) handlers.AppHandler {
 			}
 		}
 
-		order, err := service.CreateOrderFromRequest(req)
+		order, err := service.CreateOrderFromRequest(r.Context(), req)
 
+		if errors.Is(err, ErrSKUNotAllowed) {
+			return handlers.WrapError(err, "Invalid SKU Token provided in request", http.StatusBadRequest)
+		}
 		if err != nil {
 			return handlers.WrapError(err, "Error creating the order in the database", http.StatusInternalServerError)
 		}
@@ -266,7 +863,7 @@ func GetOrder(service *Service) handlers.AppHandler {
 			)
 		}
 
-		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		order, err := service.ReadableDatastore().GetOrder(*orderID.UUID())
 		if err != nil {
 			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
 		}
@@ -274,12 +871,111 @@ func GetOrder(service *Service) handlers.AppHandler {
 		status := http.StatusOK
 		if order == nil {
 			status = http.StatusNotFound
+		} else if order.Balance, err = service.GetOrderBalance(r.Context(), order); err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		} else if !order.IsPaid() {
+			// customers who left the checkout page can return to it
+			order.Checkout, err = service.Datastore.GetCheckoutSession(r.Context(), order.ID)
+			if err != nil {
+				return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+			}
 		}
 
 		return handlers.RenderContent(r.Context(), order, w, status)
 	})
 }
 
+// CancelOrder is the handler for canceling an order which has not been paid
+func CancelOrder(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.CancelOrder(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error canceling the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		return handlers.RenderContent(r.Context(), order, w, http.StatusOK)
+	})
+}
+
+// GetOrderEvents is the handler for streaming the status and credential signing progress
+// of an order as server-sent events
+func GetOrderEvents(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return handlers.WrapError(errors.New("response writer does not support flushing"),
+				"Event streams are not supported", http.StatusInternalServerError)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// stop proxies from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		// the request timeout ends the stream, clients reconnect and are sent the current state
+		_, _ = fmt.Fprintf(w, "retry: %d\n\n", orderWatchInterval.Milliseconds())
+		flusher.Flush()
+
+		logger, err := appctx.GetLogger(r.Context())
+		if err != nil {
+			_, logger = logging.SetupLogger(r.Context())
+		}
+		events, errs := service.WatchOrder(r.Context(), order.ID)
+		id := 0
+		for event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to encode order event")
+				continue
+			}
+			id++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+
+		select {
+		case err := <-errs:
+			logger.Error().Err(err).Msg("failed to watch order")
+		default:
+		}
+		return nil
+	})
+}
+
 // GetTransactions is the handler for listing the transactions for an order
 func GetTransactions(service *Service) handlers.AppHandler {
 	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
@@ -342,7 +1038,7 @@ func CreateUpholdTransaction(service *Service) handlers.AppHandler {
 			return handlers.WrapError(err, "Error creating the transaction", http.StatusBadRequest)
 		}
 
-		transaction, err = service.CreateTransactionFromRequest(req, *orderID.UUID())
+		transaction, err = service.CreateTransactionFromRequest(r.Context(), req, *orderID.UUID())
 		if err != nil {
 			return handlers.WrapError(err, "Error creating the transaction", http.StatusBadRequest)
 		}
@@ -389,6 +1085,9 @@ func CreateAnonCardTransaction(service *Service) handlers.AppHandler {
 type CreateOrderCredsRequest struct {
 	ItemID       uuid.UUID `json:"itemId" valid:"-"`
 	BlindedCreds []string  `json:"blindedCreds" valid:"base64"`
+	// WalletID is the wallet the credentials of a trial are issued to, counting against its trial limit. It
+	// must sign the request
+	WalletID *uuid.UUID `json:"walletId,omitempty" valid:"-"`
 }
 
 // CreateOrderCreds is the handler for creating order credentials
@@ -404,6 +1103,9 @@ func CreateOrderCreds(service *Service) handlers.AppHandler {
 		if err != nil {
 			return handlers.WrapValidationError(err)
 		}
+		if errs := validateBlindedCreds("blindedCreds", req.BlindedCreds); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
 
 		var orderID = new(inputs.ID)
 		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
@@ -423,7 +1125,14 @@ func CreateOrderCreds(service *Service) handlers.AppHandler {
 			return handlers.WrapError(err, "There are existing order credentials created for this order", http.StatusConflict)
 		}
 
-		err = service.CreateOrderCreds(r.Context(), *orderID.UUID(), req.ItemID, req.BlindedCreds)
+		if req.WalletID != nil && !isSignedByWallet(r.Context(), *req.WalletID) {
+			return handlers.WrapError(ErrTrialWalletUnsigned, "Error creating order creds", http.StatusUnauthorized)
+		}
+
+		err = service.CreateOrderCreds(r.Context(), *orderID.UUID(), req.ItemID, req.BlindedCreds, req.WalletID)
+		if cbr.IsCircuitOpen(err) {
+			return handlers.WrapError(err, "Credential issuing is temporarily unavailable", http.StatusServiceUnavailable)
+		}
 		if err != nil {
 			return handlers.WrapError(err, "Error creating order creds", http.StatusBadRequest)
 		}
@@ -445,7 +1154,7 @@ func GetOrderCreds(service *Service) handlers.AppHandler {
 			)
 		}
 
-		creds, err := service.Datastore.GetOrderCreds(*orderID.UUID(), false)
+		creds, err := service.ReadableDatastore().GetOrderCreds(*orderID.UUID(), false)
 		if err != nil {
 			return handlers.WrapError(err, "Error getting claim", http.StatusBadRequest)
 		}
@@ -483,7 +1192,7 @@ func DeleteOrderCreds(service *Service) handlers.AppHandler {
 			)
 		}
 
-		err := service.Datastore.DeleteOrderCreds(*orderID.UUID())
+		err := service.Datastore.DeleteOrderCreds(r.Context(), *orderID.UUID())
 		if err != nil {
 			return handlers.WrapError(err, "Error deleting credentials", http.StatusBadRequest)
 		}
@@ -523,7 +1232,7 @@ func GetOrderCredsByID(service *Service) handlers.AppHandler {
 				validationPayload)
 		}
 
-		creds, err := service.Datastore.GetOrderCredsByItemID(*orderID.UUID(), *itemID.UUID(), false)
+		creds, err := service.ReadableDatastore().GetOrderCredsByItemID(*orderID.UUID(), *itemID.UUID(), false)
 		if err != nil {
 			return handlers.WrapError(err, "Error getting claim", http.StatusBadRequest)
 		}
@@ -569,6 +1278,10 @@ func MakeVote(service *Service) handlers.AppHandler {
 		if err != nil {
 			return handlers.WrapValidationError(err)
 		}
+		if errs := validateCredentialBindings("credentials", req.Credentials); len(errs) > 0 {
+			logger.Warn().Int("credentials", len(req.Credentials)).Msg("failed credential validation")
+			return handlers.ValidationError("request body", errs)
+		}
 
 		err = service.Vote(r.Context(), req.Credentials, req.Vote)
 		if err != nil {
@@ -671,7 +1384,7 @@ func VerifyCredential(service *Service) handlers.AppHandler {
 			return handlers.WrapError(err, "Error in request validation", http.StatusBadRequest)
 		}
 
-		if req.Type == "single-use" {
+		if req.Type == "single-use" || req.Type == timeLimitedCredentialType {
 			var bytes []byte
 			bytes, err = base64.StdEncoding.DecodeString(req.Presentation)
 			if err != nil {
@@ -683,20 +1396,63 @@ func VerifyCredential(service *Service) handlers.AppHandler {
 			if err != nil {
 				return handlers.WrapError(err, "Error in presentation formatting", http.StatusBadRequest)
 			}
+			errs := map[string]interface{}{}
+			if msg := base64LengthError(decodedCredential.TokenPreimage, tokenPreimageLength); msg != "" {
+				errs["presentation.t"] = msg
+			}
+			if msg := base64LengthError(decodedCredential.Signature, signatureLength); msg != "" {
+				errs["presentation.signature"] = msg
+			}
+			if len(errs) > 0 {
+				return handlers.ValidationError("request body", errs)
+			}
 
-			// Ensure that the credential being redeemed (opaque to merchant) matches the outer credential details
+			// Ensure that the credential being redeemed (opaque to merchant) matches the outer credential details,
+			// whichever version of the issuer signed it
 			issuerID, err := encodeIssuerID(req.MerchantID, req.SKU)
 			if err != nil {
 				return handlers.WrapError(err, "Error in outer merchantId or sku", http.StatusBadRequest)
 			}
-			if issuerID != decodedCredential.Issuer {
+			merchantID, sku, err := decodeIssuerID(decodedCredential.Issuer)
+			if err != nil {
+				return handlers.WrapError(err, "Error in presentation issuer", http.StatusBadRequest)
+			}
+			if credentialIssuerID, err := encodeIssuerID(merchantID, sku); err != nil || issuerID != credentialIssuerID {
 				return handlers.WrapError(nil, "Error, outer merchant and sku don't match issuer", http.StatusBadRequest)
 			}
+			// time-limited credentials are signed by the issuer of their window, and only redeem within it
+			if _, windowed := decodeIssuerWindow(decodedCredential.Issuer); windowed != (req.Type == timeLimitedCredentialType) {
+				return handlers.WrapError(nil, "Error, credential type doesn't match issuer", http.StatusBadRequest)
+			}
+			if !credentialWindowOpen(decodedCredential.Issuer, time.Now()) {
+				return handlers.WrapError(nil, "Error, credential is outside its validity window", http.StatusBadRequest)
+			}
+
+			err = checkCredentialsRedeemed(r.Context(), service.ReadableDatastore(), decodedCredential.TokenPreimage)
+			if err != nil {
+				return handlers.WrapError(err, "Error verifying credentials", http.StatusInternalServerError)
+			}
 
 			err = service.cbClient.RedeemCredential(r.Context(), decodedCredential.Issuer, decodedCredential.TokenPreimage, decodedCredential.Signature, decodedCredential.Issuer)
+			if cbr.IsCircuitOpen(err) {
+				return handlers.WrapError(err, "Credential verification is temporarily unavailable", http.StatusServiceUnavailable)
+			}
+			if cbr.IsDuplicateRedemption(err) {
+				return handlers.WrapError(ErrCredentialRedeemed.Wrap(err), "Error verifying credentials", http.StatusConflict)
+			}
 			if err != nil {
 				return handlers.WrapError(err, "Error verifying credentials", http.StatusInternalServerError)
 			}
+			service.recordRedemptions(r.Context(), req.MerchantID, 1)
+			redeemed := newRedeemedCredentials([]cbr.CredentialRedemption{decodedCredential}, decodedCredential.Issuer)
+			if err := service.Datastore.InsertCredentialRedemptions(r.Context(), nil, redeemed); err != nil {
+				// the challenge bypass server still rejects the credential if it is submitted again
+				logger, lerr := appctx.GetLogger(r.Context())
+				if lerr != nil {
+					_, logger = logging.SetupLogger(r.Context())
+				}
+				logger.Warn().Err(err).Msg("failed to record credential redemption")
+			}
 
 			return handlers.RenderContent(r.Context(), "Credentials successfully verified", w, http.StatusOK)
 		}
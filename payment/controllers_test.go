@@ -18,11 +18,13 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/altcurrency"
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	mockcb "github.com/brave-intl/bat-go/utils/clients/cbr/mock"
 	"github.com/brave-intl/bat-go/utils/httpsignature"
 	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/test/fixtures"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
 	"github.com/brave-intl/bat-go/wallet"
@@ -49,6 +51,9 @@ func TestControllersTestSuite(t *testing.T) {
 }
 
 func (suite *ControllersTestSuite) SetupSuite() {
+	// started in containers unless DATABASE_URL and KAFKA_BROKERS point at running ones
+	fixtures.Require(suite.T(), fixtures.Options{Postgres: true, Kafka: true})
+
 	govalidator.SetFieldsRequiredByDefault(true)
 	pg, err := NewPostgres("", false, "")
 	suite.Require().NoError(err, "Failed to get postgres conn")
@@ -67,7 +72,7 @@ func (suite *ControllersTestSuite) SetupSuite() {
 	EncryptionKey = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0"
 	InitEncryptionKeys()
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 	suite.service = &Service{
 		Datastore: pg,
 	}
@@ -702,7 +707,7 @@ func (suite *ControllersTestSuite) TestAnonymousCardE2E() {
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:          strings.Split(kafkaBrokers, ","),
 		Topic:            voteTopic,
-		Dialer:           service.kafkaDialer,
+		Dialer:           dialer,
 		MaxWait:          time.Second,
 		RebalanceTimeout: time.Second,
 		Logger:           kafka.LoggerFunc(log.Printf),
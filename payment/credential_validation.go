@@ -0,0 +1,97 @@
+package payment
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// defaultMaxCredentialsPerRequest caps the credentials submitted to be signed or redeemed in one request
+	defaultMaxCredentialsPerRequest = 10000
+
+	// pointLength is the length of public keys and blinded credentials, which are ristretto points
+	pointLength = 32
+	// signatureLength is the length of the signature binding a credential to its payload
+	signatureLength = 64
+	// tokenPreimageLength is the length of the token preimage a credential is redeemed with
+	tokenPreimageLength = 64
+)
+
+// maxCredentialsPerRequest is the most credentials submitted in one request, from MAX_CREDENTIALS_PER_REQUEST
+func maxCredentialsPerRequest() int {
+	if max, err := strconv.Atoi(os.Getenv("MAX_CREDENTIALS_PER_REQUEST")); err == nil && max > 0 {
+		return max
+	}
+	return defaultMaxCredentialsPerRequest
+}
+
+// base64LengthError describes why the value is not the standard base64 encoding of length bytes, empty
+// when it is
+func base64LengthError(value string, length int) string {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(decoded) != length {
+		return fmt.Sprintf("must be %d base64 encoded bytes", length)
+	}
+	return ""
+}
+
+// validateCredentialCount checks the number of credentials submitted in the field
+func validateCredentialCount(field string, count int, errs map[string]interface{}) bool {
+	if count == 0 {
+		errs[field] = "must not be empty"
+		return false
+	}
+	if max := maxCredentialsPerRequest(); count > max {
+		errs[field] = fmt.Sprintf("must have at most %d credentials", max)
+		return false
+	}
+	return true
+}
+
+// validateCredentialBindings checks the credential bindings submitted in the field are well formed and
+// distinct, so malformed credentials are not sent to the challenge bypass server. The errors are by field,
+// the field of each offending binding named by its index
+func validateCredentialBindings(field string, bindings []CredentialBinding) map[string]interface{} {
+	errs := map[string]interface{}{}
+	if !validateCredentialCount(field, len(bindings), errs) {
+		return errs
+	}
+
+	seen := make(map[string]int, len(bindings))
+	for i, binding := range bindings {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if msg := base64LengthError(binding.PublicKey, pointLength); msg != "" {
+			errs[name+".publicKey"] = msg
+		}
+		if msg := base64LengthError(binding.TokenPreimage, tokenPreimageLength); msg != "" {
+			errs[name+".t"] = msg
+		}
+		if msg := base64LengthError(binding.Signature, signatureLength); msg != "" {
+			errs[name+".signature"] = msg
+		}
+		if first, ok := seen[binding.TokenPreimage]; ok {
+			errs[name] = fmt.Sprintf("duplicates %s[%d]", field, first)
+			continue
+		}
+		seen[binding.TokenPreimage] = i
+	}
+	return errs
+}
+
+// validateBlindedCreds checks the blinded credentials submitted in the field are well formed, the errors are
+// by field, each offending credential named by its index
+func validateBlindedCreds(field string, blindedCreds []string) map[string]interface{} {
+	errs := map[string]interface{}{}
+	if !validateCredentialCount(field, len(blindedCreds), errs) {
+		return errs
+	}
+
+	for i, cred := range blindedCreds {
+		if msg := base64LengthError(cred, pointLength); msg != "" {
+			errs[fmt.Sprintf("%s[%d]", field, i)] = msg
+		}
+	}
+	return errs
+}
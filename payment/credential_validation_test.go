@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodedBytes(n int, b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, n))
+}
+
+func TestValidateCredentialBindings(t *testing.T) {
+	binding := func(b byte) CredentialBinding {
+		return CredentialBinding{PublicKey: encodedBytes(32, 0), TokenPreimage: encodedBytes(64, b), Signature: encodedBytes(64, 0)}
+	}
+	assert.Empty(t, validateCredentialBindings("credentials", []CredentialBinding{binding(1), binding(2)}))
+
+	short := binding(3)
+	short.Signature = encodedBytes(32, 0)
+	malformed := binding(4)
+	malformed.PublicKey = "not base64"
+	errs := validateCredentialBindings("credentials", []CredentialBinding{binding(1), short, malformed, binding(1)})
+	assert.Equal(t, map[string]interface{}{
+		"credentials[1].signature": "must be 64 base64 encoded bytes",
+		"credentials[2].publicKey": "must be 32 base64 encoded bytes",
+		"credentials[3]":           "duplicates credentials[0]",
+	}, errs)
+
+	assert.Equal(t, map[string]interface{}{"credentials": "must not be empty"}, validateCredentialBindings("credentials", nil))
+
+	require.NoError(t, os.Setenv("MAX_CREDENTIALS_PER_REQUEST", "1"))
+	defer func() { _ = os.Unsetenv("MAX_CREDENTIALS_PER_REQUEST") }()
+	assert.Equal(t, map[string]interface{}{"credentials": "must have at most 1 credentials"},
+		validateCredentialBindings("credentials", []CredentialBinding{binding(1), binding(2)}))
+}
+
+func TestCreateOrderCredsValidation(t *testing.T) {
+	r := chi.NewRouter()
+	r.Method("POST", "/{orderID}/credentials", CreateOrderCreds(&Service{}))
+
+	body, err := json.Marshal(CreateOrderCredsRequest{
+		ItemID:       uuid.NewV4(),
+		BlindedCreds: []string{encodedBytes(32, 1), encodedBytes(31, 1), encodedBytes(32, 2)},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/"+uuid.NewV4().String()+"/credentials", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code, "malformed credentials are rejected before the order is read")
+
+	var appErr handlers.AppError
+	require.NoError(t, json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(&appErr))
+	assert.Equal(t, map[string]interface{}{
+		"validationErrors": map[string]interface{}{"blindedCreds[1]": "must be 32 base64 encoded bytes"},
+	}, appErr.Data)
+}
@@ -2,22 +2,52 @@ package payment
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	appctx "github.com/brave-intl/bat-go/utils/context"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
 	"github.com/brave-intl/bat-go/utils/jsonutils"
+	cache "github.com/patrickmn/go-cache"
 	uuid "github.com/satori/go.uuid"
 )
 
 const (
 	defaultMaxTokensPerIssuer = 4000000 // ~1M BAT
+
+	// issuerCacheTTL bounds how long another instance's rotation takes to be seen by this one
+	issuerCacheTTL   = 5 * time.Minute
+	issuerCachePurge = 10 * time.Minute
 )
 
+// issuerCache holds the issuers credentials are redeemed with by public key, shared by every request
+var issuerCache = cache.New(issuerCacheTTL, issuerCachePurge)
+
+// invalidateIssuers drops the issuers from the issuer cache
+func invalidateIssuers(issuers ...Issuer) {
+	for _, issuer := range issuers {
+		issuerCache.Delete(issuer.PublicKey)
+	}
+}
+
+// getIssuerByPublicKey returns the issuer with the public key from the issuer cache, looking it up on a miss
+func getIssuerByPublicKey(db ReadOnlyDatastore, publicKey string) (*Issuer, error) {
+	if cached, ok := issuerCache.Get(publicKey); ok {
+		issuer := cached.(Issuer)
+		return &issuer, nil
+	}
+	issuer, err := db.GetIssuerByPublicKey(publicKey)
+	if err != nil || issuer == nil {
+		return issuer, err
+	}
+	issuerCache.SetDefault(publicKey, *issuer)
+	return issuer, nil
+}
+
 func decodeIssuerID(issuerID string) (string, string, error) {
 	var (
 		merchantID string
@@ -48,6 +78,11 @@ func encodeIssuerID(merchantID, sku string) (string, error) {
 	return u.String(), nil
 }
 
+// IssuerID is the name of the issuer of the merchant's sku
+func IssuerID(merchantID, sku string) (string, error) {
+	return encodeIssuerID(merchantID, sku)
+}
+
 // CredentialBinding includes info needed to redeem a single credential
 type CredentialBinding struct {
 	PublicKey     string `json:"publicKey" valid:"base64"`
@@ -70,69 +105,149 @@ func DeduplicateCredentialBindings(tokens ...CredentialBinding) []CredentialBind
 	return result
 }
 
-// Issuer includes information about a particular credential issuer
+// Issuer includes information about a particular credential issuer. A merchant's issuer is rotated by
+// replacing it with a new version, each version a distinct issuer of the challenge bypass server which
+// signs credentials within its validity window and redeems them for good
 type Issuer struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
-	MerchantID string    `json:"merchantId" db:"merchant_id"`
-	PublicKey  string    `json:"publicKey" db:"public_key"`
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	MerchantID string     `json:"merchantId" db:"merchant_id"`
+	PublicKey  string     `json:"publicKey" db:"public_key"`
+	Version    int        `json:"version" db:"version"`
+	ValidFrom  time.Time  `json:"validFrom" db:"valid_from"`
+	ValidTo    *time.Time `json:"validTo,omitempty" db:"valid_to"`
+	// DisabledAt is when an operator stopped the issuer signing credentials
+	DisabledAt *time.Time `json:"disabledAt,omitempty" db:"disabled_at"`
 }
 
-// CreateIssuer creates a new challenge bypass credential issuer, saving it's information into the datastore
-func (service *Service) CreateIssuer(ctx context.Context, merchantID string) (*Issuer, error) {
-	issuer := &Issuer{MerchantID: merchantID}
+// createIssuer creates the challenge bypass credential issuer, filling in its public key. Its token cap is
+// the one configured for its merchant and sku
+func (service *Service) createIssuer(ctx context.Context, issuer *Issuer) error {
+	settings, err := service.getMerchantSettings(ctx, issuer.MerchantID)
+	if err != nil {
+		return fmt.Errorf("failed to get merchant settings: %w", err)
+	}
 
-	err := service.cbClient.CreateIssuer(ctx, issuer.Name(), defaultMaxTokensPerIssuer)
+	err = service.cbClient.CreateIssuer(ctx, issuer.Name(), settings.maxTokensPerIssuer(issuer.SKU()))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := service.cbClient.GetIssuer(ctx, issuer.Name())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	issuer.PublicKey = resp.PublicKey
+	return nil
+}
+
+// CreateIssuer creates a new challenge bypass credential issuer, saving it's information into the datastore
+func (service *Service) CreateIssuer(ctx context.Context, merchantID string) (*Issuer, error) {
+	issuer := &Issuer{MerchantID: merchantID, Version: 1}
+	if err := service.createIssuer(ctx, issuer); err != nil {
+		return nil, err
+	}
+	defer invalidateIssuers(*issuer)
 
 	return service.Datastore.InsertIssuer(issuer)
 }
 
-// Name returns the name of the issuer as known by the challenge bypass server
+// RotateIssuer creates the next version of the merchant's issuer, which signs credentials from now on.
+// Credentials signed by the previous versions still redeem
+func (service *Service) RotateIssuer(ctx context.Context, merchantID string) (*Issuer, error) {
+	issuers, err := service.Datastore.GetIssuers(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(issuers) == 0 {
+		return service.CreateIssuer(ctx, merchantID)
+	}
+
+	// issuers are returned newest version first
+	issuer := &Issuer{MerchantID: merchantID, Version: issuers[0].Version + 1}
+	if err := service.createIssuer(ctx, issuer); err != nil {
+		return nil, err
+	}
+	// the validity of the previous versions ends with the rotation
+	defer invalidateIssuers(append(issuers, *issuer)...)
+
+	return service.Datastore.RotateIssuer(ctx, issuer)
+}
+
+// Name returns the name of the issuer as known by the challenge bypass server, versions after the first
+// carry their version alongside the sku so the merchant and sku still decode from it
 func (issuer *Issuer) Name() string {
-	return issuer.MerchantID
+	if issuer.Version <= 1 {
+		return issuer.MerchantID
+	}
+	separator := "?"
+	if strings.Contains(issuer.MerchantID, "?") {
+		separator = "&"
+	}
+	return issuer.MerchantID + separator + "v=" + strconv.Itoa(issuer.Version)
 }
 
-// GetOrCreateIssuer gets a matching issuer if one exists and otherwise creates one
+// SKU returns the sku the issuer signs credentials of, empty for the issuers of a whole merchant
+func (issuer *Issuer) SKU() string {
+	_, sku, err := decodeIssuerID(issuer.MerchantID)
+	if err != nil {
+		return ""
+	}
+	return sku
+}
+
+// GetOrCreateIssuer gets the currently active issuer if one exists and otherwise creates one, the next
+// version when the merchant's issuers have all expired or been disabled
 func (service *Service) GetOrCreateIssuer(ctx context.Context, merchantID string) (*Issuer, error) {
 	issuer, err := service.Datastore.GetIssuer(merchantID)
 	if issuer == nil {
-		issuer, err = service.CreateIssuer(ctx, merchantID)
+		issuer, err = service.RotateIssuer(ctx, merchantID)
 	}
 
 	return issuer, err
 }
 
-// OrderCreds encapsulates the credentials to be signed in response to a completed order
+// OrderCreds encapsulates the credentials to be signed in response to a completed order. Time-limited
+// credentials are signed in dated batches, one per window, which carry their window's validity
 type OrderCreds struct {
-	ID           uuid.UUID                  `json:"id" db:"item_id"`
-	OrderID      uuid.UUID                  `json:"orderId" db:"order_id"`
-	IssuerID     uuid.UUID                  `json:"issuerId" db:"issuer_id"`
-	BlindedCreds jsonutils.JSONStringArray  `json:"blindedCreds" db:"blinded_creds"`
-	SignedCreds  *jsonutils.JSONStringArray `json:"signedCreds" db:"signed_creds"`
-	BatchProof   *string                    `json:"batchProof" db:"batch_proof"`
-	PublicKey    *string                    `json:"publicKey" db:"public_key"`
+	ID           uuid.UUID            `json:"id" db:"item_id"`
+	OrderID      uuid.UUID            `json:"orderId" db:"order_id"`
+	IssuerID     uuid.UUID            `json:"issuerId" db:"issuer_id"`
+	BlindedCreds jsonutils.TextArray  `json:"blindedCreds" db:"blinded_creds"`
+	SignedCreds  *jsonutils.TextArray `json:"signedCreds" db:"signed_creds"`
+	BatchProof   *string              `json:"batchProof" db:"batch_proof"`
+	PublicKey    *string              `json:"publicKey" db:"public_key"`
+	ValidFrom    *time.Time           `json:"validFrom,omitempty" db:"valid_from"`
+	ValidTo      *time.Time           `json:"validTo,omitempty" db:"valid_to"`
 }
 
-// CreateOrderCreds if the order is complete
-func (service *Service) CreateOrderCreds(ctx context.Context, orderID uuid.UUID, itemID uuid.UUID, blindedCreds []string) error {
+// CreateOrderCreds if the order is complete, or if it is a trial which the wallet may take. The wallet must
+// be the one which signed the request, see walletSigned
+func (service *Service) CreateOrderCreds(ctx context.Context, orderID uuid.UUID, itemID uuid.UUID, blindedCreds []string, walletID *uuid.UUID) error {
 	order, err := service.Datastore.GetOrder(orderID)
 	if err != nil {
 		return errorutils.Wrap(err, "error finding order")
 	}
+	if order == nil {
+		return ErrOrderNotFound
+	}
 
+	// free orders created before their merchant offered trials are paid already
 	if !order.IsPaid() {
-		return errors.New("order has not yet been paid")
+		if !order.isTrial() {
+			return ErrOrderNotPaid
+		}
+		if err := service.claimTrial(ctx, order, walletID); err != nil {
+			return err
+		}
+	}
+
+	settings, err := service.Datastore.GetMerchantSettings(ctx, order.MerchantID)
+	if err != nil {
+		return errorutils.Wrap(err, "error getting merchant settings")
 	}
+	blindedCreds = settings.limitCreds(blindedCreds)
 
 	// get the order items, need to create issuers based on the
 	// special sku values on the order items
@@ -143,8 +258,13 @@ func (service *Service) CreateOrderCreds(ctx context.Context, orderID uuid.UUID,
 			return errorutils.Wrap(err, "error encoding issuer name")
 		}
 
-		// create the issuer
-		issuer, err := service.GetOrCreateIssuer(ctx, issuerID)
+		// create the issuer, time-limited credentials are signed by the issuer of the current window
+		var issuer *Issuer
+		if orderItem.CredentialType == timeLimitedCredentialType {
+			issuer, err = service.getOrCreateWindowIssuer(ctx, order.MerchantID, orderItem.SKU, credentialWindowStart(time.Now()))
+		} else {
+			issuer, err = service.GetOrCreateIssuer(ctx, issuerID)
+		}
 		if err != nil {
 			return errorutils.Wrap(err, "error finding issuer")
 		}
@@ -157,14 +277,19 @@ func (service *Service) CreateOrderCreds(ctx context.Context, orderID uuid.UUID,
 			ID:           itemID,
 			OrderID:      orderID,
 			IssuerID:     issuer.ID,
-			BlindedCreds: jsonutils.JSONStringArray(blindedCreds),
+			BlindedCreds: jsonutils.TextArray(blindedCreds),
+		}
+		if orderItem.CredentialType == timeLimitedCredentialType {
+			orderCreds.ValidFrom = &issuer.ValidFrom
+			orderCreds.ValidTo = issuer.ValidTo
 		}
 
-		err = service.Datastore.InsertOrderCreds(&orderCreds)
+		err = service.Datastore.InsertOrderCreds(ctx, &orderCreds)
 		if err != nil {
 			return errorutils.Wrap(err, "error inserting order creds")
 		}
 	}
+	service.NotifyOrderChanged(orderID)
 
 	return nil
 }
@@ -176,22 +301,7 @@ type OrderWorker interface {
 
 // SignOrderCreds signs the blinded credentials
 func (service *Service) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
-	resp, err := service.cbClient.SignCredentials(ctx, issuer.Name(), blindedCreds)
-	if err != nil {
-		return nil, err
-	}
-
-	signedTokens := jsonutils.JSONStringArray(resp.SignedTokens)
-
-	creds := &OrderCreds{
-		ID:           orderID,
-		BlindedCreds: blindedCreds,
-		SignedCreds:  &signedTokens,
-		BatchProof:   &resp.BatchProof,
-		PublicKey:    &issuer.PublicKey,
-	}
-
-	return creds, nil
+	return signOrderCreds(ctx, service.cbClient, orderID, issuer, blindedCreds)
 }
 
 // generateCredentialRedemptions - helper to create credential redemptions from cred bindings
@@ -204,9 +314,9 @@ var generateCredentialRedemptions = func(ctx context.Context, cb []CredentialBin
 		issuers            = make(map[string]*Issuer)
 	)
 
-	db, ok := ctx.Value(appctx.DatastoreCTXKey).(Datastore)
-	if !ok {
-		return nil, errors.New("failed to get datastore from context")
+	var db ReadOnlyDatastore
+	if err := appctx.Datastore(ctx, &db); err != nil {
+		return nil, fmt.Errorf("failed to get datastore from context: %w", err)
 	}
 
 	for i := 0; i < len(cb); i++ {
@@ -219,16 +329,33 @@ var generateCredentialRedemptions = func(ctx context.Context, cb []CredentialBin
 
 		publicKey := cb[i].PublicKey
 
+		// every version of an issuer is looked up, so credentials signed before a rotation still redeem
 		if issuer, ok = issuers[publicKey]; !ok {
-			issuer, err = db.GetIssuerByPublicKey(publicKey)
+			issuer, err = getIssuerByPublicKey(db, publicKey)
 			if err != nil {
 				return nil, fmt.Errorf("error finding issuer: %w", err)
 			}
+			if issuer == nil {
+				return nil, fmt.Errorf("error finding issuer: no issuer with public key %s", publicKey)
+			}
+			issuers[publicKey] = issuer
+		}
+		if !credentialWindowOpen(issuer.Name(), time.Now()) {
+			return nil, fmt.Errorf("error redeeming credential: the window of issuer %s is not open", issuer.Name())
 		}
 
 		requestCredentials[i].Issuer = issuer.Name()
 		requestCredentials[i].TokenPreimage = cb[i].TokenPreimage
 		requestCredentials[i].Signature = cb[i].Signature
 	}
+
+	// credentials submitted again are rejected here rather than by the challenge bypass server
+	tokenPreimages := make([]string, len(requestCredentials))
+	for i := range requestCredentials {
+		tokenPreimages[i] = requestCredentials[i].TokenPreimage
+	}
+	if err := checkCredentialsRedeemed(ctx, db, tokenPreimages...); err != nil {
+		return nil, err
+	}
 	return requestCredentials, nil
 }
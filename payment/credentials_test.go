@@ -1,8 +1,13 @@
 package payment
 
 import (
+	"context"
 	"fmt"
 	"testing"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDeduplicateCredentialBindings(t *testing.T) {
@@ -85,3 +90,86 @@ func TestIssuerID(t *testing.T) {
 		}
 	}
 }
+
+func TestRotateIssuer(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	client := &issuerClient{}
+	service := &Service{Datastore: ds, cbClient: client}
+
+	issuerID, err := encodeIssuerID("brave.com", "anon-card-vote")
+	require.NoError(t, err)
+
+	first, err := service.GetOrCreateIssuer(ctx, issuerID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Version)
+	assert.Equal(t, issuerID, first.Name(), "the first version keeps the name issuers had before rotation")
+
+	second, err := service.RotateIssuer(ctx, issuerID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+	assert.Equal(t, issuerID+"&v=2", second.Name())
+	assert.Equal(t, []string{issuerID, issuerID + "&v=2"}, client.created)
+	assert.Equal(t, "brave.com?v=3", (&Issuer{MerchantID: "brave.com", Version: 3}).Name())
+
+	merchantID, sku, err := decodeIssuerID(second.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "brave.com", merchantID)
+	assert.Equal(t, "anon-card-vote", sku)
+
+	active, err := service.GetOrCreateIssuer(ctx, issuerID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, active.ID, "the rotated version signs from now on")
+
+	// credentials signed before the rotation redeem against the version which signed them
+	ctx = context.WithValue(ctx, appctx.DatastoreCTXKey, Datastore(ds))
+	redemptions, err := generateCredentialRedemptions(ctx, []CredentialBinding{
+		{PublicKey: first.PublicKey, TokenPreimage: "a", Signature: "a"},
+		{PublicKey: second.PublicKey, TokenPreimage: "b", Signature: "b"},
+		{PublicKey: first.PublicKey, TokenPreimage: "c", Signature: "c"},
+	})
+	require.NoError(t, err)
+	require.Len(t, redemptions, 3)
+	assert.Equal(t, first.Name(), redemptions[0].Issuer)
+	assert.Equal(t, second.Name(), redemptions[1].Issuer)
+	assert.Equal(t, first.Name(), redemptions[2].Issuer)
+
+	_, err = generateCredentialRedemptions(ctx, []CredentialBinding{{PublicKey: "unknown", TokenPreimage: "d"}})
+	assert.Error(t, err)
+}
+
+func TestIssuerCache(t *testing.T) {
+	issuerCache.Flush()
+	defer issuerCache.Flush()
+
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds, cbClient: &issuerClient{}}
+	issuer, err := service.CreateIssuer(ctx, "brave.com?sku=anon-card-vote")
+	require.NoError(t, err)
+
+	// redemptions share the issuers looked up by earlier requests
+	ctx = context.WithValue(ctx, appctx.DatastoreCTXKey, Datastore(ds))
+	for _, preimage := range []string{"a", "b"} {
+		redemptions, err := generateCredentialRedemptions(ctx, []CredentialBinding{
+			{PublicKey: issuer.PublicKey, TokenPreimage: preimage, Signature: preimage},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, issuer.Name(), redemptions[0].Issuer)
+	}
+	assert.Equal(t, 1, ds.calls["GetIssuerByPublicKey"])
+
+	// unknown public keys are not cached, they may belong to an issuer created since
+	for i := 0; i < 2; i++ {
+		_, err = generateCredentialRedemptions(ctx, []CredentialBinding{{PublicKey: "unknown", TokenPreimage: "c"}})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 3, ds.calls["GetIssuerByPublicKey"])
+
+	// rotating the issuer invalidates its previous versions
+	_, err = service.RotateIssuer(ctx, issuer.MerchantID)
+	require.NoError(t, err)
+	_, err = generateCredentialRedemptions(ctx, []CredentialBinding{{PublicKey: issuer.PublicKey, TokenPreimage: "d"}})
+	require.NoError(t, err)
+	assert.Equal(t, 4, ds.calls["GetIssuerByPublicKey"])
+}
@@ -0,0 +1,340 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/utils/clients/gemini"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// TransferPending is the status of a custodian transfer which has not settled yet
+	TransferPending = "pending"
+	// TransferCompleted is the status of a custodian transfer which settled, paying towards its order
+	TransferCompleted = "completed"
+	// TransferFailed is the status of a custodian transfer which will not settle
+	TransferFailed = "failed"
+
+	// custodianSettlementBatch is how many pending transfers are checked each run of the settlement job
+	custodianSettlementBatch = 100
+)
+
+var (
+	// ErrUnknownCustodian is the error when paying with a custodian orders are not paid through
+	ErrUnknownCustodian = errorutils.NewApplicationError("unknown_custodian", http.StatusBadRequest, "orders cannot be paid through this custodian", false)
+	// ErrWalletNotLinked is the error when paying from a wallet which is not linked to the custodian
+	ErrWalletNotLinked = errorutils.NewApplicationError("wallet_not_linked", http.StatusBadRequest, "the wallet is not linked to the custodian", false)
+	// ErrOrderNotPayableInBAT is the error when paying in BAT for an order which has no BAT price
+	ErrOrderNotPayableInBAT = errorutils.NewApplicationError("order_not_payable_in_bat", http.StatusBadRequest, "the order cannot be paid in BAT", false)
+	// ErrTransferNotFromWallet is the error when a transfer was not sent from the wallet's linked account
+	ErrTransferNotFromWallet = errorutils.NewApplicationError("transfer_not_from_wallet", http.StatusBadRequest, "the transfer was not sent from the wallet's linked account", false)
+	// ErrInvalidTransfer is the error when a transfer does not pay towards the order
+	ErrInvalidTransfer = errorutils.NewApplicationError("invalid_transfer", http.StatusBadRequest, "the transfer does not pay the order", false)
+	// ErrTransferSubmitted is the error when a transfer was submitted towards an order already
+	ErrTransferSubmitted = errorutils.NewApplicationError("transfer_submitted", http.StatusConflict, "the transfer was submitted already", false)
+	// ErrCustodianWalletUnsigned is returned when a transfer is submitted for a wallet which did not sign the request
+	ErrCustodianWalletUnsigned = errorutils.NewApplicationError("custodian_wallet_unsigned", http.StatusUnauthorized, "the request must be signed by the paying wallet", false)
+)
+
+// CustodianTransfer is a BAT transfer from a linked custodial wallet as the custodian reports it
+type CustodianTransfer struct {
+	ID          string
+	Status      string
+	Currency    string
+	Amount      decimal.Decimal
+	Destination string
+	// Origin is the account the transfer was sent from, empty when the custodian does not report it
+	Origin string
+}
+
+// Custodian looks up the transfers made through a custodian of linked wallets
+type Custodian interface {
+	// GetTransfer returns the transfer with the id
+	GetTransfer(ctx context.Context, id string) (*CustodianTransfer, error)
+	// SettlementAddress is the account transfers paying orders are sent to
+	SettlementAddress() string
+}
+
+// upholdCustodian looks up uphold transactions
+type upholdCustodian struct {
+	address string
+}
+
+func (c *upholdCustodian) GetTransfer(ctx context.Context, id string) (*CustodianTransfer, error) {
+	var wallet uphold.Wallet
+	info, err := wallet.GetTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	status := TransferPending
+	switch info.Status {
+	case "completed":
+		status = TransferCompleted
+	case "failed", "cancelled", "canceled":
+		status = TransferFailed
+	}
+	return &CustodianTransfer{
+		ID:          info.ID,
+		Status:      status,
+		Currency:    info.AltCurrency.String(),
+		Amount:      info.AltCurrency.FromProbi(info.Probi),
+		Destination: info.Destination,
+		Origin:      info.Source,
+	}, nil
+}
+
+func (c *upholdCustodian) SettlementAddress() string {
+	return c.address
+}
+
+// geminiCustodian looks up the payments made to the gemini client by their transaction reference. Gemini does
+// not report the account a payment was sent from, so its transfers cannot be tied to a wallet and are rejected
+type geminiCustodian struct {
+	client  gemini.Client
+	conf    gemini.Conf
+	address string
+}
+
+func (c *geminiCustodian) GetTransfer(ctx context.Context, id string) (*CustodianTransfer, error) {
+	result, err := c.client.CheckTxStatus(ctx, c.conf.APIKey, c.conf.ClientID, id)
+	if err != nil {
+		return nil, err
+	}
+	transfer := &CustodianTransfer{ID: id, Status: TransferPending}
+	if result.Result != "OK" {
+		transfer.Status = TransferFailed
+	} else if result.Status != nil {
+		switch strings.ToLower(*result.Status) {
+		case "completed":
+			transfer.Status = TransferCompleted
+		case "failed", "error":
+			transfer.Status = TransferFailed
+		}
+	}
+	if result.Currency != nil {
+		transfer.Currency = strings.ToUpper(*result.Currency)
+	}
+	if result.Amount != nil {
+		transfer.Amount = *result.Amount
+	}
+	if result.Destination != nil {
+		transfer.Destination = *result.Destination
+	}
+	return transfer, nil
+}
+
+func (c *geminiCustodian) SettlementAddress() string {
+	return c.address
+}
+
+// loadCustodians configures the custodians orders are paid through, uphold when UPHOLD_SETTLEMENT_ADDRESS is
+// set and gemini when GEMINI_ENABLED and GEMINI_SETTLEMENT_ADDRESS are
+func loadCustodians() (map[string]Custodian, error) {
+	custodians := map[string]Custodian{}
+	if uphold.UpholdSettlementAddress != "" {
+		custodians["uphold"] = &upholdCustodian{address: uphold.UpholdSettlementAddress}
+	}
+	if address := os.Getenv("GEMINI_SETTLEMENT_ADDRESS"); os.Getenv("GEMINI_ENABLED") == "true" && address != "" {
+		client, err := gemini.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gemini client: %w", err)
+		}
+		custodians["gemini"] = &geminiCustodian{
+			client: client,
+			conf: gemini.Conf{
+				ClientID: os.Getenv("GEMINI_CLIENT_ID"),
+				APIKey:   os.Getenv("GEMINI_CLIENT_KEY"),
+			},
+			address: address,
+		}
+	}
+	return custodians, nil
+}
+
+// custodianKinds are the kinds of the transactions of the custodians orders are paid through
+func (s *Service) custodianKinds() []string {
+	kinds := make([]string, 0, len(s.custodians))
+	for kind := range s.custodians {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// verifyTransfer checks a transfer pays the order, that it is a BAT transfer from the wallet's linked account
+// to the custodian's settlement account which has not failed. The amount paid is the amount the custodian
+// reports
+func verifyTransfer(order *Order, custodian Custodian, transfer *CustodianTransfer, account string) error {
+	if order.Currency != "BAT" && order.BATTotalPrice == nil {
+		return ErrOrderNotPayableInBAT
+	}
+	switch {
+	case account == "" || transfer.Origin != account:
+		return ErrTransferNotFromWallet
+	case transfer.Currency != "BAT":
+		return fmt.Errorf("%w: transfer is in %s", ErrInvalidTransfer, transfer.Currency)
+	case transfer.Destination != custodian.SettlementAddress():
+		return fmt.Errorf("%w: invalid settlement address", ErrInvalidTransfer)
+	case !transfer.Amount.IsPositive():
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidTransfer)
+	case transfer.Status == TransferFailed:
+		return fmt.Errorf("%w: transfer failed", ErrInvalidTransfer)
+	}
+	return nil
+}
+
+// CreateCustodianTransaction records a BAT transfer from a wallet's linked custodial account towards an order.
+// A completed transfer pays towards the order at once, while a pending transfer pays once it settles, see
+// SettleCustodianTransfers
+func (s *Service) CreateCustodianTransaction(ctx context.Context, orderID, walletID uuid.UUID, custodianName, transferID string) (*Transaction, error) {
+	custodian, ok := s.custodians[custodianName]
+	if !ok {
+		return nil, ErrUnknownCustodian
+	}
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := payableOrder(order); err != nil {
+		return nil, err
+	}
+
+	// the transfer cannot be tied to the wallet without its linked account
+	if s.wallet == nil {
+		return nil, errors.New("custodian transactions require the wallet service")
+	}
+
+	existing, err := s.Datastore.GetTransaction(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrTransferSubmitted
+	}
+
+	link, err := s.wallet.Datastore.GetCustodianLinkByWalletID(ctx, walletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWalletNotLinked
+	} else if err != nil {
+		return nil, err
+	}
+	if link.Custodian != custodianName {
+		return nil, ErrWalletNotLinked
+	}
+	// transfers are only accepted from the account the wallet was linked to, the settlement account is shared
+	info, err := s.wallet.Datastore.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, ErrWalletNotLinked
+	}
+
+	transfer, err := custodian.GetTransfer(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s transfer: %w", custodianName, err)
+	}
+	if err := verifyTransfer(order, custodian, transfer, info.UserDepositDestination); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.Datastore.CreateTransaction(orderID, transferID, transfer.Status, transfer.Currency, custodianName, transfer.Amount)
+	if err != nil {
+		return nil, errorutils.Wrap(err, "error recording custodian transaction")
+	}
+	s.streamTransaction(transaction)
+
+	if err := s.UpdateOrderStatus(ctx, orderID); err != nil {
+		return nil, errorutils.Wrap(err, "error updating order status")
+	}
+	return transaction, nil
+}
+
+// SettleCustodianTransfers checks the pending transfers towards orders with their custodians, recording those
+// which settled as payments and marking the orders they pay paid
+func (s *Service) SettleCustodianTransfers(ctx context.Context) (bool, error) {
+	if len(s.custodians) == 0 {
+		return false, nil
+	}
+	pending, err := s.Datastore.GetPendingTransactions(ctx, s.custodianKinds(), custodianSettlementBatch)
+	if err != nil {
+		return false, err
+	}
+
+	_, logger := logging.SetupLogger(ctx)
+	for _, transaction := range pending {
+		transfer, err := s.custodians[transaction.Kind].GetTransfer(ctx, transaction.ExternalTransactionID)
+		if err != nil {
+			// the custodian may be unavailable, the transfer is checked again next run
+			logger.Warn().Err(err).Str("transaction_id", transaction.ID.String()).Msg("failed to get transfer")
+			continue
+		}
+		if transfer.Status == TransferPending {
+			continue
+		}
+
+		settled, err := s.Datastore.SettleTransaction(ctx, transaction.ID, transfer.Status)
+		if err != nil {
+			return false, err
+		}
+		if settled == nil {
+			continue
+		}
+		s.streamTransaction(settled)
+		if err := s.UpdateOrderStatus(ctx, settled.OrderID); err != nil {
+			return false, fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+	return false, nil
+}
+
+// CreateCustodianTransactionRequest includes the transfer from a linked custodial wallet paying an order
+type CreateCustodianTransactionRequest struct {
+	WalletID   uuid.UUID `json:"paymentId" valid:"-"`
+	Custodian  string    `json:"custodian" valid:"in(uphold|gemini)"`
+	TransferID string    `json:"transferId" valid:"required"`
+}
+
+// CreateCustodianTransaction is the handler for paying an order with a BAT transfer from a linked custodial
+// wallet
+func CreateCustodianTransaction(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req CreateCustodianTransactionRequest
+		if err := requestutils.ReadJSON(r.Body, &req); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		if _, err := govalidator.ValidateStruct(req); err != nil {
+			return handlers.WrapValidationError(err)
+		}
+
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+
+		// transfers are tied to the wallet's linked account, so only the wallet may submit them
+		if !isSignedByWallet(r.Context(), req.WalletID) {
+			return handlers.WrapError(ErrCustodianWalletUnsigned, "Error creating the transaction", http.StatusUnauthorized)
+		}
+
+		transaction, err := service.CreateCustodianTransaction(r.Context(), *orderID.UUID(), req.WalletID, req.Custodian, req.TransferID)
+		if err != nil {
+			return handlers.WrapError(err, "Error creating the transaction", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), transaction, w, http.StatusCreated)
+	})
+}
@@ -0,0 +1,228 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/httpsignature"
+	"github.com/brave-intl/bat-go/wallet"
+	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+)
+
+// fakeCustodian reports the transfers it was given
+type fakeCustodian struct {
+	transfers map[string]*CustodianTransfer
+}
+
+func (c *fakeCustodian) GetTransfer(ctx context.Context, id string) (*CustodianTransfer, error) {
+	transfer, ok := c.transfers[id]
+	if !ok {
+		return nil, errors.New("transfer not found")
+	}
+	copied := *transfer
+	return &copied, nil
+}
+
+func (c *fakeCustodian) SettlementAddress() string {
+	return "settlement"
+}
+
+func TestCreateCustodianTransaction(t *testing.T) {
+	ctx := context.Background()
+	walletID, otherID, unlinkedID := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	account := "account-" + walletID.String()
+	batTotal := decimal.New(20, 0)
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{
+		Status:        "pending",
+		Currency:      "USD",
+		TotalPrice:    decimal.New(5, 0),
+		BATTotalPrice: &batTotal,
+	})
+	custodian := &fakeCustodian{transfers: map[string]*CustodianTransfer{
+		"elsewhere": {ID: "elsewhere", Status: TransferCompleted, Currency: "BAT", Amount: batTotal, Destination: "attacker", Origin: account},
+		"in-usd":    {ID: "in-usd", Status: TransferCompleted, Currency: "USD", Amount: decimal.New(5, 0), Destination: "settlement", Origin: account},
+		"other":     {ID: "other", Status: TransferCompleted, Currency: "BAT", Amount: batTotal, Destination: "settlement", Origin: "account-" + otherID.String()},
+		"unknown":   {ID: "unknown", Status: TransferCompleted, Currency: "BAT", Amount: batTotal, Destination: "settlement"},
+		"first":     {ID: "first", Status: TransferCompleted, Currency: "BAT", Amount: decimal.New(8, 0), Destination: "settlement", Origin: account},
+		"second":    {ID: "second", Status: TransferPending, Currency: "BAT", Amount: decimal.New(12, 0), Destination: "settlement", Origin: account},
+	}}
+	wallets := newFakeWallets()
+	wallets.link(walletID, "uphold", account)
+	wallets.link(otherID, "uphold", "account-"+otherID.String())
+	service := &Service{
+		Datastore:     ds,
+		wallet:        &wallet.Service{Datastore: wallets},
+		orderWatchers: newOrderNotifier(),
+		custodians:    map[string]Custodian{"uphold": custodian},
+	}
+
+	_, err := service.CreateCustodianTransaction(ctx, order.ID, walletID, "bitflyer", "first")
+	assert.True(t, errors.Is(err, ErrUnknownCustodian))
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, unlinkedID, "uphold", "first")
+	assert.True(t, errors.Is(err, ErrWalletNotLinked))
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "elsewhere")
+	assert.True(t, errors.Is(err, ErrInvalidTransfer), "transfers must be sent to the settlement address")
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "in-usd")
+	assert.True(t, errors.Is(err, ErrInvalidTransfer), "transfers must be in BAT")
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "other")
+	assert.True(t, errors.Is(err, ErrTransferNotFromWallet), "transfers from another account do not pay for the wallet")
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "unknown")
+	assert.True(t, errors.Is(err, ErrTransferNotFromWallet), "transfers without an origin cannot be tied to the wallet")
+
+	transaction, err := service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "first")
+	require.NoError(t, err)
+	assert.Equal(t, "uphold", transaction.Kind)
+	assert.Equal(t, "pending", order.Status, "the first transfer pays part of the order")
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "first")
+	assert.True(t, errors.Is(err, ErrTransferSubmitted))
+
+	_, err = service.CreateCustodianTransaction(ctx, order.ID, walletID, "uphold", "second")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", order.Status, "pending transfers pay nothing until they settle")
+
+	_, err = service.SettleCustodianTransfers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", order.Status)
+
+	custodian.transfers["second"].Status = TransferCompleted
+	_, err = service.SettleCustodianTransfers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "paid", order.Status, "the order is paid once its transfers settle")
+}
+
+func TestCreateCustodianTransactionNotPayableInBAT(t *testing.T) {
+	walletID := uuid.NewV4()
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "pending", Currency: "USD", TotalPrice: decimal.New(5, 0)})
+	wallets := newFakeWallets()
+	wallets.link(walletID, "gemini", "account-"+walletID.String())
+	service := &Service{
+		Datastore: ds,
+		wallet:    &wallet.Service{Datastore: wallets},
+		custodians: map[string]Custodian{"gemini": &fakeCustodian{transfers: map[string]*CustodianTransfer{
+			"transfer": {ID: "transfer", Status: TransferCompleted, Currency: "BAT", Amount: decimal.New(20, 0), Destination: "settlement"},
+		}}},
+	}
+
+	_, err := service.CreateCustodianTransaction(context.Background(), order.ID, walletID, "gemini", "transfer")
+	assert.True(t, errors.Is(err, ErrOrderNotPayableInBAT), "orders without a BAT price are not paid in BAT")
+}
+
+func TestSettleTransaction(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	transactionID, orderID := uuid.NewV4(), uuid.NewV4()
+	now := time.Now()
+	columns := []string{"id", "order_id", "created_at", "updated_at", "external_transaction_id", "status", "currency", "kind", "amount"}
+
+	// a completed transaction is recorded as a payment towards its order
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transactions SET status = \$1(.+)WHERE id = \$2 AND status = 'pending'`).
+		WithArgs("completed", transactionID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(transactionID, orderID, now, now, "transfer", "completed", "BAT", "uphold", "20"))
+	mock.ExpectExec(`INSERT INTO order_payments (.+) ON CONFLICT \(transaction_id\) DO NOTHING`).
+		WithArgs(orderID, transactionID, "uphold", "BAT", "20", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	transaction, err := pg.SettleTransaction(context.Background(), transactionID, "completed")
+	require.NoError(t, err)
+	require.NotNil(t, transaction)
+	assert.Equal(t, "completed", transaction.Status)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transactions SET status`).WithArgs("failed", transactionID).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectRollback()
+	transaction, err = pg.SettleTransaction(context.Background(), transactionID, "failed")
+	require.NoError(t, err)
+	assert.Nil(t, transaction, "a transaction is settled once")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCustodianTransactionWalletSigned(t *testing.T) {
+	publicKey, privateKey, err := httpsignature.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	otherPublicKey, otherPrivateKey, err := httpsignature.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	walletID, otherID := uuid.NewV4(), uuid.NewV4()
+	account := "account-" + walletID.String()
+	wallets := newFakeWallets()
+	wallets.link(walletID, "uphold", account)
+	wallets.wallets[walletID].PublicKey = hex.EncodeToString(publicKey)
+	wallets.link(otherID, "uphold", "account-"+otherID.String())
+	wallets.wallets[otherID].PublicKey = hex.EncodeToString(otherPublicKey)
+
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "pending", Currency: "BAT", TotalPrice: decimal.New(5, 0)})
+	custodian := &fakeCustodian{transfers: map[string]*CustodianTransfer{
+		"transfer": {ID: "transfer", Status: TransferCompleted, Currency: "BAT", Amount: decimal.New(5, 0), Destination: "settlement", Origin: account},
+	}}
+	service := &Service{
+		Datastore:     ds,
+		wallet:        &wallet.Service{Datastore: wallets},
+		nonces:        middleware.NewMemoryNonceStore(),
+		orderWatchers: newOrderNotifier(),
+		custodians:    map[string]Custodian{"uphold": custodian},
+	}
+	r := chi.NewRouter()
+	r.Method("POST", "/{orderID}/transactions/custodian", walletSigned(service)(CreateCustodianTransaction(service)))
+
+	serve := func(signer *uuid.UUID, key ed25519.PrivateKey) *httptest.ResponseRecorder {
+		body, err := json.Marshal(CreateCustodianTransactionRequest{WalletID: walletID, Custodian: "uphold", TransferID: "transfer"})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/"+order.ID.String()+"/transactions/custodian", bytes.NewReader(body))
+		if signer != nil {
+			req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			req.Header.Set(middleware.NonceHeader, uuid.NewV4().String())
+			s := httpsignature.Signature{}
+			s.Algorithm = httpsignature.ED25519
+			s.KeyID = signer.String()
+			s.Headers = []string{"digest", "(request-target)", "date", "nonce"}
+			require.NoError(t, s.Sign(key, crypto.Hash(0), req))
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(nil, nil).Code, "transfers are submitted by the paying wallet")
+	rr := serve(&otherID, otherPrivateKey)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "other wallets cannot submit transfers for the wallet")
+	assert.Contains(t, rr.Body.String(), "custodian_wallet_unsigned")
+	assert.Equal(t, "pending", order.Status)
+
+	rr = serve(&walletID, privateKey)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "paid", order.Status)
+}
+
+func TestCreateCustodianTransactionWithoutWallets(t *testing.T) {
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "pending", Currency: "BAT", TotalPrice: decimal.New(5, 0)})
+	service := &Service{Datastore: ds, custodians: map[string]Custodian{"uphold": &fakeCustodian{}}}
+
+	_, err := service.CreateCustodianTransaction(context.Background(), order.ID, uuid.NewV4(), "uphold", "transfer")
+	assert.Error(t, err, "transfers cannot be tied to a wallet without the wallet service")
+	assert.Empty(t, ds.transactions)
+}
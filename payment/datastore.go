@@ -6,16 +6,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	uuid "github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 
 	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/closers"
 	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/datastore"
 	"github.com/brave-intl/bat-go/utils/inputs"
-	"github.com/brave-intl/bat-go/utils/jsonutils"
 	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/workers"
 
 	// needed for magic migration
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -24,51 +30,214 @@ import (
 // Datastore abstracts over the underlying datastore
 type Datastore interface {
 	grantserver.Datastore
-	// CreateOrder is used to create an order for payments
-	CreateOrder(totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, orderItems []OrderItem) (*Order, error)
+	// CreateOrder is used to create an order for payments, using the voucher discounting it if there is one
+	CreateOrder(ctx context.Context, totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, rate *ExchangeRate, orderItems []OrderItem, voucher *VoucherRedemption) (*Order, error)
+	// CreateOrders creates the orders together, either every order is created or none are
+	CreateOrders(ctx context.Context, orders []NewOrder) ([]Order, error)
 	// GetOrder by ID
 	GetOrder(orderID uuid.UUID) (*Order, error)
+	// ListOrders returns the orders of a merchant matching the filter, in the order of its sort
+	ListOrders(ctx context.Context, filter OrderFilter) ([]Order, error)
 	// UpdateOrder updates an order when it has been paid
-	UpdateOrder(orderID uuid.UUID, status string) error
+	UpdateOrder(ctx context.Context, orderID uuid.UUID, status string) error
+	// GetStaleOrders returns the unpaid orders which have not changed for their merchant's order expiry
+	GetStaleOrders(ctx context.Context, defaultExpiry time.Duration, limit int) ([]uuid.UUID, error)
 	// CreateTransaction creates a transaction
 	CreateTransaction(orderID uuid.UUID, externalTransactionID string, status string, currency string, kind string, amount decimal.Decimal) (*Transaction, error)
+	// InsertCheckoutSession records the checkout session an order is paid through
+	InsertCheckoutSession(ctx context.Context, session *CheckoutSession) error
+	// GetCheckoutSession returns the checkout session of an order, nil when it has none
+	GetCheckoutSession(ctx context.Context, orderID uuid.UUID) (*CheckoutSession, error)
 	// GetTransaction returns a transaction given an external transaction id
 	GetTransaction(externalTransactionID string) (*Transaction, error)
 	// GetTransactions returns all the transactions for a specific order
 	GetTransactions(orderID uuid.UUID) (*[]Transaction, error)
 	// GetPagedMerchantTransactions returns all the transactions for a specific order
 	GetPagedMerchantTransactions(ctx context.Context, merchantID uuid.UUID, pagination *inputs.Pagination) (*[]Transaction, int, error)
-	// GetSumForTransactions gets a decimal sum of for transactions for an order
-	GetSumForTransactions(orderID uuid.UUID) (decimal.Decimal, error)
+	// GetTransactionsUpdatedBetween returns the transactions updated within [from, to), with their merchant
+	GetTransactionsUpdatedBetween(ctx context.Context, from, to time.Time) ([]ExportedTransaction, error)
+	// GetPendingTransactions returns the oldest pending transactions of the kinds
+	GetPendingTransactions(ctx context.Context, kinds []string, limit int) ([]Transaction, error)
+	// SettleTransaction sets the status of a pending transaction, recording it as a payment once completed
+	SettleTransaction(ctx context.Context, transactionID uuid.UUID, status string) (*Transaction, error)
+	// InsertOrderDeposit stores the deposit address of an order, unless the order has one already
+	InsertOrderDeposit(ctx context.Context, deposit *OrderDeposit) error
+	// GetOrderDeposit returns the deposit address of an order, nil when it has none
+	GetOrderDeposit(ctx context.Context, orderID uuid.UUID) (*OrderDeposit, error)
+	// GetOrderDepositsByAddress returns the deposit addresses among the addresses
+	GetOrderDepositsByAddress(ctx context.Context, addresses []string) ([]OrderDeposit, error)
+	// UpdateOrderDeposit sets what the deposit address of an order received
+	UpdateOrderDeposit(ctx context.Context, orderID uuid.UUID, status string, received, excess decimal.Decimal) error
+	// GetChainCursor returns the last block of the chain scanned for deposits, 0 when none was
+	GetChainCursor(ctx context.Context, chain string) (uint64, error)
+	// AdvanceChainCursor moves the cursor of the chain from the last block scanned to the next
+	AdvanceChainCursor(ctx context.Context, chain string, from, to uint64) (bool, error)
+	// CreateVoucher stores a new voucher
+	CreateVoucher(ctx context.Context, voucher *Voucher) (*Voucher, error)
+	// GetVoucher returns the voucher with the code, nil when there is none
+	GetVoucher(ctx context.Context, code string) (*Voucher, error)
+	// ListVouchers returns the vouchers of every merchant, or of the one given, newest first
+	ListVouchers(ctx context.Context, merchantID string, limit int) ([]Voucher, error)
 	// InsertIssuer
 	InsertIssuer(issuer *Issuer) (*Issuer, error)
-	// GetIssuer
+	// GetIssuer returns the currently active issuer of the merchant
 	GetIssuer(merchantID string) (*Issuer, error)
-	// GetIssuerByPublicKey
+	// GetIssuers returns every version of the merchant's issuer, newest first
+	GetIssuers(ctx context.Context, merchantID string) ([]Issuer, error)
+	// RotateIssuer inserts the next version of an issuer, ending the validity of the active versions
+	RotateIssuer(ctx context.Context, issuer *Issuer) (*Issuer, error)
+	// GetIssuerByPublicKey returns the issuer of any version with the public key
 	GetIssuerByPublicKey(publicKey string) (*Issuer, error)
+	// GetIssuerByID returns the issuer of any version with the id, nil if there is none
+	GetIssuerByID(ctx context.Context, id uuid.UUID) (*Issuer, error)
+	// ListIssuers returns the issuers of every merchant, or of the one given, newest first with their usage
+	ListIssuers(ctx context.Context, merchantID string, limit int) ([]IssuerUsage, error)
+	// DisableIssuer stops the issuer signing credentials, nil if there is no such issuer
+	DisableIssuer(ctx context.Context, id uuid.UUID) (*Issuer, error)
 	// InsertOrderCreds
-	InsertOrderCreds(creds *OrderCreds) error
+	InsertOrderCreds(ctx context.Context, creds *OrderCreds) error
 	// GetOrderCreds
 	GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCreds, error)
 	// DeleteOrderCreds
-	DeleteOrderCreds(orderID uuid.UUID) error
+	DeleteOrderCreds(ctx context.Context, orderID uuid.UUID) error
 	// GetOrderCredsByItemID retrieves an order credential by item id
 	GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (*OrderCreds, error)
 	// RunNextOrderJob
 	RunNextOrderJob(ctx context.Context, worker OrderWorker) (bool, error)
+	// GetExpiringCredentialWindows returns the latest windows of time-limited credentials of paid orders which
+	// end before the time
+	GetExpiringCredentialWindows(ctx context.Context, endingBefore time.Time, limit int) ([]CredentialWindow, error)
+	// InsertCredentialWindow adds a window to an item's time-limited credentials, to be signed
+	InsertCredentialWindow(ctx context.Context, creds *OrderCreds) error
+	// RunNextCredentialWindow signs the next window of time-limited credentials waiting to be signed
+	RunNextCredentialWindow(ctx context.Context, worker OrderWorker) (bool, error)
+	// GetOrderEvents returns the log of an order, in sequence
+	GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]OrderLogEvent, error)
+	// GetOrderHistory returns the transitions of an order, in sequence
+	GetOrderHistory(ctx context.Context, orderID uuid.UUID) ([]OrderHistoryEntry, error)
+	// GetOrderPayments returns the ledger of the completed payments made towards an order, oldest first
+	GetOrderPayments(ctx context.Context, orderID uuid.UUID) ([]OrderPayment, error)
+	// DispatchOrderEvents passes the oldest undispatched order events to dispatch, returning how many succeeded
+	DispatchOrderEvents(ctx context.Context, limit int, dispatch func(context.Context, OrderLogEvent) error) (int, error)
+	// PublishOrderEvents passes the oldest unpublished order events of the outbox to publish, returning how many succeeded
+	PublishOrderEvents(ctx context.Context, limit int, publish func(context.Context, OrderLogEvent) error) (int, error)
+	// GetStuckOrders returns the order items whose credentials were requested before the time and are unsigned
+	GetStuckOrders(ctx context.Context, requestedBefore time.Time, limit int) ([]StuckOrder, error)
+	// RebuildOrderProjection overwrites the row of an order with its state replayed from its log
+	RebuildOrderProjection(ctx context.Context, projection *OrderProjection) error
 
 	// GetKeys ret
 	GetKeys(merchant string, showExpired bool) (*[]Key, error)
 	// CreateKey
-	CreateKey(merchant string, name string, encryptedSecretKey string, nonce string) (*Key, error)
+	CreateKey(merchant string, name string, encryptedSecretKey string, nonce string, scopes []string, tokenHash string) (*Key, error)
 	// DeleteKey
 	DeleteKey(id uuid.UUID, delaySeconds int) (*Key, error)
+	// GetKey returns an unexpired key by id
+	GetKey(id uuid.UUID) (*Key, error)
+	// GetKeyByTokenHash returns an unexpired key by the hash of its token
+	GetKeyByTokenHash(tokenHash string) (*Key, error)
+	// GetKeyWithSecret returns an unexpired key by id with its decrypted secret
+	GetKeyWithSecret(id uuid.UUID) (*Key, error)
+	// MarkKeyUsed records that a key was used to authenticate a request
+	MarkKeyUsed(id uuid.UUID) error
+	// UpdateKeyRateLimit sets the rate limit and daily quota of a key
+	UpdateKeyRateLimit(id uuid.UUID, perMinute *int, burst int, dailyQuota *int) (*Key, error)
+
+	// CreateMerchant onboards a merchant
+	CreateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error)
+	// GetMerchant returns a merchant which has not been deleted
+	GetMerchant(ctx context.Context, id string) (*Merchant, error)
+	// GetMerchants returns all merchants which have not been deleted
+	GetMerchants(ctx context.Context) ([]Merchant, error)
+	// UpdateMerchant updates the settings of a merchant
+	UpdateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error)
+	// GetMerchantSettings returns the limits configured for a merchant, nil when none are
+	GetMerchantSettings(ctx context.Context, merchantID string) (*MerchantSettings, error)
+	// UpsertMerchantSettings sets the limits of a merchant
+	UpsertMerchantSettings(ctx context.Context, settings *MerchantSettings) (*MerchantSettings, error)
+	// DeleteMerchant marks a merchant as deleted
+	DeleteMerchant(ctx context.Context, id string) (*Merchant, error)
+	// GetMerchantEncryptionKey returns the public key registered for encrypting payloads to the merchant
+	GetMerchantEncryptionKey(merchantID string) (*MerchantEncryptionKey, error)
+	// SetMerchantEncryptionKey registers the public key for encrypting payloads to the merchant
+	SetMerchantEncryptionKey(merchantID string, jwk string) (*MerchantEncryptionKey, error)
+	// CreateWebhookSecret adds a new version of the merchant's webhook secret, expiring the others after the overlap
+	CreateWebhookSecret(ctx context.Context, merchantID string, encryptedSecret string, nonce string, overlap time.Duration) (*WebhookSecret, error)
+	// GetWebhookSecrets returns the active webhook secrets of a merchant, newest first
+	GetWebhookSecrets(ctx context.Context, merchantID string) ([]WebhookSecret, error)
+	// InsertWebhookDelivery records a webhook delivery to a merchant
+	InsertWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// GetWebhookDeliveries returns the most recent webhook deliveries to a merchant
+	GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]WebhookDelivery, error)
+	// DeleteWebhookDeliveries removes deliveries made before the time, returning how many were removed
+	DeleteWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+	// GetCredentialRedemptions returns the redemptions of the credentials with the token preimages which were redeemed
+	GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) ([]RedeemedCredential, error)
+	// InsertCredentialRedemptions records redeemed credentials, within the transaction if there is one
+	InsertCredentialRedemptions(ctx context.Context, tx *sqlx.Tx, redemptions []RedeemedCredential) error
+	// RecordMerchantUsage adds to the merchant's usage of the day, within the transaction if there is one
+	RecordMerchantUsage(ctx context.Context, tx *sqlx.Tx, usage MerchantUsage) error
+	// GetMerchantUsage returns the monthly usage of a merchant from the month of since, oldest first
+	GetMerchantUsage(ctx context.Context, merchantID string, since time.Time) ([]MerchantUsage, error)
+	// RollupMerchantUsage totals the daily usage before the time by month, returning how many months were rolled up
+	RollupMerchantUsage(ctx context.Context, before time.Time) (int64, error)
+	// CreateMerchantSigningKey registers a key the merchant signs order creation requests with
+	CreateMerchantSigningKey(ctx context.Context, key *MerchantSigningKey) (*MerchantSigningKey, error)
+	// GetMerchantSigningKey returns an unrevoked merchant signing key by id
+	GetMerchantSigningKey(ctx context.Context, id uuid.UUID) (*MerchantSigningKey, error)
+	// GetMerchantSigningKeys returns the unrevoked signing keys of a merchant
+	GetMerchantSigningKeys(ctx context.Context, merchantID string) ([]MerchantSigningKey, error)
+	// RevokeMerchantSigningKey revokes a signing key of a merchant
+	RevokeMerchantSigningKey(ctx context.Context, merchantID string, id uuid.UUID) (*MerchantSigningKey, error)
+	// InsertAuditEvent appends an event to the audit log
+	InsertAuditEvent(ctx context.Context, event *middleware.AuditEvent) error
+	// GetAuditEvents returns audit events, newest first, optionally filtered by actor
+	GetAuditEvents(ctx context.Context, actor string, since time.Time, limit int) ([]middleware.AuditEvent, error)
+	// ReserveIdempotencyKey records a request under its idempotency key unless the key is in use
+	ReserveIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest, ttl time.Duration) (*middleware.IdempotentRequest, bool, error)
+	// CompleteIdempotencyKey stores the response to the request of an idempotency key
+	CompleteIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest) error
+	// ReleaseIdempotencyKey forgets an idempotency key so its request can be retried
+	ReleaseIdempotencyKey(ctx context.Context, scope string, key string) error
 
 	// Votes
 	GetUncommittedVotesForUpdate(ctx context.Context) (*sqlx.Tx, []*VoteRecord, error)
 	CommitVote(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) error
 	MarkVoteErrored(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) error
 	InsertVote(ctx context.Context, vr VoteRecord) error
+	// GetVotesCreatedBetween returns the votes queued within [from, to), without their credentials
+	GetVotesCreatedBetween(ctx context.Context, from, to time.Time) ([]ExportedVote, error)
+	// RollupVoteTallies adds up to limit processed votes to the vote tallies, returning how many votes were
+	// tallied and how many tallies they changed
+	RollupVoteTallies(ctx context.Context, limit int) (int64, int64, error)
+	// GetVoteTallies returns the tallies of a channel, or of every channel, for the days within [from, to)
+	GetVoteTallies(ctx context.Context, channel string, from, to time.Time) ([]VoteTally, error)
+	// InsertVoteTallyRollup records a run of the vote tally rollup
+	InsertVoteTallyRollup(ctx context.Context, rollup *VoteTallyRollup) error
+	// GetLatestVoteTallyRollup returns the run of the vote tally rollup which started last, nil if it never ran
+	GetLatestVoteTallyRollup(ctx context.Context) (*VoteTallyRollup, error)
+	// ClaimOrderTrial takes the trial of an order for a wallet, unless another wallet took it or the wallet
+	// took limit trials with the merchant
+	ClaimOrderTrial(ctx context.Context, orderID, walletID uuid.UUID, merchantID string, limit int) error
+	// InsertOrderReceipt stores the receipt of an order, unless the order has one already
+	InsertOrderReceipt(ctx context.Context, receipt *OrderReceipt) error
+	// GetOrderReceipt returns the receipt of an order, nil when it has none
+	GetOrderReceipt(ctx context.Context, orderID uuid.UUID) (*OrderReceipt, error)
+}
+
+// ReadOnlyDatastore includes the database methods on the read paths which can be served by a read replica
+type ReadOnlyDatastore interface {
+	grantserver.Datastore
+	// GetOrder by ID
+	GetOrder(orderID uuid.UUID) (*Order, error)
+	// GetIssuerByPublicKey returns the issuer of any version with the public key
+	GetIssuerByPublicKey(publicKey string) (*Issuer, error)
+	// GetOrderCreds gets the credentials for an order
+	GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCreds, error)
+	// GetOrderCredsByItemID retrieves an order credential by item id
+	GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (*OrderCreds, error)
+	// GetCredentialRedemptions returns the redemptions of the credentials with the token preimages which were redeemed
+	GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) ([]RedeemedCredential, error)
 }
 
 // VoteRecord - how the ac votes are stored in the queue
@@ -79,8 +248,16 @@ type VoteRecord struct {
 	VoteEventBinary    []byte
 	Erred              bool
 	Processed          bool
+	RequestID          string
 }
 
+var (
+	// transactionsDualWrite moves transactions to transactions_v2, see DUAL_WRITE_TRANSACTIONS
+	transactionsDualWrite = datastore.NewDualWrite("transactions")
+	// votesDualWrite moves vote_drain to vote_drain_v2, see DUAL_WRITE_VOTE_DRAIN
+	votesDualWrite = datastore.NewDualWrite("vote_drain")
+)
+
 // Postgres is a Datastore wrapper around a postgres database
 type Postgres struct {
 	grantserver.Postgres
@@ -97,16 +274,27 @@ func NewPostgres(databaseURL string, performMigration bool, migrationTrack strin
 	return nil, err
 }
 
+// NewReadOnlyPostgres creates a new Postgres RO Datastore
+func NewReadOnlyPostgres(databaseURL string, performMigration bool, migrationTrack string, dbStatsPrefix ...string) (ReadOnlyDatastore, error) {
+	pg, err := grantserver.NewPostgres(databaseURL, performMigration, migrationTrack, dbStatsPrefix...)
+	if pg != nil {
+		return &ReadOnlyDatastoreWithPrometheus{
+			base: &Postgres{*pg}, instanceName: "payment_ro_datastore",
+		}, err
+	}
+	return nil, err
+}
+
 // CreateKey creates an encrypted key in the database based on the merchant
-func (pg *Postgres) CreateKey(merchant string, name string, encryptedSecretKey string, nonce string) (*Key, error) {
+func (pg *Postgres) CreateKey(merchant string, name string, encryptedSecretKey string, nonce string, scopes []string, tokenHash string) (*Key, error) {
 	// interface and create an api key
 	var key Key
 	err := pg.RawDB().Get(&key, `
-			INSERT INTO api_keys (merchant_id, name, encrypted_secret_key, nonce)
-			VALUES ($1, $2, $3, $4)
-			RETURNING id, name, merchant_id, encrypted_secret_key, nonce, created_at, expiry
+			INSERT INTO api_keys (merchant_id, name, encrypted_secret_key, nonce, scopes, token_hash)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, name, merchant_id, encrypted_secret_key, nonce, created_at, expiry, scopes, token_hash
 		`,
-		merchant, name, encryptedSecretKey, nonce)
+		merchant, name, encryptedSecretKey, nonce, pq.Array(scopes), tokenHash)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create key for merchant: %w", err)
@@ -138,6 +326,98 @@ func (pg *Postgres) DeleteKey(id uuid.UUID, delaySeconds int) (*Key, error) {
 	return &key, nil
 }
 
+// GetKey returns an unexpired key by id, without its secret
+func (pg *Postgres) GetKey(id uuid.UUID) (*Key, error) {
+	var key Key
+	err := pg.RawDB().Get(&key, `
+			SELECT id, name, merchant_id, created_at, expiry, rate_limit_per_minute, rate_limit_burst, daily_quota,
+				scopes, token_hash, last_used_at
+			FROM api_keys
+			WHERE id = $1 AND (expiry IS NULL or expiry > CURRENT_TIMESTAMP)
+		`, id.String())
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetKeyByTokenHash returns an unexpired key by the hash of its token, without its secret
+func (pg *Postgres) GetKeyByTokenHash(tokenHash string) (*Key, error) {
+	var key Key
+	err := pg.RawDB().Get(&key, `
+			SELECT id, name, merchant_id, created_at, expiry, rate_limit_per_minute, rate_limit_burst, daily_quota,
+				scopes, token_hash, last_used_at
+			FROM api_keys
+			WHERE token_hash = $1 AND (expiry IS NULL or expiry > CURRENT_TIMESTAMP)
+		`, tokenHash)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get key by token: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetKeyWithSecret returns an unexpired key by id with its decrypted secret, which requests are signed with
+func (pg *Postgres) GetKeyWithSecret(id uuid.UUID) (*Key, error) {
+	var key Key
+	err := pg.RawDB().Get(&key, `
+			SELECT id, name, merchant_id, encrypted_secret_key, nonce, created_at, expiry, scopes
+			FROM api_keys
+			WHERE id = $1 AND (expiry IS NULL or expiry > CURRENT_TIMESTAMP)
+		`, id.String())
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+	if err := key.SetSecretKey(); err != nil {
+		return nil, fmt.Errorf("failed to set secret key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// MarkKeyUsed records that a key was used, at most once a minute to avoid a write per request
+func (pg *Postgres) MarkKeyUsed(id uuid.UUID) error {
+	_, err := pg.RawDB().Exec(`
+			UPDATE api_keys
+			SET last_used_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - interval '1 minute')
+		`, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to mark key used: %w", err)
+	}
+	return nil
+}
+
+// UpdateKeyRateLimit sets the rate limit and daily quota of a key, nil values remove the limit
+func (pg *Postgres) UpdateKeyRateLimit(id uuid.UUID, perMinute *int, burst int, dailyQuota *int) (*Key, error) {
+	var key Key
+	err := pg.RawDB().Get(&key, `
+			UPDATE api_keys
+			SET rate_limit_per_minute = $2, rate_limit_burst = $3, daily_quota = $4
+			WHERE id = $1
+			RETURNING id, name, merchant_id, created_at, expiry, rate_limit_per_minute, rate_limit_burst, daily_quota,
+				scopes, last_used_at
+		`, id.String(), perMinute, burst, dailyQuota)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update key rate limit: %w", err)
+	}
+
+	return &key, nil
+}
+
 // GetKeys returns a list of active API keys
 func (pg *Postgres) GetKeys(merchant string, showExpired bool) (*[]Key, error) {
 	expiredQuery := "AND (expiry IS NULL or expiry > CURRENT_TIMESTAMP)"
@@ -147,7 +427,9 @@ func (pg *Postgres) GetKeys(merchant string, showExpired bool) (*[]Key, error) {
 
 	var keys []Key
 	err := pg.RawDB().Select(&keys, `
-			SELECT id, name, merchant_id, created_at, expiry FROM api_keys
+			SELECT id, name, merchant_id, created_at, expiry, rate_limit_per_minute, rate_limit_burst, daily_quota,
+				scopes, last_used_at
+			FROM api_keys
 			WHERE merchant_id = $1
 		`+expiredQuery+" ORDER BY name, created_at",
 		merchant)
@@ -159,17 +441,32 @@ func (pg *Postgres) GetKeys(merchant string, showExpired bool) (*[]Key, error) {
 	return &keys, nil
 }
 
-// CreateOrder creates orders given the total price, merchant ID, status and items of the order
-func (pg *Postgres) CreateOrder(totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, orderItems []OrderItem) (*Order, error) {
+// CreateOrder creates orders given the total price, merchant ID, status and items of the order. Orders
+// priced in fiat carry the BAT exchange rate they were placed at, if any, and their total in BAT
+func (pg *Postgres) CreateOrder(ctx context.Context, totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, rate *ExchangeRate, orderItems []OrderItem, voucher *VoucherRedemption) (*Order, error) {
 	tx := pg.RawDB().MustBegin()
+	defer pg.RollbackTx(tx)
+
+	var (
+		exchangeRate  *decimal.Decimal
+		batTotalPrice *decimal.Decimal
+		ratedAt       *time.Time
+	)
+	if currency == "BAT" {
+		batTotalPrice = &totalPrice
+	} else if rate != nil {
+		batTotal := rate.ToBAT(totalPrice)
+		exchangeRate, batTotalPrice, ratedAt = &rate.Rate, &batTotal, &rate.UpdatedAt
+	}
 
 	var order Order
 	err := tx.Get(&order, `
-			INSERT INTO orders (total_price, merchant_id, status, currency, location)
-			VALUES ($1, $2, $3, $4, $5)
-			RETURNING id, created_at, currency, updated_at, total_price, merchant_id, location, status
+			INSERT INTO orders (total_price, merchant_id, status, currency, location, exchange_rate, bat_total_price, rated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id, created_at, currency, updated_at, total_price, merchant_id, location, status,
+				exchange_rate, bat_total_price, rated_at
 		`,
-		totalPrice, merchantID, status, currency, location)
+		totalPrice, merchantID, status, currency, location, exchangeRate, batTotalPrice, ratedAt)
 
 	if err != nil {
 		return nil, err
@@ -189,20 +486,214 @@ func (pg *Postgres) CreateOrder(totalPrice decimal.Decimal, merchantID string, s
 			return nil, err
 		}
 	}
+	order.Items = orderItems
+
+	if voucher != nil {
+		// the use is counted with the order, so a voucher used up since it was applied fails the order
+		result, err := tx.ExecContext(ctx, `
+				UPDATE vouchers SET uses = uses + 1
+				WHERE id = $1 AND (max_uses IS NULL OR uses < max_uses)
+					AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			`, voucher.VoucherID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use voucher: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return nil, ErrVoucherInvalid
+		}
+		_, err = tx.ExecContext(ctx, `
+				INSERT INTO voucher_redemptions (order_id, voucher_id, discount) VALUES ($1, $2, $3)
+			`, order.ID, voucher.VoucherID, voucher.Discount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record voucher redemption: %w", err)
+		}
+		order.Voucher = voucher
+	}
+
+	if _, err := appendOrderEvent(ctx, tx, order.ID, OrderLogCreated, newOrderCreatedPayload(&order)); err != nil {
+		return nil, err
+	}
+	priced := orderPricedPayload{
+		TotalPrice:    order.TotalPrice,
+		Currency:      order.Currency,
+		ExchangeRate:  order.ExchangeRate,
+		BATTotalPrice: order.BATTotalPrice,
+		RatedAt:       order.RatedAt,
+	}
+	if voucher != nil {
+		priced.Voucher, priced.Discount = &voucher.Code, &voucher.Discount
+	}
+	if _, err := appendOrderEvent(ctx, tx, order.ID, OrderLogPriced, priced); err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
 
-	order.Items = orderItems
-
 	return &order, nil
 }
 
+// maxRowsPerInsert is the most rows inserted by one statement, keeping within the parameters postgres allows
+var maxRowsPerInsert = 1000
+
+// CreateOrders creates the orders in one transaction, inserting their rows and items with multi-row inserts,
+// either every order is created or none are
+func (pg *Postgres) CreateOrders(ctx context.Context, newOrders []NewOrder) ([]Order, error) {
+	if len(newOrders) == 0 {
+		return []Order{}, nil
+	}
+
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer pg.RollbackTx(tx)
+
+	// ids are generated here so the orders and their items can be inserted together
+	orderIDs := make([]uuid.UUID, len(newOrders))
+	orderRows := make([][]interface{}, len(newOrders))
+	itemRows := [][]interface{}{}
+	for i, o := range newOrders {
+		var (
+			exchangeRate  *decimal.Decimal
+			batTotalPrice *decimal.Decimal
+			ratedAt       *time.Time
+		)
+		if o.Currency == "BAT" {
+			total := o.TotalPrice
+			batTotalPrice = &total
+		} else if o.Rate != nil {
+			batTotal := o.Rate.ToBAT(o.TotalPrice)
+			exchangeRate, batTotalPrice, ratedAt = &o.Rate.Rate, &batTotal, &o.Rate.UpdatedAt
+		}
+		orderIDs[i] = uuid.NewV4()
+		orderRows[i] = []interface{}{orderIDs[i], o.TotalPrice, o.MerchantID, o.Status, o.Currency, o.Location,
+			exchangeRate, batTotalPrice, ratedAt}
+		for _, item := range o.Items {
+			itemRows = append(itemRows, []interface{}{orderIDs[i], item.SKU, item.Quantity, item.Price, item.Currency,
+				item.Subtotal, item.Location, item.Description, item.CredentialType})
+		}
+	}
+
+	created := []Order{}
+	for _, rows := range chunkRows(orderRows) {
+		var inserted []Order
+		err := tx.SelectContext(ctx, &inserted, `
+			INSERT INTO orders (id, total_price, merchant_id, status, currency, location, exchange_rate, bat_total_price, rated_at)
+			VALUES `+valuesPlaceholders(rows)+`
+			RETURNING id, created_at, currency, updated_at, total_price, merchant_id, location, status,
+				exchange_rate, bat_total_price, rated_at
+		`, flattenRows(rows)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert orders: %w", err)
+		}
+		created = append(created, inserted...)
+	}
+
+	items := map[uuid.UUID][]OrderItem{}
+	for _, rows := range chunkRows(itemRows) {
+		var inserted []OrderItem
+		err := tx.SelectContext(ctx, &inserted, `
+			INSERT INTO order_items (order_id, sku, quantity, price, currency, subtotal, location, description, credential_type)
+			VALUES `+valuesPlaceholders(rows)+`
+			RETURNING id, order_id, sku, created_at, updated_at, currency, quantity, price, location, description, credential_type, (quantity * price) as subtotal
+		`, flattenRows(rows)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert order items: %w", err)
+		}
+		for _, item := range inserted {
+			items[item.OrderID] = append(items[item.OrderID], item)
+		}
+	}
+
+	// the rows returned are not guaranteed to be in the order they were inserted
+	byID := make(map[uuid.UUID]Order, len(created))
+	for _, order := range created {
+		byID[order.ID] = order
+	}
+	orders := make([]Order, len(orderIDs))
+	for i, id := range orderIDs {
+		order, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("order %s was not inserted", id)
+		}
+		order.Items = items[id]
+		if order.Items == nil {
+			order.Items = []OrderItem{}
+		}
+
+		if _, err := appendOrderEvent(ctx, tx, order.ID, OrderLogCreated, newOrderCreatedPayload(&order)); err != nil {
+			return nil, err
+		}
+		priced := orderPricedPayload{
+			TotalPrice:    order.TotalPrice,
+			Currency:      order.Currency,
+			ExchangeRate:  order.ExchangeRate,
+			BATTotalPrice: order.BATTotalPrice,
+			RatedAt:       order.RatedAt,
+		}
+		if _, err := appendOrderEvent(ctx, tx, order.ID, OrderLogPriced, priced); err != nil {
+			return nil, err
+		}
+		orders[i] = order
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// chunkRows splits rows into the chunks inserted by one statement each
+func chunkRows(rows [][]interface{}) [][][]interface{} {
+	chunks := [][][]interface{}{}
+	for len(rows) > maxRowsPerInsert {
+		chunks = append(chunks, rows[:maxRowsPerInsert])
+		rows = rows[maxRowsPerInsert:]
+	}
+	if len(rows) > 0 {
+		chunks = append(chunks, rows)
+	}
+	return chunks
+}
+
+// valuesPlaceholders returns the placeholders of a multi-row insert of the rows, such as ($1, $2), ($3, $4)
+func valuesPlaceholders(rows [][]interface{}) string {
+	var b strings.Builder
+	n := 1
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", n)
+			n++
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// flattenRows returns the arguments of a multi-row insert of the rows
+func flattenRows(rows [][]interface{}) []interface{} {
+	args := []interface{}{}
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+	return args
+}
+
 // GetOrder queries the database and returns an order
 func (pg *Postgres) GetOrder(orderID uuid.UUID) (*Order, error) {
 	statement := `
-		SELECT id, created_at, currency, updated_at, total_price, merchant_id, location, status
+		SELECT id, created_at, currency, updated_at, total_price, merchant_id, location, status,
+			exchange_rate, bat_total_price, rated_at
 		FROM orders WHERE id = $1`
 	order := Order{}
 	err := pg.RawDB().Get(&order, statement, orderID)
@@ -226,6 +717,42 @@ func (pg *Postgres) GetOrder(orderID uuid.UUID) (*Order, error) {
 	return &order, nil
 }
 
+// ListOrders returns the orders of a merchant matching the filter, in the order of its sort, with their items
+func (pg *Postgres) ListOrders(ctx context.Context, filter OrderFilter) ([]Order, error) {
+	statement, args := listOrdersStatement(filter)
+	orders := []Order{}
+	if err := pg.RawDB().SelectContext(ctx, &orders, statement, args...); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	orderIDs := make([]uuid.UUID, len(orders))
+	for i := range orders {
+		orderIDs[i] = orders[i].ID
+	}
+	items := []OrderItem{}
+	err := pg.RawDB().SelectContext(ctx, &items, `
+		SELECT id, order_id, sku, created_at, updated_at, currency, quantity, price, (quantity * price) as subtotal, location, description, credential_type
+		FROM order_items WHERE order_id = ANY($1)`, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list order items: %w", err)
+	}
+
+	itemsByOrder := map[uuid.UUID][]OrderItem{}
+	for _, item := range items {
+		itemsByOrder[item.OrderID] = append(itemsByOrder[item.OrderID], item)
+	}
+	for i := range orders {
+		orders[i].Items = itemsByOrder[orders[i].ID]
+		if orders[i].Items == nil {
+			orders[i].Items = []OrderItem{}
+		}
+	}
+	return orders, nil
+}
+
 // GetPagedMerchantTransactions - get a paginated list of transactions for a merchant
 func (pg *Postgres) GetPagedMerchantTransactions(
 	ctx context.Context, merchantID uuid.UUID, pagination *inputs.Pagination) (*[]Transaction, int, error) {
@@ -297,51 +824,241 @@ func (pg *Postgres) GetPagedMerchantTransactions(
 
 // GetTransactions returns the list of transactions given an orderID
 func (pg *Postgres) GetTransactions(orderID uuid.UUID) (*[]Transaction, error) {
-	statement := `
+	read := func(table string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			statement := `
 		SELECT id, order_id, created_at, updated_at, external_transaction_id, status, currency, kind, amount
-		FROM transactions WHERE order_id = $1`
-	transactions := []Transaction{}
-	err := pg.RawDB().Select(&transactions, statement, orderID)
+		FROM ` + table + ` WHERE order_id = $1 ORDER BY created_at, id`
+			transactions := []Transaction{}
+			err := pg.RawDB().Select(&transactions, statement, orderID)
+
+			if err != nil {
+				return nil, err
+			}
 
+			return &transactions, nil
+		}
+	}
+	transactions, err := transactionsDualWrite.Read(context.Background(), "GetTransactions",
+		read("transactions"), read("transactions_v2"))
 	if err != nil {
 		return nil, err
 	}
+	return transactions.(*[]Transaction), nil
+}
 
-	return &transactions, nil
+// GetTransactionsUpdatedBetween returns the transactions updated within [from, to), with their merchant
+func (pg *Postgres) GetTransactionsUpdatedBetween(ctx context.Context, from, to time.Time) ([]ExportedTransaction, error) {
+	statement := `
+		SELECT t.id, t.order_id, t.created_at, t.updated_at, t.external_transaction_id, t.status, t.currency,
+			t.kind, t.amount, o.merchant_id
+		FROM transactions t JOIN orders o ON o.id = t.order_id
+		WHERE t.updated_at >= $1 AND t.updated_at < $2
+		ORDER BY t.updated_at, t.id`
+	transactions := []ExportedTransaction{}
+	if err := pg.RawDB().SelectContext(ctx, &transactions, statement, from, to); err != nil {
+		return nil, fmt.Errorf("failed to get transactions for export: %w", err)
+	}
+	return transactions, nil
 }
 
 // GetTransaction returns a single of transaction given an external transaction Id
 func (pg *Postgres) GetTransaction(externalTransactionID string) (*Transaction, error) {
-	statement := `
+	read := func(table string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			statement := `
 		SELECT id, order_id, created_at, updated_at, external_transaction_id, status, currency, kind, amount
-		FROM transactions WHERE external_transaction_id = $1`
-	transaction := Transaction{}
-	err := pg.RawDB().Get(&transaction, statement, externalTransactionID)
+		FROM ` + table + ` WHERE external_transaction_id = $1`
+			transaction := Transaction{}
+			err := pg.RawDB().Get(&transaction, statement, externalTransactionID)
 
-	if err == sql.ErrNoRows {
-		return nil, nil
-	} else if err != nil {
+			if err == sql.ErrNoRows {
+				return (*Transaction)(nil), nil
+			} else if err != nil {
+				return nil, err
+			}
+
+			return &transaction, nil
+		}
+	}
+	transaction, err := transactionsDualWrite.Read(context.Background(), "GetTransaction",
+		read("transactions"), read("transactions_v2"))
+	if err != nil {
 		return nil, err
 	}
+	return transaction.(*Transaction), nil
+}
 
-	return &transaction, nil
+// UpdateOrder updates the orders status, appending the change to the order's events.
+// 	Status should either be one of pending, paid, fulfilled, canceled, refunded or expired.
+func (pg *Postgres) UpdateOrder(ctx context.Context, orderID uuid.UUID, status string) error {
+	return pg.WithTxRetry(ctx, "UpdateOrder", func(tx *sqlx.Tx) error {
+		var previous string
+		err := tx.Get(&previous, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+		if err == sql.ErrNoRows {
+			return errors.New("no rows updated")
+		} else if err != nil {
+			return err
+		}
+		// an order is paid by whichever of its transactions completes it, only the first change is an event
+		if previous == status {
+			return nil
+		}
+		// an order is only expired while it is unpaid, whatever it moved to since it was found stale
+		if status == OrderLogExpired && previous != "pending" {
+			return errOrderNotStale
+		}
+		// the row is locked, so an order can't be paid while it is being canceled
+		if previous == OrderLogCanceled {
+			return ErrOrderCanceled
+		}
+		// the credentials of a refunded order are revoked, paying it again would not give them back
+		if previous == OrderLogRefunded {
+			return ErrOrderRefunded
+		}
+		if status == OrderLogCanceled && previous != "pending" {
+			return ErrOrderNotCancelable
+		}
+		if status == OrderLogRefunded && previous != "paid" {
+			return ErrOrderNotRefundable
+		}
+		if previous == OrderLogExpired && status != "pending" {
+			return ErrOrderExpired
+		}
+		if status == "pending" && previous != OrderLogExpired {
+			return ErrOrderNotExpired
+		}
+
+		// the use of an expired order's voucher is given back, and taken again if the order is reactivated
+		if status == OrderLogExpired {
+			_, err = tx.Exec(`
+				UPDATE vouchers SET uses = uses - 1
+				WHERE id = (SELECT voucher_id FROM voucher_redemptions WHERE order_id = $1)
+			`, orderID)
+			if err != nil {
+				return fmt.Errorf("failed to release voucher: %w", err)
+			}
+		}
+		// a refund reverses what was paid towards the order in each currency, so its ledger no longer settles it
+		if status == OrderLogRefunded {
+			_, err = tx.Exec(`
+				INSERT INTO order_payments (order_id, kind, currency, amount)
+				SELECT order_id, 'refund', currency, -sum(amount)
+				FROM order_payments
+				WHERE order_id = $1
+				GROUP BY order_id, currency
+				HAVING sum(amount) <> 0
+			`, orderID)
+			if err != nil {
+				return fmt.Errorf("failed to reverse order payments: %w", err)
+			}
+		}
+		if previous == OrderLogExpired {
+			var voucherID uuid.UUID
+			err = tx.Get(&voucherID, `SELECT voucher_id FROM voucher_redemptions WHERE order_id = $1`, orderID)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err == nil {
+				result, err := tx.Exec(`
+					UPDATE vouchers SET uses = uses + 1
+					WHERE id = $1 AND (max_uses IS NULL OR uses < max_uses)
+						AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				`, voucherID)
+				if err != nil {
+					return fmt.Errorf("failed to use voucher: %w", err)
+				}
+				if n, err := result.RowsAffected(); err != nil || n == 0 {
+					return ErrVoucherInvalid
+				}
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE orders set status = $1, updated_at = CURRENT_TIMESTAMP where id = $2`, status, orderID)
+		if err != nil {
+			return err
+		}
+		_, err = appendOrderEvent(ctx, tx, orderID, orderStatusEvent(status), orderStatusPayload{Status: status, Previous: previous})
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// GetStaleOrders returns the unpaid orders which have not changed for their merchant's order expiry, or the
+// default expiry for merchants without one, oldest first. Orders never expire when neither is set, and
+// trials, which are never paid, do not expire
+func (pg *Postgres) GetStaleOrders(ctx context.Context, defaultExpiry time.Duration, limit int) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	err := pg.RawDB().SelectContext(ctx, &ids, `
+			SELECT o.id
+			FROM orders o
+			LEFT JOIN merchant_settings s ON s.merchant_id = o.merchant_id
+			WHERE o.status = 'pending' AND o.total_price > 0
+				AND o.updated_at < CURRENT_TIMESTAMP - make_interval(mins => coalesce(s.order_expiry_minutes, nullif($1, 0)))
+			ORDER BY o.updated_at
+			LIMIT $2
+		`, int(defaultExpiry/time.Minute), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale orders: %w", err)
+	}
+	return ids, nil
 }
 
-// UpdateOrder updates the orders status.
-// 	Status should either be one of pending, paid, fulfilled, or canceled.
-func (pg *Postgres) UpdateOrder(orderID uuid.UUID, status string) error {
-	result, err := pg.RawDB().Exec(`UPDATE orders set status = $1, updated_at = CURRENT_TIMESTAMP where id = $2`, status, orderID)
+// InsertCheckoutSession records the checkout session an order is paid through
+func (pg *Postgres) InsertCheckoutSession(ctx context.Context, session *CheckoutSession) error {
+	return pg.RawDB().GetContext(ctx, &session.CreatedAt, `
+			INSERT INTO order_checkout_sessions (order_id, provider, session_id, url)
+			VALUES ($1, $2, $3, $4)
+			RETURNING created_at
+		`, session.OrderID, session.Provider, session.SessionID, session.URL)
+}
 
+// InsertOrderReceipt stores the receipt of an order, unless the order has one already
+func (pg *Postgres) InsertOrderReceipt(ctx context.Context, receipt *OrderReceipt) error {
+	_, err := pg.RawDB().ExecContext(ctx, `
+			INSERT INTO order_receipts (order_id, key_id, receipt)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (order_id) DO NOTHING
+		`, receipt.OrderID, receipt.KeyID, receipt.Receipt)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to insert order receipt: %w", err)
 	}
+	return nil
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 0 || err != nil {
-		return errors.New("no rows updated")
+// GetOrderReceipt returns the receipt of an order, nil when it has none
+func (pg *Postgres) GetOrderReceipt(ctx context.Context, orderID uuid.UUID) (*OrderReceipt, error) {
+	var receipt OrderReceipt
+	err := pg.RawDB().GetContext(ctx, &receipt, `
+			SELECT order_id, key_id, receipt, created_at
+			FROM order_receipts
+			WHERE order_id = $1
+		`, orderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get order receipt: %w", err)
 	}
+	return &receipt, nil
+}
 
-	return nil
+// GetCheckoutSession returns the checkout session of an order, nil when it has none
+func (pg *Postgres) GetCheckoutSession(ctx context.Context, orderID uuid.UUID) (*CheckoutSession, error) {
+	var session CheckoutSession
+	err := pg.RawDB().GetContext(ctx, &session, `
+			SELECT order_id, provider, session_id, url, created_at
+			FROM order_checkout_sessions
+			WHERE order_id = $1
+		`, orderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get checkout session: %w", err)
+	}
+	return &session, nil
 }
 
 // CreateTransaction creates a transaction given an orderID, externalTransactionID, currency, and a kind of transaction
@@ -350,17 +1067,48 @@ func (pg *Postgres) CreateTransaction(orderID uuid.UUID, externalTransactionID s
 	defer pg.RollbackTx(tx)
 
 	var transaction Transaction
-	err := tx.Get(&transaction,
-		`
+	err := transactionsDualWrite.WriteTx(context.Background(), tx, "CreateTransaction",
+		func() error {
+			return tx.Get(&transaction,
+				`
 			INSERT INTO transactions (order_id, external_transaction_id, status, currency, kind, amount)
 			VALUES ($1, $2, $3, $4, $5, $6)
 			RETURNING id, order_id, created_at, updated_at, external_transaction_id, status, currency, kind, amount
 	`, orderID, externalTransactionID, status, currency, kind, amount)
-
+		},
+		func() error {
+			// the merchant is denormalized onto the new layout
+			result, err := tx.Exec(`
+			INSERT INTO transactions_v2 (id, order_id, merchant_id, created_at, updated_at,
+				external_transaction_id, status, currency, kind, amount)
+			SELECT $1, o.id, o.merchant_id, $2, $3, $4, $5, $6, $7, $8
+			FROM orders o WHERE o.id = $9
+	`, transaction.ID, transaction.CreatedAt, transaction.UpdatedAt, transaction.ExternalTransactionID,
+				transaction.Status, transaction.Currency, transaction.Kind, transaction.Amount, orderID)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n != 1 {
+				return fmt.Errorf("order %s not found", orderID)
+			}
+			return nil
+		})
 	if err != nil {
 		return nil, err
 	}
 
+	// completed transactions are payments towards the order, recorded in its ledger
+	if transaction.Status == "completed" {
+		_, err = tx.Exec(`
+			INSERT INTO order_payments (order_id, transaction_id, kind, currency, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (transaction_id) DO NOTHING
+		`, orderID, transaction.ID, transaction.Kind, transaction.Currency, transaction.Amount, transaction.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record order payment: %w", err)
+		}
+	}
+
 	err = tx.Commit()
 
 	if err != nil {
@@ -370,89 +1118,430 @@ func (pg *Postgres) CreateTransaction(orderID uuid.UUID, externalTransactionID s
 	return &transaction, nil
 }
 
-// GetSumForTransactions returns the calculated sum
-func (pg *Postgres) GetSumForTransactions(orderID uuid.UUID) (decimal.Decimal, error) {
-	var sum decimal.Decimal
-
-	err := pg.RawDB().Get(&sum, `
-		SELECT COALESCE(SUM(amount), 0.0) as sum
-		FROM transactions
-		WHERE order_id = $1 AND status = 'completed'
-	`, orderID)
-
-	return sum, err
-}
-
-// InsertIssuer inserts the given issuer
-func (pg *Postgres) InsertIssuer(issuer *Issuer) (*Issuer, error) {
-	statement := `
-	INSERT INTO order_cred_issuers (merchant_id, public_key)
-	VALUES ($1, $2)
-	RETURNING id, created_at, merchant_id, public_key`
-	var issuers []Issuer
-	err := pg.RawDB().Select(&issuers, statement, issuer.MerchantID, issuer.PublicKey)
+// GetPendingTransactions returns the oldest pending transactions of the kinds
+func (pg *Postgres) GetPendingTransactions(ctx context.Context, kinds []string, limit int) ([]Transaction, error) {
+	transactions := []Transaction{}
+	err := pg.RawDB().SelectContext(ctx, &transactions, `
+			SELECT id, order_id, created_at, updated_at, external_transaction_id, status, currency, kind, amount
+			FROM transactions
+			WHERE status = 'pending' AND kind = ANY($1)
+			ORDER BY updated_at, id
+			LIMIT $2
+		`, pq.Array(kinds), limit)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get pending transactions: %w", err)
 	}
+	return transactions, nil
+}
 
-	if len(issuers) != 1 {
-		return nil, errors.New("unexpected number of issuers returned")
-	}
+// SettleTransaction sets the status of a pending transaction, recording it as a payment towards its order
+// once completed. It returns the settled transaction, or nil when the transaction was settled already
+func (pg *Postgres) SettleTransaction(ctx context.Context, transactionID uuid.UUID, status string) (*Transaction, error) {
+	var settled *Transaction
+	err := pg.WithTxRetry(ctx, "SettleTransaction", func(tx *sqlx.Tx) error {
+		settled = nil
+		var transaction Transaction
+		err := transactionsDualWrite.WriteTx(ctx, tx, "SettleTransaction",
+			func() error {
+				return tx.GetContext(ctx, &transaction, `
+			UPDATE transactions SET status = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2 AND status = 'pending'
+			RETURNING id, order_id, created_at, updated_at, external_transaction_id, status, currency, kind, amount
+	`, status, transactionID)
+			},
+			func() error {
+				_, err := tx.ExecContext(ctx, `
+			UPDATE transactions_v2 SET status = $1, updated_at = $2 WHERE id = $3
+	`, transaction.Status, transaction.UpdatedAt, transaction.ID)
+				return err
+			})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to settle transaction: %w", err)
+		}
 
-	return &issuers[0], nil
+		if transaction.Status == "completed" {
+			_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_payments (order_id, transaction_id, kind, currency, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (transaction_id) DO NOTHING
+		`, transaction.OrderID, transaction.ID, transaction.Kind, transaction.Currency, transaction.Amount, transaction.UpdatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to record order payment: %w", err)
+			}
+		}
+		settled = &transaction
+		return tx.Commit()
+	})
+	return settled, err
 }
 
-// GetIssuer retrieves the given issuer
-func (pg *Postgres) GetIssuer(merchantID string) (*Issuer, error) {
-	statement := "select id, created_at, merchant_id, public_key from order_cred_issuers where merchant_id = $1"
-	var issuer Issuer
-	err := pg.RawDB().Get(&issuer, statement, merchantID)
+const orderDepositColumns = "order_id, address, status, received, excess, created_at, updated_at"
+
+// InsertOrderDeposit stores the deposit address of an order, unless the order has one already
+func (pg *Postgres) InsertOrderDeposit(ctx context.Context, deposit *OrderDeposit) error {
+	_, err := pg.RawDB().ExecContext(ctx, `
+			INSERT INTO order_deposits (order_id, address)
+			VALUES ($1, $2)
+			ON CONFLICT (order_id) DO NOTHING
+		`, deposit.OrderID, deposit.Address)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to insert order deposit: %w", err)
 	}
-
-	return &issuer, nil
+	return nil
 }
 
-// GetIssuerByPublicKey or return an error
-func (pg *Postgres) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
-	statement := "select id, created_at, merchant_id, public_key from order_cred_issuers where public_key = $1"
-	var issuer Issuer
-	err := pg.RawDB().Get(&issuer, statement, publicKey)
+// GetOrderDeposit returns the deposit address of an order, nil when it has none
+func (pg *Postgres) GetOrderDeposit(ctx context.Context, orderID uuid.UUID) (*OrderDeposit, error) {
+	var deposit OrderDeposit
+	err := pg.RawDB().GetContext(ctx, &deposit, `SELECT `+orderDepositColumns+` FROM order_deposits WHERE order_id = $1`, orderID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get order deposit: %w", err)
 	}
-
-	return &issuer, nil
+	return &deposit, nil
 }
 
-// InsertOrderCreds inserts the given order creds
-func (pg *Postgres) InsertOrderCreds(creds *OrderCreds) error {
-	blindedCredsJSON, err := json.Marshal(creds.BlindedCreds)
+// GetOrderDepositsByAddress returns the deposit addresses among the addresses
+func (pg *Postgres) GetOrderDepositsByAddress(ctx context.Context, addresses []string) ([]OrderDeposit, error) {
+	deposits := []OrderDeposit{}
+	err := pg.RawDB().SelectContext(ctx, &deposits, `
+			SELECT `+orderDepositColumns+`
+			FROM order_deposits
+			WHERE address = ANY($1)
+			ORDER BY created_at, order_id
+		`, pq.Array(addresses))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to get order deposits: %w", err)
 	}
+	return deposits, nil
+}
+
+// UpdateOrderDeposit sets what the deposit address of an order received
+func (pg *Postgres) UpdateOrderDeposit(ctx context.Context, orderID uuid.UUID, status string, received, excess decimal.Decimal) error {
+	_, err := pg.RawDB().ExecContext(ctx, `
+			UPDATE order_deposits
+			SET status = $1, received = $2, excess = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE order_id = $4
+		`, status, received, excess, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order deposit: %w", err)
+	}
+	return nil
+}
+
+// GetChainCursor returns the last block of the chain scanned for deposits, 0 when none was
+func (pg *Postgres) GetChainCursor(ctx context.Context, chain string) (uint64, error) {
+	var block uint64
+	err := pg.RawDB().GetContext(ctx, &block, `SELECT block FROM chain_cursors WHERE chain = $1`, chain)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get chain cursor: %w", err)
+	}
+	return block, nil
+}
+
+// AdvanceChainCursor moves the cursor of the chain from the last block scanned to the next, returning false
+// when another scanner moved it since
+func (pg *Postgres) AdvanceChainCursor(ctx context.Context, chain string, from, to uint64) (bool, error) {
+	var (
+		result sql.Result
+		err    error
+	)
+	if from == 0 {
+		result, err = pg.RawDB().ExecContext(ctx, `
+			INSERT INTO chain_cursors (chain, block) VALUES ($1, $2)
+			ON CONFLICT (chain) DO NOTHING
+		`, chain, to)
+	} else {
+		result, err = pg.RawDB().ExecContext(ctx, `
+			UPDATE chain_cursors SET block = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE chain = $2 AND block = $3
+		`, to, chain, from)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to advance chain cursor: %w", err)
+	}
+	advanced, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return advanced == 1, nil
+}
+
+const voucherColumns = "id, code, merchant_id, kind, amount, currency, max_uses, uses, expires_at, created_at"
+
+// CreateVoucher stores a new voucher, returning ErrVoucherExists when its code is taken
+func (pg *Postgres) CreateVoucher(ctx context.Context, voucher *Voucher) (*Voucher, error) {
+	var created Voucher
+	err := pg.RawDB().GetContext(ctx, &created, `
+			INSERT INTO vouchers (code, merchant_id, kind, amount, currency, max_uses, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING `+voucherColumns+`
+		`, voucher.Code, voucher.MerchantID, voucher.Kind, voucher.Amount, voucher.Currency, voucher.MaxUses, voucher.ExpiresAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrVoucherExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create voucher: %w", err)
+	}
+	return &created, nil
+}
+
+// GetVoucher returns the voucher with the code, nil when there is none
+func (pg *Postgres) GetVoucher(ctx context.Context, code string) (*Voucher, error) {
+	var voucher Voucher
+	err := pg.RawDB().GetContext(ctx, &voucher, `SELECT `+voucherColumns+` FROM vouchers WHERE code = $1`, code)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get voucher: %w", err)
+	}
+	return &voucher, nil
+}
+
+// ListVouchers returns the vouchers of every merchant, or of the one given, newest first
+func (pg *Postgres) ListVouchers(ctx context.Context, merchantID string, limit int) ([]Voucher, error) {
+	vouchers := []Voucher{}
+	err := pg.RawDB().SelectContext(ctx, &vouchers, `
+			SELECT `+voucherColumns+` FROM vouchers
+			WHERE $1 = '' OR merchant_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		`, merchantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vouchers: %w", err)
+	}
+	return vouchers, nil
+}
+
+const issuerColumns = "id, created_at, merchant_id, public_key, version, valid_from, valid_to, disabled_at"
+
+// InsertIssuer inserts the given issuer, valid from now unless it has a window of its own
+func (pg *Postgres) InsertIssuer(issuer *Issuer) (*Issuer, error) {
+	statement := `
+	INSERT INTO order_cred_issuers (merchant_id, public_key, version, valid_from, valid_to)
+	VALUES ($1, $2, $3, coalesce($4, current_timestamp), $5)
+	RETURNING ` + issuerColumns
+	var issuers []Issuer
+	version := issuer.Version
+	if version == 0 {
+		version = 1
+	}
+	var validFrom *time.Time
+	if !issuer.ValidFrom.IsZero() {
+		validFrom = &issuer.ValidFrom
+	}
+	err := pg.RawDB().Select(&issuers, statement, issuer.MerchantID, issuer.PublicKey, version, validFrom, issuer.ValidTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(issuers) != 1 {
+		return nil, errors.New("unexpected number of issuers returned")
+	}
+
+	return &issuers[0], nil
+}
+
+// GetIssuer retrieves the currently active issuer of the merchant, disabled issuers are never active
+func (pg *Postgres) GetIssuer(merchantID string) (*Issuer, error) {
+	statement := `
+	select ` + issuerColumns + ` from order_cred_issuers
+	where merchant_id = $1 and valid_from <= current_timestamp
+		and (valid_to is null or valid_to > current_timestamp) and disabled_at is null
+	order by version desc
+	limit 1`
+	var issuer Issuer
+	err := pg.RawDB().Get(&issuer, statement, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuer, nil
+}
+
+// GetIssuers returns every version of the merchant's issuer, newest first
+func (pg *Postgres) GetIssuers(ctx context.Context, merchantID string) ([]Issuer, error) {
+	issuers := []Issuer{}
+	err := pg.RawDB().SelectContext(ctx, &issuers, `
+			select `+issuerColumns+` from order_cred_issuers
+			where merchant_id = $1
+			order by version desc, created_at desc
+		`, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issuers: %w", err)
+	}
+	return issuers, nil
+}
+
+// RotateIssuer inserts the next version of an issuer, ending the validity of the active versions so
+// only the new one signs credentials
+func (pg *Postgres) RotateIssuer(ctx context.Context, issuer *Issuer) (*Issuer, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer pg.RollbackTx(tx)
+
+	_, err = tx.ExecContext(ctx, `
+			update order_cred_issuers
+			set valid_to = current_timestamp
+			where merchant_id = $1 and (valid_to is null or valid_to > current_timestamp)
+		`, issuer.MerchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire issuers: %w", err)
+	}
+
+	var rotated Issuer
+	err = tx.GetContext(ctx, &rotated, `
+			insert into order_cred_issuers (merchant_id, public_key, version)
+			values ($1, $2, $3)
+			returning `+issuerColumns,
+		issuer.MerchantID, issuer.PublicKey, issuer.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert issuer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &rotated, nil
+}
+
+// GetIssuerByPublicKey or return an error, whether or not the issuer is still active
+func (pg *Postgres) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
+	statement := "select " + issuerColumns + " from order_cred_issuers where public_key = $1"
+	var issuer Issuer
+	err := pg.RawDB().Get(&issuer, statement, publicKey)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &issuer, nil
+}
+
+// GetIssuerByID returns the issuer of any version with the id, nil if there is none
+func (pg *Postgres) GetIssuerByID(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	var issuer Issuer
+	err := pg.RawDB().GetContext(ctx, &issuer, "select "+issuerColumns+" from order_cred_issuers where id = $1", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get issuer: %w", err)
+	}
+	return &issuer, nil
+}
+
+// ListIssuers returns the issuers of every merchant, or of the one given whatever the sku, newest first with
+// the number of tokens each has signed
+func (pg *Postgres) ListIssuers(ctx context.Context, merchantID string, limit int) ([]IssuerUsage, error) {
+	issuers := []IssuerUsage{}
+	err := pg.RawDB().SelectContext(ctx, &issuers, `
+			select `+issuerColumns+`,
+				coalesce((
+					select sum(cardinality(c.signed_creds)) from order_creds c
+					where c.issuer_id = i.id and c.signed_creds is not null
+				), 0) + coalesce((
+					select sum(cardinality(w.signed_creds)) from order_cred_windows w
+					where w.issuer_id = i.id and w.signed_creds is not null
+				), 0) as tokens_signed
+			from order_cred_issuers i
+			where $1 = '' or split_part(merchant_id, '?', 1) = $1
+			order by created_at desc
+			limit $2
+		`, merchantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issuers: %w", err)
+	}
+	return issuers, nil
+}
+
+// DisableIssuer stops the issuer signing credentials, the time it was first disabled is kept. Credentials
+// it signed still redeem
+func (pg *Postgres) DisableIssuer(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	var issuer Issuer
+	err := pg.RawDB().GetContext(ctx, &issuer, `
+			update order_cred_issuers set disabled_at = coalesce(disabled_at, current_timestamp)
+			where id = $1
+			returning `+issuerColumns, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to disable issuer: %w", err)
+	}
+	return &issuer, nil
+}
+
+// InsertOrderCreds inserts the given order creds
+func (pg *Postgres) InsertOrderCreds(ctx context.Context, creds *OrderCreds) error {
+	tx, err := pg.RawDB().Beginx()
+	if err != nil {
+		return err
+	}
+	defer pg.RollbackTx(tx)
 
 	statement := `
 	insert into order_creds (item_id, order_id, issuer_id, blinded_creds)
 	values ($1, $2, $3, $4)`
-	_, err = pg.RawDB().Exec(statement, creds.ID, creds.OrderID, creds.IssuerID, blindedCredsJSON)
-	return err
+	_, err = tx.Exec(statement, creds.ID, creds.OrderID, creds.IssuerID, creds.BlindedCreds)
+	if err != nil {
+		return err
+	}
+	// queued along with the credentials, so they are signed even if the process stops now. Time-limited
+	// credentials are signed window by window instead
+	if creds.ValidFrom != nil && creds.ValidTo != nil {
+		_, err = tx.Exec(`
+			insert into order_cred_windows (item_id, order_id, issuer_id, valid_from, valid_to)
+			values ($1, $2, $3, $4, $5)`, creds.ID, creds.OrderID, creds.IssuerID, creds.ValidFrom, creds.ValidTo)
+	} else {
+		_, err = tx.Exec(`insert into order_signing_jobs (item_id, order_id) values ($1, $2)`, creds.ID, creds.OrderID)
+	}
+	if err != nil {
+		return err
+	}
+	issuerID := creds.IssuerID
+	_, err = appendOrderEvent(ctx, tx, creds.OrderID, OrderLogCredsRequested, orderCredsPayload{
+		ItemID:   creds.ID,
+		IssuerID: &issuerID,
+		Count:    len(creds.BlindedCreds),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetOrderCreds returns the order credentials for a OrderID
 func (pg *Postgres) GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCreds, error) {
 	orderCreds := []OrderCreds{}
 
+	// time-limited credentials are returned by window, those of windows which ended are left out
 	query := `
-		select item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key
-		from order_creds
-		where order_id = $1`
+		select item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key, valid_from, valid_to
+		from (
+			select item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key,
+				null::timestamp with time zone as valid_from, null::timestamp with time zone as valid_to
+			from order_creds
+			where order_id = $1
+				and not exists (select 1 from order_cred_windows w where w.item_id = order_creds.item_id)
+			union all
+			select w.item_id, w.order_id, w.issuer_id, c.blinded_creds, w.signed_creds, w.batch_proof, w.public_key,
+				w.valid_from, w.valid_to
+			from order_cred_windows w
+			inner join order_creds c on c.item_id = w.item_id
+			where w.order_id = $1 and w.valid_to > current_timestamp
+		) creds`
 	if isSigned {
-		query += " and signed_creds is not null"
+		query += " where signed_creds is not null"
 	}
+	query += " order by item_id, valid_from nulls first"
 
 	err := pg.RawDB().Select(&orderCreds, query, orderID)
 	if err != nil {
@@ -467,31 +1556,58 @@ func (pg *Postgres) GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCre
 }
 
 // DeleteOrderCreds deletes the order credentials for a OrderID
-func (pg *Postgres) DeleteOrderCreds(orderID uuid.UUID) error {
+func (pg *Postgres) DeleteOrderCreds(ctx context.Context, orderID uuid.UUID) error {
+
+	tx, err := pg.RawDB().Beginx()
+	if err != nil {
+		return err
+	}
+	defer pg.RollbackTx(tx)
 
 	query := `
 		delete
 		from order_creds
 		where order_id = $1`
 
-	_, err := pg.RawDB().Exec(query, orderID)
+	result, err := tx.Exec(query, orderID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err = appendOrderEvent(ctx, tx, orderID, OrderLogCredsDeleted, map[string]interface{}{})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // GetOrderCredsByItemID returns the order credentials for a OrderID by the itemID
 func (pg *Postgres) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (*OrderCreds, error) {
 	orderCreds := OrderCreds{}
 
+	// time-limited credentials are those of the window which started last
 	query := `
-		SELECT item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key
-		FROM order_creds
-		WHERE order_id = $1 AND item_id = $2`
+		SELECT item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key, valid_from, valid_to
+		FROM (
+			SELECT c.item_id, c.order_id, coalesce(w.issuer_id, c.issuer_id) AS issuer_id, c.blinded_creds,
+				CASE WHEN w.item_id IS NULL THEN c.signed_creds ELSE w.signed_creds END AS signed_creds,
+				CASE WHEN w.item_id IS NULL THEN c.batch_proof ELSE w.batch_proof END AS batch_proof,
+				CASE WHEN w.item_id IS NULL THEN c.public_key ELSE w.public_key END AS public_key,
+				w.valid_from, w.valid_to
+			FROM order_creds c
+			LEFT JOIN LATERAL (
+				SELECT * FROM order_cred_windows
+				WHERE item_id = c.item_id AND valid_from <= current_timestamp
+				ORDER BY valid_from DESC
+				LIMIT 1
+			) w ON true
+			WHERE c.order_id = $1 AND c.item_id = $2
+		) creds`
 	if isSigned {
-		query += " and signed_creds is not null"
+		query += " where signed_creds is not null"
 	}
 
 	err := pg.RawDB().Get(&orderCreds, query, orderID, itemID)
@@ -516,11 +1632,17 @@ func (pg *Postgres) GetUncommittedVotesForUpdate(ctx context.Context) (*sqlx.Tx,
 		return tx, nil, fmt.Errorf("failed to aquire transaction: %w", err)
 	}
 
+	// the queue is read from the table reads are served from, the other is kept in step by id
+	table := "vote_drain"
+	if votesDualWrite.Mode() == datastore.DualWriteReadNew {
+		table = "vote_drain_v2"
+	}
+
 	statement := `
 select
-	id, credentials, vote_text, vote_event, erred, processed
+	id, credentials, vote_text, vote_event, erred, processed, coalesce(request_id, '')
 from
-	vote_drain
+	` + table + `
 where
 	processed = false AND
 	erred = false
@@ -535,7 +1657,7 @@ FOR UPDATE
 	for rows.Next() {
 		var vr = new(VoteRecord)
 		if err := rows.Scan(&vr.ID, &vr.RequestCredentials, &vr.VoteText,
-			&vr.VoteEventBinary, &vr.Erred, &vr.Processed); err != nil {
+			&vr.VoteEventBinary, &vr.Erred, &vr.Processed, &vr.RequestID); err != nil {
 			return tx, nil, fmt.Errorf("failed to scan vote drain record: %w", err)
 		}
 		// add to results
@@ -561,8 +1683,15 @@ func (pg *Postgres) MarkVoteErrored(ctx context.Context, vr VoteRecord, tx *sqlx
 	}
 	logger.Debug().Msg("about to set errored to true for this vote")
 
-	var statement = `update vote_drain set erred=true where id=$1`
-	_, err = tx.ExecContext(ctx, statement, vr.ID)
+	err = votesDualWrite.WriteTx(ctx, tx, "MarkVoteErrored",
+		func() error {
+			_, err := tx.ExecContext(ctx, `update vote_drain set erred=true where id=$1`, vr.ID)
+			return err
+		},
+		func() error {
+			_, err := tx.ExecContext(ctx, `update vote_drain_v2 set erred=true where id=$1`, vr.ID)
+			return err
+		})
 
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update vote_drain")
@@ -580,8 +1709,15 @@ func (pg *Postgres) CommitVote(ctx context.Context, vr VoteRecord, tx *sqlx.Tx)
 	}
 	logger.Debug().Msg("about to set processed to true for this vote")
 
-	var statement = `update vote_drain set processed=true where id=$1`
-	_, err = tx.ExecContext(ctx, statement, vr.ID)
+	err = votesDualWrite.WriteTx(ctx, tx, "CommitVote",
+		func() error {
+			_, err := tx.ExecContext(ctx, `update vote_drain set processed=true where id=$1`, vr.ID)
+			return err
+		},
+		func() error {
+			_, err := tx.ExecContext(ctx, `update vote_drain_v2 set processed=true where id=$1`, vr.ID)
+			return err
+		})
 
 	if err != nil {
 		logger.Error().Err(err).Msg("unable to update processed=true for vote drain job")
@@ -590,82 +1726,1184 @@ func (pg *Postgres) CommitVote(ctx context.Context, vr VoteRecord, tx *sqlx.Tx)
 	return nil
 }
 
+// GetVotesCreatedBetween returns the votes queued within [from, to), without their credentials
+func (pg *Postgres) GetVotesCreatedBetween(ctx context.Context, from, to time.Time) ([]ExportedVote, error) {
+	statement := `
+	select
+		id, created_at, vote_text, json_array_length(credentials) as credentials, erred,
+		coalesce(errcode, '') as errcode, processed, coalesce(request_id, '') as request_id
+	from vote_drain
+	where created_at >= $1 and created_at < $2
+	order by created_at, id`
+	votes := []ExportedVote{}
+	if err := pg.RawDB().SelectContext(ctx, &votes, statement, from, to); err != nil {
+		return nil, fmt.Errorf("failed to get votes for export: %w", err)
+	}
+	return votes, nil
+}
+
 // InsertVote - Add a vote to our "queue" to be processed
 func (pg *Postgres) InsertVote(ctx context.Context, vr VoteRecord) error {
-	var (
-		statement = `
-	insert into vote_drain (credentials, vote_text, vote_event)
-	values ($1, $2, $3)`
-		_, err = pg.RawDB().ExecContext(ctx, statement, vr.RequestCredentials, vr.VoteText, vr.VoteEventBinary)
-	)
+	if !votesDualWrite.Enabled() {
+		var (
+			statement = `
+	insert into vote_drain (credentials, vote_text, vote_event, request_id)
+	values ($1, $2, $3, nullif($4, ''))`
+			_, err = pg.RawDB().ExecContext(ctx, statement, vr.RequestCredentials, vr.VoteText, vr.VoteEventBinary, vr.RequestID)
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert vote to drain: %w", err)
+		}
+		return nil
+	}
+
+	// both queues hold the vote under the same id, so processing it can be recorded in both
+	var id uuid.UUID
+	err := votesDualWrite.Write(ctx, "InsertVote",
+		func() error {
+			return pg.RawDB().GetContext(ctx, &id, `
+	insert into vote_drain (credentials, vote_text, vote_event, request_id)
+	values ($1, $2, $3, nullif($4, ''))
+	returning id`, vr.RequestCredentials, vr.VoteText, vr.VoteEventBinary, vr.RequestID)
+		},
+		func() error {
+			_, err := pg.RawDB().ExecContext(ctx, `
+	insert into vote_drain_v2 (id, credentials, vote_text, vote_event, request_id)
+	values ($1, $2, $3, $4, nullif($5, ''))`, id, vr.RequestCredentials, vr.VoteText, vr.VoteEventBinary, vr.RequestID)
+			return err
+		})
 	if err != nil {
 		return fmt.Errorf("failed to insert vote to drain: %w", err)
 	}
 	return nil
 }
 
-// RunNextOrderJob to sign order credentials if there is a order waiting, returning true if a job was attempted
+// RollupVoteTallies adds up to limit processed votes to the vote tallies, returning how many votes were
+// tallied and how many tallies they changed. The votes are marked tallied in the same statement, so a vote
+// is counted once however many rollups run at the same time. Votes are read from vote_drain, which is
+// written whatever the mode of its dual write
+func (pg *Postgres) RollupVoteTallies(ctx context.Context, limit int) (int64, int64, error) {
+	var votes, tallies int64
+	err := pg.RawDB().QueryRowxContext(ctx, `
+			WITH batch AS (
+				UPDATE vote_drain SET tallied = true
+				WHERE id IN (
+					SELECT id FROM vote_drain
+					WHERE processed AND NOT tallied AND created_at IS NOT NULL
+					ORDER BY created_at
+					LIMIT $1
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING created_at, credentials, convert_from(decode(vote_text, 'base64'), 'UTF8')::json AS vote
+			), upserted AS (
+				INSERT INTO vote_tallies (channel, day, type, funding_source, votes, tally)
+				SELECT vote->>'channel', (created_at AT TIME ZONE 'UTC')::date, vote->>'type',
+					CASE WHEN credentials->0->>'issuer' LIKE '%sku=`+AnonCardVoteSKU+`%'
+						THEN 'anonymous-card' ELSE 'user-wallet' END,
+					count(*), sum(json_array_length(credentials))
+				FROM batch
+				GROUP BY 1, 2, 3, 4
+				ON CONFLICT (channel, day, type, funding_source) DO UPDATE SET
+					votes = vote_tallies.votes + excluded.votes,
+					tally = vote_tallies.tally + excluded.tally,
+					updated_at = current_timestamp
+				RETURNING 1
+			)
+			SELECT (SELECT count(*) FROM batch), (SELECT count(*) FROM upserted)
+		`, limit).Scan(&votes, &tallies)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to roll up vote tallies: %w", err)
+	}
+	return votes, tallies, nil
+}
+
+// GetVoteTallies returns the tallies of a channel, or of every channel, for the days within [from, to)
+func (pg *Postgres) GetVoteTallies(ctx context.Context, channel string, from, to time.Time) ([]VoteTally, error) {
+	tallies := []VoteTally{}
+	err := pg.RawDB().SelectContext(ctx, &tallies, `
+			SELECT channel, day, type, funding_source, votes, tally, updated_at
+			FROM vote_tallies
+			WHERE ($1 = '' OR channel = $1) AND day >= $2::date AND day < $3::date
+			ORDER BY day, channel, type, funding_source
+		`, channel, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote tallies: %w", err)
+	}
+	return tallies, nil
+}
+
+// InsertVoteTallyRollup records a run of the vote tally rollup
+func (pg *Postgres) InsertVoteTallyRollup(ctx context.Context, rollup *VoteTallyRollup) error {
+	err := pg.RawDB().GetContext(ctx, &rollup.ID, `
+			INSERT INTO vote_tally_rollups (trigger, actor, votes, tallies, batches, error, started_at, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, rollup.Trigger, rollup.Actor, rollup.Votes, rollup.Tallies, rollup.Batches, rollup.Error,
+		rollup.StartedAt, rollup.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record vote tally rollup: %w", err)
+	}
+	return nil
+}
+
+// GetLatestVoteTallyRollup returns the run of the vote tally rollup which started last, nil if it never ran
+func (pg *Postgres) GetLatestVoteTallyRollup(ctx context.Context) (*VoteTallyRollup, error) {
+	var rollup VoteTallyRollup
+	err := pg.RawDB().GetContext(ctx, &rollup, `
+			SELECT id, trigger, actor, votes, tallies, batches, error, started_at, finished_at
+			FROM vote_tally_rollups
+			ORDER BY started_at DESC
+			LIMIT 1
+		`)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest vote tally rollup: %w", err)
+	}
+	return &rollup, nil
+}
+
+// ClaimOrderTrial takes the trial of an order for a wallet, unless another wallet took it or the wallet
+// took limit trials with the merchant. The trials of a wallet with a merchant are counted under a lock, so
+// concurrent claims cannot exceed the limit
+func (pg *Postgres) ClaimOrderTrial(ctx context.Context, orderID, walletID uuid.UUID, merchantID string, limit int) error {
+	return pg.WithTxRetry(ctx, "ClaimOrderTrial", func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || $2))`, walletID.String(), merchantID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet trials: %w", err)
+		}
+
+		var claimedBy uuid.UUID
+		err = tx.GetContext(ctx, &claimedBy, `SELECT wallet_id FROM order_trials WHERE order_id = $1 FOR UPDATE`, orderID)
+		if err == nil {
+			if uuid.Equal(claimedBy, walletID) {
+				return nil
+			}
+			return ErrTrialClaimed
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get order trial: %w", err)
+		}
+
+		var trials int
+		err = tx.GetContext(ctx, &trials, `
+				SELECT count(*) FROM order_trials
+				WHERE wallet_id = $1 AND merchant_id = $2
+			`, walletID, merchantID)
+		if err != nil {
+			return fmt.Errorf("failed to count wallet trials: %w", err)
+		}
+		if trials >= limit {
+			return ErrTrialLimit
+		}
+
+		// another wallet, holding a lock of its own, may have taken the trial since
+		result, err := tx.ExecContext(ctx, `
+				INSERT INTO order_trials (order_id, wallet_id, merchant_id)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (order_id) DO NOTHING
+			`, orderID, walletID, merchantID)
+		if err != nil {
+			return fmt.Errorf("failed to insert order trial: %w", err)
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+			return ErrTrialClaimed
+		}
+		return tx.Commit()
+	})
+}
+
+// RunNextOrderJob claims the next visible signing job and signs its order credentials, returning true if a
+// job was attempted. The job is claimed in its own transaction, so no lock is held while signing, and
+// stays hidden from other workers for the visibility timeout, after which the job of a worker which died
+// is taken over. Failed attempts are retried with backoff until the job runs out of attempts
 func (pg *Postgres) RunNextOrderJob(ctx context.Context, worker OrderWorker) (bool, error) {
-	tx, err := pg.RawDB().Beginx()
-	attempted := false
+	job, err := pg.claimSigningJob(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	if err := workers.Claim(ctx, nil, fmt.Sprintf("order %s item %s", job.OrderID, job.ItemID)); err != nil {
+		return true, err
+	}
+	creds, err := worker.SignOrderCreds(ctx, job.OrderID, job.Issuer, job.BlindedCreds)
+	if err == nil {
+		err = verifySignedCreds(job.Issuer, job.BlindedCreds, creds)
+	}
 	if err != nil {
-		return attempted, err
+		if ferr := pg.failSigningJob(ctx, job, err); ferr != nil {
+			return true, fmt.Errorf("failed to record signing failure: %w", ferr)
+		}
+		return true, err
 	}
-	defer pg.RollbackTx(tx)
 
-	type SigningJob struct {
-		Issuer
-		OrderID      uuid.UUID                 `db:"order_id"`
-		BlindedCreds jsonutils.JSONStringArray `db:"blinded_creds"`
+	if err := pg.completeSigningJob(ctx, job, creds); err != nil {
+		return true, err
 	}
+	signingJobResults.WithLabelValues("signed").Inc()
+	return true, nil
+}
 
-	statement := `
-SELECT
-	order_cred_issuers.id,
-	order_cred_issuers.created_at,
-	order_cred_issuers.merchant_id,
-	order_cred_issuers.public_key,
-	order_cred.order_id,
-	order_cred.blinded_creds
-FROM
-	(
-		SELECT item_id, order_id, issuer_id, blinded_creds, signed_creds, batch_proof, public_key
-		FROM order_creds
-		WHERE batch_proof is null
-		FOR UPDATE skip locked
-		limit 1
-	) order_cred
-INNER JOIN order_cred_issuers
-ON order_cred.issuer_id = order_cred_issuers.id`
-
-	jobs := []SigningJob{}
-	err = tx.Select(&jobs, statement)
-	if err != nil {
-		return attempted, err
+// claimSigningJob takes the next visible signing job, hiding it for the visibility timeout
+func (pg *Postgres) claimSigningJob(ctx context.Context) (*orderSigningJob, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer pg.RollbackTx(tx)
 
+	jobs := []orderSigningJob{}
+	err = tx.SelectContext(ctx, &jobs, `
+			WITH job AS (
+				SELECT item_id
+				FROM order_signing_jobs
+				WHERE status IN ('pending', 'running') AND visible_at <= current_timestamp
+				ORDER BY visible_at
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			), claimed AS (
+				UPDATE order_signing_jobs
+				SET status = 'running', attempts = attempts + 1, updated_at = current_timestamp,
+					visible_at = current_timestamp + $1::interval
+				FROM job
+				WHERE order_signing_jobs.item_id = job.item_id
+				RETURNING order_signing_jobs.item_id, order_signing_jobs.attempts
+			)
+			SELECT
+				order_cred_issuers.id,
+				order_cred_issuers.created_at,
+				order_cred_issuers.merchant_id,
+				order_cred_issuers.public_key,
+				order_cred_issuers.version,
+				order_cred_issuers.valid_from,
+				order_cred_issuers.valid_to,
+				order_creds.order_id,
+				order_creds.item_id,
+				order_creds.blinded_creds,
+				claimed.attempts
+			FROM claimed
+			INNER JOIN order_creds ON order_creds.item_id = claimed.item_id
+			INNER JOIN order_cred_issuers ON order_creds.issuer_id = order_cred_issuers.id
+		`, fmt.Sprintf("%d seconds", int(signingVisibilityTimeout.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim signing job: %w", err)
+	}
 	if len(jobs) != 1 {
-		return attempted, nil
+		return nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// failSigningJob reschedules the job after its backoff, or fails it when the error is permanent or it has
+// run out of attempts
+func (pg *Postgres) failSigningJob(ctx context.Context, job *orderSigningJob, cause error) error {
+	status := SigningJobPending
+	if !retryableSigningError(cause) || job.Attempts >= signingMaxAttempts {
+		status = SigningJobFailed
+	}
+	signingJobResults.WithLabelValues(status).Inc()
+
+	// the attempt only counts while it still owns the job, a worker which took the job over owns it now
+	_, err := pg.RawDB().ExecContext(ctx, `
+			UPDATE order_signing_jobs
+			SET status = $3, last_error = $4, updated_at = current_timestamp,
+				visible_at = current_timestamp + $5::interval
+			WHERE item_id = $1 AND attempts = $2 AND status = 'running'
+		`, job.ItemID, job.Attempts, status, cause.Error(),
+		fmt.Sprintf("%d seconds", int(signingBackoff(job.Attempts).Seconds())))
+	return err
+}
+
+// completeSigningJob stores the signed credentials, unless a worker which took the job over got there first
+func (pg *Postgres) completeSigningJob(ctx context.Context, job *orderSigningJob, creds *OrderCreds) error {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer pg.RollbackTx(tx)
+
+	result, err := tx.ExecContext(ctx, `
+			UPDATE order_signing_jobs
+			SET status = 'signed', last_error = null, updated_at = current_timestamp
+			WHERE item_id = $1 AND status = 'running'
+		`, job.ItemID)
+	if err != nil {
+		return fmt.Errorf("failed to complete signing job: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		// signed already, or the credentials were deleted while being signed
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `update order_creds set signed_creds = $1, batch_proof = $2, public_key = $3 where item_id = $4`,
+		creds.SignedCreds, creds.BatchProof, creds.PublicKey, job.ItemID)
+	if err != nil {
+		return err
+	}
+	signed := 0
+	if creds.SignedCreds != nil {
+		signed = len(*creds.SignedCreds)
+	}
+	_, err = appendOrderEvent(ctx, tx, job.OrderID, OrderLogCredsSigned, orderCredsPayload{ItemID: job.ItemID, Count: signed})
+	if err != nil {
+		return err
+	}
+	err = pg.RecordMerchantUsage(ctx, tx, MerchantUsage{
+		MerchantID:   issuerMerchant(job.Issuer.MerchantID),
+		SigningCalls: 1,
+		TokensIssued: int64(signed),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetExpiringCredentialWindows returns the latest windows of time-limited credentials of paid orders which
+// end before the time, soonest first
+func (pg *Postgres) GetExpiringCredentialWindows(ctx context.Context, endingBefore time.Time, limit int) ([]CredentialWindow, error) {
+	windows := []CredentialWindow{}
+	err := pg.RawDB().SelectContext(ctx, &windows, `
+			SELECT item_id, order_id, issuer_id, valid_from, valid_to, merchant_id, sku
+			FROM (
+				SELECT DISTINCT ON (w.item_id) w.item_id, w.order_id, w.issuer_id, w.valid_from, w.valid_to,
+					orders.merchant_id, order_items.sku
+				FROM order_cred_windows w
+				INNER JOIN orders ON orders.id = w.order_id
+				INNER JOIN order_items ON order_items.id = w.item_id
+				WHERE orders.status = 'paid'
+				ORDER BY w.item_id, w.valid_from DESC
+			) latest
+			WHERE valid_to < $1
+			ORDER BY valid_to
+			LIMIT $2
+		`, endingBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring credential windows: %w", err)
+	}
+	return windows, nil
+}
+
+// InsertCredentialWindow adds a window to an item's time-limited credentials, to be signed with the
+// credentials the item was submitted with. A window which was already added is left as it is
+func (pg *Postgres) InsertCredentialWindow(ctx context.Context, creds *OrderCreds) error {
+	_, err := pg.RawDB().ExecContext(ctx, `
+			INSERT INTO order_cred_windows (item_id, order_id, issuer_id, valid_from, valid_to)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (item_id, valid_from) DO NOTHING
+		`, creds.ID, creds.OrderID, creds.IssuerID, creds.ValidFrom, creds.ValidTo)
+	if err != nil {
+		return fmt.Errorf("failed to insert credential window: %w", err)
+	}
+	return nil
+}
+
+// RunNextCredentialWindow locks the next window of time-limited credentials waiting to be signed and signs
+// it, returning true if a window was attempted. Windows are signed once a week per item, so the lock is
+// held while signing, and a failed window is retried after the backoff of signing jobs
+func (pg *Postgres) RunNextCredentialWindow(ctx context.Context, worker OrderWorker) (bool, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
 	}
+	defer pg.RollbackTx(tx)
 
+	jobs := []orderSigningJob{}
+	err = tx.SelectContext(ctx, &jobs, `
+			SELECT
+				order_cred_issuers.id,
+				order_cred_issuers.created_at,
+				order_cred_issuers.merchant_id,
+				order_cred_issuers.public_key,
+				order_cred_issuers.version,
+				order_cred_issuers.valid_from,
+				order_cred_issuers.valid_to,
+				w.order_id,
+				w.item_id,
+				order_creds.blinded_creds,
+				w.attempts
+			FROM order_cred_windows w
+			INNER JOIN order_creds ON order_creds.item_id = w.item_id
+			INNER JOIN order_cred_issuers ON order_cred_issuers.id = w.issuer_id
+			WHERE w.signed_creds IS NULL AND w.visible_at <= current_timestamp AND w.valid_to > current_timestamp
+			ORDER BY w.valid_from
+			FOR UPDATE OF w SKIP LOCKED
+			LIMIT 1
+		`)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim credential window: %w", err)
+	}
+	if len(jobs) != 1 {
+		return false, nil
+	}
 	job := jobs[0]
 
-	attempted = true
 	creds, err := worker.SignOrderCreds(ctx, job.OrderID, job.Issuer, job.BlindedCreds)
+	if err == nil {
+		err = verifySignedCreds(job.Issuer, job.BlindedCreds, creds)
+	}
 	if err != nil {
-		// FIXME certain errors are not recoverable
-		return attempted, err
+		_, ferr := tx.ExecContext(ctx, `
+				UPDATE order_cred_windows
+				SET attempts = attempts + 1, visible_at = current_timestamp + $3::interval
+				WHERE item_id = $1 AND issuer_id = $2
+			`, job.ItemID, job.Issuer.ID, fmt.Sprintf("%d seconds", int(signingBackoff(job.Attempts+1).Seconds())))
+		if ferr != nil {
+			return true, fmt.Errorf("failed to record signing failure: %w", ferr)
+		}
+		if ferr := tx.Commit(); ferr != nil {
+			return true, ferr
+		}
+		return true, err
 	}
 
-	_, err = tx.Exec(`update order_creds set signed_creds = $1, batch_proof = $2, public_key = $3 where order_id = $4`, creds.SignedCreds, creds.BatchProof, creds.PublicKey, creds.ID)
+	_, err = tx.ExecContext(ctx, `
+			UPDATE order_cred_windows
+			SET signed_creds = $3, batch_proof = $4, public_key = $5, attempts = attempts + 1
+			WHERE item_id = $1 AND issuer_id = $2
+		`, job.ItemID, job.Issuer.ID, creds.SignedCreds, creds.BatchProof, creds.PublicKey)
+	if err != nil {
+		return true, err
+	}
+	signed := 0
+	if creds.SignedCreds != nil {
+		signed = len(*creds.SignedCreds)
+	}
+	_, err = appendOrderEvent(ctx, tx, job.OrderID, OrderLogCredsSigned, orderCredsPayload{ItemID: job.ItemID, Count: signed})
 	if err != nil {
-		return attempted, err
+		return true, err
+	}
+	err = pg.RecordMerchantUsage(ctx, tx, MerchantUsage{
+		MerchantID:   issuerMerchant(job.Issuer.MerchantID),
+		SigningCalls: 1,
+		TokensIssued: int64(signed),
+	})
+	if err != nil {
+		return true, err
 	}
 
-	err = tx.Commit()
+	return true, tx.Commit()
+}
+
+// GetOrderEvents returns the log of an order, in sequence
+func (pg *Postgres) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]OrderLogEvent, error) {
+	events := []OrderLogEvent{}
+	err := pg.RawDB().SelectContext(ctx, &events, `
+			SELECT id, order_id, sequence, type, payload, created_at, dispatched_at
+			FROM order_events WHERE order_id = $1
+			ORDER BY sequence
+		`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order events: %w", err)
+	}
+	return events, nil
+}
+
+// GetOrderHistory returns the transitions of an order, in sequence, with the payload of the event of each
+func (pg *Postgres) GetOrderHistory(ctx context.Context, orderID uuid.UUID) ([]OrderHistoryEntry, error) {
+	history := []OrderHistoryEntry{}
+	err := pg.RawDB().SelectContext(ctx, &history, `
+			SELECT h.id, h.order_id, h.sequence, h.transition, h.actor, h.before, h.after, e.payload, h.created_at
+			FROM order_history h
+			JOIN order_events e ON e.order_id = h.order_id AND e.sequence = h.sequence
+			WHERE h.order_id = $1
+			ORDER BY h.sequence
+		`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+	return history, nil
+}
+
+// GetOrderPayments returns the ledger of the completed payments made towards an order, oldest first
+func (pg *Postgres) GetOrderPayments(ctx context.Context, orderID uuid.UUID) ([]OrderPayment, error) {
+	payments := []OrderPayment{}
+	err := pg.RawDB().SelectContext(ctx, &payments, `
+			SELECT id, order_id, transaction_id, kind, currency, amount, created_at
+			FROM order_payments
+			WHERE order_id = $1
+			ORDER BY created_at, id
+		`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order payments: %w", err)
+	}
+	return payments, nil
+}
+
+// DispatchOrderEvents passes the oldest undispatched order events to dispatch, marking each dispatched as it
+// succeeds. It stops at the first error, leaving that event and the rest for the next run
+func (pg *Postgres) DispatchOrderEvents(ctx context.Context, limit int, dispatch func(context.Context, OrderLogEvent) error) (int, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer pg.RollbackTx(tx)
+
+	events := []OrderLogEvent{}
+	err = tx.SelectContext(ctx, &events, `
+			SELECT id, order_id, sequence, type, payload, created_at, dispatched_at
+			FROM order_events WHERE dispatched_at IS NULL
+			ORDER BY created_at, sequence
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get undispatched order events: %w", err)
+	}
+
+	dispatched := 0
+	var dispatchErr error
+	for _, event := range events {
+		if dispatchErr = dispatch(ctx, event); dispatchErr != nil {
+			dispatchErr = fmt.Errorf("failed to dispatch event %d of order %s: %w", event.Sequence, event.OrderID, dispatchErr)
+			break
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE order_events SET dispatched_at = CURRENT_TIMESTAMP WHERE id = $1`, event.ID)
+		if err != nil {
+			return dispatched, fmt.Errorf("failed to mark order event dispatched: %w", err)
+		}
+		dispatched++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return dispatched, dispatchErr
+}
+
+// PublishOrderEvents passes the oldest unpublished order events of the outbox to publish, marking each
+// published as it succeeds. It stops at the first error, leaving that event and the rest for the next run
+func (pg *Postgres) PublishOrderEvents(ctx context.Context, limit int, publish func(context.Context, OrderLogEvent) error) (int, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer pg.RollbackTx(tx)
+
+	events := []OrderLogEvent{}
+	err = tx.SelectContext(ctx, &events, `
+			SELECT e.id, e.order_id, e.sequence, e.type, e.payload, e.created_at, e.dispatched_at
+			FROM order_event_outbox o JOIN order_events e ON e.id = o.event_id
+			WHERE o.published_at IS NULL
+			ORDER BY o.created_at, e.sequence
+			LIMIT $1
+			FOR UPDATE OF o SKIP LOCKED
+		`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unpublished order events: %w", err)
+	}
+
+	published := 0
+	var publishErr error
+	for _, event := range events {
+		if publishErr = publish(ctx, event); publishErr != nil {
+			publishErr = fmt.Errorf("failed to publish event %d of order %s: %w", event.Sequence, event.OrderID, publishErr)
+			break
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE order_event_outbox SET published_at = CURRENT_TIMESTAMP WHERE event_id = $1`, event.ID)
+		if err != nil {
+			return published, fmt.Errorf("failed to mark order event published: %w", err)
+		}
+		published++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// GetStuckOrders returns the order items whose credentials were requested before the time and have not
+// been signed since, oldest first
+func (pg *Postgres) GetStuckOrders(ctx context.Context, requestedBefore time.Time, limit int) ([]StuckOrder, error) {
+	stuck := []StuckOrder{}
+	err := pg.RawDB().SelectContext(ctx, &stuck, `
+			SELECT r.order_id, (r.payload->>'itemId')::uuid AS item_id, r.created_at AS requested_at
+			FROM order_events r
+			WHERE r.type = 'creds_requested' AND r.created_at < $1
+				AND NOT EXISTS (
+					SELECT 1 FROM order_events l
+					WHERE l.order_id = r.order_id AND l.sequence > r.sequence
+						AND (l.type = 'creds_deleted' OR (l.type = 'creds_signed' AND l.payload->>'itemId' = r.payload->>'itemId'))
+				)
+			ORDER BY r.created_at
+			LIMIT $2
+		`, requestedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stuck orders: %w", err)
+	}
+	return stuck, nil
+}
+
+// RebuildOrderProjection overwrites the row of an order with its state replayed from its log
+func (pg *Postgres) RebuildOrderProjection(ctx context.Context, projection *OrderProjection) error {
+	result, err := pg.RawDB().ExecContext(ctx, `
+			UPDATE orders
+			SET status = $1, total_price = $2, currency = $3, merchant_id = $4, updated_at = $5, event_sequence = $6,
+				exchange_rate = $8, bat_total_price = $9, rated_at = $10
+			WHERE id = $7 AND event_sequence <= $6
+		`, projection.Order.Status, projection.Order.TotalPrice, projection.Order.Currency, projection.Order.MerchantID,
+		projection.Order.UpdatedAt, projection.Sequence, projection.Order.ID,
+		projection.Order.ExchangeRate, projection.Order.BATTotalPrice, projection.Order.RatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild order: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return errors.New("order changed while it was replayed, replay it again")
+	}
+	return nil
+}
+
+// CreateMerchant onboards a merchant
+func (pg *Postgres) CreateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error) {
+	var created Merchant
+	err := pg.RawDB().GetContext(ctx, &created, `
+			INSERT INTO merchants (id, name, allowed_skus, webhook_urls)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, name, allowed_skus, webhook_urls, created_at, updated_at
+		`, merchant.ID, merchant.Name, pq.Array(merchant.AllowedSKUs), pq.Array(merchant.WebhookURLs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merchant: %w", err)
+	}
+	return &created, nil
+}
+
+// GetMerchant returns a merchant which has not been deleted
+func (pg *Postgres) GetMerchant(ctx context.Context, id string) (*Merchant, error) {
+	var merchant Merchant
+	err := pg.RawDB().GetContext(ctx, &merchant, `
+			SELECT id, name, allowed_skus, webhook_urls, created_at, updated_at
+			FROM merchants
+			WHERE id = $1 AND deleted_at IS NULL
+		`, id)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	return &merchant, nil
+}
+
+// GetMerchants returns all merchants which have not been deleted
+func (pg *Postgres) GetMerchants(ctx context.Context) ([]Merchant, error) {
+	merchants := []Merchant{}
+	err := pg.RawDB().SelectContext(ctx, &merchants, `
+			SELECT id, name, allowed_skus, webhook_urls, created_at, updated_at
+			FROM merchants
+			WHERE deleted_at IS NULL
+			ORDER BY id
+		`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchants: %w", err)
+	}
+	return merchants, nil
+}
+
+// UpdateMerchant updates the settings of a merchant which has not been deleted
+func (pg *Postgres) UpdateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error) {
+	var updated Merchant
+	err := pg.RawDB().GetContext(ctx, &updated, `
+			UPDATE merchants
+			SET name = $2, allowed_skus = $3, webhook_urls = $4, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, name, allowed_skus, webhook_urls, created_at, updated_at
+		`, merchant.ID, merchant.Name, pq.Array(merchant.AllowedSKUs), pq.Array(merchant.WebhookURLs))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update merchant: %w", err)
+	}
+	return &updated, nil
+}
+
+// GetMerchantSettings returns the limits configured for a merchant, nil when none are
+func (pg *Postgres) GetMerchantSettings(ctx context.Context, merchantID string) (*MerchantSettings, error) {
+	var settings MerchantSettings
+	err := pg.RawDB().GetContext(ctx, &settings, `
+			SELECT merchant_id, max_tokens_per_issuer, sku_max_tokens_per_issuer, cred_buffer_size,
+				rate_limit_per_minute, rate_limit_burst, ip_rate_limit_per_minute, ip_rate_limit_burst,
+				order_expiry_minutes, updated_at
+			FROM merchant_settings
+			WHERE merchant_id = $1
+		`, merchantID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get merchant settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// UpsertMerchantSettings sets the limits of a merchant, replacing those set before
+func (pg *Postgres) UpsertMerchantSettings(ctx context.Context, settings *MerchantSettings) (*MerchantSettings, error) {
+	var upserted MerchantSettings
+	err := pg.RawDB().GetContext(ctx, &upserted, `
+			INSERT INTO merchant_settings (merchant_id, max_tokens_per_issuer, sku_max_tokens_per_issuer, cred_buffer_size,
+				rate_limit_per_minute, rate_limit_burst, ip_rate_limit_per_minute, ip_rate_limit_burst, order_expiry_minutes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (merchant_id) DO UPDATE
+			SET max_tokens_per_issuer = excluded.max_tokens_per_issuer,
+				sku_max_tokens_per_issuer = excluded.sku_max_tokens_per_issuer, cred_buffer_size = excluded.cred_buffer_size,
+				rate_limit_per_minute = excluded.rate_limit_per_minute, rate_limit_burst = excluded.rate_limit_burst,
+				ip_rate_limit_per_minute = excluded.ip_rate_limit_per_minute,
+				ip_rate_limit_burst = excluded.ip_rate_limit_burst, order_expiry_minutes = excluded.order_expiry_minutes,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING merchant_id, max_tokens_per_issuer, sku_max_tokens_per_issuer, cred_buffer_size,
+				rate_limit_per_minute, rate_limit_burst, ip_rate_limit_per_minute, ip_rate_limit_burst,
+				order_expiry_minutes, updated_at
+		`, settings.MerchantID, settings.MaxTokensPerIssuer, settings.SKUMaxTokensPerIssuer, settings.CredBufferSize,
+		settings.RateLimitPerMinute, settings.RateLimitBurst, settings.IPRateLimitPerMinute, settings.IPRateLimitBurst,
+		settings.OrderExpiryMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set merchant settings: %w", err)
+	}
+	return &upserted, nil
+}
+
+// DeleteMerchant marks a merchant as deleted, existing orders keep referencing it
+func (pg *Postgres) DeleteMerchant(ctx context.Context, id string) (*Merchant, error) {
+	var deleted Merchant
+	err := pg.RawDB().GetContext(ctx, &deleted, `
+			UPDATE merchants
+			SET deleted_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING id, name, allowed_skus, webhook_urls, created_at, updated_at
+		`, id)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to delete merchant: %w", err)
+	}
+	return &deleted, nil
+}
+
+// GetMerchantEncryptionKey returns the public key registered for encrypting payloads to the merchant
+func (pg *Postgres) GetMerchantEncryptionKey(merchantID string) (*MerchantEncryptionKey, error) {
+	var key MerchantEncryptionKey
+	err := pg.RawDB().Get(&key, `
+			SELECT merchant_id, jwk, created_at, updated_at
+			FROM merchant_encryption_keys
+			WHERE merchant_id = $1
+		`, merchantID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get merchant encryption key: %w", err)
+	}
+	return &key, nil
+}
+
+// SetMerchantEncryptionKey registers the public key for encrypting payloads to the merchant, replacing any existing key
+func (pg *Postgres) SetMerchantEncryptionKey(merchantID string, jwk string) (*MerchantEncryptionKey, error) {
+	var key MerchantEncryptionKey
+	err := pg.RawDB().Get(&key, `
+			INSERT INTO merchant_encryption_keys (merchant_id, jwk)
+			VALUES ($1, $2)
+			ON CONFLICT (merchant_id) DO UPDATE SET jwk = $2, updated_at = CURRENT_TIMESTAMP
+			RETURNING merchant_id, jwk, created_at, updated_at
+		`, merchantID, jwk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set merchant encryption key: %w", err)
+	}
+	return &key, nil
+}
+
+// CreateWebhookSecret adds a new version of the merchant's webhook secret, expiring the active secrets after the overlap
+func (pg *Postgres) CreateWebhookSecret(ctx context.Context, merchantID string, encryptedSecret string, nonce string, overlap time.Duration) (*WebhookSecret, error) {
+	tx, err := pg.RawDB().BeginTxx(ctx, nil)
 	if err != nil {
-		return attempted, err
+		return nil, err
+	}
+	defer pg.RollbackTx(tx)
+
+	// secrets already expiring sooner keep their expiry, so repeated rotations cannot extend them
+	_, err = tx.ExecContext(ctx, `
+			UPDATE merchant_webhook_secrets
+			SET expires_at = least(coalesce(expires_at, 'infinity'), current_timestamp + $2)
+			WHERE merchant_id = $1 AND (expires_at IS NULL OR expires_at > current_timestamp)
+		`, merchantID, fmt.Sprintf("%vs", int(overlap.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire webhook secrets: %w", err)
+	}
+
+	var secret WebhookSecret
+	err = tx.GetContext(ctx, &secret, `
+			INSERT INTO merchant_webhook_secrets (merchant_id, version, encrypted_secret, nonce)
+			SELECT $1, coalesce(max(version), 0) + 1, $2, $3
+			FROM merchant_webhook_secrets
+			WHERE merchant_id = $1
+			RETURNING id, merchant_id, version, encrypted_secret, nonce, created_at, expires_at
+		`, merchantID, encryptedSecret, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook secret: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// GetWebhookSecrets returns the active webhook secrets of a merchant, newest first
+func (pg *Postgres) GetWebhookSecrets(ctx context.Context, merchantID string) ([]WebhookSecret, error) {
+	secrets := []WebhookSecret{}
+	err := pg.RawDB().SelectContext(ctx, &secrets, `
+			SELECT id, merchant_id, version, encrypted_secret, nonce, created_at, expires_at
+			FROM merchant_webhook_secrets
+			WHERE merchant_id = $1 AND (expires_at IS NULL OR expires_at > current_timestamp)
+			ORDER BY version DESC
+		`, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// InsertWebhookDelivery records a webhook delivery to a merchant
+func (pg *Postgres) InsertWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	err := pg.RawDB().QueryRowxContext(ctx, `
+			INSERT INTO webhook_deliveries (merchant_id, url, event, secret_version, signature, status, error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+		`, delivery.MerchantID, delivery.URL, delivery.Event, delivery.SecretVersion, delivery.Signature,
+		delivery.Status, delivery.Error).Scan(&delivery.ID, &delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the most recent webhook deliveries to a merchant
+func (pg *Postgres) GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := pg.RawDB().SelectContext(ctx, &deliveries, `
+			SELECT id, merchant_id, url, event, secret_version, signature, status, error, created_at
+			FROM webhook_deliveries
+			WHERE merchant_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		`, merchantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordMerchantUsage adds to the merchant's usage of the day, within the transaction if there is one
+func (pg *Postgres) RecordMerchantUsage(ctx context.Context, tx *sqlx.Tx, usage MerchantUsage) error {
+	var execer sqlx.ExecerContext = pg.RawDB()
+	if tx != nil {
+		execer = tx
+	}
+	_, err := execer.ExecContext(ctx, `
+			INSERT INTO merchant_usage (merchant_id, day, signing_calls, tokens_issued, redemptions)
+			VALUES ($1, current_date, $2, $3, $4)
+			ON CONFLICT (merchant_id, day) DO UPDATE SET
+				signing_calls = merchant_usage.signing_calls + $2,
+				tokens_issued = merchant_usage.tokens_issued + $3,
+				redemptions = merchant_usage.redemptions + $4
+		`, usage.MerchantID, usage.SigningCalls, usage.TokensIssued, usage.Redemptions)
+	if err != nil {
+		return fmt.Errorf("failed to record merchant usage: %w", err)
+	}
+	return nil
+}
+
+// InsertCredentialRedemptions records redeemed credentials, within the transaction if there is one. A
+// credential which was already recorded keeps its first redemption
+func (pg *Postgres) InsertCredentialRedemptions(ctx context.Context, tx *sqlx.Tx, redemptions []RedeemedCredential) error {
+	if len(redemptions) == 0 {
+		return nil
+	}
+	var execer sqlx.ExecerContext = pg.RawDB()
+	if tx != nil {
+		execer = tx
+	}
+
+	var (
+		preimages = make([]string, len(redemptions))
+		issuers   = make([]string, len(redemptions))
+		payloads  = make([]string, len(redemptions))
+	)
+	for i, redemption := range redemptions {
+		preimages[i] = redemption.TokenPreimage
+		issuers[i] = redemption.IssuerID
+		payloads[i] = redemption.Payload
+	}
+	_, err := execer.ExecContext(ctx, `
+			INSERT INTO credential_redemptions (token_preimage, issuer_id, payload)
+			SELECT * FROM unnest($1::text[], $2::text[], $3::text[])
+			ON CONFLICT (token_preimage) DO NOTHING
+		`, pq.Array(preimages), pq.Array(issuers), pq.Array(payloads))
+	if err != nil {
+		return fmt.Errorf("failed to record credential redemptions: %w", err)
+	}
+	return nil
+}
+
+// GetCredentialRedemptions returns the redemptions of the credentials with the token preimages which were redeemed
+func (pg *Postgres) GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) ([]RedeemedCredential, error) {
+	redemptions := []RedeemedCredential{}
+	if len(tokenPreimages) == 0 {
+		return redemptions, nil
+	}
+	err := pg.RawDB().SelectContext(ctx, &redemptions, `
+			SELECT token_preimage, issuer_id, payload, redeemed_at
+			FROM credential_redemptions
+			WHERE token_preimage = ANY($1)
+		`, pq.Array(tokenPreimages))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential redemptions: %w", err)
+	}
+	return redemptions, nil
+}
+
+// GetMerchantUsage returns the monthly usage of a merchant from the month of since, oldest first. Months
+// which have not been rolled up yet are totalled from the daily usage
+func (pg *Postgres) GetMerchantUsage(ctx context.Context, merchantID string, since time.Time) ([]MerchantUsage, error) {
+	usage := []MerchantUsage{}
+	err := pg.RawDB().SelectContext(ctx, &usage, `
+			SELECT merchant_id, month AS period, signing_calls, tokens_issued, redemptions
+			FROM merchant_usage_monthly
+			WHERE merchant_id = $1 AND month >= date_trunc('month', $2::timestamptz)
+			UNION ALL
+			SELECT merchant_id, date_trunc('month', day)::date AS period,
+				sum(signing_calls)::bigint, sum(tokens_issued)::bigint, sum(redemptions)::bigint
+			FROM merchant_usage u
+			WHERE merchant_id = $1 AND day >= date_trunc('month', $2::timestamptz)
+				AND NOT EXISTS (
+					SELECT 1 FROM merchant_usage_monthly m
+					WHERE m.merchant_id = u.merchant_id AND m.month = date_trunc('month', u.day)
+				)
+			GROUP BY merchant_id, date_trunc('month', day)
+			ORDER BY period
+		`, merchantID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant usage: %w", err)
+	}
+	return usage, nil
+}
+
+// RollupMerchantUsage totals the daily usage before the time by month, returning how many months were
+// rolled up. Months already rolled up are totalled again, so late usage is not lost
+func (pg *Postgres) RollupMerchantUsage(ctx context.Context, before time.Time) (int64, error) {
+	result, err := pg.RawDB().ExecContext(ctx, `
+			INSERT INTO merchant_usage_monthly (merchant_id, month, signing_calls, tokens_issued, redemptions)
+			SELECT merchant_id, date_trunc('month', day)::date,
+				sum(signing_calls), sum(tokens_issued), sum(redemptions)
+			FROM merchant_usage
+			WHERE day < date_trunc('month', $1::timestamptz)
+			GROUP BY merchant_id, date_trunc('month', day)
+			ON CONFLICT (merchant_id, month) DO UPDATE SET
+				signing_calls = excluded.signing_calls,
+				tokens_issued = excluded.tokens_issued,
+				redemptions = excluded.redemptions,
+				rolled_up_at = current_timestamp
+		`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up merchant usage: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteWebhookDeliveries removes deliveries made before the time, returning how many were removed
+func (pg *Postgres) DeleteWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := pg.RawDB().ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateMerchantSigningKey registers a key the merchant signs order creation requests with
+func (pg *Postgres) CreateMerchantSigningKey(ctx context.Context, key *MerchantSigningKey) (*MerchantSigningKey, error) {
+	var created MerchantSigningKey
+	err := pg.RawDB().GetContext(ctx, &created, `
+			INSERT INTO merchant_signing_keys (merchant_id, name, public_key, allowed_skus, max_amount)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, merchant_id, name, public_key, allowed_skus, max_amount, created_at, revoked_at
+		`, key.MerchantID, key.Name, key.PublicKey, key.AllowedSKUs, key.MaxAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merchant signing key: %w", err)
+	}
+	return &created, nil
+}
+
+// GetMerchantSigningKey returns an unrevoked merchant signing key by id
+func (pg *Postgres) GetMerchantSigningKey(ctx context.Context, id uuid.UUID) (*MerchantSigningKey, error) {
+	var key MerchantSigningKey
+	err := pg.RawDB().GetContext(ctx, &key, `
+			SELECT id, merchant_id, name, public_key, allowed_skus, max_amount, created_at, revoked_at
+			FROM merchant_signing_keys
+			WHERE id = $1 AND revoked_at IS NULL
+		`, id)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get merchant signing key: %w", err)
+	}
+	return &key, nil
+}
+
+// GetMerchantSigningKeys returns the unrevoked signing keys of a merchant
+func (pg *Postgres) GetMerchantSigningKeys(ctx context.Context, merchantID string) ([]MerchantSigningKey, error) {
+	keys := []MerchantSigningKey{}
+	err := pg.RawDB().SelectContext(ctx, &keys, `
+			SELECT id, merchant_id, name, public_key, allowed_skus, max_amount, created_at, revoked_at
+			FROM merchant_signing_keys
+			WHERE merchant_id = $1 AND revoked_at IS NULL
+			ORDER BY name, created_at
+		`, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant signing keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeMerchantSigningKey revokes a signing key of a merchant
+func (pg *Postgres) RevokeMerchantSigningKey(ctx context.Context, merchantID string, id uuid.UUID) (*MerchantSigningKey, error) {
+	var key MerchantSigningKey
+	err := pg.RawDB().GetContext(ctx, &key, `
+			UPDATE merchant_signing_keys
+			SET revoked_at = current_timestamp
+			WHERE id = $1 AND merchant_id = $2 AND revoked_at IS NULL
+			RETURNING id, merchant_id, name, public_key, allowed_skus, max_amount, created_at, revoked_at
+		`, id, merchantID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to revoke merchant signing key: %w", err)
+	}
+	return &key, nil
+}
+
+// InsertAuditEvent appends an event to the audit log
+func (pg *Postgres) InsertAuditEvent(ctx context.Context, event *middleware.AuditEvent) error {
+	entities, err := json.Marshal(event.Entities)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entities: %w", err)
+	}
+
+	err = pg.RawDB().QueryRowxContext(ctx, `
+			INSERT INTO audit_events (actor, method, route, path, entities, status, request_id)
+			VALUES ($1, $2, $3, $4, $5, $6, nullif($7, ''))
+			RETURNING id, created_at
+		`, event.Actor, event.Method, event.Route, event.Path, entities, event.Status, event.RequestID).
+		Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
 	}
+	return nil
+}
 
-	return attempted, nil
+// ReserveIdempotencyKey inserts the request under its key, replacing a request whose key expired. When the key
+// is in use the request it was used with is returned instead
+func (pg *Postgres) ReserveIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest, ttl time.Duration) (*middleware.IdempotentRequest, bool, error) {
+	err := pg.RawDB().GetContext(ctx, &request.CreatedAt, `
+			INSERT INTO idempotency_keys (scope, key, request_hash)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (scope, key) DO UPDATE
+			SET request_hash = excluded.request_hash, status = null, header = null, body = null,
+				created_at = current_timestamp
+			WHERE idempotency_keys.created_at < current_timestamp - $4::interval
+			RETURNING created_at
+		`, request.Scope, request.Key, request.RequestHash, fmt.Sprintf("%d seconds", int(ttl.Seconds())))
+	if err == nil {
+		return request, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var (
+		existing middleware.IdempotentRequest
+		header   []byte
+	)
+	err = pg.RawDB().QueryRowxContext(ctx, `
+			SELECT scope, key, request_hash, status, header, body, created_at
+			FROM idempotency_keys WHERE scope = $1 AND key = $2
+		`, request.Scope, request.Key).
+		Scan(&existing.Scope, &existing.Key, &existing.RequestHash, &existing.Status, &header, &existing.Body, &existing.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if header != nil {
+		if err := json.Unmarshal(header, &existing.Header); err != nil {
+			return nil, false, fmt.Errorf("failed to decode idempotent response header: %w", err)
+		}
+	}
+	return &existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request an idempotency key was reserved for
+func (pg *Postgres) CompleteIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest) error {
+	header, err := json.Marshal(request.Header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotent response header: %w", err)
+	}
+	_, err = pg.RawDB().ExecContext(ctx, `
+			UPDATE idempotency_keys SET status = $4, header = $5, body = $6
+			WHERE scope = $1 AND key = $2 AND request_hash = $3
+		`, request.Scope, request.Key, request.RequestHash, request.Status, header, request.Body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes an idempotency key whose response was not stored
+func (pg *Postgres) ReleaseIdempotencyKey(ctx context.Context, scope string, key string) error {
+	_, err := pg.RawDB().ExecContext(ctx, `
+			DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status IS NULL
+		`, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEvents returns audit events created after since, newest first, optionally filtered by actor
+func (pg *Postgres) GetAuditEvents(ctx context.Context, actor string, since time.Time, limit int) ([]middleware.AuditEvent, error) {
+	rows, err := pg.RawDB().QueryContext(ctx, `
+			SELECT id, created_at, actor, method, route, path, entities, status, coalesce(request_id, '')
+			FROM audit_events
+			WHERE created_at > $1 AND ($2 = '' OR actor = $2)
+			ORDER BY created_at DESC
+			LIMIT $3
+		`, since, actor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer closers.Panic(rows)
+
+	events := []middleware.AuditEvent{}
+	for rows.Next() {
+		var (
+			event    middleware.AuditEvent
+			entities []byte
+		)
+		if err := rows.Scan(&event.ID, &event.CreatedAt, &event.Actor, &event.Method, &event.Route,
+			&event.Path, &entities, &event.Status, &event.RequestID); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if err := json.Unmarshal(entities, &event.Entities); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit entities: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row errors after scanning audit events: %w", err)
+	}
+	return events, nil
 }
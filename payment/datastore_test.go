@@ -2,6 +2,8 @@ package payment
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +13,9 @@ import (
 	"github.com/brave-intl/bat-go/utils/inputs"
 	"github.com/jmoiron/sqlx"
 	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetPagedMerchantTransactions(t *testing.T) {
@@ -78,3 +83,150 @@ func TestGetPagedMerchantTransactions(t *testing.T) {
 		t.Errorf("should have total count of 3 transactions: %d\n", c)
 	}
 }
+
+func TestRotateKeyCarriesOverRateLimit(t *testing.T) {
+	oldEncryptionKey := EncryptionKey
+	defer func() {
+		EncryptionKey = oldEncryptionKey
+		InitEncryptionKeys()
+	}()
+	EncryptionKey = "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0"
+	InitEncryptionKeys()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	service := &Service{Datastore: pg}
+
+	oldID, newID := uuid.NewV4(), uuid.NewV4()
+	now := time.Now()
+	encrypted, nonce, err := GenerateSecret()
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT (.+) FROM api_keys WHERE id = \$1`).WithArgs(oldID.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "merchant_id", "created_at", "expiry",
+			"rate_limit_per_minute", "rate_limit_burst", "daily_quota", "scopes", "token_hash", "last_used_at"}).
+			AddRow(oldID.String(), "default", "brave.com", now, nil, 60, 10, 5000, "{transactions:read}", "hash", nil))
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs("brave.com", "default", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "merchant_id", "encrypted_secret_key", "nonce",
+			"created_at", "expiry", "scopes", "token_hash"}).
+			AddRow(newID.String(), "default", "brave.com", encrypted, nonce, now, nil, "{transactions:read}", "new-hash"))
+	mock.ExpectQuery(`UPDATE api_keys SET rate_limit_per_minute = \$2, rate_limit_burst = \$3, daily_quota = \$4`).
+		WithArgs(newID.String(), 60, 10, 5000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "merchant_id", "created_at", "expiry",
+			"rate_limit_per_minute", "rate_limit_burst", "daily_quota", "scopes", "last_used_at"}).
+			AddRow(newID.String(), "default", "brave.com", now, nil, 60, 10, 5000, "{transactions:read}", nil))
+	mock.ExpectQuery(`UPDATE api_keys SET expiry=\(current_timestamp \+ \$2\)`).WithArgs(oldID.String(), "300s").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "merchant_id", "created_at", "expiry"}).
+			AddRow(oldID.String(), "default", "brave.com", now, now.Add(5*time.Minute)))
+
+	key, err := service.RotateKey(context.Background(), oldID, 300)
+	require.NoError(t, err)
+	assert.Equal(t, newID.String(), key.ID)
+	assert.NotEmpty(t, key.Token)
+	require.NotNil(t, key.RateLimitPerMinute, "the rate limit is carried over to the new key")
+	assert.Equal(t, 60, *key.RateLimitPerMinute)
+	assert.Equal(t, 10, key.RateLimitBurst)
+	require.NotNil(t, key.DailyQuota)
+	assert.Equal(t, 5000, *key.DailyQuota)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// returnedRow answers a multi-row insert with a row for each id CreateOrders generates, as it is matched
+type returnedRow struct {
+	rows *sqlmock.Rows
+	row  func(id string) []driver.Value
+}
+
+func (r returnedRow) Match(v driver.Value) bool {
+	id, ok := v.(string)
+	if ok {
+		r.rows.AddRow(r.row(id)...)
+	}
+	return ok
+}
+
+func TestCreateOrdersChunksInserts(t *testing.T) {
+	defer func(max int) { maxRowsPerInsert = max }(maxRowsPerInsert)
+	maxRowsPerInsert = 2
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+	now := time.Now()
+	item := OrderItem{SKU: "user-wallet-vote", Quantity: 2, Price: decimal.NewFromFloat(0.25),
+		Subtotal: decimal.NewFromFloat(0.5), Currency: "BAT", CredentialType: "single-use"}
+	newOrders := make([]NewOrder, 3)
+	for i := range newOrders {
+		newOrders[i] = NewOrder{MerchantID: "brave.com", TotalPrice: item.Subtotal, Currency: "BAT",
+			Location: "brave.com", Status: "pending", Items: []OrderItem{item}}
+	}
+
+	// each chunk is one statement of as many rows, each row of nine parameters led by the order id
+	expectInsert := func(query string, rows int, columns []string, row func(id string) []driver.Value) {
+		returned := sqlmock.NewRows(columns)
+		args := []driver.Value{}
+		for i := 0; i < rows; i++ {
+			args = append(args, returnedRow{rows: returned, row: row})
+			for j := 1; j < 9; j++ {
+				args = append(args, sqlmock.AnyArg())
+			}
+		}
+		mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(returned)
+	}
+	orderColumns := []string{"id", "created_at", "currency", "updated_at", "total_price", "merchant_id", "location",
+		"status", "exchange_rate", "bat_total_price", "rated_at"}
+	orderRow := func(id string) []driver.Value {
+		return []driver.Value{id, now, "BAT", now, "0.5", "brave.com", "brave.com", "pending", nil, "0.5", nil}
+	}
+	itemColumns := []string{"id", "order_id", "sku", "created_at", "updated_at", "currency", "quantity", "price",
+		"location", "description", "credential_type", "subtotal"}
+	itemRow := func(orderID string) []driver.Value {
+		return []driver.Value{uuid.NewV4().String(), orderID, item.SKU, now, now, "BAT", 2, "0.25", "brave.com", "",
+			"single-use", "0.5"}
+	}
+
+	mock.ExpectBegin()
+	expectInsert(`INSERT INTO orders (.+) VALUES \(\$1, (.+), \$9\), \(\$10, (.+), \$18\) RETURNING`, 2, orderColumns, orderRow)
+	expectInsert(`INSERT INTO orders (.+) VALUES \(\$1, (.+), \$9\) RETURNING`, 1, orderColumns, orderRow)
+	expectInsert(`INSERT INTO order_items (.+) VALUES \(\$1, (.+), \$9\), \(\$10, (.+), \$18\) RETURNING`, 2, itemColumns, itemRow)
+	expectInsert(`INSERT INTO order_items (.+) VALUES \(\$1, (.+), \$9\) RETURNING`, 1, itemColumns, itemRow)
+	for range newOrders {
+		for _, eventType := range []string{OrderLogCreated, OrderLogPriced} {
+			eventID := uuid.NewV4()
+			mock.ExpectQuery(`UPDATE orders SET event_sequence = event_sequence \+ 1`).
+				WillReturnRows(sqlmock.NewRows([]string{"event_sequence"}).AddRow(1))
+			mock.ExpectQuery(`INSERT INTO order_events`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "sequence", "type", "payload", "created_at", "dispatched_at"}).
+					AddRow(eventID, uuid.NewV4(), 1, eventType, []byte(`{}`), now, nil))
+			if eventType == OrderLogCreated {
+				mock.ExpectExec(`INSERT INTO order_event_outbox`).WithArgs(eventID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+		}
+	}
+	mock.ExpectCommit()
+
+	orders, err := pg.CreateOrders(context.Background(), newOrders)
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+	for _, order := range orders {
+		assert.Equal(t, "brave.com", order.MerchantID)
+		require.Len(t, order.Items, 1, "items are matched to their orders across chunks")
+		assert.Equal(t, order.ID, order.Items[0].OrderID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// a failed chunk rolls back the orders inserted before it
+	mock.ExpectBegin()
+	expectInsert(`INSERT INTO orders`, 2, orderColumns, orderRow)
+	mock.ExpectQuery(`INSERT INTO orders`).WillReturnError(errors.New("too many parameters"))
+	mock.ExpectRollback()
+	_, err = pg.CreateOrders(context.Background(), newOrders)
+	assert.EqualError(t, err, "failed to insert orders: too many parameters")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
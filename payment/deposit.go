@@ -0,0 +1,422 @@
+package payment
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/ethereum"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/go-chi/chi"
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// DepositAwaiting is the status of a deposit address which has received nothing
+	DepositAwaiting = "awaiting"
+	// DepositUnderpaid is the status of a deposit address which received less than its order's remaining balance
+	DepositUnderpaid = "underpaid"
+	// DepositPaid is the status of a deposit address which received exactly what its order was due
+	DepositPaid = "paid"
+	// DepositOverpaid is the status of a deposit address which received more than its order was due, the
+	// excess to be refunded
+	DepositOverpaid = "overpaid"
+
+	// ethereumChain names the chain in the chain cursors and the kind of the transactions it pays orders with
+	ethereumChain = "ethereum"
+	// batTokenAddress is the address of the BAT token contract on mainnet
+	batTokenAddress = "0x0d8775f648430679a709e98d2b0cb6250d2887ef"
+	// batDecimals is the number of decimals of the BAT token
+	batDecimals = 18
+	// depositScanBlocks is how many blocks are scanned for deposits at a time
+	depositScanBlocks = 100
+)
+
+var (
+	// ErrDepositsNotConfigured is the error when requesting a deposit address without DEPOSIT_FACTORY_ADDRESS set
+	ErrDepositsNotConfigured = errorutils.NewApplicationError("deposits_not_configured", http.StatusServiceUnavailable, "DEPOSIT_FACTORY_ADDRESS is not set", false)
+	// ErrOrderNotPending is the error when requesting a deposit address for an order which is paid or canceled
+	ErrOrderNotPending = errorutils.NewApplicationError("order_not_pending", http.StatusBadRequest, "the order is not awaiting payment", false)
+	// ErrDepositNotFound is the error when getting the deposit address of an order which has none
+	ErrDepositNotFound = errorutils.NewApplicationError("deposit_not_found", http.StatusNotFound, "the order has no deposit address", false)
+)
+
+// OrderDeposit is the on-chain address an order is paid to in BAT, and what it received once confirmed. Received
+// and Excess are in BAT
+type OrderDeposit struct {
+	OrderID   uuid.UUID       `json:"orderId" db:"order_id"`
+	Address   string          `json:"address" db:"address"`
+	Status    string          `json:"status" db:"status"`
+	Received  decimal.Decimal `json:"received" db:"received"`
+	Excess    decimal.Decimal `json:"excess" db:"excess"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time       `json:"updatedAt" db:"updated_at"`
+}
+
+// DepositInstructions is the deposit address of an order, with the token to send to it and how much remains
+type DepositInstructions struct {
+	OrderDeposit
+	Chain         string           `json:"chain"`
+	Token         string           `json:"token"`
+	AmountDue     *decimal.Decimal `json:"amountDue,omitempty"`
+	Confirmations int              `json:"confirmations"`
+}
+
+// depositConfig is how deposit addresses are derived and watched, see loadDepositConfig
+type depositConfig struct {
+	factory       string
+	initCodeHash  []byte
+	token         string
+	confirmations int
+	startBlock    uint64
+}
+
+// loadDepositConfig configures deposit addresses when DEPOSIT_FACTORY_ADDRESS is set. Deposit addresses are those
+// of the forwarding contracts the factory deploys with CREATE2, from the hash of their init code in
+// DEPOSIT_INIT_CODE_HASH and a salt of the order id left padded to 32 bytes, so the contract of an order can
+// be deployed to sweep its deposits later. BAT transfers of the token at BAT_TOKEN_ADDRESS, mainnet BAT
+// unless set, are watched from ETH_START_BLOCK and count once they have ETH_CONFIRMATIONS (12)
+func loadDepositConfig() (*depositConfig, error) {
+	factory := os.Getenv("DEPOSIT_FACTORY_ADDRESS")
+	if factory == "" {
+		return nil, nil
+	}
+	config := &depositConfig{
+		factory:       strings.ToLower(factory),
+		token:         strings.ToLower(os.Getenv("BAT_TOKEN_ADDRESS")),
+		confirmations: 12,
+	}
+	if config.token == "" {
+		config.token = batTokenAddress
+	}
+
+	initCodeHash, err := hex.DecodeString(strings.TrimPrefix(os.Getenv("DEPOSIT_INIT_CODE_HASH"), "0x"))
+	if err != nil || len(initCodeHash) != 32 {
+		return nil, errors.New("DEPOSIT_INIT_CODE_HASH must be a 32 byte hex hash")
+	}
+	config.initCodeHash = initCodeHash
+	if _, err := config.address(uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	if raw := os.Getenv("ETH_CONFIRMATIONS"); raw != "" {
+		config.confirmations, err = strconv.Atoi(raw)
+		if err != nil || config.confirmations < 1 {
+			return nil, fmt.Errorf("invalid ETH_CONFIRMATIONS %q", raw)
+		}
+	}
+	if raw := os.Getenv("ETH_START_BLOCK"); raw != "" {
+		config.startBlock, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ETH_START_BLOCK %q: %w", raw, err)
+		}
+	}
+	return config, nil
+}
+
+// address is the deposit address of an order
+func (c *depositConfig) address(orderID uuid.UUID) (string, error) {
+	var salt [32]byte
+	copy(salt[32-uuid.Size:], orderID.Bytes())
+	return ethereum.Create2Address(c.factory, salt, c.initCodeHash)
+}
+
+// excessBAT is how much more than its total an order's ledger paid, in BAT
+func excessBAT(order *Order, balance *OrderBalance) decimal.Decimal {
+	if order.Status == OrderLogCanceled {
+		// nothing is due for a canceled order, so every deposit is to be refunded
+		return balance.Paid.Mul(batPerUnit(order))
+	}
+	if !balance.Paid.GreaterThan(order.TotalPrice) {
+		return decimal.Zero
+	}
+	return balance.Paid.Sub(order.TotalPrice).Mul(batPerUnit(order))
+}
+
+// batPerUnit is the BAT price of a unit of the order's currency, at the rate snapshotted when it was placed
+func batPerUnit(order *Order) decimal.Decimal {
+	if order.Currency == "BAT" || order.BATTotalPrice == nil || !order.TotalPrice.IsPositive() {
+		return decimal.New(1, 0)
+	}
+	return order.BATTotalPrice.DivRound(order.TotalPrice, batDecimals)
+}
+
+// depositStatus is the status of a deposit address from what it received and the ledger of its order
+func depositStatus(order *Order, balance *OrderBalance, received decimal.Decimal) (string, decimal.Decimal) {
+	excess := excessBAT(order, balance)
+	switch {
+	case !received.IsPositive():
+		return DepositAwaiting, decimal.Zero
+	case excess.IsPositive():
+		if excess.GreaterThan(received) {
+			// the deposit address is only answerable for what it received
+			excess = received
+		}
+		return DepositOverpaid, excess
+	case balance.Settled:
+		return DepositPaid, decimal.Zero
+	default:
+		return DepositUnderpaid, decimal.Zero
+	}
+}
+
+// depositInstructions returns the deposit address of an order with how much remains to be paid to it
+func (s *Service) depositInstructions(ctx context.Context, order *Order, deposit *OrderDeposit) (*DepositInstructions, error) {
+	balance, err := s.GetOrderBalance(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	deposit.Address = ethereum.ChecksumAddress(deposit.Address)
+	instructions := &DepositInstructions{
+		OrderDeposit:  *deposit,
+		Chain:         ethereumChain,
+		Token:         ethereum.ChecksumAddress(s.deposits.token),
+		Confirmations: s.deposits.confirmations,
+	}
+	if order.Status == "pending" {
+		instructions.AmountDue = balance.BATRemaining
+	}
+	return instructions, nil
+}
+
+// CreateOrderDeposit returns the deposit address of an order awaiting payment in BAT, creating it the first
+// time it is requested
+func (s *Service) CreateOrderDeposit(ctx context.Context, orderID uuid.UUID) (*DepositInstructions, error) {
+	if s.deposits == nil {
+		return nil, ErrDepositsNotConfigured
+	}
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != "pending" {
+		return nil, ErrOrderNotPending
+	}
+	if order.Currency != "BAT" && order.BATTotalPrice == nil {
+		return nil, ErrOrderNotPayableInBAT
+	}
+
+	address, err := s.deposits.address(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Datastore.InsertOrderDeposit(ctx, &OrderDeposit{OrderID: orderID, Address: address}); err != nil {
+		return nil, err
+	}
+	deposit, err := s.Datastore.GetOrderDeposit(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.depositInstructions(ctx, order, deposit)
+}
+
+// GetOrderDeposit returns the deposit address of an order and what it received
+func (s *Service) GetOrderDeposit(ctx context.Context, orderID uuid.UUID) (*DepositInstructions, error) {
+	if s.deposits == nil {
+		return nil, ErrDepositsNotConfigured
+	}
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	deposit, err := s.Datastore.GetOrderDeposit(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if deposit == nil {
+		return nil, ErrDepositNotFound
+	}
+	return s.depositInstructions(ctx, order, deposit)
+}
+
+// ScanChainDeposits scans the blocks confirmed since the last scan for BAT transfers to deposit addresses,
+// recording each as a payment towards the order of the address. Blocks are scanned once they have the
+// configured confirmations, so no confirmed deposit is reorganized away. It returns true while confirmed
+// blocks remain to be scanned
+func (s *Service) ScanChainDeposits(ctx context.Context) (bool, error) {
+	if s.deposits == nil || s.eth == nil {
+		return false, nil
+	}
+	head, err := s.eth.BlockNumber(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block number: %w", err)
+	}
+	confirmations := uint64(s.deposits.confirmations)
+	if head+1 < confirmations {
+		return false, nil
+	}
+	confirmed := head + 1 - confirmations
+
+	cursor, err := s.Datastore.GetChainCursor(ctx, ethereumChain)
+	if err != nil {
+		return false, err
+	}
+	from := cursor + 1
+	if cursor == 0 {
+		from = s.deposits.startBlock
+		if from == 0 {
+			from = confirmed
+		}
+	}
+	if from > confirmed {
+		return false, nil
+	}
+	to := from + depositScanBlocks - 1
+	if to > confirmed {
+		to = confirmed
+	}
+
+	logs, err := s.eth.GetLogs(ctx, ethereum.LogFilter{
+		FromBlock: from,
+		ToBlock:   to,
+		Address:   s.deposits.token,
+		Topics:    [][]string{{ethereum.TransferTopic}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get transfer logs: %w", err)
+	}
+	transfers := map[string][]*ethereum.Transfer{}
+	addresses := []string{}
+	for _, log := range logs {
+		transfer, err := ethereum.ParseTransfer(log)
+		if err != nil || log.Removed {
+			continue
+		}
+		if _, ok := transfers[transfer.To]; !ok {
+			addresses = append(addresses, transfer.To)
+		}
+		transfers[transfer.To] = append(transfers[transfer.To], transfer)
+	}
+
+	if len(addresses) > 0 {
+		deposits, err := s.Datastore.GetOrderDepositsByAddress(ctx, addresses)
+		if err != nil {
+			return false, err
+		}
+		for _, deposit := range deposits {
+			for _, transfer := range transfers[deposit.Address] {
+				if err := s.recordChainDeposit(ctx, deposit, transfer); err != nil {
+					return false, err
+				}
+			}
+			if err := s.updateOrderDeposit(ctx, deposit.OrderID); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// another scanner may have scanned the blocks since, its payments are the same
+	if _, err := s.Datastore.AdvanceChainCursor(ctx, ethereumChain, cursor, to); err != nil {
+		return false, err
+	}
+	return to < confirmed, nil
+}
+
+// recordChainDeposit records a BAT transfer to a deposit address as a payment towards its order, once
+func (s *Service) recordChainDeposit(ctx context.Context, deposit OrderDeposit, transfer *ethereum.Transfer) error {
+	externalID := fmt.Sprintf("%s:%d", transfer.TransactionHash, transfer.LogIndex)
+	existing, err := s.Datastore.GetTransaction(externalID)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	amount := decimal.NewFromBigInt(transfer.Value, -batDecimals)
+	transaction, err := s.Datastore.CreateTransaction(deposit.OrderID, externalID, "completed", "BAT", ethereumChain, amount)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		// recorded by another scanner
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to record deposit: %w", err)
+	}
+	s.streamTransaction(transaction)
+
+	_, logger := logging.SetupLogger(ctx)
+	logger.Info().
+		Str("order_id", deposit.OrderID.String()).
+		Str("transaction_hash", transfer.TransactionHash).
+		Str("amount", amount.String()).
+		Msg("recorded deposit")
+	return nil
+}
+
+// updateOrderDeposit sets the status of an order's deposit address from what it received, marking the order
+// paid once its ledger settles it
+func (s *Service) updateOrderDeposit(ctx context.Context, orderID uuid.UUID) error {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	payments, err := s.Datastore.GetOrderPayments(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	received := decimal.Zero
+	for _, payment := range payments {
+		if payment.Kind == ethereumChain {
+			received = received.Add(payment.Amount)
+		}
+	}
+	balance := newOrderBalance(order, payments)
+	status, excess := depositStatus(order, balance, received)
+	if err := s.Datastore.UpdateOrderDeposit(ctx, orderID, status, received, excess); err != nil {
+		return err
+	}
+
+	if order.Status == "pending" && balance.Settled && order.TotalPrice.IsPositive() {
+		if err := s.Datastore.UpdateOrder(ctx, orderID, "paid"); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+	s.NotifyOrderChanged(orderID)
+	return nil
+}
+
+// CreateOrderDeposit is the handler for the deposit address an order is paid to in BAT
+func CreateOrderDeposit(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+
+		deposit, err := service.CreateOrderDeposit(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error creating the deposit address", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), deposit, w, http.StatusCreated)
+	})
+}
+
+// GetOrderDeposit is the handler for the deposit address of an order and what it received
+func GetOrderDeposit(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+
+		deposit, err := service.GetOrderDeposit(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the deposit address", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), deposit, w, http.StatusOK)
+	})
+}
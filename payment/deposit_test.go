@@ -0,0 +1,191 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/clients/ethereum"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain serves the transfer logs it was given up to its head
+type fakeChain struct {
+	head uint64
+	logs []ethereum.Log
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) GetLogs(ctx context.Context, filter ethereum.LogFilter) ([]ethereum.Log, error) {
+	if filter.ToBlock > c.head {
+		return nil, errors.New("block not found")
+	}
+	logs := []ethereum.Log{}
+	for _, log := range c.logs {
+		block, _ := ethereum.ParseQuantity(log.BlockNumber)
+		if block >= filter.FromBlock && block <= filter.ToBlock {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// transfer logs a transfer of BAT to the address at the block
+func (c *fakeChain) transfer(to string, bat int64, block uint64) {
+	value := new(big.Int).Mul(big.NewInt(bat), big.NewInt(1e18))
+	c.logs = append(c.logs, ethereum.Log{
+		Topics:          []string{ethereum.TransferTopic, ethereum.AddressTopic("0x01"), ethereum.AddressTopic(to)},
+		Data:            "0x" + value.Text(16),
+		BlockNumber:     ethereum.EncodeQuantity(block),
+		TransactionHash: fmt.Sprintf("0x%x", len(c.logs)),
+		LogIndex:        "0x0",
+	})
+}
+
+func TestScanChainDeposits(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{
+		Status:     "pending",
+		Currency:   "BAT",
+		TotalPrice: decimal.New(10, 0),
+	})
+	chain := &fakeChain{head: 100}
+	service := &Service{
+		Datastore:     ds,
+		orderWatchers: newOrderNotifier(),
+		eth:           chain,
+		deposits: &depositConfig{
+			factory:       "0x0000000000000000000000000000000000000000",
+			initCodeHash:  ethereum.Keccak256([]byte{0x00}),
+			token:         batTokenAddress,
+			confirmations: 3,
+			startBlock:    90,
+		},
+	}
+
+	instructions, err := service.CreateOrderDeposit(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, DepositAwaiting, instructions.Status)
+	assert.Equal(t, "10", instructions.AmountDue.String())
+	address := ds.deposits[order.ID].Address
+	assert.Equal(t, ethereum.ChecksumAddress(address), instructions.Address)
+	again, err := service.CreateOrderDeposit(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, instructions.Address, again.Address, "an order has one deposit address")
+
+	chain.transfer(address, 4, 95)
+	chain.transfer("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", 50, 96)
+	chain.transfer(address, 7, 99)
+
+	// blocks are scanned once they have three confirmations, up to block 98
+	more, err := service.ScanChainDeposits(ctx)
+	require.NoError(t, err)
+	assert.False(t, more)
+	assert.Equal(t, uint64(98), ds.cursors[ethereumChain])
+	deposit := ds.deposits[order.ID]
+	assert.Equal(t, DepositUnderpaid, deposit.Status)
+	assert.Equal(t, "4", deposit.Received.String())
+	assert.Equal(t, "pending", order.Status)
+
+	chain.head = 101
+	_, err = service.ScanChainDeposits(ctx)
+	require.NoError(t, err)
+	deposit = ds.deposits[order.ID]
+	assert.Equal(t, DepositOverpaid, deposit.Status)
+	assert.Equal(t, "11", deposit.Received.String())
+	assert.Equal(t, "1", deposit.Excess.String(), "the excess is to be refunded")
+	assert.Equal(t, "paid", order.Status, "the order is paid once its deposits are confirmed")
+
+	// scanning blocks again records their transfers once
+	ds.cursors[ethereumChain] = 90
+	_, err = service.ScanChainDeposits(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ds.transactions, 2)
+	assert.Equal(t, "11", ds.deposits[order.ID].Received.String())
+
+	_, err = service.CreateOrderDeposit(ctx, order.ID)
+	assert.True(t, errors.Is(err, ErrOrderNotPending))
+}
+
+func TestDepositStatus(t *testing.T) {
+	batTotal := decimal.New(20, 0)
+	order := &Order{Status: "pending", Currency: "USD", TotalPrice: decimal.New(5, 0), BATTotalPrice: &batTotal}
+	bat := func(amount int64) []OrderPayment {
+		return []OrderPayment{{Kind: ethereumChain, Currency: "BAT", Amount: decimal.New(amount, 0)}}
+	}
+
+	status, _ := depositStatus(order, newOrderBalance(order, nil), decimal.Zero)
+	assert.Equal(t, DepositAwaiting, status)
+	status, _ = depositStatus(order, newOrderBalance(order, bat(12)), decimal.New(12, 0))
+	assert.Equal(t, DepositUnderpaid, status)
+	status, _ = depositStatus(order, newOrderBalance(order, bat(20)), decimal.New(20, 0))
+	assert.Equal(t, DepositPaid, status)
+	status, excess := depositStatus(order, newOrderBalance(order, bat(24)), decimal.New(24, 0))
+	assert.Equal(t, DepositOverpaid, status)
+	assert.Equal(t, "4", excess.String(), "the excess of an order priced in fiat is in BAT")
+
+	order.Status = OrderLogCanceled
+	status, excess = depositStatus(order, newOrderBalance(order, bat(8)), decimal.New(8, 0))
+	assert.Equal(t, DepositOverpaid, status, "deposits to canceled orders are refunded")
+	assert.Equal(t, "8", excess.String())
+}
+
+func TestOrderDeposit(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID := uuid.NewV4()
+
+	mock.ExpectExec(`INSERT INTO order_deposits (.+) ON CONFLICT \(order_id\) DO NOTHING`).
+		WithArgs(orderID, "0xaddress").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, pg.InsertOrderDeposit(context.Background(), &OrderDeposit{OrderID: orderID, Address: "0xaddress"}))
+
+	mock.ExpectExec(`UPDATE order_deposits SET status = \$1, received = \$2, excess = \$3(.+)WHERE order_id = \$4`).
+		WithArgs("overpaid", "12", "2", orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, pg.UpdateOrderDeposit(context.Background(), orderID, "overpaid", decimal.New(12, 0), decimal.New(2, 0)))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdvanceChainCursor(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+	// the first scan of a chain creates its cursor
+	mock.ExpectExec(`INSERT INTO chain_cursors (.+) ON CONFLICT \(chain\) DO NOTHING`).WithArgs("ethereum", 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	advanced, err := pg.AdvanceChainCursor(context.Background(), "ethereum", 0, 100)
+	require.NoError(t, err)
+	assert.True(t, advanced)
+
+	mock.ExpectExec(`UPDATE chain_cursors SET block = \$1(.+)WHERE chain = \$2 AND block = \$3`).WithArgs(200, "ethereum", 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	advanced, err = pg.AdvanceChainCursor(context.Background(), "ethereum", 100, 200)
+	require.NoError(t, err)
+	assert.True(t, advanced)
+
+	mock.ExpectExec(`UPDATE chain_cursors`).WithArgs(200, "ethereum", 100).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	advanced, err = pg.AdvanceChainCursor(context.Background(), "ethereum", 100, 200)
+	require.NoError(t, err)
+	assert.False(t, advanced, "a cursor moved by another scanner is not advanced")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// MerchantEncryptionKey is the public key, a JSON web key, which payloads sent to a merchant are encrypted with
+type MerchantEncryptionKey struct {
+	Merchant  string    `json:"merchant" db:"merchant_id"`
+	JWK       string    `json:"jwk" db:"jwk"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// ParseEncryptionKey parses and checks a merchant's public JSON web key can be used for encryption
+func ParseEncryptionKey(raw string) (*jose.JSONWebKey, error) {
+	var jwk jose.JSONWebKey
+	if err := jwk.UnmarshalJSON([]byte(raw)); err != nil {
+		return nil, fmt.Errorf("failed to parse jwk: %w", err)
+	}
+	if !jwk.IsPublic() {
+		return nil, errors.New("jwk must be a public key")
+	}
+	if jwk.Use != "" && jwk.Use != "enc" {
+		return nil, errors.New("jwk must be an encryption key")
+	}
+	// make sure we are able to encrypt to the key before accepting it
+	if _, err := middleware.EncryptJWE(&jwk, []byte("{}"), "application/json"); err != nil {
+		return nil, err
+	}
+	return &jwk, nil
+}
+
+// merchantEncryptionKey gets the parsed encryption key of a merchant, or nil if none is registered
+func (s *Service) merchantEncryptionKey(merchantID string) (*jose.JSONWebKey, error) {
+	key, err := s.Datastore.GetMerchantEncryptionKey(merchantID)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return ParseEncryptionKey(key.JWK)
+}
+
+// merchantRecipientKey - implement middleware.RecipientKeyLookup for merchant routes
+func (s *Service) merchantRecipientKey(r *http.Request) (*jose.JSONWebKey, error) {
+	return s.merchantEncryptionKey(chi.URLParam(r, "merchantID"))
+}
+
+// orderRecipientKey - implement middleware.RecipientKeyLookup for order routes, using the key of the order's merchant
+func (s *Service) orderRecipientKey(r *http.Request) (*jose.JSONWebKey, error) {
+	orderID, err := uuid.FromString(chi.URLParam(r, "orderID"))
+	if err != nil {
+		return nil, nil
+	}
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	return s.merchantEncryptionKey(order.MerchantID)
+}
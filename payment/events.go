@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// OrderEventStatus is sent when the status of an order changes
+	OrderEventStatus = "status"
+	// OrderEventCredentials is sent as the credentials of an order are submitted and signed
+	OrderEventCredentials = "credentials"
+	// OrderEventItemSigned is sent when the credentials of an order item have been signed
+	OrderEventItemSigned = "item_signed"
+
+	orderWatchInterval = time.Second
+)
+
+// OrderEvent is a change to the state of an order, streamed to clients during checkout
+type OrderEvent struct {
+	Type           string     `json:"type"`
+	OrderID        uuid.UUID  `json:"orderId"`
+	Status         string     `json:"status,omitempty"`
+	ItemID         *uuid.UUID `json:"itemId,omitempty"`
+	SubmittedItems int        `json:"submittedItems"`
+	SignedItems    int        `json:"signedItems"`
+	TotalItems     int        `json:"totalItems"`
+}
+
+// orderState is the part of an order clients watch for changes
+type orderState struct {
+	status    string
+	total     int
+	submitted map[uuid.UUID]bool
+	signed    map[uuid.UUID]bool
+}
+
+// orderNotifier wakes the watchers of an order when this instance changes it, watchers
+// otherwise pick up changes made elsewhere, such as by the signing worker, on their next poll
+type orderNotifier struct {
+	mu       sync.Mutex
+	watchers map[uuid.UUID]map[chan struct{}]bool
+}
+
+func newOrderNotifier() *orderNotifier {
+	return &orderNotifier{watchers: map[uuid.UUID]map[chan struct{}]bool{}}
+}
+
+func (n *orderNotifier) subscribe(orderID uuid.UUID) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if n == nil {
+		return ch
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.watchers[orderID] == nil {
+		n.watchers[orderID] = map[chan struct{}]bool{}
+	}
+	n.watchers[orderID][ch] = true
+	return ch
+}
+
+func (n *orderNotifier) unsubscribe(orderID uuid.UUID, ch chan struct{}) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.watchers[orderID], ch)
+	if len(n.watchers[orderID]) == 0 {
+		delete(n.watchers, orderID)
+	}
+}
+
+func (n *orderNotifier) notify(orderID uuid.UUID) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.watchers[orderID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// NotifyOrderChanged wakes anyone watching the order
+func (s *Service) NotifyOrderChanged(orderID uuid.UUID) {
+	s.orderWatchers.notify(orderID)
+}
+
+// getOrderState reads the watched state of an order, returning nil if it does not exist
+func (s *Service) getOrderState(orderID uuid.UUID) (*orderState, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	creds, err := s.Datastore.GetOrderCreds(orderID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &orderState{
+		status:    order.Status,
+		total:     len(order.Items),
+		submitted: map[uuid.UUID]bool{},
+		signed:    map[uuid.UUID]bool{},
+	}
+	if creds != nil {
+		for _, cred := range *creds {
+			state.submitted[cred.ID] = true
+			if cred.SignedCreds != nil {
+				state.signed[cred.ID] = true
+			}
+		}
+	}
+	return state, nil
+}
+
+// diffOrderState returns the events needed to bring a watcher from the previous state to the next
+func diffOrderState(orderID uuid.UUID, prev, next *orderState) []OrderEvent {
+	progress := func(eventType string) OrderEvent {
+		return OrderEvent{
+			Type:           eventType,
+			OrderID:        orderID,
+			Status:         next.status,
+			SubmittedItems: len(next.submitted),
+			SignedItems:    len(next.signed),
+			TotalItems:     next.total,
+		}
+	}
+
+	events := []OrderEvent{}
+	if prev == nil || prev.status != next.status {
+		events = append(events, progress(OrderEventStatus))
+	}
+	for itemID := range next.signed {
+		if prev == nil || !prev.signed[itemID] {
+			event := progress(OrderEventItemSigned)
+			id := itemID
+			event.ItemID = &id
+			events = append(events, event)
+		}
+	}
+	if prev == nil || len(prev.submitted) != len(next.submitted) || len(prev.signed) != len(next.signed) {
+		events = append(events, progress(OrderEventCredentials))
+	}
+	return events
+}
+
+// WatchOrder streams the events of an order until the context is done, starting with its
+// current state. The channel is closed when watching stops
+func (s *Service) WatchOrder(ctx context.Context, orderID uuid.UUID) (<-chan OrderEvent, <-chan error) {
+	events := make(chan OrderEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		wake := s.orderWatchers.subscribe(orderID)
+		defer s.orderWatchers.unsubscribe(orderID, wake)
+
+		ticker := time.NewTicker(orderWatchInterval)
+		defer ticker.Stop()
+
+		var prev *orderState
+		for {
+			next, err := s.getOrderState(orderID)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if next != nil {
+				for _, event := range diffOrderState(orderID, prev, next) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+				prev = next
+			}
+
+			select {
+			case <-ticker.C:
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, errs
+}
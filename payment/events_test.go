@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffOrderState(t *testing.T) {
+	orderID := uuid.NewV4()
+	itemID := uuid.NewV4()
+
+	pending := &orderState{status: "pending", total: 1, submitted: map[uuid.UUID]bool{}, signed: map[uuid.UUID]bool{}}
+	events := diffOrderState(orderID, nil, pending)
+	if assert.Len(t, events, 2, "watchers are first sent the current state") {
+		assert.Equal(t, OrderEventStatus, events[0].Type)
+		assert.Equal(t, OrderEventCredentials, events[1].Type)
+	}
+	assert.Empty(t, diffOrderState(orderID, pending, pending))
+
+	submitted := &orderState{status: "paid", total: 1, submitted: map[uuid.UUID]bool{itemID: true}, signed: map[uuid.UUID]bool{}}
+	events = diffOrderState(orderID, pending, submitted)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "paid", events[0].Status)
+		assert.Equal(t, 1, events[1].SubmittedItems)
+	}
+
+	signed := &orderState{status: "paid", total: 1, submitted: map[uuid.UUID]bool{itemID: true}, signed: map[uuid.UUID]bool{itemID: true}}
+	events = diffOrderState(orderID, submitted, signed)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, OrderEventItemSigned, events[0].Type)
+		assert.Equal(t, itemID, *events[0].ItemID)
+		assert.Equal(t, OrderEventCredentials, events[1].Type)
+		assert.Equal(t, 1, events[1].SignedItems)
+	}
+}
+
+func TestOrderNotifier(t *testing.T) {
+	orderID := uuid.NewV4()
+	notifier := newOrderNotifier()
+	wake := notifier.subscribe(orderID)
+
+	notifier.notify(uuid.NewV4())
+	notifier.notify(orderID)
+	notifier.notify(orderID)
+	select {
+	case <-wake:
+	case <-time.After(time.Second):
+		t.Fatal("expected watcher to be woken")
+	}
+
+	notifier.unsubscribe(orderID, wake)
+	assert.Empty(t, notifier.watchers)
+
+	var unset *orderNotifier
+	unset.notify(orderID)
+}
@@ -0,0 +1,47 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoExchangeRate is returned when an order priced in a fiat currency cannot be given a BAT exchange rate
+var ErrNoExchangeRate = errorutils.NewApplicationError("no_exchange_rate", http.StatusServiceUnavailable, "no BAT exchange rate for the currency", true)
+
+// ExchangeRate is the price of one BAT in a fiat currency, snapshotted onto an order when it is placed
+type ExchangeRate struct {
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// ToBAT converts an amount in the rate's currency to BAT
+func (rate ExchangeRate) ToBAT(amount decimal.Decimal) decimal.Decimal {
+	return amount.DivRound(rate.Rate, 18)
+}
+
+// snapshotExchangeRate fetches the BAT exchange rate of the currency orders are priced in. Orders priced in
+// BAT need no rate, nor do fiat orders when there is no ratios service, which can then only be paid in
+// their own currency
+func (s *Service) snapshotExchangeRate(ctx context.Context, currency string) (*ExchangeRate, error) {
+	if currency == "BAT" || s.ratios == nil {
+		return nil, nil
+	}
+	rates, err := s.ratios.FetchRate(ctx, "BAT", currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the BAT exchange rate: %w", err)
+	}
+	if rates == nil {
+		return nil, fmt.Errorf("%s: %w", currency, ErrNoExchangeRate)
+	}
+	rate, ok := rates.Payload[currency]
+	if !ok || !rate.IsPositive() {
+		return nil, fmt.Errorf("%s: %w", currency, ErrNoExchangeRate)
+	}
+	return &ExchangeRate{Currency: currency, Rate: rate, UpdatedAt: rates.LastUpdated}, nil
+}
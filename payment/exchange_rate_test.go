@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/ratios"
+	ratiosmock "github.com/brave-intl/bat-go/utils/clients/ratios/mock"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotExchangeRate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRatios := ratiosmock.NewMockClient(mockCtrl)
+	updatedAt := time.Now().Add(-time.Minute)
+	mockRatios.EXPECT().FetchRate(gomock.Any(), "BAT", "USD").
+		Return(&ratios.RateResponse{LastUpdated: updatedAt, Payload: map[string]decimal.Decimal{"USD": decimal.RequireFromString("0.25")}}, nil)
+	mockRatios.EXPECT().FetchRate(gomock.Any(), "BAT", "EUR").
+		Return(&ratios.RateResponse{Payload: map[string]decimal.Decimal{}}, nil)
+
+	service := &Service{}
+	rate, err := service.snapshotExchangeRate(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Nil(t, rate, "without a ratios service fiat orders are not priced in BAT")
+
+	service.ratios = mockRatios
+	rate, err = service.snapshotExchangeRate(context.Background(), "BAT")
+	require.NoError(t, err)
+	assert.Nil(t, rate)
+
+	rate, err = service.snapshotExchangeRate(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", rate.Currency)
+	assert.True(t, decimal.RequireFromString("0.25").Equal(rate.Rate))
+	assert.Equal(t, updatedAt, rate.UpdatedAt)
+	assert.True(t, decimal.RequireFromString("39.96").Equal(rate.ToBAT(decimal.RequireFromString("9.99"))))
+
+	_, err = service.snapshotExchangeRate(context.Background(), "EUR")
+	assert.True(t, errors.Is(err, ErrNoExchangeRate))
+}
+
+func TestProjectOrderExchangeRate(t *testing.T) {
+	orderID := uuid.NewV4()
+	rate, batTotal, ratedAt := decimal.RequireFromString("0.25"), decimal.RequireFromString("40"), time.Now().UTC().Truncate(time.Second)
+	events := []OrderLogEvent{
+		orderLogEvent(t, orderID, 1, OrderLogCreated, newOrderCreatedPayload(&Order{MerchantID: "brave.com", Currency: "USD", Status: "pending"})),
+		orderLogEvent(t, orderID, 2, OrderLogPriced, orderPricedPayload{
+			TotalPrice: decimal.RequireFromString("10"), Currency: "USD", ExchangeRate: &rate, BATTotalPrice: &batTotal, RatedAt: &ratedAt,
+		}),
+	}
+
+	projection, err := ProjectOrder(events)
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(*projection.Order.ExchangeRate))
+	assert.True(t, batTotal.Equal(*projection.Order.BATTotalPrice))
+	assert.True(t, ratedAt.Equal(*projection.Order.RatedAt))
+
+	row := projection.Order
+	assert.Empty(t, projection.Drift(&row))
+	row.BATTotalPrice = nil
+	assert.Equal(t, []string{"batTotalPrice is unset, its events say 40"}, projection.Drift(&row))
+}
@@ -0,0 +1,240 @@
+package payment
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/s3"
+	"github.com/brave-intl/bat-go/utils/workers"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// exportPartRows is the most rows written to one file of a partition
+	exportPartRows = 100000
+	// exportMaxDays is the longest date range an export can be re-run for at once
+	exportMaxDays    = 31
+	exportDateFormat = "2006-01-02"
+)
+
+// ErrExportNotConfigured is the error when exporting without EXPORT_LOCATION set
+var ErrExportNotConfigured = errorutils.NewApplicationError("export_not_configured", http.StatusServiceUnavailable, "EXPORT_LOCATION is not set", false)
+
+// ExportedTransaction is a transaction as exported to the data warehouse
+type ExportedTransaction struct {
+	Transaction
+	MerchantID string `json:"merchantId" db:"merchant_id"`
+}
+
+// ExportedVote is a queued vote as exported to the data warehouse, with only the number of its credentials
+type ExportedVote struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	VoteText    string    `json:"voteText" db:"vote_text"`
+	Credentials int       `json:"credentials" db:"credentials"`
+	Erred       bool      `json:"erred" db:"erred"`
+	ErrCode     string    `json:"errcode,omitempty" db:"errcode"`
+	Processed   bool      `json:"processed" db:"processed"`
+	RequestID   string    `json:"requestId,omitempty" db:"request_id"`
+}
+
+// ExportFile is a file of an exported partition
+type ExportFile struct {
+	Dataset  string `json:"dataset"`
+	Location string `json:"location"`
+	Rows     int    `json:"rows"`
+	Bytes    int    `json:"bytes"`
+	SHA256   string `json:"sha256"`
+}
+
+// ExportManifest lists the files exported for a day. It is written last, so a partition with a
+// manifest is complete
+type ExportManifest struct {
+	Date        string       `json:"date"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+	Files       []ExportFile `json:"files"`
+}
+
+// exportLocation is where exports are written, an s3 uri or a local directory
+func exportLocation() string {
+	return strings.TrimSuffix(os.Getenv("EXPORT_LOCATION"), "/")
+}
+
+// ExportDay exports the transactions updated and votes queued on the day, in UTC, as gzipped ndjson
+// partitioned by date under EXPORT_LOCATION. Re-running a day replaces its partition
+func (s *Service) ExportDay(ctx context.Context, day time.Time) (*ExportManifest, error) {
+	location := exportLocation()
+	if location == "" {
+		return nil, ErrExportNotConfigured
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	manifest := &ExportManifest{
+		Date:        from.Format(exportDateFormat),
+		GeneratedAt: time.Now().UTC(),
+		Files:       []ExportFile{},
+	}
+
+	if err := workers.Claim(ctx, nil, "export "+manifest.Date); err != nil {
+		return nil, err
+	}
+	_ = workers.Progress(ctx, "exporting transactions")
+	transactions, err := s.Datastore.GetTransactionsUpdatedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]interface{}, len(transactions))
+	for i := range transactions {
+		rows[i] = transactions[i]
+	}
+	files, err := writeExportPartition(ctx, location, "transactions", manifest.Date, rows)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, files...)
+
+	_ = workers.Progress(ctx, "exporting votes")
+	votes, err := s.Datastore.GetVotesCreatedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows = make([]interface{}, len(votes))
+	for i := range votes {
+		rows[i] = votes[i]
+	}
+	files, err = writeExportPartition(ctx, location, "votes", manifest.Date, rows)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, files...)
+
+	out, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export manifest: %w", err)
+	}
+	manifestLocation := fmt.Sprintf("%s/manifests/dt=%s.json", location, manifest.Date)
+	if err := s3.WriteLocation(ctx, manifestLocation, out, "application/json"); err != nil {
+		return nil, fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeExportPartition writes the rows of a dataset's partition as gzipped ndjson files of at most
+// exportPartRows rows. A partition without rows is written as one empty file, so it is not mistaken
+// for a missed day
+func writeExportPartition(ctx context.Context, location, dataset, date string, rows []interface{}) ([]ExportFile, error) {
+	var files []ExportFile
+	for part := 0; part == 0 || part*exportPartRows < len(rows); part++ {
+		end := (part + 1) * exportPartRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := rows[part*exportPartRows : end]
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		enc := json.NewEncoder(gz)
+		for _, row := range chunk {
+			if err := enc.Encode(row); err != nil {
+				return nil, fmt.Errorf("failed to encode %s row: %w", dataset, err)
+			}
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		file := ExportFile{
+			Dataset:  dataset,
+			Location: fmt.Sprintf("%s/%s/dt=%s/part-%05d.ndjson.gz", location, dataset, date, part),
+			Rows:     len(chunk),
+			Bytes:    buf.Len(),
+			SHA256:   hex.EncodeToString(sum[:]),
+		}
+		if err := s3.WriteLocation(ctx, file.Location, buf.Bytes(), "application/gzip"); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.Location, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// RunNightlyExport exports the previous day, scheduled when EXPORT_LOCATION is set
+func (s *Service) RunNightlyExport(ctx context.Context) error {
+	manifest, err := s.ExportDay(ctx, time.Now().UTC().AddDate(0, 0, -1))
+	if err != nil {
+		return err
+	}
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		logger.Info().Str("date", manifest.Date).Int("files", len(manifest.Files)).Msg("exported to the data warehouse")
+	}
+	return nil
+}
+
+// ExportRouter lets operators re-run the export of a date range
+func ExportRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("POST", "/", RunExport(service))
+	return r
+}
+
+// ExportRequest is a date range to export, inclusive
+type ExportRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RunExport is the handler for re-running the export of a date range
+func RunExport(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req ExportRequest
+		if err := requestutils.ReadJSON(r.Body, &req); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		from, ferr := time.Parse(exportDateFormat, req.From)
+		to, terr := time.Parse(exportDateFormat, req.To)
+		validationPayload := map[string]interface{}{}
+		if ferr != nil {
+			validationPayload["from"] = "must be a date of the form 2006-01-02"
+		}
+		if terr != nil {
+			validationPayload["to"] = "must be a date of the form 2006-01-02"
+		}
+		if ferr == nil && terr == nil {
+			if to.Before(from) {
+				validationPayload["to"] = "must not be before from"
+			} else if to.Sub(from) >= exportMaxDays*24*time.Hour {
+				validationPayload["to"] = fmt.Sprintf("at most %d days can be exported at once", exportMaxDays)
+			}
+		}
+		if len(validationPayload) > 0 {
+			return handlers.ValidationError("request body", validationPayload)
+		}
+
+		manifests := []ExportManifest{}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			manifest, err := service.ExportDay(r.Context(), day)
+			if err != nil {
+				return handlers.WrapError(err, "Error exporting "+day.Format(exportDateFormat), http.StatusInternalServerError)
+			}
+			manifests = append(manifests, *manifest)
+		}
+		return handlers.RenderContent(r.Context(), manifests, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportDay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	require.NoError(t, os.Setenv("EXPORT_LOCATION", dir+"/"))
+	defer func() { _ = os.Unsetenv("EXPORT_LOCATION") }()
+
+	day := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com"})
+	ds.transactions = []Transaction{
+		{ID: uuid.NewV4(), OrderID: order.ID, UpdatedAt: day, Amount: decimal.NewFromFloat(1.5)},
+		{ID: uuid.NewV4(), OrderID: order.ID, UpdatedAt: day.AddDate(0, 0, 1)},
+	}
+	service := &Service{Datastore: ds}
+
+	manifest, err := service.ExportDay(context.Background(), day)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-06-01", manifest.Date)
+	require.Len(t, manifest.Files, 2)
+
+	txFile := manifest.Files[0]
+	assert.Equal(t, "transactions", txFile.Dataset)
+	assert.Equal(t, filepath.Join(dir, "transactions/dt=2021-06-01/part-00000.ndjson.gz"), txFile.Location)
+	assert.Equal(t, 1, txFile.Rows)
+
+	data, err := ioutil.ReadFile(txFile.Location)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), txFile.SHA256)
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	scanner := bufio.NewScanner(gz)
+	require.True(t, scanner.Scan())
+	var exported map[string]interface{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &exported))
+	assert.Equal(t, "brave.com", exported["merchantId"])
+	assert.Equal(t, ds.transactions[0].ID.String(), exported["id"])
+	assert.False(t, scanner.Scan())
+
+	// a day without votes still has its partition written
+	assert.Equal(t, "votes", manifest.Files[1].Dataset)
+	assert.Equal(t, 0, manifest.Files[1].Rows)
+	_, err = os.Stat(manifest.Files[1].Location)
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "manifests/dt=2021-06-01.json"))
+	assert.NoError(t, err)
+}
+
+func TestRunExport(t *testing.T) {
+	service := &Service{Datastore: newFakeDatastore()}
+	router := ExportRouter(service)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"from": "2021-06-02", "to": "2021-06-01"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"from": "2021-01-01", "to": "2021-06-01"}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, post(`{"from": "2021-06-01", "to": "2021-06-01"}`).Code)
+
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	require.NoError(t, os.Setenv("EXPORT_LOCATION", dir))
+	defer func() { _ = os.Unsetenv("EXPORT_LOCATION") }()
+
+	rr := post(`{"from": "2021-06-01", "to": "2021-06-03"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var manifests []ExportManifest
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &manifests))
+	require.Len(t, manifests, 3)
+	assert.Equal(t, "2021-06-03", manifests[2].Date)
+}
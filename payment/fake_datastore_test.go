@@ -0,0 +1,1972 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/brave-intl/bat-go/wallet"
+	migrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+// errNotFaked is returned by the methods of fakeDatastore which only make sense inside a database transaction,
+// those are covered by sqlmock tests
+var errNotFaked = errors.New("not supported by the fake datastore")
+
+// fakeOrderTrial is the wallet which claimed the trial of an order
+type fakeOrderTrial struct {
+	walletID   uuid.UUID
+	merchantID string
+}
+
+// fakeDatastore is an in memory Datastore shared by the tests of the service and its handlers. It keeps to the
+// rules the postgres datastore enforces, like which order status changes are allowed, so tests see the same
+// errors. Every method counts its calls, and fails with the error set for it in errs
+type fakeDatastore struct {
+	mu sync.Mutex
+	// db is returned by RawDB
+	db    *sqlx.DB
+	calls map[string]int
+	errs  map[string]error
+
+	orders       map[uuid.UUID]*Order
+	created      [][]NewOrder
+	transactions []Transaction
+	payments     []OrderPayment
+	sessions     map[uuid.UUID]CheckoutSession
+	deposits     map[uuid.UUID]OrderDeposit
+	cursors      map[string]uint64
+	vouchers     map[string]Voucher
+	issuers      []Issuer
+	creds        []OrderCreds
+	windows      []OrderCreds
+	events       []OrderLogEvent
+	published    map[uuid.UUID]bool
+	keys         map[string]*Key
+	merchants    map[string]*Merchant
+	settings     map[string]MerchantSettings
+	encryption   map[string]MerchantEncryptionKey
+	secrets      []WebhookSecret
+	deliveries   []WebhookDelivery
+	redeemed     []RedeemedCredential
+	usage        []MerchantUsage
+	signingKeys  map[uuid.UUID]*MerchantSigningKey
+	audit        []middleware.AuditEvent
+	idempotent   map[string]middleware.IdempotentRequest
+	votes        []ExportedVote
+	tallied      int
+	tallies      []VoteTally
+	rollups      []VoteTallyRollup
+	trials       map[uuid.UUID]fakeOrderTrial
+	receipts     map[uuid.UUID]OrderReceipt
+}
+
+func newFakeDatastore() *fakeDatastore {
+	return &fakeDatastore{
+		calls:       map[string]int{},
+		errs:        map[string]error{},
+		orders:      map[uuid.UUID]*Order{},
+		sessions:    map[uuid.UUID]CheckoutSession{},
+		deposits:    map[uuid.UUID]OrderDeposit{},
+		cursors:     map[string]uint64{},
+		vouchers:    map[string]Voucher{},
+		published:   map[uuid.UUID]bool{},
+		keys:        map[string]*Key{},
+		merchants:   map[string]*Merchant{},
+		settings:    map[string]MerchantSettings{},
+		encryption:  map[string]MerchantEncryptionKey{},
+		signingKeys: map[uuid.UUID]*MerchantSigningKey{},
+		idempotent:  map[string]middleware.IdempotentRequest{},
+		trials:      map[uuid.UUID]fakeOrderTrial{},
+		receipts:    map[uuid.UUID]OrderReceipt{},
+	}
+}
+
+// call counts a call of the method, returning the error it fails with
+func (ds *fakeDatastore) call(method string) error {
+	ds.calls[method]++
+	return ds.errs[method]
+}
+
+// addOrder stores the order, returning it as stored so tests can follow its status
+func (ds *fakeDatastore) addOrder(order Order) *Order {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if uuid.Equal(order.ID, uuid.Nil) {
+		order.ID = uuid.NewV4()
+	}
+	if order.Status == "" {
+		order.Status = "pending"
+	}
+	ds.orders[order.ID] = &order
+	return &order
+}
+
+// addEvent appends the event to the order's log and its outbox
+func (ds *fakeDatastore) addEvent(event OrderLogEvent) OrderLogEvent {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.appendEvent(event)
+}
+
+func (ds *fakeDatastore) appendEvent(event OrderLogEvent) OrderLogEvent {
+	if uuid.Equal(event.ID, uuid.Nil) {
+		event.ID = uuid.NewV4()
+	}
+	if event.Sequence == 0 {
+		for _, e := range ds.events {
+			if uuid.Equal(e.OrderID, event.OrderID) && e.Sequence > event.Sequence {
+				event.Sequence = e.Sequence
+			}
+		}
+		event.Sequence++
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	ds.events = append(ds.events, event)
+	return event
+}
+
+// signCreds sets signed credentials on the item, as the signing job does
+func (ds *fakeDatastore) signCreds(itemID uuid.UUID, signed ...string) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for i := range ds.creds {
+		if uuid.Equal(ds.creds[i].ID, itemID) {
+			signedCreds := append(ds.creds[i].BlindedCreds[:0:0], signed...)
+			ds.creds[i].SignedCreds = &signedCreds
+		}
+	}
+}
+
+// redemptions is the sum of the redemptions recorded for the merchant
+func (ds *fakeDatastore) redemptions(merchantID string) int64 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var redemptions int64
+	for _, usage := range ds.usage {
+		if usage.MerchantID == merchantID {
+			redemptions += usage.Redemptions
+		}
+	}
+	return redemptions
+}
+
+func (ds *fakeDatastore) RawDB() *sqlx.DB {
+	return ds.db
+}
+
+func (ds *fakeDatastore) NewMigrate() (*migrate.Migrate, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeDatastore) Migrate(ctx context.Context, targetVersion uint) error {
+	return errNotFaked
+}
+
+func (ds *fakeDatastore) MigrationStatus(ctx context.Context) (*grantserver.MigrationStatus, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeDatastore) RollbackTxAndHandle(tx *sqlx.Tx) error {
+	return nil
+}
+
+func (ds *fakeDatastore) RollbackTx(tx *sqlx.Tx) {}
+
+func (ds *fakeDatastore) CreateOrder(ctx context.Context, totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, rate *ExchangeRate, orderItems []OrderItem, voucher *VoucherRedemption) (*Order, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateOrder"); err != nil {
+		return nil, err
+	}
+	order := ds.insertOrder(NewOrder{MerchantID: merchantID, TotalPrice: totalPrice, Currency: currency,
+		Location: location, Status: status, Rate: rate, Items: orderItems})
+	order.Voucher = voucher
+	return &order, nil
+}
+
+func (ds *fakeDatastore) insertOrder(newOrder NewOrder) Order {
+	now := time.Now()
+	order := Order{
+		ID:         uuid.NewV4(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Currency:   newOrder.Currency,
+		TotalPrice: newOrder.TotalPrice,
+		MerchantID: newOrder.MerchantID,
+		Status:     newOrder.Status,
+		Items:      make([]OrderItem, len(newOrder.Items)),
+	}
+	order.Location.String, order.Location.Valid = newOrder.Location, newOrder.Location != ""
+	if newOrder.Rate != nil {
+		batTotalPrice := newOrder.TotalPrice.Div(newOrder.Rate.Rate)
+		order.ExchangeRate, order.BATTotalPrice, order.RatedAt = &newOrder.Rate.Rate, &batTotalPrice, &newOrder.Rate.UpdatedAt
+	}
+	for i, item := range newOrder.Items {
+		item.ID, item.OrderID = uuid.NewV4(), order.ID
+		order.Items[i] = item
+	}
+	stored := order
+	ds.orders[order.ID] = &stored
+	ds.appendEvent(OrderLogEvent{OrderID: order.ID, Type: OrderLogCreated})
+	return order
+}
+
+func (ds *fakeDatastore) CreateOrders(ctx context.Context, newOrders []NewOrder) ([]Order, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateOrders"); err != nil {
+		return nil, err
+	}
+	ds.created = append(ds.created, newOrders)
+	orders := make([]Order, len(newOrders))
+	for i, newOrder := range newOrders {
+		orders[i] = ds.insertOrder(newOrder)
+	}
+	return orders, nil
+}
+
+func (ds *fakeDatastore) GetOrder(orderID uuid.UUID) (*Order, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrder"); err != nil {
+		return nil, err
+	}
+	order, ok := ds.orders[orderID]
+	if !ok {
+		return nil, nil
+	}
+	found := *order
+	return &found, nil
+}
+
+func (ds *fakeDatastore) ListOrders(ctx context.Context, filter OrderFilter) ([]Order, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ListOrders"); err != nil {
+		return nil, err
+	}
+	sortTime := func(order Order) time.Time {
+		if strings.TrimPrefix(filter.Sort, "-") == "updatedAt" {
+			return order.UpdatedAt
+		}
+		return order.CreatedAt
+	}
+	descending := strings.HasPrefix(filter.Sort, "-")
+	before := func(a, b Order) bool {
+		ta, tb := sortTime(a), sortTime(b)
+		if !ta.Equal(tb) {
+			return ta.Before(tb) != descending
+		}
+		if uuid.Equal(a.ID, b.ID) {
+			return false
+		}
+		return (a.ID.String() < b.ID.String()) != descending
+	}
+
+	orders := []Order{}
+	for _, order := range ds.orders {
+		if order.MerchantID != filter.MerchantID ||
+			(len(filter.Statuses) > 0 && !containsString(filter.Statuses, order.Status)) ||
+			(filter.CreatedAfter != nil && !order.CreatedAt.After(*filter.CreatedAfter)) ||
+			(filter.CreatedBefore != nil && !order.CreatedAt.Before(*filter.CreatedBefore)) ||
+			(filter.Location != "" && order.Location.String != filter.Location) ||
+			(filter.After != nil && !before(Order{ID: filter.After.ID, CreatedAt: filter.After.Time,
+				UpdatedAt: filter.After.Time}, *order)) {
+			continue
+		}
+		orders = append(orders, *order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return before(orders[i], orders[j]) })
+	if len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+	}
+	return orders, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (ds *fakeDatastore) UpdateOrder(ctx context.Context, orderID uuid.UUID, status string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("UpdateOrder"); err != nil {
+		return err
+	}
+	order, ok := ds.orders[orderID]
+	if !ok {
+		return errors.New("no rows updated")
+	}
+	previous := order.Status
+	switch {
+	case previous == status:
+		return nil
+	case status == OrderLogExpired && previous != "pending":
+		return errOrderNotStale
+	case previous == OrderLogCanceled:
+		return ErrOrderCanceled
+	case previous == OrderLogRefunded:
+		return ErrOrderRefunded
+	case status == OrderLogCanceled && previous != "pending":
+		return ErrOrderNotCancelable
+	case status == OrderLogRefunded && previous != "paid":
+		return ErrOrderNotRefundable
+	case previous == OrderLogExpired && status != "pending":
+		return ErrOrderExpired
+	case status == "pending" && previous != OrderLogExpired:
+		return ErrOrderNotExpired
+	}
+	if status == OrderLogRefunded {
+		paid := map[string]decimal.Decimal{}
+		currencies := []string{}
+		for _, payment := range ds.payments {
+			if uuid.Equal(payment.OrderID, orderID) {
+				if _, ok := paid[payment.Currency]; !ok {
+					currencies = append(currencies, payment.Currency)
+				}
+				paid[payment.Currency] = paid[payment.Currency].Add(payment.Amount)
+			}
+		}
+		for _, currency := range currencies {
+			if !paid[currency].IsZero() {
+				ds.payments = append(ds.payments, OrderPayment{ID: uuid.NewV4(), OrderID: orderID, Kind: "refund",
+					Currency: currency, Amount: paid[currency].Neg(), CreatedAt: time.Now()})
+			}
+		}
+	}
+	order.Status, order.UpdatedAt = status, time.Now()
+	ds.appendEvent(OrderLogEvent{OrderID: orderID, Type: orderStatusEvent(status)})
+	return nil
+}
+
+func (ds *fakeDatastore) GetStaleOrders(ctx context.Context, defaultExpiry time.Duration, limit int) ([]uuid.UUID, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetStaleOrders"); err != nil {
+		return nil, err
+	}
+	stale := []Order{}
+	for _, order := range ds.orders {
+		expiry := defaultExpiry
+		if settings, ok := ds.settings[order.MerchantID]; ok && settings.OrderExpiryMinutes != nil {
+			expiry = time.Duration(*settings.OrderExpiryMinutes) * time.Minute
+		}
+		if order.Status == "pending" && order.TotalPrice.IsPositive() && expiry > 0 &&
+			order.UpdatedAt.Before(time.Now().Add(-expiry)) {
+			stale = append(stale, *order)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].UpdatedAt.Before(stale[j].UpdatedAt) })
+	ids := []uuid.UUID{}
+	for i := 0; i < len(stale) && i < limit; i++ {
+		ids = append(ids, stale[i].ID)
+	}
+	return ids, nil
+}
+
+func (ds *fakeDatastore) CreateTransaction(orderID uuid.UUID, externalTransactionID string, status string, currency string, kind string, amount decimal.Decimal) (*Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateTransaction"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	transaction := Transaction{
+		ID:                    uuid.NewV4(),
+		OrderID:               orderID,
+		CreatedAt:             now,
+		UpdatedAt:             now,
+		ExternalTransactionID: externalTransactionID,
+		Status:                status,
+		Currency:              currency,
+		Kind:                  kind,
+		Amount:                amount,
+	}
+	ds.transactions = append(ds.transactions, transaction)
+	ds.recordPayment(transaction)
+	return &transaction, nil
+}
+
+// recordPayment adds a completed transaction to its order's payments
+func (ds *fakeDatastore) recordPayment(transaction Transaction) {
+	if transaction.Status != TransferCompleted {
+		return
+	}
+	ds.payments = append(ds.payments, OrderPayment{
+		ID:            uuid.NewV4(),
+		OrderID:       transaction.OrderID,
+		TransactionID: &transaction.ID,
+		Kind:          transaction.Kind,
+		Currency:      transaction.Currency,
+		Amount:        transaction.Amount,
+		CreatedAt:     transaction.UpdatedAt,
+	})
+}
+
+func (ds *fakeDatastore) InsertCheckoutSession(ctx context.Context, session *CheckoutSession) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertCheckoutSession"); err != nil {
+		return err
+	}
+	if _, ok := ds.sessions[session.OrderID]; !ok {
+		session.CreatedAt = time.Now()
+		ds.sessions[session.OrderID] = *session
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) GetCheckoutSession(ctx context.Context, orderID uuid.UUID) (*CheckoutSession, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetCheckoutSession"); err != nil {
+		return nil, err
+	}
+	session, ok := ds.sessions[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (ds *fakeDatastore) GetTransaction(externalTransactionID string) (*Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetTransaction"); err != nil {
+		return nil, err
+	}
+	for _, transaction := range ds.transactions {
+		if transaction.ExternalTransactionID == externalTransactionID {
+			return &transaction, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) GetTransactions(orderID uuid.UUID) (*[]Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetTransactions"); err != nil {
+		return nil, err
+	}
+	transactions := []Transaction{}
+	for _, transaction := range ds.transactions {
+		if uuid.Equal(transaction.OrderID, orderID) {
+			transactions = append(transactions, transaction)
+		}
+	}
+	return &transactions, nil
+}
+
+func (ds *fakeDatastore) GetPagedMerchantTransactions(ctx context.Context, merchantID uuid.UUID, pagination *inputs.Pagination) (*[]Transaction, int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetPagedMerchantTransactions"); err != nil {
+		return nil, 0, err
+	}
+	transactions := []Transaction{}
+	for _, transaction := range ds.transactions {
+		if order, ok := ds.orders[transaction.OrderID]; ok && order.MerchantID == merchantID.String() {
+			transactions = append(transactions, transaction)
+		}
+	}
+	total := len(transactions)
+	if start := pagination.Page * pagination.Items; start < len(transactions) {
+		transactions = transactions[start:]
+	} else {
+		transactions = []Transaction{}
+	}
+	if len(transactions) > pagination.Items {
+		transactions = transactions[:pagination.Items]
+	}
+	return &transactions, total, nil
+}
+
+func (ds *fakeDatastore) GetTransactionsUpdatedBetween(ctx context.Context, from, to time.Time) ([]ExportedTransaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetTransactionsUpdatedBetween"); err != nil {
+		return nil, err
+	}
+	transactions := []ExportedTransaction{}
+	for _, transaction := range ds.transactions {
+		order, ok := ds.orders[transaction.OrderID]
+		if ok && !transaction.UpdatedAt.Before(from) && transaction.UpdatedAt.Before(to) {
+			transactions = append(transactions, ExportedTransaction{Transaction: transaction, MerchantID: order.MerchantID})
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].UpdatedAt.Before(transactions[j].UpdatedAt)
+	})
+	return transactions, nil
+}
+
+func (ds *fakeDatastore) GetPendingTransactions(ctx context.Context, kinds []string, limit int) ([]Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetPendingTransactions"); err != nil {
+		return nil, err
+	}
+	pending := []Transaction{}
+	for _, transaction := range ds.transactions {
+		if transaction.Status == TransferPending && containsString(kinds, transaction.Kind) && len(pending) < limit {
+			pending = append(pending, transaction)
+		}
+	}
+	return pending, nil
+}
+
+func (ds *fakeDatastore) SettleTransaction(ctx context.Context, transactionID uuid.UUID, status string) (*Transaction, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("SettleTransaction"); err != nil {
+		return nil, err
+	}
+	for i := range ds.transactions {
+		transaction := &ds.transactions[i]
+		if uuid.Equal(transaction.ID, transactionID) && transaction.Status == TransferPending {
+			transaction.Status, transaction.UpdatedAt = status, time.Now()
+			ds.recordPayment(*transaction)
+			settled := *transaction
+			return &settled, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) InsertOrderDeposit(ctx context.Context, deposit *OrderDeposit) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertOrderDeposit"); err != nil {
+		return err
+	}
+	if _, ok := ds.deposits[deposit.OrderID]; !ok {
+		now := time.Now()
+		ds.deposits[deposit.OrderID] = OrderDeposit{OrderID: deposit.OrderID, Address: deposit.Address,
+			Status: DepositAwaiting, CreatedAt: now, UpdatedAt: now}
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) GetOrderDeposit(ctx context.Context, orderID uuid.UUID) (*OrderDeposit, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderDeposit"); err != nil {
+		return nil, err
+	}
+	deposit, ok := ds.deposits[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &deposit, nil
+}
+
+func (ds *fakeDatastore) GetOrderDepositsByAddress(ctx context.Context, addresses []string) ([]OrderDeposit, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderDepositsByAddress"); err != nil {
+		return nil, err
+	}
+	deposits := []OrderDeposit{}
+	for _, deposit := range ds.deposits {
+		if containsString(addresses, deposit.Address) {
+			deposits = append(deposits, deposit)
+		}
+	}
+	return deposits, nil
+}
+
+func (ds *fakeDatastore) UpdateOrderDeposit(ctx context.Context, orderID uuid.UUID, status string, received, excess decimal.Decimal) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("UpdateOrderDeposit"); err != nil {
+		return err
+	}
+	deposit, ok := ds.deposits[orderID]
+	if !ok {
+		return nil
+	}
+	deposit.Status, deposit.Received, deposit.Excess, deposit.UpdatedAt = status, received, excess, time.Now()
+	ds.deposits[orderID] = deposit
+	return nil
+}
+
+func (ds *fakeDatastore) GetChainCursor(ctx context.Context, chain string) (uint64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetChainCursor"); err != nil {
+		return 0, err
+	}
+	return ds.cursors[chain], nil
+}
+
+func (ds *fakeDatastore) AdvanceChainCursor(ctx context.Context, chain string, from, to uint64) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("AdvanceChainCursor"); err != nil {
+		return false, err
+	}
+	if ds.cursors[chain] != from {
+		return false, nil
+	}
+	ds.cursors[chain] = to
+	return true, nil
+}
+
+func (ds *fakeDatastore) CreateVoucher(ctx context.Context, voucher *Voucher) (*Voucher, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateVoucher"); err != nil {
+		return nil, err
+	}
+	if _, ok := ds.vouchers[voucher.Code]; ok {
+		return nil, ErrVoucherExists
+	}
+	created := *voucher
+	created.ID, created.Uses, created.CreatedAt = uuid.NewV4(), 0, time.Now()
+	ds.vouchers[created.Code] = created
+	return &created, nil
+}
+
+func (ds *fakeDatastore) GetVoucher(ctx context.Context, code string) (*Voucher, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetVoucher"); err != nil {
+		return nil, err
+	}
+	voucher, ok := ds.vouchers[code]
+	if !ok {
+		return nil, nil
+	}
+	return &voucher, nil
+}
+
+func (ds *fakeDatastore) ListVouchers(ctx context.Context, merchantID string, limit int) ([]Voucher, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ListVouchers"); err != nil {
+		return nil, err
+	}
+	vouchers := []Voucher{}
+	for _, voucher := range ds.vouchers {
+		if voucher.MerchantID == merchantID {
+			vouchers = append(vouchers, voucher)
+		}
+	}
+	sort.Slice(vouchers, func(i, j int) bool { return vouchers[i].CreatedAt.After(vouchers[j].CreatedAt) })
+	if len(vouchers) > limit {
+		vouchers = vouchers[:limit]
+	}
+	return vouchers, nil
+}
+
+func (ds *fakeDatastore) InsertIssuer(issuer *Issuer) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertIssuer"); err != nil {
+		return nil, err
+	}
+	return ds.insertIssuer(issuer), nil
+}
+
+func (ds *fakeDatastore) insertIssuer(issuer *Issuer) *Issuer {
+	issuer.ID, issuer.CreatedAt = uuid.NewV4(), time.Now()
+	if issuer.ValidFrom.IsZero() {
+		issuer.ValidFrom = issuer.CreatedAt
+	}
+	ds.issuers = append(ds.issuers, *issuer)
+	return issuer
+}
+
+func (ds *fakeDatastore) GetIssuer(merchantID string) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetIssuer"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var current *Issuer
+	for i := range ds.issuers {
+		issuer := ds.issuers[i]
+		if issuer.MerchantID == merchantID && !issuer.ValidFrom.After(now) &&
+			(issuer.ValidTo == nil || issuer.ValidTo.After(now)) && issuer.DisabledAt == nil &&
+			(current == nil || issuer.Version >= current.Version) {
+			current = &issuer
+		}
+	}
+	if current == nil {
+		return nil, sql.ErrNoRows
+	}
+	return current, nil
+}
+
+func (ds *fakeDatastore) GetIssuers(ctx context.Context, merchantID string) ([]Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetIssuers"); err != nil {
+		return nil, err
+	}
+	issuers := []Issuer{}
+	for i := len(ds.issuers) - 1; i >= 0; i-- {
+		if ds.issuers[i].MerchantID == merchantID {
+			issuers = append(issuers, ds.issuers[i])
+		}
+	}
+	sort.SliceStable(issuers, func(i, j int) bool { return issuers[i].Version > issuers[j].Version })
+	return issuers, nil
+}
+
+func (ds *fakeDatastore) RotateIssuer(ctx context.Context, issuer *Issuer) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("RotateIssuer"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range ds.issuers {
+		if ds.issuers[i].MerchantID == issuer.MerchantID && ds.issuers[i].ValidTo == nil {
+			ds.issuers[i].ValidTo = &now
+		}
+	}
+	return ds.insertIssuer(issuer), nil
+}
+
+func (ds *fakeDatastore) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetIssuerByPublicKey"); err != nil {
+		return nil, err
+	}
+	for _, issuer := range ds.issuers {
+		if issuer.PublicKey == publicKey {
+			return &issuer, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) GetIssuerByID(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetIssuerByID"); err != nil {
+		return nil, err
+	}
+	for _, issuer := range ds.issuers {
+		if uuid.Equal(issuer.ID, id) {
+			return &issuer, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) ListIssuers(ctx context.Context, merchantID string, limit int) ([]IssuerUsage, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ListIssuers"); err != nil {
+		return nil, err
+	}
+	issuers := []IssuerUsage{}
+	for i := len(ds.issuers) - 1; i >= 0 && len(issuers) < limit; i-- {
+		issuer := ds.issuers[i]
+		if merchantID != "" && issuerMerchant(issuer.MerchantID) != merchantID {
+			continue
+		}
+		usage := IssuerUsage{Issuer: issuer}
+		for _, creds := range append(append([]OrderCreds{}, ds.creds...), ds.windows...) {
+			if uuid.Equal(creds.IssuerID, issuer.ID) && creds.SignedCreds != nil {
+				usage.TokensSigned += len(*creds.SignedCreds)
+			}
+		}
+		issuers = append(issuers, usage)
+	}
+	return issuers, nil
+}
+
+func (ds *fakeDatastore) DisableIssuer(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("DisableIssuer"); err != nil {
+		return nil, err
+	}
+	for i := range ds.issuers {
+		if uuid.Equal(ds.issuers[i].ID, id) {
+			if ds.issuers[i].DisabledAt == nil {
+				now := time.Now()
+				ds.issuers[i].DisabledAt = &now
+			}
+			disabled := ds.issuers[i]
+			return &disabled, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) InsertOrderCreds(ctx context.Context, creds *OrderCreds) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertOrderCreds"); err != nil {
+		return err
+	}
+	for i := range ds.creds {
+		if uuid.Equal(ds.creds[i].ID, creds.ID) {
+			ds.creds[i] = *creds
+			return nil
+		}
+	}
+	ds.creds = append(ds.creds, *creds)
+	ds.appendEvent(OrderLogEvent{OrderID: creds.OrderID, Type: OrderLogCredsRequested})
+	return nil
+}
+
+func (ds *fakeDatastore) GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCreds, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderCreds"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	orderCreds := []OrderCreds{}
+	for _, creds := range ds.creds {
+		if !uuid.Equal(creds.OrderID, orderID) {
+			continue
+		}
+		// time-limited credentials are returned by window, those of windows which ended are left out
+		windows := []OrderCreds{}
+		windowed := false
+		for _, window := range ds.windows {
+			if uuid.Equal(window.ID, creds.ID) {
+				windowed = true
+				if window.ValidTo.After(now) {
+					window.BlindedCreds = creds.BlindedCreds
+					windows = append(windows, window)
+				}
+			}
+		}
+		if !windowed {
+			windows = append(windows, creds)
+		}
+		for _, window := range windows {
+			if !isSigned || window.SignedCreds != nil {
+				orderCreds = append(orderCreds, window)
+			}
+		}
+	}
+	return &orderCreds, nil
+}
+
+func (ds *fakeDatastore) DeleteOrderCreds(ctx context.Context, orderID uuid.UUID) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("DeleteOrderCreds"); err != nil {
+		return err
+	}
+	kept := ds.creds[:0]
+	for _, creds := range ds.creds {
+		if !uuid.Equal(creds.OrderID, orderID) {
+			kept = append(kept, creds)
+		}
+	}
+	ds.creds = kept
+	return nil
+}
+
+func (ds *fakeDatastore) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (*OrderCreds, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderCredsByItemID"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, creds := range ds.creds {
+		if !uuid.Equal(creds.OrderID, orderID) || !uuid.Equal(creds.ID, itemID) {
+			continue
+		}
+		// time-limited credentials are those of the window which started last
+		var latest *OrderCreds
+		for i := range ds.windows {
+			window := ds.windows[i]
+			if uuid.Equal(window.ID, itemID) && !window.ValidFrom.After(now) &&
+				(latest == nil || window.ValidFrom.After(*latest.ValidFrom)) {
+				latest = &window
+			}
+		}
+		if latest != nil {
+			latest.BlindedCreds = creds.BlindedCreds
+			creds = *latest
+		}
+		if isSigned && creds.SignedCreds == nil {
+			return nil, nil
+		}
+		return &creds, nil
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) RunNextOrderJob(ctx context.Context, worker OrderWorker) (bool, error) {
+	return false, errNotFaked
+}
+
+func (ds *fakeDatastore) GetExpiringCredentialWindows(ctx context.Context, endingBefore time.Time, limit int) ([]CredentialWindow, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetExpiringCredentialWindows"); err != nil {
+		return nil, err
+	}
+	latest := map[uuid.UUID]OrderCreds{}
+	for _, window := range ds.windows {
+		if order, ok := ds.orders[window.OrderID]; !ok || order.Status != "paid" {
+			continue
+		}
+		if last, ok := latest[window.ID]; !ok || window.ValidFrom.After(*last.ValidFrom) {
+			latest[window.ID] = window
+		}
+	}
+	expiring := []CredentialWindow{}
+	for _, window := range latest {
+		if !window.ValidTo.Before(endingBefore) {
+			continue
+		}
+		for _, item := range ds.orders[window.OrderID].Items {
+			if uuid.Equal(item.ID, window.ID) {
+				expiring = append(expiring, CredentialWindow{OrderCreds: window,
+					MerchantID: ds.orders[window.OrderID].MerchantID, SKU: item.SKU})
+			}
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool { return expiring[i].ValidTo.Before(*expiring[j].ValidTo) })
+	if len(expiring) > limit {
+		expiring = expiring[:limit]
+	}
+	return expiring, nil
+}
+
+func (ds *fakeDatastore) InsertCredentialWindow(ctx context.Context, creds *OrderCreds) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertCredentialWindow"); err != nil {
+		return err
+	}
+	for _, window := range ds.windows {
+		if uuid.Equal(window.ID, creds.ID) && window.ValidFrom.Equal(*creds.ValidFrom) {
+			return nil
+		}
+	}
+	ds.windows = append(ds.windows, *creds)
+	return nil
+}
+
+func (ds *fakeDatastore) RunNextCredentialWindow(ctx context.Context, worker OrderWorker) (bool, error) {
+	return false, errNotFaked
+}
+
+func (ds *fakeDatastore) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]OrderLogEvent, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderEvents"); err != nil {
+		return nil, err
+	}
+	events := []OrderLogEvent{}
+	for _, event := range ds.events {
+		if uuid.Equal(event.OrderID, orderID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (ds *fakeDatastore) GetOrderHistory(ctx context.Context, orderID uuid.UUID) ([]OrderHistoryEntry, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeDatastore) GetOrderPayments(ctx context.Context, orderID uuid.UUID) ([]OrderPayment, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderPayments"); err != nil {
+		return nil, err
+	}
+	payments := []OrderPayment{}
+	for _, payment := range ds.payments {
+		if uuid.Equal(payment.OrderID, orderID) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (ds *fakeDatastore) DispatchOrderEvents(ctx context.Context, limit int, dispatch func(context.Context, OrderLogEvent) error) (int, error) {
+	return ds.drainEvents(ctx, "DispatchOrderEvents", limit, dispatch, func(event *OrderLogEvent, handled bool) bool {
+		if handled {
+			now := time.Now()
+			event.DispatchedAt = &now
+		}
+		return event.DispatchedAt == nil
+	})
+}
+
+func (ds *fakeDatastore) PublishOrderEvents(ctx context.Context, limit int, publish func(context.Context, OrderLogEvent) error) (int, error) {
+	return ds.drainEvents(ctx, "PublishOrderEvents", limit, publish, func(event *OrderLogEvent, handled bool) bool {
+		// only the events with a topic are in the outbox
+		if _, ok := orderLogTopics[event.Type]; !ok {
+			return false
+		}
+		if handled {
+			ds.published[event.ID] = true
+		}
+		return !ds.published[event.ID]
+	})
+}
+
+// drainEvents passes the pending events to handle, in order, until it fails. pending tells whether an event
+// is pending, marking it handled first when asked to. The datastore is unlocked while events are handled
+func (ds *fakeDatastore) drainEvents(ctx context.Context, method string, limit int, handle func(context.Context, OrderLogEvent) error, pending func(event *OrderLogEvent, handled bool) bool) (int, error) {
+	ds.mu.Lock()
+	if err := ds.call(method); err != nil {
+		ds.mu.Unlock()
+		return 0, err
+	}
+	events := []OrderLogEvent{}
+	for i := range ds.events {
+		if pending(&ds.events[i], false) && len(events) < limit {
+			events = append(events, ds.events[i])
+		}
+	}
+	ds.mu.Unlock()
+
+	handled := 0
+	for _, event := range events {
+		if err := handle(ctx, event); err != nil {
+			return handled, fmt.Errorf("failed to handle event %d of order %s: %w", event.Sequence, event.OrderID, err)
+		}
+		ds.mu.Lock()
+		for i := range ds.events {
+			if uuid.Equal(ds.events[i].ID, event.ID) {
+				pending(&ds.events[i], true)
+			}
+		}
+		ds.mu.Unlock()
+		handled++
+	}
+	return handled, nil
+}
+
+func (ds *fakeDatastore) GetStuckOrders(ctx context.Context, requestedBefore time.Time, limit int) ([]StuckOrder, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeDatastore) RebuildOrderProjection(ctx context.Context, projection *OrderProjection) error {
+	return errNotFaked
+}
+
+func (ds *fakeDatastore) GetKeys(merchant string, showExpired bool) (*[]Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetKeys"); err != nil {
+		return nil, err
+	}
+	keys := []Key{}
+	for _, key := range ds.keys {
+		if key.Merchant == merchant && (showExpired || key.Expiry == nil || key.Expiry.After(time.Now())) {
+			found := *key
+			found.EncryptedSecretKey, found.Nonce, found.TokenHash = "", "", nil
+			keys = append(keys, found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return &keys, nil
+}
+
+func (ds *fakeDatastore) CreateKey(merchant string, name string, encryptedSecretKey string, nonce string, scopes []string, tokenHash string) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateKey"); err != nil {
+		return nil, err
+	}
+	key := Key{
+		ID:                 uuid.NewV4().String(),
+		Name:               name,
+		Merchant:           merchant,
+		EncryptedSecretKey: encryptedSecretKey,
+		Nonce:              nonce,
+		CreatedAt:          time.Now(),
+		Scopes:             scopes,
+	}
+	if tokenHash != "" {
+		key.TokenHash = &tokenHash
+	}
+	stored := key
+	ds.keys[key.ID] = &stored
+	if err := key.SetSecretKey(); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (ds *fakeDatastore) DeleteKey(id uuid.UUID, delaySeconds int) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("DeleteKey"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.keys[id.String()]
+	if !ok {
+		return nil, nil
+	}
+	expiry := time.Now().Add(time.Duration(delaySeconds) * time.Second)
+	key.Expiry = &expiry
+	return &Key{ID: key.ID, Name: key.Name, Merchant: key.Merchant, CreatedAt: key.CreatedAt, Expiry: key.Expiry}, nil
+}
+
+// getKey is the key unless it expired
+func (ds *fakeDatastore) getKey(id string) *Key {
+	key, ok := ds.keys[id]
+	if !ok || (key.Expiry != nil && !key.Expiry.After(time.Now())) {
+		return nil
+	}
+	found := *key
+	return &found
+}
+
+func (ds *fakeDatastore) GetKey(id uuid.UUID) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetKey"); err != nil {
+		return nil, err
+	}
+	key := ds.getKey(id.String())
+	if key != nil {
+		key.EncryptedSecretKey, key.Nonce = "", ""
+	}
+	return key, nil
+}
+
+func (ds *fakeDatastore) GetKeyByTokenHash(tokenHash string) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetKeyByTokenHash"); err != nil {
+		return nil, err
+	}
+	for id, key := range ds.keys {
+		if key.TokenHash != nil && *key.TokenHash == tokenHash {
+			return ds.getKey(id), nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeDatastore) GetKeyWithSecret(id uuid.UUID) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetKeyWithSecret"); err != nil {
+		return nil, err
+	}
+	return ds.getKey(id.String()), nil
+}
+
+func (ds *fakeDatastore) MarkKeyUsed(id uuid.UUID) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("MarkKeyUsed"); err != nil {
+		return err
+	}
+	if key, ok := ds.keys[id.String()]; ok {
+		now := time.Now()
+		key.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) UpdateKeyRateLimit(id uuid.UUID, perMinute *int, burst int, dailyQuota *int) (*Key, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("UpdateKeyRateLimit"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.keys[id.String()]
+	if !ok {
+		return nil, nil
+	}
+	key.RateLimitPerMinute, key.RateLimitBurst, key.DailyQuota = perMinute, burst, dailyQuota
+	updated := *key
+	updated.EncryptedSecretKey, updated.Nonce, updated.TokenHash = "", "", nil
+	return &updated, nil
+}
+
+func (ds *fakeDatastore) CreateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateMerchant"); err != nil {
+		return nil, err
+	}
+	if _, ok := ds.merchants[merchant.ID]; ok {
+		return nil, fmt.Errorf("failed to create merchant: merchant %s exists", merchant.ID)
+	}
+	created := *merchant
+	created.CreatedAt, created.UpdatedAt = time.Now(), time.Now()
+	stored := created
+	ds.merchants[merchant.ID] = &stored
+	return &created, nil
+}
+
+func (ds *fakeDatastore) GetMerchant(ctx context.Context, id string) (*Merchant, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchant"); err != nil {
+		return nil, err
+	}
+	merchant, ok := ds.merchants[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *merchant
+	return &found, nil
+}
+
+func (ds *fakeDatastore) GetMerchants(ctx context.Context) ([]Merchant, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchants"); err != nil {
+		return nil, err
+	}
+	merchants := []Merchant{}
+	for _, merchant := range ds.merchants {
+		merchants = append(merchants, *merchant)
+	}
+	sort.Slice(merchants, func(i, j int) bool { return merchants[i].ID < merchants[j].ID })
+	return merchants, nil
+}
+
+func (ds *fakeDatastore) UpdateMerchant(ctx context.Context, merchant *Merchant) (*Merchant, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("UpdateMerchant"); err != nil {
+		return nil, err
+	}
+	stored, ok := ds.merchants[merchant.ID]
+	if !ok {
+		return nil, nil
+	}
+	stored.Name, stored.AllowedSKUs, stored.WebhookURLs, stored.UpdatedAt =
+		merchant.Name, merchant.AllowedSKUs, merchant.WebhookURLs, time.Now()
+	updated := *stored
+	return &updated, nil
+}
+
+func (ds *fakeDatastore) GetMerchantSettings(ctx context.Context, merchantID string) (*MerchantSettings, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchantSettings"); err != nil {
+		return nil, err
+	}
+	settings, ok := ds.settings[merchantID]
+	if !ok {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+func (ds *fakeDatastore) UpsertMerchantSettings(ctx context.Context, settings *MerchantSettings) (*MerchantSettings, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("UpsertMerchantSettings"); err != nil {
+		return nil, err
+	}
+	upserted := *settings
+	upserted.UpdatedAt = time.Now()
+	ds.settings[settings.MerchantID] = upserted
+	return &upserted, nil
+}
+
+func (ds *fakeDatastore) DeleteMerchant(ctx context.Context, id string) (*Merchant, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("DeleteMerchant"); err != nil {
+		return nil, err
+	}
+	merchant, ok := ds.merchants[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(ds.merchants, id)
+	return merchant, nil
+}
+
+func (ds *fakeDatastore) GetMerchantEncryptionKey(merchantID string) (*MerchantEncryptionKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchantEncryptionKey"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.encryption[merchantID]
+	if !ok {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+func (ds *fakeDatastore) SetMerchantEncryptionKey(merchantID string, jwk string) (*MerchantEncryptionKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("SetMerchantEncryptionKey"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.encryption[merchantID]
+	if !ok {
+		key = MerchantEncryptionKey{Merchant: merchantID, CreatedAt: time.Now()}
+	}
+	key.JWK, key.UpdatedAt = jwk, time.Now()
+	ds.encryption[merchantID] = key
+	return &key, nil
+}
+
+func (ds *fakeDatastore) CreateWebhookSecret(ctx context.Context, merchantID string, encryptedSecret string, nonce string, overlap time.Duration) (*WebhookSecret, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateWebhookSecret"); err != nil {
+		return nil, err
+	}
+	// secrets already expiring sooner keep their expiry, so repeated rotations cannot extend them
+	now := time.Now()
+	expiresAt := now.Add(overlap)
+	version := 0
+	for i := range ds.secrets {
+		secret := &ds.secrets[i]
+		if secret.MerchantID != merchantID {
+			continue
+		}
+		if secret.Version > version {
+			version = secret.Version
+		}
+		if secret.ExpiresAt == nil || secret.ExpiresAt.After(expiresAt) {
+			secret.ExpiresAt = &expiresAt
+		}
+	}
+	secret := WebhookSecret{
+		ID:              uuid.NewV4(),
+		MerchantID:      merchantID,
+		Version:         version + 1,
+		EncryptedSecret: encryptedSecret,
+		Nonce:           nonce,
+		CreatedAt:       now,
+	}
+	ds.secrets = append(ds.secrets, secret)
+	return &secret, nil
+}
+
+func (ds *fakeDatastore) GetWebhookSecrets(ctx context.Context, merchantID string) ([]WebhookSecret, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetWebhookSecrets"); err != nil {
+		return nil, err
+	}
+	active := []WebhookSecret{}
+	for i := len(ds.secrets) - 1; i >= 0; i-- {
+		secret := ds.secrets[i]
+		if secret.MerchantID == merchantID && (secret.ExpiresAt == nil || secret.ExpiresAt.After(time.Now())) {
+			active = append(active, secret)
+		}
+	}
+	return active, nil
+}
+
+func (ds *fakeDatastore) InsertWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertWebhookDelivery"); err != nil {
+		return err
+	}
+	delivery.ID, delivery.CreatedAt = uuid.NewV4(), time.Now()
+	ds.deliveries = append(ds.deliveries, *delivery)
+	return nil
+}
+
+func (ds *fakeDatastore) GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) ([]WebhookDelivery, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetWebhookDeliveries"); err != nil {
+		return nil, err
+	}
+	deliveries := []WebhookDelivery{}
+	for i := len(ds.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if ds.deliveries[i].MerchantID == merchantID {
+			deliveries = append(deliveries, ds.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (ds *fakeDatastore) DeleteWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("DeleteWebhookDeliveries"); err != nil {
+		return 0, err
+	}
+	kept := ds.deliveries[:0]
+	for _, delivery := range ds.deliveries {
+		if !delivery.CreatedAt.Before(before) {
+			kept = append(kept, delivery)
+		}
+	}
+	deleted := int64(len(ds.deliveries) - len(kept))
+	ds.deliveries = kept
+	return deleted, nil
+}
+
+func (ds *fakeDatastore) GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) ([]RedeemedCredential, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetCredentialRedemptions"); err != nil {
+		return nil, err
+	}
+	redemptions := []RedeemedCredential{}
+	for _, redemption := range ds.redeemed {
+		if containsString(tokenPreimages, redemption.TokenPreimage) {
+			redemptions = append(redemptions, redemption)
+		}
+	}
+	return redemptions, nil
+}
+
+func (ds *fakeDatastore) InsertCredentialRedemptions(ctx context.Context, tx *sqlx.Tx, redemptions []RedeemedCredential) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertCredentialRedemptions"); err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		redeemed := false
+		for _, r := range ds.redeemed {
+			redeemed = redeemed || r.TokenPreimage == redemption.TokenPreimage
+		}
+		if !redeemed {
+			redemption.RedeemedAt = time.Now()
+			ds.redeemed = append(ds.redeemed, redemption)
+		}
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) RecordMerchantUsage(ctx context.Context, tx *sqlx.Tx, usage MerchantUsage) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("RecordMerchantUsage"); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range ds.usage {
+		if ds.usage[i].MerchantID == usage.MerchantID && ds.usage[i].Period.Equal(day) {
+			ds.usage[i].SigningCalls += usage.SigningCalls
+			ds.usage[i].TokensIssued += usage.TokensIssued
+			ds.usage[i].Redemptions += usage.Redemptions
+			return nil
+		}
+	}
+	usage.Period = day
+	ds.usage = append(ds.usage, usage)
+	return nil
+}
+
+func (ds *fakeDatastore) GetMerchantUsage(ctx context.Context, merchantID string, since time.Time) ([]MerchantUsage, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchantUsage"); err != nil {
+		return nil, err
+	}
+	since = since.UTC()
+	since = time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := map[time.Time]*MerchantUsage{}
+	usage := []MerchantUsage{}
+	for _, day := range ds.usage {
+		month := time.Date(day.Period.Year(), day.Period.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if day.MerchantID != merchantID || month.Before(since) {
+			continue
+		}
+		if _, ok := months[month]; !ok {
+			months[month] = &MerchantUsage{MerchantID: merchantID, Period: month}
+		}
+		months[month].SigningCalls += day.SigningCalls
+		months[month].TokensIssued += day.TokensIssued
+		months[month].Redemptions += day.Redemptions
+	}
+	for _, month := range months {
+		usage = append(usage, *month)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Period.Before(usage[j].Period) })
+	return usage, nil
+}
+
+func (ds *fakeDatastore) RollupMerchantUsage(ctx context.Context, before time.Time) (int64, error) {
+	return 0, errNotFaked
+}
+
+func (ds *fakeDatastore) CreateMerchantSigningKey(ctx context.Context, key *MerchantSigningKey) (*MerchantSigningKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CreateMerchantSigningKey"); err != nil {
+		return nil, err
+	}
+	created := *key
+	created.ID, created.CreatedAt, created.RevokedAt = uuid.NewV4(), time.Now(), nil
+	stored := created
+	ds.signingKeys[created.ID] = &stored
+	return &created, nil
+}
+
+func (ds *fakeDatastore) GetMerchantSigningKey(ctx context.Context, id uuid.UUID) (*MerchantSigningKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchantSigningKey"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.signingKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return nil, nil
+	}
+	found := *key
+	return &found, nil
+}
+
+func (ds *fakeDatastore) GetMerchantSigningKeys(ctx context.Context, merchantID string) ([]MerchantSigningKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetMerchantSigningKeys"); err != nil {
+		return nil, err
+	}
+	keys := []MerchantSigningKey{}
+	for _, key := range ds.signingKeys {
+		if key.MerchantID == merchantID {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (ds *fakeDatastore) RevokeMerchantSigningKey(ctx context.Context, merchantID string, id uuid.UUID) (*MerchantSigningKey, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("RevokeMerchantSigningKey"); err != nil {
+		return nil, err
+	}
+	key, ok := ds.signingKeys[id]
+	if !ok || key.MerchantID != merchantID || key.RevokedAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	revoked := *key
+	return &revoked, nil
+}
+
+func (ds *fakeDatastore) InsertAuditEvent(ctx context.Context, event *middleware.AuditEvent) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertAuditEvent"); err != nil {
+		return err
+	}
+	event.ID, event.CreatedAt = uuid.NewV4().String(), time.Now()
+	ds.audit = append(ds.audit, *event)
+	return nil
+}
+
+func (ds *fakeDatastore) GetAuditEvents(ctx context.Context, actor string, since time.Time, limit int) ([]middleware.AuditEvent, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetAuditEvents"); err != nil {
+		return nil, err
+	}
+	events := []middleware.AuditEvent{}
+	for i := len(ds.audit) - 1; i >= 0 && len(events) < limit; i-- {
+		event := ds.audit[i]
+		if event.CreatedAt.After(since) && (actor == "" || event.Actor == actor) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (ds *fakeDatastore) ReserveIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest, ttl time.Duration) (*middleware.IdempotentRequest, bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ReserveIdempotencyKey"); err != nil {
+		return nil, false, err
+	}
+	scoped := request.Scope + "\x00" + request.Key
+	if existing, ok := ds.idempotent[scoped]; ok && existing.CreatedAt.After(time.Now().Add(-ttl)) {
+		return &existing, false, nil
+	}
+	request.CreatedAt = time.Now()
+	ds.idempotent[scoped] = middleware.IdempotentRequest{Scope: request.Scope, Key: request.Key,
+		RequestHash: request.RequestHash, CreatedAt: request.CreatedAt}
+	return request, true, nil
+}
+
+func (ds *fakeDatastore) CompleteIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("CompleteIdempotencyKey"); err != nil {
+		return err
+	}
+	scoped := request.Scope + "\x00" + request.Key
+	if existing, ok := ds.idempotent[scoped]; ok && existing.RequestHash == request.RequestHash {
+		existing.Status, existing.Header, existing.Body = request.Status, request.Header, request.Body
+		ds.idempotent[scoped] = existing
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) ReleaseIdempotencyKey(ctx context.Context, scope string, key string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ReleaseIdempotencyKey"); err != nil {
+		return err
+	}
+	scoped := scope + "\x00" + key
+	if existing, ok := ds.idempotent[scoped]; ok && existing.Status == nil {
+		delete(ds.idempotent, scoped)
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) GetUncommittedVotesForUpdate(ctx context.Context) (*sqlx.Tx, []*VoteRecord, error) {
+	return nil, nil, errNotFaked
+}
+
+func (ds *fakeDatastore) CommitVote(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) error {
+	return errNotFaked
+}
+
+func (ds *fakeDatastore) MarkVoteErrored(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) error {
+	return errNotFaked
+}
+
+func (ds *fakeDatastore) InsertVote(ctx context.Context, vr VoteRecord) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertVote"); err != nil {
+		return err
+	}
+	ds.votes = append(ds.votes, ExportedVote{ID: uuid.NewV4(), CreatedAt: time.Now(), VoteText: vr.VoteText,
+		RequestID: vr.RequestID})
+	return nil
+}
+
+func (ds *fakeDatastore) GetVotesCreatedBetween(ctx context.Context, from, to time.Time) ([]ExportedVote, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetVotesCreatedBetween"); err != nil {
+		return nil, err
+	}
+	votes := []ExportedVote{}
+	for _, vote := range ds.votes {
+		if !vote.CreatedAt.Before(from) && vote.CreatedAt.Before(to) {
+			votes = append(votes, vote)
+		}
+	}
+	return votes, nil
+}
+
+func (ds *fakeDatastore) RollupVoteTallies(ctx context.Context, limit int) (int64, int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("RollupVoteTallies"); err != nil {
+		return 0, 0, err
+	}
+	batch := ds.votes[ds.tallied:]
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	ds.tallied += len(batch)
+	channels := map[string]bool{}
+	for _, vote := range batch {
+		channels[vote.VoteText] = true
+	}
+	return int64(len(batch)), int64(len(channels)), nil
+}
+
+func (ds *fakeDatastore) GetVoteTallies(ctx context.Context, channel string, from, to time.Time) ([]VoteTally, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetVoteTallies"); err != nil {
+		return nil, err
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	tallies := []VoteTally{}
+	for _, tally := range ds.tallies {
+		if (channel == "" || tally.Channel == channel) && !tally.Day.Before(from) && tally.Day.Before(to) {
+			tallies = append(tallies, tally)
+		}
+	}
+	return tallies, nil
+}
+
+func (ds *fakeDatastore) InsertVoteTallyRollup(ctx context.Context, rollup *VoteTallyRollup) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertVoteTallyRollup"); err != nil {
+		return err
+	}
+	rollup.ID = uuid.NewV4()
+	ds.rollups = append(ds.rollups, *rollup)
+	return nil
+}
+
+func (ds *fakeDatastore) GetLatestVoteTallyRollup(ctx context.Context) (*VoteTallyRollup, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetLatestVoteTallyRollup"); err != nil {
+		return nil, err
+	}
+	if len(ds.rollups) == 0 {
+		return nil, nil
+	}
+	latest := ds.rollups[len(ds.rollups)-1]
+	return &latest, nil
+}
+
+func (ds *fakeDatastore) ClaimOrderTrial(ctx context.Context, orderID, walletID uuid.UUID, merchantID string, limit int) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("ClaimOrderTrial"); err != nil {
+		return err
+	}
+	if trial, ok := ds.trials[orderID]; ok {
+		if uuid.Equal(trial.walletID, walletID) {
+			return nil
+		}
+		return ErrTrialClaimed
+	}
+	trials := 0
+	for _, trial := range ds.trials {
+		if uuid.Equal(trial.walletID, walletID) && trial.merchantID == merchantID {
+			trials++
+		}
+	}
+	if trials >= limit {
+		return ErrTrialLimit
+	}
+	ds.trials[orderID] = fakeOrderTrial{walletID: walletID, merchantID: merchantID}
+	return nil
+}
+
+func (ds *fakeDatastore) InsertOrderReceipt(ctx context.Context, receipt *OrderReceipt) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("InsertOrderReceipt"); err != nil {
+		return err
+	}
+	if _, ok := ds.receipts[receipt.OrderID]; !ok {
+		inserted := *receipt
+		inserted.CreatedAt = time.Now()
+		ds.receipts[receipt.OrderID] = inserted
+	}
+	return nil
+}
+
+func (ds *fakeDatastore) GetOrderReceipt(ctx context.Context, orderID uuid.UUID) (*OrderReceipt, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := ds.call("GetOrderReceipt"); err != nil {
+		return nil, err
+	}
+	receipt, ok := ds.receipts[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &receipt, nil
+}
+
+// fakeWallets is an in memory wallet.Datastore for the tests which look up the wallets of orders, like the
+// custodian linked to a wallet or the public key a trial wallet signs with
+type fakeWallets struct {
+	mu      sync.Mutex
+	wallets map[uuid.UUID]*walletutils.Info
+	links   map[uuid.UUID]*wallet.CustodianLink
+}
+
+func newFakeWallets() *fakeWallets {
+	return &fakeWallets{
+		wallets: map[uuid.UUID]*walletutils.Info{},
+		links:   map[uuid.UUID]*wallet.CustodianLink{},
+	}
+}
+
+// link links the wallet to the account of the custodian, which the wallet deposits to
+func (ds *fakeWallets) link(walletID uuid.UUID, custodian string, account string) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.wallets[walletID] = &walletutils.Info{ID: walletID.String(), UserDepositDestination: account}
+	ds.links[walletID] = &wallet.CustodianLink{WalletID: &walletID, Custodian: custodian}
+}
+
+func (ds *fakeWallets) RawDB() *sqlx.DB {
+	return nil
+}
+
+func (ds *fakeWallets) NewMigrate() (*migrate.Migrate, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeWallets) Migrate(ctx context.Context, targetVersion uint) error {
+	return errNotFaked
+}
+
+func (ds *fakeWallets) MigrationStatus(ctx context.Context) (*grantserver.MigrationStatus, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeWallets) RollbackTxAndHandle(tx *sqlx.Tx) error {
+	return nil
+}
+
+func (ds *fakeWallets) RollbackTx(tx *sqlx.Tx) {}
+
+func (ds *fakeWallets) LinkWallet(ctx context.Context, ID string, providerID string, providerLinkingID uuid.UUID, anonymousAddress *uuid.UUID, depositProvider string) error {
+	walletID, err := uuid.FromString(ID)
+	if err != nil {
+		return err
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	info, ok := ds.wallets[walletID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	info.UserDepositDestination = providerID
+	info.ProviderLinkingID = &providerLinkingID
+	info.AnonymousAddress = anonymousAddress
+	ds.links[walletID] = &wallet.CustodianLink{WalletID: &walletID, Custodian: depositProvider}
+	return nil
+}
+
+func (ds *fakeWallets) IncreaseLinkingLimit(ctx context.Context, providerLinkingID uuid.UUID) error {
+	return nil
+}
+
+func (ds *fakeWallets) GetLinkingLimitInfo(ctx context.Context, providerLinkingID string) (wallet.LinkingInfo, error) {
+	return wallet.LinkingInfo{}, nil
+}
+
+func (ds *fakeWallets) GetByProviderLinkingID(ctx context.Context, providerLinkingID uuid.UUID) (*[]walletutils.Info, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	infos := []walletutils.Info{}
+	for _, info := range ds.wallets {
+		if info.ProviderLinkingID != nil && uuid.Equal(*info.ProviderLinkingID, providerLinkingID) {
+			infos = append(infos, *info)
+		}
+	}
+	return &infos, nil
+}
+
+func (ds *fakeWallets) GetWallet(ctx context.Context, ID uuid.UUID) (*walletutils.Info, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	info, ok := ds.wallets[ID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *info
+	return &copied, nil
+}
+
+func (ds *fakeWallets) GetWalletByPublicKey(ctx context.Context, publicKey string) (*walletutils.Info, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, info := range ds.wallets {
+		if info.PublicKey == publicKey {
+			copied := *info
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ds *fakeWallets) InsertWallet(ctx context.Context, info *walletutils.Info) error {
+	walletID, err := uuid.FromString(info.ID)
+	if err != nil {
+		return err
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	inserted := *info
+	ds.wallets[walletID] = &inserted
+	return nil
+}
+
+func (ds *fakeWallets) InsertBitFlyerRequestID(ctx context.Context, requestID string) error {
+	return nil
+}
+
+func (ds *fakeWallets) UpsertWallet(ctx context.Context, info *walletutils.Info) error {
+	return ds.InsertWallet(ctx, info)
+}
+
+func (ds *fakeWallets) ConnectCustodialWallet(ctx context.Context, cl *wallet.CustodianLink, depositDest string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	info, ok := ds.wallets[*cl.WalletID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	info.UserDepositDestination = depositDest
+	connected := *cl
+	ds.links[*cl.WalletID] = &connected
+	return nil
+}
+
+func (ds *fakeWallets) DisconnectCustodialWallet(ctx context.Context, walletID uuid.UUID) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	delete(ds.links, walletID)
+	return nil
+}
+
+func (ds *fakeWallets) GetCustodianLinkByWalletID(ctx context.Context, ID uuid.UUID) (*wallet.CustodianLink, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	link, ok := ds.links[ID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get CustodianLink from DB: %w", sql.ErrNoRows)
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (ds *fakeWallets) GetCustodianLinkCount(ctx context.Context, linkingID uuid.UUID) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (ds *fakeWallets) ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) ([]walletutils.Info, error) {
+	return nil, errNotFaked
+}
+
+func (ds *fakeWallets) SampleUpholdWallets(ctx context.Context, limit int) ([]walletutils.Info, error) {
+	return nil, errNotFaked
+}
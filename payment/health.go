@@ -0,0 +1,41 @@
+package payment
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// readinessTimeout bounds each dependency check of the payment readiness endpoint
+const readinessTimeout = 5 * time.Second
+
+// ReadinessChecks are the dependencies the payment service serves with. Its database is critical, while its
+// read replica, which it falls back from, and kafka and the challenge bypass server, when configured, only
+// degrade it
+func (s *Service) ReadinessChecks() []handlers.DependencyCheck {
+	checks := []handlers.DependencyCheck{
+		handlers.PingCheck("db", true, s.Datastore.RawDB()),
+	}
+	if s.RoDatastore != nil {
+		checks = append(checks, handlers.PingCheck("db_replica", false, s.RoDatastore.RawDB()))
+	}
+	if brokers := os.Getenv("KAFKA_BROKERS"); brokers != "" {
+		checks = append(checks, handlers.DialCheck("kafka", false, strings.Split(brokers, ",")...))
+	}
+	if server := os.Getenv("CHALLENGE_BYPASS_SERVER"); server != "" {
+		checks = append(checks, handlers.HTTPCheck("challenge_bypass", false, server))
+	}
+	return checks
+}
+
+// HealthRouter serves the liveness of the payment service at /health, and at /ready its readiness, which
+// checks its dependencies and responds 503 while its database is unavailable
+func HealthRouter(service *Service, version, buildTime, commit string) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/health", handlers.HealthCheckHandler(version, buildTime, commit))
+	r.Get("/ready", handlers.ReadinessHandler(readinessTimeout, service.ReadinessChecks()...))
+	return r
+}
@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPingDatastore is a datastore whose database only answers pings
+func newPingDatastore(t *testing.T, pingErr error) *fakeDatastore {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mockDB.Close() })
+	mock.ExpectPing().WillReturnError(pingErr)
+	ds := newFakeDatastore()
+	ds.db = sqlx.NewDb(mockDB, "sqlmock")
+	return ds
+}
+
+func TestPaymentReadiness(t *testing.T) {
+	require.NoError(t, os.Unsetenv("KAFKA_BROKERS"))
+	require.NoError(t, os.Unsetenv("CHALLENGE_BYPASS_SERVER"))
+
+	ready := func(service *Service) (int, handlers.ReadinessResponse) {
+		rr := httptest.NewRecorder()
+		HealthRouter(service, "", "", "").ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		var resp handlers.ReadinessResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	code, resp := ready(&Service{
+		Datastore:   newPingDatastore(t, nil),
+		RoDatastore: newPingDatastore(t, errors.New("replica is down")),
+	})
+	assert.Equal(t, http.StatusOK, code, "the service is ready without its replica")
+	assert.Equal(t, handlers.DependencyDegraded, resp.Status)
+	assert.Equal(t, handlers.DependencyOK, resp.Dependencies["db"].Status)
+	assert.Equal(t, "replica is down", resp.Dependencies["db_replica"].Error)
+
+	code, resp = ready(&Service{Datastore: newPingDatastore(t, errors.New("primary is down"))})
+	assert.Equal(t, http.StatusServiceUnavailable, code, "the service is not ready without its database")
+	assert.Equal(t, handlers.DependencyUnavailable, resp.Status)
+	assert.NotContains(t, resp.Dependencies, "db_replica")
+}
@@ -10,7 +10,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/client_golang/prometheus"
@@ -43,134 +46,1018 @@ func NewDatastoreWithPrometheus(base Datastore, instanceName string) DatastoreWi
 	}
 }
 
+// AdvanceChainCursor implements Datastore
+func (_d DatastoreWithPrometheus) AdvanceChainCursor(ctx context.Context, chain string, from uint64, to uint64) (b1 bool, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".AdvanceChainCursor")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "AdvanceChainCursor", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.AdvanceChainCursor(ctx, chain, from, to)
+}
+
+// ClaimOrderTrial implements Datastore
+func (_d DatastoreWithPrometheus) ClaimOrderTrial(ctx context.Context, orderID uuid.UUID, walletID uuid.UUID, merchantID string, limit int) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ClaimOrderTrial")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ClaimOrderTrial", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.ClaimOrderTrial(ctx, orderID, walletID, merchantID, limit)
+}
+
 // CommitVote implements Datastore
 func (_d DatastoreWithPrometheus) CommitVote(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CommitVote")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CommitVote", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CommitVote(ctx, vr, tx)
+}
+
+// CompleteIdempotencyKey implements Datastore
+func (_d DatastoreWithPrometheus) CompleteIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CompleteIdempotencyKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CompleteIdempotencyKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CompleteIdempotencyKey(ctx, request)
+}
+
+// CreateKey implements Datastore
+func (_d DatastoreWithPrometheus) CreateKey(merchant string, name string, encryptedSecretKey string, nonce string, scopes []string, tokenHash string) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.CreateKey(merchant, name, encryptedSecretKey, nonce, scopes, tokenHash)
+}
+
+// CreateMerchant implements Datastore
+func (_d DatastoreWithPrometheus) CreateMerchant(ctx context.Context, merchant *Merchant) (mp1 *Merchant, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateMerchant")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateMerchant", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateMerchant(ctx, merchant)
+}
+
+// CreateMerchantSigningKey implements Datastore
+func (_d DatastoreWithPrometheus) CreateMerchantSigningKey(ctx context.Context, key *MerchantSigningKey) (mp1 *MerchantSigningKey, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateMerchantSigningKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateMerchantSigningKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateMerchantSigningKey(ctx, key)
+}
+
+// CreateOrder implements Datastore
+func (_d DatastoreWithPrometheus) CreateOrder(ctx context.Context, totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, rate *ExchangeRate, orderItems []OrderItem, voucher *VoucherRedemption) (op1 *Order, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateOrder")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateOrder", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateOrder(ctx, totalPrice, merchantID, status, currency, location, rate, orderItems, voucher)
+}
+
+// CreateOrders implements Datastore
+func (_d DatastoreWithPrometheus) CreateOrders(ctx context.Context, orders []NewOrder) (o1 []Order, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateOrders")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateOrders", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateOrders(ctx, orders)
+}
+
+// CreateTransaction implements Datastore
+func (_d DatastoreWithPrometheus) CreateTransaction(orderID uuid.UUID, externalTransactionID string, status string, currency string, kind string, amount decimal.Decimal) (tp1 *Transaction, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateTransaction", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.CreateTransaction(orderID, externalTransactionID, status, currency, kind, amount)
+}
+
+// CreateVoucher implements Datastore
+func (_d DatastoreWithPrometheus) CreateVoucher(ctx context.Context, voucher *Voucher) (vp1 *Voucher, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateVoucher")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateVoucher", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateVoucher(ctx, voucher)
+}
+
+// CreateWebhookSecret implements Datastore
+func (_d DatastoreWithPrometheus) CreateWebhookSecret(ctx context.Context, merchantID string, encryptedSecret string, nonce string, overlap time.Duration) (wp1 *WebhookSecret, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateWebhookSecret")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateWebhookSecret", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.CreateWebhookSecret(ctx, merchantID, encryptedSecret, nonce, overlap)
+}
+
+// DeleteKey implements Datastore
+func (_d DatastoreWithPrometheus) DeleteKey(id uuid.UUID, delaySeconds int) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.DeleteKey(id, delaySeconds)
+}
+
+// DeleteMerchant implements Datastore
+func (_d DatastoreWithPrometheus) DeleteMerchant(ctx context.Context, id string) (mp1 *Merchant, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DeleteMerchant")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteMerchant", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.DeleteMerchant(ctx, id)
+}
+
+// DeleteOrderCreds implements Datastore
+func (_d DatastoreWithPrometheus) DeleteOrderCreds(ctx context.Context, orderID uuid.UUID) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DeleteOrderCreds")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteOrderCreds", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.DeleteOrderCreds(ctx, orderID)
+}
+
+// DeleteWebhookDeliveries implements Datastore
+func (_d DatastoreWithPrometheus) DeleteWebhookDeliveries(ctx context.Context, before time.Time) (i1 int64, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DeleteWebhookDeliveries")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteWebhookDeliveries", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.DeleteWebhookDeliveries(ctx, before)
+}
+
+// DisableIssuer implements Datastore
+func (_d DatastoreWithPrometheus) DisableIssuer(ctx context.Context, id uuid.UUID) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DisableIssuer")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DisableIssuer", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.DisableIssuer(ctx, id)
+}
+
+// DispatchOrderEvents implements Datastore
+func (_d DatastoreWithPrometheus) DispatchOrderEvents(ctx context.Context, limit int, dispatch func(context.Context, OrderLogEvent) error) (i1 int, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DispatchOrderEvents")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DispatchOrderEvents", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.DispatchOrderEvents(ctx, limit, dispatch)
+}
+
+// GetAuditEvents implements Datastore
+func (_d DatastoreWithPrometheus) GetAuditEvents(ctx context.Context, actor string, since time.Time, limit int) (aa1 []middleware.AuditEvent, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetAuditEvents")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetAuditEvents", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetAuditEvents(ctx, actor, since, limit)
+}
+
+// GetChainCursor implements Datastore
+func (_d DatastoreWithPrometheus) GetChainCursor(ctx context.Context, chain string) (u1 uint64, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetChainCursor")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetChainCursor", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetChainCursor(ctx, chain)
+}
+
+// GetCheckoutSession implements Datastore
+func (_d DatastoreWithPrometheus) GetCheckoutSession(ctx context.Context, orderID uuid.UUID) (cp1 *CheckoutSession, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCheckoutSession")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCheckoutSession", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetCheckoutSession(ctx, orderID)
+}
+
+// GetCredentialRedemptions implements Datastore
+func (_d DatastoreWithPrometheus) GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) (ra1 []RedeemedCredential, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCredentialRedemptions")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCredentialRedemptions", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetCredentialRedemptions(ctx, tokenPreimages)
+}
+
+// GetExpiringCredentialWindows implements Datastore
+func (_d DatastoreWithPrometheus) GetExpiringCredentialWindows(ctx context.Context, endingBefore time.Time, limit int) (ca1 []CredentialWindow, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetExpiringCredentialWindows")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetExpiringCredentialWindows", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetExpiringCredentialWindows(ctx, endingBefore, limit)
+}
+
+// GetIssuer implements Datastore
+func (_d DatastoreWithPrometheus) GetIssuer(merchantID string) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuer", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetIssuer(merchantID)
+}
+
+// GetIssuerByID implements Datastore
+func (_d DatastoreWithPrometheus) GetIssuerByID(ctx context.Context, id uuid.UUID) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetIssuerByID")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuerByID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetIssuerByID(ctx, id)
+}
+
+// GetIssuerByPublicKey implements Datastore
+func (_d DatastoreWithPrometheus) GetIssuerByPublicKey(publicKey string) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuerByPublicKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetIssuerByPublicKey(publicKey)
+}
+
+// GetIssuers implements Datastore
+func (_d DatastoreWithPrometheus) GetIssuers(ctx context.Context, merchantID string) (ia1 []Issuer, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetIssuers")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuers", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetIssuers(ctx, merchantID)
+}
+
+// GetKey implements Datastore
+func (_d DatastoreWithPrometheus) GetKey(id uuid.UUID) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetKey(id)
+}
+
+// GetKeyByTokenHash implements Datastore
+func (_d DatastoreWithPrometheus) GetKeyByTokenHash(tokenHash string) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetKeyByTokenHash", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetKeyByTokenHash(tokenHash)
+}
+
+// GetKeyWithSecret implements Datastore
+func (_d DatastoreWithPrometheus) GetKeyWithSecret(id uuid.UUID) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetKeyWithSecret", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetKeyWithSecret(id)
+}
+
+// GetKeys implements Datastore
+func (_d DatastoreWithPrometheus) GetKeys(merchant string, showExpired bool) (kap1 *[]Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetKeys", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetKeys(merchant, showExpired)
+}
+
+// GetLatestVoteTallyRollup implements Datastore
+func (_d DatastoreWithPrometheus) GetLatestVoteTallyRollup(ctx context.Context) (vp1 *VoteTallyRollup, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetLatestVoteTallyRollup")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetLatestVoteTallyRollup", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetLatestVoteTallyRollup(ctx)
+}
+
+// GetMerchant implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchant(ctx context.Context, id string) (mp1 *Merchant, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchant")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchant", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchant(ctx, id)
+}
+
+// GetMerchantEncryptionKey implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchantEncryptionKey(merchantID string) (mp1 *MerchantEncryptionKey, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchantEncryptionKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetMerchantEncryptionKey(merchantID)
+}
+
+// GetMerchantSettings implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchantSettings(ctx context.Context, merchantID string) (mp1 *MerchantSettings, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchantSettings")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchantSettings", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchantSettings(ctx, merchantID)
+}
+
+// GetMerchantSigningKey implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchantSigningKey(ctx context.Context, id uuid.UUID) (mp1 *MerchantSigningKey, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchantSigningKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchantSigningKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchantSigningKey(ctx, id)
+}
+
+// GetMerchantSigningKeys implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchantSigningKeys(ctx context.Context, merchantID string) (ma1 []MerchantSigningKey, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchantSigningKeys")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchantSigningKeys", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchantSigningKeys(ctx, merchantID)
+}
+
+// GetMerchantUsage implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchantUsage(ctx context.Context, merchantID string, since time.Time) (ma1 []MerchantUsage, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchantUsage")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchantUsage", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchantUsage(ctx, merchantID, since)
+}
+
+// GetMerchants implements Datastore
+func (_d DatastoreWithPrometheus) GetMerchants(ctx context.Context) (ma1 []Merchant, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetMerchants")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetMerchants", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetMerchants(ctx)
+}
+
+// GetOrder implements Datastore
+func (_d DatastoreWithPrometheus) GetOrder(orderID uuid.UUID) (op1 *Order, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrder", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrder(orderID)
+}
+
+// GetOrderCreds implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderCreds(orderID uuid.UUID, isSigned bool) (oap1 *[]OrderCreds, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCreds", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrderCreds(orderID, isSigned)
+}
+
+// GetOrderCredsByItemID implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (op1 *OrderCreds, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCredsByItemID", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrderCredsByItemID(orderID, itemID, isSigned)
+}
+
+// GetOrderDeposit implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderDeposit(ctx context.Context, orderID uuid.UUID) (op1 *OrderDeposit, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderDeposit")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderDeposit", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderDeposit(ctx, orderID)
+}
+
+// GetOrderDepositsByAddress implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderDepositsByAddress(ctx context.Context, addresses []string) (oa1 []OrderDeposit, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderDepositsByAddress")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderDepositsByAddress", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderDepositsByAddress(ctx, addresses)
+}
+
+// GetOrderEvents implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderEvents(ctx context.Context, orderID uuid.UUID) (oa1 []OrderLogEvent, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderEvents")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderEvents", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderEvents(ctx, orderID)
+}
+
+// GetOrderHistory implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderHistory(ctx context.Context, orderID uuid.UUID) (oa1 []OrderHistoryEntry, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderHistory")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderHistory", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderHistory(ctx, orderID)
+}
+
+// GetOrderPayments implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderPayments(ctx context.Context, orderID uuid.UUID) (oa1 []OrderPayment, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderPayments")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderPayments", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderPayments(ctx, orderID)
+}
+
+// GetOrderReceipt implements Datastore
+func (_d DatastoreWithPrometheus) GetOrderReceipt(ctx context.Context, orderID uuid.UUID) (op1 *OrderReceipt, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetOrderReceipt")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderReceipt", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetOrderReceipt(ctx, orderID)
+}
+
+// GetPagedMerchantTransactions implements Datastore
+func (_d DatastoreWithPrometheus) GetPagedMerchantTransactions(ctx context.Context, merchantID uuid.UUID, pagination *inputs.Pagination) (tap1 *[]Transaction, i1 int, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetPagedMerchantTransactions")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetPagedMerchantTransactions", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetPagedMerchantTransactions(ctx, merchantID, pagination)
+}
+
+// GetPendingTransactions implements Datastore
+func (_d DatastoreWithPrometheus) GetPendingTransactions(ctx context.Context, kinds []string, limit int) (ta1 []Transaction, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetPendingTransactions")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetPendingTransactions", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetPendingTransactions(ctx, kinds, limit)
+}
+
+// GetStaleOrders implements Datastore
+func (_d DatastoreWithPrometheus) GetStaleOrders(ctx context.Context, defaultExpiry time.Duration, limit int) (ua1 []uuid.UUID, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetStaleOrders")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetStaleOrders", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetStaleOrders(ctx, defaultExpiry, limit)
+}
+
+// GetStuckOrders implements Datastore
+func (_d DatastoreWithPrometheus) GetStuckOrders(ctx context.Context, requestedBefore time.Time, limit int) (sa1 []StuckOrder, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetStuckOrders")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetStuckOrders", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetStuckOrders(ctx, requestedBefore, limit)
+}
+
+// GetTransaction implements Datastore
+func (_d DatastoreWithPrometheus) GetTransaction(externalTransactionID string) (tp1 *Transaction, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetTransaction", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetTransaction(externalTransactionID)
+}
+
+// GetTransactions implements Datastore
+func (_d DatastoreWithPrometheus) GetTransactions(orderID uuid.UUID) (tap1 *[]Transaction, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetTransactions", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetTransactions(orderID)
+}
+
+// GetTransactionsUpdatedBetween implements Datastore
+func (_d DatastoreWithPrometheus) GetTransactionsUpdatedBetween(ctx context.Context, from time.Time, to time.Time) (ea1 []ExportedTransaction, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetTransactionsUpdatedBetween")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetTransactionsUpdatedBetween", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetTransactionsUpdatedBetween(ctx, from, to)
+}
+
+// GetUncommittedVotesForUpdate implements Datastore
+func (_d DatastoreWithPrometheus) GetUncommittedVotesForUpdate(ctx context.Context) (tp1 *sqlx.Tx, vpa1 []*VoteRecord, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetUncommittedVotesForUpdate")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetUncommittedVotesForUpdate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetUncommittedVotesForUpdate(ctx)
+}
+
+// GetVoteTallies implements Datastore
+func (_d DatastoreWithPrometheus) GetVoteTallies(ctx context.Context, channel string, from time.Time, to time.Time) (va1 []VoteTally, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetVoteTallies")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CommitVote", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetVoteTallies", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.CommitVote(ctx, vr, tx)
+	return _d.base.GetVoteTallies(ctx, channel, from, to)
 }
 
-// CreateKey implements Datastore
-func (_d DatastoreWithPrometheus) CreateKey(merchant string, name string, encryptedSecretKey string, nonce string) (kp1 *Key, err error) {
+// GetVotesCreatedBetween implements Datastore
+func (_d DatastoreWithPrometheus) GetVotesCreatedBetween(ctx context.Context, from time.Time, to time.Time) (ea1 []ExportedVote, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetVotesCreatedBetween")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateKey", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetVotesCreatedBetween", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.CreateKey(merchant, name, encryptedSecretKey, nonce)
+	return _d.base.GetVotesCreatedBetween(ctx, from, to)
 }
 
-// CreateOrder implements Datastore
-func (_d DatastoreWithPrometheus) CreateOrder(totalPrice decimal.Decimal, merchantID string, status string, currency string, location string, orderItems []OrderItem) (op1 *Order, err error) {
+// GetVoucher implements Datastore
+func (_d DatastoreWithPrometheus) GetVoucher(ctx context.Context, code string) (vp1 *Voucher, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetVoucher")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateOrder", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetVoucher", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.CreateOrder(totalPrice, merchantID, status, currency, location, orderItems)
+	return _d.base.GetVoucher(ctx, code)
 }
 
-// CreateTransaction implements Datastore
-func (_d DatastoreWithPrometheus) CreateTransaction(orderID uuid.UUID, externalTransactionID string, status string, currency string, kind string, amount decimal.Decimal) (tp1 *Transaction, err error) {
+// GetWebhookDeliveries implements Datastore
+func (_d DatastoreWithPrometheus) GetWebhookDeliveries(ctx context.Context, merchantID string, limit int) (wa1 []WebhookDelivery, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWebhookDeliveries")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateTransaction", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWebhookDeliveries", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.CreateTransaction(orderID, externalTransactionID, status, currency, kind, amount)
+	return _d.base.GetWebhookDeliveries(ctx, merchantID, limit)
 }
 
-// DeleteKey implements Datastore
-func (_d DatastoreWithPrometheus) DeleteKey(id uuid.UUID, delaySeconds int) (kp1 *Key, err error) {
+// GetWebhookSecrets implements Datastore
+func (_d DatastoreWithPrometheus) GetWebhookSecrets(ctx context.Context, merchantID string) (wa1 []WebhookSecret, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWebhookSecrets")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteKey", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWebhookSecrets", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.DeleteKey(id, delaySeconds)
+	return _d.base.GetWebhookSecrets(ctx, merchantID)
 }
 
-// DeleteOrderCreds implements Datastore
-func (_d DatastoreWithPrometheus) DeleteOrderCreds(orderID uuid.UUID) (err error) {
+// InsertAuditEvent implements Datastore
+func (_d DatastoreWithPrometheus) InsertAuditEvent(ctx context.Context, event *middleware.AuditEvent) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertAuditEvent")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DeleteOrderCreds", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertAuditEvent", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.DeleteOrderCreds(orderID)
+	return _d.base.InsertAuditEvent(ctx, event)
 }
 
-// GetIssuer implements Datastore
-func (_d DatastoreWithPrometheus) GetIssuer(merchantID string) (ip1 *Issuer, err error) {
+// InsertCheckoutSession implements Datastore
+func (_d DatastoreWithPrometheus) InsertCheckoutSession(ctx context.Context, session *CheckoutSession) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertCheckoutSession")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuer", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertCheckoutSession", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetIssuer(merchantID)
+	return _d.base.InsertCheckoutSession(ctx, session)
 }
 
-// GetIssuerByPublicKey implements Datastore
-func (_d DatastoreWithPrometheus) GetIssuerByPublicKey(publicKey string) (ip1 *Issuer, err error) {
+// InsertCredentialRedemptions implements Datastore
+func (_d DatastoreWithPrometheus) InsertCredentialRedemptions(ctx context.Context, tx *sqlx.Tx, redemptions []RedeemedCredential) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertCredentialRedemptions")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuerByPublicKey", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertCredentialRedemptions", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetIssuerByPublicKey(publicKey)
+	return _d.base.InsertCredentialRedemptions(ctx, tx, redemptions)
 }
 
-// GetKeys implements Datastore
-func (_d DatastoreWithPrometheus) GetKeys(merchant string, showExpired bool) (kap1 *[]Key, err error) {
+// InsertCredentialWindow implements Datastore
+func (_d DatastoreWithPrometheus) InsertCredentialWindow(ctx context.Context, creds *OrderCreds) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertCredentialWindow")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetKeys", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertCredentialWindow", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetKeys(merchant, showExpired)
+	return _d.base.InsertCredentialWindow(ctx, creds)
 }
 
-// GetOrder implements Datastore
-func (_d DatastoreWithPrometheus) GetOrder(orderID uuid.UUID) (op1 *Order, err error) {
+// InsertIssuer implements Datastore
+func (_d DatastoreWithPrometheus) InsertIssuer(issuer *Issuer) (ip1 *Issuer, err error) {
 	_since := time.Now()
 	defer func() {
 		result := "ok"
@@ -178,139 +1065,157 @@ func (_d DatastoreWithPrometheus) GetOrder(orderID uuid.UUID) (op1 *Order, err e
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrder", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertIssuer", result).Observe(time.Since(_since).Seconds())
 	}()
-	return _d.base.GetOrder(orderID)
+	return _d.base.InsertIssuer(issuer)
 }
 
-// GetOrderCreds implements Datastore
-func (_d DatastoreWithPrometheus) GetOrderCreds(orderID uuid.UUID, isSigned bool) (oap1 *[]OrderCreds, err error) {
+// InsertOrderCreds implements Datastore
+func (_d DatastoreWithPrometheus) InsertOrderCreds(ctx context.Context, creds *OrderCreds) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertOrderCreds")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCreds", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertOrderCreds", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetOrderCreds(orderID, isSigned)
+	return _d.base.InsertOrderCreds(ctx, creds)
 }
 
-// GetOrderCredsByItemID implements Datastore
-func (_d DatastoreWithPrometheus) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (op1 *OrderCreds, err error) {
+// InsertOrderDeposit implements Datastore
+func (_d DatastoreWithPrometheus) InsertOrderDeposit(ctx context.Context, deposit *OrderDeposit) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertOrderDeposit")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCredsByItemID", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertOrderDeposit", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetOrderCredsByItemID(orderID, itemID, isSigned)
+	return _d.base.InsertOrderDeposit(ctx, deposit)
 }
 
-// GetPagedMerchantTransactions implements Datastore
-func (_d DatastoreWithPrometheus) GetPagedMerchantTransactions(ctx context.Context, merchantID uuid.UUID, pagination *inputs.Pagination) (tap1 *[]Transaction, i1 int, err error) {
+// InsertOrderReceipt implements Datastore
+func (_d DatastoreWithPrometheus) InsertOrderReceipt(ctx context.Context, receipt *OrderReceipt) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertOrderReceipt")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetPagedMerchantTransactions", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertOrderReceipt", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetPagedMerchantTransactions(ctx, merchantID, pagination)
+	return _d.base.InsertOrderReceipt(ctx, receipt)
 }
 
-// GetSumForTransactions implements Datastore
-func (_d DatastoreWithPrometheus) GetSumForTransactions(orderID uuid.UUID) (d1 decimal.Decimal, err error) {
+// InsertVote implements Datastore
+func (_d DatastoreWithPrometheus) InsertVote(ctx context.Context, vr VoteRecord) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertVote")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetSumForTransactions", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertVote", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetSumForTransactions(orderID)
+	return _d.base.InsertVote(ctx, vr)
 }
 
-// GetTransaction implements Datastore
-func (_d DatastoreWithPrometheus) GetTransaction(externalTransactionID string) (tp1 *Transaction, err error) {
+// InsertVoteTallyRollup implements Datastore
+func (_d DatastoreWithPrometheus) InsertVoteTallyRollup(ctx context.Context, rollup *VoteTallyRollup) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertVoteTallyRollup")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetTransaction", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertVoteTallyRollup", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetTransaction(externalTransactionID)
+	return _d.base.InsertVoteTallyRollup(ctx, rollup)
 }
 
-// GetTransactions implements Datastore
-func (_d DatastoreWithPrometheus) GetTransactions(orderID uuid.UUID) (tap1 *[]Transaction, err error) {
+// InsertWebhookDelivery implements Datastore
+func (_d DatastoreWithPrometheus) InsertWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertWebhookDelivery")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetTransactions", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertWebhookDelivery", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetTransactions(orderID)
+	return _d.base.InsertWebhookDelivery(ctx, delivery)
 }
 
-// GetUncommittedVotesForUpdate implements Datastore
-func (_d DatastoreWithPrometheus) GetUncommittedVotesForUpdate(ctx context.Context) (tp1 *sqlx.Tx, vpa1 []*VoteRecord, err error) {
+// ListIssuers implements Datastore
+func (_d DatastoreWithPrometheus) ListIssuers(ctx context.Context, merchantID string, limit int) (ia1 []IssuerUsage, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ListIssuers")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetUncommittedVotesForUpdate", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ListIssuers", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.GetUncommittedVotesForUpdate(ctx)
+	return _d.base.ListIssuers(ctx, merchantID, limit)
 }
 
-// InsertIssuer implements Datastore
-func (_d DatastoreWithPrometheus) InsertIssuer(issuer *Issuer) (ip1 *Issuer, err error) {
+// ListOrders implements Datastore
+func (_d DatastoreWithPrometheus) ListOrders(ctx context.Context, filter OrderFilter) (oa1 []Order, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ListOrders")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertIssuer", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ListOrders", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.InsertIssuer(issuer)
+	return _d.base.ListOrders(ctx, filter)
 }
 
-// InsertOrderCreds implements Datastore
-func (_d DatastoreWithPrometheus) InsertOrderCreds(creds *OrderCreds) (err error) {
+// ListVouchers implements Datastore
+func (_d DatastoreWithPrometheus) ListVouchers(ctx context.Context, merchantID string, limit int) (va1 []Voucher, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ListVouchers")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertOrderCreds", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ListVouchers", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.InsertOrderCreds(creds)
+	return _d.base.ListVouchers(ctx, merchantID, limit)
 }
 
-// InsertVote implements Datastore
-func (_d DatastoreWithPrometheus) InsertVote(ctx context.Context, vr VoteRecord) (err error) {
+// MarkKeyUsed implements Datastore
+func (_d DatastoreWithPrometheus) MarkKeyUsed(id uuid.UUID) (err error) {
 	_since := time.Now()
 	defer func() {
 		result := "ok"
@@ -318,14 +1223,15 @@ func (_d DatastoreWithPrometheus) InsertVote(ctx context.Context, vr VoteRecord)
 			result = "error"
 		}
 
-		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertVote", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MarkKeyUsed", result).Observe(time.Since(_since).Seconds())
 	}()
-	return _d.base.InsertVote(ctx, vr)
+	return _d.base.MarkKeyUsed(id)
 }
 
 // MarkVoteErrored implements Datastore
 func (_d DatastoreWithPrometheus) MarkVoteErrored(ctx context.Context, vr VoteRecord, tx *sqlx.Tx) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MarkVoteErrored")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -333,13 +1239,15 @@ func (_d DatastoreWithPrometheus) MarkVoteErrored(ctx context.Context, vr VoteRe
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MarkVoteErrored", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.MarkVoteErrored(ctx, vr, tx)
 }
 
 // Migrate implements Datastore
-func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d DatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -347,8 +1255,25 @@ func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements Datastore
+func (_d DatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements Datastore
@@ -365,6 +1290,22 @@ func (_d DatastoreWithPrometheus) NewMigrate() (mp1 *migrate.Migrate, err error)
 	return _d.base.NewMigrate()
 }
 
+// PublishOrderEvents implements Datastore
+func (_d DatastoreWithPrometheus) PublishOrderEvents(ctx context.Context, limit int, publish func(context.Context, OrderLogEvent) error) (i1 int, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".PublishOrderEvents")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "PublishOrderEvents", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.PublishOrderEvents(ctx, limit, publish)
+}
+
 // RawDB implements Datastore
 func (_d DatastoreWithPrometheus) RawDB() (dp1 *sqlx.DB) {
 	_since := time.Now()
@@ -375,6 +1316,86 @@ func (_d DatastoreWithPrometheus) RawDB() (dp1 *sqlx.DB) {
 	return _d.base.RawDB()
 }
 
+// RebuildOrderProjection implements Datastore
+func (_d DatastoreWithPrometheus) RebuildOrderProjection(ctx context.Context, projection *OrderProjection) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RebuildOrderProjection")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RebuildOrderProjection", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RebuildOrderProjection(ctx, projection)
+}
+
+// RecordMerchantUsage implements Datastore
+func (_d DatastoreWithPrometheus) RecordMerchantUsage(ctx context.Context, tx *sqlx.Tx, usage MerchantUsage) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RecordMerchantUsage")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RecordMerchantUsage", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RecordMerchantUsage(ctx, tx, usage)
+}
+
+// ReleaseIdempotencyKey implements Datastore
+func (_d DatastoreWithPrometheus) ReleaseIdempotencyKey(ctx context.Context, scope string, key string) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ReleaseIdempotencyKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ReleaseIdempotencyKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.ReleaseIdempotencyKey(ctx, scope, key)
+}
+
+// ReserveIdempotencyKey implements Datastore
+func (_d DatastoreWithPrometheus) ReserveIdempotencyKey(ctx context.Context, request *middleware.IdempotentRequest, ttl time.Duration) (ip1 *middleware.IdempotentRequest, b1 bool, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ReserveIdempotencyKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ReserveIdempotencyKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.ReserveIdempotencyKey(ctx, request, ttl)
+}
+
+// RevokeMerchantSigningKey implements Datastore
+func (_d DatastoreWithPrometheus) RevokeMerchantSigningKey(ctx context.Context, merchantID string, id uuid.UUID) (mp1 *MerchantSigningKey, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RevokeMerchantSigningKey")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RevokeMerchantSigningKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RevokeMerchantSigningKey(ctx, merchantID, id)
+}
+
 // RollbackTx implements Datastore
 func (_d DatastoreWithPrometheus) RollbackTx(tx *sqlx.Tx) {
 	_since := time.Now()
@@ -400,9 +1421,74 @@ func (_d DatastoreWithPrometheus) RollbackTxAndHandle(tx *sqlx.Tx) (err error) {
 	return _d.base.RollbackTxAndHandle(tx)
 }
 
+// RollupMerchantUsage implements Datastore
+func (_d DatastoreWithPrometheus) RollupMerchantUsage(ctx context.Context, before time.Time) (i1 int64, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RollupMerchantUsage")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RollupMerchantUsage", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RollupMerchantUsage(ctx, before)
+}
+
+// RollupVoteTallies implements Datastore
+func (_d DatastoreWithPrometheus) RollupVoteTallies(ctx context.Context, limit int) (i1 int64, i2 int64, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RollupVoteTallies")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RollupVoteTallies", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RollupVoteTallies(ctx, limit)
+}
+
+// RotateIssuer implements Datastore
+func (_d DatastoreWithPrometheus) RotateIssuer(ctx context.Context, issuer *Issuer) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RotateIssuer")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RotateIssuer", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RotateIssuer(ctx, issuer)
+}
+
+// RunNextCredentialWindow implements Datastore
+func (_d DatastoreWithPrometheus) RunNextCredentialWindow(ctx context.Context, worker OrderWorker) (b1 bool, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextCredentialWindow")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextCredentialWindow", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RunNextCredentialWindow(ctx, worker)
+}
+
 // RunNextOrderJob implements Datastore
 func (_d DatastoreWithPrometheus) RunNextOrderJob(ctx context.Context, worker OrderWorker) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextOrderJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -410,13 +1496,75 @@ func (_d DatastoreWithPrometheus) RunNextOrderJob(ctx context.Context, worker Or
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextOrderJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RunNextOrderJob(ctx, worker)
 }
 
+// SetMerchantEncryptionKey implements Datastore
+func (_d DatastoreWithPrometheus) SetMerchantEncryptionKey(merchantID string, jwk string) (mp1 *MerchantEncryptionKey, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "SetMerchantEncryptionKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.SetMerchantEncryptionKey(merchantID, jwk)
+}
+
+// SettleTransaction implements Datastore
+func (_d DatastoreWithPrometheus) SettleTransaction(ctx context.Context, transactionID uuid.UUID, status string) (tp1 *Transaction, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".SettleTransaction")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "SettleTransaction", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.SettleTransaction(ctx, transactionID, status)
+}
+
+// UpdateKeyRateLimit implements Datastore
+func (_d DatastoreWithPrometheus) UpdateKeyRateLimit(id uuid.UUID, perMinute *int, burst int, dailyQuota *int) (kp1 *Key, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpdateKeyRateLimit", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.UpdateKeyRateLimit(id, perMinute, burst, dailyQuota)
+}
+
+// UpdateMerchant implements Datastore
+func (_d DatastoreWithPrometheus) UpdateMerchant(ctx context.Context, merchant *Merchant) (mp1 *Merchant, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UpdateMerchant")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpdateMerchant", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.UpdateMerchant(ctx, merchant)
+}
+
 // UpdateOrder implements Datastore
-func (_d DatastoreWithPrometheus) UpdateOrder(orderID uuid.UUID, status string) (err error) {
+func (_d DatastoreWithPrometheus) UpdateOrder(ctx context.Context, orderID uuid.UUID, status string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UpdateOrder")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -424,6 +1572,39 @@ func (_d DatastoreWithPrometheus) UpdateOrder(orderID uuid.UUID, status string)
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpdateOrder", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.UpdateOrder(ctx, orderID, status)
+}
+
+// UpdateOrderDeposit implements Datastore
+func (_d DatastoreWithPrometheus) UpdateOrderDeposit(ctx context.Context, orderID uuid.UUID, status string, received decimal.Decimal, excess decimal.Decimal) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UpdateOrderDeposit")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpdateOrderDeposit", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.UpdateOrderDeposit(ctx, orderID, status, received, excess)
+}
+
+// UpsertMerchantSettings implements Datastore
+func (_d DatastoreWithPrometheus) UpsertMerchantSettings(ctx context.Context, settings *MerchantSettings) (mp1 *MerchantSettings, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UpsertMerchantSettings")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpsertMerchantSettings", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.UpdateOrder(orderID, status)
+	return _d.base.UpsertMerchantSettings(ctx, settings)
 }
@@ -0,0 +1,197 @@
+package payment
+
+// DO NOT EDIT!
+// This code is generated with http://github.com/hexdigest/gowrap tool
+// using ../.prom-gowrap.tmpl template
+
+//go:generate gowrap gen -p github.com/brave-intl/bat-go/payment -i ReadOnlyDatastore -t ../.prom-gowrap.tmpl -o instrumented_read_only_datastore.go
+
+import (
+	"context"
+	"time"
+
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	migrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ReadOnlyDatastoreWithPrometheus implements ReadOnlyDatastore interface with all methods wrapped
+// with Prometheus metrics
+type ReadOnlyDatastoreWithPrometheus struct {
+	base         ReadOnlyDatastore
+	instanceName string
+}
+
+var readonlydatastoreDurationSummaryVec = promauto.NewSummaryVec(
+	prometheus.SummaryOpts{
+		Name:       "payment_readonly_datastore_duration_seconds",
+		Help:       "readonlydatastore runtime duration and result",
+		MaxAge:     time.Minute,
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	},
+	[]string{"instance_name", "method", "result"})
+
+// NewReadOnlyDatastoreWithPrometheus returns an instance of the ReadOnlyDatastore decorated with prometheus summary metric
+func NewReadOnlyDatastoreWithPrometheus(base ReadOnlyDatastore, instanceName string) ReadOnlyDatastoreWithPrometheus {
+	return ReadOnlyDatastoreWithPrometheus{
+		base:         base,
+		instanceName: instanceName,
+	}
+}
+
+// GetCredentialRedemptions implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) (ra1 []RedeemedCredential, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCredentialRedemptions")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCredentialRedemptions", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetCredentialRedemptions(ctx, tokenPreimages)
+}
+
+// GetIssuerByPublicKey implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetIssuerByPublicKey(publicKey string) (ip1 *Issuer, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuerByPublicKey", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetIssuerByPublicKey(publicKey)
+}
+
+// GetOrder implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetOrder(orderID uuid.UUID) (op1 *Order, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrder", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrder(orderID)
+}
+
+// GetOrderCreds implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetOrderCreds(orderID uuid.UUID, isSigned bool) (oap1 *[]OrderCreds, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCreds", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrderCreds(orderID, isSigned)
+}
+
+// GetOrderCredsByItemID implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (op1 *OrderCreds, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetOrderCredsByItemID", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.GetOrderCredsByItemID(orderID, itemID, isSigned)
+}
+
+// Migrate implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.MigrationStatus(ctx)
+}
+
+// NewMigrate implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) NewMigrate() (mp1 *migrate.Migrate, err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "NewMigrate", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.NewMigrate()
+}
+
+// RawDB implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) RawDB() (dp1 *sqlx.DB) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RawDB", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.RawDB()
+}
+
+// RollbackTx implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) RollbackTx(tx *sqlx.Tx) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RollbackTx", result).Observe(time.Since(_since).Seconds())
+	}()
+	_d.base.RollbackTx(tx)
+	return
+}
+
+// RollbackTxAndHandle implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) RollbackTxAndHandle(tx *sqlx.Tx) (err error) {
+	_since := time.Now()
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RollbackTxAndHandle", result).Observe(time.Since(_since).Seconds())
+	}()
+	return _d.base.RollbackTxAndHandle(tx)
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/brave-intl/bat-go/middleware"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ErrIssuerTimeLimited is returned when rotating the issuer of a credential window, which is replaced by
+// disabling it instead
+var ErrIssuerTimeLimited = errorutils.NewApplicationError("issuer_time_limited", http.StatusConflict, "the issuers of time-limited credentials are replaced by disabling them", false)
+
+// IssuerUsage is an issuer with the number of tokens it has signed
+type IssuerUsage struct {
+	Issuer
+	TokensSigned int `json:"tokensSigned" db:"tokens_signed"`
+}
+
+// ListIssuers returns the issuers of every merchant, or of the one given, newest first
+func (service *Service) ListIssuers(ctx context.Context, merchantID string, limit int) ([]IssuerUsage, error) {
+	return service.Datastore.ListIssuers(ctx, merchantID, limit)
+}
+
+// RotateIssuerByID creates the next version of the issuer, nil if there is no such issuer
+func (service *Service) RotateIssuerByID(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	issuer, err := service.Datastore.GetIssuerByID(ctx, id)
+	if err != nil || issuer == nil {
+		return nil, err
+	}
+	if _, ok := decodeIssuerWindow(issuer.MerchantID); ok {
+		return nil, ErrIssuerTimeLimited
+	}
+	return service.RotateIssuer(ctx, issuer.MerchantID)
+}
+
+// DisableIssuer stops the issuer signing credentials, nil if there is no such issuer. The next credentials
+// of its merchant are signed by a new version
+func (service *Service) DisableIssuer(ctx context.Context, id uuid.UUID) (*Issuer, error) {
+	issuer, err := service.Datastore.DisableIssuer(ctx, id)
+	if err != nil || issuer == nil {
+		return nil, err
+	}
+	invalidateIssuers(*issuer)
+	return issuer, nil
+}
+
+// IssuerRouter lets admins list the credential issuers, rotate them and disable them
+func IssuerRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.AuditLog(service.Datastore))
+	r.Method("GET", "/", adminAuthorized(service, middleware.InstrumentHandler("ListIssuers", ListIssuers(service))))
+	r.Method("POST", "/{issuerID}/rotate", adminAuthorized(service, middleware.InstrumentHandler("RotateIssuerByID", RotateIssuerByID(service))))
+	r.Method("POST", "/{issuerID}/disable", adminAuthorized(service, middleware.InstrumentHandler("DisableIssuer", DisableIssuer(service))))
+	return r
+}
+
+// ListIssuers is the handler for listing issuers, filtered by the merchant and limit query parameters
+func ListIssuers(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > 1000 {
+				return handlers.ValidationError("request query parameters", map[string]interface{}{
+					"limit": "must be between 1 and 1000",
+				})
+			}
+		}
+
+		issuers, err := service.ListIssuers(r.Context(), r.URL.Query().Get("merchant"), limit)
+		if err != nil {
+			return handlers.WrapError(err, "Error listing issuers", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), issuers, w, http.StatusOK)
+	})
+}
+
+// RotateIssuerByID is the handler for replacing an issuer with its next version
+func RotateIssuerByID(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var issuerID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), issuerID, chi.URLParam(r, "issuerID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"issuerID": err.Error()})
+		}
+
+		issuer, err := service.RotateIssuerByID(r.Context(), *issuerID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error rotating issuer", http.StatusInternalServerError)
+		}
+		if issuer == nil {
+			return &handlers.AppError{
+				Message: "Issuer not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "issuer", issuer.ID.String())
+
+		return handlers.RenderContent(r.Context(), issuer, w, http.StatusCreated)
+	})
+}
+
+// DisableIssuer is the handler for stopping an issuer signing credentials
+func DisableIssuer(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var issuerID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), issuerID, chi.URLParam(r, "issuerID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"issuerID": err.Error()})
+		}
+
+		issuer, err := service.DisableIssuer(r.Context(), *issuerID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error disabling issuer", http.StatusInternalServerError)
+		}
+		if issuer == nil {
+			return &handlers.AppError{
+				Message: "Issuer not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "issuer", issuer.ID.String())
+
+		return handlers.RenderContent(r.Context(), issuer, w, http.StatusOK)
+	})
+}
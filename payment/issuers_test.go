@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisableIssuer(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Datastore: newFakeDatastore(), cbClient: &issuerClient{}}
+
+	issuerID, err := encodeIssuerID("brave.com", "anon-card-vote")
+	require.NoError(t, err)
+	first, err := service.GetOrCreateIssuer(ctx, issuerID)
+	require.NoError(t, err)
+
+	disabled, err := service.DisableIssuer(ctx, first.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, disabled.DisabledAt)
+
+	active, err := service.GetOrCreateIssuer(ctx, issuerID)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, active.ID, "a disabled issuer is no longer returned")
+	assert.Equal(t, 2, active.Version)
+
+	missing, err := service.DisableIssuer(ctx, uuid.NewV4())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestDisableWindowIssuer(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Datastore: newFakeDatastore(), cbClient: &issuerClient{}}
+	validFrom := credentialWindowStart(time.Now())
+
+	first, err := service.getOrCreateWindowIssuer(ctx, "brave.com", "brave-vpn", validFrom)
+	require.NoError(t, err)
+
+	_, err = service.RotateIssuerByID(ctx, first.ID)
+	assert.Equal(t, ErrIssuerTimeLimited, err)
+
+	_, err = service.DisableIssuer(ctx, first.ID)
+	require.NoError(t, err)
+	next, err := service.getOrCreateWindowIssuer(ctx, "brave.com", "brave-vpn", validFrom)
+	require.NoError(t, err)
+	assert.Equal(t, 2, next.Version, "the next version signs the rest of the window")
+	assert.Equal(t, first.ValidFrom, next.ValidFrom)
+	assert.Equal(t, first.ValidTo, next.ValidTo)
+}
@@ -2,13 +2,16 @@ package payment
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/cryptography"
+	"github.com/lib/pq"
 )
 
 // EncryptionKey for encrypting secrets
@@ -18,16 +21,86 @@ var byteEncryptionKey [32]byte
 // What the merchant key length should be
 var keyLength = 24
 
-// Key represents a merchant's keys to validate skus
+const (
+	// KeyScopeTransactionsRead allows a key to list the transactions of its merchant
+	KeyScopeTransactionsRead = "transactions:read"
+	// KeyScopeKeysManage allows a key to manage the keys of its merchant
+	KeyScopeKeysManage = "keys:manage"
+	// KeyScopeWebhooksManage allows a key to manage the webhook secrets of its merchant
+	KeyScopeWebhooksManage = "webhooks:manage"
+	// KeyScopeUsageRead allows a key to read the upstream usage of its merchant
+	KeyScopeUsageRead = "usage:read"
+	// KeyScopeOrdersRead allows a key to list the orders of its merchant
+	KeyScopeOrdersRead = "orders:read"
+	// KeyScopeOrdersManage allows a key to create, cancel and refund the orders of its merchant, with signed requests
+	KeyScopeOrdersManage = "orders:manage"
+	// KeyScopeCredentialsVerify allows a key to verify and redeem the credentials presented to its merchant
+	KeyScopeCredentialsVerify = "credentials:verify"
+)
+
+// keyScopes are the scopes which can be granted to a key
+var keyScopes = map[string]bool{
+	KeyScopeTransactionsRead:  true,
+	KeyScopeKeysManage:        true,
+	KeyScopeWebhooksManage:    true,
+	KeyScopeUsageRead:         true,
+	KeyScopeOrdersRead:        true,
+	KeyScopeOrdersManage:      true,
+	KeyScopeCredentialsVerify: true,
+}
+
+// Key represents a merchant's keys to validate skus. A key also carries a token, only returned
+// when the key is created, which authenticates merchant requests within the key's scopes
 type Key struct {
-	ID                 string     `json:"id" db:"id"`
-	Name               string     `json:"name" db:"name"`
-	Merchant           string     `json:"merchant" db:"merchant_id"`
-	SecretKey          string     `json:"secretKey"`
-	EncryptedSecretKey string     `json:"-" db:"encrypted_secret_key"`
-	Nonce              string     `json:"-" db:"nonce"`
-	CreatedAt          time.Time  `json:"createdAt" db:"created_at"`
-	Expiry             *time.Time `json:"expiry" db:"expiry"`
+	ID                 string         `json:"id" db:"id"`
+	Name               string         `json:"name" db:"name"`
+	Merchant           string         `json:"merchant" db:"merchant_id"`
+	SecretKey          string         `json:"secretKey"`
+	EncryptedSecretKey string         `json:"-" db:"encrypted_secret_key"`
+	Nonce              string         `json:"-" db:"nonce"`
+	CreatedAt          time.Time      `json:"createdAt" db:"created_at"`
+	Expiry             *time.Time     `json:"expiry" db:"expiry"`
+	RateLimitPerMinute *int           `json:"rateLimitPerMinute,omitempty" db:"rate_limit_per_minute"`
+	RateLimitBurst     int            `json:"rateLimitBurst,omitempty" db:"rate_limit_burst"`
+	DailyQuota         *int           `json:"dailyQuota,omitempty" db:"daily_quota"`
+	Scopes             pq.StringArray `json:"scopes" db:"scopes"`
+	Token              string         `json:"token,omitempty"`
+	TokenHash          *string        `json:"-" db:"token_hash"`
+	LastUsedAt         *time.Time     `json:"lastUsedAt" db:"last_used_at"`
+}
+
+// ValidateKeyScopes checks that only known scopes are requested
+func ValidateKeyScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !keyScopes[scope] {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+	return nil
+}
+
+// APIKey returns the middleware representation of the key
+func (key *Key) APIKey() *middleware.APIKey {
+	return &middleware.APIKey{
+		ID:       key.ID,
+		Merchant: key.Merchant,
+		Scopes:   key.Scopes,
+	}
+}
+
+// RateLimit returns the limits configured for the key, or nil if the key is not limited
+func (key *Key) RateLimit() *middleware.KeyRateLimit {
+	if key.RateLimitPerMinute == nil && key.DailyQuota == nil {
+		return nil
+	}
+	limit := &middleware.KeyRateLimit{Burst: key.RateLimitBurst}
+	if key.RateLimitPerMinute != nil {
+		limit.PerMinute = *key.RateLimitPerMinute
+	}
+	if key.DailyQuota != nil {
+		limit.DailyQuota = *key.DailyQuota
+	}
+	return limit
 }
 
 // InitEncryptionKeys copies the specified encryption key into memory once
@@ -67,6 +140,21 @@ func randomString(n int) (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
+// HashToken hashes a key token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken creates a random token for a key, returning the token and its hash
+func GenerateToken() (token string, tokenHash string, err error) {
+	token, err = randomString(keyLength)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
 // GenerateSecret creates a random key for merchants
 func GenerateSecret() (secret string, nonce string, err error) {
 	unencryptedSecret, err := randomString(keyLength)
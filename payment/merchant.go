@@ -0,0 +1,85 @@
+package payment
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/lib/pq"
+)
+
+var (
+	merchantIDRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,127}$`)
+	// ErrSKUNotAllowed is returned when an order includes a sku its merchant may not sell
+	ErrSKUNotAllowed = errorutils.NewApplicationError("sku_not_allowed", http.StatusBadRequest, "sku is not allowed for merchant", false)
+)
+
+// Merchant is an integration which sells skus and is referenced by MerchantID throughout payment
+type Merchant struct {
+	ID          string         `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	AllowedSKUs pq.StringArray `json:"allowedSkus" db:"allowed_skus"`
+	WebhookURLs pq.StringArray `json:"webhookUrls" db:"webhook_urls"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+// MerchantDetails is a merchant along with its keys and issuer settings
+type MerchantDetails struct {
+	*Merchant
+	Keys   []Key   `json:"keys"`
+	Issuer *Issuer `json:"issuer,omitempty"`
+}
+
+// Validate checks the merchant is well formed, returning the errors by field
+func (merchant *Merchant) Validate() map[string]interface{} {
+	errs := map[string]interface{}{}
+	if !merchantIDRE.MatchString(merchant.ID) {
+		errs["id"] = "must be alphanumeric, optionally with . _ or -"
+	}
+	if merchant.Name == "" {
+		errs["name"] = "value is required"
+	}
+	for _, sku := range merchant.AllowedSKUs {
+		if sku == "" {
+			errs["allowedSkus"] = "skus must not be empty"
+			break
+		}
+	}
+	for _, webhookURL := range merchant.WebhookURLs {
+		if err := validateWebhookURL(webhookURL); err != nil {
+			errs["webhookUrls"] = err.Error()
+			break
+		}
+	}
+	return errs
+}
+
+// validateWebhookURL checks webhooks are absolute, using https outside of local development
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid webhook url: %s", raw)
+	}
+	if u.Scheme != "https" && !(os.Getenv("ENV") == "local" && u.Scheme == "http") {
+		return fmt.Errorf("webhook url must use https: %s", raw)
+	}
+	return nil
+}
+
+// AllowsSKU checks if the merchant may sell the sku, merchants without allowed skus may sell any
+func (merchant *Merchant) AllowsSKU(sku string) bool {
+	if len(merchant.AllowedSKUs) == 0 {
+		return true
+	}
+	for _, allowed := range merchant.AllowedSKUs {
+		if allowed == sku {
+			return true
+		}
+	}
+	return false
+}
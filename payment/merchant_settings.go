@@ -0,0 +1,182 @@
+package payment
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// SKULimits are limits by sku, stored as a JSON object
+type SKULimits map[string]int
+
+// Scan the src sql type into the passed SKULimits
+func (limits *SKULimits) Scan(src interface{}) error {
+	if src == nil {
+		*limits = nil
+		return nil
+	}
+	var jt types.JSONText
+	if err := jt.Scan(src); err != nil {
+		return err
+	}
+	return jt.Unmarshal(limits)
+}
+
+// Value the driver.Value representation, null when there are no limits
+func (limits SKULimits) Value() (driver.Value, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]int(limits))
+	if err != nil {
+		return nil, err
+	}
+	return types.JSONText(data).Value()
+}
+
+// MerchantSettings are the limits operators configure for a merchant, unset limits take the defaults
+type MerchantSettings struct {
+	MerchantID string `json:"merchantId" db:"merchant_id"`
+	// MaxTokensPerIssuer is how many credentials each of the merchant's issuers may sign, read as issuers
+	// are created or rotated
+	MaxTokensPerIssuer *int `json:"maxTokensPerIssuer" db:"max_tokens_per_issuer"`
+	// SKUMaxTokensPerIssuer overrides MaxTokensPerIssuer for the issuers of some of the merchant's skus
+	SKUMaxTokensPerIssuer SKULimits `json:"skuMaxTokensPerIssuer,omitempty" db:"sku_max_tokens_per_issuer"`
+	// CredBufferSize is the most blinded credentials signed for an item in one submission
+	CredBufferSize *int `json:"credBufferSize" db:"cred_buffer_size"`
+	// RateLimitPerMinute is how many credential signing and redemption requests the merchant takes each minute
+	RateLimitPerMinute *int `json:"rateLimitPerMinute" db:"rate_limit_per_minute"`
+	// RateLimitBurst is how many requests the merchant takes in excess of its rate
+	RateLimitBurst *int `json:"rateLimitBurst" db:"rate_limit_burst"`
+	// IPRateLimitPerMinute is how many of those requests each IP address makes each minute
+	IPRateLimitPerMinute *int `json:"ipRateLimitPerMinute" db:"ip_rate_limit_per_minute"`
+	// IPRateLimitBurst is how many requests each IP address makes in excess of its rate
+	IPRateLimitBurst *int `json:"ipRateLimitBurst" db:"ip_rate_limit_burst"`
+	// OrderExpiryMinutes is how long the merchant's unpaid orders last without changing before they expire
+	OrderExpiryMinutes *int      `json:"orderExpiryMinutes" db:"order_expiry_minutes"`
+	UpdatedAt          time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Validate checks the limits are positive, returning the errors by field
+func (settings *MerchantSettings) Validate() map[string]interface{} {
+	errs := map[string]interface{}{}
+	if settings.MaxTokensPerIssuer != nil && *settings.MaxTokensPerIssuer < 1 {
+		errs["maxTokensPerIssuer"] = "must be positive"
+	}
+	for sku, maxTokens := range settings.SKUMaxTokensPerIssuer {
+		if sku == "" || maxTokens < 1 {
+			errs["skuMaxTokensPerIssuer"] = "must be positive, by sku"
+		}
+	}
+	if settings.CredBufferSize != nil && *settings.CredBufferSize < 1 {
+		errs["credBufferSize"] = "must be positive"
+	}
+	if settings.RateLimitPerMinute != nil && *settings.RateLimitPerMinute < 1 {
+		errs["rateLimitPerMinute"] = "must be positive"
+	}
+	if settings.RateLimitBurst != nil && *settings.RateLimitBurst < 0 {
+		errs["rateLimitBurst"] = "must not be negative"
+	}
+	if settings.IPRateLimitPerMinute != nil && *settings.IPRateLimitPerMinute < 1 {
+		errs["ipRateLimitPerMinute"] = "must be positive"
+	}
+	if settings.IPRateLimitBurst != nil && *settings.IPRateLimitBurst < 0 {
+		errs["ipRateLimitBurst"] = "must not be negative"
+	}
+	if settings.OrderExpiryMinutes != nil && *settings.OrderExpiryMinutes < 1 {
+		errs["orderExpiryMinutes"] = "must be positive"
+	}
+	return errs
+}
+
+// maxTokensPerIssuer is the token cap of the issuers of the merchant's sku
+func (settings *MerchantSettings) maxTokensPerIssuer(sku string) int {
+	if settings == nil {
+		return defaultMaxTokensPerIssuer
+	}
+	if maxTokens, ok := settings.SKUMaxTokensPerIssuer[sku]; ok {
+		return maxTokens
+	}
+	if settings.MaxTokensPerIssuer == nil {
+		return defaultMaxTokensPerIssuer
+	}
+	return *settings.MaxTokensPerIssuer
+}
+
+// limitCreds truncates blinded credentials submitted for an item to the merchant's buffer size
+func (settings *MerchantSettings) limitCreds(blindedCreds []string) []string {
+	if settings == nil || settings.CredBufferSize == nil || len(blindedCreds) <= *settings.CredBufferSize {
+		return blindedCreds
+	}
+	return blindedCreds[:*settings.CredBufferSize]
+}
+
+// getMerchantSettings returns the settings of the merchant of an issuer, nil when it has none
+func (service *Service) getMerchantSettings(ctx context.Context, issuerID string) (*MerchantSettings, error) {
+	merchantID, _, err := decodeIssuerID(issuerID)
+	if err != nil {
+		return nil, err
+	}
+	return service.Datastore.GetMerchantSettings(ctx, merchantID)
+}
+
+// GetMerchantSettings is the handler for the limits of a merchant, with the defaults of those not set
+func GetMerchantSettings(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		merchantID := chi.URLParam(r, "merchantID")
+		settings, err := service.Datastore.GetMerchantSettings(r.Context(), merchantID)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant settings", http.StatusInternalServerError)
+		}
+		if settings == nil {
+			settings = &MerchantSettings{MerchantID: merchantID}
+		}
+		if settings.MaxTokensPerIssuer == nil {
+			maxTokens := defaultMaxTokensPerIssuer
+			settings.MaxTokensPerIssuer = &maxTokens
+		}
+		settings.withRateLimitDefaults()
+		return handlers.RenderContent(r.Context(), settings, w, http.StatusOK)
+	})
+}
+
+// UpdateMerchantSettings is the handler for setting the limits of a merchant, limits left out are reset to
+// their defaults
+func UpdateMerchantSettings(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var settings MerchantSettings
+		if err := requestutils.ReadJSON(r.Body, &settings); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		if errs := settings.Validate(); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
+		settings.MerchantID = chi.URLParam(r, "merchantID")
+
+		merchant, err := service.Datastore.GetMerchant(r.Context(), settings.MerchantID)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant", http.StatusInternalServerError)
+		}
+		if merchant == nil {
+			return &handlers.AppError{
+				Message: "Merchant not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "merchant_settings", settings.MerchantID)
+
+		updated, err := service.Datastore.UpsertMerchantSettings(r.Context(), &settings)
+		if err != nil {
+			return handlers.WrapError(err, "Error updating merchant settings", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), updated, w, http.StatusOK)
+	})
+}
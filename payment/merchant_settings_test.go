@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerchantSettingsValidate(t *testing.T) {
+	zero, positive := 0, 10
+	assert.Empty(t, (&MerchantSettings{}).Validate())
+	assert.Empty(t, (&MerchantSettings{MaxTokensPerIssuer: &positive, CredBufferSize: &positive}).Validate())
+	assert.Contains(t, (&MerchantSettings{MaxTokensPerIssuer: &zero}).Validate(), "maxTokensPerIssuer")
+	assert.Contains(t, (&MerchantSettings{CredBufferSize: &zero}).Validate(), "credBufferSize")
+	assert.Empty(t, (&MerchantSettings{RateLimitPerMinute: &positive, RateLimitBurst: &zero, IPRateLimitBurst: &zero}).Validate())
+	assert.Contains(t, (&MerchantSettings{IPRateLimitPerMinute: &zero}).Validate(), "ipRateLimitPerMinute")
+	assert.Empty(t, (&MerchantSettings{SKUMaxTokensPerIssuer: SKULimits{"brave-vpn-premium": 10}}).Validate())
+	assert.Contains(t, (&MerchantSettings{SKUMaxTokensPerIssuer: SKULimits{"brave-vpn-premium": 0}}).Validate(), "skuMaxTokensPerIssuer")
+}
+
+func TestMerchantSettingsLimits(t *testing.T) {
+	var unset *MerchantSettings
+	assert.Equal(t, defaultMaxTokensPerIssuer, unset.maxTokensPerIssuer(""))
+	assert.Equal(t, []string{"a", "b", "c"}, unset.limitCreds([]string{"a", "b", "c"}))
+
+	maxTokens, bufferSize := 100, 2
+	settings := &MerchantSettings{MaxTokensPerIssuer: &maxTokens, CredBufferSize: &bufferSize}
+	assert.Equal(t, 100, settings.maxTokensPerIssuer(""))
+	assert.Equal(t, []string{"a", "b"}, settings.limitCreds([]string{"a", "b", "c"}))
+	assert.Equal(t, []string{"a"}, settings.limitCreds([]string{"a"}))
+
+	// each sku's issuers may have their own cap
+	settings.SKUMaxTokensPerIssuer = SKULimits{"brave-vpn-premium": 5}
+	issuerID, err := encodeIssuerID("brave.com", "brave-vpn-premium")
+	require.NoError(t, err)
+	vpn := &Issuer{MerchantID: issuerID, Version: 2}
+	assert.Equal(t, "brave-vpn-premium", vpn.SKU())
+	assert.Equal(t, 5, settings.maxTokensPerIssuer(vpn.SKU()))
+	assert.Equal(t, 100, settings.maxTokensPerIssuer("brave-talk-premium"))
+	assert.Equal(t, "", (&Issuer{MerchantID: "brave.com"}).SKU())
+}
+
+func TestSKULimitsValue(t *testing.T) {
+	value, err := SKULimits{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value, "no limits are stored as null")
+
+	value, err = SKULimits{"brave-vpn-premium": 5}.Value()
+	require.NoError(t, err)
+	var limits SKULimits
+	require.NoError(t, limits.Scan(value))
+	assert.Equal(t, SKULimits{"brave-vpn-premium": 5}, limits)
+	require.NoError(t, limits.Scan(nil))
+	assert.Nil(t, limits)
+}
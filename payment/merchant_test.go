@@ -0,0 +1,127 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerchantValidate(t *testing.T) {
+	merchant := &Merchant{
+		ID:          "brave.com",
+		Name:        "Brave",
+		AllowedSKUs: []string{"brave-vpn-premium"},
+		WebhookURLs: []string{"https://brave.com/webhook"},
+	}
+	assert.Empty(t, merchant.Validate())
+
+	merchant = &Merchant{
+		ID:          "bad id",
+		WebhookURLs: []string{"http://brave.com/webhook"},
+	}
+	errs := merchant.Validate()
+	assert.Contains(t, errs, "id")
+	assert.Contains(t, errs, "name")
+	assert.Contains(t, errs, "webhookUrls", "webhooks must use https")
+}
+
+func TestMerchantAllowsSKU(t *testing.T) {
+	merchant := &Merchant{}
+	assert.True(t, merchant.AllowsSKU("anything"), "merchants without allowed skus may sell any")
+
+	merchant.AllowedSKUs = []string{"brave-vpn-premium"}
+	assert.True(t, merchant.AllowsSKU("brave-vpn-premium"))
+	assert.False(t, merchant.AllowsSKU("user-wallet-vote"))
+}
+
+func TestScopedTokenRouteGroups(t *testing.T) {
+	oldEnv := os.Getenv("ENV")
+	defer func() { _ = os.Setenv("ENV", oldEnv) }()
+	require.NoError(t, os.Setenv("ENV", "test"))
+
+	tokens, err := middleware.NewScopedTokens([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	store, err := middleware.NewRateLimitStore(context.Background(), "")
+	require.NoError(t, err)
+	service := &Service{Datastore: newFakeDatastore(), rateLimitStore: store, scopedTokens: tokens}
+
+	r := chi.NewRouter()
+	r.Mount("/v1/merchants", MerchantRouter(service))
+	r.Mount("/v1/admin/vouchers", VoucherRouter(service))
+	router := middleware.BearerToken(r)
+
+	issue := func(role, merchant string, scopes ...string) string {
+		token, err := tokens.Issue(&appctx.Principal{Subject: "ops", Role: role, Merchant: merchant, Scopes: scopes}, time.Minute)
+		require.NoError(t, err)
+		return token
+	}
+	get := func(path, token string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	operator := issue(appctx.RoleService, "", ScopeMerchantsManage)
+	assert.Equal(t, http.StatusOK, get("/v1/merchants/", operator))
+	assert.Equal(t, http.StatusForbidden, get("/v1/merchants/", issue(appctx.RoleService, "", ScopeAuditRead)),
+		"operator routes require the scope")
+	assert.Equal(t, http.StatusForbidden, get("/v1/merchants/", issue(appctx.RoleMerchant, "brave.com", ScopeMerchantsManage)),
+		"merchants are not operators")
+	assert.Equal(t, http.StatusUnauthorized, get("/v1/merchants/", ""))
+
+	assert.Equal(t, http.StatusOK, get("/v1/admin/vouchers/", issue(appctx.RoleAdmin, "")))
+	assert.Equal(t, http.StatusForbidden, get("/v1/admin/vouchers/", operator), "admin routes require the admin role")
+
+	merchant := issue(appctx.RoleMerchant, "brave.com", KeyScopeKeysManage)
+	assert.Equal(t, http.StatusOK, get("/v1/merchants/brave.com/keys", merchant))
+	assert.Equal(t, http.StatusForbidden, get("/v1/merchants/brave.software/keys", merchant),
+		"merchants only act for their own merchant")
+	assert.Equal(t, http.StatusForbidden, get("/v1/merchants/brave.com/keys", issue(appctx.RoleMerchant, "brave.com", KeyScopeUsageRead)))
+	assert.Equal(t, http.StatusForbidden, get("/v1/merchants/brave.com/keys", "not-a-token"))
+}
+
+func TestScopedTokenMerchantSignedRoutes(t *testing.T) {
+	oldEnv := os.Getenv("ENV")
+	defer func() { _ = os.Setenv("ENV", oldEnv) }()
+	require.NoError(t, os.Setenv("ENV", "test"))
+
+	tokens, err := middleware.NewScopedTokens([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	store, err := middleware.NewRateLimitStore(context.Background(), "")
+	require.NoError(t, err)
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com", Status: "paid", Currency: "BAT", TotalPrice: decimal.New(5, 0)})
+	service := &Service{Datastore: ds, rateLimitStore: store, scopedTokens: tokens, orderWatchers: newOrderNotifier()}
+
+	r := chi.NewRouter()
+	r.Mount("/v1/merchants", MerchantRouter(service))
+	router := middleware.BearerToken(r)
+
+	refund := func(merchant string, scopes ...string) int {
+		token, err := tokens.Issue(&appctx.Principal{Subject: "ops", Role: appctx.RoleMerchant, Merchant: merchant, Scopes: scopes}, time.Minute)
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/v1/merchants/brave.com/orders/"+order.ID.String()+"/refund", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, refund("brave.software", KeyScopeOrdersManage), "merchants only act for their own merchant")
+	assert.Equal(t, http.StatusForbidden, refund("brave.com", KeyScopeOrdersRead))
+	assert.Equal(t, "paid", order.Status)
+	assert.Equal(t, http.StatusOK, refund("brave.com", KeyScopeOrdersManage), "scoped tokens are not signed as api keys are")
+	assert.Equal(t, OrderLogRefunded, order.Status)
+}
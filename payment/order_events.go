@@ -0,0 +1,480 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// OrderLogCreated is appended when an order is placed, carrying its merchant, currency and items
+	OrderLogCreated = "created"
+	// OrderLogPriced is appended when the total price of an order is set
+	OrderLogPriced = "priced"
+	// OrderLogPaid is appended when an order's transactions cover its total price
+	OrderLogPaid = "paid"
+	// OrderLogCredsRequested is appended when blinded credentials are submitted for an item
+	OrderLogCredsRequested = "creds_requested"
+	// OrderLogCredsSigned is appended when the signing worker has signed an item's credentials
+	OrderLogCredsSigned = "creds_signed"
+	// OrderLogCredsDeleted is appended when an order's credentials are deleted so they can be resubmitted
+	OrderLogCredsDeleted = "creds_deleted"
+	// OrderLogRefunded is appended when an order is refunded
+	OrderLogRefunded = "refunded"
+	// OrderLogCanceled is appended when an unpaid order is canceled, which is final
+	OrderLogCanceled = "canceled"
+	// OrderLogExpired is appended when an unpaid order expires, until it is reactivated
+	OrderLogExpired = "expired"
+	// OrderLogStatusChanged is appended when an order moves to any other status
+	OrderLogStatusChanged = "status_changed"
+
+	orderEventDispatchBatch = 100
+)
+
+// orderLogWebhooks are the events merchants are sent webhooks for
+var orderLogWebhooks = map[string]string{
+	OrderLogPaid:        "order.paid",
+	OrderLogRefunded:    "order.refunded",
+	OrderLogCanceled:    "order.canceled",
+	OrderLogExpired:     "order.expired",
+	OrderLogCredsSigned: "order.creds.signed",
+}
+
+var (
+	// ErrOrderCanceled is returned when changing the status of a canceled order
+	ErrOrderCanceled = errorutils.NewApplicationError("order_canceled", http.StatusConflict, "order is canceled", false)
+	// ErrOrderNotCancelable is returned when canceling an order which is no longer pending
+	ErrOrderNotCancelable = errorutils.NewApplicationError("order_not_cancelable", http.StatusConflict, "only pending orders can be canceled", false)
+	// ErrOrderExpired is returned when changing the status of an expired order other than by reactivating it
+	ErrOrderExpired = errorutils.NewApplicationError("order_expired", http.StatusConflict, "order has expired", false)
+	// ErrOrderNotExpired is returned when reactivating an order which has not expired
+	ErrOrderNotExpired = errorutils.NewApplicationError("order_not_expired", http.StatusConflict, "only expired orders can be reactivated", false)
+	// ErrOrderNotFound is returned for orders which do not exist, or which the caller may not see
+	ErrOrderNotFound = errorutils.NewApplicationError("order_not_found", http.StatusNotFound, "order not found", false)
+)
+
+// OrderLogEvent is a change to an order, as appended to its log. The orders row is the projection of the
+// order's events, advanced in the same transaction as each is appended
+type OrderLogEvent struct {
+	ID           uuid.UUID       `json:"id" db:"id"`
+	OrderID      uuid.UUID       `json:"orderId" db:"order_id"`
+	Sequence     int             `json:"sequence" db:"sequence"`
+	Type         string          `json:"type" db:"type"`
+	Payload      json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt    time.Time       `json:"createdAt" db:"created_at"`
+	DispatchedAt *time.Time      `json:"dispatchedAt" db:"dispatched_at"`
+}
+
+type orderItemPayload struct {
+	ID             uuid.UUID       `json:"id"`
+	SKU            string          `json:"sku"`
+	Currency       string          `json:"currency"`
+	Quantity       int             `json:"quantity"`
+	Price          decimal.Decimal `json:"price"`
+	Subtotal       decimal.Decimal `json:"subtotal"`
+	Location       *string         `json:"location"`
+	Description    *string         `json:"description"`
+	CredentialType string          `json:"credentialType"`
+}
+
+type orderCreatedPayload struct {
+	MerchantID string             `json:"merchantId"`
+	Currency   string             `json:"currency"`
+	Location   *string            `json:"location"`
+	Status     string             `json:"status"`
+	Items      []orderItemPayload `json:"items"`
+	// TotalPrice and Backfilled are only set on the events of orders placed before the log
+	TotalPrice *decimal.Decimal `json:"totalPrice,omitempty"`
+	Backfilled bool             `json:"backfilled,omitempty"`
+}
+
+type orderPricedPayload struct {
+	TotalPrice    decimal.Decimal  `json:"totalPrice"`
+	Currency      string           `json:"currency"`
+	ExchangeRate  *decimal.Decimal `json:"exchangeRate,omitempty"`
+	BATTotalPrice *decimal.Decimal `json:"batTotalPrice,omitempty"`
+	RatedAt       *time.Time       `json:"ratedAt,omitempty"`
+	// Voucher and Discount are set when a voucher discounted the total price
+	Voucher  *string          `json:"voucher,omitempty"`
+	Discount *decimal.Decimal `json:"discount,omitempty"`
+}
+
+type orderStatusPayload struct {
+	Status   string `json:"status"`
+	Previous string `json:"previous"`
+}
+
+type orderCredsPayload struct {
+	ItemID   uuid.UUID  `json:"itemId"`
+	IssuerID *uuid.UUID `json:"issuerId,omitempty"`
+	Count    int        `json:"count"`
+}
+
+func nullStringPtr(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func equalDecimals(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func formatDecimal(d *decimal.Decimal) string {
+	if d == nil {
+		return "unset"
+	}
+	return d.String()
+}
+
+func newOrderCreatedPayload(order *Order) orderCreatedPayload {
+	payload := orderCreatedPayload{
+		MerchantID: order.MerchantID,
+		Currency:   order.Currency,
+		Status:     order.Status,
+		Items:      []orderItemPayload{},
+	}
+	if order.Location.Valid {
+		payload.Location = &order.Location.String
+	}
+	for _, item := range order.Items {
+		p := orderItemPayload{
+			ID:             item.ID,
+			SKU:            item.SKU,
+			Currency:       item.Currency,
+			Quantity:       item.Quantity,
+			Price:          item.Price,
+			Subtotal:       item.Subtotal,
+			CredentialType: item.CredentialType,
+		}
+		if item.Location.Valid {
+			location := item.Location.String
+			p.Location = &location
+		}
+		if item.Description.Valid {
+			description := item.Description.String
+			p.Description = &description
+		}
+		payload.Items = append(payload.Items, p)
+	}
+	return payload
+}
+
+// orderStatusEvent is the event appended when an order moves to the status
+func orderStatusEvent(status string) string {
+	switch status {
+	case OrderLogPaid, OrderLogRefunded, OrderLogCanceled, OrderLogExpired:
+		return status
+	}
+	return OrderLogStatusChanged
+}
+
+// appendOrderEvent appends an event to the log of the order within the transaction, advancing the sequence
+// of its row. The row stays locked until the transaction ends, so appends to an order are serialized
+func appendOrderEvent(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID, eventType string, payload interface{}) (*OrderLogEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order event: %w", err)
+	}
+	// the actor is recorded with the order's history by the trigger on order_events
+	if actor := orderActor(ctx); actor != "" {
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('payment.actor', $1, true)`, actor); err != nil {
+			return nil, fmt.Errorf("failed to set the actor of order %s: %w", orderID, err)
+		}
+	}
+	var sequence int
+	err = tx.GetContext(ctx, &sequence, `
+			UPDATE orders SET event_sequence = event_sequence + 1 WHERE id = $1
+			RETURNING event_sequence
+		`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance the event sequence of order %s: %w", orderID, err)
+	}
+	var event OrderLogEvent
+	err = tx.GetContext(ctx, &event, `
+			INSERT INTO order_events (order_id, sequence, type, payload)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, order_id, sequence, type, payload, created_at, dispatched_at
+		`, orderID, sequence, eventType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append order event: %w", err)
+	}
+	if _, ok := orderLogTopics[eventType]; ok {
+		// the event is published from the outbox, which commits or rolls back with the change it records
+		_, err = tx.ExecContext(ctx, `INSERT INTO order_event_outbox (event_id) VALUES ($1)`, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add order event to the outbox: %w", err)
+		}
+	}
+	return &event, nil
+}
+
+// OrderProjection is the state of an order rebuilt from its log
+type OrderProjection struct {
+	Order    Order `json:"order"`
+	Sequence int   `json:"sequence"`
+	// Credentials are requested or signed, keyed by item id
+	Credentials map[string]string `json:"credentials"`
+}
+
+// ProjectOrder replays the events of an order, in sequence, into its state
+func ProjectOrder(events []OrderLogEvent) (*OrderProjection, error) {
+	if len(events) == 0 || events[0].Type != OrderLogCreated {
+		return nil, fmt.Errorf("the log of an order must start with its %s event", OrderLogCreated)
+	}
+	projection := &OrderProjection{Credentials: map[string]string{}}
+	order := &projection.Order
+	for i, event := range events {
+		if event.Sequence != i+1 {
+			return nil, fmt.Errorf("order %s is missing event %d of its log", event.OrderID, i+1)
+		}
+		projection.Sequence = event.Sequence
+
+		switch event.Type {
+		case OrderLogCreated:
+			var payload orderCreatedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			order.ID = event.OrderID
+			order.CreatedAt, order.UpdatedAt = event.CreatedAt, event.CreatedAt
+			order.MerchantID, order.Currency, order.Status = payload.MerchantID, payload.Currency, payload.Status
+			order.Location.NullString = nullStringPtr(payload.Location)
+			if payload.TotalPrice != nil {
+				order.TotalPrice = *payload.TotalPrice
+			}
+			order.Items = make([]OrderItem, len(payload.Items))
+			for j, item := range payload.Items {
+				order.Items[j] = OrderItem{
+					ID:             item.ID,
+					OrderID:        event.OrderID,
+					SKU:            item.SKU,
+					Currency:       item.Currency,
+					Quantity:       item.Quantity,
+					Price:          item.Price,
+					Subtotal:       item.Subtotal,
+					CredentialType: item.CredentialType,
+				}
+				order.Items[j].Location.NullString = nullStringPtr(item.Location)
+				order.Items[j].Description.NullString = nullStringPtr(item.Description)
+			}
+		case OrderLogPriced:
+			var payload orderPricedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			order.TotalPrice = payload.TotalPrice
+			order.ExchangeRate, order.BATTotalPrice, order.RatedAt = payload.ExchangeRate, payload.BATTotalPrice, payload.RatedAt
+		case OrderLogPaid, OrderLogRefunded, OrderLogCanceled, OrderLogExpired, OrderLogStatusChanged:
+			var payload orderStatusPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			order.Status = payload.Status
+			order.UpdatedAt = event.CreatedAt
+		case OrderLogCredsRequested, OrderLogCredsSigned:
+			var payload orderCredsPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			projection.Credentials[payload.ItemID.String()] = map[string]string{
+				OrderLogCredsRequested: "requested",
+				OrderLogCredsSigned:    "signed",
+			}[event.Type]
+		case OrderLogCredsDeleted:
+			projection.Credentials = map[string]string{}
+		default:
+			return nil, fmt.Errorf("event %d has unknown type %s", event.Sequence, event.Type)
+		}
+	}
+	return projection, nil
+}
+
+// Drift lists the fields of the order's row which differ from its projection, empty when they agree
+func (projection *OrderProjection) Drift(order *Order) []string {
+	drift := []string{}
+	if order.Status != projection.Order.Status {
+		drift = append(drift, fmt.Sprintf("status is %s, its events say %s", order.Status, projection.Order.Status))
+	}
+	if !order.TotalPrice.Equal(projection.Order.TotalPrice) {
+		drift = append(drift, fmt.Sprintf("totalPrice is %s, its events say %s", order.TotalPrice, projection.Order.TotalPrice))
+	}
+	if !equalDecimals(order.BATTotalPrice, projection.Order.BATTotalPrice) {
+		drift = append(drift, fmt.Sprintf("batTotalPrice is %s, its events say %s",
+			formatDecimal(order.BATTotalPrice), formatDecimal(projection.Order.BATTotalPrice)))
+	}
+	if order.Currency != projection.Order.Currency {
+		drift = append(drift, fmt.Sprintf("currency is %s, its events say %s", order.Currency, projection.Order.Currency))
+	}
+	if order.MerchantID != projection.Order.MerchantID {
+		drift = append(drift, fmt.Sprintf("merchantId is %s, its events say %s", order.MerchantID, projection.Order.MerchantID))
+	}
+	if len(order.Items) != len(projection.Order.Items) {
+		drift = append(drift, fmt.Sprintf("has %d items, its events say %d", len(order.Items), len(projection.Order.Items)))
+	}
+	return drift
+}
+
+// OrderLog is the log of an order, its state replayed from the log and how its row differs from it
+type OrderLog struct {
+	Events     []OrderLogEvent  `json:"events"`
+	Projection *OrderProjection `json:"projection"`
+	Drift      []string         `json:"drift"`
+}
+
+// StuckOrder is an order whose credentials were requested but have not been signed
+type StuckOrder struct {
+	OrderID     uuid.UUID `json:"orderId" db:"order_id"`
+	ItemID      uuid.UUID `json:"itemId" db:"item_id"`
+	RequestedAt time.Time `json:"requestedAt" db:"requested_at"`
+}
+
+// GetOrderLog returns the log of an order replayed against its row, or nil if there is no such order
+func (s *Service) GetOrderLog(ctx context.Context, orderID uuid.UUID) (*OrderLog, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	events, err := s.Datastore.GetOrderEvents(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := ProjectOrder(events)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderLog{Events: events, Projection: projection, Drift: projection.Drift(order)}, nil
+}
+
+// ReplayOrder rebuilds the row of an order from its log, returning the rebuilt log
+func (s *Service) ReplayOrder(ctx context.Context, orderID uuid.UUID) (*OrderLog, error) {
+	log, err := s.GetOrderLog(ctx, orderID)
+	if err != nil || log == nil {
+		return log, err
+	}
+	if err := s.Datastore.RebuildOrderProjection(ctx, log.Projection); err != nil {
+		return nil, err
+	}
+	s.NotifyOrderChanged(orderID)
+	return s.GetOrderLog(ctx, orderID)
+}
+
+// RunNextOrderEventDispatch sends the webhooks of the order events appended since the last run, an event
+// is only marked dispatched once its webhooks are queued so none are lost if the server stops
+func (s *Service) RunNextOrderEventDispatch(ctx context.Context) (bool, error) {
+	n, err := s.Datastore.DispatchOrderEvents(ctx, orderEventDispatchBatch, s.dispatchOrderEvent)
+	return n > 0, err
+}
+
+// dispatchOrderEvent queues the webhook of an event for the order's merchant, when merchants are sent one
+func (s *Service) dispatchOrderEvent(ctx context.Context, event OrderLogEvent) error {
+	webhook, ok := orderLogWebhooks[event.Type]
+	if !ok || s.deliveries == nil {
+		return nil
+	}
+	order, err := s.Datastore.GetOrder(event.OrderID)
+	if err != nil || order == nil {
+		return err
+	}
+	body := map[string]interface{}{
+		"event":    webhook,
+		"orderId":  order.ID,
+		"sequence": event.Sequence,
+	}
+	if event.Type == OrderLogCredsSigned {
+		var payload orderCredsPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode order event: %w", err)
+		}
+		body["itemId"], body["count"] = payload.ItemID, payload.Count
+	} else {
+		var payload orderStatusPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode order event: %w", err)
+		}
+		body["status"] = payload.Status
+	}
+	_, err = s.QueueWebhook(ctx, order.MerchantID, webhook, body)
+	return err
+}
+
+// OrderEventRouter lets operators replay the logs of orders, read their history and find the orders stuck
+// waiting for signing
+func OrderEventRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/stuck", GetStuckOrders(service))
+	r.Method("GET", "/{orderID}", GetOrderLog(service))
+	r.Method("GET", "/{orderID}/history", GetOrderHistory(service))
+	r.Method("POST", "/{orderID}/replay", ReplayOrder(service))
+	return r
+}
+
+// GetOrderLog is the handler for an order's events, its replayed state and drift from its row
+func GetOrderLog(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+		log, err := service.GetOrderLog(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting order events", http.StatusInternalServerError)
+		}
+		if log == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+		return handlers.RenderContent(r.Context(), log, w, http.StatusOK)
+	})
+}
+
+// ReplayOrder is the handler for rebuilding the row of an order from its events
+func ReplayOrder(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+		log, err := service.ReplayOrder(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error replaying order", http.StatusInternalServerError)
+		}
+		if log == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+		return handlers.RenderContent(r.Context(), log, w, http.StatusOK)
+	})
+}
+
+// GetStuckOrders is the handler for the orders whose credentials have waited longer than olderThan to be signed
+func GetStuckOrders(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		olderThan := 10 * time.Minute
+		if v := r.URL.Query().Get("olderThan"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return handlers.ValidationError("request", map[string]interface{}{"olderThan": "must be a duration such as 10m"})
+			}
+			olderThan = d
+		}
+		stuck, err := service.Datastore.GetStuckOrders(r.Context(), time.Now().Add(-olderThan), 100)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting stuck orders", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), stuck, w, http.StatusOK)
+	})
+}
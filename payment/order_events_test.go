@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/notification"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderLogEvent(t *testing.T, orderID uuid.UUID, sequence int, eventType string, payload interface{}) OrderLogEvent {
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return OrderLogEvent{
+		ID:        uuid.NewV4(),
+		OrderID:   orderID,
+		Sequence:  sequence,
+		Type:      eventType,
+		Payload:   data,
+		CreatedAt: time.Now().Add(time.Duration(sequence) * time.Minute),
+	}
+}
+
+func TestProjectOrder(t *testing.T) {
+	orderID, itemID := uuid.NewV4(), uuid.NewV4()
+	order := &Order{
+		MerchantID: "brave.com",
+		Currency:   "BAT",
+		Status:     "pending",
+		Items: []OrderItem{{
+			ID:             itemID,
+			SKU:            "brave-vpn",
+			Currency:       "BAT",
+			Quantity:       2,
+			Price:          decimal.NewFromFloat(0.25),
+			Subtotal:       decimal.NewFromFloat(0.5),
+			CredentialType: "single-use",
+		}},
+	}
+	order.Items[0].Description.String, order.Items[0].Description.Valid = "vpn", true
+
+	events := []OrderLogEvent{
+		orderLogEvent(t, orderID, 1, OrderLogCreated, newOrderCreatedPayload(order)),
+		orderLogEvent(t, orderID, 2, OrderLogPriced, orderPricedPayload{TotalPrice: decimal.NewFromFloat(0.5), Currency: "BAT"}),
+		orderLogEvent(t, orderID, 3, OrderLogPaid, orderStatusPayload{Status: "paid", Previous: "pending"}),
+		orderLogEvent(t, orderID, 4, OrderLogCredsRequested, orderCredsPayload{ItemID: itemID, Count: 2}),
+	}
+
+	projection, err := ProjectOrder(events)
+	require.NoError(t, err)
+	assert.Equal(t, 4, projection.Sequence)
+	assert.Equal(t, "paid", projection.Order.Status)
+	assert.Equal(t, events[2].CreatedAt, projection.Order.UpdatedAt)
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(projection.Order.TotalPrice))
+	require.Len(t, projection.Order.Items, 1)
+	assert.Equal(t, "brave-vpn", projection.Order.Items[0].SKU)
+	assert.Equal(t, "vpn", projection.Order.Items[0].Description.String)
+	assert.False(t, projection.Order.Items[0].Location.Valid)
+	assert.Equal(t, map[string]string{itemID.String(): "requested"}, projection.Credentials)
+
+	row := projection.Order
+	assert.Empty(t, projection.Drift(&row))
+	row.Status = "pending"
+	assert.Equal(t, []string{"status is pending, its events say paid"}, projection.Drift(&row))
+
+	events = append(events, orderLogEvent(t, orderID, 5, OrderLogCredsSigned, orderCredsPayload{ItemID: itemID, Count: 2}))
+	projection, err = ProjectOrder(events)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{itemID.String(): "signed"}, projection.Credentials)
+
+	_, err = ProjectOrder(append(events[:2:2], events[3]))
+	assert.EqualError(t, err, "order "+orderID.String()+" is missing event 3 of its log")
+	_, err = ProjectOrder(events[1:])
+	assert.Error(t, err)
+}
+
+func TestUpdateOrderAppendsEvent(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID, eventID := uuid.NewV4(), uuid.NewV4()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+	mock.ExpectExec(`UPDATE orders set status = (.+)`).WithArgs("paid", orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE orders SET event_sequence = event_sequence \+ 1`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"event_sequence"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO order_events`).
+		WithArgs(orderID, 3, OrderLogPaid, []byte(`{"status":"paid","previous":"pending"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "sequence", "type", "payload", "created_at", "dispatched_at"}).
+			AddRow(eventID, orderID, 3, OrderLogPaid, []byte(`{}`), time.Now(), nil))
+	mock.ExpectExec(`INSERT INTO order_event_outbox`).WithArgs(eventID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, pg.UpdateOrder(context.Background(), orderID, "paid"))
+
+	// paying an order which is already paid is not a change
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectRollback()
+	require.NoError(t, pg.UpdateOrder(context.Background(), orderID, "paid"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderCanceled(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID := uuid.NewV4()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderNotCancelable, pg.UpdateOrder(context.Background(), orderID, OrderLogCanceled))
+
+	// a canceled order is never paid, even by a transaction which completes it afterwards
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(OrderLogCanceled))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderCanceled, pg.UpdateOrder(context.Background(), orderID, "paid"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatchOrderEvent(t *testing.T) {
+	ctx := context.Background()
+	itemID := uuid.NewV4()
+	ds := newFakeDatastore()
+	orderID := ds.addOrder(Order{MerchantID: "brave.com"}).ID
+	ds.merchants["brave.com"] = &Merchant{ID: "brave.com", WebhookURLs: []string{"https://brave.com/webhooks"}}
+	store := &insertOnlyQueueStore{}
+	service := &Service{Datastore: ds}
+	service.UseDeliveryQueue(notification.NewQueue(store))
+
+	events := []OrderLogEvent{
+		orderLogEvent(t, orderID, 1, OrderLogCreated, orderCreatedPayload{MerchantID: "brave.com"}),
+		orderLogEvent(t, orderID, 2, OrderLogCredsSigned, orderCredsPayload{ItemID: itemID, Count: 2}),
+		orderLogEvent(t, orderID, 3, OrderLogCanceled, orderStatusPayload{Status: OrderLogCanceled, Previous: "pending"}),
+	}
+	for _, event := range events {
+		require.NoError(t, service.dispatchOrderEvent(ctx, event))
+	}
+
+	// merchants are not sent webhooks of every event
+	require.Len(t, store.inserted, 2)
+	bodies := make([]map[string]interface{}, len(store.inserted))
+	for i, delivery := range store.inserted {
+		var payload webhookPayload
+		require.NoError(t, json.Unmarshal(delivery.Payload, &payload))
+		require.NoError(t, json.Unmarshal(payload.Body, &bodies[i]))
+		assert.Equal(t, bodies[i]["event"], payload.Event)
+	}
+	assert.Equal(t, "order.creds.signed", bodies[0]["event"])
+	assert.Equal(t, itemID.String(), bodies[0]["itemId"])
+	assert.Equal(t, float64(2), bodies[0]["count"])
+	assert.Equal(t, "order.canceled", bodies[1]["event"])
+	assert.Equal(t, OrderLogCanceled, bodies[1]["status"])
+}
@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+const orderExpiryBatch = 100
+
+// errOrderNotStale is returned when expiring an order which changed since it was found stale
+var errOrderNotStale = errors.New("order is no longer pending")
+
+// defaultOrderExpiry is how long unpaid orders of merchants without an order expiry last, from
+// ORDER_EXPIRY_MINUTES. Orders do not expire by default
+func defaultOrderExpiry() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("ORDER_EXPIRY_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return 0
+}
+
+// ExpireOrders expires the unpaid orders which have not changed for their merchant's order expiry, giving back
+// the uses of their vouchers. Merchants are sent an order.expired webhook and the event is published to the
+// order topic
+func (s *Service) ExpireOrders(ctx context.Context) (bool, error) {
+	orderIDs, err := s.Datastore.GetStaleOrders(ctx, defaultOrderExpiry(), orderExpiryBatch)
+	if err != nil {
+		return false, err
+	}
+
+	expired := 0
+	for _, orderID := range orderIDs {
+		// an order paid since it was found is left alone
+		err := s.Datastore.UpdateOrder(ctx, orderID, OrderLogExpired)
+		if errors.Is(err, errOrderNotStale) {
+			continue
+		}
+		if err != nil {
+			return expired > 0, err
+		}
+		expired++
+		s.NotifyOrderChanged(orderID)
+	}
+
+	if expired > 0 {
+		logger, err := appctx.GetLogger(ctx)
+		if err == nil {
+			logger.Info().Int("expired", expired).Msg("expired stale orders")
+		}
+	}
+	return expired > 0, nil
+}
+
+// ReactivateOrder moves an expired order back to pending, returning the order or nil when there is no such
+// order. The order's voucher is used again, failing if it was used up or has expired since. Payments which
+// arrived while the order was expired pay for it once it is reactivated
+func (s *Service) ReactivateOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if err := s.Datastore.UpdateOrder(ctx, orderID, "pending"); err != nil {
+		return nil, err
+	}
+	s.NotifyOrderChanged(orderID)
+	if err := s.UpdateOrderStatus(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.Datastore.GetOrder(orderID)
+}
+
+// ReactivateOrder is the handler for reactivating an expired order so it can be paid
+func ReactivateOrder(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.ReactivateOrder(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error reactivating the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		return handlers.RenderContent(r.Context(), order, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,128 @@
+package payment
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireOrders(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	stale := ds.addOrder(Order{MerchantID: "brave.com", TotalPrice: decimal.New(1, 0), UpdatedAt: time.Now().Add(-48 * time.Hour)})
+	fresh := ds.addOrder(Order{MerchantID: "brave.com", TotalPrice: decimal.New(1, 0), UpdatedAt: time.Now().Add(-time.Hour)})
+	custom := ds.addOrder(Order{MerchantID: "other.com", TotalPrice: decimal.New(1, 0), UpdatedAt: time.Now().Add(-time.Hour)})
+	expiry := 30
+	ds.settings["other.com"] = MerchantSettings{MerchantID: "other.com", OrderExpiryMinutes: &expiry}
+	service := &Service{Datastore: ds}
+
+	more, err := service.ExpireOrders(ctx)
+	require.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, OrderLogExpired, custom.Status, "merchants may set when their orders expire")
+	assert.Equal(t, "pending", stale.Status, "orders do not expire by default")
+
+	require.NoError(t, os.Setenv("ORDER_EXPIRY_MINUTES", "1440"))
+	defer func() { _ = os.Unsetenv("ORDER_EXPIRY_MINUTES") }()
+	ds.errs["UpdateOrder"] = errOrderNotStale
+	more, err = service.ExpireOrders(ctx)
+	require.NoError(t, err)
+	assert.False(t, more)
+	assert.Equal(t, "pending", stale.Status, "orders paid since they were found are left alone")
+
+	delete(ds.errs, "UpdateOrder")
+	more, err = service.ExpireOrders(ctx)
+	require.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, OrderLogExpired, stale.Status)
+	assert.Equal(t, "pending", fresh.Status)
+
+	more, err = service.ExpireOrders(ctx)
+	require.NoError(t, err)
+	assert.False(t, more)
+}
+
+func TestUpdateOrderExpired(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID, eventID, voucherID := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+
+	// expiring an order gives back the use of its voucher
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+	mock.ExpectExec(`UPDATE vouchers SET uses = uses - 1`).WithArgs(orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE orders set status = (.+)`).WithArgs(OrderLogExpired, orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE orders SET event_sequence = event_sequence \+ 1`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"event_sequence"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO order_events`).
+		WithArgs(orderID, 3, OrderLogExpired, []byte(`{"status":"expired","previous":"pending"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "sequence", "type", "payload", "created_at", "dispatched_at"}).
+			AddRow(eventID, orderID, 3, OrderLogExpired, []byte(`{}`), time.Now(), nil))
+	mock.ExpectExec(`INSERT INTO order_event_outbox`).WithArgs(eventID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, pg.UpdateOrder(context.Background(), orderID, OrderLogExpired))
+
+	// an order paid since it was found stale is not expired
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectRollback()
+	assert.Equal(t, errOrderNotStale, pg.UpdateOrder(context.Background(), orderID, OrderLogExpired))
+
+	// an expired order is reactivated before it is paid
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(OrderLogExpired))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderExpired, pg.UpdateOrder(context.Background(), orderID, "paid"))
+
+	// reactivating an order fails when its voucher was used up in the meantime
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(OrderLogExpired))
+	mock.ExpectQuery(`SELECT voucher_id FROM voucher_redemptions`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"voucher_id"}).AddRow(voucherID))
+	mock.ExpectExec(`UPDATE vouchers SET uses = uses \+ 1`).WithArgs(voucherID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrVoucherInvalid, pg.UpdateOrder(context.Background(), orderID, "pending"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(OrderLogCanceled))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderCanceled, pg.UpdateOrder(context.Background(), orderID, "pending"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetStaleOrders(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	stale := uuid.NewV4()
+
+	mock.ExpectQuery(`SELECT o.id(.+)LEFT JOIN merchant_settings(.+)coalesce\(s.order_expiry_minutes, nullif\(\$1, 0\)\)(.+)LIMIT \$2`).
+		WithArgs(90, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(stale))
+	ids, err := pg.GetStaleOrders(context.Background(), 90*time.Minute, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{stale}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+// OrderHistoryEntry is a transition of an order, who made it and the order before and after. Entries are
+// recorded by the database as each event is appended to the order's log and can never be changed
+type OrderHistoryEntry struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	OrderID    uuid.UUID       `json:"orderId" db:"order_id"`
+	Sequence   int             `json:"sequence" db:"sequence"`
+	Transition string          `json:"transition" db:"transition"`
+	Actor      string          `json:"actor" db:"actor"`
+	Before     json.RawMessage `json:"before" db:"before"`
+	After      json.RawMessage `json:"after" db:"after"`
+	// Details is the payload of the transition's event
+	Details   json.RawMessage `json:"details" db:"payload"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// orderActor identifies who is changing an order, the authenticated caller of an audited request, an api
+// key, another authenticated principal or http signing key, or anonymous for other requests. Outside of a
+// request it is empty and the change is recorded as made by the system
+func orderActor(ctx context.Context) string {
+	if actor := middleware.AuditActor(ctx); actor != "" {
+		return actor
+	}
+	if key, ok := middleware.GetAPIKey(ctx); ok {
+		return "api_key:" + key.ID
+	}
+	if principal, err := appctx.GetPrincipal(ctx); err == nil {
+		return principal.Role + ":" + principal.Subject
+	}
+	if keyID, err := middleware.GetKeyID(ctx); err == nil {
+		return "signing_key:" + keyID
+	}
+	if requestutils.GetRequestID(ctx) != "" {
+		return "anonymous"
+	}
+	return ""
+}
+
+// GetOrderHistory returns the transitions of an order, or nil if there is no such order
+func (s *Service) GetOrderHistory(ctx context.Context, orderID uuid.UUID) ([]OrderHistoryEntry, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	return s.Datastore.GetOrderHistory(ctx, orderID)
+}
+
+// GetOrderHistory is the handler for the transitions of an order
+func GetOrderHistory(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+		history, err := service.GetOrderHistory(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting order history", http.StatusInternalServerError)
+		}
+		if history == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+		return handlers.RenderContent(r.Context(), history, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderActor(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, orderActor(ctx), "changes outside of a request are made by the system")
+
+	requestCtx := context.WithValue(ctx, requestutils.RequestID, "request")
+	assert.Equal(t, "anonymous", orderActor(requestCtx))
+	assert.Equal(t, "signing_key:brave.com", orderActor(middleware.AddKeyID(requestCtx, "brave.com")))
+	assert.Equal(t, "api_key:1", orderActor(middleware.AddAPIKey(requestCtx, &middleware.APIKey{ID: "1", Merchant: "brave.com"})))
+}
+
+func TestAppendOrderEventActor(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID := uuid.NewV4()
+	ctx := middleware.AddAPIKey(context.Background(), &middleware.APIKey{ID: "1", Merchant: "brave.com"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+	mock.ExpectExec(`UPDATE orders set status = (.+)`).WithArgs(OrderLogCanceled, orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT set_config\('payment.actor', (.+), true\)`).WithArgs("api_key:1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`UPDATE orders SET event_sequence = event_sequence \+ 1`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"event_sequence"}).AddRow(2))
+	mock.ExpectQuery(`INSERT INTO order_events`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "sequence", "type", "payload", "created_at", "dispatched_at"}).
+			AddRow(uuid.NewV4(), orderID, 2, OrderLogCanceled, []byte(`{}`), time.Now(), nil))
+	mock.ExpectCommit()
+	require.NoError(t, pg.UpdateOrder(ctx, orderID, OrderLogCanceled))
+
+	assert.NoError(t, mock.ExpectationsWereMet(), "the actor is set for the history recorded with the event")
+}
@@ -0,0 +1,250 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// defaultOrderListLimit is how many orders are listed per page unless a limit is requested
+	defaultOrderListLimit = 50
+	// maxOrderListLimit caps the orders listed per page
+	maxOrderListLimit = 200
+	// defaultOrderSort lists the newest orders first
+	defaultOrderSort = "-createdAt"
+)
+
+var (
+	// orderSortColumns are the columns orders can be sorted by, a leading - sorts descending
+	orderSortColumns = map[string]string{
+		"createdAt": "created_at",
+		"updatedAt": "updated_at",
+	}
+	// orderStatuses are the statuses orders can be filtered by
+	orderStatuses = map[string]bool{
+		"pending":        true,
+		OrderLogPaid:     true,
+		OrderLogCanceled: true,
+		OrderLogRefunded: true,
+		OrderLogExpired:  true,
+	}
+
+	// ErrOrderCursor is returned for cursors which do not continue a listing with the same sort
+	ErrOrderCursor = errors.New("invalid order cursor")
+)
+
+// OrderCursor is where a page of orders ends, the next page starts after it
+type OrderCursor struct {
+	Sort string
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// Encode the cursor as an opaque string
+func (c OrderCursor) Encode() string {
+	raw := strings.Join([]string{c.Sort, c.Time.UTC().Format(time.RFC3339Nano), c.ID.String()}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeOrderCursor decodes a cursor returned with an earlier page of a listing with the sort
+func decodeOrderCursor(encoded string, sort string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrOrderCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, ErrOrderCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, ErrOrderCursor
+	}
+	id, err := uuid.FromString(parts[2])
+	if err != nil {
+		return nil, ErrOrderCursor
+	}
+	return &OrderCursor{Sort: sort, Time: t, ID: id}, nil
+}
+
+// OrderFilter selects the orders of a merchant to list
+type OrderFilter struct {
+	MerchantID    string
+	Statuses      []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Location      string
+	// Sort is createdAt or updatedAt, descending with a leading -
+	Sort  string
+	After *OrderCursor
+	Limit int
+}
+
+// sortColumn returns the column and direction the filter sorts by
+func (f OrderFilter) sortColumn() (string, bool) {
+	descending := strings.HasPrefix(f.Sort, "-")
+	return orderSortColumns[strings.TrimPrefix(f.Sort, "-")], descending
+}
+
+// cursor returns the cursor a page ending with the order ends at
+func (f OrderFilter) cursor(order Order) OrderCursor {
+	cursor := OrderCursor{Sort: f.Sort, Time: order.CreatedAt, ID: order.ID}
+	if column, _ := f.sortColumn(); column == "updated_at" {
+		cursor.Time = order.UpdatedAt
+	}
+	return cursor
+}
+
+// OrderList is a page of orders, NextCursor continues the listing when there are more
+type OrderList struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// ListOrders lists a page of the orders matching the filter
+func (s *Service) ListOrders(ctx context.Context, filter OrderFilter) (*OrderList, error) {
+	limit := filter.Limit
+	// one more than the page tells whether there is a next page
+	filter.Limit = limit + 1
+	orders, err := s.Datastore.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &OrderList{Orders: orders}
+	if len(orders) > limit {
+		list.Orders = orders[:limit]
+		list.NextCursor = filter.cursor(orders[limit-1]).Encode()
+	}
+	return list, nil
+}
+
+// parseOrderFilter reads the filter of an order listing from the query, returning the invalid parameters
+func parseOrderFilter(r *http.Request) (OrderFilter, map[string]interface{}) {
+	var (
+		query   = r.URL.Query()
+		invalid = map[string]interface{}{}
+		filter  = OrderFilter{
+			MerchantID: chi.URLParam(r, "merchantID"),
+			Location:   query.Get("location"),
+			Sort:       defaultOrderSort,
+			Limit:      defaultOrderListLimit,
+		}
+	)
+
+	for _, status := range query["status"] {
+		for _, s := range strings.Split(status, ",") {
+			if !orderStatuses[s] {
+				invalid["status"] = "must be pending, paid, canceled or refunded"
+			}
+			filter.Statuses = append(filter.Statuses, s)
+		}
+	}
+	for param, t := range map[string]**time.Time{"createdAfter": &filter.CreatedAfter, "createdBefore": &filter.CreatedBefore} {
+		if v := query.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				invalid[param] = "must be an RFC 3339 timestamp"
+				continue
+			}
+			*t = &parsed
+		}
+	}
+	if v := query.Get("sort"); v != "" {
+		if _, ok := orderSortColumns[strings.TrimPrefix(v, "-")]; !ok {
+			invalid["sort"] = "must be createdAt or updatedAt, with a leading - to sort descending"
+		}
+		filter.Sort = v
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxOrderListLimit {
+			invalid["limit"] = "must be between 1 and " + strconv.Itoa(maxOrderListLimit)
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := decodeOrderCursor(v, filter.Sort)
+		if err != nil {
+			invalid["cursor"] = "must be the cursor of a listing with the same sort"
+		}
+		filter.After = cursor
+	}
+	return filter, invalid
+}
+
+// ListMerchantOrders is the handler for listing the orders of a merchant, filtered by status, creation
+// time and location, and paged with the cursor returned with each page
+func ListMerchantOrders(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		filter, invalid := parseOrderFilter(r)
+		if len(invalid) > 0 {
+			return handlers.ValidationError("request query parameters", invalid)
+		}
+
+		list, err := service.ListOrders(r.Context(), filter)
+		if err != nil {
+			return handlers.WrapError(err, "Error listing orders", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), list, w, http.StatusOK)
+	})
+}
+
+// listOrdersStatement builds the query listing the orders matching the filter, keyset paged by the sort
+// column and id
+func listOrdersStatement(filter OrderFilter) (string, []interface{}) {
+	var (
+		conditions = []string{"merchant_id = $1"}
+		args       = []interface{}{filter.MerchantID}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+arg(pq.Array(filter.Statuses))+")")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedBefore))
+	}
+	if filter.Location != "" {
+		conditions = append(conditions, "location = "+arg(filter.Location))
+	}
+
+	column, descending := filter.sortColumn()
+	if column == "" {
+		column = "created_at"
+	}
+	direction, after := "ASC", ">"
+	if descending {
+		direction, after = "DESC", "<"
+	}
+	if filter.After != nil {
+		conditions = append(conditions,
+			fmt.Sprintf("(%s, id) %s (%s, %s)", column, after, arg(filter.After.Time), arg(filter.After.ID)))
+	}
+
+	statement := fmt.Sprintf(`
+		SELECT id, created_at, currency, updated_at, total_price, merchant_id, location, status,
+			exchange_rate, bat_total_price, rated_at
+		FROM orders
+		WHERE %s
+		ORDER BY %s %s, id %s
+		LIMIT %s`, strings.Join(conditions, " AND "), column, direction, direction, arg(filter.Limit))
+	return statement, args
+}
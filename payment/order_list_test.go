@@ -0,0 +1,115 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderCursor(t *testing.T) {
+	cursor := OrderCursor{Sort: "-updatedAt", Time: time.Now().UTC(), ID: uuid.NewV4()}
+
+	decoded, err := decodeOrderCursor(cursor.Encode(), "-updatedAt")
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+
+	_, err = decodeOrderCursor(cursor.Encode(), "createdAt")
+	assert.Equal(t, ErrOrderCursor, err, "cursors only continue a listing with the same sort")
+	_, err = decodeOrderCursor("not a cursor", "-updatedAt")
+	assert.Equal(t, ErrOrderCursor, err)
+}
+
+func TestParseOrderFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?status=paid,canceled&createdAfter=2021-01-01T00:00:00Z&location=brave.com&sort=updatedAt&limit=10", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("merchantID", "brave.com")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	filter, invalid := parseOrderFilter(r)
+	assert.Empty(t, invalid)
+	assert.Equal(t, "brave.com", filter.MerchantID)
+	assert.Equal(t, []string{"paid", "canceled"}, filter.Statuses)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), filter.CreatedAfter.UTC())
+	assert.Nil(t, filter.CreatedBefore)
+	assert.Equal(t, "updatedAt", filter.Sort)
+	assert.Equal(t, 10, filter.Limit)
+
+	r = httptest.NewRequest("GET", "/?status=shipped&createdBefore=yesterday&sort=price&limit=1000&cursor=bogus", nil)
+	_, invalid = parseOrderFilter(r)
+	for _, param := range []string{"status", "createdBefore", "sort", "limit", "cursor"} {
+		assert.Contains(t, invalid, param)
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	now := time.Now()
+	ds := newFakeDatastore()
+	orders := make([]Order, 3)
+	for i := range orders {
+		orders[i] = *ds.addOrder(Order{MerchantID: "brave.com", CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+	ds.addOrder(Order{MerchantID: "other.com", CreatedAt: now})
+	service := &Service{Datastore: ds}
+
+	list, err := service.ListOrders(context.Background(), OrderFilter{MerchantID: "brave.com", Sort: defaultOrderSort, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, orders[:2], list.Orders)
+
+	cursor, err := decodeOrderCursor(list.NextCursor, defaultOrderSort)
+	require.NoError(t, err)
+	assert.Equal(t, orders[1].ID, cursor.ID)
+	assert.True(t, orders[1].CreatedAt.Equal(cursor.Time))
+
+	list, err = service.ListOrders(context.Background(), OrderFilter{MerchantID: "brave.com", Sort: defaultOrderSort, After: cursor, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, orders[2:], list.Orders, "the cursor continues the listing")
+	assert.Empty(t, list.NextCursor, "the last page has no cursor")
+
+	list, err = service.ListOrders(context.Background(), OrderFilter{MerchantID: "brave.com", Sort: defaultOrderSort, Limit: 3})
+	require.NoError(t, err)
+	assert.Len(t, list.Orders, 3)
+	assert.Empty(t, list.NextCursor, "the last page has no cursor")
+}
+
+func TestListOrdersStatement(t *testing.T) {
+	after := &OrderCursor{Sort: "-createdAt", Time: time.Now(), ID: uuid.NewV4()}
+	statement, args := listOrdersStatement(OrderFilter{
+		MerchantID: "brave.com", Statuses: []string{"paid"}, Location: "brave.com", Sort: "-createdAt", After: after, Limit: 11,
+	})
+	assert.Contains(t, statement, "merchant_id = $1 AND status = ANY($2) AND location = $3 AND (created_at, id) < ($4, $5)")
+	assert.Contains(t, statement, "ORDER BY created_at DESC, id DESC")
+	assert.Contains(t, statement, "LIMIT $6")
+	assert.Len(t, args, 6)
+
+	statement, _ = listOrdersStatement(OrderFilter{MerchantID: "brave.com", Sort: "updatedAt", Limit: 11})
+	assert.Contains(t, statement, "ORDER BY updated_at ASC, id ASC")
+}
+
+func TestMerchantOrder(t *testing.T) {
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com"})
+	service := &Service{Datastore: ds}
+	handler := merchantOrder(service, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(merchantID string, orderID string) int {
+		r := httptest.NewRequest("POST", "/", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("merchantID", merchantID)
+		rctx.URLParams.Add("orderID", orderID)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("brave.com", order.ID.String()))
+	assert.Equal(t, http.StatusNotFound, serve("other.com", order.ID.String()), "orders of other merchants are not found")
+	assert.Equal(t, http.StatusNotFound, serve("brave.com", uuid.NewV4().String()))
+	assert.Equal(t, http.StatusNotFound, serve("brave.com", "not-an-id"))
+}
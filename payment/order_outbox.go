@@ -0,0 +1,134 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/linkedin/goavro"
+	uuid "github.com/satori/go.uuid"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+const orderEventPublishBatch = 100
+
+// orderLogTopics are the events published to the order topic for downstream consumers, by the type of their message
+var orderLogTopics = map[string]string{
+	OrderLogCreated:     "order.created",
+	OrderLogPaid:        "order.paid",
+	OrderLogCredsSigned: "order.creds.signed",
+	OrderLogRefunded:    "order.refunded",
+	OrderLogExpired:     "order.expired",
+}
+
+// OrderEventMessage is the message published to the order topic for an order event. Its ID is the id of
+// the event, which consumers dedupe on as an event is published again if marking it published fails
+type OrderEventMessage struct {
+	ID         uuid.UUID
+	Type       string
+	OrderID    uuid.UUID
+	Sequence   int
+	MerchantID string
+	Status     string
+	Currency   string
+	TotalPrice string
+	CreatedAt  time.Time
+	// ItemID and Count are only set on order.creds.signed
+	ItemID *uuid.UUID
+	Count  *int
+}
+
+// newOrderEventMessage is the message of an event of the order, its status the one the event moved the order to
+func newOrderEventMessage(event OrderLogEvent, order *Order) (*OrderEventMessage, error) {
+	msg := &OrderEventMessage{
+		ID:         event.ID,
+		Type:       orderLogTopics[event.Type],
+		OrderID:    event.OrderID,
+		Sequence:   event.Sequence,
+		MerchantID: order.MerchantID,
+		Status:     order.Status,
+		Currency:   order.Currency,
+		TotalPrice: order.TotalPrice.String(),
+		CreatedAt:  event.CreatedAt,
+	}
+	switch event.Type {
+	case OrderLogCreated:
+		var payload orderCreatedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to decode order event: %w", err)
+		}
+		msg.Status = payload.Status
+	case OrderLogCredsSigned:
+		var payload orderCredsPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to decode order event: %w", err)
+		}
+		msg.ItemID, msg.Count = &payload.ItemID, &payload.Count
+	default:
+		var payload orderStatusPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to decode order event: %w", err)
+		}
+		msg.Status = payload.Status
+	}
+	return msg, nil
+}
+
+// CodecEncode - encode using avro order event codec
+func (msg *OrderEventMessage) CodecEncode(codec *goavro.Codec) ([]byte, error) {
+	var itemID, count interface{}
+	if msg.ItemID != nil {
+		itemID = goavro.Union("string", msg.ItemID.String())
+	}
+	if msg.Count != nil {
+		count = goavro.Union("long", int64(*msg.Count))
+	}
+	return codec.BinaryFromNative(nil, map[string]interface{}{
+		"id":         msg.ID.String(),
+		"type":       msg.Type,
+		"orderId":    msg.OrderID.String(),
+		"sequence":   int64(msg.Sequence),
+		"merchantId": msg.MerchantID,
+		"status":     msg.Status,
+		"currency":   msg.Currency,
+		"totalPrice": msg.TotalPrice,
+		"createdAt":  msg.CreatedAt.Format(time.RFC3339),
+		"itemId":     itemID,
+		"count":      count,
+	})
+}
+
+// PublishOrderEvents publishes the order events added to the outbox since the last run, an event is only
+// marked published once the bus has taken it, so none are lost if the server stops
+func (s *Service) PublishOrderEvents(ctx context.Context) (bool, error) {
+	if s.orderProducer == nil {
+		return false, nil
+	}
+	n, err := s.Datastore.PublishOrderEvents(ctx, orderEventPublishBatch, s.publishOrderEvent)
+	return n > 0, err
+}
+
+// publishOrderEvent writes the message of an order event to the order topic, keyed by the order so the
+// events of an order are consumed in sequence
+func (s *Service) publishOrderEvent(ctx context.Context, event OrderLogEvent) error {
+	order, err := s.Datastore.GetOrder(event.OrderID)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return fmt.Errorf("order %s not found", event.OrderID)
+	}
+	msg, err := newOrderEventMessage(event, order)
+	if err != nil {
+		return err
+	}
+	value, err := msg.CodecEncode(s.codecs["orderEvent"])
+	if err != nil {
+		return fmt.Errorf("failed to encode avro codec: %w", err)
+	}
+	return s.orderProducer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(order.ID.String()),
+		Value: value,
+	})
+}
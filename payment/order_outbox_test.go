@@ -0,0 +1,82 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	uuid "github.com/satori/go.uuid"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProducer keeps the messages written to it, failing once it has taken its limit
+type recordingProducer struct {
+	msgs  []kafka.Message
+	limit int
+}
+
+func (p *recordingProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if len(p.msgs)+len(msgs) > p.limit {
+		return errors.New("broker unavailable")
+	}
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *recordingProducer) Close() error {
+	return nil
+}
+
+func TestPublishOrderEvents(t *testing.T) {
+	codecs, err := kafkautils.GenerateCodecs(map[string]string{"orderEvent": orderEventSchema})
+	require.NoError(t, err)
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com", Status: "paid", Currency: "USD", TotalPrice: decimal.New(5, 0)})
+	itemID := uuid.NewV4()
+	event := func(sequence int, eventType string, payload string) OrderLogEvent {
+		return ds.addEvent(OrderLogEvent{OrderID: order.ID, Sequence: sequence, Type: eventType, Payload: []byte(payload)})
+	}
+	outbox := []OrderLogEvent{
+		event(1, OrderLogCreated, `{"merchantId":"brave.com","currency":"USD","status":"pending","items":[]}`),
+		event(2, OrderLogPriced, `{"totalPrice":"5","currency":"USD"}`),
+		event(3, OrderLogPaid, `{"status":"paid","previous":"pending"}`),
+		event(5, OrderLogCredsSigned, `{"itemId":"`+itemID.String()+`","count":10}`),
+	}
+	producer := &recordingProducer{limit: 2}
+	service := &Service{Datastore: ds, codecs: codecs, orderProducer: producer}
+
+	_, err = service.PublishOrderEvents(context.Background())
+	assert.Error(t, err, "events are left in the outbox when the bus fails")
+	assert.Len(t, ds.published, 2)
+
+	producer.limit = 3
+	more, err := service.PublishOrderEvents(context.Background())
+	require.NoError(t, err)
+	assert.True(t, more)
+	require.Len(t, producer.msgs, 3)
+
+	for _, msg := range producer.msgs {
+		assert.Equal(t, order.ID.String(), string(msg.Key), "the events of an order are keyed by the order")
+	}
+	native, _, err := codecs["orderEvent"].NativeFromBinary(producer.msgs[0].Value)
+	require.NoError(t, err)
+	created := native.(map[string]interface{})
+	assert.Equal(t, outbox[0].ID.String(), created["id"])
+	assert.Equal(t, "order.created", created["type"])
+	assert.Equal(t, "pending", created["status"], "the status is the one the event moved the order to")
+	assert.Equal(t, "brave.com", created["merchantId"])
+	assert.Equal(t, "5", created["totalPrice"])
+	assert.Nil(t, created["itemId"])
+
+	native, _, err = codecs["orderEvent"].NativeFromBinary(producer.msgs[2].Value)
+	require.NoError(t, err)
+	signed := native.(map[string]interface{})
+	assert.Equal(t, "order.creds.signed", signed["type"])
+	assert.Equal(t, int64(5), signed["sequence"])
+	assert.Equal(t, map[string]interface{}{"string": itemID.String()}, signed["itemId"])
+	assert.Equal(t, map[string]interface{}{"long": int64(10)}, signed["count"])
+}
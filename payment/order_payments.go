@@ -0,0 +1,127 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+// OrderPayment is a completed payment towards an order, recorded in the order's ledger with the transaction
+// which made it
+type OrderPayment struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	OrderID       uuid.UUID       `json:"orderId" db:"order_id"`
+	TransactionID *uuid.UUID      `json:"transactionId,omitempty" db:"transaction_id"`
+	Kind          string          `json:"kind" db:"kind"`
+	Currency      string          `json:"currency" db:"currency"`
+	Amount        decimal.Decimal `json:"amount" db:"amount"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// OrderBalance is what has been paid towards an order and what remains to be paid, in the order's currency
+// and, when the order has a BAT total, in BAT
+type OrderBalance struct {
+	Currency     string           `json:"currency"`
+	Paid         decimal.Decimal  `json:"paid"`
+	Remaining    decimal.Decimal  `json:"remaining"`
+	BATRemaining *decimal.Decimal `json:"batRemaining,omitempty"`
+	// Settled is set once the payments cover the order's total
+	Settled  bool           `json:"settled"`
+	Payments []OrderPayment `json:"payments"`
+}
+
+// newOrderBalance totals the payments made towards an order. Payments in the order's currency count at
+// their amount, and payments in BAT towards an order priced in fiat count for the share of its BAT total
+// they pay, at the rate snapshotted when the order was placed. Payments in other currencies do not count
+func newOrderBalance(order *Order, payments []OrderPayment) *OrderBalance {
+	var inCurrency, inBAT decimal.Decimal
+	for _, payment := range payments {
+		switch {
+		case payment.Currency == order.Currency:
+			inCurrency = inCurrency.Add(payment.Amount)
+		case payment.Currency == "BAT" && order.BATTotalPrice != nil:
+			inBAT = inBAT.Add(payment.Amount)
+		}
+	}
+
+	balance := &OrderBalance{
+		Currency: order.Currency,
+		Paid:     inCurrency,
+		Payments: payments,
+	}
+	if payments == nil {
+		balance.Payments = []OrderPayment{}
+	}
+	batTotal := order.BATTotalPrice
+	if order.Currency != "BAT" && batTotal != nil && batTotal.IsPositive() {
+		balance.Paid = balance.Paid.Add(inBAT.Mul(order.TotalPrice).DivRound(*batTotal, 18))
+		// settled exactly, as paying the whole BAT total must pay the order however it rounds
+		balance.Settled = inCurrency.Mul(*batTotal).Add(inBAT.Mul(order.TotalPrice)).
+			GreaterThanOrEqual(order.TotalPrice.Mul(*batTotal))
+	} else {
+		balance.Settled = inCurrency.GreaterThanOrEqual(order.TotalPrice)
+	}
+
+	balance.Remaining = decimal.Zero
+	if !balance.Settled && order.TotalPrice.GreaterThan(balance.Paid) {
+		balance.Remaining = order.TotalPrice.Sub(balance.Paid)
+	}
+	switch {
+	case order.Currency == "BAT":
+		balance.BATRemaining = &balance.Remaining
+	case batTotal != nil && order.TotalPrice.IsPositive():
+		batRemaining := balance.Remaining.Mul(*batTotal).DivRound(order.TotalPrice, 18)
+		balance.BATRemaining = &batRemaining
+	}
+	return balance
+}
+
+// GetOrderBalance returns what has been paid towards the order and what remains, from its ledger
+func (s *Service) GetOrderBalance(ctx context.Context, order *Order) (*OrderBalance, error) {
+	payments, err := s.Datastore.GetOrderPayments(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newOrderBalance(order, payments), nil
+}
+
+// isOrderPaid tells whether the ledger of an order settles it, possibly across several installments. Free
+// orders which are not paid already are trials, which no payment settles
+func (s *Service) isOrderPaid(ctx context.Context, order *Order) (bool, error) {
+	balance, err := s.GetOrderBalance(ctx, order)
+	if err != nil {
+		return false, err
+	}
+	order.Balance = balance
+	return balance.Settled && order.TotalPrice.IsPositive(), nil
+}
+
+// GetOrderPayments is the handler for the ledger of an order and its remaining balance
+func GetOrderPayments(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+
+		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		balance, err := service.GetOrderBalance(r.Context(), order)
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order's payments", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), balance, w, http.StatusOK)
+	})
+}
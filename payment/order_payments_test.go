@@ -0,0 +1,73 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderBalance(t *testing.T) {
+	batTotal := decimal.RequireFromString("33.3")
+	order := &Order{ID: uuid.NewV4(), Currency: "USD", Status: "pending", TotalPrice: decimal.RequireFromString("9.99"), BATTotalPrice: &batTotal}
+	payment := func(currency string, amount string) OrderPayment {
+		return OrderPayment{OrderID: order.ID, Currency: currency, Amount: decimal.RequireFromString(amount)}
+	}
+
+	balance := newOrderBalance(order, nil)
+	assert.False(t, balance.Settled)
+	assert.True(t, order.TotalPrice.Equal(balance.Remaining))
+	assert.True(t, batTotal.Equal(*balance.BATRemaining))
+	assert.Equal(t, []OrderPayment{}, balance.Payments)
+
+	balance = newOrderBalance(order, []OrderPayment{payment("USD", "4.99"), payment("BAT", "11.1"), payment("EUR", "100")})
+	assert.False(t, balance.Settled, "payments in other currencies do not count")
+	assert.Equal(t, "8.32", balance.Paid.String())
+	assert.Equal(t, "1.67", balance.Remaining.String())
+	assert.Equal(t, "5.567", balance.BATRemaining.Round(3).String())
+
+	balance = newOrderBalance(order, []OrderPayment{payment("BAT", "33.3")})
+	assert.True(t, balance.Settled, "paying the BAT total pays the order")
+
+	balance = newOrderBalance(order, []OrderPayment{payment("USD", "4.99"), payment("BAT", "16.7")})
+	assert.True(t, balance.Settled, "an order is settled across installments in several currencies")
+	assert.True(t, balance.Remaining.IsZero())
+	assert.True(t, balance.BATRemaining.IsZero())
+	order.Balance = balance
+	assert.True(t, order.IsPaid(), "an order settled by its ledger is paid")
+	order.Status = OrderLogCanceled
+	assert.False(t, order.IsPaid())
+
+	order = &Order{ID: uuid.NewV4(), Currency: "USD", Status: "pending", TotalPrice: decimal.RequireFromString("9.99")}
+	balance = newOrderBalance(order, []OrderPayment{payment("BAT", "100")})
+	assert.False(t, balance.Settled, "fiat orders without a BAT total can only be paid in their currency")
+	assert.Nil(t, balance.BATRemaining)
+}
+
+func TestIsOrderPaid(t *testing.T) {
+	batTotal := decimal.RequireFromString("39.96")
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Currency: "USD", TotalPrice: decimal.RequireFromString("9.99"), BATTotalPrice: &batTotal})
+	service := &Service{Datastore: ds}
+	ctx := context.Background()
+
+	_, _ = ds.CreateTransaction(order.ID, "1", "completed", "BAT", "uphold", decimal.RequireFromString("30"))
+	_, _ = ds.CreateTransaction(order.ID, "2", "pending", "BAT", "uphold", decimal.RequireFromString("9.96"))
+	paid, err := service.isOrderPaid(ctx, order)
+	require.NoError(t, err)
+	assert.False(t, paid, "only completed transactions are payments")
+
+	_, _ = ds.CreateTransaction(order.ID, "3", "completed", "BAT", "uphold", decimal.RequireFromString("9.96"))
+	paid, err = service.isOrderPaid(ctx, order)
+	require.NoError(t, err)
+	assert.True(t, paid, "payments in BAT are checked against the total at the snapshotted rate")
+
+	order = ds.addOrder(Order{Currency: "USD", TotalPrice: decimal.RequireFromString("9.99"), BATTotalPrice: &batTotal})
+	_, _ = ds.CreateTransaction(order.ID, "4", "completed", "USD", PaymentMethodStripe, decimal.RequireFromString("9.99"))
+	paid, err = service.isOrderPaid(ctx, order)
+	require.NoError(t, err)
+	assert.True(t, paid)
+}
@@ -0,0 +1,103 @@
+// Package paymenttest generates the credentials partners and tests redeem with the payment service
+package paymenttest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+)
+
+// TestCredentials are credentials signed by a merchant's issuer along with the redemption payloads
+// partners test their integration with
+type TestCredentials struct {
+	Issuer    string `json:"issuer"`
+	PublicKey string `json:"publicKey"`
+	Payload   string `json:"payload"`
+	// Credentials are bound to the payload, as redeemed with a vote
+	Credentials []payment.CredentialBinding `json:"credentials"`
+	// Presentations are single use presentations bound to the issuer, as verified for a merchant
+	Presentations []string `json:"presentations"`
+}
+
+// GenerateTestCredentials blinds count new tokens, has the merchant's sku issuer sign them and returns
+// them unblinded as redemption payloads. The issuer must already exist
+func GenerateTestCredentials(ctx context.Context, client cbr.Client, merchantID, sku string, count int, payload string) (*TestCredentials, error) {
+	if count < 1 {
+		return nil, errors.New("at least one credential must be generated")
+	}
+	issuerID, err := payment.IssuerID(merchantID, sku)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := client.GetIssuer(ctx, issuerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issuer: %w", err)
+	}
+
+	tokens := make([]*ristretto.Token, count)
+	blinded := make([]string, count)
+	for i := range tokens {
+		if tokens[i], err = ristretto.NewToken(); err != nil {
+			return nil, fmt.Errorf("failed to create token: %w", err)
+		}
+		if blinded[i], err = tokens[i].Blind(); err != nil {
+			return nil, fmt.Errorf("failed to blind token: %w", err)
+		}
+	}
+
+	resp, err := client.SignCredentials(ctx, issuerID, blinded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign credentials: %w", err)
+	}
+	if len(resp.SignedTokens) != count {
+		return nil, fmt.Errorf("expected %d signed tokens, got %d", count, len(resp.SignedTokens))
+	}
+
+	creds := &TestCredentials{
+		Issuer:    issuerID,
+		PublicKey: issuer.PublicKey,
+		Payload:   payload,
+	}
+	for i, token := range tokens {
+		unblinded, err := token.Unblind(resp.SignedTokens[i])
+		if err != nil {
+			return nil, err
+		}
+		creds.Credentials = append(creds.Credentials, payment.CredentialBinding{
+			PublicKey:     issuer.PublicKey,
+			TokenPreimage: unblinded.EncodedPreimage(),
+			Signature:     unblinded.Sign(payload),
+		})
+
+		presentation, err := SingleUsePresentation(merchantID, sku, unblinded)
+		if err != nil {
+			return nil, err
+		}
+		creds.Presentations = append(creds.Presentations, presentation)
+	}
+	return creds, nil
+}
+
+// SingleUsePresentation encodes an unblinded credential of the merchant's sku issuer as the single
+// use presentation merchants verify, which is redeemed with the issuer as the payload
+func SingleUsePresentation(merchantID, sku string, token *ristretto.UnblindedToken) (string, error) {
+	issuerID, err := payment.IssuerID(merchantID, sku)
+	if err != nil {
+		return "", err
+	}
+	presentation, err := json.Marshal(cbr.CredentialRedemption{
+		Issuer:        issuerID,
+		TokenPreimage: token.EncodedPreimage(),
+		Signature:     token.Sign(issuerID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(presentation), nil
+}
@@ -0,0 +1,59 @@
+package paymenttest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signingClient signs credentials in process with a single issuer key
+type signingClient struct {
+	cbr.Client
+	key *ristretto.SigningKey
+}
+
+func (c *signingClient) GetIssuer(ctx context.Context, issuer string) (*cbr.IssuerResponse, error) {
+	return &cbr.IssuerResponse{Name: issuer, PublicKey: c.key.PublicKey()}, nil
+}
+
+func (c *signingClient) SignCredentials(ctx context.Context, issuer string, creds []string) (*cbr.CredentialsIssueResponse, error) {
+	resp := &cbr.CredentialsIssueResponse{}
+	for _, blinded := range creds {
+		signed, err := c.key.Sign(blinded)
+		if err != nil {
+			return nil, err
+		}
+		resp.SignedTokens = append(resp.SignedTokens, signed)
+	}
+	return resp, nil
+}
+
+func TestGenerateTestCredentials(t *testing.T) {
+	key, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+
+	creds, err := GenerateTestCredentials(context.Background(), &signingClient{key: key}, "brave.com", "anon-card-vote", 2, "vote")
+	require.NoError(t, err)
+	assert.Equal(t, "brave.com?sku=anon-card-vote", creds.Issuer)
+	assert.Len(t, creds.Credentials, 2)
+	assert.Len(t, creds.Presentations, 2)
+
+	for _, binding := range creds.Credentials {
+		assert.Equal(t, key.PublicKey(), binding.PublicKey)
+		assert.NoError(t, key.Verify(binding.TokenPreimage, binding.Signature, "vote"))
+	}
+	for _, presentation := range creds.Presentations {
+		decoded, err := base64.StdEncoding.DecodeString(presentation)
+		require.NoError(t, err)
+		var redemption cbr.CredentialRedemption
+		require.NoError(t, json.Unmarshal(decoded, &redemption))
+		assert.Equal(t, creds.Issuer, redemption.Issuer)
+		assert.NoError(t, key.Verify(redemption.TokenPreimage, redemption.Signature, redemption.Issuer))
+	}
+}
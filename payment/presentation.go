@@ -0,0 +1,219 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+)
+
+const (
+	// VerdictValid is the verdict of a credential which was redeemed by its presentation
+	VerdictValid = "valid"
+	// VerdictUnverified is the verdict of a credential which passed every check of a dry run. Its signature is
+	// only checked by the challenge bypass server, which holds the issuer's signing key, when it is redeemed
+	VerdictUnverified = "unverified"
+	// VerdictMalformed is the verdict of a credential which is not well formed
+	VerdictMalformed = "malformed"
+	// VerdictDuplicate is the verdict of a credential presented more than once
+	VerdictDuplicate = "duplicate"
+	// VerdictUnknownIssuer is the verdict of a credential whose public key is not of any issuer
+	VerdictUnknownIssuer = "unknown_issuer"
+	// VerdictWrongMerchant is the verdict of a credential issued for another merchant or sku
+	VerdictWrongMerchant = "wrong_merchant"
+	// VerdictOutsideWindow is the verdict of a time-limited credential presented outside its validity window
+	VerdictOutsideWindow = "outside_window"
+	// VerdictRedeemed is the verdict of a credential which was already redeemed
+	VerdictRedeemed = "redeemed"
+	// VerdictInvalidSignature is the verdict of a credential the challenge bypass server rejected
+	VerdictInvalidSignature = "invalid_signature"
+)
+
+// CredentialPresentation is a set of credentials presented to a merchant, bound to a payload
+type CredentialPresentation struct {
+	MerchantID string `json:"merchantId" valid:"-"`
+	// SKU restricts the credentials to those issued for the sku, any sku of the merchant when empty
+	SKU string `json:"sku" valid:"-"`
+	// Payload is what the credentials are bound to, the name of their issuer when empty, as for subscriptions
+	Payload     string              `json:"payload" valid:"-"`
+	Credentials []CredentialBinding `json:"credentials" valid:"-"`
+	// DryRun checks the credentials without redeeming them
+	DryRun bool `json:"dryRun" valid:"-"`
+}
+
+// CredentialVerdict is the verdict of one of the credentials of a presentation
+type CredentialVerdict struct {
+	Index         int    `json:"index"`
+	TokenPreimage string `json:"t"`
+	Issuer        string `json:"issuer,omitempty"`
+	Verdict       string `json:"verdict"`
+}
+
+// Valid is whether the credential was redeemed, or in a dry run would be unless its signature is rejected
+func (v CredentialVerdict) Valid() bool {
+	return v.Verdict == VerdictValid || v.Verdict == VerdictUnverified
+}
+
+// VerifyCredentialPresentation checks each credential of the presentation against the issuers of its
+// merchant and the credentials already redeemed, then redeems those which pass unless it is a dry run. The
+// verdicts are in the order of the credentials. Failing to reach the datastore or the challenge bypass
+// server fails the whole presentation, credentials redeemed before the failure stay redeemed
+func (s *Service) VerifyCredentialPresentation(ctx context.Context, presentation CredentialPresentation) ([]CredentialVerdict, error) {
+	var (
+		now      = time.Now()
+		verdicts = make([]CredentialVerdict, len(presentation.Credentials))
+		issuers  = map[string]*Issuer{}
+		seen     = map[string]bool{}
+		pending  []int
+	)
+	for i, binding := range presentation.Credentials {
+		verdicts[i] = CredentialVerdict{Index: i, TokenPreimage: binding.TokenPreimage}
+		if base64LengthError(binding.PublicKey, pointLength) != "" ||
+			base64LengthError(binding.TokenPreimage, tokenPreimageLength) != "" ||
+			base64LengthError(binding.Signature, signatureLength) != "" {
+			verdicts[i].Verdict = VerdictMalformed
+			continue
+		}
+		if seen[binding.TokenPreimage] {
+			verdicts[i].Verdict = VerdictDuplicate
+			continue
+		}
+		seen[binding.TokenPreimage] = true
+
+		issuer, ok := issuers[binding.PublicKey]
+		if !ok {
+			var err error
+			if issuer, err = getIssuerByPublicKey(s.ReadableDatastore(), binding.PublicKey); err != nil {
+				return nil, fmt.Errorf("error finding issuer: %w", err)
+			}
+			issuers[binding.PublicKey] = issuer
+		}
+		if issuer == nil {
+			verdicts[i].Verdict = VerdictUnknownIssuer
+			continue
+		}
+		verdicts[i].Issuer = issuer.Name()
+
+		merchantID, sku, err := decodeIssuerID(issuer.Name())
+		if err != nil || merchantID != presentation.MerchantID || (presentation.SKU != "" && sku != presentation.SKU) {
+			verdicts[i].Verdict = VerdictWrongMerchant
+			continue
+		}
+		if !credentialWindowOpen(issuer.Name(), now) {
+			verdicts[i].Verdict = VerdictOutsideWindow
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	// credentials redeemed before are rejected here rather than by the challenge bypass server
+	if len(pending) > 0 {
+		tokenPreimages := make([]string, len(pending))
+		for j, i := range pending {
+			tokenPreimages[j] = verdicts[i].TokenPreimage
+		}
+		redeemed, err := s.ReadableDatastore().GetCredentialRedemptions(ctx, tokenPreimages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check credential redemptions: %w", err)
+		}
+		spent := make(map[string]bool, len(redeemed))
+		for _, r := range redeemed {
+			spent[r.TokenPreimage] = true
+		}
+		unspent := pending[:0]
+		for _, i := range pending {
+			if spent[verdicts[i].TokenPreimage] {
+				verdicts[i].Verdict = VerdictRedeemed
+				continue
+			}
+			unspent = append(unspent, i)
+		}
+		pending = unspent
+	}
+
+	if presentation.DryRun {
+		for _, i := range pending {
+			verdicts[i].Verdict = VerdictUnverified
+		}
+		return verdicts, nil
+	}
+
+	var redeemed []RedeemedCredential
+	for _, i := range pending {
+		binding := presentation.Credentials[i]
+		payload := presentation.Payload
+		if payload == "" {
+			payload = verdicts[i].Issuer
+		}
+		err := s.cbClient.RedeemCredential(ctx, verdicts[i].Issuer, binding.TokenPreimage, binding.Signature, payload)
+		switch {
+		case err == nil:
+			verdicts[i].Verdict = VerdictValid
+			redeemed = append(redeemed, RedeemedCredential{TokenPreimage: binding.TokenPreimage,
+				IssuerID: verdicts[i].Issuer, Payload: payload})
+		case cbr.IsDuplicateRedemption(err):
+			verdicts[i].Verdict = VerdictRedeemed
+		case cbr.IsRejectedRedemption(err):
+			verdicts[i].Verdict = VerdictInvalidSignature
+		default:
+			return nil, err
+		}
+	}
+
+	if len(redeemed) > 0 {
+		s.recordRedemptions(ctx, presentation.MerchantID, int64(len(redeemed)))
+		if err := s.Datastore.InsertCredentialRedemptions(ctx, nil, redeemed); err != nil {
+			// the challenge bypass server still rejects the credentials if they are presented again
+			logger, lerr := appctx.GetLogger(ctx)
+			if lerr != nil {
+				_, logger = logging.SetupLogger(ctx)
+			}
+			logger.Warn().Err(err).Msg("failed to record credential redemptions")
+		}
+	}
+	return verdicts, nil
+}
+
+// VerifyCredentialPresentationResponse includes the verdict of each credential of a presentation
+type VerifyCredentialPresentationResponse struct {
+	Verdicts []CredentialVerdict `json:"verdicts"`
+}
+
+// VerifyMerchantCredentials is the handler for a merchant verifying the credentials presented to it
+func VerifyMerchantCredentials(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req CredentialPresentation
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		_, err = govalidator.ValidateStruct(req)
+		if err != nil {
+			return handlers.WrapValidationError(err)
+		}
+		errs := map[string]interface{}{}
+		if !validateCredentialCount("credentials", len(req.Credentials), errs) {
+			return handlers.ValidationError("request body", errs)
+		}
+		req.MerchantID = chi.URLParam(r, "merchantID")
+
+		verdicts, err := service.VerifyCredentialPresentation(r.Context(), req)
+		if cbr.IsCircuitOpen(err) {
+			return handlers.WrapError(err, "Credential verification is temporarily unavailable", http.StatusServiceUnavailable)
+		}
+		if err != nil {
+			return handlers.WrapError(err, "Error verifying credentials", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), VerifyCredentialPresentationResponse{Verdicts: verdicts}, w, http.StatusOK)
+	})
+}
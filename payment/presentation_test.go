@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr/cbrtest"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signTestCredentials has the server's issuer sign count new credentials, bound to the payload
+func signTestCredentials(t *testing.T, server *cbrtest.Server, issuer string, count int, payload string) []CredentialBinding {
+	ctx := context.Background()
+	issuerResp, err := server.GetIssuer(ctx, issuer)
+	require.NoError(t, err)
+
+	tokens := make([]*ristretto.Token, count)
+	blinded := make([]string, count)
+	for i := range tokens {
+		tokens[i], err = ristretto.NewToken()
+		require.NoError(t, err)
+		blinded[i], err = tokens[i].Blind()
+		require.NoError(t, err)
+	}
+	resp, err := server.SignCredentials(ctx, issuer, blinded)
+	require.NoError(t, err)
+
+	bindings := make([]CredentialBinding, count)
+	for i, token := range tokens {
+		unblinded, err := token.Unblind(resp.SignedTokens[i])
+		require.NoError(t, err)
+		bindings[i] = CredentialBinding{PublicKey: issuerResp.PublicKey, TokenPreimage: unblinded.EncodedPreimage(),
+			Signature: unblinded.Sign(payload)}
+	}
+	return bindings
+}
+
+func TestVerifyCredentialPresentation(t *testing.T) {
+	ctx := context.Background()
+	server := cbrtest.NewServer()
+	issuer := Issuer{MerchantID: "brave.com?sku=anon-card-vote", Version: 1}
+	require.NoError(t, server.CreateIssuer(ctx, issuer.Name(), defaultMaxTokensPerIssuer))
+	creds := signTestCredentials(t, server, issuer.Name(), 4, "vote")
+	issuer.PublicKey = creds[0].PublicKey
+
+	ds := newFakeDatastore()
+	ds.issuers = []Issuer{issuer}
+	service := &Service{Datastore: ds, cbClient: server}
+
+	forged := creds[2]
+	forged.Signature = creds[3].Signature
+	unknown := creds[3]
+	unknown.PublicKey = encodedBytes(32, 7)
+	require.NoError(t, ds.InsertCredentialRedemptions(ctx, nil, []RedeemedCredential{{TokenPreimage: creds[1].TokenPreimage}}))
+
+	presentation := CredentialPresentation{
+		MerchantID:  "brave.com",
+		Payload:     "vote",
+		Credentials: []CredentialBinding{creds[0], creds[1], forged, unknown, creds[0], {}},
+		DryRun:      true,
+	}
+	verdicts := func(vs []CredentialVerdict) []string {
+		result := make([]string, len(vs))
+		for i, v := range vs {
+			result[i] = v.Verdict
+		}
+		return result
+	}
+
+	dry, err := service.VerifyCredentialPresentation(ctx, presentation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{VerdictUnverified, VerdictRedeemed, VerdictUnverified, VerdictUnknownIssuer, VerdictDuplicate, VerdictMalformed},
+		verdicts(dry), "signatures are not checked without redeeming")
+	assert.Equal(t, issuer.Name(), dry[0].Issuer)
+	assert.Equal(t, 0, server.Redeemed(issuer.Name()), "dry runs redeem nothing")
+
+	presentation.DryRun = false
+	redeemed, err := service.VerifyCredentialPresentation(ctx, presentation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{VerdictValid, VerdictRedeemed, VerdictInvalidSignature, VerdictUnknownIssuer, VerdictDuplicate, VerdictMalformed},
+		verdicts(redeemed))
+	assert.True(t, redeemed[0].Valid())
+	assert.Equal(t, 1, server.Redeemed(issuer.Name()))
+	recorded, err := ds.GetCredentialRedemptions(ctx, []string{creds[0].TokenPreimage})
+	require.NoError(t, err)
+	assert.Len(t, recorded, 1, "redemptions are recorded")
+	assert.Equal(t, int64(1), ds.redemptions("brave.com"))
+
+	presentation.Credentials = []CredentialBinding{creds[0]}
+	again, err := service.VerifyCredentialPresentation(ctx, presentation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{VerdictRedeemed}, verdicts(again), "credentials redeem once")
+
+	presentation.MerchantID = "other.com"
+	presentation.Credentials = []CredentialBinding{creds[3]}
+	other, err := service.VerifyCredentialPresentation(ctx, presentation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{VerdictWrongMerchant}, verdicts(other))
+}
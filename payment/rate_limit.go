@@ -0,0 +1,178 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+// rateLimitDefault is the rate limit from the environment variable, zero when it is unset
+func rateLimitDefault(name string) int {
+	if limit, err := strconv.Atoi(os.Getenv(name)); err == nil && limit > 0 {
+		return limit
+	}
+	return 0
+}
+
+// merchantRateLimit is the limit of the merchant's credential signing and redemption requests, defaulting to
+// CREDENTIAL_RATE_LIMIT_PER_MINUTE and CREDENTIAL_RATE_LIMIT_BURST. Nil when the merchant is not limited
+func (settings *MerchantSettings) merchantRateLimit() *middleware.KeyRateLimit {
+	return rateLimit(settings.RateLimitPerMinute, settings.RateLimitBurst, "CREDENTIAL_RATE_LIMIT")
+}
+
+// ipRateLimit is the limit of the requests each IP address makes to the merchant, defaulting to
+// CREDENTIAL_IP_RATE_LIMIT_PER_MINUTE and CREDENTIAL_IP_RATE_LIMIT_BURST. Nil when IP addresses are not limited
+func (settings *MerchantSettings) ipRateLimit() *middleware.KeyRateLimit {
+	return rateLimit(settings.IPRateLimitPerMinute, settings.IPRateLimitBurst, "CREDENTIAL_IP_RATE_LIMIT")
+}
+
+// rateLimit is the limit set, or the default of the environment variables with the prefix
+func rateLimit(perMinute, burst *int, defaultPrefix string) *middleware.KeyRateLimit {
+	limit := &middleware.KeyRateLimit{
+		PerMinute: rateLimitDefault(defaultPrefix + "_PER_MINUTE"),
+		Burst:     rateLimitDefault(defaultPrefix + "_BURST"),
+	}
+	if perMinute != nil {
+		limit.PerMinute = *perMinute
+	}
+	if burst != nil {
+		limit.Burst = *burst
+	}
+	if limit.PerMinute == 0 {
+		return nil
+	}
+	return limit
+}
+
+// withRateLimitDefaults fills in the rate limits not set with their defaults
+func (settings *MerchantSettings) withRateLimitDefaults() {
+	if limit := settings.merchantRateLimit(); limit != nil {
+		settings.RateLimitPerMinute, settings.RateLimitBurst = &limit.PerMinute, &limit.Burst
+	}
+	if limit := settings.ipRateLimit(); limit != nil {
+		settings.IPRateLimitPerMinute, settings.IPRateLimitBurst = &limit.PerMinute, &limit.Burst
+	}
+}
+
+// merchantFunc returns the merchant a request is made to, empty when there is none
+type merchantFunc func(r *http.Request) (string, error)
+
+// orderMerchant is the merchant of the order in the route
+func orderMerchant(service *Service) merchantFunc {
+	return func(r *http.Request) (string, error) {
+		orderID, err := uuid.FromString(chi.URLParam(r, "orderID"))
+		if err != nil {
+			return "", nil
+		}
+		order, err := service.Datastore.GetOrder(orderID)
+		if err != nil || order == nil {
+			return "", err
+		}
+		return order.MerchantID, nil
+	}
+}
+
+// urlMerchant is the merchant named by the merchantID of the request url
+func urlMerchant(r *http.Request) (string, error) {
+	return chi.URLParam(r, "merchantID"), nil
+}
+
+// bodyMerchant is the merchant named by the merchantId of the request body, which is left for the handler to read
+func bodyMerchant(r *http.Request) (string, error) {
+	body, err := requestutils.Read(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		MerchantID string `json:"merchantId"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		// the handler rejects the malformed body
+		return "", nil
+	}
+	return req.MerchantID, nil
+}
+
+func rateLimitedMerchant(ctx context.Context) *MerchantSettings {
+	settings, _ := ctx.Value(appctx.RateLimitedMerchantCTXKey).(*MerchantSettings)
+	return settings
+}
+
+// merchantBucket puts all of a merchant's requests in one bucket
+func merchantBucket(r *http.Request) string {
+	if settings := rateLimitedMerchant(r.Context()); settings != nil {
+		return "merchant:" + settings.MerchantID
+	}
+	return ""
+}
+
+// ipBucket puts the requests each IP address makes to a merchant in their own bucket
+func ipBucket(r *http.Request) string {
+	settings := rateLimitedMerchant(r.Context())
+	if settings == nil {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + settings.MerchantID + ":" + ip
+}
+
+func lookupMerchantRateLimit(ctx context.Context, bucket string) (*middleware.KeyRateLimit, error) {
+	return rateLimitedMerchant(ctx).merchantRateLimit(), nil
+}
+
+func lookupIPRateLimit(ctx context.Context, bucket string) (*middleware.KeyRateLimit, error) {
+	return rateLimitedMerchant(ctx).ipRateLimit(), nil
+}
+
+// credentialRateLimited rate limits credential signing and redemption per merchant, and per IP address within
+// each merchant, as the merchant's settings configure. Limited requests are rejected with 429 Too Many Requests
+// and a Retry-After header. Requests without a merchant are passed through for the handler to reject
+func credentialRateLimited(service *Service, merchantOf merchantFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if service.rateLimitStore == nil {
+			return next
+		}
+		byMerchant := middleware.KeyRateLimiter(context.Background(), service.rateLimitStore, merchantBucket, lookupMerchantRateLimit)
+		byIP := middleware.KeyRateLimiter(context.Background(), service.rateLimitStore, ipBucket, lookupIPRateLimit)
+		limited := byMerchant(byIP(next))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			merchantID, err := merchantOf(r)
+			if err != nil {
+				handlers.RenderError(w, r, handlers.WrapError(err, "Error getting merchant", http.StatusInternalServerError))
+				return
+			}
+			if merchantID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			settings, err := service.Datastore.GetMerchantSettings(r.Context(), merchantID)
+			if err != nil {
+				handlers.RenderError(w, r, handlers.WrapError(err, "Error getting merchant settings", http.StatusInternalServerError))
+				return
+			}
+			if settings == nil {
+				settings = &MerchantSettings{MerchantID: merchantID}
+			}
+			ctx := context.WithValue(r.Context(), appctx.RateLimitedMerchantCTXKey, settings)
+			limited.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialRateLimited(t *testing.T) {
+	store, err := middleware.NewRateLimitStore(context.Background(), "")
+	require.NoError(t, err)
+	slow, fast, burst := 1, 60, 10
+	ds := newFakeDatastore()
+	ipLimited := ds.addOrder(Order{MerchantID: "brave.com"}).ID
+	merchantLimited := ds.addOrder(Order{MerchantID: "brave.software"}).ID
+	ds.settings = map[string]MerchantSettings{
+		"brave.com":      {MerchantID: "brave.com", RateLimitPerMinute: &fast, RateLimitBurst: &burst, IPRateLimitPerMinute: &slow},
+		"brave.software": {MerchantID: "brave.software", RateLimitPerMinute: &slow},
+	}
+	service := &Service{Datastore: ds, rateLimitStore: store}
+
+	r := chi.NewRouter()
+	r.Method("POST", "/{orderID}/credentials", credentialRateLimited(service, orderMerchant(service))(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	post := func(orderID uuid.UUID, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/"+orderID.String()+"/credentials", nil)
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, post(ipLimited, "10.0.0.1").Code)
+	rr := post(ipLimited, "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, post(ipLimited, "10.0.0.2").Code, "each IP address has its own bucket")
+
+	assert.Equal(t, http.StatusOK, post(merchantLimited, "10.0.0.1").Code)
+	rr = post(merchantLimited, "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "the merchant's bucket is shared by all IP addresses")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, post(uuid.NewV4(), "10.0.0.1").Code, "unknown orders are left to the handler")
+}
+
+func TestBodyMerchant(t *testing.T) {
+	req := httptest.NewRequest("POST", "/subscription/verifications", strings.NewReader(`{"merchantId":"brave.com"}`))
+	merchantID, err := bodyMerchant(req)
+	require.NoError(t, err)
+	assert.Equal(t, "brave.com", merchantID)
+
+	var body VerifyCredentialRequest
+	require.NoError(t, requestutils.ReadJSON(req.Body, &body), "the body is left for the handler")
+	assert.Equal(t, "brave.com", body.MerchantID)
+}
+
+func TestMerchantRateLimitDefaults(t *testing.T) {
+	var unset MerchantSettings
+	assert.Nil(t, unset.merchantRateLimit(), "merchants are not limited by default")
+
+	require.NoError(t, os.Setenv("CREDENTIAL_RATE_LIMIT_PER_MINUTE", "600"))
+	require.NoError(t, os.Setenv("CREDENTIAL_RATE_LIMIT_BURST", "60"))
+	defer func() {
+		_ = os.Unsetenv("CREDENTIAL_RATE_LIMIT_PER_MINUTE")
+		_ = os.Unsetenv("CREDENTIAL_RATE_LIMIT_BURST")
+	}()
+	assert.Equal(t, &middleware.KeyRateLimit{PerMinute: 600, Burst: 60}, unset.merchantRateLimit())
+	assert.Nil(t, unset.ipRateLimit())
+
+	perMinute := 10
+	settings := MerchantSettings{IPRateLimitPerMinute: &perMinute}
+	assert.Equal(t, &middleware.KeyRateLimit{PerMinute: 10}, settings.ipRateLimit())
+	settings.withRateLimitDefaults()
+	assert.Equal(t, 600, *settings.RateLimitPerMinute)
+	assert.Equal(t, 60, *settings.RateLimitBurst)
+	assert.Equal(t, 0, *settings.IPRateLimitBurst)
+}
+
+func TestMerchantRouterKeyRateLimit(t *testing.T) {
+	oldEnv := os.Getenv("ENV")
+	defer func() { _ = os.Setenv("ENV", oldEnv) }()
+	require.NoError(t, os.Setenv("ENV", "test"))
+
+	store, err := middleware.NewRateLimitStore(context.Background(), "")
+	require.NoError(t, err)
+	ds := newFakeDatastore()
+	newKey := func(perMinute *int) string {
+		token, tokenHash, err := GenerateToken()
+		require.NoError(t, err)
+		key := &Key{ID: uuid.NewV4().String(), Merchant: "brave.com", Scopes: []string{KeyScopeKeysManage},
+			TokenHash: &tokenHash, RateLimitPerMinute: perMinute}
+		ds.keys[key.ID] = key
+		return token
+	}
+	perMinute := 1
+	limited, unlimited := newKey(&perMinute), newKey(nil)
+	router := middleware.BearerToken(MerchantRouter(&Service{Datastore: ds, rateLimitStore: store}))
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/brave.com/keys", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(limited)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Limit"), "the limit of the authenticated key applies")
+	rr = get(limited)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get(unlimited).Code, "keys without limits are not limited")
+	}
+	assert.Equal(t, http.StatusUnauthorized, get("").Code)
+}
@@ -0,0 +1,245 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/ed25519"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// ErrReceiptsNotConfigured is the error when requesting a receipt without RECEIPT_SIGNING_KEY set
+var ErrReceiptsNotConfigured = errorutils.NewApplicationError("receipts_not_configured", http.StatusServiceUnavailable, "RECEIPT_SIGNING_KEY is not set", false)
+
+// Receipt is the signed statement that an order was paid, what was bought and how it was paid for
+type Receipt struct {
+	OrderID    uuid.UUID        `json:"orderId"`
+	MerchantID string           `json:"merchantId"`
+	Currency   string           `json:"currency"`
+	TotalPrice decimal.Decimal  `json:"totalPrice"`
+	Items      []ReceiptItem    `json:"items"`
+	Payments   []ReceiptPayment `json:"payments"`
+	IssuedAt   time.Time        `json:"issuedAt"`
+}
+
+// ReceiptItem is an item of a receipt
+type ReceiptItem struct {
+	SKU      string          `json:"sku"`
+	Quantity int             `json:"quantity"`
+	Price    decimal.Decimal `json:"price"`
+	Subtotal decimal.Decimal `json:"subtotal"`
+}
+
+// ReceiptPayment is a completed transaction of a receipt, referenced by the id its provider gave it
+type ReceiptPayment struct {
+	Reference string          `json:"reference"`
+	Kind      string          `json:"kind"`
+	Currency  string          `json:"currency"`
+	Amount    decimal.Decimal `json:"amount"`
+	PaidAt    time.Time       `json:"paidAt"`
+}
+
+// OrderReceipt is the receipt of an order as stored with it, a compact JWS of the Receipt signed with the key
+// KeyID names in the published receipt keys
+type OrderReceipt struct {
+	OrderID   uuid.UUID `json:"orderId" db:"order_id"`
+	KeyID     string    `json:"keyId" db:"key_id"`
+	Receipt   string    `json:"receipt" db:"receipt"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// LoadReceiptKeys loads the ed25519 JSON web key receipts are signed with from RECEIPT_SIGNING_KEY, if set,
+// and the public keys receipts were signed with before it was rotated from RECEIPT_RETIRED_KEYS, a JSON web
+// key set. Keys without a key id are given their thumbprint
+func LoadReceiptKeys() (*jose.JSONWebKey, []jose.JSONWebKey, error) {
+	raw := os.Getenv("RECEIPT_SIGNING_KEY")
+	if raw == "" {
+		return nil, nil, nil
+	}
+	var key jose.JSONWebKey
+	if err := key.UnmarshalJSON([]byte(raw)); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse receipt signing key: %w", err)
+	}
+	if _, ok := key.Key.(ed25519.PrivateKey); !ok {
+		return nil, nil, errors.New("receipt signing key must be an ed25519 private key")
+	}
+	if err := setKeyID(&key); err != nil {
+		return nil, nil, err
+	}
+
+	published := []jose.JSONWebKey{key.Public()}
+	if raw := os.Getenv("RECEIPT_RETIRED_KEYS"); raw != "" {
+		var retired jose.JSONWebKeySet
+		if err := json.Unmarshal([]byte(raw), &retired); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse retired receipt keys: %w", err)
+		}
+		for i := range retired.Keys {
+			if !retired.Keys[i].IsPublic() {
+				return nil, nil, errors.New("retired receipt keys must be public keys")
+			}
+			if err := setKeyID(&retired.Keys[i]); err != nil {
+				return nil, nil, err
+			}
+			published = append(published, retired.Keys[i])
+		}
+	}
+	return &key, published, nil
+}
+
+// setKeyID names a key by its thumbprint unless it is named already
+func setKeyID(key *jose.JSONWebKey) error {
+	if key.KeyID != "" {
+		return nil
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to compute receipt key thumbprint: %w", err)
+	}
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return nil
+}
+
+// receiptable tells whether the order was paid, so a receipt can be issued for it
+func (order Order) receiptable() bool {
+	return order.IsPaid() || order.Status == "fulfilled" || order.Status == OrderLogRefunded
+}
+
+// signReceipt signs the receipt of a paid order, listing its completed transactions as its payments
+func (s *Service) signReceipt(order *Order) (*OrderReceipt, error) {
+	transactions, err := s.Datastore.GetTransactions(order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	receipt := Receipt{
+		OrderID:    order.ID,
+		MerchantID: order.MerchantID,
+		Currency:   order.Currency,
+		TotalPrice: order.TotalPrice,
+		Items:      []ReceiptItem{},
+		Payments:   []ReceiptPayment{},
+		IssuedAt:   time.Now().UTC(),
+	}
+	for _, item := range order.Items {
+		receipt.Items = append(receipt.Items, ReceiptItem{
+			SKU:      item.SKU,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+			Subtotal: item.Subtotal,
+		})
+	}
+	if transactions != nil {
+		for _, transaction := range *transactions {
+			if transaction.Status != "completed" {
+				continue
+			}
+			receipt.Payments = append(receipt.Payments, ReceiptPayment{
+				Reference: transaction.ExternalTransactionID,
+				Kind:      transaction.Kind,
+				Currency:  transaction.Currency,
+				Amount:    transaction.Amount,
+				PaidAt:    transaction.CreatedAt,
+			})
+		}
+	}
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: s.receiptKey}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receipt signer: %w", err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	compact, err := jws.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize receipt: %w", err)
+	}
+	return &OrderReceipt{OrderID: order.ID, KeyID: s.receiptKey.KeyID, Receipt: compact}, nil
+}
+
+// GetOrderReceipt returns the receipt of a paid order. The receipt is issued the first time it is requested
+// once the order is paid and stored with the order, so the same receipt is returned from then on
+func (s *Service) GetOrderReceipt(ctx context.Context, order *Order) (*OrderReceipt, error) {
+	receipt, err := s.Datastore.GetOrderReceipt(ctx, order.ID)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+	if s.receiptKey == nil {
+		return nil, ErrReceiptsNotConfigured
+	}
+	if !order.receiptable() {
+		return nil, ErrOrderNotPaid
+	}
+
+	receipt, err = s.signReceipt(order)
+	if err != nil {
+		return nil, err
+	}
+	// a receipt issued concurrently is kept, and returned in place of this one
+	if err := s.Datastore.InsertOrderReceipt(ctx, receipt); err != nil {
+		return nil, err
+	}
+	return s.Datastore.GetOrderReceipt(ctx, order.ID)
+}
+
+// GetOrderReceipt is the handler for the signed receipt of a paid order
+func GetOrderReceipt(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(r.Context(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{"orderID": err.Error()})
+		}
+
+		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		receipt, err := service.GetOrderReceipt(r.Context(), order)
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order's receipt", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), receipt, w, http.StatusOK)
+	})
+}
+
+// GetReceiptKeys is the handler for the JSON web key set of the public keys receipts are verified with
+func GetReceiptKeys(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		if service.receiptKey == nil {
+			return handlers.WrapError(ErrReceiptsNotConfigured, "", http.StatusServiceUnavailable)
+		}
+		w.Header().Set("cache-control", "public, max-age=3600")
+		return handlers.RenderContent(r.Context(), jose.JSONWebKeySet{Keys: service.receiptKeys}, w, http.StatusOK)
+	})
+}
+
+// WellKnownRouter serves the well known documents of the payment service, the receipt keys at
+// /payment-receipt-keys.json
+func WellKnownRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/payment-receipt-keys.json", middleware.InstrumentHandler("GetReceiptKeys", GetReceiptKeys(service)))
+	return r
+}
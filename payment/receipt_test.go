@@ -0,0 +1,126 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func setReceiptSigningKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	raw, err := (&jose.JSONWebKey{Key: priv}).MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, os.Setenv("RECEIPT_SIGNING_KEY", string(raw)))
+	t.Cleanup(func() { _ = os.Unsetenv("RECEIPT_SIGNING_KEY") })
+}
+
+func TestGetOrderReceipt(t *testing.T) {
+	setReceiptSigningKey(t)
+	receiptKey, receiptKeys, err := LoadReceiptKeys()
+	require.NoError(t, err)
+	require.Len(t, receiptKeys, 1)
+	assert.NotEmpty(t, receiptKey.KeyID, "keys are named by their thumbprint")
+
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds, receiptKey: receiptKey, receiptKeys: receiptKeys}
+	order := &Order{
+		ID:         uuid.NewV4(),
+		MerchantID: "brave.com",
+		Status:     "pending",
+		Currency:   "USD",
+		TotalPrice: decimal.New(5, 0),
+		Items:      []OrderItem{{SKU: "brave-vpn", Quantity: 1, Price: decimal.New(5, 0), Subtotal: decimal.New(5, 0)}},
+	}
+
+	ds.transactions = []Transaction{
+		{OrderID: order.ID, ExternalTransactionID: "pi_1", Status: "completed", Kind: "stripe", Currency: "USD", Amount: decimal.New(5, 0)},
+		{OrderID: order.ID, ExternalTransactionID: "pi_2", Status: "failed", Kind: "stripe", Currency: "USD", Amount: decimal.New(5, 0)},
+	}
+
+	_, err = service.GetOrderReceipt(context.Background(), order)
+	assert.True(t, errors.Is(err, ErrOrderNotPaid), "receipts are only issued for paid orders")
+
+	order.Status = "paid"
+	receipt, err := service.GetOrderReceipt(context.Background(), order)
+	require.NoError(t, err)
+	assert.Equal(t, receiptKey.KeyID, receipt.KeyID)
+
+	jws, err := jose.ParseSigned(receipt.Receipt)
+	require.NoError(t, err)
+	assert.Equal(t, receiptKey.KeyID, jws.Signatures[0].Header.KeyID)
+	payload, err := jws.Verify(&receiptKeys[0])
+	require.NoError(t, err, "receipts verify with the published key")
+	var signed Receipt
+	require.NoError(t, json.Unmarshal(payload, &signed))
+	assert.Equal(t, order.ID, signed.OrderID)
+	assert.Equal(t, "5", signed.TotalPrice.String())
+	require.Len(t, signed.Items, 1)
+	assert.Equal(t, "brave-vpn", signed.Items[0].SKU)
+	require.Len(t, signed.Payments, 1, "only completed transactions are payments")
+	assert.Equal(t, "pi_1", signed.Payments[0].Reference)
+
+	again, err := service.GetOrderReceipt(context.Background(), order)
+	require.NoError(t, err)
+	assert.Equal(t, receipt.Receipt, again.Receipt, "the stored receipt is returned from then on")
+
+	rr := httptest.NewRecorder()
+	rr.Header().Set("content-type", "application/json")
+	WellKnownRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/payment-receipt-keys.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var keys jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &keys))
+	require.Len(t, keys.Key(receiptKey.KeyID), 1)
+	assert.True(t, keys.Key(receiptKey.KeyID)[0].IsPublic(), "only the public key is published")
+}
+
+func TestGetOrderReceiptNotConfigured(t *testing.T) {
+	service := &Service{Datastore: newFakeDatastore()}
+	_, err := service.GetOrderReceipt(context.Background(), &Order{ID: uuid.NewV4(), Status: "paid"})
+	assert.True(t, errors.Is(err, ErrReceiptsNotConfigured))
+}
+
+func TestOrderReceipt(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID := uuid.NewV4()
+
+	// an order keeps the first receipt issued for it
+	mock.ExpectExec(`INSERT INTO order_receipts (.+) ON CONFLICT \(order_id\) DO NOTHING`).
+		WithArgs(orderID, "key", "receipt").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, pg.InsertOrderReceipt(context.Background(), &OrderReceipt{OrderID: orderID, KeyID: "key", Receipt: "receipt"}))
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT order_id, key_id, receipt, created_at FROM order_receipts WHERE order_id = \$1`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "key_id", "receipt", "created_at"}).AddRow(orderID, "key", "receipt", now))
+	receipt, err := pg.GetOrderReceipt(context.Background(), orderID)
+	require.NoError(t, err)
+	assert.Equal(t, &OrderReceipt{OrderID: orderID, KeyID: "key", Receipt: "receipt", CreatedAt: now}, receipt)
+
+	mock.ExpectQuery(`FROM order_receipts`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "key_id", "receipt", "created_at"}))
+	receipt, err = pg.GetOrderReceipt(context.Background(), orderID)
+	require.NoError(t, err)
+	assert.Nil(t, receipt, "orders without a receipt have none")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,126 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+)
+
+// ErrCredentialRedeemed is returned for credentials which were already redeemed, without redeeming them again
+var ErrCredentialRedeemed = errorutils.NewApplicationError("credential_redeemed", http.StatusConflict, "credential was already redeemed", false)
+
+// RedeemedCredential is a credential redeemed with the challenge bypass server, recorded so credentials
+// submitted again are rejected without a round trip to it
+type RedeemedCredential struct {
+	TokenPreimage string    `db:"token_preimage"`
+	IssuerID      string    `db:"issuer_id"`
+	Payload       string    `db:"payload"`
+	RedeemedAt    time.Time `db:"redeemed_at"`
+}
+
+// newRedeemedCredentials returns the records of the credentials redeemed toward the payload
+func newRedeemedCredentials(credentials []cbr.CredentialRedemption, payload string) []RedeemedCredential {
+	redeemed := make([]RedeemedCredential, len(credentials))
+	for i, credential := range credentials {
+		redeemed[i] = RedeemedCredential{
+			TokenPreimage: credential.TokenPreimage,
+			IssuerID:      credential.Issuer,
+			Payload:       payload,
+		}
+	}
+	return redeemed
+}
+
+// checkCredentialsRedeemed returns ErrCredentialRedeemed when any of the credentials was already redeemed
+func checkCredentialsRedeemed(ctx context.Context, db ReadOnlyDatastore, tokenPreimages ...string) error {
+	redeemed, err := db.GetCredentialRedemptions(ctx, tokenPreimages)
+	if err != nil {
+		return fmt.Errorf("failed to check credential redemptions: %w", err)
+	}
+	if len(redeemed) > 0 {
+		return fmt.Errorf("%w: %d of %d credentials were redeemed", ErrCredentialRedeemed, len(redeemed), len(tokenPreimages))
+	}
+	return nil
+}
+
+// defaultRedemptionBatchSize is how many credentials are redeemed per bulk redemption unless
+// REDEMPTION_BATCH_SIZE is set
+const defaultRedemptionBatchSize = 100
+
+// redemptionBatchSize is how many credentials are redeemed per call to the bulk redemption endpoint
+func redemptionBatchSize() int {
+	if size, err := strconv.Atoi(os.Getenv("REDEMPTION_BATCH_SIZE")); err == nil && size > 0 {
+		return size
+	}
+	return defaultRedemptionBatchSize
+}
+
+// CredentialRedemptionError is the failure to redeem one credential
+type CredentialRedemptionError struct {
+	Credential cbr.CredentialRedemption
+	Err        error
+}
+
+// RedemptionErrors are the credentials of a redemption which failed to redeem, the rest were redeemed
+type RedemptionErrors struct {
+	Failed []CredentialRedemptionError
+	Total  int
+}
+
+func (e *RedemptionErrors) Error() string {
+	return fmt.Sprintf("failed to redeem %d of %d credentials: %s", len(e.Failed), e.Total, e.Failed[0].Err)
+}
+
+// Unwrap returns the error of the first credential which failed to redeem
+func (e *RedemptionErrors) Unwrap() error {
+	return e.Failed[0].Err
+}
+
+// redeemCredentials redeems the credentials toward the payload in batches through the bulk redemption endpoint,
+// returning the credentials which were redeemed. The credentials of a batch which fails are returned in
+// RedemptionErrors, and while the circuit to the challenge bypass server is open the rest are not attempted
+func (s *Service) redeemCredentials(ctx context.Context, credentials []cbr.CredentialRedemption, payload string) ([]cbr.CredentialRedemption, error) {
+	var (
+		batchSize = s.redemptionBatchSize
+		redeemed  = make([]cbr.CredentialRedemption, 0, len(credentials))
+		failed    []CredentialRedemptionError
+		stopErr   error
+	)
+	if batchSize < 1 {
+		batchSize = defaultRedemptionBatchSize
+	}
+
+	for start := 0; start < len(credentials); start += batchSize {
+		end := start + batchSize
+		if end > len(credentials) {
+			end = len(credentials)
+		}
+		batch := credentials[start:end]
+
+		err := stopErr
+		if err == nil {
+			err = s.cbClient.RedeemCredentials(ctx, batch, payload)
+			if cbr.IsCircuitOpen(err) {
+				stopErr = err
+			}
+		}
+		if err != nil {
+			for _, credential := range batch {
+				failed = append(failed, CredentialRedemptionError{Credential: credential, Err: err})
+			}
+			continue
+		}
+		redeemed = append(redeemed, batch...)
+	}
+
+	if len(failed) > 0 {
+		return redeemed, &RedemptionErrors{Failed: failed, Total: len(credentials)}
+	}
+	return redeemed, nil
+}
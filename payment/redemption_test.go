@@ -0,0 +1,141 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/clients/cbr/cbrtest"
+	mockcb "github.com/brave-intl/bat-go/utils/clients/cbr/mock"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/golang/mock/gomock"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRedemptions(n int) []cbr.CredentialRedemption {
+	credentials := make([]cbr.CredentialRedemption, n)
+	for i := range credentials {
+		credentials[i] = cbr.CredentialRedemption{Issuer: "issuer", TokenPreimage: fmt.Sprintf("t%d", i), Signature: "sig"}
+	}
+	return credentials
+}
+
+func TestRedeemCredentialsBatches(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockCB := mockcb.NewMockClient(mockCtrl)
+	service := &Service{cbClient: mockCB, redemptionBatchSize: 2}
+
+	credentials := testRedemptions(5)
+	failure := errors.New("cbr duplicate redemption")
+	gomock.InOrder(
+		mockCB.EXPECT().RedeemCredentials(gomock.Any(), credentials[0:2], "vote").Return(nil),
+		mockCB.EXPECT().RedeemCredentials(gomock.Any(), credentials[2:4], "vote").Return(failure),
+		mockCB.EXPECT().RedeemCredentials(gomock.Any(), credentials[4:5], "vote").Return(nil),
+	)
+
+	redeemed, err := service.redeemCredentials(context.Background(), credentials, "vote")
+	assert.Equal(t, append(credentials[0:2:2], credentials[4]), redeemed)
+
+	var redemptionErrs *RedemptionErrors
+	require.True(t, errors.As(err, &redemptionErrs))
+	assert.Equal(t, 5, redemptionErrs.Total)
+	require.Len(t, redemptionErrs.Failed, 2)
+	assert.Equal(t, credentials[2], redemptionErrs.Failed[0].Credential)
+	assert.Equal(t, credentials[3], redemptionErrs.Failed[1].Credential)
+	assert.True(t, errors.Is(err, failure))
+}
+
+func TestRedeemCredentialsCircuitOpen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockCB := mockcb.NewMockClient(mockCtrl)
+	service := &Service{cbClient: mockCB, redemptionBatchSize: 2}
+
+	credentials := testRedemptions(6)
+	mockCB.EXPECT().RedeemCredentials(gomock.Any(), credentials[0:2], "vote").
+		Return(&cbr.CircuitOpenError{Method: "RedeemCredentials"})
+
+	redeemed, err := service.redeemCredentials(context.Background(), credentials, "vote")
+	assert.Empty(t, redeemed)
+	assert.True(t, cbr.IsCircuitOpen(err))
+	var redemptionErrs *RedemptionErrors
+	require.True(t, errors.As(err, &redemptionErrs))
+	assert.Len(t, redemptionErrs.Failed, 6, "batches after the circuit opened are not attempted")
+}
+
+func TestGenerateCredentialRedemptionsRedeemed(t *testing.T) {
+	issuerCache.Flush()
+	defer issuerCache.Flush()
+
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds, cbClient: &issuerClient{}}
+	issuer, err := service.CreateIssuer(ctx, "brave.com?sku=anon-card-vote")
+	require.NoError(t, err)
+
+	redeemed := []cbr.CredentialRedemption{{Issuer: issuer.Name(), TokenPreimage: "a", Signature: "a"}}
+	require.NoError(t, ds.InsertCredentialRedemptions(ctx, nil, newRedeemedCredentials(redeemed, "vote")))
+
+	ctx = context.WithValue(ctx, appctx.DatastoreCTXKey, Datastore(ds))
+	_, err = generateCredentialRedemptions(ctx, []CredentialBinding{
+		{PublicKey: issuer.PublicKey, TokenPreimage: "b", Signature: "b"},
+		{PublicKey: issuer.PublicKey, TokenPreimage: "a", Signature: "a"},
+	})
+	assert.True(t, errors.Is(err, ErrCredentialRedeemed), "credentials which were redeemed are rejected before cbr is called")
+
+	redemptions, err := generateCredentialRedemptions(ctx, []CredentialBinding{
+		{PublicKey: issuer.PublicKey, TokenPreimage: "b", Signature: "b"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, redemptions, 1)
+}
+
+func TestSignAndRedeemOrderCreds(t *testing.T) {
+	ctx := context.Background()
+	server := cbrtest.NewServer()
+	service := &Service{cbClient: server}
+
+	issuer := Issuer{MerchantID: "brave.com?sku=anon-card-vote", Version: 1}
+	require.NoError(t, server.CreateIssuer(ctx, issuer.Name(), defaultMaxTokensPerIssuer))
+	resp, err := server.GetIssuer(ctx, issuer.Name())
+	require.NoError(t, err)
+	issuer.PublicKey = resp.PublicKey
+
+	tokens := make([]*ristretto.Token, 2)
+	blinded := make([]string, len(tokens))
+	for i := range tokens {
+		tokens[i], err = ristretto.NewToken()
+		require.NoError(t, err)
+		blinded[i], err = tokens[i].Blind()
+		require.NoError(t, err)
+	}
+
+	creds, err := service.SignOrderCreds(ctx, uuid.NewV4(), issuer, blinded)
+	require.NoError(t, err)
+	require.NoError(t, verifySignedCreds(issuer, blinded, creds), "credentials are signed with the issuer key")
+
+	var redemptions []cbr.CredentialRedemption
+	for i, token := range tokens {
+		unblinded, err := token.Unblind((*creds.SignedCreds)[i])
+		require.NoError(t, err)
+		redemptions = append(redemptions, cbr.CredentialRedemption{
+			Issuer:        issuer.Name(),
+			TokenPreimage: unblinded.EncodedPreimage(),
+			Signature:     unblinded.Sign("vote"),
+		})
+	}
+
+	redeemed, err := service.redeemCredentials(ctx, redemptions, "vote")
+	require.NoError(t, err)
+	assert.Len(t, redeemed, 2)
+	assert.Equal(t, 2, server.Redeemed(issuer.Name()))
+
+	_, err = service.redeemCredentials(ctx, redemptions[:1], "vote")
+	assert.True(t, cbr.IsDuplicateRedemption(err), "credentials redeem once")
+}
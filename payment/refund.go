@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	// ErrOrderNotRefundable is returned when refunding an order which has not been paid
+	ErrOrderNotRefundable = errorutils.NewApplicationError("order_not_refundable", http.StatusConflict, "only paid orders can be refunded", false)
+	// ErrOrderRefunded is returned when changing the status of, or paying towards, a refunded order
+	ErrOrderRefunded = errorutils.NewApplicationError("order_refunded", http.StatusConflict, "order is refunded", false)
+)
+
+// RefundOrder refunds a paid order, revoking its signed credentials with the challenge bypass server so they
+// can no longer be redeemed and deleting them so the order's credentials can't be fetched. The refund reverses
+// the order's ledger, and a refunded order is never paid again. It returns nil when there is no such order.
+// Refunding an order which is already refunded retries the revocation, so a refund which failed part way can
+// be finished
+func (s *Service) RefundOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if order.Status != "paid" && order.Status != OrderLogRefunded {
+		return nil, ErrOrderNotRefundable
+	}
+
+	// credentials are revoked first, the order is only refunded once none of them can be redeemed
+	if err := s.revokeOrderCreds(ctx, orderID); err != nil {
+		return nil, err
+	}
+	if err := s.Datastore.UpdateOrder(ctx, orderID, OrderLogRefunded); err != nil {
+		return nil, err
+	}
+	if err := s.Datastore.DeleteOrderCreds(ctx, orderID); err != nil {
+		return nil, fmt.Errorf("failed to delete revoked credentials: %w", err)
+	}
+	s.NotifyOrderChanged(orderID)
+	return s.Datastore.GetOrder(orderID)
+}
+
+// revokeOrderCreds revokes the signed credentials of each of the order's items with the issuer which signed them
+func (s *Service) revokeOrderCreds(ctx context.Context, orderID uuid.UUID) error {
+	creds, err := s.Datastore.GetOrderCreds(orderID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get order credentials: %w", err)
+	}
+	if creds == nil {
+		return nil
+	}
+
+	for _, cred := range *creds {
+		if cred.PublicKey == nil {
+			continue
+		}
+		issuer, err := s.Datastore.GetIssuerByPublicKey(*cred.PublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to get issuer of credentials: %w", err)
+		}
+		if issuer == nil {
+			return fmt.Errorf("no issuer has the public key of item %s", cred.ID)
+		}
+		if err := s.cbClient.RevokeCredentials(ctx, issuer.Name(), cred.BlindedCreds); err != nil {
+			return fmt.Errorf("failed to revoke credentials of item %s: %w", cred.ID, err)
+		}
+	}
+	return nil
+}
+
+// RefundOrder is the handler for refunding a paid order and revoking its credentials
+func RefundOrder(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.RefundOrder(r.Context(), *orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error refunding the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		return handlers.RenderContent(r.Context(), order, w, http.StatusOK)
+	})
+}
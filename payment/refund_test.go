@@ -0,0 +1,134 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revokingClient records the credentials revoked with each issuer
+type revokingClient struct {
+	cbr.Client
+	revoked map[string][]string
+	err     error
+}
+
+func (c *revokingClient) RevokeCredentials(ctx context.Context, issuer string, creds []string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.revoked[issuer] = append(c.revoked[issuer], creds...)
+	return nil
+}
+
+func TestRefundOrder(t *testing.T) {
+	ctx := context.Background()
+	publicKey := "key"
+	signed := jsonutils.TextArray{"signed"}
+	ds := newFakeDatastore()
+	stored := ds.addOrder(Order{Status: "paid", Currency: "BAT", TotalPrice: decimal.New(5, 0)})
+	orderID := stored.ID
+	transactionID := uuid.NewV4()
+	ds.payments = []OrderPayment{{ID: uuid.NewV4(), OrderID: orderID, TransactionID: &transactionID, Kind: "uphold",
+		Currency: "BAT", Amount: decimal.New(5, 0)}}
+	ds.creds = []OrderCreds{
+		{ID: uuid.NewV4(), OrderID: orderID, BlindedCreds: []string{"a", "b"}, SignedCreds: &signed, PublicKey: &publicKey},
+		{ID: uuid.NewV4(), OrderID: orderID, BlindedCreds: []string{"c"}},
+	}
+	ds.issuers = []Issuer{{MerchantID: "brave.com?sku=vpn", PublicKey: publicKey, Version: 2}}
+	client := &revokingClient{revoked: map[string][]string{}, err: errors.New("unavailable")}
+	service := &Service{Datastore: ds, cbClient: client}
+
+	// the order is only refunded once its credentials are revoked
+	_, err := service.RefundOrder(ctx, orderID)
+	assert.Error(t, err)
+	assert.Equal(t, "paid", stored.Status)
+	assert.Len(t, ds.creds, 2)
+
+	client.err = nil
+	order, err := service.RefundOrder(ctx, orderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderLogRefunded, order.Status)
+	assert.Empty(t, ds.creds)
+	// credentials which were not signed yet have nothing to revoke
+	assert.Equal(t, map[string][]string{"brave.com?sku=vpn&v=2": {"a", "b"}}, client.revoked)
+
+	// the refund reverses the ledger, so the order is not paid again by what was paid before
+	paid, err := service.isOrderPaid(ctx, order)
+	require.NoError(t, err)
+	assert.False(t, paid)
+	require.Len(t, ds.payments, 2)
+	assert.Nil(t, ds.payments[1].TransactionID)
+	assert.True(t, decimal.New(-5, 0).Equal(ds.payments[1].Amount))
+	assert.Equal(t, ErrOrderRefunded, ds.UpdateOrder(ctx, orderID, "paid"), "refunded orders are never paid again")
+	_, err = service.CreateAnonCardTransaction(ctx, uuid.NewV4(), "transaction", orderID)
+	assert.Equal(t, ErrOrderRefunded, err, "no payments are taken towards refunded orders")
+
+	// refunds can be retried, reversing the ledger once
+	_, err = service.RefundOrder(ctx, orderID)
+	assert.NoError(t, err)
+	assert.Len(t, ds.payments, 2)
+
+	stored.Status = "pending"
+	_, err = service.RefundOrder(ctx, orderID)
+	assert.Equal(t, ErrOrderNotRefundable, err)
+
+	delete(ds.orders, orderID)
+	order, err = service.RefundOrder(ctx, orderID)
+	assert.NoError(t, err)
+	assert.Nil(t, order)
+}
+
+func TestUpdateOrderRefunded(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID, eventID := uuid.NewV4(), uuid.NewV4()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+	mock.ExpectExec(`INSERT INTO order_payments \(order_id, kind, currency, amount\) SELECT order_id, 'refund', currency, -sum\(amount\)`).
+		WithArgs(orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE orders set status = (.+)`).WithArgs(OrderLogRefunded, orderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE orders SET event_sequence = event_sequence \+ 1`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"event_sequence"}).AddRow(4))
+	mock.ExpectQuery(`INSERT INTO order_events`).
+		WithArgs(orderID, 4, OrderLogRefunded, []byte(`{"status":"refunded","previous":"paid"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "sequence", "type", "payload", "created_at", "dispatched_at"}).
+			AddRow(eventID, orderID, 4, OrderLogRefunded, []byte(`{}`), time.Now(), nil))
+	mock.ExpectExec(`INSERT INTO order_event_outbox`).WithArgs(eventID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, pg.UpdateOrder(context.Background(), orderID, OrderLogRefunded))
+
+	// only paid orders are refunded
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderNotRefundable, pg.UpdateOrder(context.Background(), orderID, OrderLogRefunded))
+
+	// a refunded order is not paid again, whatever settles towards it afterwards
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM orders WHERE id = (.+) FOR UPDATE`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(OrderLogRefunded))
+	mock.ExpectRollback()
+	assert.Equal(t, ErrOrderRefunded, pg.UpdateOrder(context.Background(), orderID, "paid"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package payment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+// replicaRetryAfter is how long reads skip a replica which failed a query before it is tried again
+const replicaRetryAfter = 30 * time.Second
+
+var replicaFallbacks = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "payment_replica_fallbacks_total",
+		Help: "Reads served by the primary instead of the read replica, by method and reason",
+	},
+	[]string{"method", "reason"},
+)
+
+func init() {
+	prometheus.MustRegister(replicaFallbacks)
+}
+
+// replicaDatastore reads from a read replica, falling back to the primary when the replica fails a query or
+// has not yet replicated what is read. A replica which failed a query is skipped until replicaRetryAfter has
+// passed, so reads do not wait on a replica which is down
+type replicaDatastore struct {
+	ReadOnlyDatastore
+	primary Datastore
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// newReplicaDatastore returns a read only datastore reading from the replica ahead of the primary
+func newReplicaDatastore(replica ReadOnlyDatastore, primary Datastore) *replicaDatastore {
+	return &replicaDatastore{ReadOnlyDatastore: replica, primary: primary}
+}
+
+// available is whether reads should try the replica
+func (ds *replicaDatastore) available() bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return time.Now().After(ds.downUntil)
+}
+
+// fallback records a read which falls back to the primary, marking the replica down when it failed the read
+func (ds *replicaDatastore) fallback(method string, err error) {
+	reason := "miss"
+	if err != nil {
+		reason = "error"
+		ds.mu.Lock()
+		ds.downUntil = time.Now().Add(replicaRetryAfter)
+		ds.mu.Unlock()
+	}
+	replicaFallbacks.WithLabelValues(method, reason).Inc()
+}
+
+// GetOrder from the replica, or from the primary when the replica is down or the order has not replicated
+func (ds *replicaDatastore) GetOrder(orderID uuid.UUID) (*Order, error) {
+	if ds.available() {
+		order, err := ds.ReadOnlyDatastore.GetOrder(orderID)
+		if err == nil && order != nil {
+			return order, nil
+		}
+		ds.fallback("GetOrder", err)
+	}
+	return ds.primary.GetOrder(orderID)
+}
+
+// GetIssuerByPublicKey from the replica, or from the primary when the replica is down or the issuer has not
+// replicated
+func (ds *replicaDatastore) GetIssuerByPublicKey(publicKey string) (*Issuer, error) {
+	if ds.available() {
+		issuer, err := ds.ReadOnlyDatastore.GetIssuerByPublicKey(publicKey)
+		if err == nil && issuer != nil {
+			return issuer, nil
+		}
+		ds.fallback("GetIssuerByPublicKey", err)
+	}
+	return ds.primary.GetIssuerByPublicKey(publicKey)
+}
+
+// GetOrderCreds from the replica, or from the primary when the replica is down or the credentials have not
+// replicated
+func (ds *replicaDatastore) GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]OrderCreds, error) {
+	if ds.available() {
+		creds, err := ds.ReadOnlyDatastore.GetOrderCreds(orderID, isSigned)
+		if err == nil && creds != nil {
+			return creds, nil
+		}
+		ds.fallback("GetOrderCreds", err)
+	}
+	return ds.primary.GetOrderCreds(orderID, isSigned)
+}
+
+// GetOrderCredsByItemID from the replica, or from the primary when the replica is down or the credentials
+// have not replicated
+func (ds *replicaDatastore) GetOrderCredsByItemID(orderID uuid.UUID, itemID uuid.UUID, isSigned bool) (*OrderCreds, error) {
+	if ds.available() {
+		creds, err := ds.ReadOnlyDatastore.GetOrderCredsByItemID(orderID, itemID, isSigned)
+		if err == nil && creds != nil {
+			return creds, nil
+		}
+		ds.fallback("GetOrderCredsByItemID", err)
+	}
+	return ds.primary.GetOrderCredsByItemID(orderID, itemID, isSigned)
+}
+
+// GetCredentialRedemptions from the replica, or from the primary when the replica is down. Redemptions which
+// have not replicated are not looked for on the primary, the challenge bypass server rejects them anyway
+func (ds *replicaDatastore) GetCredentialRedemptions(ctx context.Context, tokenPreimages []string) ([]RedeemedCredential, error) {
+	if ds.available() {
+		redemptions, err := ds.ReadOnlyDatastore.GetCredentialRedemptions(ctx, tokenPreimages)
+		if err == nil {
+			return redemptions, nil
+		}
+		ds.fallback("GetCredentialRedemptions", err)
+	}
+	return ds.primary.GetCredentialRedemptions(ctx, tokenPreimages)
+}
+
+// UseReadReplica serves the read heavy paths, fetching orders, polling for order credentials and looking up
+// issuers while redeeming credentials, from a read replica. Reads fall back to the primary while the replica
+// is down, and for rows which have not replicated yet
+func (s *Service) UseReadReplica(replica ReadOnlyDatastore) {
+	s.RoDatastore = newReplicaDatastore(replica, s.Datastore)
+}
+
+// ReadableDatastore returns a read only datastore if available, otherwise a normal datastore
+func (s *Service) ReadableDatastore() ReadOnlyDatastore {
+	if s.RoDatastore != nil {
+		return s.RoDatastore
+	}
+	return s.Datastore
+}
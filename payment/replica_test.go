@@ -0,0 +1,55 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaDatastoreGetOrder(t *testing.T) {
+	replica, primary := newFakeDatastore(), newFakeDatastore()
+	replicated := primary.addOrder(Order{})
+	recent := primary.addOrder(Order{})
+	replica.addOrder(*replicated)
+	ds := newReplicaDatastore(replica, primary)
+
+	order, err := ds.GetOrder(replicated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, replicated, order)
+	assert.Equal(t, 0, primary.calls["GetOrder"], "replicated orders are read from the replica")
+
+	order, err = ds.GetOrder(recent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, recent, order, "orders which have not replicated are read from the primary")
+	assert.True(t, ds.available(), "a miss does not mark the replica down")
+
+	replica.errs["GetOrder"] = errors.New("connection refused")
+	order, err = ds.GetOrder(replicated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, replicated, order, "reads fall back to the primary when the replica fails")
+	assert.False(t, ds.available())
+
+	reads := replica.calls["GetOrder"]
+	_, err = ds.GetOrder(replicated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reads, replica.calls["GetOrder"], "a replica which is down is skipped")
+
+	ds.downUntil = time.Now().Add(-time.Second)
+	delete(replica.errs, "GetOrder")
+	_, err = ds.GetOrder(replicated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reads+1, replica.calls["GetOrder"], "the replica is tried again once it has been down a while")
+}
+
+func TestReadableDatastore(t *testing.T) {
+	primary := newFakeDatastore()
+	service := &Service{Datastore: primary}
+	assert.Equal(t, ReadOnlyDatastore(primary), service.ReadableDatastore())
+
+	service.UseReadReplica(newFakeDatastore())
+	_, ok := service.ReadableDatastore().(*replicaDatastore)
+	assert.True(t, ok)
+}
@@ -9,36 +9,115 @@ import (
 
 	"errors"
 
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/notification"
+	"github.com/brave-intl/bat-go/utils/scheduler"
 	srv "github.com/brave-intl/bat-go/utils/service"
 	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
 	"github.com/brave-intl/bat-go/wallet"
 	"github.com/getsentry/sentry-go"
 	"github.com/linkedin/goavro"
+	"github.com/throttled/throttled"
 
+	"github.com/brave-intl/bat-go/utils/bus"
+	"github.com/brave-intl/bat-go/utils/clients/bigquery"
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/clients/ethereum"
+	"github.com/brave-intl/bat-go/utils/clients/ratios"
+	"github.com/brave-intl/bat-go/utils/clients/stripe"
 	appctx "github.com/brave-intl/bat-go/utils/context"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
 	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/leader"
 	uuid "github.com/satori/go.uuid"
-	kafka "github.com/segmentio/kafka-go"
 	"github.com/shopspring/decimal"
+	jose "gopkg.in/square/go-jose.v2"
 )
 
 var (
-	voteTopic = os.Getenv("ENV") + ".payment.vote"
+	voteTopic       = os.Getenv("ENV") + ".payment.vote"
+	orderEventTopic = os.Getenv("ENV") + ".payment.order"
 )
 
 // Service contains datastore
 type Service struct {
-	wallet           *wallet.Service
-	cbClient         cbr.Client
-	Datastore        Datastore
+	wallet    *wallet.Service
+	cbClient  cbr.Client
+	Datastore Datastore
+	// RoDatastore serves the read heavy paths from a read replica, see UseReadReplica
+	RoDatastore      ReadOnlyDatastore
 	codecs           map[string]*goavro.Codec
-	kafkaWriter      *kafka.Writer
-	kafkaDialer      *kafka.Dialer
+	producer         bus.Producer
+	orderProducer    bus.Producer
 	jobs             []srv.Job
 	pauseVoteUntil   time.Time
 	pauseVoteUntilMu sync.RWMutex
+	rateLimitStore   throttled.GCRAStore
+	jweKey           *jose.JSONWebKey
+	orderWatchers    *orderNotifier
+	nonces           middleware.NonceStore
+	// scopedTokens authenticates the scoped tokens of operators and services, when SCOPED_TOKEN_SECRET is set
+	scopedTokens *middleware.ScopedTokens
+	// transactionStream streams recorded transactions to BigQuery, when BIGQUERY_STREAM_TRANSACTIONS is set
+	transactionStream *bigquery.Streamer
+	// deliveries queues webhooks to merchants, see UseDeliveryQueue
+	deliveries *notification.Queue
+	// signer signs order credentials, the service itself unless UseSigner is called
+	signer OrderWorker
+	// stripeClient creates checkout sessions for orders paid with Stripe, when STRIPE_SECRET_KEY is set
+	stripeClient        stripe.Client
+	stripeWebhookSecret string
+	// redemptionBatchSize is how many credentials are redeemed per bulk redemption, see REDEMPTION_BATCH_SIZE
+	redemptionBatchSize int
+	// ratios prices orders placed in fiat currencies in BAT, when RATIOS_SERVICE is set
+	ratios ratios.Client
+	// receiptKey signs the receipts of paid orders, when RECEIPT_SIGNING_KEY is set, and receiptKeys are
+	// the public keys receipts are verified with, see LoadReceiptKeys
+	receiptKey  *jose.JSONWebKey
+	receiptKeys []jose.JSONWebKey
+	// custodians are those orders are paid through with BAT transfers from linked wallets, see loadCustodians
+	custodians map[string]Custodian
+	// deposits derives the deposit addresses orders are paid to on-chain, which eth watches, when
+	// DEPOSIT_FACTORY_ADDRESS is set, see loadDepositConfig
+	deposits *depositConfig
+	eth      ethereum.Client
+}
+
+// ScheduledJobs - Implement scheduler.JobService interface
+func (s *Service) ScheduledJobs() []scheduler.Job {
+	jobs := []scheduler.Job{
+		{
+			Name:     "sweep-webhook-deliveries",
+			Schedule: "0 3 * * *",
+			Jitter:   10 * time.Minute,
+			Family:   leader.FamilySweeps,
+			Func:     s.SweepWebhookDeliveries,
+		},
+		{
+			Name:     "rollup-merchant-usage",
+			Schedule: "0 2 1 * *",
+			Jitter:   10 * time.Minute,
+			Family:   leader.FamilyExports,
+			Func:     s.RollupMerchantUsage,
+		},
+		{
+			Name:     "rollup-vote-tallies",
+			Schedule: voteTallySchedule(),
+			Jitter:   time.Minute,
+			Family:   leader.FamilyExports,
+			Func:     s.RollupVoteTallies,
+		},
+	}
+	if exportLocation() != "" {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "export-warehouse",
+			Schedule: "0 1 * * *",
+			Jitter:   10 * time.Minute,
+			Family:   leader.FamilyExports,
+			Func:     s.RunNightlyExport,
+		})
+	}
+	return jobs
 }
 
 // PauseWorker - pause worker until time specified
@@ -60,20 +139,25 @@ func (s *Service) Jobs() []srv.Job {
 	return s.jobs
 }
 
-// InitKafka by creating a kafka writer and creating local copies of codecs
+// InitKafka by creating a producer on the topic's message bus and creating local copies of codecs
 func (s *Service) InitKafka(ctx context.Context) error {
 
 	// TODO: eventually as cobra/viper
 	ctx = context.WithValue(ctx, appctx.KafkaBrokersCTXKey, os.Getenv("KAFKA_BROKERS"))
 
 	var err error
-	s.kafkaWriter, s.kafkaDialer, err = kafkautils.InitKafkaWriter(ctx, voteTopic)
+	s.producer, err = bus.NewProducer(ctx, voteTopic)
 	if err != nil {
-		return fmt.Errorf("failed to initialize kafka: %w", err)
+		return fmt.Errorf("failed to initialize the message bus: %w", err)
+	}
+	s.orderProducer, err = bus.NewProducer(ctx, orderEventTopic)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the message bus: %w", err)
 	}
 
 	s.codecs, err = kafkautils.GenerateCodecs(map[string]string{
-		"vote": voteSchema,
+		"vote":       voteSchema,
+		"orderEvent": orderEventSchema,
 	})
 
 	if err != nil {
@@ -82,6 +166,116 @@ func (s *Service) InitKafka(ctx context.Context) error {
 	return nil
 }
 
+// LookupKeyRateLimit gets the rate limit configured for a merchant api key
+func (s *Service) LookupKeyRateLimit(ctx context.Context, keyID string) (*middleware.KeyRateLimit, error) {
+	id, err := uuid.FromString(keyID)
+	if err != nil {
+		// not one of our api keys, so nothing is configured for it
+		return nil, nil
+	}
+	key, err := s.Datastore.GetKey(id)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return key.RateLimit(), nil
+}
+
+// LookupHMACSecret returns the secret of an api key, which the key's requests to order management endpoints
+// are signed with
+func (s *Service) LookupHMACSecret(ctx context.Context, keyID string) ([]byte, error) {
+	id, err := uuid.FromString(keyID)
+	if err != nil {
+		// not one of our api keys, so it has no secret
+		return nil, nil
+	}
+	key, err := s.Datastore.GetKeyWithSecret(id)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return []byte(key.SecretKey), nil
+}
+
+// CreateKey creates a key for the merchant within the scopes, the returned key includes its token
+func (s *Service) CreateKey(ctx context.Context, merchant string, name string, scopes []string) (*Key, error) {
+	if err := ValidateKeyScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	encrypted, nonce, err := GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	token, tokenHash, err := GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key token: %w", err)
+	}
+
+	key, err := s.Datastore.CreateKey(merchant, name, encrypted, nonce, scopes, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	key.Token = token
+	return key, nil
+}
+
+// RotateKey replaces a key with a new one granted the same scopes and rate limits, the old key remains
+// valid for overlapSeconds so integrations can switch over without dropping requests
+func (s *Service) RotateKey(ctx context.Context, id uuid.UUID, overlapSeconds int) (*Key, error) {
+	old, err := s.Datastore.GetKey(id)
+	if err != nil || old == nil {
+		return nil, err
+	}
+
+	key, err := s.CreateKey(ctx, old.Merchant, old.Name, old.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if old.RateLimitPerMinute != nil || old.DailyQuota != nil {
+		keyID, err := uuid.FromString(key.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key id: %w", err)
+		}
+		limited, err := s.Datastore.UpdateKeyRateLimit(keyID, old.RateLimitPerMinute, old.RateLimitBurst, old.DailyQuota)
+		if err != nil || limited == nil {
+			// the new key is never handed out without the limits of the key it replaces
+			if _, derr := s.Datastore.DeleteKey(keyID, 0); derr != nil {
+				return nil, fmt.Errorf("failed to expire key without rate limit: %w", derr)
+			}
+			return nil, fmt.Errorf("failed to carry over rate limit: %w", err)
+		}
+		key.RateLimitPerMinute = limited.RateLimitPerMinute
+		key.RateLimitBurst = limited.RateLimitBurst
+		key.DailyQuota = limited.DailyQuota
+	}
+
+	if _, err := s.Datastore.DeleteKey(id, overlapSeconds); err != nil {
+		return nil, fmt.Errorf("failed to expire rotated key: %w", err)
+	}
+	return key, nil
+}
+
+// AuthenticateAPIKey - implement middleware.APIKeyAuthenticator
+func (s *Service) AuthenticateAPIKey(ctx context.Context, token string) (*middleware.APIKey, error) {
+	key, err := s.Datastore.GetKeyByTokenHash(HashToken(token))
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	id, err := uuid.FromString(key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key id: %w", err)
+	}
+	if err := s.Datastore.MarkKeyUsed(id); err != nil {
+		// tracking usage should not fail the request
+		logger, lerr := appctx.GetLogger(ctx)
+		if lerr == nil {
+			logger.Warn().Err(err).Str("key_id", key.ID).Msg("failed to mark key used")
+		}
+	}
+	return key.APIKey(), nil
+}
+
 // InitService creates a service using the passed datastore and clients configured from the environment
 func InitService(ctx context.Context, datastore Datastore, walletService *wallet.Service) (*Service, error) {
 	cbClient, err := cbr.New()
@@ -89,25 +283,138 @@ func InitService(ctx context.Context, datastore Datastore, walletService *wallet
 		return nil, err
 	}
 
+	rateLimitStore, err := middleware.NewRateLimitStore(ctx, "payment_key_rate_limit:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit store: %w", err)
+	}
+
+	jweKey, err := middleware.LoadJWEDecryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	scopedTokens, err := middleware.LoadScopedTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	receiptKey, receiptKeys, err := LoadReceiptKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	custodians, err := loadCustodians()
+	if err != nil {
+		return nil, err
+	}
+
+	deposits, err := loadDepositConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	nonces, err := middleware.NewNonceStore(ctx, "payment_nonce:")
+	if err != nil {
+		return nil, err
+	}
+
 	service := &Service{
-		wallet:           walletService,
-		cbClient:         cbClient,
-		Datastore:        datastore,
-		pauseVoteUntilMu: sync.RWMutex{},
+		wallet:              walletService,
+		cbClient:            cbClient,
+		Datastore:           datastore,
+		pauseVoteUntilMu:    sync.RWMutex{},
+		rateLimitStore:      rateLimitStore,
+		jweKey:              jweKey,
+		orderWatchers:       newOrderNotifier(),
+		nonces:              nonces,
+		scopedTokens:        scopedTokens,
+		redemptionBatchSize: redemptionBatchSize(),
+		receiptKey:          receiptKey,
+		receiptKeys:         receiptKeys,
+		custodians:          custodians,
+		deposits:            deposits,
+	}
+
+	if os.Getenv("STRIPE_SECRET_KEY") != "" {
+		service.stripeClient, err = stripe.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stripe client: %w", err)
+		}
+		service.stripeWebhookSecret = os.Getenv("STRIPE_WEBHOOK_SECRET")
+	}
+
+	if deposits != nil {
+		service.eth, err = ethereum.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ethereum client: %w", err)
+		}
+	}
+
+	if os.Getenv("RATIOS_SERVICE") != "" {
+		service.ratios, err = ratios.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ratios client: %w", err)
+		}
 	}
 
 	// setup runnable jobs
 	service.jobs = []srv.Job{
 		{
+			Name:    "vote_drain",
+			Service: "payment",
 			Func:    service.RunNextVoteDrainJob,
 			Cadence: 5 * time.Second,
 			Workers: 1,
 		},
 		{
+			Name:    "order",
+			Service: "payment",
 			Func:    service.RunNextOrderJob,
 			Cadence: 1 * time.Second,
 			Workers: 1,
 		},
+		{
+			Name:    "credential_windows",
+			Service: "payment",
+			Func:    service.RunNextCredentialWindowJob,
+			Cadence: 1 * time.Minute,
+			Workers: 1,
+		},
+		{
+			Name:    "order_events",
+			Service: "payment",
+			Func:    service.RunNextOrderEventDispatch,
+			Cadence: 1 * time.Second,
+			Workers: 1,
+		},
+		{
+			Name:    "custodian_transfers",
+			Service: "payment",
+			Func:    service.SettleCustodianTransfers,
+			Cadence: 1 * time.Minute,
+			Workers: 1,
+		},
+		{
+			Name:    "chain_deposits",
+			Service: "payment",
+			Func:    service.ScanChainDeposits,
+			Cadence: 15 * time.Second,
+			Workers: 1,
+		},
+		{
+			Name:    "order_event_outbox",
+			Service: "payment",
+			Func:    service.PublishOrderEvents,
+			Cadence: 5 * time.Second,
+			Workers: 1,
+		},
+		{
+			Name:    "order_expiry",
+			Service: "payment",
+			Func:    service.ExpireOrders,
+			Cadence: 1 * time.Minute,
+			Workers: 1,
+		},
 	}
 
 	err = service.InitKafka(ctx)
@@ -115,16 +422,37 @@ func InitService(ctx context.Context, datastore Datastore, walletService *wallet
 		return nil, err
 	}
 
+	if bigquery.StreamEnabled("transactions") {
+		client, err := bigquery.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bigquery client: %w", err)
+		}
+		service.transactionStream = bigquery.NewStreamer(client, "transactions", 10000)
+		go service.transactionStream.Run(ctx)
+	}
+
 	return service, nil
 }
 
-// CreateOrderFromRequest creates an order from the request
-func (s *Service) CreateOrderFromRequest(req CreateOrderRequest) (*Order, error) {
+// NewOrder is an order priced from its request, to be created
+type NewOrder struct {
+	MerchantID string
+	TotalPrice decimal.Decimal
+	Currency   string
+	Location   string
+	Status     string
+	// Rate is the exchange rate an order priced in fiat is placed at, if any
+	Rate  *ExchangeRate
+	Items []OrderItem
+}
+
+// priceOrder prices the items of an order of the merchant, which may restrict the skus it sells, checking they
+// can be paid with the payment method. The order is yet to be given its exchange rate
+func (s *Service) priceOrder(merchantID string, merchant *Merchant, req CreateOrderRequest) (*NewOrder, error) {
 	totalPrice := decimal.New(0, 0)
 	orderItems := []OrderItem{}
 	var currency string
 	var location string
-	var status string
 
 	for i := 0; i < len(req.Items); i++ {
 		orderItem, err := CreateOrderItemFromMacaroon(req.Items[i].SKU, req.Items[i].Quantity)
@@ -148,42 +476,144 @@ func (s *Service) CreateOrderFromRequest(req CreateOrderRequest) (*Order, error)
 		orderItems = append(orderItems, *orderItem)
 	}
 
-	// If order consists entirely of zero cost items ( e.g. trials ), we can consider it paid
-	if totalPrice.IsZero() {
+	if err := s.checkPaymentMethod(req.PaymentMethod, orderItems); err != nil {
+		return nil, err
+	}
+
+	// registered merchants may restrict which skus they sell
+	if merchant != nil {
+		for _, item := range orderItems {
+			if !merchant.AllowsSKU(item.SKU) {
+				return nil, fmt.Errorf("%s: %w", item.SKU, ErrSKUNotAllowed)
+			}
+		}
+	}
+
+	// If order consists entirely of zero cost items ( e.g. trials ), we can consider it paid, unless the
+	// merchant offers trials, whose credentials are only issued to the wallets taking them, see claimTrial
+	status := "pending"
+	if totalPrice.IsZero() && !trialMerchants()[merchantID] {
 		status = "paid"
-	} else {
-		status = "pending"
 	}
 
-	order, err := s.Datastore.CreateOrder(totalPrice, "brave.com", status, currency, location, orderItems)
+	return &NewOrder{
+		MerchantID: merchantID,
+		TotalPrice: totalPrice,
+		Currency:   currency,
+		Location:   location,
+		Status:     status,
+		Items:      orderItems,
+	}, nil
+}
+
+// CreateOrderFromRequest creates an order from the request
+func (s *Service) CreateOrderFromRequest(ctx context.Context, req CreateOrderRequest) (*Order, error) {
+	merchantID := "brave.com"
+	merchant, err := s.Datastore.GetMerchant(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	priced, err := s.priceOrder(merchantID, merchant, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// orders priced in fiat are paid in BAT at the rate they were placed at, however the rate moves after
+	priced.Rate, err = s.snapshotExchangeRate(ctx, priced.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPrice := priced.TotalPrice
+	var voucher *VoucherRedemption
+	if req.VoucherCode != "" {
+		voucher, err = s.applyVoucher(ctx, req.VoucherCode, merchantID, priced.Currency, totalPrice)
+		if err != nil {
+			return nil, err
+		}
+		totalPrice = totalPrice.Sub(voucher.Discount)
+	}
+
+	order, err := s.Datastore.CreateOrder(ctx, totalPrice, merchantID, priced.Status, priced.Currency, priced.Location, priced.Rate, priced.Items, voucher)
+	if err != nil {
+		return nil, err
+	}
+
+	// trials are not paid for
+	if req.PaymentMethod == PaymentMethodStripe && !order.IsPaid() && order.TotalPrice.IsPositive() {
+		order.Checkout, err = s.createStripeCheckoutSession(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return order, err
+	return order, nil
 }
 
 // UpdateOrderStatus checks to see if an order has been paid and updates it if so
-func (s *Service) UpdateOrderStatus(orderID uuid.UUID) error {
+func (s *Service) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID) error {
 	order, err := s.Datastore.GetOrder(orderID)
 	if err != nil {
 		return err
 	}
 
-	sum, err := s.Datastore.GetSumForTransactions(orderID)
+	paid, err := s.isOrderPaid(ctx, order)
 	if err != nil {
 		return err
 	}
 
-	if sum.GreaterThanOrEqual(order.TotalPrice) {
-		err = s.Datastore.UpdateOrder(orderID, "paid")
+	if paid {
+		err = s.Datastore.UpdateOrder(ctx, orderID, "paid")
 		if err != nil {
 			return err
 		}
+		s.NotifyOrderChanged(orderID)
+	}
+
+	return nil
+}
+
+// CancelOrder cancels an order which has not been paid, returning the canceled order or nil when there is
+// no such order. The order's merchant is sent an order.canceled webhook
+func (s *Service) CancelOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if err := s.Datastore.UpdateOrder(ctx, orderID, OrderLogCanceled); err != nil {
+		return nil, err
 	}
+	s.NotifyOrderChanged(orderID)
+	return s.Datastore.GetOrder(orderID)
+}
 
+// payableOrder checks that payments can still be made towards the order. Canceled, expired and refunded
+// orders take none, a payment towards them would never be issued credentials
+func payableOrder(order *Order) error {
+	switch {
+	case order == nil:
+		return ErrOrderNotFound
+	case order.Status == OrderLogCanceled:
+		return ErrOrderCanceled
+	case order.Status == OrderLogExpired:
+		return ErrOrderExpired
+	case order.Status == OrderLogRefunded:
+		return ErrOrderRefunded
+	}
 	return nil
 }
 
 // CreateTransactionFromRequest queries the endpoints and creates a transaciton
-func (s *Service) CreateTransactionFromRequest(req CreateTransactionRequest, orderID uuid.UUID) (*Transaction, error) {
+func (s *Service) CreateTransactionFromRequest(ctx context.Context, req CreateTransactionRequest, orderID uuid.UUID) (*Transaction, error) {
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := payableOrder(order); err != nil {
+		return nil, err
+	}
+
 	var wallet uphold.Wallet
 	upholdTransaction, err := wallet.GetTransaction(req.ExternalTransactionID.String())
 
@@ -205,18 +635,20 @@ func (s *Service) CreateTransactionFromRequest(req CreateTransactionRequest, ord
 	if err != nil {
 		return nil, errorutils.Wrap(err, "error recording transaction")
 	}
+	s.streamTransaction(transaction)
 
-	isPaid, err := s.IsOrderPaid(transaction.OrderID)
+	isPaid, err := s.IsOrderPaid(ctx, transaction.OrderID)
 	if err != nil {
 		return nil, errorutils.Wrap(err, "error submitting anon card transaction")
 	}
 
 	// If the transaction that was satisifies the order then let's update the status
 	if isPaid {
-		err = s.Datastore.UpdateOrder(transaction.OrderID, "paid")
+		err = s.Datastore.UpdateOrder(ctx, transaction.OrderID, "paid")
 		if err != nil {
 			return nil, errorutils.Wrap(err, "error updating order status")
 		}
+		s.NotifyOrderChanged(transaction.OrderID)
 	}
 
 	return transaction, err
@@ -224,6 +656,15 @@ func (s *Service) CreateTransactionFromRequest(req CreateTransactionRequest, ord
 
 // CreateAnonCardTransaction takes a signed transaction and executes it on behalf of an anon card
 func (s *Service) CreateAnonCardTransaction(ctx context.Context, walletID uuid.UUID, transaction string, orderID uuid.UUID) (*Transaction, error) {
+	// the transaction is submitted to uphold right away, so it must not be sent for an order which takes no payments
+	order, err := s.Datastore.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := payableOrder(order); err != nil {
+		return nil, err
+	}
+
 	txInfo, err := s.wallet.SubmitAnonCardTransaction(
 		ctx,
 		walletID,
@@ -238,8 +679,9 @@ func (s *Service) CreateAnonCardTransaction(ctx context.Context, walletID uuid.U
 	if err != nil {
 		return nil, errorutils.Wrap(err, "error recording anon card transaction")
 	}
+	s.streamTransaction(txn)
 
-	err = s.UpdateOrderStatus(orderID)
+	err = s.UpdateOrderStatus(ctx, orderID)
 	if err != nil {
 		return nil, errorutils.Wrap(err, "error updating order status")
 	}
@@ -248,25 +690,39 @@ func (s *Service) CreateAnonCardTransaction(ctx context.Context, walletID uuid.U
 }
 
 // IsOrderPaid determines if the order has been paid
-func (s *Service) IsOrderPaid(orderID uuid.UUID) (bool, error) {
+func (s *Service) IsOrderPaid(ctx context.Context, orderID uuid.UUID) (bool, error) {
 	// Now that the transaction has been created let's check to see if that fulfilled the order.
 	order, err := s.Datastore.GetOrder(orderID)
 	if err != nil {
 		return false, err
 	}
 
-	sum, err := s.Datastore.GetSumForTransactions(orderID)
-	if err != nil {
-		return false, err
+	return s.isOrderPaid(ctx, order)
+}
+
+// UseSigner signs order credentials with the signer, such as a RemoteSigner streaming them to a
+// dedicated signer deployment, rather than calling the challenge bypass server directly. Order jobs
+// claim signing jobs from the queue, so workers order jobs sign over the signer at once
+func (s *Service) UseSigner(signer OrderWorker, workers int) {
+	s.signer = signer
+	for i := range s.jobs {
+		if s.jobs[i].Name == "order" && workers > 0 {
+			s.jobs[i].Workers = workers
+		}
 	}
+}
 
-	return sum.GreaterThanOrEqual(order.TotalPrice), nil
+func (s *Service) orderWorker() OrderWorker {
+	if s.signer != nil {
+		return s.signer
+	}
+	return s
 }
 
 // RunNextOrderJob takes the next order job and completes it
 func (s *Service) RunNextOrderJob(ctx context.Context) (bool, error) {
 	for {
-		attempted, err := s.Datastore.RunNextOrderJob(ctx, s)
+		attempted, err := s.Datastore.RunNextOrderJob(ctx, s.orderWorker())
 		if err != nil {
 			sentry.CaptureMessage(err.Error())
 			sentry.Flush(time.Second * 2)
@@ -0,0 +1,450 @@
+package payment
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// signerCodecName is the content subtype of the signing stream, whose messages are json encoded
+const signerCodecName = "json"
+
+var (
+	signerJobs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "signer_jobs_total",
+			Help: "Signing jobs received over the signing stream, by result",
+		},
+		[]string{"result"},
+	)
+	signerStreamPending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "signer_stream_pending_jobs",
+			Help: "Signing jobs sent over the signing stream which are waiting for their signed batch",
+		},
+	)
+	// ErrSigningStreamClosed - the signing stream broke before the job's batch was signed
+	ErrSigningStreamClosed = errors.New("signing stream closed")
+	// ErrSignerDraining - the signer is shutting down and signs no new jobs
+	ErrSignerDraining = errors.New("signer is shutting down")
+)
+
+func init() {
+	prometheus.MustRegister(signerJobs, signerStreamPending)
+	encoding.RegisterCodec(signerCodec{})
+}
+
+// signerCodec encodes signing stream messages as json, so the stream needs no generated protobuf types
+type signerCodec struct{}
+
+func (signerCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (signerCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (signerCodec) Name() string {
+	return signerCodecName
+}
+
+// SigningJob is the blinded credentials of an order item, sent to a signer
+type SigningJob struct {
+	ID           string    `json:"id"`
+	OrderID      uuid.UUID `json:"orderId"`
+	Issuer       Issuer    `json:"issuer"`
+	BlindedCreds []string  `json:"blindedCreds"`
+	// Traceparent continues the trace of the order worker which sent the job
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// SignedBatch is the result of a signing job, streamed back as soon as it is signed
+type SignedBatch struct {
+	JobID string      `json:"jobId"`
+	Creds *OrderCreds `json:"creds,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// signerService is the handler type of the signing stream
+type signerService interface {
+	Sign(stream grpc.ServerStream) error
+}
+
+var signerServiceDesc = grpc.ServiceDesc{
+	ServiceName: "payment.Signer",
+	HandlerType: (*signerService)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Sign",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(signerService).Sign(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "payment/signer.go",
+}
+
+// signOrderCreds signs the blinded credentials with the challenge bypass server
+func signOrderCreds(ctx context.Context, client cbr.Client, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	resp, err := client.SignCredentials(ctx, issuer.Name(), blindedCreds)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTokens := jsonutils.TextArray(resp.SignedTokens)
+
+	creds := &OrderCreds{
+		ID:           orderID,
+		BlindedCreds: blindedCreds,
+		SignedCreds:  &signedTokens,
+		BatchProof:   &resp.BatchProof,
+		PublicKey:    &issuer.PublicKey,
+	}
+
+	return creds, nil
+}
+
+// CBRSigner is an OrderWorker signing with the challenge bypass server, for signer deployments which
+// have no datastore of their own
+type CBRSigner struct {
+	client cbr.Client
+}
+
+// NewCBRSigner creates a signer with a challenge bypass client configured from the environment
+func NewCBRSigner() (*CBRSigner, error) {
+	client, err := cbr.New()
+	if err != nil {
+		return nil, err
+	}
+	return &CBRSigner{client: client}, nil
+}
+
+// SignOrderCreds signs the blinded credentials
+func (s *CBRSigner) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	return signOrderCreds(ctx, s.client, orderID, issuer, blindedCreds)
+}
+
+// SignerServer serves the signing stream, signing the jobs sent over it with a worker and sending each
+// batch back as soon as it is signed, in whatever order the jobs finish
+type SignerServer struct {
+	worker      OrderWorker
+	token       string
+	concurrency int
+
+	drainMu  sync.RWMutex
+	draining bool
+	inFlight sync.WaitGroup
+}
+
+// NewSignerServer creates a signer server signing up to concurrency jobs of each stream at once. Streams
+// must carry the token as a bearer authorization, unless it is empty
+func NewSignerServer(worker OrderWorker, token string, concurrency int) *SignerServer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &SignerServer{worker: worker, token: token, concurrency: concurrency}
+}
+
+// Register the signing stream with the grpc server
+func (s *SignerServer) Register(server *grpc.Server) {
+	server.RegisterService(&signerServiceDesc, s)
+}
+
+func (s *SignerServer) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid signer token")
+}
+
+// Sign receives signing jobs until the client closes its side of the stream, returning once every
+// received job has been signed and sent back
+func (s *SignerServer) Sign(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	if err := s.authorize(ctx); err != nil {
+		return err
+	}
+
+	var (
+		sendMu  sync.Mutex
+		sending sync.WaitGroup
+		slots   = make(chan struct{}, s.concurrency)
+	)
+	defer sending.Wait()
+
+	for {
+		var job SigningJob
+		if err := stream.RecvMsg(&job); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		s.drainMu.RLock()
+		draining := s.draining
+		if !draining {
+			s.inFlight.Add(1)
+		}
+		s.drainMu.RUnlock()
+
+		sending.Add(1)
+		go func(job SigningJob) {
+			defer func() {
+				<-slots
+				sending.Done()
+			}()
+
+			batch := SignedBatch{JobID: job.ID}
+			var (
+				creds *OrderCreds
+				err   = ErrSignerDraining
+			)
+			jobCtx := ctx
+			if sc, err := tracing.ParseTraceparent(job.Traceparent); err == nil {
+				jobCtx = tracing.ContextWithRemoteParent(ctx, sc)
+			}
+			jobCtx, span := tracing.StartSpan(jobCtx, "signer.Sign", tracing.WithKind(tracing.SpanKindServer))
+			span.SetAttribute("order_id", job.OrderID)
+			span.SetAttribute("creds", len(job.BlindedCreds))
+			if !draining {
+				creds, err = s.worker.SignOrderCreds(jobCtx, job.OrderID, job.Issuer, job.BlindedCreds)
+				defer s.inFlight.Done()
+			}
+			span.End(err)
+			if err != nil {
+				batch.Error = err.Error()
+				signerJobs.WithLabelValues("error").Inc()
+			} else {
+				batch.Creds = creds
+				signerJobs.WithLabelValues("signed").Inc()
+			}
+
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			// a failed send means the stream is broken, the client fails the job and it is retried
+			_ = stream.SendMsg(&batch)
+		}(job)
+	}
+}
+
+// Drain stops signing new jobs, failing them so they are retried elsewhere, and waits for the jobs
+// being signed to be sent back, returning false if the context is done first. Streams stay open, so
+// the grpc server is stopped rather than gracefully stopped once drained
+func (s *SignerServer) Drain(ctx context.Context) bool {
+	s.drainMu.Lock()
+	s.draining = true
+	s.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pendingJob is a signing job waiting for its batch on a stream
+type pendingJob struct {
+	stream grpc.ClientStream
+	result chan SignedBatch
+}
+
+// RemoteSigner is an OrderWorker signing over the signing stream of a signer deployment. Order workers
+// share one stream, each waiting for the batch of its own job. Jobs pending on a stream which breaks
+// fail, leaving their signing jobs to be retried after their backoff
+type RemoteSigner struct {
+	conn  *grpc.ClientConn
+	token string
+
+	// sendMu serializes opening the stream and sending over it
+	sendMu sync.Mutex
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+
+	pendingMu sync.Mutex
+	pending   map[string]pendingJob
+}
+
+// NewRemoteSigner creates a signer streaming jobs over the connection
+func NewRemoteSigner(conn *grpc.ClientConn, token string) *RemoteSigner {
+	return &RemoteSigner{
+		conn:    conn,
+		token:   token,
+		pending: map[string]pendingJob{},
+	}
+}
+
+// DialSigner connects to the signer deployment at the address
+func DialSigner(ctx context.Context, address string, token string) (*RemoteSigner, error) {
+	conn, err := grpc.DialContext(ctx, address,
+		grpc.WithInsecure(),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(signerCodecName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial signer: %w", err)
+	}
+	return NewRemoteSigner(conn, token), nil
+}
+
+// SignOrderCreds sends the blinded credentials to the signer, waiting for them to be signed
+func (s *RemoteSigner) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (creds *OrderCreds, err error) {
+	ctx, span := tracing.StartSpan(ctx, "signer.Sign", tracing.WithKind(tracing.SpanKindClient))
+	defer func() { span.End(err) }()
+
+	job := SigningJob{
+		ID:           uuid.NewV4().String(),
+		OrderID:      orderID,
+		Issuer:       issuer,
+		BlindedCreds: blindedCreds,
+	}
+	if sc := span.Context(); sc.IsValid() {
+		job.Traceparent = sc.Traceparent()
+	}
+	result := make(chan SignedBatch, 1)
+	if err := s.send(job, result); err != nil {
+		return nil, err
+	}
+
+	select {
+	case batch := <-result:
+		if batch.Error != "" {
+			return nil, fmt.Errorf("failed to sign order creds: %s", batch.Error)
+		}
+		if batch.Creds == nil {
+			return nil, errors.New("failed to sign order creds: empty batch")
+		}
+		return batch.Creds, nil
+	case <-ctx.Done():
+		s.forget(job.ID)
+		return nil, ctx.Err()
+	}
+}
+
+// send the job over the stream, opening one if there is none
+func (s *RemoteSigner) send(job SigningJob, result chan SignedBatch) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.stream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		if s.token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.token)
+		}
+		stream, err := s.conn.NewStream(ctx, &signerServiceDesc.Streams[0], "/payment.Signer/Sign")
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to open signing stream: %w", err)
+		}
+		s.stream, s.cancel = stream, cancel
+		go s.receive(stream)
+	}
+
+	s.pendingMu.Lock()
+	s.pending[job.ID] = pendingJob{stream: s.stream, result: result}
+	signerStreamPending.Inc()
+	s.pendingMu.Unlock()
+
+	if err := s.stream.SendMsg(&job); err != nil {
+		s.forget(job.ID)
+		// the receiver sees the stream break too, and fails the jobs still pending on it
+		s.cancel()
+		s.stream = nil
+		return fmt.Errorf("failed to send signing job: %w", err)
+	}
+	return nil
+}
+
+// receive the batches of the stream, passing each to its job
+func (s *RemoteSigner) receive(stream grpc.ClientStream) {
+	for {
+		var batch SignedBatch
+		if err := stream.RecvMsg(&batch); err != nil {
+			s.closed(stream, err)
+			return
+		}
+		s.pendingMu.Lock()
+		job, ok := s.pending[batch.JobID]
+		if ok {
+			delete(s.pending, batch.JobID)
+			signerStreamPending.Dec()
+		}
+		s.pendingMu.Unlock()
+		if ok {
+			job.result <- batch
+		}
+	}
+}
+
+// closed fails the jobs pending on the broken stream, the next job opening a new one
+func (s *RemoteSigner) closed(stream grpc.ClientStream, err error) {
+	s.sendMu.Lock()
+	if s.stream == stream {
+		s.cancel()
+		s.stream = nil
+	}
+	s.sendMu.Unlock()
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	for id, job := range s.pending {
+		if job.stream != stream {
+			continue
+		}
+		delete(s.pending, id)
+		signerStreamPending.Dec()
+		job.result <- SignedBatch{JobID: id, Error: fmt.Sprintf("%s: %s", ErrSigningStreamClosed, err)}
+	}
+}
+
+func (s *RemoteSigner) forget(jobID string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if _, ok := s.pending[jobID]; ok {
+		delete(s.pending, jobID)
+		signerStreamPending.Dec()
+	}
+}
+
+// Close the stream and the connection, failing any pending jobs
+func (s *RemoteSigner) Close() error {
+	s.sendMu.Lock()
+	if s.stream != nil {
+		s.cancel()
+	}
+	s.sendMu.Unlock()
+	return s.conn.Close()
+}
@@ -0,0 +1,154 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeSigner signs by reversing each blinded credential, failing those in fail. With release set, it
+// signals started and waits for release before signing
+type fakeSigner struct {
+	fail    map[string]bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeSigner) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	if f.release != nil {
+		f.started <- struct{}{}
+		<-f.release
+	}
+	signed := jsonutils.TextArray{}
+	for _, cred := range blindedCreds {
+		if f.fail[cred] {
+			return nil, errors.New("cbr unavailable")
+		}
+		runes := []rune(cred)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		signed = append(signed, string(runes))
+	}
+	proof := "proof"
+	return &OrderCreds{ID: orderID, BlindedCreds: blindedCreds, SignedCreds: &signed, BatchProof: &proof, PublicKey: &issuer.PublicKey}, nil
+}
+
+func startSigner(t *testing.T, worker OrderWorker, serverToken, clientToken string) (*SignerServer, *RemoteSigner) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	signerServer := NewSignerServer(worker, serverToken, 4)
+	signerServer.Register(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithInsecure(),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(signerCodecName)),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	require.NoError(t, err)
+	signer := NewRemoteSigner(conn, clientToken)
+	t.Cleanup(func() { _ = signer.Close() })
+	return signerServer, signer
+}
+
+func TestRemoteSigner(t *testing.T) {
+	_, signer := startSigner(t, &fakeSigner{fail: map[string]bool{"bad": true}}, "secret", "secret")
+	issuer := Issuer{ID: uuid.NewV4(), MerchantID: "brave.com", PublicKey: "key"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// jobs share the stream, each receiving its own batch
+	var wg sync.WaitGroup
+	for _, cred := range []string{"abc", "def", "ghi", "jkl"} {
+		wg.Add(1)
+		go func(cred string) {
+			defer wg.Done()
+			orderID := uuid.NewV4()
+			creds, err := signer.SignOrderCreds(ctx, orderID, issuer, []string{cred})
+			if assert.NoError(t, err) {
+				assert.Equal(t, orderID, creds.ID)
+				assert.Equal(t, "key", *creds.PublicKey)
+				require.Len(t, *creds.SignedCreds, 1)
+				assert.Equal(t, []rune(cred)[2], []rune((*creds.SignedCreds)[0])[0])
+			}
+		}(cred)
+	}
+	wg.Wait()
+
+	_, err := signer.SignOrderCreds(ctx, uuid.NewV4(), issuer, []string{"bad"})
+	assert.EqualError(t, err, "failed to sign order creds: cbr unavailable")
+}
+
+func TestRemoteSignerUnauthorized(t *testing.T) {
+	_, signer := startSigner(t, &fakeSigner{}, "secret", "wrong")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := signer.SignOrderCreds(ctx, uuid.NewV4(), Issuer{}, []string{"abc"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ErrSigningStreamClosed.Error())
+	assert.Contains(t, err.Error(), "invalid signer token")
+}
+
+func TestSignerServerDrain(t *testing.T) {
+	worker := &fakeSigner{started: make(chan struct{}), release: make(chan struct{})}
+	signerServer, signer := startSigner(t, worker, "", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the job being signed when draining starts is still sent back
+	signed := make(chan error, 1)
+	go func() {
+		_, err := signer.SignOrderCreds(ctx, uuid.NewV4(), Issuer{}, []string{"abc"})
+		signed <- err
+	}()
+	<-worker.started
+
+	drained := make(chan bool, 1)
+	go func() { drained <- signerServer.Drain(ctx) }()
+	close(worker.release)
+	assert.True(t, <-drained)
+	assert.NoError(t, <-signed)
+
+	// later jobs are failed, to be retried by another signer
+	_, err := signer.SignOrderCreds(ctx, uuid.NewV4(), Issuer{}, []string{"def"})
+	assert.EqualError(t, err, "failed to sign order creds: "+ErrSignerDraining.Error())
+}
+
+// tracedSigner records the trace the credentials were signed in
+type tracedSigner struct {
+	fakeSigner
+	traceID tracing.TraceID
+}
+
+func (s *tracedSigner) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	s.traceID = tracing.SpanFromContext(ctx).Context().TraceID
+	return s.fakeSigner.SignOrderCreds(ctx, orderID, issuer, blindedCreds)
+}
+
+func TestRemoteSignerTrace(t *testing.T) {
+	worker := &tracedSigner{}
+	_, signer := startSigner(t, worker, "secret", "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	parent := tracing.SpanContext{TraceID: tracing.TraceID{1}, SpanID: tracing.SpanID{2}}
+	ctx = tracing.ContextWithRemoteParent(ctx, parent)
+	_, err := signer.SignOrderCreds(ctx, uuid.NewV4(), Issuer{PublicKey: "key"}, []string{"abc"})
+	require.NoError(t, err)
+	assert.Equal(t, parent.TraceID, worker.traceID, "jobs are signed in the trace of the order worker")
+}
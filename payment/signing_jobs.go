@@ -0,0 +1,117 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// SigningJobPending is a signing job waiting to be signed, or to be retried
+	SigningJobPending = "pending"
+	// SigningJobRunning is a signing job claimed by a worker, which other workers take over once its
+	// visibility timeout passes
+	SigningJobRunning = "running"
+	// SigningJobSigned is a signing job whose credentials are signed
+	SigningJobSigned = "signed"
+	// SigningJobFailed is a signing job which failed permanently, or ran out of attempts
+	SigningJobFailed = "failed"
+
+	// signingVisibilityTimeout is how long a claimed job is hidden from other workers, longer than a signing
+	// call can take so only jobs of workers which died are taken over
+	signingVisibilityTimeout = 2 * time.Minute
+	// signingMaxAttempts is how many times a job is attempted before it fails
+	signingMaxAttempts = 10
+	// signingBackoffBase is the delay before the first retry, doubling with each attempt
+	signingBackoffBase = 5 * time.Second
+	// signingBackoffMax caps the delay between retries
+	signingBackoffMax = 10 * time.Minute
+)
+
+// ErrInvalidBatchProof is returned when signed credentials do not come with a proof that they were signed
+// with their issuer's key. Such credentials are never stored
+var ErrInvalidBatchProof = errors.New("signed credentials failed batch proof verification")
+
+// BatchProofVerifier checks the batch proof of credentials signed with the public key of their issuer
+type BatchProofVerifier func(publicKey string, blindedCreds, signedCreds []string, batchProof string) error
+
+// batchProofVerifier verifies the batch proofs of signed credentials before they are stored, none are
+// verified until one is set with VerifyBatchProofs
+var batchProofVerifier BatchProofVerifier
+
+// VerifyBatchProofs has the batch proofs of signed credentials checked by the verifier before they are
+// stored, or stops checking them when it is nil. It is set at startup, before any job runs
+func VerifyBatchProofs(verifier BatchProofVerifier) {
+	batchProofVerifier = verifier
+}
+
+var signingJobResults = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "order_signing_jobs_total",
+		Help: "Attempts of order signing jobs, by result",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(signingJobResults)
+}
+
+// orderSigningJob is a signing job claimed from the queue, along with the credentials it signs
+type orderSigningJob struct {
+	Issuer
+	OrderID      uuid.UUID           `db:"order_id"`
+	ItemID       uuid.UUID           `db:"item_id"`
+	BlindedCreds jsonutils.TextArray `db:"blinded_creds"`
+	Attempts     int                 `db:"attempts"`
+}
+
+// signingBackoff is the delay before retrying a job which failed its attempt
+func signingBackoff(attempts int) time.Duration {
+	backoff := signingBackoffBase
+	for i := 1; i < attempts && backoff < signingBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > signingBackoffMax {
+		return signingBackoffMax
+	}
+	return backoff
+}
+
+// verifySignedCreds checks the batch proof of signed credentials against the public key of their issuer,
+// when batch proofs are verified
+func verifySignedCreds(issuer Issuer, blindedCreds []string, creds *OrderCreds) error {
+	verifier := batchProofVerifier
+	if verifier == nil {
+		return nil
+	}
+	if creds.SignedCreds == nil || creds.BatchProof == nil {
+		return fmt.Errorf("%w: no signed credentials or proof", ErrInvalidBatchProof)
+	}
+	if err := verifier(issuer.PublicKey, blindedCreds, *creds.SignedCreds, *creds.BatchProof); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBatchProof, err)
+	}
+	return nil
+}
+
+// retryableSigningError is whether a signing attempt might succeed if retried, the challenge bypass
+// server rejecting the credentials will reject them again. Batches whose proofs do not verify are retried,
+// so their jobs are not lost to a verifier which disagrees with the server while it is rolled back
+func retryableSigningError(err error) bool {
+	var bundle *errorutils.ErrorBundle
+	if errors.As(err, &bundle) {
+		if state, ok := bundle.Data().(clients.HTTPState); ok {
+			status := state.Status
+			return status < http.StatusBadRequest || status >= http.StatusInternalServerError ||
+				status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+		}
+	}
+	return true
+}
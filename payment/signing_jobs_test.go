@@ -0,0 +1,182 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWorker fails every signing attempt with its error
+type failingWorker struct {
+	err error
+}
+
+func (w failingWorker) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	return nil, w.err
+}
+
+// keyWorker signs every batch with its key, whether or not it is the issuer's
+type keyWorker struct {
+	key *ristretto.SigningKey
+}
+
+func (w keyWorker) SignOrderCreds(ctx context.Context, orderID uuid.UUID, issuer Issuer, blindedCreds []string) (*OrderCreds, error) {
+	signed, proof, err := w.key.SignBatch(blindedCreds)
+	if err != nil {
+		return nil, err
+	}
+	signedCreds := jsonutils.TextArray(signed)
+	return &OrderCreds{ID: orderID, BlindedCreds: blindedCreds, SignedCreds: &signedCreds, BatchProof: &proof, PublicKey: &issuer.PublicKey}, nil
+}
+
+func TestVerifySignedCreds(t *testing.T) {
+	key, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+	token, err := ristretto.NewToken()
+	require.NoError(t, err)
+	blinded, err := token.Blind()
+	require.NoError(t, err)
+	issuer := Issuer{PublicKey: key.PublicKey()}
+
+	other, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+	creds, err := keyWorker{key: other}.SignOrderCreds(context.Background(), uuid.NewV4(), issuer, []string{blinded})
+	require.NoError(t, err)
+	assert.NoError(t, verifySignedCreds(issuer, []string{blinded}, creds), "batch proofs are not verified without a verifier")
+
+	VerifyBatchProofs(ristretto.VerifyBatchProof)
+	defer VerifyBatchProofs(nil)
+	creds, err = keyWorker{key: key}.SignOrderCreds(context.Background(), uuid.NewV4(), issuer, []string{blinded})
+	require.NoError(t, err)
+	assert.NoError(t, verifySignedCreds(issuer, []string{blinded}, creds))
+
+	creds, err = keyWorker{key: other}.SignOrderCreds(context.Background(), uuid.NewV4(), issuer, []string{blinded})
+	require.NoError(t, err)
+	err = verifySignedCreds(issuer, []string{blinded}, creds)
+	assert.True(t, errors.Is(err, ErrInvalidBatchProof), "credentials signed with another key are rejected")
+	assert.True(t, retryableSigningError(err), "batches are retried while a verifier disagreeing with the server is rolled back")
+
+	creds.BatchProof = nil
+	assert.True(t, errors.Is(verifySignedCreds(issuer, []string{blinded}, creds), ErrInvalidBatchProof))
+}
+
+func TestSigningBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, signingBackoff(1))
+	assert.Equal(t, 10*time.Second, signingBackoff(2))
+	assert.Equal(t, 40*time.Second, signingBackoff(4))
+	assert.Equal(t, signingBackoffMax, signingBackoff(signingMaxAttempts))
+	assert.Equal(t, signingBackoffMax, signingBackoff(100))
+}
+
+func TestRetryableSigningError(t *testing.T) {
+	assert.True(t, retryableSigningError(errors.New("connection reset")))
+	assert.True(t, retryableSigningError(clients.NewHTTPError(nil, "v1/blindedToken", "unavailable", http.StatusServiceUnavailable, nil)))
+	assert.True(t, retryableSigningError(clients.NewHTTPError(nil, "v1/blindedToken", "slow down", http.StatusTooManyRequests, nil)))
+	assert.False(t, retryableSigningError(clients.NewHTTPError(nil, "v1/blindedToken", "bad tokens", http.StatusBadRequest, nil)))
+}
+
+func TestRunNextOrderJobFailure(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		attempts int
+		status   string
+	}{
+		{"transient errors are retried", errors.New("connection reset"), 1, SigningJobPending},
+		{"rejected credentials fail", clients.NewHTTPError(nil, "v1/blindedToken", "bad tokens", http.StatusBadRequest, nil), 1, SigningJobFailed},
+		{"the last attempt fails", errors.New("connection reset"), signingMaxAttempts, SigningJobFailed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+			pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+			itemID, orderID := uuid.NewV4(), uuid.NewV4()
+			mock.ExpectBegin()
+			mock.ExpectQuery(`WITH job AS`).
+				WithArgs("120 seconds").
+				WillReturnRows(sqlmock.NewRows([]string{
+					"id", "created_at", "merchant_id", "public_key", "version", "valid_from", "valid_to",
+					"order_id", "item_id", "blinded_creds", "attempts",
+				}).AddRow(uuid.NewV4(), time.Now(), "brave.com?sku=vote", "key", 1, time.Now(), nil,
+					orderID, itemID, `["a"]`, c.attempts))
+			mock.ExpectCommit()
+			mock.ExpectExec(`UPDATE order_signing_jobs`).
+				WithArgs(itemID, c.attempts, c.status, c.err.Error(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			attempted, err := pg.RunNextOrderJob(context.Background(), failingWorker{err: c.err})
+			assert.True(t, attempted)
+			assert.Equal(t, c.err, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRunNextOrderJobInvalidProof(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+	issuerKey, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+	signerKey, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+	token, err := ristretto.NewToken()
+	require.NoError(t, err)
+	blinded, err := token.Blind()
+	require.NoError(t, err)
+	VerifyBatchProofs(ristretto.VerifyBatchProof)
+	defer VerifyBatchProofs(nil)
+
+	itemID, orderID := uuid.NewV4(), uuid.NewV4()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH job AS`).
+		WithArgs("120 seconds").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "created_at", "merchant_id", "public_key", "version", "valid_from", "valid_to",
+			"order_id", "item_id", "blinded_creds", "attempts",
+		}).AddRow(uuid.NewV4(), time.Now(), "brave.com?sku=vote", issuerKey.PublicKey(), 1, time.Now(), nil,
+			orderID, itemID, `{`+blinded+`}`, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE order_signing_jobs`).
+		WithArgs(itemID, 1, SigningJobPending, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	attempted, err := pg.RunNextOrderJob(context.Background(), keyWorker{key: signerKey})
+	assert.True(t, attempted)
+	assert.True(t, errors.Is(err, ErrInvalidBatchProof))
+	assert.NoError(t, mock.ExpectationsWereMet(), "credentials which fail verification are never stored")
+}
+
+func TestRunNextOrderJobEmpty(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH job AS`).WillReturnRows(sqlmock.NewRows([]string{"item_id"}))
+	mock.ExpectRollback()
+
+	attempted, err := pg.RunNextOrderJob(context.Background(), failingWorker{})
+	assert.NoError(t, err)
+	assert.False(t, attempted, "jobs which are not visible yet are not attempted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
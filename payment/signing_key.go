@@ -0,0 +1,252 @@
+package payment
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/brave-intl/bat-go/middleware"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/httpsignature"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/ed25519"
+)
+
+// signedOrderTTL is how far the date of a signed order request may be from now before it is rejected as stale
+const signedOrderTTL = 5 * time.Minute
+
+// ErrSigningKeyScope is returned when an order is outside the scope of the key it was signed with
+var ErrSigningKeyScope = errorutils.NewApplicationError("signing_key_scope", http.StatusForbidden, "order is outside the scope of the signing key", false)
+
+// MerchantSigningKey is an ed25519 key a merchant's backend signs order creation requests with
+type MerchantSigningKey struct {
+	ID          uuid.UUID        `json:"id" db:"id"`
+	MerchantID  string           `json:"merchantId" db:"merchant_id"`
+	Name        string           `json:"name" db:"name"`
+	PublicKey   string           `json:"publicKey" db:"public_key"`
+	AllowedSKUs pq.StringArray   `json:"allowedSkus" db:"allowed_skus"`
+	MaxAmount   *decimal.Decimal `json:"maxAmount" db:"max_amount"`
+	CreatedAt   time.Time        `json:"createdAt" db:"created_at"`
+	RevokedAt   *time.Time       `json:"revokedAt" db:"revoked_at"`
+}
+
+// AllowsSKU checks if orders signed with the key may include the sku, keys without allowed skus may sell any
+func (key *MerchantSigningKey) AllowsSKU(sku string) bool {
+	if len(key.AllowedSKUs) == 0 {
+		return true
+	}
+	for _, allowed := range key.AllowedSKUs {
+		if allowed == sku {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupPublicKey based on the HTTP signing keyID, which for orders is the id of a merchant signing key
+func (s *Service) LookupPublicKey(ctx context.Context, keyID string) (*httpsignature.Verifier, error) {
+	id, err := uuid.FromString(keyID)
+	if err != nil {
+		// an unknown key id is treated the same as a missing key
+		return nil, nil
+	}
+
+	key, err := s.Datastore.GetMerchantSigningKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	publicKey, err := hex.DecodeString(key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+	verifier := httpsignature.Verifier(httpsignature.Ed25519PubKey(publicKey))
+	return &verifier, nil
+}
+
+// CreateSignedOrder creates an order on behalf of a merchant, checking it falls within the scope of the key the
+// request was signed with
+func (s *Service) CreateSignedOrder(ctx context.Context, key *MerchantSigningKey, req CreateOrderRequest) (*Order, error) {
+	total := decimal.Zero
+	for _, item := range req.Items {
+		orderItem, err := CreateOrderItemFromMacaroon(item.SKU, item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		if orderItem.Location.String != key.MerchantID {
+			return nil, fmt.Errorf("%s is sold by %s: %w", orderItem.SKU, orderItem.Location.String, ErrSigningKeyScope)
+		}
+		if !key.AllowsSKU(orderItem.SKU) {
+			return nil, fmt.Errorf("%s: %w", orderItem.SKU, ErrSigningKeyScope)
+		}
+		total = total.Add(orderItem.Subtotal)
+	}
+	if key.MaxAmount != nil && total.GreaterThan(*key.MaxAmount) {
+		return nil, fmt.Errorf("total of %s exceeds %s: %w", total, key.MaxAmount, ErrSigningKeyScope)
+	}
+
+	return s.CreateOrderFromRequest(ctx, req)
+}
+
+// CreateSignedOrder is the handler for creating orders with requests signed by a merchant signing key
+func CreateSignedOrder(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		keyID, err := middleware.GetKeyID(r.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting signing key", http.StatusUnauthorized)
+		}
+		key, err := service.Datastore.GetMerchantSigningKey(r.Context(), uuid.FromStringOrNil(keyID))
+		if err != nil {
+			return handlers.WrapError(err, "Error getting signing key", http.StatusInternalServerError)
+		}
+		if key == nil {
+			return &handlers.AppError{
+				Message: "Signing key not found",
+				Code:    http.StatusUnauthorized,
+			}
+		}
+
+		var req CreateOrderRequest
+		err = requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		_, err = govalidator.ValidateStruct(req)
+		if err != nil {
+			return handlers.WrapValidationError(err)
+		}
+		if len(req.Items) == 0 {
+			return handlers.ValidationError(
+				"Error validating request body",
+				map[string]interface{}{
+					"items": "array must contain at least one item",
+				},
+			)
+		}
+		for _, item := range req.Items {
+			if !IsValidSKU(item.SKU) {
+				return handlers.WrapError(err, "Invalid SKU Token provided in request", http.StatusBadRequest)
+			}
+		}
+
+		order, err := service.CreateSignedOrder(r.Context(), key, req)
+		if errors.Is(err, ErrSKUNotAllowed) {
+			return handlers.WrapError(err, "Invalid SKU Token provided in request", http.StatusBadRequest)
+		}
+		if err != nil {
+			return handlers.WrapError(err, "Error creating the order in the database", http.StatusInternalServerError)
+		}
+
+		return handlers.RenderContent(r.Context(), order, w, http.StatusCreated)
+	})
+}
+
+// CreateMerchantSigningKeyRequest includes the public key and scopes of a merchant signing key
+type CreateMerchantSigningKeyRequest struct {
+	Name        string           `json:"name" valid:"-"`
+	PublicKey   string           `json:"publicKey" valid:"-"`
+	AllowedSKUs []string         `json:"allowedSkus" valid:"-"`
+	MaxAmount   *decimal.Decimal `json:"maxAmount" valid:"-"`
+}
+
+// Validate checks the signing key request is well formed, returning the errors by field
+func (req *CreateMerchantSigningKeyRequest) Validate() map[string]interface{} {
+	errs := map[string]interface{}{}
+	if req.Name == "" {
+		errs["name"] = "must not be empty"
+	}
+	if publicKey, err := hex.DecodeString(req.PublicKey); err != nil || len(publicKey) != ed25519.PublicKeySize {
+		errs["publicKey"] = "must be a hex encoded ed25519 public key"
+	}
+	if req.MaxAmount != nil && !req.MaxAmount.IsPositive() {
+		errs["maxAmount"] = "must be positive"
+	}
+	return errs
+}
+
+// GetMerchantSigningKeys is the handler for listing the active signing keys of a merchant
+func GetMerchantSigningKeys(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		keys, err := service.Datastore.GetMerchantSigningKeys(r.Context(), chi.URLParam(r, "merchantID"))
+		if err != nil {
+			return handlers.WrapError(err, "Error getting signing keys", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), keys, w, http.StatusOK)
+	})
+}
+
+// CreateMerchantSigningKey is the handler for registering a signing key for a merchant
+func CreateMerchantSigningKey(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req CreateMerchantSigningKeyRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		if errs := req.Validate(); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
+
+		key := &MerchantSigningKey{
+			MerchantID:  chi.URLParam(r, "merchantID"),
+			Name:        req.Name,
+			PublicKey:   req.PublicKey,
+			AllowedSKUs: req.AllowedSKUs,
+			MaxAmount:   req.MaxAmount,
+		}
+		if key.AllowedSKUs == nil {
+			key.AllowedSKUs = []string{}
+		}
+
+		created, err := service.Datastore.CreateMerchantSigningKey(r.Context(), key)
+		if err != nil {
+			return handlers.WrapError(err, "Error creating signing key", http.StatusInternalServerError)
+		}
+		middleware.AuditEntity(r.Context(), "signing_key", created.ID.String())
+
+		return handlers.RenderContent(r.Context(), created, w, http.StatusCreated)
+	})
+}
+
+// RevokeMerchantSigningKey is the handler for revoking a signing key of a merchant
+func RevokeMerchantSigningKey(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var id = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), id, chi.URLParam(r, "id")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"id": err.Error(),
+				},
+			)
+		}
+
+		key, err := service.Datastore.RevokeMerchantSigningKey(r.Context(), chi.URLParam(r, "merchantID"), *id.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error revoking signing key", http.StatusInternalServerError)
+		}
+		if key == nil {
+			return &handlers.AppError{
+				Message: "Signing key not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "signing_key", key.ID.String())
+
+		return handlers.RenderContent(r.Context(), key, w, http.StatusOK)
+	})
+}
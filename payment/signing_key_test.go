@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/httpsignature"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSignedOrderScope(t *testing.T) {
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds}
+	// the first development sku is a 0.25 BAT vote
+	vote := CreateOrderRequest{Items: []OrderItemRequest{{SKU: developmentSKUs[0], Quantity: 4}}}
+
+	key := &MerchantSigningKey{MerchantID: "brave.com"}
+	order, err := service.CreateSignedOrder(context.Background(), key, vote)
+	require.NoError(t, err)
+	assert.Equal(t, "1", order.TotalPrice.String())
+
+	key = &MerchantSigningKey{MerchantID: "other.com"}
+	_, err = service.CreateSignedOrder(context.Background(), key, vote)
+	assert.ErrorIs(t, err, ErrSigningKeyScope, "keys may only create orders for their merchant's skus")
+
+	key = &MerchantSigningKey{MerchantID: "brave.com", AllowedSKUs: []string{"free-trial"}}
+	_, err = service.CreateSignedOrder(context.Background(), key, vote)
+	assert.ErrorIs(t, err, ErrSigningKeyScope, "keys may be limited to some skus")
+
+	max := decimal.NewFromFloat(0.5)
+	key = &MerchantSigningKey{MerchantID: "brave.com", MaxAmount: &max}
+	_, err = service.CreateSignedOrder(context.Background(), key, vote)
+	assert.ErrorIs(t, err, ErrSigningKeyScope, "keys may be limited to an amount")
+	assert.Len(t, ds.orders, 1)
+}
+
+func TestCreateSignedOrderHandler(t *testing.T) {
+	publicKey, privateKey, err := httpsignature.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+
+	ds := newFakeDatastore()
+	signingKey, err := ds.CreateMerchantSigningKey(context.Background(), &MerchantSigningKey{
+		MerchantID: "brave.com",
+		PublicKey:  hex.EncodeToString(publicKey),
+	})
+	require.NoError(t, err)
+	service := &Service{Datastore: ds}
+	handler := middleware.HTTPSignedOnly(service, middleware.RequireNonce(middleware.NewMemoryNonceStore(), signedOrderTTL))(CreateSignedOrder(service))
+
+	body, err := json.Marshal(CreateOrderRequest{Items: []OrderItemRequest{{SKU: developmentSKUs[0], Quantity: 1}}})
+	require.NoError(t, err)
+
+	serve := func(keyID string) int {
+		req := httptest.NewRequest("POST", "/v1/orders/signed", bytes.NewBuffer(body))
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		req.Header.Set(middleware.NonceHeader, uuid.NewV4().String())
+		var s httpsignature.Signature
+		s.Algorithm = httpsignature.ED25519
+		s.KeyID = keyID
+		s.Headers = []string{"digest", "(request-target)", "date", "nonce"}
+		require.NoError(t, s.Sign(privateKey, crypto.Hash(0), req))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, serve(signingKey.ID.String()))
+	assert.Equal(t, http.StatusNotFound, serve(uuid.NewV4().String()), "unknown keys should fail")
+	assert.Equal(t, http.StatusNotFound, serve("not-a-uuid"), "malformed key ids should fail")
+	assert.Len(t, ds.orders, 1)
+}
+
+func TestMerchantSigningKey(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	keyID := uuid.NewV4()
+	now := time.Now()
+	columns := []string{"id", "merchant_id", "name", "public_key", "allowed_skus", "max_amount", "created_at", "revoked_at"}
+
+	mock.ExpectQuery(`FROM merchant_signing_keys WHERE id = \$1 AND revoked_at IS NULL`).WithArgs(keyID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(keyID, "brave.com", "server", "public", "{vote}", "10", now, nil))
+	key, err := pg.GetMerchantSigningKey(context.Background(), keyID)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, "brave.com", key.MerchantID)
+	assert.Equal(t, []string{"vote"}, []string(key.AllowedSKUs))
+	require.NotNil(t, key.MaxAmount)
+	assert.True(t, decimal.New(10, 0).Equal(*key.MaxAmount))
+
+	mock.ExpectQuery(`UPDATE merchant_signing_keys SET revoked_at = current_timestamp WHERE id = \$1 AND merchant_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(keyID, "brave.com").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(keyID, "brave.com", "server", "public", "{vote}", nil, now, now))
+	key, err = pg.RevokeMerchantSigningKey(context.Background(), "brave.com", keyID)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	assert.NotNil(t, key.RevokedAt)
+
+	// revoked keys are not found, nor revoked again
+	mock.ExpectQuery(`FROM merchant_signing_keys`).WithArgs(keyID).WillReturnRows(sqlmock.NewRows(columns))
+	key, err = pg.GetMerchantSigningKey(context.Background(), keyID)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+	mock.ExpectQuery(`UPDATE merchant_signing_keys`).WithArgs(keyID, "brave.com").WillReturnRows(sqlmock.NewRows(columns))
+	key, err = pg.RevokeMerchantSigningKey(context.Background(), "brave.com", keyID)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,202 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/inputs"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/net/websocket"
+)
+
+// orderSocketLifetime bounds how long a signing progress socket stays open, it outlives
+// the request timeout since signing large orders can take a while
+const orderSocketLifetime = 10 * time.Minute
+
+// OrderSigningProgress is pushed over the signing progress socket, signed item events
+// include the signed credentials of the item
+type OrderSigningProgress struct {
+	OrderEvent
+	Credentials *OrderCreds `json:"credentials,omitempty"`
+}
+
+// signingProgress returns the signing progress of the event, with the signed credentials of signed items
+func (s *Service) signingProgress(orderID uuid.UUID, event OrderEvent) (OrderSigningProgress, error) {
+	progress := OrderSigningProgress{OrderEvent: event}
+	if event.Type == OrderEventItemSigned {
+		creds, err := s.Datastore.GetOrderCredsByItemID(orderID, *event.ItemID, true)
+		if err != nil {
+			return progress, err
+		}
+		progress.Credentials = creds
+	}
+	return progress, nil
+}
+
+// signingComplete is whether the event reports every item of the order signed
+func signingComplete(event OrderEvent) bool {
+	return event.Type == OrderEventCredentials && event.TotalItems > 0 && event.SignedItems >= event.TotalItems
+}
+
+// acceptAnyOrigin allows clients which do not send an origin, such as mobile clients, the
+// socket is authorized by the order id alone just as the order credential endpoints are
+func acceptAnyOrigin(config *websocket.Config, r *http.Request) error {
+	return nil
+}
+
+// GetOrderCredsSocket is the handler for a websocket which pushes the signing progress of
+// each order item as the signing worker completes it, closing once every item is signed
+func GetOrderCredsSocket(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		logger, err := appctx.GetLogger(r.Context())
+		if err != nil {
+			_, logger = logging.SetupLogger(r.Context())
+		}
+
+		server := websocket.Server{
+			Handshake: acceptAnyOrigin,
+			Handler: func(ws *websocket.Conn) {
+				defer func() { _ = ws.Close() }()
+				_ = ws.SetDeadline(time.Now().Add(orderSocketLifetime))
+
+				// detached from the request context so the request timeout does not end the socket
+				ctx, cancel := context.WithTimeout(context.Background(), orderSocketLifetime)
+				defer cancel()
+				go func() {
+					// client messages are ignored, a failed read means the client has gone away
+					_, _ = io.Copy(ioutil.Discard, ws)
+					cancel()
+				}()
+
+				events, errs := service.WatchOrder(ctx, order.ID)
+				for event := range events {
+					progress, err := service.signingProgress(order.ID, event)
+					if err != nil {
+						logger.Error().Err(err).Msg("failed to get signed order credentials")
+						return
+					}
+					if err := websocket.JSON.Send(ws, progress); err != nil {
+						return
+					}
+					if signingComplete(event) {
+						return
+					}
+				}
+
+				select {
+				case err := <-errs:
+					logger.Error().Err(err).Msg("failed to watch order")
+				default:
+				}
+			},
+		}
+		server.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// GetOrderCredsEvents is the handler for a server-sent event stream of the signing progress of each
+// order item, as pushed over the signing progress socket, for clients which cannot hold a websocket.
+// The stream ends once every item is signed, clients reconnecting are sent the signed items again
+func GetOrderCredsEvents(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var orderID = new(inputs.ID)
+		if err := inputs.DecodeAndValidateString(context.Background(), orderID, chi.URLParam(r, "orderID")); err != nil {
+			return handlers.ValidationError(
+				"Error validating request url parameter",
+				map[string]interface{}{
+					"orderID": err.Error(),
+				},
+			)
+		}
+
+		order, err := service.Datastore.GetOrder(*orderID.UUID())
+		if err != nil {
+			return handlers.WrapError(err, "Error retrieving the order", http.StatusInternalServerError)
+		}
+		if order == nil {
+			return handlers.WrapError(ErrOrderNotFound, "", http.StatusNotFound)
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return handlers.WrapError(errors.New("response writer does not support flushing"),
+				"Event streams are not supported", http.StatusInternalServerError)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// stop proxies from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		// the request timeout ends the stream, clients reconnect and are sent the current state
+		_, _ = fmt.Fprintf(w, "retry: %d\n\n", orderWatchInterval.Milliseconds())
+		flusher.Flush()
+
+		logger, err := appctx.GetLogger(r.Context())
+		if err != nil {
+			_, logger = logging.SetupLogger(r.Context())
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		events, errs := service.WatchOrder(ctx, order.ID)
+		id := 0
+		for event := range events {
+			progress, err := service.signingProgress(order.ID, event)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get signed order credentials")
+				return nil
+			}
+			data, err := json.Marshal(progress)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to encode signing progress")
+				continue
+			}
+			id++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data); err != nil {
+				return nil
+			}
+			flusher.Flush()
+			if signingComplete(event) {
+				return nil
+			}
+		}
+
+		select {
+		case err := <-errs:
+			logger.Error().Err(err).Msg("failed to watch order")
+		default:
+		}
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestGetOrderCredsSocket(t *testing.T) {
+	itemID := uuid.NewV4()
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "paid", Items: []OrderItem{{ID: itemID}}})
+	ds.creds = []OrderCreds{{ID: itemID, OrderID: order.ID}}
+	service := &Service{Datastore: ds, orderWatchers: newOrderNotifier()}
+
+	r := chi.NewRouter()
+	r.Method("GET", "/v1/orders/{orderID}/credentials/ws", GetOrderCredsSocket(service))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	_, err := websocket.Dial(wsURL+"/v1/orders/"+uuid.NewV4().String()+"/credentials/ws", "", server.URL)
+	assert.Error(t, err, "unknown orders should not be upgraded")
+
+	ws, err := websocket.Dial(wsURL+"/v1/orders/"+order.ID.String()+"/credentials/ws", "", server.URL)
+	require.NoError(t, err)
+	defer func() { _ = ws.Close() }()
+	require.NoError(t, ws.SetDeadline(time.Now().Add(5*time.Second)))
+
+	var progress OrderSigningProgress
+	require.NoError(t, websocket.JSON.Receive(ws, &progress))
+	assert.Equal(t, OrderEventStatus, progress.Type)
+	require.NoError(t, websocket.JSON.Receive(ws, &progress))
+	assert.Equal(t, OrderEventCredentials, progress.Type)
+	assert.Equal(t, 0, progress.SignedItems)
+
+	ds.signCreds(itemID, "signed")
+	service.NotifyOrderChanged(order.ID)
+
+	require.NoError(t, websocket.JSON.Receive(ws, &progress))
+	assert.Equal(t, OrderEventItemSigned, progress.Type)
+	if assert.NotNil(t, progress.Credentials) {
+		assert.Equal(t, itemID, progress.Credentials.ID)
+		assert.NotNil(t, progress.Credentials.SignedCreds)
+	}
+	require.NoError(t, websocket.JSON.Receive(ws, &progress))
+	assert.Equal(t, 1, progress.SignedItems)
+
+	assert.Error(t, websocket.JSON.Receive(ws, &progress), "the socket closes once every item is signed")
+}
+
+func TestGetOrderCredsEvents(t *testing.T) {
+	itemID := uuid.NewV4()
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "paid", Items: []OrderItem{{ID: itemID}}})
+	ds.creds = []OrderCreds{{ID: itemID, OrderID: order.ID}}
+	service := &Service{Datastore: ds, orderWatchers: newOrderNotifier()}
+
+	r := chi.NewRouter()
+	r.Method("GET", "/v1/orders/{orderID}/credentials/events", GetOrderCredsEvents(service))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/orders/" + uuid.NewV4().String() + "/credentials/events")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/v1/orders/" + order.ID.String() + "/credentials/events")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// next returns the next event of the stream, nil once the stream ends
+	lines := bufio.NewScanner(resp.Body)
+	next := func() *OrderSigningProgress {
+		for lines.Scan() {
+			if data := strings.TrimPrefix(lines.Text(), "data: "); data != lines.Text() {
+				var progress OrderSigningProgress
+				require.NoError(t, json.Unmarshal([]byte(data), &progress))
+				return &progress
+			}
+		}
+		return nil
+	}
+
+	assert.Equal(t, OrderEventStatus, next().Type)
+	assert.Equal(t, 0, next().SignedItems)
+
+	ds.signCreds(itemID, "signed")
+	service.NotifyOrderChanged(order.ID)
+
+	progress := next()
+	require.NotNil(t, progress)
+	assert.Equal(t, OrderEventItemSigned, progress.Type)
+	if assert.NotNil(t, progress.Credentials) {
+		assert.Equal(t, itemID, progress.Credentials.ID)
+		assert.NotNil(t, progress.Credentials.SignedCreds)
+	}
+	assert.Equal(t, 1, next().SignedItems)
+	assert.Nil(t, next(), "the stream ends once every item is signed")
+}
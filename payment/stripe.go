@@ -0,0 +1,189 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/stripe"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PaymentMethodStripe is the payment method of orders paid through a Stripe checkout session
+const PaymentMethodStripe = "stripe"
+
+var (
+	// ErrPaymentMethod is returned for orders with a payment method other than stripe, orders without one are
+	// paid with BAT transactions
+	ErrPaymentMethod = errorutils.NewApplicationError("unknown_payment_method", http.StatusBadRequest, "unknown payment method", false)
+	// ErrStripeNotEnabled is returned for orders paid with Stripe when STRIPE_SECRET_KEY is not set
+	ErrStripeNotEnabled = errorutils.NewApplicationError("stripe_not_enabled", http.StatusBadRequest, "stripe payments are not enabled", false)
+	// ErrStripeCurrency is returned for orders paid with Stripe in a currency other than USD
+	ErrStripeCurrency = errorutils.NewApplicationError("stripe_currency_not_supported", http.StatusBadRequest, "stripe payments are only taken in USD", false)
+	// ErrCheckoutSessionNotFound is returned for Stripe webhooks about a session no order was paid through
+	ErrCheckoutSessionNotFound = errorutils.NewApplicationError("checkout_session_not_found", http.StatusBadRequest, "checkout session not found", false)
+)
+
+// CheckoutSession is the hosted checkout session an order is paid through, the customer pays at its url
+type CheckoutSession struct {
+	OrderID   uuid.UUID `json:"-" db:"order_id"`
+	Provider  string    `json:"provider" db:"provider"`
+	SessionID string    `json:"sessionId" db:"session_id"`
+	URL       string    `json:"url" db:"url"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// checkoutURL substitutes the order into a success or cancel url configured for checkout sessions
+func checkoutURL(key string, orderID uuid.UUID) string {
+	return strings.Replace(os.Getenv(key), "{orderId}", orderID.String(), -1)
+}
+
+// checkPaymentMethod checks an order of the items can be paid with the payment method
+func (s *Service) checkPaymentMethod(paymentMethod string, items []OrderItem) error {
+	if paymentMethod == "" {
+		return nil
+	}
+	if paymentMethod != PaymentMethodStripe {
+		return ErrPaymentMethod
+	}
+	if s.stripeClient == nil {
+		return ErrStripeNotEnabled
+	}
+	for _, item := range items {
+		if item.Currency != "USD" {
+			return ErrStripeCurrency
+		}
+	}
+	return nil
+}
+
+// createStripeCheckoutSession creates the Stripe checkout session the order is paid through. The customer
+// returns to STRIPE_SUCCESS_URI or STRIPE_CANCEL_URI, with {orderId} replaced by the order's id
+func (s *Service) createStripeCheckoutSession(ctx context.Context, order *Order) (*CheckoutSession, error) {
+	req := &stripe.CheckoutSessionRequest{
+		ClientReferenceID: order.ID.String(),
+		SuccessURL:        checkoutURL("STRIPE_SUCCESS_URI", order.ID),
+		CancelURL:         checkoutURL("STRIPE_CANCEL_URI", order.ID),
+		Metadata:          map[string]string{"orderId": order.ID.String()},
+	}
+	for _, item := range order.Items {
+		name := item.SKU
+		if item.Description.Valid && item.Description.String != "" {
+			name = item.Description.String
+		}
+		req.LineItems = append(req.LineItems, stripe.LineItem{
+			Name:       name,
+			Currency:   item.Currency,
+			UnitAmount: item.Price.Shift(2).Round(0).IntPart(),
+			Quantity:   item.Quantity,
+		})
+	}
+	// stripe charges the sum of the line items, a discounted order is charged its total as a single item
+	if order.Voucher != nil {
+		req.LineItems = []stripe.LineItem{{
+			Name:       fmt.Sprintf("Order %s with voucher %s", order.ID, order.Voucher.Code),
+			Currency:   order.Currency,
+			UnitAmount: order.TotalPrice.Shift(2).Round(0).IntPart(),
+			Quantity:   1,
+		}}
+	}
+
+	stripeSession, err := s.stripeClient.CreateCheckoutSession(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stripe checkout session: %w", err)
+	}
+
+	session := &CheckoutSession{
+		OrderID:   order.ID,
+		Provider:  PaymentMethodStripe,
+		SessionID: stripeSession.ID,
+		URL:       stripeSession.URL,
+	}
+	if err := s.Datastore.InsertCheckoutSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to record checkout session: %w", err)
+	}
+	return session, nil
+}
+
+// PayStripeCheckout records the payment of a paid Stripe checkout session as a transaction of its order,
+// marking the order paid so its credentials can be created. Stripe retries webhooks, so a payment which was
+// already recorded is not recorded again
+func (s *Service) PayStripeCheckout(ctx context.Context, stripeSession *stripe.CheckoutSession) error {
+	orderID, err := uuid.FromString(stripeSession.ClientReferenceID)
+	if err != nil {
+		return ErrCheckoutSessionNotFound
+	}
+	session, err := s.Datastore.GetCheckoutSession(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.Provider != PaymentMethodStripe || session.SessionID != stripeSession.ID {
+		return ErrCheckoutSessionNotFound
+	}
+
+	externalID := stripeSession.PaymentIntent
+	if externalID == "" {
+		externalID = stripeSession.ID
+	}
+	transaction, err := s.Datastore.GetTransaction(externalID)
+	if err != nil {
+		return fmt.Errorf("failed to get stripe transaction: %w", err)
+	}
+	if transaction == nil {
+		// amounts are in cents
+		amount := decimal.New(stripeSession.AmountTotal, -2)
+		transaction, err = s.Datastore.CreateTransaction(orderID, externalID, "completed",
+			strings.ToUpper(stripeSession.Currency), PaymentMethodStripe, amount)
+		if err != nil {
+			return fmt.Errorf("failed to record stripe transaction: %w", err)
+		}
+		s.streamTransaction(transaction)
+	}
+
+	return s.UpdateOrderStatus(ctx, orderID)
+}
+
+// StripeWebhook is the handler for webhooks sent by Stripe, signed with STRIPE_WEBHOOK_SECRET. Paid
+// checkout sessions pay their order, other events are acknowledged and ignored
+func StripeWebhook(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		if service.stripeClient == nil || service.stripeWebhookSecret == "" {
+			return &handlers.AppError{
+				Message: "Stripe payments are not enabled",
+				Code:    http.StatusNotFound,
+			}
+		}
+
+		payload, err := requestutils.Read(r.Body)
+		if err != nil {
+			return handlers.WrapError(err, "Error reading request body", http.StatusBadRequest)
+		}
+		err = stripe.VerifyWebhookSignature(payload, r.Header.Get(stripe.SignatureHeader), service.stripeWebhookSecret,
+			stripe.DefaultTolerance, time.Now())
+		if err != nil {
+			return handlers.WrapError(err, "Invalid webhook signature", http.StatusBadRequest)
+		}
+
+		var event stripe.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		if !event.IsCheckoutPaid() {
+			return handlers.RenderContent(r.Context(), "event ignored", w, http.StatusOK)
+		}
+
+		err = service.PayStripeCheckout(r.Context(), &event.Data.Object)
+		if err != nil {
+			return handlers.WrapError(err, "Error paying order", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), "order paid", w, http.StatusOK)
+	})
+}
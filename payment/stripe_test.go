@@ -0,0 +1,58 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/stripe"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripeWebhook(t *testing.T) {
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{Status: "pending", Currency: "USD", TotalPrice: decimal.RequireFromString("9.99")})
+	orderID := order.ID
+	require.NoError(t, ds.InsertCheckoutSession(context.Background(),
+		&CheckoutSession{OrderID: orderID, Provider: PaymentMethodStripe, SessionID: "cs_test_1"}))
+	service := &Service{Datastore: ds, stripeClient: stripe.ClientWithPrometheus{}, stripeWebhookSecret: "whsec_test"}
+	handler := StripeWebhook(service)
+
+	send := func(payload string, signedAt time.Time) int {
+		r := httptest.NewRequest("POST", "/v1/webhooks/stripe", strings.NewReader(payload))
+		r.Header.Set(stripe.SignatureHeader, stripe.SignWebhook([]byte(payload), "whsec_test", signedAt))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	completed := `{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_test_1",
+		"client_reference_id":"` + orderID.String() + `","payment_status":"paid","payment_intent":"pi_1",
+		"amount_total":999,"currency":"usd"}}}`
+
+	assert.Equal(t, http.StatusBadRequest, send(completed, time.Now().Add(-time.Hour)), "old signatures are replays")
+	assert.Equal(t, "pending", order.Status)
+
+	assert.Equal(t, http.StatusOK, send(`{"id":"evt_0","type":"checkout.session.expired"}`, time.Now()))
+	assert.Equal(t, "pending", order.Status)
+
+	assert.Equal(t, http.StatusOK, send(completed, time.Now()))
+	assert.Equal(t, "paid", order.Status)
+	if assert.Len(t, ds.transactions, 1) {
+		assert.Equal(t, "pi_1", ds.transactions[0].ExternalTransactionID)
+		assert.Equal(t, "USD", ds.transactions[0].Currency)
+		assert.Equal(t, PaymentMethodStripe, ds.transactions[0].Kind)
+		assert.True(t, decimal.RequireFromString("9.99").Equal(ds.transactions[0].Amount))
+	}
+
+	// stripe retries webhooks, the payment is only recorded once
+	assert.Equal(t, http.StatusOK, send(completed, time.Now()))
+	assert.Len(t, ds.transactions, 1)
+
+	other := strings.Replace(completed, "cs_test_1", "cs_test_2", 1)
+	assert.Equal(t, http.StatusBadRequest, send(other, time.Now()), "sessions orders were not paid through are rejected")
+}
@@ -0,0 +1,165 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// timeLimitedCredentialType is the credential type of items whose credentials expire with their window
+	timeLimitedCredentialType = "time-limited"
+
+	// credentialWindow is how long a batch of time-limited credentials is valid. Windows start on Mondays
+	// at midnight UTC, as truncating to whole weeks counts from the first of January of year one, a Monday
+	credentialWindow = 7 * 24 * time.Hour
+	// credentialWindowSignAhead is how long before a window ends the credentials of the next are signed,
+	// so clients can fetch them before they need them
+	credentialWindowSignAhead = 2 * 24 * time.Hour
+	// credentialWindowRenewals is how many items get their next window each time the job runs
+	credentialWindowRenewals = 100
+
+	// credentialWindowParam carries the start of its window in the id of the issuer of a window
+	credentialWindowParam  = "valid_from"
+	credentialWindowLayout = "2006-01-02"
+)
+
+// CredentialWindow is the latest window of an item's time-limited credentials, along with the merchant and
+// sku which issue the item's next window
+type CredentialWindow struct {
+	OrderCreds
+	MerchantID string `db:"merchant_id"`
+	SKU        string `db:"sku"`
+}
+
+// credentialWindowStart returns the start of the window the time falls in
+func credentialWindowStart(t time.Time) time.Time {
+	return t.UTC().Truncate(credentialWindow)
+}
+
+// encodeWindowIssuerID is the id of the issuer of a merchant's sku which signs the window starting at
+// validFrom, the merchant and sku decode from it as from any other issuer id
+func encodeWindowIssuerID(merchantID, sku string, validFrom time.Time) (string, error) {
+	v := url.Values{}
+	v.Add("sku", sku)
+	v.Add(credentialWindowParam, validFrom.UTC().Format(credentialWindowLayout))
+
+	u, err := url.Parse(merchantID + "?" + v.Encode())
+	if err != nil {
+		return "", fmt.Errorf("parse merchant id: %w", err)
+	}
+
+	return u.String(), nil
+}
+
+// decodeIssuerWindow returns the start of the window of an issuer, false if it does not sign a window
+func decodeIssuerWindow(issuerID string) (time.Time, bool) {
+	u, err := url.Parse(issuerID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	validFrom, err := time.Parse(credentialWindowLayout, u.Query().Get(credentialWindowParam))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return validFrom, true
+}
+
+// credentialWindowOpen is whether credentials of the issuer redeem at the time, those of issuers which do
+// not sign a window always do
+func credentialWindowOpen(issuerID string, now time.Time) bool {
+	validFrom, ok := decodeIssuerWindow(issuerID)
+	if !ok {
+		return true
+	}
+	return !now.Before(validFrom) && now.Before(validFrom.Add(credentialWindow))
+}
+
+// getOrCreateWindowIssuer returns the issuer of a merchant's sku which signs the window starting at
+// validFrom, creating it when the window is first signed or its issuer was disabled
+func (service *Service) getOrCreateWindowIssuer(ctx context.Context, merchantID, sku string, validFrom time.Time) (*Issuer, error) {
+	issuerID, err := encodeWindowIssuerID(merchantID, sku, validFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	issuers, err := service.Datastore.GetIssuers(ctx, issuerID)
+	if err != nil {
+		return nil, err
+	}
+	version := 1
+	if len(issuers) > 0 {
+		if issuers[0].DisabledAt == nil {
+			return &issuers[0], nil
+		}
+		// a disabled window issuer is replaced by the next version, signing the rest of the window
+		version = issuers[0].Version + 1
+	}
+
+	validTo := validFrom.Add(credentialWindow)
+	issuer := &Issuer{MerchantID: issuerID, Version: version, ValidFrom: validFrom, ValidTo: &validTo}
+	if err := service.createIssuer(ctx, issuer); err != nil {
+		return nil, err
+	}
+	defer invalidateIssuers(*issuer)
+
+	return service.Datastore.InsertIssuer(issuer)
+}
+
+// RenewCredentialWindows adds the next window to the time-limited credentials of paid orders whose latest
+// window ends within the sign ahead, returning how many were renewed
+func (service *Service) RenewCredentialWindows(ctx context.Context) (int, error) {
+	windows, err := service.Datastore.GetExpiringCredentialWindows(ctx, time.Now().Add(credentialWindowSignAhead), credentialWindowRenewals)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, window := range windows {
+		// windows follow on from each other, so the credentials never lapse, and windows missed while the job
+		// was not running are skipped
+		validFrom := *window.ValidTo
+		if current := credentialWindowStart(time.Now()); current.After(validFrom) {
+			validFrom = current
+		}
+		issuer, err := service.getOrCreateWindowIssuer(ctx, window.MerchantID, window.SKU, validFrom)
+		if err != nil {
+			return i, fmt.Errorf("failed to get issuer of the next window: %w", err)
+		}
+		err = service.Datastore.InsertCredentialWindow(ctx, &OrderCreds{
+			ID:        window.ID,
+			OrderID:   window.OrderID,
+			IssuerID:  issuer.ID,
+			ValidFrom: &issuer.ValidFrom,
+			ValidTo:   issuer.ValidTo,
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to insert the next window: %w", err)
+		}
+	}
+	return len(windows), nil
+}
+
+// RunNextCredentialWindowJob renews the windows which end soon and signs the windows waiting to be signed
+func (service *Service) RunNextCredentialWindowJob(ctx context.Context) (bool, error) {
+	renewed, err := service.RenewCredentialWindows(ctx)
+	if err != nil {
+		sentry.CaptureMessage(err.Error())
+		return renewed > 0, fmt.Errorf("failed to renew credential windows: %w", err)
+	}
+
+	worked := renewed > 0
+	for {
+		attempted, err := service.Datastore.RunNextCredentialWindow(ctx, service.orderWorker())
+		if err != nil {
+			sentry.CaptureMessage(err.Error())
+			return true, fmt.Errorf("failed to sign credential window: %w", err)
+		}
+		if !attempted {
+			return worked, nil
+		}
+		worked = true
+	}
+}
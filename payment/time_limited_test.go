@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialWindowStart(t *testing.T) {
+	// a thursday afternoon falls in the window starting the monday before
+	start := credentialWindowStart(time.Date(2026, 10, 15, 15, 4, 5, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Monday, start.Weekday())
+	assert.Equal(t, start, credentialWindowStart(start))
+}
+
+func TestCredentialWindowOpen(t *testing.T) {
+	validFrom := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	issuerID, err := encodeWindowIssuerID("brave.com", "brave-vpn", validFrom)
+	require.NoError(t, err)
+
+	merchantID, sku, err := decodeIssuerID(issuerID)
+	require.NoError(t, err)
+	assert.Equal(t, "brave.com", merchantID)
+	assert.Equal(t, "brave-vpn", sku)
+
+	decoded, ok := decodeIssuerWindow(issuerID)
+	assert.True(t, ok)
+	assert.Equal(t, validFrom, decoded)
+
+	assert.True(t, credentialWindowOpen(issuerID, validFrom))
+	assert.True(t, credentialWindowOpen(issuerID, validFrom.Add(credentialWindow-time.Second)))
+	assert.False(t, credentialWindowOpen(issuerID, validFrom.Add(-time.Second)))
+	assert.False(t, credentialWindowOpen(issuerID, validFrom.Add(credentialWindow)))
+
+	// credentials of issuers which don't sign a window always redeem
+	singleUse, err := encodeIssuerID("brave.com", "anon-card-vote")
+	require.NoError(t, err)
+	_, ok = decodeIssuerWindow(singleUse)
+	assert.False(t, ok)
+	assert.True(t, credentialWindowOpen(singleUse, validFrom))
+}
+
+func TestRenewCredentialWindows(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	client := &issuerClient{}
+	service := &Service{Datastore: ds, cbClient: client}
+
+	// the windows of the paid orders end as the current window starts
+	validFrom := credentialWindowStart(time.Now())
+	lastFrom := validFrom.Add(-credentialWindow)
+	for _, status := range []string{"paid", "paid", "pending"} {
+		itemID := uuid.NewV4()
+		order := ds.addOrder(Order{MerchantID: "brave.com", Status: status, Items: []OrderItem{{ID: itemID, SKU: "brave-vpn"}}})
+		ds.windows = append(ds.windows, OrderCreds{ID: itemID, OrderID: order.ID, ValidFrom: &lastFrom, ValidTo: &validFrom})
+	}
+	expiring := append([]OrderCreds{}, ds.windows[:2]...)
+
+	renewed, err := service.RenewCredentialWindows(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, renewed, "only the windows of paid orders are renewed")
+	inserted := ds.windows[3:]
+	require.Len(t, inserted, 2)
+
+	// the next window follows on from the last, signed by one issuer for every item
+	next, ok := decodeIssuerWindow(client.created[0])
+	assert.True(t, ok)
+	assert.Equal(t, validFrom, next)
+	assert.Len(t, client.created, 1)
+	for i, creds := range inserted {
+		assert.Equal(t, expiring[i].ID, creds.ID)
+		assert.Equal(t, ds.issuers[0].ID, creds.IssuerID)
+		assert.Equal(t, validFrom, *creds.ValidFrom)
+		assert.Equal(t, validFrom.Add(credentialWindow), *creds.ValidTo)
+	}
+}
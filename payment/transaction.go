@@ -3,6 +3,8 @@ package payment
 import (
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/clients/bigquery"
+
 	uuid "github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 )
@@ -19,3 +21,29 @@ type Transaction struct {
 	Kind                  string          `json:"kind" db:"kind"`
 	Amount                decimal.Decimal `json:"amount" db:"amount"`
 }
+
+// BigQueryRow maps the transaction to a row of the BigQuery transactions table. Amounts are NUMERIC,
+// which are streamed as strings so no precision is lost
+func (t Transaction) BigQueryRow() bigquery.Row {
+	return bigquery.Row{
+		InsertID: t.ID.String(),
+		JSON: map[string]interface{}{
+			"id":                      t.ID.String(),
+			"order_id":                t.OrderID.String(),
+			"created_at":              t.CreatedAt.UTC().Format(time.RFC3339Nano),
+			"updated_at":              t.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			"external_transaction_id": t.ExternalTransactionID,
+			"status":                  t.Status,
+			"currency":                t.Currency,
+			"kind":                    t.Kind,
+			"amount":                  t.Amount.String(),
+		},
+	}
+}
+
+// streamTransaction streams a recorded transaction to BigQuery when streaming is enabled
+func (s *Service) streamTransaction(transaction *Transaction) {
+	if s.transactionStream != nil && transaction != nil {
+		s.transactionStream.Enqueue(transaction.BigQueryRow())
+	}
+}
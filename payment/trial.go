@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brave-intl/bat-go/middleware"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// defaultTrialLimit is how many trials a wallet may take with each merchant unless TRIAL_LIMIT_PER_WALLET is set
+const defaultTrialLimit = 1
+
+var (
+	// ErrOrderNotPaid is returned when credentials are requested for an order which is neither paid nor a trial
+	ErrOrderNotPaid = errorutils.NewApplicationError("order_not_paid", http.StatusBadRequest, "order has not yet been paid", false)
+	// ErrTrialWalletRequired is returned when the credentials of a trial are requested without a wallet
+	ErrTrialWalletRequired = errorutils.NewApplicationError("trial_wallet_required", http.StatusBadRequest, "the credentials of a trial are issued to a wallet", false)
+	// ErrTrialWalletUnsigned is returned when the credentials of a trial are requested for a wallet which did not
+	// sign the request
+	ErrTrialWalletUnsigned = errorutils.NewApplicationError("trial_wallet_unsigned", http.StatusUnauthorized, "the request must be signed by the trial wallet", false)
+	// ErrTrialWalletNotFound is returned when the credentials of a trial are requested for an unknown wallet
+	ErrTrialWalletNotFound = errorutils.NewApplicationError("trial_wallet_not_found", http.StatusBadRequest, "trial wallet not found", false)
+	// ErrTrialLimit is returned when a wallet has taken as many trials with the merchant as it may
+	ErrTrialLimit = errorutils.NewApplicationError("trial_limit_reached", http.StatusForbidden, "wallet has reached its trial limit", false)
+	// ErrTrialClaimed is returned when the trial of an order was already taken by another wallet
+	ErrTrialClaimed = errorutils.NewApplicationError("trial_claimed", http.StatusConflict, "trial was taken by another wallet", false)
+)
+
+// trialMerchants are the merchants offering trials, from the comma separated TRIAL_MERCHANTS
+func trialMerchants() map[string]bool {
+	merchants := map[string]bool{}
+	for _, merchant := range strings.Split(os.Getenv("TRIAL_MERCHANTS"), ",") {
+		if merchant = strings.TrimSpace(merchant); merchant != "" {
+			merchants[merchant] = true
+		}
+	}
+	return merchants
+}
+
+// trialLimit is how many trials a wallet may take with each merchant
+func trialLimit() int {
+	if limit, err := strconv.Atoi(os.Getenv("TRIAL_LIMIT_PER_WALLET")); err == nil && limit > 0 {
+		return limit
+	}
+	return defaultTrialLimit
+}
+
+// isTrial tells whether the order is a trial, an order of zero cost items from a merchant offering trials
+func (order Order) isTrial() bool {
+	return order.TotalPrice.IsZero() && trialMerchants()[order.MerchantID]
+}
+
+// walletSigned verifies the signature of requests signed by a wallet, as the wallet claim routes do, so the
+// credentials of a trial are only issued to the wallet which signed the request for them. Requests which are
+// not signed are passed on, the credentials of paid orders are requested without a wallet
+func walletSigned(service *Service) func(http.Handler) http.Handler {
+	signed := middleware.HTTPSignedOnly(service.wallet, middleware.RequireNonce(service.nonces, signedOrderTTL))
+	return func(next http.Handler) http.Handler {
+		verified := signed(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Signature") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			verified.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isSignedByWallet tells whether the request was signed by the wallet, see walletSigned
+func isSignedByWallet(ctx context.Context, walletID uuid.UUID) bool {
+	keyID, err := middleware.GetKeyID(ctx)
+	if err != nil {
+		return false
+	}
+	signer, err := uuid.FromString(keyID)
+	return err == nil && uuid.Equal(signer, walletID)
+}
+
+// claimTrial counts the trial against the limit of the wallet, which must exist. The trial of an order is
+// taken by a single wallet, which may request its credentials again without it counting twice
+func (service *Service) claimTrial(ctx context.Context, order *Order, walletID *uuid.UUID) error {
+	if walletID == nil {
+		return ErrTrialWalletRequired
+	}
+	if service.wallet != nil {
+		info, err := service.wallet.Datastore.GetWallet(ctx, *walletID)
+		if err != nil {
+			return fmt.Errorf("failed to get trial wallet: %w", err)
+		}
+		if info == nil {
+			return ErrTrialWalletNotFound
+		}
+	}
+	return service.Datastore.ClaimOrderTrial(ctx, order.ID, *walletID, order.MerchantID, trialLimit())
+}
@@ -0,0 +1,163 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/httpsignature"
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/brave-intl/bat-go/wallet"
+	"github.com/go-chi/chi"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceFreeOrder(t *testing.T) {
+	require.NoError(t, os.Setenv("TRIAL_MERCHANTS", "example.com"))
+	defer func() { _ = os.Unsetenv("TRIAL_MERCHANTS") }()
+	service := &Service{}
+	// the third development sku is a free trial
+	free := CreateOrderRequest{Items: []OrderItemRequest{{SKU: developmentSKUs[2], Quantity: 1}}}
+
+	order, err := service.priceOrder("brave.com", nil, free)
+	require.NoError(t, err)
+	assert.Equal(t, "paid", order.Status, "free orders of merchants without trials are paid")
+
+	order, err = service.priceOrder("example.com", nil, free)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", order.Status, "trials are never paid")
+}
+
+func TestIsTrial(t *testing.T) {
+	require.NoError(t, os.Setenv("TRIAL_MERCHANTS", "brave.com, example.com"))
+	defer func() { _ = os.Unsetenv("TRIAL_MERCHANTS") }()
+
+	assert.True(t, Order{MerchantID: "example.com", TotalPrice: decimal.Zero}.isTrial())
+	assert.False(t, Order{MerchantID: "example.com", TotalPrice: decimal.New(1, 0)}.isTrial(), "paid orders are not trials")
+	assert.False(t, Order{MerchantID: "other.com", TotalPrice: decimal.Zero}.isTrial(), "only allowlisted merchants offer trials")
+	assert.False(t, Order{MerchantID: "brave.com", TotalPrice: decimal.Zero, Status: "pending"}.IsPaid(), "trials are never paid")
+}
+
+func TestCreateOrderCredsTrial(t *testing.T) {
+	require.NoError(t, os.Unsetenv("TRIAL_MERCHANTS"))
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com", TotalPrice: decimal.Zero, Status: "pending"})
+	service := &Service{Datastore: ds}
+	walletID := uuid.NewV4()
+
+	err := service.CreateOrderCreds(context.Background(), order.ID, uuid.NewV4(), nil, &walletID)
+	assert.True(t, errors.Is(err, ErrOrderNotPaid), "free orders of merchants without trials are not paid")
+
+	require.NoError(t, os.Setenv("TRIAL_MERCHANTS", "brave.com"))
+	defer func() { _ = os.Unsetenv("TRIAL_MERCHANTS") }()
+
+	err = service.CreateOrderCreds(context.Background(), order.ID, uuid.NewV4(), nil, nil)
+	assert.True(t, errors.Is(err, ErrTrialWalletRequired))
+	assert.Empty(t, ds.trials)
+
+	require.NoError(t, service.claimTrial(context.Background(), order, &walletID))
+	require.NoError(t, service.claimTrial(context.Background(), order, &walletID), "the trial is claimed again by its wallet")
+	otherWalletID := uuid.NewV4()
+	assert.True(t, errors.Is(service.claimTrial(context.Background(), order, &otherWalletID), ErrTrialClaimed))
+
+	err = service.CreateOrderCreds(context.Background(), uuid.NewV4(), uuid.NewV4(), nil, &walletID)
+	assert.True(t, errors.Is(err, ErrOrderNotFound))
+}
+
+func TestClaimOrderTrial(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+	orderID, walletID := uuid.NewV4(), uuid.NewV4()
+
+	expectClaim := func(trials int) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs(walletID.String(), "brave.com").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT wallet_id FROM order_trials`).WithArgs(orderID).
+			WillReturnRows(sqlmock.NewRows([]string{"wallet_id"}))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM order_trials`).WithArgs(walletID, "brave.com").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(trials))
+	}
+
+	expectClaim(0)
+	mock.ExpectExec(`INSERT INTO order_trials`).WithArgs(orderID, walletID, "brave.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, pg.ClaimOrderTrial(context.Background(), orderID, walletID, "brave.com", 1))
+
+	expectClaim(1)
+	mock.ExpectRollback()
+	err = pg.ClaimOrderTrial(context.Background(), orderID, walletID, "brave.com", 1)
+	assert.True(t, errors.Is(err, ErrTrialLimit), "wallets cannot take more trials than the limit")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT wallet_id FROM order_trials`).WithArgs(orderID).
+		WillReturnRows(sqlmock.NewRows([]string{"wallet_id"}).AddRow(uuid.NewV4().String()))
+	mock.ExpectRollback()
+	err = pg.ClaimOrderTrial(context.Background(), orderID, walletID, "brave.com", 1)
+	assert.True(t, errors.Is(err, ErrTrialClaimed), "the trial of an order is taken by a single wallet")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOrderCredsWalletSigned(t *testing.T) {
+	require.NoError(t, os.Setenv("TRIAL_MERCHANTS", "brave.com"))
+	defer func() { _ = os.Unsetenv("TRIAL_MERCHANTS") }()
+
+	publicKey, privateKey, err := httpsignature.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	walletID, otherWalletID := uuid.NewV4(), uuid.NewV4()
+	wallets := newFakeWallets()
+	wallets.wallets[walletID] = &walletutils.Info{ID: walletID.String(), PublicKey: hex.EncodeToString(publicKey)}
+
+	// the trial was taken by another wallet, so requests reaching the service fail once the signature is verified
+	ds := newFakeDatastore()
+	order := ds.addOrder(Order{MerchantID: "brave.com", TotalPrice: decimal.Zero, Status: "pending"})
+	ds.trials[order.ID] = fakeOrderTrial{walletID: otherWalletID, merchantID: "brave.com"}
+	service := &Service{Datastore: ds, wallet: &wallet.Service{Datastore: wallets}, nonces: middleware.NewMemoryNonceStore()}
+	r := chi.NewRouter()
+	r.Method("POST", "/{orderID}/credentials", walletSigned(service)(CreateOrderCreds(service)))
+
+	serve := func(requested uuid.UUID, signer *uuid.UUID) *httptest.ResponseRecorder {
+		body, err := json.Marshal(CreateOrderCredsRequest{ItemID: uuid.NewV4(), BlindedCreds: []string{encodedBytes(32, 1)}, WalletID: &requested})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/"+order.ID.String()+"/credentials", bytes.NewReader(body))
+		if signer != nil {
+			req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			req.Header.Set(middleware.NonceHeader, uuid.NewV4().String())
+			s := httpsignature.Signature{}
+			s.Algorithm = httpsignature.ED25519
+			s.KeyID = signer.String()
+			s.Headers = []string{"digest", "(request-target)", "date", "nonce"}
+			require.NoError(t, s.Sign(privateKey, crypto.Hash(0), req))
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(walletID, nil).Code, "trial wallets must sign the request")
+	assert.Equal(t, http.StatusNotFound, serve(walletID, &otherWalletID).Code, "unknown wallets cannot sign")
+	rr := serve(walletID, &walletID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "trial_claimed", "requests signed by the wallet reach the trial")
+	assert.Equal(t, otherWalletID, ds.trials[order.ID].walletID)
+}
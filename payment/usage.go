@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultUsageMonths = 12
+	maxUsageMonths     = 36
+)
+
+// MerchantUsage is the upstream usage attributed to a merchant, by day when recorded and by month when
+// listed
+type MerchantUsage struct {
+	MerchantID string    `json:"merchantId" db:"merchant_id"`
+	Period     time.Time `json:"period" db:"period"`
+	// SigningCalls are the calls made to the challenge bypass server to sign credentials
+	SigningCalls int64 `json:"signingCalls" db:"signing_calls"`
+	// TokensIssued are the credentials signed by those calls
+	TokensIssued int64 `json:"tokensIssued" db:"tokens_issued"`
+	// Redemptions are the credentials redeemed with the challenge bypass server
+	Redemptions int64 `json:"redemptions" db:"redemptions"`
+}
+
+// issuerMerchant returns the merchant an issuer or credential belongs to, its issuer id also carries the sku
+func issuerMerchant(issuerID string) string {
+	merchantID, _, err := decodeIssuerID(issuerID)
+	if err != nil || merchantID == "" {
+		return issuerID
+	}
+	return merchantID
+}
+
+// recordRedemptions attributes redeemed credentials to their merchant, failures are logged rather than
+// failing the redemption which already happened
+func (service *Service) recordRedemptions(ctx context.Context, merchantID string, redemptions int64) {
+	err := service.Datastore.RecordMerchantUsage(ctx, nil, MerchantUsage{MerchantID: merchantID, Redemptions: redemptions})
+	if err != nil {
+		if logger, lerr := appctx.GetLogger(ctx); lerr == nil {
+			logger.Warn().Err(err).Str("merchant_id", merchantID).Msg("failed to record merchant usage")
+		}
+	}
+}
+
+// RollupMerchantUsage rolls the daily usage of the months before the current one up into monthly totals
+func (service *Service) RollupMerchantUsage(ctx context.Context) error {
+	now := time.Now().UTC()
+	_, err := service.Datastore.RollupMerchantUsage(ctx, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	return err
+}
+
+// GetMerchantUsage is the handler for the monthly upstream usage of a merchant, oldest month first
+func GetMerchantUsage(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		months := defaultUsageMonths
+		if v := r.URL.Query().Get("months"); v != "" {
+			var err error
+			if months, err = strconv.Atoi(v); err != nil || months <= 0 || months > maxUsageMonths {
+				return handlers.ValidationError("request query parameters", map[string]interface{}{
+					"months": "must be between 1 and " + strconv.Itoa(maxUsageMonths),
+				})
+			}
+		}
+
+		now := time.Now().UTC()
+		since := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+		usage, err := service.Datastore.GetMerchantUsage(r.Context(), chi.URLParam(r, "merchantID"), since)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting merchant usage", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), usage, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,63 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuerMerchant(t *testing.T) {
+	assert.Equal(t, "brave.com", issuerMerchant("brave.com?sku=anon-card-vote"))
+	assert.Equal(t, "brave.com", issuerMerchant("brave.com"))
+}
+
+func TestGetMerchantUsage(t *testing.T) {
+	ds := newFakeDatastore()
+	now := time.Now().UTC()
+	// the current month counts as one, the usage of three months is from the start of the month two months back
+	since := time.Date(now.Year(), now.Month()-2, 1, 0, 0, 0, 0, time.UTC)
+	ds.usage = []MerchantUsage{
+		{MerchantID: "brave.com", Period: since.AddDate(0, 0, -1), TokensIssued: 50},
+		{MerchantID: "brave.com", Period: since, SigningCalls: 2, TokensIssued: 100},
+		{MerchantID: "other.com", Period: since, TokensIssued: 10},
+	}
+	service := &Service{Datastore: ds}
+	r := chi.NewRouter()
+	r.Method("GET", "/{merchantID}/usage", GetMerchantUsage(service))
+
+	get := func() []MerchantUsage {
+		req := httptest.NewRequest("GET", "/brave.com/usage?months=3", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var usage []MerchantUsage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &usage))
+		return usage
+	}
+
+	usage := get()
+	require.Len(t, usage, 1)
+	assert.Equal(t, "brave.com", usage[0].MerchantID)
+	assert.Equal(t, int64(100), usage[0].TokensIssued)
+	assert.True(t, since.Equal(usage[0].Period))
+
+	service.recordRedemptions(context.Background(), "brave.com", 1)
+	assert.Equal(t, int64(1), ds.redemptions("brave.com"))
+	usage = get()
+	require.Len(t, usage, 2)
+	assert.Equal(t, int64(1), usage[1].Redemptions, "redemptions count towards the current month")
+
+	for _, months := range []string{"0", "37", "a"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/brave.com/usage?months="+months, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, months)
+	}
+}
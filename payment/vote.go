@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -15,6 +15,9 @@ import (
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
 	"github.com/brave-intl/bat-go/utils/inputs"
 	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/brave-intl/bat-go/utils/workers"
 	"github.com/jmoiron/sqlx"
 	"github.com/linkedin/goavro"
 	uuid "github.com/satori/go.uuid"
@@ -31,7 +34,7 @@ const (
 
 var (
 	// ErrInvalidSKUToken - the sku was invalid
-	ErrInvalidSKUToken = errors.New("failed to validate sku token")
+	ErrInvalidSKUToken = errorutils.NewApplicationError("invalid_sku_token", http.StatusBadRequest, "failed to validate sku token", false)
 	// ErrInvalidSKUTokenSKU - the sku was invalid
 	ErrInvalidSKUTokenSKU = fmt.Errorf("invalid sku in sku token: %w", ErrInvalidSKUToken)
 	// ErrInvalidSKUTokenBadMerchant - the merchant in the sku is invalid
@@ -162,11 +165,14 @@ func rollbackTx(ds Datastore, tx *sqlx.Tx, wrap string, err error) error {
 }
 
 // RunNextVoteDrainJob - Attempt to drain the vote queue
-func (service *Service) RunNextVoteDrainJob(ctx context.Context) (bool, error) {
+func (service *Service) RunNextVoteDrainJob(ctx context.Context) (attempted bool, err error) {
 	logger, err := appctx.GetLogger(ctx)
 	if err != nil {
 		ctx, logger = logging.SetupLogger(ctx)
 	}
+	// the batch is traced as a whole, its redemptions and kafka writes are traced within it
+	ctx, span := tracing.StartSpan(ctx, "payment.RunNextVoteDrainJob")
+	defer func() { span.End(err) }()
 
 	select {
 	case <-ctx.Done():
@@ -186,10 +192,20 @@ func (service *Service) RunNextVoteDrainJob(ctx context.Context) (bool, error) {
 			logger.Error().Err(err).Msg("failed to get uncommitted votes from drain queue")
 			return true, rollbackTx(service.Datastore, tx, "failed to get uncommitted votes from drain queue", err)
 		}
+		span.SetAttribute("votes", len(records))
+		if err := workers.Claim(ctx, tx, "vote drain batch"); err != nil {
+			return true, rollbackTx(service.Datastore, tx, "failed to claim votes from drain queue", err)
+		}
+		// redemptions by merchant, recorded with the batch
+		redemptions := map[string]int64{}
 		for _, record := range records {
 			if record == nil {
 				continue
 			}
+			// carry the id of the request which queued the vote to cbr and kafka
+			ctx := requestutils.WithRequestID(ctx, record.RequestID)
+			logger := logger.With().Str("req_id", record.RequestID).Logger()
+
 			var requestCredentials = []cbr.CredentialRedemption{}
 			err := json.Unmarshal([]byte(record.RequestCredentials), &requestCredentials)
 			if err != nil {
@@ -200,8 +216,8 @@ func (service *Service) RunNextVoteDrainJob(ctx context.Context) (bool, error) {
 				}
 				// okay if it is errored, we will update the errored column
 			}
-			// redeem the credentials
-			err = service.cbClient.RedeemCredentials(ctx, requestCredentials, record.VoteText)
+			// redeem the credentials, in batches
+			redeemed, err := service.redeemCredentials(ctx, requestCredentials, record.VoteText)
 			if err != nil {
 				logger.Error().Err(err).Msg("failed to redeem credentials")
 				if err := service.Datastore.MarkVoteErrored(ctx, *record, tx); err != nil {
@@ -209,8 +225,16 @@ func (service *Service) RunNextVoteDrainJob(ctx context.Context) (bool, error) {
 				}
 				// okay if errored, update errored column
 			}
+			// credentials of batches which were redeemed are spent even when others failed
+			for _, credential := range redeemed {
+				redemptions[issuerMerchant(credential.Issuer)]++
+			}
+			err = service.Datastore.InsertCredentialRedemptions(ctx, tx, newRedeemedCredentials(redeemed, record.VoteText))
+			if err != nil {
+				return true, rollbackTx(service.Datastore, tx, "failed to record credential redemptions", err)
+			}
 			// write the message to kafka if successful
-			if err = service.kafkaWriter.WriteMessages(ctx,
+			if err = service.producer.WriteMessages(ctx,
 				kafka.Message{
 					Value: record.VoteEventBinary,
 				},
@@ -228,6 +252,12 @@ func (service *Service) RunNextVoteDrainJob(ctx context.Context) (bool, error) {
 				return true, rollbackTx(service.Datastore, tx, "failed to commit vote to drain vote queue", err)
 			}
 		}
+		for merchantID, redeemed := range redemptions {
+			err := service.Datastore.RecordMerchantUsage(ctx, tx, MerchantUsage{MerchantID: merchantID, Redemptions: redeemed})
+			if err != nil {
+				return true, rollbackTx(service.Datastore, tx, "failed to record merchant usage", err)
+			}
+		}
 		// finalize the record
 		if err := tx.Commit(); err != nil {
 			logger.Error().Err(err).Msg("failed to commit the transaction")
@@ -254,7 +284,7 @@ func (service *Service) Vote(
 
 	// generate all the cb credential redemptions
 	requestCredentials, err := generateCredentialRedemptions(
-		context.WithValue(ctx, appctx.DatastoreCTXKey, service.Datastore), credentials)
+		appctx.WithDatastore(ctx, service.ReadableDatastore()), credentials)
 	if err != nil {
 		return fmt.Errorf("error generating credential redemptions: %w", err)
 	}
@@ -330,6 +360,7 @@ func (service *Service) Vote(
 				RequestCredentials: string(rcSerial),
 				VoteText:           voteText,
 				VoteEventBinary:    voteEventBinary,
+				RequestID:          requestutils.GetRequestID(ctx),
 			}); err != nil {
 			return fmt.Errorf("datastore failure vote_drain: %w", err)
 		}
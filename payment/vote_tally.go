@@ -0,0 +1,196 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// defaultVoteTallySchedule is when processed votes are rolled up unless VOTE_TALLY_SCHEDULE is set
+	defaultVoteTallySchedule = "*/15 * * * *"
+	// defaultVoteTallyBatchSize is how many votes are tallied at a time unless VOTE_TALLY_BATCH_SIZE is set
+	defaultVoteTallyBatchSize = 10000
+	// maxVoteTallyDays is the longest range of days vote tallies are returned for at once
+	maxVoteTallyDays = 92
+)
+
+// VoteTally is the processed votes of a channel on a day, by vote type and funding source
+type VoteTally struct {
+	Channel       string    `json:"channel" db:"channel"`
+	Day           time.Time `json:"day" db:"day"`
+	Type          string    `json:"type" db:"type"`
+	FundingSource string    `json:"fundingSource" db:"funding_source"`
+	// Votes is how many votes were made, Tally how many credentials they were made with
+	Votes     int64     `json:"votes" db:"votes"`
+	Tally     int64     `json:"tally" db:"tally"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// VoteTallyRollup is a run rolling processed votes up into the vote tallies
+type VoteTallyRollup struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	// Trigger is scheduled for runs of the scheduled job and manual for those started by an admin
+	Trigger    string    `json:"trigger" db:"trigger"`
+	Actor      string    `json:"actor" db:"actor"`
+	Votes      int64     `json:"votes" db:"votes"`
+	Tallies    int64     `json:"tallies" db:"tallies"`
+	Batches    int       `json:"batches" db:"batches"`
+	Error      *string   `json:"error,omitempty" db:"error"`
+	StartedAt  time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt time.Time `json:"finishedAt" db:"finished_at"`
+}
+
+// voteTallySchedule is the cron schedule of the vote tally rollup
+func voteTallySchedule() string {
+	if schedule := os.Getenv("VOTE_TALLY_SCHEDULE"); schedule != "" {
+		return schedule
+	}
+	return defaultVoteTallySchedule
+}
+
+// voteTallyBatchSize is how many votes are tallied in each statement of a rollup
+func voteTallyBatchSize() int {
+	if size, err := strconv.Atoi(os.Getenv("VOTE_TALLY_BATCH_SIZE")); err == nil && size > 0 {
+		return size
+	}
+	return defaultVoteTallyBatchSize
+}
+
+// RollupVoteTallies is the scheduled job rolling processed votes up into the vote tallies
+func (s *Service) RollupVoteTallies(ctx context.Context) error {
+	_, err := s.rollupVoteTallies(ctx, "scheduled")
+	return err
+}
+
+// rollupVoteTallies tallies processed votes in batches until every processed vote is tallied, recording
+// the run along with who started it
+func (s *Service) rollupVoteTallies(ctx context.Context, trigger string) (*VoteTallyRollup, error) {
+	rollup := &VoteTallyRollup{Trigger: trigger, Actor: "system", StartedAt: time.Now()}
+	if actor := middleware.AuditActor(ctx); actor != "" {
+		rollup.Actor = actor
+	}
+
+	batchSize := voteTallyBatchSize()
+	var rollupErr error
+	for {
+		votes, tallies, err := s.Datastore.RollupVoteTallies(ctx, batchSize)
+		if err != nil {
+			rollupErr = err
+			break
+		}
+		rollup.Batches++
+		rollup.Votes += votes
+		rollup.Tallies += tallies
+		if votes < int64(batchSize) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			rollupErr = err
+			break
+		}
+	}
+	if rollupErr != nil {
+		msg := rollupErr.Error()
+		rollup.Error = &msg
+	}
+	rollup.FinishedAt = time.Now()
+
+	if err := s.Datastore.InsertVoteTallyRollup(ctx, rollup); err != nil && rollupErr == nil {
+		return rollup, err
+	}
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		logger.Info().
+			Str("trigger", trigger).
+			Int64("votes", rollup.Votes).
+			Int64("tallies", rollup.Tallies).
+			Int("batches", rollup.Batches).
+			Msg("vote tally rollup finished")
+	}
+	return rollup, rollupErr
+}
+
+// VoteTallyRouter lets admins read the vote tallies, check the latest rollup and start one
+func VoteTallyRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.AuditLog(service.Datastore))
+	r.Method("GET", "/", adminAuthorized(service, middleware.InstrumentHandler("GetVoteTallies", GetVoteTallies(service))))
+	r.Method("GET", "/rollups/latest", adminAuthorized(service, middleware.InstrumentHandler("GetLatestVoteTallyRollup", GetLatestVoteTallyRollup(service))))
+	r.Method("POST", "/rollups", adminAuthorized(service, middleware.InstrumentHandler("RollupVoteTallies", RollupVoteTallies(service))))
+	return r
+}
+
+// GetVoteTallies is the handler for the tallies of the days within the from and to query parameters,
+// the last week when unset, filtered by the channel query parameter
+func GetVoteTallies(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		from := to.AddDate(0, 0, -7)
+		invalid := map[string]interface{}{}
+		if v := r.URL.Query().Get("from"); v != "" {
+			day, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				invalid["from"] = "must be a date"
+			}
+			from = day
+		}
+		if v := r.URL.Query().Get("to"); v != "" {
+			day, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				invalid["to"] = "must be a date"
+			}
+			to = day
+		}
+		if len(invalid) == 0 && (!from.Before(to) || to.Sub(from) > maxVoteTallyDays*24*time.Hour) {
+			invalid["to"] = "must be after from, by at most " + strconv.Itoa(maxVoteTallyDays) + " days"
+		}
+		if len(invalid) > 0 {
+			return handlers.ValidationError("request query parameters", invalid)
+		}
+
+		tallies, err := service.Datastore.GetVoteTallies(r.Context(), r.URL.Query().Get("channel"), from, to)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting vote tallies", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), tallies, w, http.StatusOK)
+	})
+}
+
+// GetLatestVoteTallyRollup is the handler for the rollup which started last
+func GetLatestVoteTallyRollup(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		rollup, err := service.Datastore.GetLatestVoteTallyRollup(r.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting vote tally rollup", http.StatusInternalServerError)
+		}
+		if rollup == nil {
+			return &handlers.AppError{
+				Message: "No vote tally rollup has run",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), rollup, w, http.StatusOK)
+	})
+}
+
+// RollupVoteTallies is the handler for rolling up processed votes now, the rollup is returned once it finishes
+func RollupVoteTallies(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		rollup, err := service.rollupVoteTallies(r.Context(), "manual")
+		if rollup != nil && rollup.ID != uuid.Nil {
+			middleware.AuditEntity(r.Context(), "vote_tally_rollup", rollup.ID.String())
+		}
+		if err != nil {
+			return handlers.WrapError(err, "Error rolling up vote tallies", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), rollup, w, http.StatusCreated)
+	})
+}
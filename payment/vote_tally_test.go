@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupVoteTallies(t *testing.T) {
+	require.NoError(t, os.Setenv("VOTE_TALLY_BATCH_SIZE", "10"))
+	defer func() { _ = os.Unsetenv("VOTE_TALLY_BATCH_SIZE") }()
+
+	ds := newFakeDatastore()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, ds.InsertVote(context.Background(), VoteRecord{VoteText: "vote"}))
+	}
+	service := &Service{Datastore: ds}
+	require.NoError(t, service.RollupVoteTallies(context.Background()))
+	assert.Equal(t, 25, ds.tallied, "votes are tallied until none are left")
+
+	require.Len(t, ds.rollups, 1)
+	rollup := ds.rollups[0]
+	assert.Equal(t, "scheduled", rollup.Trigger)
+	assert.Equal(t, "system", rollup.Actor)
+	assert.Equal(t, int64(25), rollup.Votes)
+	assert.Equal(t, 3, rollup.Batches)
+	assert.Nil(t, rollup.Error)
+
+	ds.errs["RollupVoteTallies"] = errors.New("deadlock")
+	rollup2, err := service.rollupVoteTallies(context.Background(), "manual")
+	assert.Error(t, err)
+	require.Len(t, ds.rollups, 2, "failed rollups are recorded")
+	assert.Equal(t, rollup2.Error, ds.rollups[1].Error)
+	assert.Contains(t, *ds.rollups[1].Error, "deadlock")
+}
+
+func TestGetVoteTalliesValidation(t *testing.T) {
+	ds := newFakeDatastore()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	january := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ds.tallies = []VoteTally{
+		{Channel: "brave.com", Day: today, Votes: 1},
+		{Channel: "brave.com", Day: today.AddDate(0, 0, -7), Votes: 2},
+		{Channel: "brave.com", Day: january, Votes: 3},
+		{Channel: "other.com", Day: january, Votes: 4},
+		{Channel: "brave.com", Day: january.AddDate(0, 1, 0), Votes: 5},
+	}
+	handler := GetVoteTallies(&Service{Datastore: ds})
+	serve := func(query string) (int, []VoteTally) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/"+query, nil))
+		var tallies []VoteTally
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tallies))
+		}
+		return rr.Code, tallies
+	}
+	votes := func(tallies []VoteTally) []int64 {
+		result := make([]int64, len(tallies))
+		for i, tally := range tallies {
+			result[i] = tally.Votes
+		}
+		return result
+	}
+
+	code, tallies := serve("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int64{1}, votes(tallies), "the last week is returned by default")
+	code, tallies = serve("?from=2021-01-01&to=2021-02-01&channel=brave.com")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int64{3}, votes(tallies))
+	code, _ = serve("?from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve("?from=2021-02-01&to=2021-01-01")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve("?from=2020-01-01&to=2021-01-01")
+	assert.Equal(t, http.StatusBadRequest, code, "ranges are limited")
+}
+
+func TestRollupVoteTalliesStatement(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDB.Close() }()
+	pg := &Postgres{Postgres: grantserver.Postgres{DB: sqlx.NewDb(mockDB, "sqlmock")}}
+
+	mock.ExpectQuery(`UPDATE vote_drain SET tallied = true(.+)FOR UPDATE SKIP LOCKED(.+)INSERT INTO vote_tallies(.+)anon-card-vote`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"votes", "tallies"}).AddRow(42, 3))
+	votes, tallies, err := pg.RollupVoteTallies(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), votes)
+	assert.Equal(t, int64(3), tallies)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
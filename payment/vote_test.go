@@ -74,7 +74,7 @@ func TestVoteAnonCard(t *testing.T) {
 
 	// make sure vote_drain was updated
 	mock.ExpectExec("insert into vote_drain").
-		WithArgs(StringContains(`issuer":"`+issuerName), voteText, BytesContains(`anonymous-card`)).
+		WithArgs(StringContains(`issuer":"`+issuerName), voteText, BytesContains(`anonymous-card`), "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	generateCredentialRedemptions = fakeGenerateCredentialRedemptions
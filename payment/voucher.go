@@ -0,0 +1,219 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// VoucherPercent vouchers take a percentage off the total of an order
+	VoucherPercent = "percent"
+	// VoucherFixed vouchers take a fixed amount off the total of an order in their currency
+	VoucherFixed = "fixed"
+)
+
+var (
+	// ErrVoucherExists is returned when creating a voucher with a code which is taken
+	ErrVoucherExists = errorutils.NewApplicationError("voucher_exists", http.StatusConflict, "a voucher with the code already exists", false)
+	// ErrVoucherInvalid is returned when ordering with a voucher which does not exist, has expired or is used up
+	ErrVoucherInvalid = errorutils.NewApplicationError("voucher_invalid", http.StatusBadRequest, "the voucher is unknown, expired or used up", false)
+	// ErrVoucherNotApplicable is returned when ordering with a voucher of another merchant or currency, or
+	// one which would discount the whole order
+	ErrVoucherNotApplicable = errorutils.NewApplicationError("voucher_not_applicable", http.StatusBadRequest, "the voucher does not apply to the order", false)
+
+	voucherCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{4,32}$`)
+)
+
+// Voucher is a promo code discounting the orders of a merchant, until it is used up or expires
+type Voucher struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Code       string    `json:"code" db:"code"`
+	MerchantID string    `json:"merchantId" db:"merchant_id"`
+	Kind       string    `json:"kind" db:"kind"`
+	// Amount is the percentage taken off percent vouchers, or the amount in Currency taken off fixed ones
+	Amount   decimal.Decimal `json:"amount" db:"amount"`
+	Currency *string         `json:"currency,omitempty" db:"currency"`
+	// MaxUses is how many orders the voucher discounts, nil when it is not limited
+	MaxUses   *int       `json:"maxUses,omitempty" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// VoucherRedemption is the discount a voucher took off an order
+type VoucherRedemption struct {
+	VoucherID uuid.UUID       `json:"-"`
+	Code      string          `json:"code"`
+	Discount  decimal.Decimal `json:"discount"`
+}
+
+// normalizeVoucherCode makes codes case insensitive
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks the voucher can be created, returning the errors by field
+func (voucher *Voucher) Validate() map[string]interface{} {
+	errs := map[string]interface{}{}
+	if !voucherCodePattern.MatchString(voucher.Code) {
+		errs["code"] = "must be 4 to 32 letters, digits, dashes or underscores"
+	}
+	if voucher.MerchantID == "" {
+		errs["merchantId"] = "is required"
+	}
+	switch voucher.Kind {
+	case VoucherPercent:
+		if !voucher.Amount.IsPositive() || voucher.Amount.GreaterThanOrEqual(decimal.New(100, 0)) {
+			errs["amount"] = "must be a percentage between 0 and 100"
+		}
+	case VoucherFixed:
+		if !voucher.Amount.IsPositive() {
+			errs["amount"] = "must be positive"
+		}
+		if voucher.Currency == nil || *voucher.Currency == "" {
+			errs["currency"] = "is required for fixed vouchers"
+		}
+	default:
+		errs["kind"] = "must be percent or fixed"
+	}
+	if voucher.MaxUses != nil && *voucher.MaxUses < 1 {
+		errs["maxUses"] = "must be positive"
+	}
+	if voucher.ExpiresAt != nil && !voucher.ExpiresAt.After(time.Now()) {
+		errs["expiresAt"] = "must be in the future"
+	}
+	return errs
+}
+
+// usable tells whether the voucher has uses left and has not expired
+func (voucher *Voucher) usable(now time.Time) bool {
+	if voucher.MaxUses != nil && voucher.Uses >= *voucher.MaxUses {
+		return false
+	}
+	return voucher.ExpiresAt == nil || now.Before(*voucher.ExpiresAt)
+}
+
+// discount is what the voucher takes off an order of the total in the currency, percentages of fiat totals
+// are rounded down to the cent
+func (voucher *Voucher) discount(currency string, total decimal.Decimal) (decimal.Decimal, error) {
+	var discount decimal.Decimal
+	switch voucher.Kind {
+	case VoucherPercent:
+		discount = total.Mul(voucher.Amount).Div(decimal.New(100, 0))
+		if currency != "BAT" {
+			discount = discount.Truncate(2)
+		}
+	case VoucherFixed:
+		if voucher.Currency == nil || *voucher.Currency != currency {
+			return decimal.Zero, fmt.Errorf("%w: the voucher is in another currency", ErrVoucherNotApplicable)
+		}
+		discount = voucher.Amount
+	}
+	// discounted orders are paid for, free orders being trials
+	if discount.GreaterThanOrEqual(total) {
+		return decimal.Zero, fmt.Errorf("%w: the voucher would discount the whole order", ErrVoucherNotApplicable)
+	}
+	return discount, nil
+}
+
+// applyVoucher discounts the total of an order of the merchant with the voucher code. The voucher is
+// only used once the order is created, which fails if it was used up in the meantime
+func (s *Service) applyVoucher(ctx context.Context, code string, merchantID string, currency string, total decimal.Decimal) (*VoucherRedemption, error) {
+	voucher, err := s.Datastore.GetVoucher(ctx, normalizeVoucherCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if voucher == nil || !voucher.usable(time.Now()) {
+		return nil, ErrVoucherInvalid
+	}
+	if voucher.MerchantID != merchantID {
+		return nil, fmt.Errorf("%w: the voucher is for another merchant", ErrVoucherNotApplicable)
+	}
+	discount, err := voucher.discount(currency, total)
+	if err != nil {
+		return nil, err
+	}
+	return &VoucherRedemption{VoucherID: voucher.ID, Code: voucher.Code, Discount: discount}, nil
+}
+
+// VoucherRouter lets admins create vouchers and follow their use
+func VoucherRouter(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.AuditLog(service.Datastore))
+	r.Method("GET", "/", adminAuthorized(service, middleware.InstrumentHandler("ListVouchers", ListVouchers(service))))
+	r.Method("POST", "/", adminAuthorized(service, middleware.InstrumentHandler("CreateVoucher", CreateVoucher(service))))
+	r.Method("GET", "/{code}", adminAuthorized(service, middleware.InstrumentHandler("GetVoucher", GetVoucher(service))))
+	return r
+}
+
+// CreateVoucher is the handler for creating a voucher
+func CreateVoucher(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var voucher Voucher
+		if err := requestutils.ReadJSON(r.Body, &voucher); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		voucher.Code = normalizeVoucherCode(voucher.Code)
+		if errs := voucher.Validate(); len(errs) > 0 {
+			return handlers.ValidationError("request body", errs)
+		}
+		middleware.AuditEntity(r.Context(), "voucher", voucher.Code)
+
+		created, err := service.Datastore.CreateVoucher(r.Context(), &voucher)
+		if err != nil {
+			return handlers.WrapError(err, "Error creating voucher", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), created, w, http.StatusCreated)
+	})
+}
+
+// ListVouchers is the handler for listing vouchers, filtered by the merchant and limit query parameters
+func ListVouchers(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > 1000 {
+				return handlers.ValidationError("request query parameters", map[string]interface{}{
+					"limit": "must be between 1 and 1000",
+				})
+			}
+		}
+
+		vouchers, err := service.Datastore.ListVouchers(r.Context(), r.URL.Query().Get("merchant"), limit)
+		if err != nil {
+			return handlers.WrapError(err, "Error listing vouchers", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), vouchers, w, http.StatusOK)
+	})
+}
+
+// GetVoucher is the handler for a voucher and how many times it was used
+func GetVoucher(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		voucher, err := service.Datastore.GetVoucher(r.Context(), normalizeVoucherCode(chi.URLParam(r, "code")))
+		if err != nil {
+			return handlers.WrapError(err, "Error getting voucher", http.StatusInternalServerError)
+		}
+		if voucher == nil {
+			return &handlers.AppError{
+				Message: "Voucher not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), voucher, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,72 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoucherValidate(t *testing.T) {
+	usd, zero, future := "USD", 0, time.Now().Add(time.Hour)
+	assert.Empty(t, (&Voucher{Code: "LAUNCH-25", MerchantID: "brave.com", Kind: VoucherPercent, Amount: decimal.New(25, 0)}).Validate())
+	assert.Empty(t, (&Voucher{Code: "FIVEOFF", MerchantID: "brave.com", Kind: VoucherFixed, Amount: decimal.New(5, 0), Currency: &usd, ExpiresAt: &future}).Validate())
+
+	errs := (&Voucher{Code: "no", Kind: VoucherPercent, Amount: decimal.New(100, 0), MaxUses: &zero}).Validate()
+	assert.Contains(t, errs, "code")
+	assert.Contains(t, errs, "merchantId")
+	assert.Contains(t, errs, "amount", "percent vouchers take less than the whole order")
+	assert.Contains(t, errs, "maxUses")
+	assert.Contains(t, (&Voucher{Code: "FIVEOFF", MerchantID: "brave.com", Kind: VoucherFixed, Amount: decimal.New(5, 0)}).Validate(), "currency")
+	assert.Contains(t, (&Voucher{Code: "FIVEOFF", MerchantID: "brave.com", Kind: "free"}).Validate(), "kind")
+}
+
+func TestApplyVoucher(t *testing.T) {
+	usd, once, past := "USD", 1, time.Now().Add(-time.Hour)
+	ds := newFakeDatastore()
+	ds.vouchers = map[string]Voucher{
+		"THIRD":   {ID: uuid.NewV4(), Code: "THIRD", MerchantID: "brave.com", Kind: VoucherPercent, Amount: decimal.New(33, 0)},
+		"FIVEOFF": {ID: uuid.NewV4(), Code: "FIVEOFF", MerchantID: "brave.com", Kind: VoucherFixed, Amount: decimal.New(5, 0), Currency: &usd},
+		"USEDUP":  {ID: uuid.NewV4(), Code: "USEDUP", MerchantID: "brave.com", Kind: VoucherPercent, Amount: decimal.New(10, 0), MaxUses: &once, Uses: 1},
+		"EXPIRED": {ID: uuid.NewV4(), Code: "EXPIRED", MerchantID: "brave.com", Kind: VoucherPercent, Amount: decimal.New(10, 0), ExpiresAt: &past},
+		"OTHER":   {ID: uuid.NewV4(), Code: "OTHER", MerchantID: "brave.software", Kind: VoucherPercent, Amount: decimal.New(10, 0)},
+	}
+	service := &Service{Datastore: ds}
+	ctx := context.Background()
+	total := decimal.RequireFromString("9.99")
+
+	redemption, err := service.applyVoucher(ctx, " third ", "brave.com", "USD", total)
+	require.NoError(t, err)
+	assert.Equal(t, "THIRD", redemption.Code, "codes are case insensitive")
+	assert.Equal(t, "3.29", redemption.Discount.String(), "fiat discounts are rounded down to the cent")
+	assert.Equal(t, ds.vouchers["THIRD"].ID, redemption.VoucherID)
+
+	redemption, err = service.applyVoucher(ctx, "FIVEOFF", "brave.com", "USD", total)
+	require.NoError(t, err)
+	assert.Equal(t, "5", redemption.Discount.String())
+
+	_, err = service.applyVoucher(ctx, "FIVEOFF", "brave.com", "BAT", total)
+	assert.True(t, errors.Is(err, ErrVoucherNotApplicable), "fixed vouchers only apply in their currency")
+	_, err = service.applyVoucher(ctx, "FIVEOFF", "brave.com", "USD", decimal.New(5, 0))
+	assert.True(t, errors.Is(err, ErrVoucherNotApplicable), "vouchers do not make orders free")
+	_, err = service.applyVoucher(ctx, "OTHER", "brave.com", "USD", total)
+	assert.True(t, errors.Is(err, ErrVoucherNotApplicable))
+
+	for _, code := range []string{"USEDUP", "EXPIRED", "UNKNOWN"} {
+		_, err = service.applyVoucher(ctx, code, "brave.com", "USD", total)
+		assert.True(t, errors.Is(err, ErrVoucherInvalid), code)
+	}
+}
+
+func TestDiscountedOrderIsPaid(t *testing.T) {
+	// a discounted order is placed with its total less the discount, which its payments settle
+	order := &Order{Status: "pending", Currency: "USD", TotalPrice: decimal.RequireFromString("6.70")}
+	payments := []OrderPayment{{Kind: PaymentMethodStripe, Currency: "USD", Amount: decimal.RequireFromString("6.70")}}
+	order.Balance = newOrderBalance(order, payments)
+	assert.True(t, order.IsPaid())
+}
@@ -0,0 +1,400 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/notification"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/cryptography"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// WebhookSignatureHeader carries the timestamp and signature of a webhook delivery
+	WebhookSignatureHeader = "Webhook-Signature"
+	// WebhookSecretVersionHeader carries the version of the secret a webhook delivery was signed with
+	WebhookSecretVersionHeader = "Webhook-Secret-Version"
+	// WebhookDeliveryIDHeader carries the id of a queued delivery, which is the same for each of its retries
+	WebhookDeliveryIDHeader = "Webhook-Delivery-Id"
+	// defaultWebhookSecretOverlap is how long rotated secrets remain active unless the merchant asks otherwise
+	defaultWebhookSecretOverlap = 24 * time.Hour
+	// webhookDeliveriesLimit is the number of deliveries listed for a merchant
+	webhookDeliveriesLimit = 100
+	// webhookDeliveryRetention is how long the delivery log is kept
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+	// webhookTimeout is how long a merchant has to respond to a webhook
+	webhookTimeout = 10 * time.Second
+)
+
+// webhookRetryPolicy retries webhooks for about a day, long enough to ride out a merchant's outage
+var webhookRetryPolicy = notification.RetryPolicy{
+	MaxAttempts:    12,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     4 * time.Hour,
+}
+
+// ErrNoWebhookSecret is returned when signing a webhook for a merchant without an active secret
+var ErrNoWebhookSecret = errors.New("merchant has no active webhook secret")
+
+// WebhookSecret is a versioned secret webhook deliveries to a merchant are signed with. The secret itself
+// is only returned when it is created
+type WebhookSecret struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	MerchantID      string     `json:"merchantId" db:"merchant_id"`
+	Version         int        `json:"version" db:"version"`
+	Secret          string     `json:"secret,omitempty"`
+	EncryptedSecret string     `json:"-" db:"encrypted_secret"`
+	Nonce           string     `json:"-" db:"nonce"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt       *time.Time `json:"expiresAt" db:"expires_at"`
+}
+
+// SetSecret decrypts the secret from the database
+func (secret *WebhookSecret) SetSecret() error {
+	encrypted, err := hex.DecodeString(secret.EncryptedSecret)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := hex.DecodeString(secret.Nonce)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := cryptography.DecryptMessage(byteEncryptionKey, encrypted, nonce)
+	if err != nil {
+		return err
+	}
+
+	secret.Secret = decrypted
+	return nil
+}
+
+// WebhookDelivery records a webhook sent to a merchant and how it was signed, so failed verifications
+// can be traced to the secret version in use
+type WebhookDelivery struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	MerchantID    string    `json:"merchantId" db:"merchant_id"`
+	URL           string    `json:"url" db:"url"`
+	Event         string    `json:"event" db:"event"`
+	SecretVersion int       `json:"secretVersion" db:"secret_version"`
+	Signature     string    `json:"signature" db:"signature"`
+	Status        *int      `json:"status" db:"status"`
+	Error         *string   `json:"error" db:"error"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+}
+
+// SignWebhookPayload computes the signature of a webhook body sent at the timestamp
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signature header against the secrets a merchant holds, any of which may have
+// signed the delivery while a rotation is in progress
+func VerifyWebhookSignature(header string, body []byte, secrets ...string) bool {
+	var (
+		timestamp time.Time
+		signature string
+	)
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			unix, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return false
+			}
+			timestamp = time.Unix(unix, 0)
+		case "v1":
+			signature = kv[1]
+		}
+	}
+	if signature == "" || timestamp.IsZero() {
+		return false
+	}
+
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, body)), []byte(signature)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RotateWebhookSecret creates a new webhook secret for the merchant, leaving existing secrets active for the overlap
+func (s *Service) RotateWebhookSecret(ctx context.Context, merchantID string, overlap time.Duration) (*WebhookSecret, error) {
+	encrypted, nonce, err := GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	secret, err := s.Datastore.CreateWebhookSecret(ctx, merchantID, encrypted, nonce, overlap)
+	if err != nil {
+		return nil, err
+	}
+	if err := secret.SetSecret(); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// SignWebhook signs a webhook body for the merchant with its newest active secret, returning the headers to send
+// along with the delivery record describing how it was signed
+func (s *Service) SignWebhook(ctx context.Context, merchantID string, url string, event string, body []byte) (http.Header, *WebhookDelivery, error) {
+	secrets, err := s.Datastore.GetWebhookSecrets(ctx, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(secrets) == 0 {
+		return nil, nil, ErrNoWebhookSecret
+	}
+
+	// secrets are returned newest first
+	secret := secrets[0]
+	if err := secret.SetSecret(); err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	now := time.Now()
+	signature := "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + SignWebhookPayload(secret.Secret, now, body)
+
+	header := http.Header{}
+	header.Set(WebhookSignatureHeader, signature)
+	header.Set(WebhookSecretVersionHeader, strconv.Itoa(secret.Version))
+
+	return header, &WebhookDelivery{
+		MerchantID:    merchantID,
+		URL:           url,
+		Event:         event,
+		SecretVersion: secret.Version,
+		Signature:     signature,
+	}, nil
+}
+
+// webhookPayload is a webhook queued for delivery, signed afresh on each attempt
+type webhookPayload struct {
+	MerchantID string          `json:"merchantId"`
+	URL        string          `json:"url"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// UseDeliveryQueue queues webhooks for delivery, retrying them until the merchant accepts them
+func (s *Service) UseDeliveryQueue(queue *notification.Queue) {
+	s.deliveries = queue
+	queue.Register(notification.ChannelWebhook, &webhookDeliverer{
+		service: s,
+		client:  &http.Client{Timeout: webhookTimeout},
+	}, webhookRetryPolicy)
+}
+
+// QueueWebhook queues the event for delivery to each of the merchant's webhook urls. Nothing is queued
+// without a delivery queue
+func (s *Service) QueueWebhook(ctx context.Context, merchantID string, event string, body interface{}) ([]*notification.Delivery, error) {
+	if s.deliveries == nil {
+		return nil, nil
+	}
+	merchant, err := s.Datastore.GetMerchant(ctx, merchantID)
+	if err != nil || merchant == nil {
+		return nil, err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook body: %w", err)
+	}
+
+	var deliveries []*notification.Delivery
+	for _, url := range merchant.WebhookURLs {
+		delivery, err := notification.NewDelivery(notification.ChannelWebhook, url, webhookPayload{
+			MerchantID: merchantID,
+			URL:        url,
+			Event:      event,
+			Body:       data,
+		})
+		if err != nil {
+			return deliveries, err
+		}
+		owner := merchantID
+		delivery.Owner = &owner
+		if err := s.deliveries.Enqueue(ctx, delivery); err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// webhookDeliverer posts queued webhooks to merchants, logging each attempt in the webhook delivery log
+type webhookDeliverer struct {
+	service *Service
+	client  *http.Client
+}
+
+// Deliver signs and posts a webhook, failing unless the merchant responds with a 2xx status
+func (d *webhookDeliverer) Deliver(ctx context.Context, delivery *notification.Delivery) (*int, error) {
+	var payload webhookPayload
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+		return nil, &notification.PermanentError{Err: fmt.Errorf("failed to decode webhook: %w", err)}
+	}
+
+	header, record, err := d.service.SignWebhook(ctx, payload.MerchantID, payload.URL, payload.Event, payload.Body)
+	if err != nil {
+		if errors.Is(err, ErrNoWebhookSecret) {
+			return nil, &notification.PermanentError{Err: err}
+		}
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, payload.URL, bytes.NewReader(payload.Body))
+	if err != nil {
+		return nil, &notification.PermanentError{Err: err}
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookDeliveryIDHeader, delivery.ID.String())
+
+	var status *int
+	resp, err := d.client.Do(req)
+	if err == nil {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+		status = &resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		msg := err.Error()
+		record.Error = &msg
+	}
+	record.Status = status
+	if ierr := d.service.Datastore.InsertWebhookDelivery(ctx, record); ierr != nil {
+		if logger, lerr := appctx.GetLogger(ctx); lerr == nil {
+			logger.Error().Err(ierr).Str("delivery", delivery.ID.String()).Msg("failed to log webhook delivery")
+		}
+	}
+	return status, err
+}
+
+// SweepWebhookDeliveries removes deliveries older than the retention period from the delivery log
+func (s *Service) SweepWebhookDeliveries(ctx context.Context) error {
+	deleted, err := s.Datastore.DeleteWebhookDeliveries(ctx, time.Now().Add(-webhookDeliveryRetention))
+	if err != nil {
+		return err
+	}
+
+	logger, err := appctx.GetLogger(ctx)
+	if err == nil {
+		logger.Info().Int64("deleted", deleted).Msg("swept webhook deliveries")
+	}
+	return nil
+}
+
+// GetWebhookSecrets is the handler for listing the active webhook secrets of a merchant, without the secrets themselves
+func GetWebhookSecrets(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		secrets, err := service.Datastore.GetWebhookSecrets(r.Context(), chi.URLParam(r, "merchantID"))
+		if err != nil {
+			return handlers.WrapError(err, "Error getting webhook secrets", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), secrets, w, http.StatusOK)
+	})
+}
+
+// RotateWebhookSecretRequest includes information needed to rotate a webhook secret
+type RotateWebhookSecretRequest struct {
+	// OverlapSeconds is how long the previous secrets remain active
+	OverlapSeconds *int `json:"overlapSeconds" valid:"-"`
+}
+
+// RotateWebhookSecret is the handler for creating a new webhook secret for a merchant
+func RotateWebhookSecret(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		var req RotateWebhookSecretRequest
+		err := requestutils.ReadJSON(r.Body, &req)
+		if err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		overlap := defaultWebhookSecretOverlap
+		if req.OverlapSeconds != nil {
+			if *req.OverlapSeconds < 0 {
+				return handlers.ValidationError("request body", map[string]interface{}{
+					"overlapSeconds": "must not be negative",
+				})
+			}
+			overlap = time.Duration(*req.OverlapSeconds) * time.Second
+		}
+
+		secret, err := service.RotateWebhookSecret(r.Context(), chi.URLParam(r, "merchantID"), overlap)
+		if err != nil {
+			return handlers.WrapError(err, "Error rotating webhook secret", http.StatusInternalServerError)
+		}
+		middleware.AuditEntity(r.Context(), "webhook_secret", secret.ID.String())
+
+		return handlers.RenderContent(r.Context(), secret, w, http.StatusCreated)
+	})
+}
+
+// GetWebhookDeliveries is the handler for listing the recent webhook deliveries to a merchant
+func GetWebhookDeliveries(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		deliveries, err := service.Datastore.GetWebhookDeliveries(r.Context(), chi.URLParam(r, "merchantID"), webhookDeliveriesLimit)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting webhook deliveries", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), deliveries, w, http.StatusOK)
+	})
+}
+
+// GetWebhookDeliveryStatus is the handler for the status and retry history of a queued webhook to a merchant
+func GetWebhookDeliveryStatus(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"deliveryID": "must be a uuid",
+			})
+		}
+
+		var status *notification.DeliveryStatus
+		if service.deliveries != nil {
+			status, err = service.deliveries.GetStatus(r.Context(), id)
+			if err != nil {
+				return handlers.WrapError(err, "Error getting webhook delivery", http.StatusInternalServerError)
+			}
+		}
+		// merchants only see their own webhooks
+		if status == nil || status.Channel != notification.ChannelWebhook ||
+			status.Owner == nil || *status.Owner != chi.URLParam(r, "merchantID") {
+			return &handlers.AppError{
+				Message: "Webhook delivery not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), status, w, http.StatusOK)
+	})
+}
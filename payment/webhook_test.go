@@ -0,0 +1,128 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/notification"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertOnlyQueueStore records queued deliveries without running them
+type insertOnlyQueueStore struct {
+	notification.QueueStore
+	inserted []*notification.Delivery
+}
+
+func (s *insertOnlyQueueStore) InsertDelivery(ctx context.Context, delivery *notification.Delivery) error {
+	delivery.ID = uuid.NewV4()
+	s.inserted = append(s.inserted, delivery)
+	return nil
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"order.paid"}`)
+	now := time.Now()
+	header := "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + SignWebhookPayload("new", now, body)
+
+	assert.True(t, VerifyWebhookSignature(header, body, "old", "new"))
+	assert.False(t, VerifyWebhookSignature(header, body, "old"))
+	assert.False(t, VerifyWebhookSignature(header, []byte(`{"event":"order.canceled"}`), "new"))
+	assert.False(t, VerifyWebhookSignature("v1="+SignWebhookPayload("new", now, body), body, "new"), "the timestamp is required")
+}
+
+func TestRotateWebhookSecret(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Datastore: newFakeDatastore()}
+
+	_, _, err := service.SignWebhook(ctx, "brave.com", "https://brave.com/hook", "order.paid", nil)
+	assert.Equal(t, ErrNoWebhookSecret, err)
+
+	first, err := service.RotateWebhookSecret(ctx, "brave.com", time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.Secret)
+	second, err := service.RotateWebhookSecret(ctx, "brave.com", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+	assert.NotEqual(t, first.Secret, second.Secret)
+
+	// deliveries are signed with the newest secret, merchants holding either secret can verify them
+	body := []byte(`{"event":"order.paid"}`)
+	header, delivery, err := service.SignWebhook(ctx, "brave.com", "https://brave.com/hook", "order.paid", body)
+	require.NoError(t, err)
+	assert.Equal(t, "2", header.Get(WebhookSecretVersionHeader))
+	assert.Equal(t, 2, delivery.SecretVersion)
+	assert.Equal(t, header.Get(WebhookSignatureHeader), delivery.Signature)
+	assert.True(t, VerifyWebhookSignature(delivery.Signature, body, first.Secret, second.Secret))
+	assert.False(t, VerifyWebhookSignature(delivery.Signature, body, first.Secret))
+
+	// without an overlap the previous secrets stop being active immediately
+	_, err = service.RotateWebhookSecret(ctx, "brave.com", 0)
+	require.NoError(t, err)
+	secrets, err := service.Datastore.GetWebhookSecrets(ctx, "brave.com")
+	require.NoError(t, err)
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, 3, secrets[0].Version)
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	ctx := context.Background()
+	ds := newFakeDatastore()
+	service := &Service{Datastore: ds}
+	secret, err := service.RotateWebhookSecret(ctx, "brave.com", time.Hour)
+	require.NoError(t, err)
+
+	status := http.StatusOK
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.True(t, VerifyWebhookSignature(r.Header.Get(WebhookSignatureHeader), body, secret.Secret))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ds.merchants["brave.com"] = &Merchant{ID: "brave.com", WebhookURLs: []string{server.URL}}
+	store := &insertOnlyQueueStore{}
+	service.UseDeliveryQueue(notification.NewQueue(store))
+
+	deliveries, err := service.QueueWebhook(ctx, "brave.com", "order.paid", map[string]string{"event": "order.paid"})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "brave.com", *deliveries[0].Owner)
+
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	assert.Equal(t, server.URL, payload.URL)
+
+	deliverer := &webhookDeliverer{service: service, client: server.Client()}
+	code, err := deliverer.Deliver(ctx, deliveries[0])
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, *code)
+	assert.Equal(t, deliveries[0].ID.String(), received.Get(WebhookDeliveryIDHeader))
+
+	// failures are logged and returned so the delivery is retried
+	status = http.StatusInternalServerError
+	code, err = deliverer.Deliver(ctx, deliveries[0])
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, *code)
+	require.Len(t, ds.deliveries, 2)
+	assert.Equal(t, http.StatusInternalServerError, *ds.deliveries[1].Status)
+	assert.NotNil(t, ds.deliveries[1].Error)
+
+	// merchants without a secret cannot be delivered to however often the delivery is retried
+	ds.secrets = nil
+	_, err = deliverer.Deliver(ctx, deliveries[0])
+	var permanentErr *notification.PermanentError
+	assert.True(t, errors.As(err, &permanentErr))
+}
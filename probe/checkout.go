@@ -0,0 +1,197 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/payment/paymenttest"
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	probeRuns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_runs_total",
+			Help: "Synthetic probe runs by result",
+		},
+		[]string{"probe", "result"},
+	)
+	probeStepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "probe_step_duration_seconds",
+			Help:    "Duration of each step of a synthetic probe",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"probe", "step", "result"},
+	)
+	probeLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_last_success_timestamp_seconds",
+			Help: "When a synthetic probe last succeeded, to alert on staleness",
+		},
+		[]string{"probe"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(probeRuns, probeStepDuration, probeLastSuccess)
+}
+
+const (
+	checkoutProbe = "checkout"
+	// checkoutCredentials is the number of credentials the checkout requests, one of which is redeemed
+	checkoutCredentials = 2
+)
+
+// Checkout probes a full checkout of a test sku: an order is created, paid by the sku being free,
+// credentials are requested, fetched once signed and one is redeemed
+type Checkout struct {
+	client *clients.SimpleHTTPClient
+	sku    string
+	// SigningTimeout is how long signed credentials are waited for
+	SigningTimeout time.Duration
+	// PollInterval is how often signed credentials are checked for
+	PollInterval time.Duration
+}
+
+// NewCheckout creates a checkout probe of the sku token against the client's environment. The
+// client's token must be authorized to verify credentials
+func NewCheckout(client *clients.SimpleHTTPClient, sku string) *Checkout {
+	return &Checkout{
+		client:         client,
+		sku:            sku,
+		SigningTimeout: 30 * time.Second,
+		PollInterval:   time.Second,
+	}
+}
+
+// Run the checkout, recording the duration of each step and the result
+func (c *Checkout) Run(ctx context.Context) error {
+	err := c.run(ctx)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	} else {
+		probeLastSuccess.With(prometheus.Labels{"probe": checkoutProbe}).SetToCurrentTime()
+	}
+	probeRuns.With(prometheus.Labels{"probe": checkoutProbe, "result": result}).Inc()
+	return err
+}
+
+func (c *Checkout) run(ctx context.Context) error {
+	var order payment.Order
+	err := step(checkoutProbe, "create-order", func() error {
+		body := payment.CreateOrderRequest{
+			Items: []payment.OrderItemRequest{{SKU: c.sku, Quantity: 1}},
+		}
+		if err := c.do(ctx, "POST", "/v1/orders", body, &order); err != nil {
+			return err
+		}
+		if len(order.Items) == 0 {
+			return fmt.Errorf("order %s has no items", order.ID)
+		}
+		if !order.IsPaid() {
+			return fmt.Errorf("order %s is %s, the probe sku must be free", order.ID, order.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	item := order.Items[0]
+	credentialsPath := fmt.Sprintf("/v1/orders/%s/credentials", order.ID)
+
+	tokens := make([]*ristretto.Token, checkoutCredentials)
+	err = step(checkoutProbe, "request-credentials", func() error {
+		body := payment.CreateOrderCredsRequest{ItemID: item.ID}
+		for i := range tokens {
+			token, err := ristretto.NewToken()
+			if err != nil {
+				return err
+			}
+			blinded, err := token.Blind()
+			if err != nil {
+				return err
+			}
+			tokens[i] = token
+			body.BlindedCreds = append(body.BlindedCreds, blinded)
+		}
+		return c.do(ctx, "POST", credentialsPath, body, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	var creds payment.OrderCreds
+	err = step(checkoutProbe, "fetch-credentials", func() error {
+		deadline := time.Now().Add(c.SigningTimeout)
+		for {
+			if err := c.do(ctx, "GET", credentialsPath+"/"+item.ID.String(), nil, &creds); err != nil {
+				return err
+			}
+			if creds.SignedCreds != nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("credentials were not signed within %s", c.SigningTimeout)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.PollInterval):
+			}
+		}
+		if len(*creds.SignedCreds) != len(tokens) {
+			return fmt.Errorf("expected %d signed credentials, got %d", len(tokens), len(*creds.SignedCreds))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return step(checkoutProbe, "redeem", func() error {
+		unblinded, err := tokens[0].Unblind((*creds.SignedCreds)[0])
+		if err != nil {
+			return err
+		}
+		presentation, err := paymenttest.SingleUsePresentation(order.MerchantID, item.SKU, unblinded)
+		if err != nil {
+			return err
+		}
+		return c.do(ctx, "POST", "/v1/credentials/subscription/verifications", payment.VerifyCredentialRequest{
+			Type:         "single-use",
+			Version:      1,
+			SKU:          item.SKU,
+			MerchantID:   order.MerchantID,
+			Presentation: presentation,
+		}, nil)
+	})
+}
+
+func (c *Checkout) do(ctx context.Context, method, path string, body interface{}, v interface{}) error {
+	req, err := c.client.NewRequest(ctx, method, path, body, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Do(ctx, req, v)
+	return err
+}
+
+// step times a step of a probe, failing it with the step's name
+func step(probe, name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	result := "ok"
+	if err != nil {
+		result = "error"
+		err = fmt.Errorf("%s: %w", name, err)
+	}
+	probeStepDuration.With(prometheus.Labels{"probe": probe, "step": name, "result": result}).
+		Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,105 @@
+package probe
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkoutServer fakes the checkout endpoints, signing credentials on the second fetch
+func checkoutServer(t *testing.T, status string) (*httptest.Server, *bool) {
+	key, err := ristretto.NewSigningKey()
+	require.NoError(t, err)
+
+	var (
+		orderID  = uuid.NewV4()
+		itemID   = uuid.NewV4()
+		blinded  []string
+		fetches  int
+		redeemed bool
+	)
+	r := chi.NewRouter()
+	r.Post("/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(payment.Order{
+			ID:         orderID,
+			MerchantID: "brave.com",
+			Status:     status,
+			Items:      []payment.OrderItem{{ID: itemID, OrderID: orderID, SKU: "probe"}},
+		}))
+	})
+	r.Post("/v1/orders/{orderID}/credentials", func(w http.ResponseWriter, r *http.Request) {
+		var req payment.CreateOrderCredsRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, itemID, req.ItemID)
+		blinded = req.BlindedCreds
+	})
+	r.Get("/v1/orders/{orderID}/credentials/{itemID}", func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		creds := payment.OrderCreds{ID: itemID, OrderID: orderID}
+		if fetches < 2 {
+			w.WriteHeader(http.StatusAccepted)
+		} else {
+			signed := jsonutils.TextArray{}
+			for _, b := range blinded {
+				s, err := key.Sign(b)
+				assert.NoError(t, err)
+				signed = append(signed, s)
+			}
+			creds.SignedCreds = &signed
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(creds))
+	})
+	r.Post("/v1/credentials/subscription/verifications", func(w http.ResponseWriter, r *http.Request) {
+		var req payment.VerifyCredentialRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		raw, err := base64.StdEncoding.DecodeString(req.Presentation)
+		assert.NoError(t, err)
+		var redemption cbr.CredentialRedemption
+		assert.NoError(t, json.Unmarshal(raw, &redemption))
+		if err := key.Verify(redemption.TokenPreimage, redemption.Signature, redemption.Issuer); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		redeemed = true
+	})
+	return httptest.NewServer(r), &redeemed
+}
+
+func TestCheckout(t *testing.T) {
+	server, redeemed := checkoutServer(t, "paid")
+	defer server.Close()
+
+	client, err := clients.New(server.URL, "")
+	require.NoError(t, err)
+	checkout := NewCheckout(client, "sku-token")
+	checkout.PollInterval = time.Millisecond
+
+	require.NoError(t, checkout.Run(context.Background()))
+	assert.True(t, *redeemed)
+}
+
+func TestCheckoutUnpaid(t *testing.T) {
+	server, redeemed := checkoutServer(t, "pending")
+	defer server.Close()
+
+	client, err := clients.New(server.URL, "")
+	require.NoError(t, err)
+	err = NewCheckout(client, "sku-token").Run(context.Background())
+	assert.Error(t, err, "a sku which is not free cannot be probed")
+	assert.Contains(t, err.Error(), "create-order")
+	assert.False(t, *redeemed)
+}
@@ -0,0 +1,49 @@
+package probe
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/scheduler"
+)
+
+// probeTimeout bounds a probe run, so a hung dependency fails the probe rather than blocking the scheduler
+const probeTimeout = 2 * time.Minute
+
+// ScheduledJobs returns the synthetic probes, none unless PROBE_CHECKOUT_SKU is set to the token of
+// a free test sku. Probes run against PROBE_TARGET, every five minutes unless PROBE_SCHEDULE is set,
+// authorized with PROBE_TOKEN
+func ScheduledJobs() ([]scheduler.Job, error) {
+	sku := os.Getenv("PROBE_CHECKOUT_SKU")
+	if sku == "" {
+		return nil, nil
+	}
+	target := os.Getenv("PROBE_TARGET")
+	if target == "" {
+		target = "http://localhost:3333"
+	}
+	schedule := os.Getenv("PROBE_SCHEDULE")
+	if schedule == "" {
+		schedule = "*/5 * * * *"
+	}
+
+	client, err := clients.New(target, os.Getenv("PROBE_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+	checkout := NewCheckout(client, sku)
+
+	return []scheduler.Job{
+		{
+			Name:     "probe-checkout",
+			Schedule: schedule,
+			Func: func(ctx context.Context) error {
+				ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+				defer cancel()
+				return checkout.Run(ctx)
+			},
+		},
+	}, nil
+}
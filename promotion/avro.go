@@ -1,5 +1,16 @@
 package promotion
 
+import (
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+)
+
+func init() {
+	// quarantined suggestions are decoded with the schema they were produced with
+	if err := kafkautils.RegisterTopic(suggestionTopic, suggestionEventSchema); err != nil {
+		panic(err)
+	}
+}
+
 const suggestionEventSchema = `{
   "namespace": "brave.grants",
   "type": "record",
@@ -13,6 +13,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	appctx "github.com/brave-intl/bat-go/utils/context"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
 	"github.com/brave-intl/bat-go/utils/handlers"
@@ -54,11 +55,14 @@ func Router(service *Service) chi.Router {
 		r.Method("POST", "/", CreatePromotion(service))
 	}
 
-	r.Method("GET", "/{claimType}/grants/summary", middleware.InstrumentHandler("GetClaimSummary", GetClaimSummary(service)))
+	// public stats endpoint, any origin may read the summary unless overridden with CORS_CLAIM_SUMMARY_ALLOWED_ORIGINS
+	claimSummaryCORS := middleware.CORS(middleware.NewPublicCORSConfig("claim-summary"))
+	r.Method("OPTIONS", "/{claimType}/grants/summary", middleware.InstrumentHandler("GetClaimSummaryOptions", claimSummaryCORS(nil)))
+	r.Method("GET", "/{claimType}/grants/summary", middleware.InstrumentHandler("GetClaimSummary", claimSummaryCORS(GetClaimSummary(service))))
 	r.Method("GET", "/", middleware.InstrumentHandler("GetAvailablePromotions", GetAvailablePromotions(service)))
 	// version 1 clobbered claims
 	r.Method("POST", "/reportclobberedclaims", middleware.InstrumentHandler("ReportClobberedClaims", PostReportClobberedClaims(service, 1)))
-	r.Method("POST", "/{promotionId}", middleware.HTTPSignedOnly(service)(middleware.InstrumentHandler("ClaimPromotion", ClaimPromotion(service))))
+	r.Method("POST", "/{promotionId}", middleware.HTTPSignedOnly(service, middleware.RequireNonceOn("ClaimPromotion", service.nonces, signedRequestTTL))(middleware.InstrumentHandler("ClaimPromotion", ClaimPromotion(service))))
 	r.Method("GET", "/{promotionId}/claims/{claimId}", middleware.InstrumentHandler("GetClaim", GetClaim(service)))
 	r.Method("GET", "/drain/{drainId}", middleware.InstrumentHandler("GetDrainPoll", GetDrainPoll(service)))
 	r.Method("POST", "/report-bap", middleware.HTTPSignedOnly(service)(middleware.InstrumentHandler("PostReportBAPEvent", PostReportBAPEvent(service))))
@@ -82,7 +86,7 @@ func SuggestionsV2Router(service *Service) (chi.Router, error) {
 	}
 
 	if enableLinkingDraining {
-		r.Method("POST", "/claim", middleware.HTTPSignedOnly(service)(middleware.InstrumentHandler("DrainSuggestionV2", DrainSuggestionV2(service))))
+		r.Method("POST", "/claim", middleware.HTTPSignedOnly(service, middleware.RequireNonceOn("DrainSuggestionV2", service.nonces, signedRequestTTL))(middleware.InstrumentHandler("DrainSuggestionV2", DrainSuggestionV2(service))))
 	}
 	return r, nil
 }
@@ -105,7 +109,7 @@ func SuggestionsRouter(service *Service) (chi.Router, error) {
 	}
 
 	if enableLinkingDraining {
-		r.Method("POST", "/claim", middleware.HTTPSignedOnly(service)(middleware.InstrumentHandler("DrainSuggestion", DrainSuggestion(service))))
+		r.Method("POST", "/claim", middleware.HTTPSignedOnly(service, middleware.RequireNonceOn("DrainSuggestion", service.nonces, signedRequestTTL))(middleware.InstrumentHandler("DrainSuggestion", DrainSuggestion(service))))
 	}
 	return r, nil
 }
@@ -272,6 +276,9 @@ func ClaimPromotion(service *Service) handlers.AppHandler {
 			if errors.Is(err, errClaimedDifferentBlindCreds) {
 				status = http.StatusConflict
 			}
+			if cbr.IsCircuitOpen(err) {
+				status = http.StatusServiceUnavailable
+			}
 
 			if errors.As(err, &target) {
 				err = target
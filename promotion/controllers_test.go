@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/clients"
 	mockbitflyer "github.com/brave-intl/bat-go/utils/clients/bitflyer/mock"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
@@ -33,6 +34,7 @@ import (
 	"github.com/brave-intl/bat-go/utils/httpsignature"
 	"github.com/brave-intl/bat-go/utils/jsonutils"
 	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/test/fixtures"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
 	"github.com/brave-intl/bat-go/wallet"
@@ -55,6 +57,9 @@ func TestControllersTestSuite(t *testing.T) {
 }
 
 func (suite *ControllersTestSuite) SetupSuite() {
+	// started in containers unless DATABASE_URL and KAFKA_BROKERS point at running ones
+	fixtures.Require(suite.T(), fixtures.Options{Postgres: true, Kafka: true})
+
 	pg, _, err := NewPostgres()
 	suite.Require().NoError(err, "Failed to get postgres conn")
 
@@ -69,7 +74,7 @@ func (suite *ControllersTestSuite) SetupSuite() {
 		suite.Require().NoError(m.Down(), "Failed to migrate down cleanly")
 	}
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 
 	enableSuggestionJob = true
 }
@@ -794,7 +799,7 @@ func (suite *ControllersTestSuite) TestSuggest() {
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:          strings.Split(kafkaBrokers, ","),
 		Topic:            suggestionTopic,
-		Dialer:           service.kafkaDialer,
+		Dialer:           dialer,
 		MaxWait:          time.Second,
 		RebalanceTimeout: time.Second,
 		Logger:           kafka.LoggerFunc(log.Printf),
@@ -3123,7 +3128,7 @@ func (suite *ControllersTestSuite) TestBraveFundsTransaction() {
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:          strings.Split(kafkaBrokers, ","),
 		Topic:            suggestionTopic,
-		Dialer:           service.kafkaDialer,
+		Dialer:           dialer,
 		MaxWait:          time.Second,
 		RebalanceTimeout: time.Second,
 		Logger:           kafka.LoggerFunc(log.Printf),
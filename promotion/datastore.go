@@ -18,6 +18,7 @@ import (
 	"github.com/brave-intl/bat-go/utils/jsonutils"
 	"github.com/brave-intl/bat-go/utils/logging"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/brave-intl/bat-go/utils/workers"
 	"github.com/getsentry/sentry-go"
 	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
@@ -121,6 +122,8 @@ type Datastore interface {
 	GetDrainPoll(drainID *uuid.UUID) (*DrainPoll, error)
 	// GetCustodianDrainInfo gets the information about a drain poll job
 	GetCustodianDrainInfo(paymentID *uuid.UUID) ([]CustodianDrain, error)
+	// GetPendingDrainJobs returns the drain jobs the drain worker would run next, without locking them
+	GetPendingDrainJobs(ctx context.Context, limit int) ([]DrainJob, error)
 }
 
 // ReadOnlyDatastore includes all database methods that can be made with a read only db connection
@@ -152,6 +155,8 @@ type ReadOnlyDatastore interface {
 	GetDrainPoll(drainID *uuid.UUID) (*DrainPoll, error)
 	// GetCustodianDrainInfo gets the information about a drain poll job
 	GetCustodianDrainInfo(paymentID *uuid.UUID) ([]CustodianDrain, error)
+	// GetPendingDrainJobs returns the drain jobs the drain worker would run next, without locking them
+	GetPendingDrainJobs(ctx context.Context, limit int) ([]DrainJob, error)
 }
 
 // Postgres is a Datastore wrapper around a postgres database
@@ -1269,6 +1274,24 @@ type DrainJob struct {
 	Completed     bool            `db:"completed"`
 	CompletedAt   pq.NullTime     `db:"completed_at"`
 	UpdatedAt     pq.NullTime     `db:"updated_at"`
+	CreatedAt     pq.NullTime     `db:"created_at"`
+}
+
+// GetPendingDrainJobs returns the drain jobs the drain worker would run next, without locking them
+func (pg *Postgres) GetPendingDrainJobs(ctx context.Context, limit int) ([]DrainJob, error) {
+	statement := `
+select *
+from claim_drain
+where not erred and transaction_id is null
+and (status is null or status not in ('complete', 'reputation-failed', 'failed'))
+order by id
+limit $1`
+
+	jobs := []DrainJob{}
+	if err := pg.RawDB().SelectContext(ctx, &jobs, statement, limit); err != nil {
+		return nil, fmt.Errorf("failed to get pending drain jobs: %w", err)
+	}
+	return jobs, nil
 }
 
 // RunNextDrainJob to process deposits if there is one waiting
@@ -1308,6 +1331,9 @@ limit 1`
 
 	job := jobs[0]
 	attempted = true
+	if err := workers.Claim(ctx, tx, "claim drain "+job.ID.String()); err != nil {
+		return attempted, err
+	}
 
 	// set job status to initialized
 	_, err = tx.Exec(`
@@ -1526,21 +1552,48 @@ where
 	return attempted, nil
 }
 
-// UpdateOrder updates the orders status.
-//	Status should either be one of pending, paid, fulfilled, or canceled.
+// UpdateOrder updates the orders status, appending the change to the order's events as payment does.
+//	Status should either be one of pending, paid, fulfilled, canceled, or refunded.
 func (pg *Postgres) UpdateOrder(orderID uuid.UUID, status string) error {
-	result, err := pg.RawDB().Exec(`UPDATE orders set status = $1, updated_at = CURRENT_TIMESTAMP where id = $2`, status, orderID)
-
+	tx, err := pg.RawDB().Beginx()
 	if err != nil {
 		return err
 	}
+	defer pg.RollbackTx(tx)
 
-	rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 0 || err != nil {
+	var previous string
+	err = tx.Get(&previous, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+	if err == sql.ErrNoRows {
 		return errors.New("no rows updated")
+	} else if err != nil {
+		return err
+	}
+	if previous == status {
+		return nil
 	}
 
-	return nil
+	_, err = tx.Exec(`
+			UPDATE orders set status = $1, updated_at = CURRENT_TIMESTAMP, event_sequence = event_sequence + 1
+			where id = $2`, status, orderID)
+	if err != nil {
+		return err
+	}
+	eventType := "status_changed"
+	if status == "paid" || status == "refunded" {
+		eventType = status
+	}
+	payload, err := json.Marshal(map[string]string{"status": status, "previous": previous})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+			INSERT INTO order_events (order_id, sequence, type, payload)
+			SELECT id, event_sequence, $2, $3 FROM orders WHERE id = $1`, orderID, eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // CreateTransaction creates a transaction given an orderID, externalTransactionID, currency, and a kind of transaction
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	"github.com/brave-intl/bat-go/utils/jsonutils"
 	testutils "github.com/brave-intl/bat-go/utils/test"
@@ -37,7 +38,7 @@ func (suite *PostgresTestSuite) SetupSuite() {
 		suite.Require().NoError(m.Down(), "Failed to migrate down cleanly")
 	}
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 }
 
 func (suite *PostgresTestSuite) SetupTest() {
@@ -35,6 +35,9 @@ var (
 	errGeminiMisconfigured      = errors.New("gemini is not configured")
 	errReputationServiceFailure = errors.New("failed to call reputation service")
 	errWalletNotReputable       = errors.New("wallet is not reputable")
+
+	// bitflyerJPYLimit is the most a single bitflyer drain may transfer, in JPY
+	bitflyerJPYLimit = decimal.NewFromFloat(100000)
 )
 
 // Drain ad suggestions into verified wallet
@@ -326,7 +329,7 @@ func (service *Service) RedeemAndTransferFunds(ctx context.Context, credentials
 			}
 		}
 
-		JPYLimit := decimal.NewFromFloat(100000)
+		JPYLimit := bitflyerJPYLimit
 		var overLimitErr error
 
 		totalJPYTransfer := total.Mul(quote.Rate)
@@ -410,20 +413,7 @@ func (service *Service) RedeemAndTransferFunds(ctx context.Context, credentials
 	} else if *wallet.UserDepositAccountProvider == "brave" {
 		// update the mint job for this walletID
 
-		promoTotal := map[string]decimal.Decimal{}
-		// iterate through the credentials
-		// get a total count per promotion
-		for _, cred := range credentials {
-			promotionID := strings.TrimSuffix(cred.Issuer, ":control")
-			v, ok := promoTotal[promotionID]
-			if ok {
-				// each credential is 0.25
-				promoTotal[promotionID] = v.Add(decimal.NewFromFloat(0.25))
-			} else {
-				promoTotal[promotionID] = decimal.NewFromFloat(0.25)
-			}
-		}
-		for k, v := range promoTotal {
+		for k, v := range mintDrainTotals(credentials) {
 			promotionID, err := uuid.FromString(k)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get promotion id as uuid: %w", err)
@@ -443,6 +433,24 @@ func (service *Service) RedeemAndTransferFunds(ctx context.Context, credentials
 		*wallet.UserDepositAccountProvider)
 }
 
+// mintDrainTotals totals the credentials being drained to a brave wallet per promotion
+func mintDrainTotals(credentials []cbr.CredentialRedemption) map[string]decimal.Decimal {
+	promoTotal := map[string]decimal.Decimal{}
+	// iterate through the credentials
+	// get a total count per promotion
+	for _, cred := range credentials {
+		promotionID := strings.TrimSuffix(cred.Issuer, ":control")
+		v, ok := promoTotal[promotionID]
+		if ok {
+			// each credential is 0.25
+			promoTotal[promotionID] = v.Add(decimal.NewFromFloat(0.25))
+		} else {
+			promoTotal[promotionID] = decimal.NewFromFloat(0.25)
+		}
+	}
+	return promoTotal
+}
+
 func redeemAndTransferGeminiFunds(
 	ctx context.Context,
 	service *Service,
@@ -0,0 +1,172 @@
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/s3"
+	"github.com/brave-intl/bat-go/utils/scheduler"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+// drainDryRunLimit is the most pending drain jobs a dry run reports on
+const drainDryRunLimit = 10000
+
+// DrainPlan is what the drain worker would do for a pending drain job
+type DrainPlan struct {
+	DrainID     uuid.UUID       `json:"drainId"`
+	ClaimID     *uuid.UUID      `json:"claimId,omitempty"`
+	WalletID    uuid.UUID       `json:"walletId"`
+	Total       decimal.Decimal `json:"total"`
+	Credentials int             `json:"credentials"`
+	Provider    string          `json:"provider,omitempty"`
+	Destination string          `json:"destination,omitempty"`
+	// Transfer is the amount which would be transferred to the custodian, after its limits
+	Transfer decimal.Decimal `json:"transfer"`
+	// Mint are the totals which would be minted to a brave wallet, keyed by promotion
+	Mint map[string]decimal.Decimal `json:"mint,omitempty"`
+	// Error is why the drain job would fail
+	Error string `json:"error,omitempty"`
+}
+
+// DrainDryRunReport is what the drain worker would do for the pending drain jobs
+type DrainDryRunReport struct {
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Plans       []DrainPlan `json:"plans"`
+	// Totals are the amounts which would be transferred, keyed by provider
+	Totals map[string]decimal.Decimal `json:"totals"`
+	Failed int                        `json:"failed"`
+}
+
+// DryRunDrainJobs computes what the drain worker would do for the pending drain jobs. No
+// credentials are redeemed, no funds are transferred and the jobs are left untouched
+func (service *Service) DryRunDrainJobs(ctx context.Context, limit int) (*DrainDryRunReport, error) {
+	jobs, err := service.ReadableDatastore().GetPendingDrainJobs(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DrainDryRunReport{
+		GeneratedAt: time.Now().UTC(),
+		Plans:       []DrainPlan{},
+		Totals:      map[string]decimal.Decimal{},
+	}
+	for _, job := range jobs {
+		plan, err := service.planDrain(ctx, job)
+		if err != nil {
+			plan.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Totals[plan.Provider] = report.Totals[plan.Provider].Add(plan.Transfer)
+		}
+		report.Plans = append(report.Plans, plan)
+	}
+	return report, nil
+}
+
+// planDrain follows RedeemAndTransferFunds up to the point of transferring funds
+func (service *Service) planDrain(ctx context.Context, job DrainJob) (DrainPlan, error) {
+	plan := DrainPlan{
+		DrainID:  job.ID,
+		ClaimID:  job.ClaimID,
+		WalletID: job.WalletID,
+		Total:    job.Total,
+		Transfer: decimal.Zero,
+	}
+
+	var credentials []cbr.CredentialRedemption
+	if err := json.Unmarshal([]byte(job.Credentials), &credentials); err != nil {
+		return plan, fmt.Errorf("invalid credentials: %w", err)
+	}
+	plan.Credentials = len(credentials)
+
+	wallet, err := service.wallet.ReadableDatastore().GetWallet(ctx, job.WalletID)
+	if err != nil {
+		return plan, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	if wallet == nil {
+		return plan, errorutils.ErrMissingWallet
+	}
+	if wallet.UserDepositDestination == "" || wallet.UserDepositAccountProvider == nil {
+		return plan, errorutils.ErrNoDepositProviderDestination
+	}
+	plan.Provider = *wallet.UserDepositAccountProvider
+	plan.Destination = wallet.UserDepositDestination
+
+	switch plan.Provider {
+	case "uphold":
+		plan.Transfer = job.Total
+	case "bitflyer":
+		if service.bfClient == nil {
+			return plan, fmt.Errorf("bitflyer is not configured")
+		}
+		quote, err := service.bfClient.FetchQuote(ctx, "BAT_JPY", false)
+		if err != nil {
+			return plan, fmt.Errorf("failed to fetch bitflyer quote: %w", err)
+		}
+		plan.Transfer = job.Total
+		if job.Total.Mul(quote.Rate).GreaterThan(bitflyerJPYLimit) {
+			plan.Transfer = bitflyerJPYLimit.Div(quote.Rate).Floor()
+		}
+	case "gemini":
+		if service.geminiConf == nil || service.geminiClient == nil {
+			return plan, errGeminiMisconfigured
+		}
+		plan.Transfer = job.Total
+	case "brave":
+		plan.Mint = mintDrainTotals(credentials)
+	default:
+		return plan, fmt.Errorf("user_deposit_account_provider unknown: %s", plan.Provider)
+	}
+	return plan, nil
+}
+
+// drainDryRun reports whether DRAIN_DRY_RUN is set, in which case drain jobs are reported on by the
+// scheduled dry run rather than run
+func drainDryRun() (bool, error) {
+	if env := os.Getenv("DRAIN_DRY_RUN"); env != "" {
+		return strconv.ParseBool(env)
+	}
+	return false, nil
+}
+
+// ScheduledJobs - Implement scheduler.JobService interface. The drain dry run is scheduled,
+// hourly unless DRAIN_DRY_RUN_SCHEDULE is set, when DRAIN_DRY_RUN is set
+func (service *Service) ScheduledJobs() []scheduler.Job {
+	if dryRun, _ := drainDryRun(); !dryRun {
+		return nil
+	}
+	schedule := os.Getenv("DRAIN_DRY_RUN_SCHEDULE")
+	if schedule == "" {
+		schedule = "0 * * * *"
+	}
+	return []scheduler.Job{
+		{
+			Name:     "drain-dry-run",
+			Schedule: schedule,
+			Func:     service.RunScheduledDrainDryRun,
+		},
+	}
+}
+
+// RunScheduledDrainDryRun writes the drain dry run report under the DRAIN_DRY_RUN_REPORT prefix,
+// which may be an s3 uri
+func (service *Service) RunScheduledDrainDryRun(ctx context.Context) error {
+	report, err := service.DryRunDrainJobs(ctx, drainDryRunLimit)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal drain dry run report: %w", err)
+	}
+	location := os.Getenv("DRAIN_DRY_RUN_REPORT") + report.GeneratedAt.Format("2006-01-02T15-04-05") + ".json"
+	return s3.WriteLocation(ctx, location, out, "application/json")
+}
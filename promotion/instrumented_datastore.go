@@ -10,8 +10,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	"github.com/brave-intl/bat-go/utils/jsonutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
@@ -146,6 +148,7 @@ func (_d DatastoreWithPrometheus) DrainClaim(drainID *uuid.UUID, claim *Claim, c
 // EnqueueMintDrainJob implements Datastore
 func (_d DatastoreWithPrometheus) EnqueueMintDrainJob(ctx context.Context, walletID uuid.UUID, promotionIDs ...uuid.UUID) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".EnqueueMintDrainJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -153,6 +156,7 @@ func (_d DatastoreWithPrometheus) EnqueueMintDrainJob(ctx context.Context, walle
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "EnqueueMintDrainJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.EnqueueMintDrainJob(ctx, walletID, promotionIDs...)
 }
@@ -297,6 +301,22 @@ func (_d DatastoreWithPrometheus) GetOrder(orderID uuid.UUID) (op1 *Order, err e
 	return _d.base.GetOrder(orderID)
 }
 
+// GetPendingDrainJobs implements Datastore
+func (_d DatastoreWithPrometheus) GetPendingDrainJobs(ctx context.Context, limit int) (da1 []DrainJob, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetPendingDrainJobs")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetPendingDrainJobs", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetPendingDrainJobs(ctx, limit)
+}
+
 // GetPreClaim implements Datastore
 func (_d DatastoreWithPrometheus) GetPreClaim(promotionID uuid.UUID, walletID string) (cp1 *Claim, err error) {
 	_since := time.Now()
@@ -356,6 +376,7 @@ func (_d DatastoreWithPrometheus) GetSumForTransactions(orderID uuid.UUID) (d1 d
 // InsertBAPReportEvent implements Datastore
 func (_d DatastoreWithPrometheus) InsertBAPReportEvent(ctx context.Context, paymentID uuid.UUID, amount decimal.Decimal) (up1 *uuid.UUID, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertBAPReportEvent")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -363,6 +384,7 @@ func (_d DatastoreWithPrometheus) InsertBAPReportEvent(ctx context.Context, paym
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertBAPReportEvent", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.InsertBAPReportEvent(ctx, paymentID, amount)
 }
@@ -370,6 +392,7 @@ func (_d DatastoreWithPrometheus) InsertBAPReportEvent(ctx context.Context, paym
 // InsertBATLossEvent implements Datastore
 func (_d DatastoreWithPrometheus) InsertBATLossEvent(ctx context.Context, paymentID uuid.UUID, reportID int, amount decimal.Decimal, platform string) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertBATLossEvent")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -377,6 +400,7 @@ func (_d DatastoreWithPrometheus) InsertBATLossEvent(ctx context.Context, paymen
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertBATLossEvent", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.InsertBATLossEvent(ctx, paymentID, reportID, amount, platform)
 }
@@ -384,6 +408,7 @@ func (_d DatastoreWithPrometheus) InsertBATLossEvent(ctx context.Context, paymen
 // InsertClobberedClaims implements Datastore
 func (_d DatastoreWithPrometheus) InsertClobberedClaims(ctx context.Context, ids []uuid.UUID, version int) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertClobberedClaims")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -391,6 +416,7 @@ func (_d DatastoreWithPrometheus) InsertClobberedClaims(ctx context.Context, ids
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertClobberedClaims", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.InsertClobberedClaims(ctx, ids, version)
 }
@@ -424,8 +450,9 @@ func (_d DatastoreWithPrometheus) InsertSuggestion(credentials []cbr.CredentialR
 }
 
 // Migrate implements Datastore
-func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d DatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -433,8 +460,25 @@ func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements Datastore
+func (_d DatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements Datastore
@@ -489,6 +533,7 @@ func (_d DatastoreWithPrometheus) RollbackTxAndHandle(tx *sqlx.Tx) (err error) {
 // RunNextClaimJob implements Datastore
 func (_d DatastoreWithPrometheus) RunNextClaimJob(ctx context.Context, worker ClaimWorker) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextClaimJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -496,6 +541,7 @@ func (_d DatastoreWithPrometheus) RunNextClaimJob(ctx context.Context, worker Cl
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextClaimJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RunNextClaimJob(ctx, worker)
 }
@@ -503,6 +549,7 @@ func (_d DatastoreWithPrometheus) RunNextClaimJob(ctx context.Context, worker Cl
 // RunNextDrainJob implements Datastore
 func (_d DatastoreWithPrometheus) RunNextDrainJob(ctx context.Context, worker DrainWorker) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextDrainJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -510,6 +557,7 @@ func (_d DatastoreWithPrometheus) RunNextDrainJob(ctx context.Context, worker Dr
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextDrainJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RunNextDrainJob(ctx, worker)
 }
@@ -517,6 +565,7 @@ func (_d DatastoreWithPrometheus) RunNextDrainJob(ctx context.Context, worker Dr
 // RunNextMintDrainJob implements Datastore
 func (_d DatastoreWithPrometheus) RunNextMintDrainJob(ctx context.Context, worker MintWorker) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextMintDrainJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -524,6 +573,7 @@ func (_d DatastoreWithPrometheus) RunNextMintDrainJob(ctx context.Context, worke
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextMintDrainJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RunNextMintDrainJob(ctx, worker)
 }
@@ -531,6 +581,7 @@ func (_d DatastoreWithPrometheus) RunNextMintDrainJob(ctx context.Context, worke
 // RunNextSuggestionJob implements Datastore
 func (_d DatastoreWithPrometheus) RunNextSuggestionJob(ctx context.Context, worker SuggestionWorker) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RunNextSuggestionJob")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -538,6 +589,7 @@ func (_d DatastoreWithPrometheus) RunNextSuggestionJob(ctx context.Context, work
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "RunNextSuggestionJob", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RunNextSuggestionJob(ctx, worker)
 }
@@ -559,6 +611,7 @@ func (_d DatastoreWithPrometheus) SaveClaimCreds(claimCreds *ClaimCreds) (err er
 // SetMintDrainPromotionTotal implements Datastore
 func (_d DatastoreWithPrometheus) SetMintDrainPromotionTotal(ctx context.Context, walletID uuid.UUID, promotionID uuid.UUID, total decimal.Decimal) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".SetMintDrainPromotionTotal")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -566,6 +619,7 @@ func (_d DatastoreWithPrometheus) SetMintDrainPromotionTotal(ctx context.Context
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "SetMintDrainPromotionTotal", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.SetMintDrainPromotionTotal(ctx, walletID, promotionID, total)
 }
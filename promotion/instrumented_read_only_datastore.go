@@ -7,8 +7,11 @@ package promotion
 //go:generate gowrap gen -p github.com/brave-intl/bat-go/promotion -i ReadOnlyDatastore -t ../.prom-gowrap.tmpl -o instrumented_read_only_datastore.go
 
 import (
+	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
@@ -167,6 +170,22 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetIssuerByPublicKey(publicKey string)
 	return _d.base.GetIssuerByPublicKey(publicKey)
 }
 
+// GetPendingDrainJobs implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) GetPendingDrainJobs(ctx context.Context, limit int) (da1 []DrainJob, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetPendingDrainJobs")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetPendingDrainJobs", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetPendingDrainJobs(ctx, limit)
+}
+
 // GetPreClaim implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetPreClaim(promotionID uuid.UUID, walletID string) (cp1 *Claim, err error) {
 	_since := time.Now()
@@ -210,17 +229,35 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetPromotionsMissingIssuer(limit int)
 }
 
 // Migrate implements ReadOnlyDatastore
-func (_d ReadOnlyDatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d ReadOnlyDatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements ReadOnlyDatastore
@@ -10,7 +10,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/altcurrency"
+	"github.com/brave-intl/bat-go/utils/bus"
 	"github.com/brave-intl/bat-go/utils/clients/bitflyer"
 	"github.com/brave-intl/bat-go/utils/clients/cbr"
 	"github.com/brave-intl/bat-go/utils/clients/gemini"
@@ -26,11 +28,14 @@ import (
 	"github.com/brave-intl/bat-go/wallet"
 	"github.com/linkedin/goavro"
 	"github.com/prometheus/client_golang/prometheus"
-	kafka "github.com/segmentio/kafka-go"
 	"golang.org/x/crypto/ed25519"
 )
 
-const localEnv = "local"
+const (
+	localEnv = "local"
+	// signedRequestTTL is how far the date of a signed claim may be from now before it is rejected as stale
+	signedRequestTTL = 5 * time.Minute
+)
 
 var (
 	suggestionTopic = os.Getenv("ENV") + ".grant.suggestion"
@@ -88,13 +93,15 @@ type Service struct {
 	geminiClient            gemini.Client
 	geminiConf              *gemini.Conf
 	codecs                  map[string]*goavro.Codec
-	kafkaWriter             *kafka.Writer
-	kafkaDialer             *kafka.Dialer
+	producer                bus.Producer
 	hotWallet               *uphold.Wallet
 	drainChannel            chan *w.TransactionInfo
 	jobs                    []srv.Job
 	pauseSuggestionsUntil   time.Time
 	pauseSuggestionsUntilMu sync.RWMutex
+	pausePayoutsUntil       time.Time
+	pausePayoutsUntilMu     sync.RWMutex
+	nonces                  middleware.NonceStore
 }
 
 // Jobs - Implement srv.JobService interface
@@ -102,16 +109,16 @@ func (s *Service) Jobs() []srv.Job {
 	return s.jobs
 }
 
-// InitKafka by creating a kafka writer and creating local copies of codecs
+// InitKafka by creating a producer on the topic's message bus and creating local copies of codecs
 func (s *Service) InitKafka(ctx context.Context) error {
 
 	// TODO: eventually as cobra/viper
 	ctx = context.WithValue(ctx, appctx.KafkaBrokersCTXKey, os.Getenv("KAFKA_BROKERS"))
 
 	var err error
-	s.kafkaWriter, s.kafkaDialer, err = kafkautils.InitKafkaWriter(ctx, suggestionTopic)
+	s.producer, err = bus.NewProducer(ctx, suggestionTopic)
 	if err != nil {
-		return fmt.Errorf("failed to initialize kafka: %w", err)
+		return fmt.Errorf("failed to initialize the message bus: %w", err)
 	}
 
 	s.codecs, err = kafkautils.GenerateCodecs(map[string]string{
@@ -242,6 +249,11 @@ func InitService(
 		return nil, err
 	}
 
+	nonces, err := middleware.NewNonceStore(ctx, "promotion_nonce:")
+	if err != nil {
+		return nil, err
+	}
+
 	service := &Service{
 		Datastore:               promotionDB,
 		RoDatastore:             promotionRODB,
@@ -252,26 +264,35 @@ func InitService(
 		reputationClient:        reputationClient,
 		wallet:                  walletService,
 		pauseSuggestionsUntilMu: sync.RWMutex{},
+		nonces:                  nonces,
 	}
 
 	// setup runnable jobs
 	service.jobs = []srv.Job{
 		{
+			Name:    "promotion_missing_issuer",
+			Service: "promotion",
 			Func:    service.RunNextPromotionMissingIssuer,
 			Cadence: 5 * time.Second,
 			Workers: 1,
 		},
 		{
+			Name:    "claim",
+			Service: "promotion",
 			Func:    service.RunNextClaimJob,
 			Cadence: 5 * time.Second,
 			Workers: 1,
 		},
 		{
+			Name:    "suggestion",
+			Service: "promotion",
 			Func:    service.RunNextSuggestionJob,
 			Cadence: 5 * time.Second,
 			Workers: 1,
 		},
 		{
+			Name:    "mint_drain",
+			Service: "promotion",
 			Func:    service.RunNextMintDrainJob,
 			Cadence: time.Second,
 			Workers: 6,
@@ -288,9 +309,17 @@ func InitService(
 		}
 	}
 
-	if enableLinkingDraining {
+	dryRun, err := drainDryRun()
+	if err != nil {
+		return nil, fmt.Errorf("invalid drain_dry_run flag: %w", err)
+	}
+
+	// in a dry run drain jobs are only reported on, by the scheduled drain dry run
+	if enableLinkingDraining && !dryRun {
 		service.jobs = append(service.jobs,
 			srv.Job{
+				Name:    "drain",
+				Service: "promotion",
 				Func:    service.RunNextDrainJob,
 				Cadence: 5 * time.Second,
 				Workers: 1,
@@ -332,8 +361,25 @@ func (s *Service) RunNextSuggestionJob(ctx context.Context) (bool, error) {
 	return s.Datastore.RunNextSuggestionJob(ctx, s)
 }
 
-// RunNextDrainJob takes the next drain job and completes it
+// PausePayouts - pause the drain worker until time specified
+func (s *Service) PausePayouts(until time.Time) {
+	s.pausePayoutsUntilMu.Lock()
+	defer s.pausePayoutsUntilMu.Unlock()
+	s.pausePayoutsUntil = until
+}
+
+// PayoutsPaused - is the drain worker paused?
+func (s *Service) PayoutsPaused() bool {
+	s.pausePayoutsUntilMu.RLock()
+	defer s.pausePayoutsUntilMu.RUnlock()
+	return time.Now().Before(s.pausePayoutsUntil)
+}
+
+// RunNextDrainJob takes the next drain job and completes it, unless payouts are paused
 func (s *Service) RunNextDrainJob(ctx context.Context) (bool, error) {
+	if s.PayoutsPaused() {
+		return false, nil
+	}
 	return s.Datastore.RunNextDrainJob(ctx, s)
 }
 
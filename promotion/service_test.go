@@ -8,6 +8,7 @@ import (
 
 	// re-using viper bind-env for wallet env variables
 	_ "github.com/brave-intl/bat-go/cmd/wallets"
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/inputs"
 	"github.com/brave-intl/bat-go/wallet"
 	"github.com/go-chi/chi"
@@ -33,7 +34,7 @@ func (suite *ServiceTestSuite) SetupSuite() {
 		suite.Require().NoError(m.Down(), "Failed to migrate down cleanly")
 	}
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 }
 
 func (suite *ServiceTestSuite) SetupTest() {
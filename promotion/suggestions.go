@@ -359,7 +359,7 @@ func (service *Service) RedeemAndCreateSuggestionEvent(ctx context.Context, cred
 	}
 
 	// write the message
-	err = service.kafkaWriter.WriteMessages(ctx,
+	err = service.producer.WriteMessages(ctx,
 		kafka.Message{
 			Value: suggestion,
 		},
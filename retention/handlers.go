@@ -0,0 +1,92 @@
+package retention
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// adminAuthorized restricts a route to the admin tokens
+func adminAuthorized(next http.Handler) http.Handler {
+	if os.Getenv("ENV") == "local" {
+		return next
+	}
+	return middleware.AdminTokenAuthorizedOnly(next)
+}
+
+// Router lets admins list the runs of retention policies and purge a policy on demand, whether or not the
+// scheduled purges are enabled
+func Router(store Store, audit middleware.AuditStore) (chi.Router, error) {
+	purger, err := PurgerFromEnv(store)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := PoliciesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.AuditLog(audit))
+	r.Method("GET", "/runs", adminAuthorized(middleware.InstrumentHandler("ListRetentionRuns", ListRuns(store))))
+	r.Method("POST", "/{policy}/purge", adminAuthorized(middleware.InstrumentHandler("PurgeRetentionPolicy", Purge(purger, policies))))
+	return r, nil
+}
+
+// ListRuns is the handler for listing the runs of retention policies, filtered by the policy and limit
+// query parameters
+func ListRuns(store Store) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		limit := 100
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > 1000 {
+				return handlers.ValidationError("request query parameters", map[string]interface{}{
+					"limit": "must be between 1 and 1000",
+				})
+			}
+		}
+
+		runs, err := store.ListRuns(r.Context(), r.URL.Query().Get("policy"), limit)
+		if err != nil {
+			return handlers.WrapError(err, "Error listing retention runs", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), runs, w, http.StatusOK)
+	})
+}
+
+// Purge is the handler for purging the expired rows of a policy now, the run is returned once it finishes
+func Purge(purger *Purger, policies []Policy) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		name := chi.URLParam(r, "policy")
+		var policy *Policy
+		for i := range policies {
+			if policies[i].Name == name {
+				policy = &policies[i]
+			}
+		}
+		if policy == nil {
+			return &handlers.AppError{
+				Message: "Retention policy not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		if policy.Retention <= 0 {
+			return &handlers.AppError{
+				Message: "Retention policy is disabled",
+				Code:    http.StatusConflict,
+			}
+		}
+		middleware.AuditEntity(r.Context(), "retention_policy", policy.Name)
+
+		run, err := purger.Purge(r.Context(), *policy, TriggerManual)
+		if err != nil {
+			return handlers.WrapError(err, "Error purging retention policy", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), run, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,45 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeHandler(t *testing.T) {
+	store := &memoryStore{remaining: 5}
+	policies := []Policy{
+		{Name: "order_creds", Table: "order_creds", TimeColumn: "created_at", Action: ActionAnonymize, Retention: day},
+		{Name: "votes", Table: "vote_drain", TimeColumn: "created_at", Action: ActionDelete},
+	}
+	handler := Purge(newTestPurger(store, 10, 3, time.Now()), policies)
+
+	serve := func(policy string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("policy", policy)
+		rr := httptest.NewRecorder()
+		rr.Header().Set("content-type", "application/json")
+		handler.ServeHTTP(rr, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve("unknown").Code)
+	assert.Equal(t, http.StatusConflict, serve("votes").Code, "disabled policies are not purged")
+	assert.Empty(t, store.runs)
+
+	rr := serve("order_creds")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &run))
+	assert.Equal(t, TriggerManual, run.Trigger)
+	assert.Equal(t, int64(5), run.Rows)
+	assert.Len(t, store.runs, 1, "manual purges are recorded")
+}
@@ -0,0 +1,382 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/leader"
+	"github.com/brave-intl/bat-go/utils/scheduler"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Action is what is done to rows past their retention
+type Action string
+
+const (
+	// ActionDelete deletes expired rows
+	ActionDelete Action = "delete"
+	// ActionAnonymize overwrites the personal data of expired rows, keeping the rest
+	ActionAnonymize Action = "anonymize"
+)
+
+const (
+	day = 24 * time.Hour
+
+	defaultBatchSize  = 1000
+	defaultMaxBatches = 100
+	defaultPause      = 100 * time.Millisecond
+)
+
+var (
+	purgedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_purged_rows_total",
+			Help: "Rows deleted or anonymized by retention policy",
+		},
+		[]string{"policy", "action"},
+	)
+	batchesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "retention_purge_batches",
+			Help: "Batches purged by the latest run of each retention policy",
+		},
+		[]string{"policy"},
+	)
+	caughtUpGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "retention_purge_caught_up",
+			Help: "Whether the latest run of each retention policy purged every expired row, 1, or ran out of batches, 0",
+		},
+		[]string{"policy"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(purgedCounter, batchesGauge, caughtUpGauge)
+}
+
+// Policy is how long the rows of a table are kept and what is done to them after
+type Policy struct {
+	// Name identifies the policy, its retention is configured by RETENTION_<NAME>_DAYS
+	Name string
+	// Table the policy applies to, it must have an id primary key unless Key says otherwise
+	Table string
+	// Key is the comma separated columns identifying a row of the table, id unless set
+	Key string
+	// Join is joined to the table to find expired rows, such as the orders credentials belong to
+	Join string
+	// TimeColumn is the column rows expire by
+	TimeColumn string
+	// Where limits the policy to the rows it may purge, such as votes already rolled up
+	Where  string
+	Action Action
+	// Set is the update anonymizing a row, for ActionAnonymize
+	Set string
+	// Setting is turned on for each purge transaction, for tables which only allow purges to delete
+	Setting string
+	// Retention is how long rows are kept, zero disables the policy
+	Retention time.Duration
+}
+
+// DefaultPolicies are what is purged and after how long, unless configured otherwise
+var DefaultPolicies = []Policy{
+	{
+		Name:       "votes",
+		Table:      "vote_drain",
+		TimeColumn: "created_at",
+		Where:      "processed AND tallied",
+		Action:     ActionDelete,
+		Retention:  90 * day,
+	},
+	{
+		Name:       "votes_v2",
+		Table:      "vote_drain_v2",
+		TimeColumn: "created_at",
+		Where:      "processed",
+		Action:     ActionDelete,
+		Retention:  90 * day,
+	},
+	{
+		Name:       "audit_events",
+		Table:      "audit_events",
+		TimeColumn: "created_at",
+		Action:     ActionDelete,
+		Setting:    "retention.purge",
+		Retention:  400 * day,
+	},
+	{
+		Name:       "deliveries",
+		Table:      "notification_deliveries",
+		TimeColumn: "updated_at",
+		Where:      "status <> 'pending'",
+		Action:     ActionDelete,
+		Retention:  30 * day,
+	},
+	{
+		Name:       "webhook_deliveries",
+		Table:      "webhook_deliveries",
+		TimeColumn: "created_at",
+		Action:     ActionDelete,
+		Retention:  30 * day,
+	},
+	{
+		Name:       "idempotency_keys",
+		Table:      "idempotency_keys",
+		TimeColumn: "created_at",
+		Action:     ActionDelete,
+		Retention:  7 * day,
+	},
+	{
+		Name:  "order_creds",
+		Table: "order_creds",
+		Key:   "item_id",
+		Join:  "INNER JOIN orders ON orders.id = order_creds.order_id",
+		// credentials expire by the completion of their order, unless they are still waiting to be signed
+		// or are time-limited credentials of an order which is still paid or a window which has not ended
+		TimeColumn: "orders.completed_at",
+		Where: "order_creds.purged_at IS NULL" +
+			" AND NOT EXISTS (SELECT 1 FROM order_signing_jobs j WHERE j.item_id = order_creds.item_id AND j.status IN ('pending', 'running'))" +
+			" AND NOT EXISTS (SELECT 1 FROM order_cred_windows w WHERE w.item_id = order_creds.item_id AND (orders.status = 'paid' OR w.valid_to > current_timestamp))",
+		Action:    ActionAnonymize,
+		Set:       "blinded_creds = '{}', signed_creds = CASE WHEN signed_creds IS NULL THEN NULL ELSE '{}' END, purged_at = current_timestamp",
+		Retention: 90 * day,
+	},
+	{
+		Name:       "order_cred_windows",
+		Table:      "order_cred_windows",
+		Key:        "item_id, valid_from",
+		TimeColumn: "valid_to",
+		Where:      "cardinality(signed_creds) > 0",
+		Action:     ActionAnonymize,
+		Set:        "signed_creds = '{}'",
+		Retention:  90 * day,
+	},
+	{
+		Name:       "notifications",
+		Table:      "notifications",
+		TimeColumn: "created_at",
+		Where:      "recipient <> 'redacted'",
+		Action:     ActionAnonymize,
+		Set:        "recipient = 'redacted', error = null",
+		Retention:  90 * day,
+	},
+}
+
+// Trigger is what started a purge
+type Trigger string
+
+const (
+	// TriggerScheduled purges are run by the scheduled job of their policy
+	TriggerScheduled Trigger = "scheduled"
+	// TriggerManual purges are started by an admin
+	TriggerManual Trigger = "manual"
+)
+
+// Run is a purge of a retention policy, as recorded in the audit table of runs
+type Run struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Policy     string    `json:"policy" db:"policy"`
+	Action     Action    `json:"action" db:"action"`
+	Trigger    Trigger   `json:"trigger" db:"trigger"`
+	Actor      string    `json:"actor" db:"actor"`
+	Cutoff     time.Time `json:"cutoff" db:"cutoff"`
+	Rows       int64     `json:"rows" db:"purged_rows"`
+	Batches    int       `json:"batches" db:"batches"`
+	CaughtUp   bool      `json:"caughtUp" db:"caught_up"`
+	Error      *string   `json:"error,omitempty" db:"error"`
+	StartedAt  time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt time.Time `json:"finishedAt" db:"finished_at"`
+}
+
+// Store purges batches of expired rows
+type Store interface {
+	// PurgeBatch deletes or anonymizes up to limit rows of the policy from before the time, returning how many
+	PurgeBatch(ctx context.Context, policy Policy, before time.Time, limit int) (int64, error)
+	// RecordRun appends the run to the audit table of runs
+	RecordRun(ctx context.Context, run *Run) error
+	// ListRuns returns the latest runs, of every policy or of the one given, newest first
+	ListRuns(ctx context.Context, policy string, limit int) ([]Run, error)
+}
+
+// Purger enforces retention policies in bounded batches, so a purge never holds long locks
+type Purger struct {
+	store      Store
+	batchSize  int
+	maxBatches int
+	pause      time.Duration
+	now        func() time.Time
+}
+
+// NewPurger creates a purger which purges up to batchSize rows at a time and up to maxBatches each run
+func NewPurger(store Store, batchSize, maxBatches int) *Purger {
+	return &Purger{store: store, batchSize: batchSize, maxBatches: maxBatches, pause: defaultPause, now: time.Now}
+}
+
+// Run purges the rows of the policy past its retention, until there are none left or it runs out of batches,
+// in which case the next run carries on
+func (p *Purger) Run(ctx context.Context, policy Policy) error {
+	_, err := p.Purge(ctx, policy, TriggerScheduled)
+	return err
+}
+
+// Purge runs the policy like Run and records the run, along with who started it, in the audit table of
+// runs. A policy with no retention is not run and nil is returned
+func (p *Purger) Purge(ctx context.Context, policy Policy, trigger Trigger) (*Run, error) {
+	if policy.Retention <= 0 {
+		return nil, nil
+	}
+	run := &Run{
+		Policy:    policy.Name,
+		Action:    policy.Action,
+		Trigger:   trigger,
+		Actor:     "system",
+		Cutoff:    p.now().Add(-policy.Retention),
+		StartedAt: p.now(),
+	}
+	if actor := middleware.AuditActor(ctx); actor != "" {
+		run.Actor = actor
+	}
+
+	purgeErr := p.purge(ctx, policy, run)
+	if purgeErr != nil {
+		msg := purgeErr.Error()
+		run.Error = &msg
+	}
+	run.FinishedAt = p.now()
+	if err := p.store.RecordRun(ctx, run); err != nil && purgeErr == nil {
+		return run, fmt.Errorf("failed to record purge of %s: %w", policy.Name, err)
+	}
+	return run, purgeErr
+}
+
+// purge deletes or anonymizes the expired rows of the policy in batches, counting them in the run
+func (p *Purger) purge(ctx context.Context, policy Policy, run *Run) error {
+	labels := prometheus.Labels{"policy": policy.Name}
+	for run.Batches < p.maxBatches {
+		n, err := p.store.PurgeBatch(ctx, policy, run.Cutoff, p.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", policy.Name, err)
+		}
+		run.Batches++
+		run.Rows += n
+		purgedCounter.With(prometheus.Labels{"policy": policy.Name, "action": string(policy.Action)}).Add(float64(n))
+		if n < int64(p.batchSize) {
+			run.CaughtUp = true
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.pause):
+		}
+	}
+
+	batchesGauge.With(labels).Set(float64(run.Batches))
+	if run.CaughtUp {
+		caughtUpGauge.With(labels).Set(1)
+	} else {
+		caughtUpGauge.With(labels).Set(0)
+	}
+	if logger, err := appctx.GetLogger(ctx); err == nil {
+		logger.Info().
+			Str("policy", policy.Name).
+			Str("trigger", string(run.Trigger)).
+			Int64("rows", run.Rows).
+			Int("batches", run.Batches).
+			Bool("caughtUp", run.CaughtUp).
+			Msg("retention purge finished")
+	}
+	return nil
+}
+
+// envInt is the integer value of an environment variable, or the fallback when it is unset
+func envInt(name string, fallback int) (int, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return n, nil
+}
+
+// PoliciesFromEnv returns the default policies with their retention overridden by RETENTION_<NAME>_DAYS,
+// zero days disables a policy
+func PoliciesFromEnv() ([]Policy, error) {
+	policies := make([]Policy, len(DefaultPolicies))
+	for i, policy := range DefaultPolicies {
+		days, err := envInt("RETENTION_"+strings.ToUpper(policy.Name)+"_DAYS", int(policy.Retention/day))
+		if err != nil {
+			return nil, err
+		}
+		policy.Retention = time.Duration(days) * day
+		policies[i] = policy
+	}
+	return policies, nil
+}
+
+// PurgerFromEnv creates a purger which purges up to RETENTION_BATCH_SIZE rows at a time and up to
+// RETENTION_MAX_BATCHES each run
+func PurgerFromEnv(store Store) (*Purger, error) {
+	batchSize, err := envInt("RETENTION_BATCH_SIZE", defaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	maxBatches, err := envInt("RETENTION_MAX_BATCHES", defaultMaxBatches)
+	if err != nil {
+		return nil, err
+	}
+	if batchSize == 0 || maxBatches == 0 {
+		return nil, fmt.Errorf("RETENTION_BATCH_SIZE and RETENTION_MAX_BATCHES must be positive")
+	}
+	return NewPurger(store, batchSize, maxBatches), nil
+}
+
+// ScheduledJobs returns a purge job for each enabled retention policy, none unless RETENTION_ENABLED is set.
+// Jobs run daily unless RETENTION_SCHEDULE is set, purging up to RETENTION_BATCH_SIZE rows at a time and
+// up to RETENTION_MAX_BATCHES each run
+func ScheduledJobs(store Store) ([]scheduler.Job, error) {
+	if os.Getenv("RETENTION_ENABLED") == "" {
+		return nil, nil
+	}
+	schedule := os.Getenv("RETENTION_SCHEDULE")
+	if schedule == "" {
+		schedule = "0 3 * * *"
+	}
+	purger, err := PurgerFromEnv(store)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := PoliciesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []scheduler.Job{}
+	for _, policy := range policies {
+		if policy.Retention <= 0 {
+			continue
+		}
+		policy := policy
+		jobs = append(jobs, scheduler.Job{
+			Name:     "retention-" + strings.ReplaceAll(policy.Name, "_", "-"),
+			Schedule: schedule,
+			Jitter:   30 * time.Minute,
+			Family:   leader.FamilySweeps,
+			Func: func(ctx context.Context) error {
+				return purger.Run(ctx, policy)
+			},
+		})
+	}
+	return jobs, nil
+}
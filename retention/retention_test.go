@@ -0,0 +1,148 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	remaining int64
+	batches   []int
+	before    time.Time
+	fail      error
+	runs      []Run
+}
+
+func (s *memoryStore) PurgeBatch(ctx context.Context, policy Policy, before time.Time, limit int) (int64, error) {
+	if s.fail != nil {
+		return 0, s.fail
+	}
+	s.before = before
+	s.batches = append(s.batches, limit)
+	n := int64(limit)
+	if s.remaining < n {
+		n = s.remaining
+	}
+	s.remaining -= n
+	return n, nil
+}
+
+func (s *memoryStore) RecordRun(ctx context.Context, run *Run) error {
+	s.runs = append(s.runs, *run)
+	return nil
+}
+
+func (s *memoryStore) ListRuns(ctx context.Context, policy string, limit int) ([]Run, error) {
+	return s.runs, nil
+}
+
+func newTestPurger(store Store, batchSize, maxBatches int, now time.Time) *Purger {
+	purger := NewPurger(store, batchSize, maxBatches)
+	purger.pause = 0
+	purger.now = func() time.Time { return now }
+	return purger
+}
+
+func TestRunPurgesInBatches(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	store := &memoryStore{remaining: 25}
+	policy := Policy{Name: "votes", Table: "vote_drain", TimeColumn: "created_at", Action: ActionDelete, Retention: 10 * day}
+
+	require.NoError(t, newTestPurger(store, 10, 5, now).Run(context.Background(), policy))
+	assert.Equal(t, []int{10, 10, 10}, store.batches)
+	assert.Equal(t, int64(0), store.remaining)
+	assert.Equal(t, now.Add(-10*day), store.before)
+
+	require.Len(t, store.runs, 1, "every run is recorded")
+	run := store.runs[0]
+	assert.Equal(t, TriggerScheduled, run.Trigger)
+	assert.Equal(t, "system", run.Actor)
+	assert.Equal(t, int64(25), run.Rows)
+	assert.Equal(t, 3, run.Batches)
+	assert.True(t, run.CaughtUp)
+	assert.Nil(t, run.Error)
+}
+
+func TestPurgeRecordsFailedRuns(t *testing.T) {
+	store := &memoryStore{fail: errors.New("deadlock")}
+	policy := Policy{Name: "votes", Table: "vote_drain", TimeColumn: "created_at", Action: ActionDelete, Retention: day}
+
+	run, err := newTestPurger(store, 10, 3, time.Now()).Purge(context.Background(), policy, TriggerManual)
+	assert.Error(t, err)
+	require.Len(t, store.runs, 1)
+	assert.Equal(t, run.Error, store.runs[0].Error)
+	assert.Contains(t, *store.runs[0].Error, "deadlock")
+	assert.Equal(t, TriggerManual, store.runs[0].Trigger)
+}
+
+func TestRunStopsAtMaxBatches(t *testing.T) {
+	store := &memoryStore{remaining: 100}
+	policy := Policy{Name: "votes", Table: "vote_drain", TimeColumn: "created_at", Action: ActionDelete, Retention: day}
+
+	require.NoError(t, newTestPurger(store, 10, 3, time.Now()).Run(context.Background(), policy))
+	assert.Len(t, store.batches, 3)
+	assert.Equal(t, int64(70), store.remaining)
+
+	policy.Retention = 0
+	store.batches = nil
+	require.NoError(t, newTestPurger(store, 10, 3, time.Now()).Run(context.Background(), policy))
+	assert.Empty(t, store.batches)
+}
+
+func TestPurgeStatement(t *testing.T) {
+	assert.Equal(t,
+		`DELETE FROM vote_drain WHERE id IN (SELECT id FROM vote_drain WHERE created_at < $1 AND (processed AND tallied) ORDER BY created_at LIMIT $2 FOR UPDATE SKIP LOCKED)`,
+		purgeStatement(DefaultPolicies[0]))
+
+	var notifications Policy
+	for _, policy := range DefaultPolicies {
+		if policy.Name == "notifications" {
+			notifications = policy
+		}
+	}
+	assert.Equal(t,
+		`UPDATE notifications SET recipient = 'redacted', error = null WHERE id IN (SELECT id FROM notifications WHERE created_at < $1 AND (recipient <> 'redacted') ORDER BY created_at LIMIT $2 FOR UPDATE SKIP LOCKED)`,
+		purgeStatement(notifications))
+
+	policies := map[string]Policy{}
+	for _, policy := range DefaultPolicies {
+		policies[policy.Name] = policy
+	}
+	statement := purgeStatement(policies["order_creds"])
+	assert.Contains(t, statement, `UPDATE order_creds SET blinded_creds = '{}'`)
+	assert.Contains(t, statement, `WHERE item_id IN (SELECT order_creds.item_id FROM order_creds INNER JOIN orders ON orders.id = order_creds.order_id WHERE orders.completed_at < $1 AND (`)
+	assert.Contains(t, statement, `ORDER BY orders.completed_at LIMIT $2 FOR UPDATE OF order_creds SKIP LOCKED)`,
+		"only the credentials are locked")
+	assert.Equal(t,
+		`UPDATE order_cred_windows SET signed_creds = '{}' WHERE (item_id, valid_from) IN (SELECT item_id, valid_from FROM order_cred_windows WHERE valid_to < $1 AND (cardinality(signed_creds) > 0) ORDER BY valid_to LIMIT $2 FOR UPDATE SKIP LOCKED)`,
+		purgeStatement(policies["order_cred_windows"]))
+}
+
+func TestScheduledJobs(t *testing.T) {
+	jobs, err := ScheduledJobs(&memoryStore{})
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	require.NoError(t, os.Setenv("RETENTION_ENABLED", "true"))
+	require.NoError(t, os.Setenv("RETENTION_AUDIT_EVENTS_DAYS", "0"))
+	defer func() {
+		_ = os.Unsetenv("RETENTION_ENABLED")
+		_ = os.Unsetenv("RETENTION_AUDIT_EVENTS_DAYS")
+	}()
+	jobs, err = ScheduledJobs(&memoryStore{})
+	require.NoError(t, err)
+	assert.Len(t, jobs, len(DefaultPolicies)-1)
+	for _, job := range jobs {
+		assert.NotEqual(t, "retention-audit-events", job.Name)
+	}
+
+	require.NoError(t, os.Setenv("RETENTION_AUDIT_EVENTS_DAYS", "forever"))
+	_, err = ScheduledJobs(&memoryStore{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore purges expired rows from the shared database
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// purgeStatement is the statement purging a batch of the policy's expired rows. Table and column names
+// come from the policies in code, never from configuration
+func purgeStatement(policy Policy) string {
+	key, columns, from, lock := "id", "id", policy.Table, "FOR UPDATE"
+	if policy.Key != "" {
+		key, columns = policy.Key, policy.Key
+		if strings.Contains(key, ",") {
+			key = "(" + key + ")"
+		}
+	}
+	if policy.Join != "" {
+		// the key of a joined table is qualified and only the policy's table is locked
+		qualified := strings.Split(columns, ", ")
+		for i := range qualified {
+			qualified[i] = policy.Table + "." + qualified[i]
+		}
+		columns = strings.Join(qualified, ", ")
+		from += " " + policy.Join
+		lock += " OF " + policy.Table
+	}
+
+	where := policy.TimeColumn + " < $1"
+	if policy.Where != "" {
+		where += " AND (" + policy.Where + ")"
+	}
+	batch := fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT $2 %s SKIP LOCKED`,
+		columns, from, where, policy.TimeColumn, lock)
+	if policy.Action == ActionAnonymize {
+		return fmt.Sprintf(`UPDATE %s SET %s WHERE %s IN (%s)`, policy.Table, policy.Set, key, batch)
+	}
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s IN (%s)`, policy.Table, key, batch)
+}
+
+// PurgeBatch deletes or anonymizes up to limit rows of the policy from before the time, returning how many
+func (s *PostgresStore) PurgeBatch(ctx context.Context, policy Policy, before time.Time, limit int) (int64, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if policy.Setting != "" {
+		if _, err := tx.ExecContext(ctx, `SELECT set_config($1, 'on', true)`, policy.Setting); err != nil {
+			return 0, fmt.Errorf("failed to turn on %s: %w", policy.Setting, err)
+		}
+	}
+	result, err := tx.ExecContext(ctx, purgeStatement(policy), before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge batch of %s: %w", policy.Table, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// RecordRun appends the run to the audit table of runs
+func (s *PostgresStore) RecordRun(ctx context.Context, run *Run) error {
+	err := s.db.GetContext(ctx, &run.ID, `
+			INSERT INTO retention_runs (policy, action, trigger, actor, cutoff, purged_rows, batches, caught_up,
+				error, started_at, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id
+		`, run.Policy, run.Action, run.Trigger, run.Actor, run.Cutoff, run.Rows, run.Batches, run.CaughtUp,
+		run.Error, run.StartedAt, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return nil
+}
+
+// ListRuns returns the latest runs, of every policy or of the one given, newest first
+func (s *PostgresStore) ListRuns(ctx context.Context, policy string, limit int) ([]Run, error) {
+	runs := []Run{}
+	err := s.db.SelectContext(ctx, &runs, `
+			SELECT id, policy, action, trigger, actor, cutoff, purged_rows, batches, caught_up, error,
+				started_at, finished_at
+			FROM retention_runs
+			WHERE $1 = '' OR policy = $1
+			ORDER BY started_at DESC
+			LIMIT $2
+		`, policy, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,54 @@
+package settlement
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DryRunReport is what a payout run would do, written instead of transferring any funds
+type DryRunReport struct {
+	Custodian   string    `json:"custodian"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Transactions would be submitted to the custodian
+	Transactions []Transaction `json:"transactions"`
+	// Skipped are already finalized, or could not be matched to a payout
+	Skipped []Transaction `json:"skipped"`
+	// Totals are the amounts which would be transferred, keyed by currency
+	Totals     map[string]decimal.Decimal `json:"totals"`
+	TotalProbi decimal.Decimal            `json:"totalProbi"`
+}
+
+// NewDryRunReport summarizes the transactions a payout run would submit
+func NewDryRunReport(custodian string, transactions []Transaction, skipped []Transaction) *DryRunReport {
+	report := &DryRunReport{
+		Custodian:    custodian,
+		GeneratedAt:  time.Now().UTC(),
+		Transactions: transactions,
+		Skipped:      skipped,
+		Totals:       map[string]decimal.Decimal{},
+		TotalProbi:   decimal.Zero,
+	}
+	if report.Transactions == nil {
+		report.Transactions = []Transaction{}
+	}
+	if report.Skipped == nil {
+		report.Skipped = []Transaction{}
+	}
+	for _, tx := range transactions {
+		report.Totals[tx.Currency] = report.Totals[tx.Currency].Add(tx.Amount)
+		report.TotalProbi = report.TotalProbi.Add(tx.Probi)
+	}
+	return report
+}
+
+// Write writes the report to the path
+func (report *DryRunReport) Write(path string) error {
+	out, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0600)
+}
@@ -0,0 +1,40 @@
+package settlement
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunReport(t *testing.T) {
+	pending := []Transaction{
+		{Channel: "a", Currency: "BAT", Amount: decimal.NewFromFloat(1.5), Probi: decimal.New(15, 17)},
+		{Channel: "b", Currency: "BAT", Amount: decimal.NewFromFloat(2), Probi: decimal.New(2, 18)},
+		{Channel: "c", Currency: "USD", Amount: decimal.NewFromFloat(3), Probi: decimal.New(10, 18)},
+	}
+	report := NewDryRunReport("uphold", pending, nil)
+	assert.Equal(t, "3.5", report.Totals["BAT"].String())
+	assert.Equal(t, "3", report.Totals["USD"].String())
+	assert.True(t, report.TotalProbi.Equal(decimal.New(135, 17)))
+	assert.Empty(t, report.Skipped)
+
+	dir, err := ioutil.TempDir("", "dry-run")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "report.json")
+	require.NoError(t, report.Write(path))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var written DryRunReport
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "uphold", written.Custodian)
+	assert.Len(t, written.Transactions, 3)
+	assert.NotNil(t, written.Skipped, "skipped should be written as an empty list")
+}
@@ -287,12 +287,41 @@ func IterateRequest(
 					logger.Error().Err(err).Msg("falied to check payout transactions status")
 					return nil, err
 				}
+			} else if action == "dryrun" {
+				submittedTransactions = DryRunBulkPayoutTransactions(
+					transactionsMap,
+					submittedTransactions,
+					bulkPayoutRequestRequirements,
+				)
 			}
 		}
 	}
 	return submittedTransactions, nil
 }
 
+// DryRunBulkPayoutTransactions categorizes the payouts of a bulk payout without uploading it, as
+// "pending" when they match a transaction and as "unmatched" otherwise
+func DryRunBulkPayoutTransactions(
+	transactionsMap map[string]settlement.Transaction,
+	submittedTransactions map[string][]settlement.Transaction,
+	bulkPayoutRequestRequirements gemini.PrivateRequestSequence,
+) map[string][]settlement.Transaction {
+	for _, payout := range bulkPayoutRequestRequirements.Base.Payouts {
+		original, ok := transactionsMap[payout.TxRef]
+		if !ok {
+			submittedTransactions["unmatched"] = append(submittedTransactions["unmatched"], settlement.Transaction{
+				Amount:      payout.Amount,
+				Currency:    payout.Currency,
+				Destination: payout.Destination,
+				Note:        "no transaction matches tx_ref " + payout.TxRef,
+			})
+			continue
+		}
+		submittedTransactions["pending"] = append(submittedTransactions["pending"], original)
+	}
+	return submittedTransactions
+}
+
 func geminiComputeTotal(geminiBulkPayoutRequestRequirements []gemini.PrivateRequestSequence) int {
 	if len(geminiBulkPayoutRequestRequirements) == 0 {
 		return 0
@@ -0,0 +1,65 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// DefaultPayoutThreshold is the minimum owed balance, in BAT, which is paid out
+	DefaultPayoutThreshold = decimal.NewFromFloat(1)
+	// DefaultFeeRate is the fraction of contributions withheld as the brave platform fee
+	DefaultFeeRate = decimal.NewFromFloat(0.05)
+
+	// ErrFeesAlreadyApplied - the owed balance already had fees taken from it
+	ErrFeesAlreadyApplied = errors.New("owed balance already has fees applied")
+)
+
+// PayoutOptions controls which owed balances are paid out and the fees taken from them
+type PayoutOptions struct {
+	// Threshold is the minimum owed balance, in BAT, which is paid out
+	Threshold decimal.Decimal
+	// FeeRate is the fraction of each contribution withheld as the platform fee
+	FeeRate decimal.Decimal
+}
+
+// PreparedPayouts are owed balances split by the custodian which pays them out
+type PreparedPayouts struct {
+	// Payouts are keyed by wallet provider
+	Payouts map[string][]AntifraudTransaction
+	// Skipped are balances below the threshold or without a connected custodian
+	Skipped []AntifraudTransaction
+}
+
+// PreparePayouts applies the threshold and fees to owed balances and splits them by custodian.
+// Fees are only taken from contributions, referrals are paid out in full
+func PreparePayouts(owed []AntifraudTransaction, options PayoutOptions) (*PreparedPayouts, error) {
+	if options.FeeRate.IsNegative() || options.FeeRate.GreaterThanOrEqual(decimal.NewFromFloat(1)) {
+		return nil, fmt.Errorf("fee rate must be in [0, 1): %s", options.FeeRate)
+	}
+
+	prepared := PreparedPayouts{
+		Payouts: make(map[string][]AntifraudTransaction),
+	}
+	for _, balance := range owed {
+		if balance.BAT.IsNegative() {
+			return nil, fmt.Errorf("owed balance for %s is negative: %s", balance.Publisher, balance.BAT)
+		}
+		if !balance.Fees.IsZero() {
+			return nil, fmt.Errorf("failed to prepare payout for %s: %w", balance.Publisher, ErrFeesAlreadyApplied)
+		}
+		if balance.WalletProvider == "" || balance.BAT.LessThan(options.Threshold) {
+			prepared.Skipped = append(prepared.Skipped, balance)
+			continue
+		}
+
+		if balance.Type == "contribution" {
+			balance.Fees = balance.BAT.Mul(options.FeeRate).Round(18)
+			balance.BAT = balance.BAT.Sub(balance.Fees)
+		}
+		prepared.Payouts[balance.WalletProvider] = append(prepared.Payouts[balance.WalletProvider], balance)
+	}
+	return &prepared, nil
+}
@@ -0,0 +1,50 @@
+package settlement
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparePayouts(t *testing.T) {
+	owed := []AntifraudTransaction{
+		{Publisher: "a.com", BAT: decimal.NewFromFloat(100), Type: "contribution", WalletProvider: "uphold"},
+		{Publisher: "b.com", BAT: decimal.NewFromFloat(10), Type: "referral", WalletProvider: "uphold"},
+		{Publisher: "c.com", BAT: decimal.NewFromFloat(20), Type: "contribution", WalletProvider: "gemini"},
+		{Publisher: "d.com", BAT: decimal.NewFromFloat(0.5), Type: "contribution", WalletProvider: "gemini"},
+		{Publisher: "e.com", BAT: decimal.NewFromFloat(50), Type: "contribution"},
+	}
+
+	prepared, err := PreparePayouts(owed, PayoutOptions{
+		Threshold: DefaultPayoutThreshold,
+		FeeRate:   DefaultFeeRate,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, prepared.Payouts["uphold"], 2)
+	contribution := prepared.Payouts["uphold"][0]
+	assert.Equal(t, "95", contribution.BAT.String())
+	assert.Equal(t, "5", contribution.Fees.String())
+	referral := prepared.Payouts["uphold"][1]
+	assert.Equal(t, "10", referral.BAT.String(), "referrals should be paid out in full")
+	assert.True(t, referral.Fees.IsZero())
+
+	require.Len(t, prepared.Payouts["gemini"], 1)
+	assert.Equal(t, "19", prepared.Payouts["gemini"][0].BAT.String())
+
+	require.Len(t, prepared.Skipped, 2)
+	assert.Equal(t, "d.com", prepared.Skipped[0].Publisher, "balances below the threshold should be skipped")
+	assert.Equal(t, "e.com", prepared.Skipped[1].Publisher, "balances without a custodian should be skipped")
+	assert.Equal(t, "0.5", prepared.Skipped[0].BAT.String(), "skipped balances should not have fees applied")
+
+	_, err = PreparePayouts([]AntifraudTransaction{
+		{Publisher: "a.com", BAT: decimal.NewFromFloat(95), Fees: decimal.NewFromFloat(5), WalletProvider: "uphold"},
+	}, PayoutOptions{FeeRate: DefaultFeeRate})
+	assert.True(t, errors.Is(err, ErrFeesAlreadyApplied))
+
+	_, err = PreparePayouts(owed, PayoutOptions{FeeRate: decimal.NewFromFloat(1)})
+	assert.Error(t, err)
+}
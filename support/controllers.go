@@ -0,0 +1,134 @@
+package support
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+	uuid "github.com/satori/go.uuid"
+)
+
+// maxErrorsLimit caps how many recent errors are listed at once
+const maxErrorsLimit = 500
+
+// Router for the support console, each route is limited to the roles with its permission
+func Router(service *Service) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/orders/{orderID}", service.authorized(PermOrders, GetOrderHistory(service)))
+	r.Method("GET", "/orders/{orderID}/credentials", service.authorized(PermCredentials, GetOrderCredentials(service)))
+	r.Method("GET", "/wallets/{walletID}", service.authorized(PermWallets, GetWalletOverview(service)))
+	r.Method("GET", "/errors", service.authorized(PermErrors, GetRecentErrors(service)))
+	return r
+}
+
+// GetOrderHistory is the handler for looking up an order and its history, including its credential status
+// when the caller may see it
+func GetOrderHistory(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "orderID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"orderID": "must be a uuid",
+			})
+		}
+
+		withCredentials := roleFromContext(r.Context()).Can(PermCredentials)
+		history, err := service.GetOrderHistory(r.Context(), id, withCredentials)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting order history", http.StatusInternalServerError)
+		}
+		if history == nil {
+			return &handlers.AppError{
+				Message: "Order not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), history, w, http.StatusOK)
+	})
+}
+
+// GetOrderCredentials is the handler for the credential status of each item of an order
+func GetOrderCredentials(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "orderID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"orderID": "must be a uuid",
+			})
+		}
+
+		statuses, err := service.GetOrderCredentials(r.Context(), id)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting credential status", http.StatusInternalServerError)
+		}
+		if statuses == nil {
+			return &handlers.AppError{
+				Message: "Order not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), statuses, w, http.StatusOK)
+	})
+}
+
+// GetWalletOverview is the handler for looking up a wallet with its custodians and claims, PII is redacted
+// unless the caller may see it
+func GetWalletOverview(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		id, err := uuid.FromString(chi.URLParam(r, "walletID"))
+		if err != nil {
+			return handlers.ValidationError("request", map[string]interface{}{
+				"walletID": "must be a uuid",
+			})
+		}
+
+		redact := !roleFromContext(r.Context()).Can(PermPII)
+		overview, err := service.GetWalletOverview(r.Context(), id, redact)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting wallet overview", http.StatusInternalServerError)
+		}
+		if overview == nil {
+			return &handlers.AppError{
+				Message: "Wallet not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+		return handlers.RenderContent(r.Context(), overview, w, http.StatusOK)
+	})
+}
+
+// GetRecentErrors is the handler for listing recent errors, since the last day unless the since query
+// parameter says otherwise
+func GetRecentErrors(service *Service) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		since := time.Now().Add(-DefaultErrorsWindow)
+		if s := r.URL.Query().Get("since"); s != "" {
+			parsed, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return handlers.ValidationError("request", map[string]interface{}{
+					"since": "must be an RFC3339 timestamp",
+				})
+			}
+			since = parsed
+		}
+		limit := 100
+		if l := r.URL.Query().Get("limit"); l != "" {
+			parsed, err := strconv.Atoi(l)
+			if err != nil || parsed < 1 || parsed > maxErrorsLimit {
+				return handlers.ValidationError("request", map[string]interface{}{
+					"limit": "must be a number from 1 to " + strconv.Itoa(maxErrorsLimit),
+				})
+			}
+			limit = parsed
+		}
+
+		redact := !roleFromContext(r.Context()).Can(PermPII)
+		errs, err := service.GetRecentErrors(r.Context(), since, limit, redact)
+		if err != nil {
+			return handlers.WrapError(err, "Error getting recent errors", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), errs, w, http.StatusOK)
+	})
+}
@@ -0,0 +1,126 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/brave-intl/bat-go/middleware"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+)
+
+// Role is the level of access a support token grants
+type Role string
+
+// Permission is what a role allows a support token to look up
+type Permission string
+
+const (
+	// RoleAgent can look up orders, wallets and credentials with PII redacted
+	RoleAgent Role = "agent"
+	// RoleEngineer can also see recent errors
+	RoleEngineer Role = "engineer"
+	// RoleAdmin can see everything, including unredacted PII
+	RoleAdmin Role = "admin"
+)
+
+const (
+	// PermOrders allows looking up orders and their history
+	PermOrders Permission = "orders"
+	// PermWallets allows looking up wallets, their custodians and claims
+	PermWallets Permission = "wallets"
+	// PermCredentials allows looking up the credential status of orders
+	PermCredentials Permission = "credentials"
+	// PermErrors allows listing recent errors
+	PermErrors Permission = "errors"
+	// PermPII allows seeing PII unredacted
+	PermPII Permission = "pii"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAgent:    {PermOrders, PermWallets, PermCredentials},
+	RoleEngineer: {PermOrders, PermWallets, PermCredentials, PermErrors},
+	RoleAdmin:    {PermOrders, PermWallets, PermCredentials, PermErrors, PermPII},
+}
+
+// Can reports whether the role has the permission
+func (role Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type roleKey struct{}
+
+// roleFromContext returns the role the request was authorized with
+func roleFromContext(ctx context.Context) Role {
+	role, _ := ctx.Value(roleKey{}).(Role)
+	return role
+}
+
+// ParseTokens parses a comma separated list of role:token pairs
+func ParseTokens(s string) (map[string]Role, error) {
+	tokens := map[string]Role{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("support token must be of the form role:token")
+		}
+		role := Role(parts[0])
+		if _, ok := rolePermissions[role]; !ok {
+			return nil, fmt.Errorf("unknown support role %s", parts[0])
+		}
+		tokens[parts[1]] = role
+	}
+	return tokens, nil
+}
+
+// TokensFromEnv parses the support tokens of SUPPORT_TOKENS
+func TokensFromEnv() (map[string]Role, error) {
+	return ParseTokens(os.Getenv("SUPPORT_TOKENS"))
+}
+
+// role of the bearer token of the request. Simple tokens are admins, as is everyone when running locally
+func (service *Service) role(ctx context.Context) (Role, bool) {
+	if os.Getenv("ENV") == "local" {
+		return RoleAdmin, true
+	}
+	token := middleware.GetBearerToken(ctx)
+	if token == "" {
+		return "", false
+	}
+	if role, ok := service.tokens[token]; ok {
+		return role, true
+	}
+	if middleware.IsSimpleToken(token) {
+		return RoleAdmin, true
+	}
+	return "", false
+}
+
+// authorized only lets through requests whose role has the permission
+func (service *Service) authorized(permission Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		role, ok := service.role(ctx)
+		if !ok || !role.Can(permission) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if role.Can(PermPII) {
+			if logger, err := appctx.GetLogger(ctx); err == nil {
+				logger.Info().Str("role", string(role)).Str("path", r.URL.Path).Msg("support lookup with pii")
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, roleKey{}, role)))
+	})
+}
@@ -0,0 +1,40 @@
+package support
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redactedPrefix replaces all but the last characters of redacted values
+const redactedPrefix = "****"
+
+// Redact masks all but the last four characters of a value, enough for support to match it against what a
+// user quotes to them. Email addresses keep their first character and domain
+func Redact(value string) string {
+	if value == "" {
+		return value
+	}
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		return value[:1] + redactedPrefix + value[at:]
+	}
+	if len(value) <= 4 {
+		return redactedPrefix
+	}
+	return redactedPrefix + value[len(value)-4:]
+}
+
+var emailPattern = regexp.MustCompile(`[^\s@<>"',;:]+@[^\s@<>"',;:]+`)
+
+// redactEmails masks the email addresses in free text such as error messages
+func redactEmails(text string) string {
+	return emailPattern.ReplaceAllStringFunc(text, Redact)
+}
+
+// redactPtr masks a value if there is one
+func redactPtr(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	redacted := Redact(*value)
+	return &redacted
+}
@@ -0,0 +1,112 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brave-intl/bat-go/notification"
+	"github.com/brave-intl/bat-go/wallet"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Store looks up what the payment and wallet datastores do not expose
+type Store interface {
+	// GetCustodianLinks returns every custodian the wallet was linked to, including disconnected ones
+	GetCustodianLinks(ctx context.Context, walletID uuid.UUID) ([]wallet.CustodianLink, error)
+	// GetClaims returns the claims of the wallet, newest first
+	GetClaims(ctx context.Context, walletID uuid.UUID) ([]Claim, error)
+	// GetOrderWebhooks returns the webhooks queued for the order, oldest first
+	GetOrderWebhooks(ctx context.Context, orderID uuid.UUID) ([]notification.Delivery, error)
+	// GetRecentErrors returns up to limit errors since the time, newest first
+	GetRecentErrors(ctx context.Context, since time.Time, limit int) ([]Error, error)
+}
+
+// PostgresStore looks up support views in the shared database
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// GetCustodianLinks returns every custodian the wallet was linked to, including disconnected ones
+func (s *PostgresStore) GetCustodianLinks(ctx context.Context, walletID uuid.UUID) ([]wallet.CustodianLink, error) {
+	links := []wallet.CustodianLink{}
+	err := s.db.SelectContext(ctx, &links, `
+			SELECT wallet_id, custodian, linking_id, created_at, linked_at, disconnected_at, deposit_destination
+			FROM wallet_custodian
+			WHERE wallet_id = $1
+			ORDER BY linked_at DESC
+		`, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custodian links: %w", err)
+	}
+	return links, nil
+}
+
+// GetClaims returns the claims of the wallet, newest first
+func (s *PostgresStore) GetClaims(ctx context.Context, walletID uuid.UUID) ([]Claim, error) {
+	claims := []Claim{}
+	err := s.db.SelectContext(ctx, &claims, `
+			SELECT id, promotion_id, created_at, approximate_value, bonus, claim_type, redeemed, redeemed_at,
+				drained, drained_at
+			FROM claims
+			WHERE wallet_id = $1
+			ORDER BY created_at DESC
+		`, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get claims: %w", err)
+	}
+	return claims, nil
+}
+
+// GetOrderWebhooks returns the webhooks queued for the order, oldest first
+func (s *PostgresStore) GetOrderWebhooks(ctx context.Context, orderID uuid.UUID) ([]notification.Delivery, error) {
+	deliveries := []notification.Delivery{}
+	err := s.db.SelectContext(ctx, &deliveries, `
+			SELECT id, channel, destination, owner, payload, status, attempts, max_attempts, next_attempt_at,
+				last_error, created_at, updated_at
+			FROM notification_deliveries
+			WHERE channel = $1 AND payload->'body'->>'orderId' = $2
+			ORDER BY created_at
+		`, notification.ChannelWebhook, orderID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order webhooks: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetRecentErrors returns up to limit errors since the time, newest first: erred votes and claim drains,
+// deliveries which failed for good and scheduled jobs whose last run failed
+func (s *PostgresStore) GetRecentErrors(ctx context.Context, since time.Time, limit int) ([]Error, error) {
+	errs := []Error{}
+	err := s.db.SelectContext(ctx, &errs, `
+			SELECT * FROM (
+				SELECT 'vote' AS source, id::text AS id, coalesce(errcode, 'erred') AS message, created_at AS at
+				FROM vote_drain
+				WHERE erred AND created_at >= $1
+				UNION ALL
+				SELECT 'claim_drain', id::text, coalesce(errcode, 'erred'), updated_at
+				FROM claim_drain
+				WHERE erred AND updated_at >= $1
+				UNION ALL
+				SELECT 'delivery', id::text, coalesce(last_error, 'failed'), updated_at
+				FROM notification_deliveries
+				WHERE status = 'failed' AND updated_at >= $1
+				UNION ALL
+				SELECT 'job', name, last_error, last_run_at
+				FROM scheduled_jobs
+				WHERE last_error IS NOT NULL AND last_run_at >= $1
+			) errors
+			ORDER BY at DESC
+			LIMIT $2
+		`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent errors: %w", err)
+	}
+	return errs, nil
+}
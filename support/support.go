@@ -0,0 +1,297 @@
+package support
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/brave-intl/bat-go/notification"
+	"github.com/brave-intl/bat-go/payment"
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultErrorsWindow is how far back recent errors are listed by default
+const DefaultErrorsWindow = 24 * time.Hour
+
+// Claim is a grant claimed by a wallet
+type Claim struct {
+	ID               uuid.UUID       `json:"id" db:"id"`
+	PromotionID      uuid.UUID       `json:"promotionId" db:"promotion_id"`
+	CreatedAt        time.Time       `json:"createdAt" db:"created_at"`
+	ApproximateValue decimal.Decimal `json:"approximateValue" db:"approximate_value"`
+	Bonus            decimal.Decimal `json:"bonus" db:"bonus"`
+	Type             *string         `json:"type" db:"claim_type"`
+	Redeemed         bool            `json:"redeemed" db:"redeemed"`
+	RedeemedAt       *time.Time      `json:"redeemedAt" db:"redeemed_at"`
+	Drained          bool            `json:"drained" db:"drained"`
+	DrainedAt        *time.Time      `json:"drainedAt" db:"drained_at"`
+}
+
+// Error is a recent failure support may be asked about
+type Error struct {
+	Source  string    `json:"source" db:"source"`
+	ID      string    `json:"id" db:"id"`
+	Message string    `json:"message" db:"message"`
+	At      time.Time `json:"at" db:"at"`
+}
+
+// CredentialStatus is how far the credentials of an order item got
+type CredentialStatus struct {
+	ItemID       uuid.UUID `json:"itemId"`
+	SKU          string    `json:"sku"`
+	IssuerID     uuid.UUID `json:"issuerId"`
+	BlindedCount int       `json:"blindedCount"`
+	SignedCount  int       `json:"signedCount"`
+	Signed       bool      `json:"signed"`
+}
+
+// Event is an entry in the history of an order
+type Event struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Status string    `json:"status"`
+	ID     uuid.UUID `json:"id"`
+}
+
+// OrderHistory is an order along with everything that happened to it
+type OrderHistory struct {
+	Order        payment.Order           `json:"order"`
+	Transactions []payment.Transaction   `json:"transactions"`
+	Webhooks     []notification.Delivery `json:"webhooks"`
+	Credentials  []CredentialStatus      `json:"credentials,omitempty"`
+	History      []Event                 `json:"history"`
+}
+
+// Wallet is a wallet with its PII redacted unless the caller may see it
+type Wallet struct {
+	ID                     string  `json:"paymentId"`
+	Provider               string  `json:"provider"`
+	ProviderID             string  `json:"providerId"`
+	PublicKey              string  `json:"publicKey"`
+	ProviderLinkingID      *string `json:"providerLinkingId"`
+	AnonymousAddress       *string `json:"anonymousAddress"`
+	UserDepositProvider    *string `json:"userDepositAccountProvider"`
+	UserDepositDestination string  `json:"userDepositCardId"`
+}
+
+// Custodian is a custodian the wallet was linked to
+type Custodian struct {
+	Custodian          string     `json:"custodian"`
+	LinkingID          *string    `json:"linkingId"`
+	DepositDestination string     `json:"depositDestination"`
+	LinkedAt           time.Time  `json:"linkedAt"`
+	DisconnectedAt     *time.Time `json:"disconnectedAt"`
+}
+
+// WalletOverview is a wallet along with its custodians and claims
+type WalletOverview struct {
+	Wallet     Wallet      `json:"wallet"`
+	Custodians []Custodian `json:"custodians"`
+	Claims     []Claim     `json:"claims"`
+	Redacted   bool        `json:"redacted"`
+}
+
+// WalletGetter looks up wallets, it is implemented by the wallet service
+type WalletGetter interface {
+	GetWallet(ctx context.Context, ID uuid.UUID) (*walletutils.Info, error)
+}
+
+// Service backs the support console, looking up orders, wallets and errors on behalf of support staff
+type Service struct {
+	payment payment.Datastore
+	wallets WalletGetter
+	store   Store
+	tokens  map[string]Role
+}
+
+// New creates a support service, tokens maps support bearer tokens to their roles
+func New(paymentDatastore payment.Datastore, wallets WalletGetter, store Store, tokens map[string]Role) *Service {
+	return &Service{payment: paymentDatastore, wallets: wallets, store: store, tokens: tokens}
+}
+
+// InitService creates a support service on the shared database, with the support tokens of SUPPORT_TOKENS
+func InitService(paymentDatastore payment.Datastore, wallets WalletGetter, db *sqlx.DB) (*Service, error) {
+	tokens, err := TokensFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return New(paymentDatastore, wallets, NewPostgresStore(db), tokens), nil
+}
+
+// GetOrderHistory returns an order with its transactions and webhooks, and its credentials when they are
+// asked for, or nil if there is no such order
+func (service *Service) GetOrderHistory(ctx context.Context, orderID uuid.UUID, withCredentials bool) (*OrderHistory, error) {
+	order, err := service.payment.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	history := OrderHistory{Order: *order}
+
+	transactions, err := service.payment.GetTransactions(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if transactions != nil {
+		history.Transactions = *transactions
+	}
+	history.Webhooks, err = service.store.GetOrderWebhooks(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if withCredentials {
+		history.Credentials, err = service.GetCredentialStatus(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := service.payment.GetOrderEvents(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		history.History = append(history.History, Event{At: event.CreatedAt, Kind: "order", Status: event.Type, ID: event.ID})
+	}
+	for _, transaction := range history.Transactions {
+		history.History = append(history.History, Event{At: transaction.CreatedAt, Kind: "transaction", Status: transaction.Status, ID: transaction.ID})
+	}
+	for _, webhook := range history.Webhooks {
+		history.History = append(history.History, Event{At: webhook.UpdatedAt, Kind: "webhook", Status: webhook.Status, ID: webhook.ID})
+	}
+	sort.SliceStable(history.History, func(i, j int) bool {
+		return history.History[i].At.Before(history.History[j].At)
+	})
+	if history.Transactions == nil {
+		history.Transactions = []payment.Transaction{}
+	}
+	return &history, nil
+}
+
+// GetCredentialStatus returns how far the credentials of each item of the order got
+func (service *Service) GetCredentialStatus(ctx context.Context, order *payment.Order) ([]CredentialStatus, error) {
+	creds, err := service.payment.GetOrderCreds(order.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	skus := map[string]string{}
+	for _, item := range order.Items {
+		skus[item.ID.String()] = item.SKU
+	}
+
+	statuses := []CredentialStatus{}
+	if creds == nil {
+		return statuses, nil
+	}
+	for _, cred := range *creds {
+		status := CredentialStatus{
+			ItemID:       cred.ID,
+			SKU:          skus[cred.ID.String()],
+			IssuerID:     cred.IssuerID,
+			BlindedCount: len(cred.BlindedCreds),
+		}
+		if cred.SignedCreds != nil {
+			status.SignedCount = len(*cred.SignedCreds)
+			status.Signed = true
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// GetOrderCredentials returns the credential status of an order, or nil if there is no such order
+func (service *Service) GetOrderCredentials(ctx context.Context, orderID uuid.UUID) ([]CredentialStatus, error) {
+	order, err := service.payment.GetOrder(orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	return service.GetCredentialStatus(ctx, order)
+}
+
+// GetWalletOverview returns a wallet with its custodians and claims, redacting its PII unless asked not to,
+// or nil if there is no such wallet
+func (service *Service) GetWalletOverview(ctx context.Context, walletID uuid.UUID, redact bool) (*WalletOverview, error) {
+	info, err := service.wallets.GetWallet(ctx, walletID)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	links, err := service.store.GetCustodianLinks(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := service.store.GetClaims(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := WalletOverview{
+		Wallet: Wallet{
+			ID:                     info.ID,
+			Provider:               info.Provider,
+			ProviderID:             info.ProviderID,
+			PublicKey:              info.PublicKey,
+			UserDepositProvider:    info.UserDepositAccountProvider,
+			UserDepositDestination: info.UserDepositDestination,
+		},
+		Custodians: []Custodian{},
+		Claims:     claims,
+		Redacted:   redact,
+	}
+	if info.ProviderLinkingID != nil {
+		id := info.ProviderLinkingID.String()
+		overview.Wallet.ProviderLinkingID = &id
+	}
+	if info.AnonymousAddress != nil {
+		address := info.AnonymousAddress.String()
+		overview.Wallet.AnonymousAddress = &address
+	}
+	for _, link := range links {
+		custodian := Custodian{
+			Custodian:          link.Custodian,
+			DepositDestination: link.DepositDestination,
+			LinkedAt:           link.LinkedAt,
+		}
+		if link.LinkingID != nil {
+			id := link.LinkingID.String()
+			custodian.LinkingID = &id
+		}
+		if link.DisconnectedAt.Valid {
+			custodian.DisconnectedAt = &link.DisconnectedAt.Time
+		}
+		overview.Custodians = append(overview.Custodians, custodian)
+	}
+
+	if redact {
+		overview.redact()
+	}
+	return &overview, nil
+}
+
+// redact the PII of the wallet and its custodians
+func (overview *WalletOverview) redact() {
+	overview.Wallet.ProviderID = Redact(overview.Wallet.ProviderID)
+	overview.Wallet.ProviderLinkingID = redactPtr(overview.Wallet.ProviderLinkingID)
+	overview.Wallet.AnonymousAddress = redactPtr(overview.Wallet.AnonymousAddress)
+	overview.Wallet.UserDepositDestination = Redact(overview.Wallet.UserDepositDestination)
+	for i := range overview.Custodians {
+		overview.Custodians[i].LinkingID = redactPtr(overview.Custodians[i].LinkingID)
+		overview.Custodians[i].DepositDestination = Redact(overview.Custodians[i].DepositDestination)
+	}
+}
+
+// GetRecentErrors returns up to limit errors since the time, newest first, redacting any email addresses
+// in them unless asked not to
+func (service *Service) GetRecentErrors(ctx context.Context, since time.Time, limit int, redact bool) ([]Error, error) {
+	errs, err := service.store.GetRecentErrors(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	if redact {
+		for i := range errs {
+			errs[i].Message = redactEmails(errs[i].Message)
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,214 @@
+package support
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/middleware"
+	"github.com/brave-intl/bat-go/notification"
+	"github.com/brave-intl/bat-go/payment"
+	"github.com/brave-intl/bat-go/utils/jsonutils"
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/brave-intl/bat-go/wallet"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type paymentDatastore struct {
+	payment.Datastore
+	order *payment.Order
+	creds []payment.OrderCreds
+}
+
+func (ds *paymentDatastore) GetOrder(orderID uuid.UUID) (*payment.Order, error) {
+	if ds.order == nil || !uuid.Equal(ds.order.ID, orderID) {
+		return nil, nil
+	}
+	return ds.order, nil
+}
+
+func (ds *paymentDatastore) GetTransactions(orderID uuid.UUID) (*[]payment.Transaction, error) {
+	return &[]payment.Transaction{{
+		ID:        uuid.NewV4(),
+		OrderID:   orderID,
+		CreatedAt: ds.order.CreatedAt.Add(time.Minute),
+		Status:    "completed",
+	}}, nil
+}
+
+func (ds *paymentDatastore) GetOrderEvents(ctx context.Context, orderID uuid.UUID) ([]payment.OrderLogEvent, error) {
+	return []payment.OrderLogEvent{
+		{ID: uuid.NewV4(), OrderID: orderID, Sequence: 1, Type: payment.OrderLogCreated, CreatedAt: ds.order.CreatedAt},
+		{ID: uuid.NewV4(), OrderID: orderID, Sequence: 2, Type: payment.OrderLogPaid, CreatedAt: ds.order.UpdatedAt},
+	}, nil
+}
+
+func (ds *paymentDatastore) GetOrderCreds(orderID uuid.UUID, isSigned bool) (*[]payment.OrderCreds, error) {
+	return &ds.creds, nil
+}
+
+type walletGetter struct {
+	info *walletutils.Info
+}
+
+func (g *walletGetter) GetWallet(ctx context.Context, ID uuid.UUID) (*walletutils.Info, error) {
+	if g.info == nil || g.info.ID != ID.String() {
+		return nil, nil
+	}
+	return g.info, nil
+}
+
+type memoryStore struct {
+	links  []wallet.CustodianLink
+	errors []Error
+}
+
+func (s *memoryStore) GetCustodianLinks(ctx context.Context, walletID uuid.UUID) ([]wallet.CustodianLink, error) {
+	return s.links, nil
+}
+
+func (s *memoryStore) GetClaims(ctx context.Context, walletID uuid.UUID) ([]Claim, error) {
+	return []Claim{}, nil
+}
+
+func (s *memoryStore) GetOrderWebhooks(ctx context.Context, orderID uuid.UUID) ([]notification.Delivery, error) {
+	return []notification.Delivery{}, nil
+}
+
+func (s *memoryStore) GetRecentErrors(ctx context.Context, since time.Time, limit int) ([]Error, error) {
+	errs := make([]Error, len(s.errors))
+	copy(errs, s.errors)
+	return errs, nil
+}
+
+func newTestService(t *testing.T) (*Service, *paymentDatastore, *walletGetter) {
+	env := os.Getenv("ENV")
+	require.NoError(t, os.Setenv("ENV", "test"))
+	t.Cleanup(func() { _ = os.Setenv("ENV", env) })
+	created := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	itemID := uuid.NewV4()
+	signed := jsonutils.TextArray{"a", "b"}
+	ds := &paymentDatastore{
+		order: &payment.Order{
+			ID:        uuid.NewV4(),
+			CreatedAt: created,
+			UpdatedAt: created.Add(2 * time.Minute),
+			Status:    "paid",
+			Items:     []payment.OrderItem{{ID: itemID, SKU: "brave-vpn"}},
+		},
+		creds: []payment.OrderCreds{{ID: itemID, BlindedCreds: jsonutils.TextArray{"a", "b"}, SignedCreds: &signed}},
+	}
+	linkingID := uuid.NewV4()
+	wallets := &walletGetter{info: &walletutils.Info{
+		ID:                     uuid.NewV4().String(),
+		Provider:               "uphold",
+		ProviderID:             "provider-id-1234",
+		UserDepositDestination: "deposit-5678",
+		ProviderLinkingID:      &linkingID,
+	}}
+	store := &memoryStore{
+		links:  []wallet.CustodianLink{{Custodian: "uphold", DepositDestination: "deposit-5678", LinkingID: &linkingID}},
+		errors: []Error{{Source: "delivery", ID: "1", Message: "bounced for user@example.com"}},
+	}
+	tokens, err := ParseTokens("agent:agent-token,engineer:engineer-token,admin:admin-token")
+	require.NoError(t, err)
+	return New(ds, wallets, store, tokens), ds, wallets
+}
+
+func get(t *testing.T, service *Service, token, path string, v interface{}) int {
+	req := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	middleware.BearerToken(Router(service)).ServeHTTP(rr, req)
+	if v != nil && rr.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), v))
+	}
+	return rr.Code
+}
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := ParseTokens(" agent:a, admin:b ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Role{"a": RoleAgent, "b": RoleAdmin}, tokens)
+
+	_, err = ParseTokens("owner:a")
+	assert.Error(t, err)
+	_, err = ParseTokens("agent")
+	assert.Error(t, err)
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "****1234", Redact("provider-id-1234"))
+	assert.Equal(t, "****", Redact("1234"))
+	assert.Equal(t, "u****@example.com", Redact("user@example.com"))
+	assert.Equal(t, "", Redact(""))
+	assert.Equal(t, "sent to u****@example.com failed", redactEmails("sent to user@example.com failed"))
+}
+
+func TestAuthorization(t *testing.T) {
+	service, _, _ := newTestService(t)
+
+	assert.Equal(t, http.StatusForbidden, get(t, service, "", "/errors", nil))
+	assert.Equal(t, http.StatusForbidden, get(t, service, "unknown", "/errors", nil))
+	assert.Equal(t, http.StatusForbidden, get(t, service, "agent-token", "/errors", nil))
+	assert.Equal(t, http.StatusOK, get(t, service, "engineer-token", "/errors", nil))
+	assert.Equal(t, http.StatusBadRequest, get(t, service, "engineer-token", "/errors?limit=0", nil))
+}
+
+func TestGetOrderHistory(t *testing.T) {
+	service, ds, _ := newTestService(t)
+
+	var history OrderHistory
+	require.Equal(t, http.StatusOK, get(t, service, "agent-token", "/orders/"+ds.order.ID.String(), &history))
+	assert.Len(t, history.Transactions, 1)
+	require.Len(t, history.History, 3)
+	assert.Equal(t, "created", history.History[0].Status)
+	assert.Equal(t, "transaction", history.History[1].Kind)
+	assert.Equal(t, "paid", history.History[2].Status)
+	require.Len(t, history.Credentials, 1)
+	assert.Equal(t, "brave-vpn", history.Credentials[0].SKU)
+	assert.True(t, history.Credentials[0].Signed)
+	assert.Equal(t, 2, history.Credentials[0].SignedCount)
+
+	assert.Equal(t, http.StatusNotFound, get(t, service, "agent-token", "/orders/"+uuid.NewV4().String(), nil))
+	assert.Equal(t, http.StatusBadRequest, get(t, service, "agent-token", "/orders/nope", nil))
+}
+
+func TestGetWalletOverviewRedacts(t *testing.T) {
+	service, _, wallets := newTestService(t)
+	path := "/wallets/" + wallets.info.ID
+
+	var overview WalletOverview
+	require.Equal(t, http.StatusOK, get(t, service, "agent-token", path, &overview))
+	assert.True(t, overview.Redacted)
+	assert.Equal(t, "****1234", overview.Wallet.ProviderID)
+	assert.Equal(t, "****5678", overview.Wallet.UserDepositDestination)
+	require.Len(t, overview.Custodians, 1)
+	assert.Equal(t, "****5678", overview.Custodians[0].DepositDestination)
+	assert.NotEqual(t, wallets.info.ProviderLinkingID.String(), *overview.Custodians[0].LinkingID)
+
+	overview = WalletOverview{}
+	require.Equal(t, http.StatusOK, get(t, service, "admin-token", path, &overview))
+	assert.False(t, overview.Redacted)
+	assert.Equal(t, "provider-id-1234", overview.Wallet.ProviderID)
+	assert.Equal(t, wallets.info.ProviderLinkingID.String(), *overview.Custodians[0].LinkingID)
+}
+
+func TestGetRecentErrorsRedacts(t *testing.T) {
+	service, _, _ := newTestService(t)
+
+	var errs []Error
+	require.Equal(t, http.StatusOK, get(t, service, "engineer-token", "/errors", &errs))
+	assert.Equal(t, "bounced for u****@example.com", errs[0].Message)
+
+	require.Equal(t, http.StatusOK, get(t, service, "admin-token", "/errors", &errs))
+	assert.Equal(t, "bounced for user@example.com", errs[0].Message)
+}
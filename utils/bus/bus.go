@@ -0,0 +1,133 @@
+// Package bus carries the messages of a topic over kafka or, for deployments without kafka, AWS SQS
+// and SNS. Messages are kafka messages whichever bus carries them, so producers and topic handlers are
+// the same on every bus
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+const (
+	// Kafka carries the topic over the kafka brokers in KAFKA_BROKERS, the default
+	Kafka = "kafka"
+	// SQS carries the topic over an SQS queue
+	SQS = "sqs"
+	// SNS publishes the topic to an SNS topic, consumed from an SQS queue subscribed to it
+	SNS = "sns"
+)
+
+// ErrUnknownBus - the bus configured for a topic is not one of kafka, sqs or sns
+var ErrUnknownBus = errors.New("unknown message bus")
+
+// Producer writes messages to a topic
+type Producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Handler handles a message consumed from a topic, a message it fails is quarantined
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+// Consumer passes the messages of a topic to a handler until the context is done
+type Consumer interface {
+	Consume(ctx context.Context, handler Handler) error
+	Close() error
+}
+
+// Config is the bus carrying a topic
+type Config struct {
+	Topic string
+	Bus   string
+	// Destination is the queue url of sqs, or the topic arn of sns
+	Destination string
+	// Queue is the url of the sqs queue the topic is consumed from, the destination for sqs
+	Queue string
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// EnvKey is the environment variable configuring the bus of a topic. The environment prefix of the
+// topic is dropped, so production.payment.vote is configured by BUS_PAYMENT_VOTE
+func EnvKey(topic string) string {
+	if env := os.Getenv("ENV"); env != "" {
+		topic = strings.TrimPrefix(topic, env+".")
+	}
+	return "BUS_" + nonAlphanumeric.ReplaceAllString(strings.ToUpper(topic), "_")
+}
+
+// ConfigFromEnv reads the bus of a topic from its environment variable, one of kafka, sqs:<queue url>
+// or sns:<topic arn>. Topics with no variable are carried by kafka. Consumers of sns topics read from
+// the queue in the variable suffixed _QUEUE
+func ConfigFromEnv(topic string) (Config, error) {
+	key := EnvKey(topic)
+	cfg := Config{Topic: topic, Bus: Kafka}
+	value := os.Getenv(key)
+	if value == "" || value == Kafka {
+		return cfg, nil
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return cfg, fmt.Errorf("%s must be kafka, sqs:<queue url> or sns:<topic arn>, got %q", key, value)
+	}
+	cfg.Bus, cfg.Destination = parts[0], parts[1]
+	switch cfg.Bus {
+	case SQS:
+		cfg.Queue = cfg.Destination
+	case SNS:
+		cfg.Queue = os.Getenv(key + "_QUEUE")
+	default:
+		return cfg, fmt.Errorf("%s: %w %s", key, ErrUnknownBus, cfg.Bus)
+	}
+	return cfg, nil
+}
+
+// NewProducer creates a producer for the topic on its configured bus. Kafka producers need the
+// brokers on the context
+func NewProducer(ctx context.Context, topic string) (Producer, error) {
+	cfg, err := ConfigFromEnv(topic)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Bus {
+	case SQS:
+		client, err := NewAWSClient()
+		if err != nil {
+			return nil, err
+		}
+		return &SQSProducer{client: client, topic: topic, queueURL: cfg.Destination}, nil
+	case SNS:
+		client, err := NewAWSClient()
+		if err != nil {
+			return nil, err
+		}
+		return &SNSProducer{client: client, topic: topic, topicARN: cfg.Destination}, nil
+	}
+	return NewKafkaProducer(ctx, topic)
+}
+
+// NewConsumer creates a consumer of the topic on its configured bus, kafka consumers join the group
+func NewConsumer(ctx context.Context, topic string, group string) (Consumer, error) {
+	cfg, err := ConfigFromEnv(topic)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Bus == Kafka {
+		return NewKafkaConsumer(ctx, topic, group)
+	}
+	if cfg.Queue == "" {
+		return nil, fmt.Errorf("%s_QUEUE must be set to consume %s from sns", EnvKey(topic), topic)
+	}
+	client, err := NewAWSClient()
+	if err != nil {
+		return nil, err
+	}
+	return &SQSConsumer{client: client, topic: topic, queueURL: cfg.Queue}, nil
+}
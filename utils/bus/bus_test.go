@@ -0,0 +1,200 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/s3"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setEnv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		require.NoError(t, os.Setenv(k, v))
+	}
+	t.Cleanup(func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	})
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		"ENV":                        "test",
+		"BUS_PAYMENT_VOTE":           "sqs:https://sqs.us-west-2.amazonaws.com/1/votes",
+		"BUS_GRANT_SUGGESTION":       "sns:arn:aws:sns:us-west-2:1:suggestions",
+		"BUS_GRANT_SUGGESTION_QUEUE": "https://sqs.us-west-2.amazonaws.com/1/suggestions",
+		"BUS_BROKEN":                 "rabbitmq:amqp://localhost",
+	})
+
+	assert.Equal(t, "BUS_PAYMENT_VOTE", EnvKey("test.payment.vote"))
+
+	cfg, err := ConfigFromEnv("test.payment.vote")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Topic:       "test.payment.vote",
+		Bus:         SQS,
+		Destination: "https://sqs.us-west-2.amazonaws.com/1/votes",
+		Queue:       "https://sqs.us-west-2.amazonaws.com/1/votes",
+	}, cfg)
+
+	cfg, err = ConfigFromEnv("test.grant.suggestion")
+	require.NoError(t, err)
+	assert.Equal(t, SNS, cfg.Bus)
+	assert.Equal(t, "arn:aws:sns:us-west-2:1:suggestions", cfg.Destination)
+	assert.Equal(t, "https://sqs.us-west-2.amazonaws.com/1/suggestions", cfg.Queue)
+
+	cfg, err = ConfigFromEnv("test.other")
+	require.NoError(t, err)
+	assert.Equal(t, Kafka, cfg.Bus)
+
+	_, err = ConfigFromEnv("broken")
+	assert.True(t, errors.Is(err, ErrUnknownBus))
+}
+
+// fakeAWS is a queue served over the sqs query api, which sns publishes to with raw message delivery off
+type fakeAWS struct {
+	mu       sync.Mutex
+	messages map[string]string
+	next     int
+	deleted  []string
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	_ = r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Form.Get("Action") {
+	case "SendMessage":
+		f.next++
+		f.messages[fmt.Sprint(f.next)] = r.Form.Get("MessageBody")
+	case "Publish":
+		f.next++
+		notification, _ := json.Marshal(snsNotification{Type: "Notification", Message: r.Form.Get("Message")})
+		f.messages[fmt.Sprint(f.next)] = string(notification)
+	case "ReceiveMessage":
+		body := "<ReceiveMessageResponse><ReceiveMessageResult>"
+		handles := make([]string, 0, len(f.messages))
+		for handle := range f.messages {
+			handles = append(handles, handle)
+		}
+		sort.Strings(handles)
+		for _, handle := range handles {
+			message := f.messages[handle]
+			body += fmt.Sprintf(`<Message><MessageId>%s</MessageId><ReceiptHandle>%s</ReceiptHandle>`+
+				`<Body>%s</Body><Attribute><Name>ApproximateReceiveCount</Name><Value>1</Value></Attribute></Message>`,
+				handle, handle, strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(message))
+		}
+		body += "</ReceiveMessageResult></ReceiveMessageResponse>"
+		_, _ = w.Write([]byte(body))
+	case "DeleteMessage":
+		handle := r.Form.Get("ReceiptHandle")
+		delete(f.messages, handle)
+		f.deleted = append(f.deleted, handle)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestSQSAndSNS(t *testing.T) {
+	fake := &fakeAWS{messages: map[string]string{}}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	client := &AWSClient{
+		SNSEndpoint: ts.URL,
+		Region:      "us-west-2",
+		Credentials: s3.Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+		client:      ts.Client(),
+		now:         time.Now,
+	}
+	ctx := context.Background()
+
+	sqs := &SQSProducer{client: client, topic: "test.payment.vote", queueURL: ts.URL + "/1/votes"}
+	require.NoError(t, sqs.WriteMessages(ctx, kafka.Message{
+		Key:     []byte("key"),
+		Value:   []byte{0x00, 0xff, 0x10},
+		Headers: []kafka.Header{{Key: "x-request-id", Value: []byte("abc")}},
+	}))
+	sns := &SNSProducer{client: client, topic: "test.payment.vote", topicARN: "arn:aws:sns:us-west-2:1:votes"}
+	require.NoError(t, sns.WriteMessages(ctx, kafka.Message{Value: []byte("published")}))
+
+	consumer := &SQSConsumer{client: client, topic: "test.payment.vote", queueURL: ts.URL + "/1/votes"}
+	// the sent message is received first, then the published one, which fails and stops consuming
+	ctx, cancel := context.WithCancel(ctx)
+	var handled []kafka.Message
+	err := consumer.Consume(ctx, func(ctx context.Context, msg kafka.Message) error {
+		handled = append(handled, msg)
+		if len(handled) == 2 {
+			cancel()
+		}
+		if string(msg.Value) == "published" {
+			return errors.New("left on the queue")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, handled, 2)
+
+	for _, msg := range handled {
+		assert.Equal(t, "test.payment.vote", msg.Topic)
+		if string(msg.Value) != "published" {
+			assert.Equal(t, []byte{0x00, 0xff, 0x10}, msg.Value)
+			assert.Equal(t, []byte("key"), msg.Key)
+			require.Len(t, msg.Headers, 2)
+			assert.Equal(t, kafka.Header{Key: "x-request-id", Value: []byte("abc")}, msg.Headers[0])
+			assert.Equal(t, "traceparent", msg.Headers[1].Key)
+		}
+	}
+	// the failed message is not deleted, so it is received again
+	assert.Len(t, fake.deleted, 1)
+	assert.Len(t, fake.messages, 1)
+}
+
+func TestSQSConsumeDrains(t *testing.T) {
+	fake := &fakeAWS{messages: map[string]string{}}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	client := &AWSClient{
+		SNSEndpoint: ts.URL,
+		Region:      "us-west-2",
+		Credentials: s3.Credentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+		client:      ts.Client(),
+		now:         time.Now,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	producer := &SQSProducer{client: client, topic: "test.payment.vote", queueURL: ts.URL + "/1/votes"}
+	require.NoError(t, producer.WriteMessages(ctx, kafka.Message{Value: []byte("first")}, kafka.Message{Value: []byte("second")}))
+
+	// shutdown begins while the first message is handled
+	consumer := &SQSConsumer{client: client, topic: "test.payment.vote", queueURL: ts.URL + "/1/votes"}
+	var handled []string
+	err := consumer.Consume(ctx, func(ctx context.Context, msg kafka.Message) error {
+		handled = append(handled, string(msg.Value))
+		cancel()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first"}, handled, "the rest of the batch is not handled once shutdown begins")
+	assert.Len(t, fake.deleted, 1, "the message being handled finishes and is deleted")
+	assert.Len(t, fake.messages, 1)
+}
@@ -0,0 +1,110 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/faults"
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaProducer writes a topic to kafka
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaProducer creates a producer writing to the topic on the brokers of the context
+func NewKafkaProducer(ctx context.Context, topic string) (*KafkaProducer, error) {
+	writer, _, err := kafkautils.InitKafkaWriter(ctx, topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize kafka: %w", err)
+	}
+	return &KafkaProducer{writer: writer}, nil
+}
+
+// WriteMessages writes the messages within a producer span, with the request id and trace context
+func (p *KafkaProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return kafkautils.WriteMessages(ctx, p.writer, msgs...)
+}
+
+// Close the writer, flushing pending messages
+func (p *KafkaProducer) Close() error {
+	return p.writer.Close()
+}
+
+// KafkaConsumer consumes a topic from kafka as part of a consumer group. Messages the handler fails are
+// quarantined to the topic's dead letter topic, so they do not block the partition
+type KafkaConsumer struct {
+	reader *kafka.Reader
+	dlq    *kafka.Writer
+}
+
+// NewKafkaConsumer creates a consumer of the topic on the brokers of the context
+func NewKafkaConsumer(ctx context.Context, topic string, group string) (*KafkaConsumer, error) {
+	dialer, _, err := kafkautils.TLSDialer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize kafka: %w", err)
+	}
+	brokers, _ := ctx.Value(appctx.KafkaBrokersCTXKey).(string)
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: strings.Split(brokers, ","),
+		GroupID: group,
+		Topic:   topic,
+		Dialer:  dialer,
+	})
+	dlq := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:  strings.Split(brokers, ","),
+		Topic:    kafkautils.DeadLetterTopic(topic),
+		Balancer: &kafka.LeastBytes{},
+		Dialer:   dialer,
+	})
+	return &KafkaConsumer{reader: reader, dlq: dlq}, nil
+}
+
+// Reader is the underlying kafka reader, for collecting its stats
+func (c *KafkaConsumer) Reader() *kafka.Reader {
+	return c.reader
+}
+
+// Consume passes each message to the handler, committing it once handled or quarantined. Once the context
+// is done no more messages are fetched, the message being handled is still handled and committed
+func (c *KafkaConsumer) Consume(ctx context.Context, handler Handler) error {
+	handleCtx := srv.Detach(ctx)
+	for {
+		err := faults.Inject(ctx, faults.Kafka)
+		var msg kafka.Message
+		if err == nil {
+			msg, err = c.reader.FetchMessage(ctx)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		msgCtx, span := kafkautils.StartConsumerSpan(handleCtx, msg)
+		err = handler(msgCtx, msg)
+		span.End(err)
+		if err != nil {
+			if err := c.dlq.WriteMessages(handleCtx, kafkautils.DeadLetterMessage(msg, err)); err != nil {
+				return fmt.Errorf("failed to quarantine message: %w", err)
+			}
+		}
+		if err := c.reader.CommitMessages(handleCtx, msg); err != nil {
+			return fmt.Errorf("failed to commit message: %w", err)
+		}
+	}
+}
+
+// Close the reader and the dead letter writer
+func (c *KafkaConsumer) Close() error {
+	if err := c.dlq.Close(); err != nil {
+		return err
+	}
+	return c.reader.Close()
+}
@@ -0,0 +1,330 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/closers"
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/brave-intl/bat-go/utils/s3"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+const (
+	sqsVersion = "2012-11-05"
+	snsVersion = "2010-03-31"
+	// sqsWaitSeconds is how long a receive waits for messages, the most sqs allows
+	sqsWaitSeconds = 20
+	// sqsBatch is the most messages a receive returns
+	sqsBatch = 10
+)
+
+// envelope is the body of a message on sqs and sns. Message values are binary and there are too few
+// message attributes for every header, so the whole kafka message is carried in the body
+type envelope struct {
+	Topic   string         `json:"topic"`
+	Key     []byte         `json:"key,omitempty"`
+	Value   []byte         `json:"value"`
+	Headers []kafka.Header `json:"headers,omitempty"`
+}
+
+// snsNotification is how sns wraps messages delivered to a queue without raw message delivery
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+func encode(ctx context.Context, topic string, msg kafka.Message) (string, error) {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers, kafkautils.RequestIDHeaders(ctx)...)
+	headers = append(headers, kafkautils.TraceHeaders(ctx)...)
+	body, err := json.Marshal(envelope{Topic: topic, Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode message: %w", err)
+	}
+	return string(body), nil
+}
+
+func decode(body string) (kafka.Message, error) {
+	var notification snsNotification
+	if err := json.Unmarshal([]byte(body), &notification); err == nil && notification.Type == "Notification" {
+		body = notification.Message
+	}
+	var e envelope
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return kafka.Message{Topic: e.Topic, Key: e.Key, Value: e.Value, Headers: e.Headers}, nil
+}
+
+// AWSClient calls the query apis of sqs and sns
+type AWSClient struct {
+	// SNSEndpoint overrides the regional sns endpoint, queue urls are always used as they are
+	SNSEndpoint string
+	Region      string
+	Credentials s3.Credentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewAWSClient creates a client configured from the standard AWS_* environment variables.
+// AWS_SNS_ENDPOINT optionally points the client at an sns compatible service
+func NewAWSClient() (*AWSClient, error) {
+	region, creds, err := s3.EnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return &AWSClient{
+		SNSEndpoint: os.Getenv("AWS_SNS_ENDPOINT"),
+		Region:      region,
+		Credentials: creds,
+		// long polls hold the request open for up to sqsWaitSeconds
+		client: &http.Client{Timeout: (sqsWaitSeconds + 10) * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+// call posts the action to the endpoint, decoding its xml response into out
+func (c *AWSClient) call(ctx context.Context, endpoint string, service string, params url.Values, out interface{}) error {
+	body := []byte(params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s3.Sign(req, body, c.Credentials, c.Region, service, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", service, params.Get("Action"), err)
+	}
+	defer closers.Panic(resp.Body)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to call %s %s: unexpected status %d: %s",
+			service, params.Get("Action"), resp.StatusCode, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// SendMessage sends the body to the queue
+func (c *AWSClient) SendMessage(ctx context.Context, queueURL string, body string) error {
+	return c.call(ctx, queueURL, "sqs", url.Values{
+		"Action":      {"SendMessage"},
+		"Version":     {sqsVersion},
+		"MessageBody": {body},
+	}, nil)
+}
+
+// ReceivedMessage is a message received from a queue
+type ReceivedMessage struct {
+	MessageID     string `xml:"MessageId"`
+	ReceiptHandle string `xml:"ReceiptHandle"`
+	Body          string `xml:"Body"`
+	Attributes    []struct {
+		Name  string `xml:"Name"`
+		Value string `xml:"Value"`
+	} `xml:"Attribute"`
+}
+
+// ReceiveCount is how many times the message has been received, including this time
+func (m ReceivedMessage) ReceiveCount() int {
+	for _, attr := range m.Attributes {
+		if attr.Name == "ApproximateReceiveCount" {
+			n, _ := strconv.Atoi(attr.Value)
+			return n
+		}
+	}
+	return 0
+}
+
+// ReceiveMessages long polls the queue for up to ten messages
+func (c *AWSClient) ReceiveMessages(ctx context.Context, queueURL string) ([]ReceivedMessage, error) {
+	var resp struct {
+		Messages []ReceivedMessage `xml:"ReceiveMessageResult>Message"`
+	}
+	err := c.call(ctx, queueURL, "sqs", url.Values{
+		"Action":              {"ReceiveMessage"},
+		"Version":             {sqsVersion},
+		"MaxNumberOfMessages": {strconv.Itoa(sqsBatch)},
+		"WaitTimeSeconds":     {strconv.Itoa(sqsWaitSeconds)},
+		"AttributeName.1":     {"ApproximateReceiveCount"},
+	}, &resp)
+	return resp.Messages, err
+}
+
+// DeleteMessage removes a handled message from the queue
+func (c *AWSClient) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
+	return c.call(ctx, queueURL, "sqs", url.Values{
+		"Action":        {"DeleteMessage"},
+		"Version":       {sqsVersion},
+		"ReceiptHandle": {receiptHandle},
+	}, nil)
+}
+
+// Publish publishes the message to the sns topic
+func (c *AWSClient) Publish(ctx context.Context, topicARN string, message string) error {
+	endpoint := c.SNSEndpoint
+	if endpoint == "" {
+		region := c.Region
+		// arn:aws:sns:<region>:<account>:<name>
+		if parts := strings.Split(topicARN, ":"); len(parts) == 6 {
+			region = parts[3]
+		}
+		endpoint = fmt.Sprintf("https://sns.%s.amazonaws.com/", region)
+	}
+	return c.call(ctx, endpoint, "sns", url.Values{
+		"Action":   {"Publish"},
+		"Version":  {snsVersion},
+		"TopicArn": {topicARN},
+		"Message":  {message},
+	}, nil)
+}
+
+func startProducerSpan(ctx context.Context, system string, topic string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.StartSpan(ctx, system+".produce "+topic, tracing.WithKind(tracing.SpanKindProducer))
+	span.SetAttribute("messaging.system", system)
+	span.SetAttribute("messaging.destination", topic)
+	return ctx, span
+}
+
+// SQSProducer writes a topic to an sqs queue
+type SQSProducer struct {
+	client   *AWSClient
+	topic    string
+	queueURL string
+}
+
+// WriteMessages sends each message to the queue within a producer span
+func (p *SQSProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) (err error) {
+	ctx, span := startProducerSpan(ctx, SQS, p.topic)
+	defer func() { span.End(err) }()
+	for _, msg := range msgs {
+		body, err := encode(ctx, p.topic, msg)
+		if err != nil {
+			return err
+		}
+		if err := p.client.SendMessage(ctx, p.queueURL, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op, messages are sent as they are written
+func (p *SQSProducer) Close() error {
+	return nil
+}
+
+// SNSProducer publishes a topic to an sns topic
+type SNSProducer struct {
+	client   *AWSClient
+	topic    string
+	topicARN string
+}
+
+// WriteMessages publishes each message within a producer span
+func (p *SNSProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) (err error) {
+	ctx, span := startProducerSpan(ctx, SNS, p.topic)
+	defer func() { span.End(err) }()
+	for _, msg := range msgs {
+		body, err := encode(ctx, p.topic, msg)
+		if err != nil {
+			return err
+		}
+		if err := p.client.Publish(ctx, p.topicARN, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op, messages are published as they are written
+func (p *SNSProducer) Close() error {
+	return nil
+}
+
+// SQSConsumer consumes a topic from an sqs queue. Messages the handler fails are left on the queue to be
+// received again once their visibility timeout passes, and moved to the queue's dead letter queue by its
+// redrive policy after its maxReceiveCount
+type SQSConsumer struct {
+	client   *AWSClient
+	topic    string
+	queueURL string
+}
+
+// Consume passes each message to the handler, deleting it from the queue once handled. Once the context is
+// done no more messages are received, the message being handled is still handled and deleted and the rest
+// of its batch is left on the queue to be received again
+func (c *SQSConsumer) Consume(ctx context.Context, handler Handler) error {
+	handleCtx := srv.Detach(ctx)
+	for {
+		received, err := c.client.ReceiveMessages(ctx, c.queueURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to receive messages: %w", err)
+		}
+		for _, m := range received {
+			if ctx.Err() != nil {
+				return nil
+			}
+			msg, err := decode(m.Body)
+			if err == nil {
+				if msg.Topic == "" {
+					msg.Topic = c.topic
+				}
+				err = c.handle(handleCtx, handler, msg, m.ReceiveCount())
+			}
+			if err != nil {
+				continue
+			}
+			if err := c.client.DeleteMessage(handleCtx, c.queueURL, m.ReceiptHandle); err != nil {
+				return fmt.Errorf("failed to delete message: %w", err)
+			}
+		}
+	}
+}
+
+// handle the message within a consumer span, continuing the trace in its headers
+func (c *SQSConsumer) handle(ctx context.Context, handler Handler, msg kafka.Message, receiveCount int) error {
+	for _, h := range msg.Headers {
+		if h.Key != tracing.TraceparentHeader {
+			continue
+		}
+		if sc, err := tracing.ParseTraceparent(string(h.Value)); err == nil {
+			ctx = tracing.ContextWithRemoteParent(ctx, sc)
+		}
+	}
+	ctx, span := tracing.StartSpan(ctx, "sqs.consume "+msg.Topic, tracing.WithKind(tracing.SpanKindConsumer))
+	span.SetAttribute("messaging.system", SQS)
+	span.SetAttribute("messaging.destination", msg.Topic)
+	span.SetAttribute("messaging.sqs.receive_count", receiveCount)
+	err := handler(ctx, msg)
+	span.End(err)
+	return err
+}
+
+// Close is a no-op, receives stop with the context passed to Consume
+func (c *SQSConsumer) Close() error {
+	return nil
+}
@@ -0,0 +1,191 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+)
+
+const (
+	defaultServer = "https://bigquery.googleapis.com"
+	// metadataServer issues access tokens for the service account of the instance
+	metadataServer    = "http://metadata.google.internal"
+	metadataTokenPath = "/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// Row is a row streamed into a table. The insert id lets BigQuery drop a row retried after it was inserted
+type Row struct {
+	InsertID string                 `json:"insertId"`
+	JSON     map[string]interface{} `json:"json"`
+}
+
+// Client abstracts over the underlying client
+type Client interface {
+	// InsertRows streams rows into a table of the dataset
+	InsertRows(ctx context.Context, table string, rows []Row) error
+}
+
+// HTTPClient streams rows with the BigQuery tabledata.insertAll api
+type HTTPClient struct {
+	client  *clients.SimpleHTTPClient
+	project string
+	dataset string
+	tokens  *tokenSource
+}
+
+// New returns a new HTTPClient for the dataset BIGQUERY_DATASET of the project BIGQUERY_PROJECT. Requests
+// are authorized with BIGQUERY_ACCESS_TOKEN when set, otherwise with the instance's service account
+func New() (Client, error) {
+	project := os.Getenv("BIGQUERY_PROJECT")
+	dataset := os.Getenv("BIGQUERY_DATASET")
+	if project == "" || dataset == "" {
+		return nil, errors.New("BIGQUERY_PROJECT and BIGQUERY_DATASET must be set")
+	}
+	serverURL := os.Getenv("BIGQUERY_SERVER")
+	if serverURL == "" {
+		serverURL = defaultServer
+	}
+	client, err := clients.New(serverURL, "")
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := clients.New(metadataServer, "")
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithPrometheus(&HTTPClient{
+		client:  client,
+		project: project,
+		dataset: dataset,
+		tokens:  &tokenSource{static: os.Getenv("BIGQUERY_ACCESS_TOKEN"), metadata: metadata},
+	}, "bigquery_client"), nil
+}
+
+type insertAllRequest struct {
+	Rows []Row `json:"rows"`
+}
+
+// ErrorProto is a reason a row was not inserted
+type ErrorProto struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// InsertError is the failure of a row to be inserted
+type InsertError struct {
+	Index  int          `json:"index"`
+	Errors []ErrorProto `json:"errors"`
+}
+
+// Invalid reports whether the row itself was rejected, rather than not inserted because another row was
+func (ie InsertError) Invalid() bool {
+	for _, e := range ie.Errors {
+		if e.Reason != "stopped" {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertRowsError is returned when rows were rejected, in which case none of the rows were inserted
+type InsertRowsError struct {
+	Errors []InsertError
+}
+
+func (ire *InsertRowsError) Error() string {
+	var reasons []string
+	for _, ie := range ire.Errors {
+		if ie.Invalid() && len(ie.Errors) > 0 {
+			reasons = append(reasons, fmt.Sprintf("row %d: %s", ie.Index, ie.Errors[0].Message))
+		}
+	}
+	return "rows were rejected: " + strings.Join(reasons, "; ")
+}
+
+// RetryableError is returned when a request failed in a way retrying may resolve
+type RetryableError struct {
+	Err error
+}
+
+func (re *RetryableError) Error() string {
+	return re.Err.Error()
+}
+
+func (re *RetryableError) Unwrap() error {
+	return re.Err
+}
+
+// InsertRows streams rows into a table of the dataset
+func (c *HTTPClient) InsertRows(ctx context.Context, table string, rows []Row) error {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return &RetryableError{Err: fmt.Errorf("failed to get access token: %w", err)}
+	}
+
+	path := fmt.Sprintf("/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll", c.project, c.dataset, table)
+	req, err := c.client.NewRequest(ctx, "POST", path, insertAllRequest{Rows: rows}, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "Bearer "+token)
+
+	var resp struct {
+		InsertErrors []InsertError `json:"insertErrors"`
+	}
+	httpResp, err := c.client.Do(ctx, req, &resp)
+	if err != nil {
+		if httpResp == nil || httpResp.StatusCode >= http.StatusInternalServerError ||
+			httpResp.StatusCode == http.StatusTooManyRequests {
+			return &RetryableError{Err: err}
+		}
+		return err
+	}
+	if len(resp.InsertErrors) > 0 {
+		return &InsertRowsError{Errors: resp.InsertErrors}
+	}
+	return nil
+}
+
+// tokenSource caches access tokens from the metadata server until shortly before they expire
+type tokenSource struct {
+	static   string
+	metadata *clients.SimpleHTTPClient
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	if ts.static != "" {
+		return ts.static, nil
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && time.Now().Before(ts.expires) {
+		return ts.token, nil
+	}
+
+	req, err := ts.metadata.NewRequest(ctx, "GET", metadataTokenPath, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if _, err := ts.metadata.Do(ctx, req, &resp); err != nil {
+		return "", err
+	}
+	ts.token = resp.AccessToken
+	ts.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return ts.token, nil
+}
@@ -0,0 +1,171 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var streamedRows = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "bigquery_streamed_rows_total",
+		Help: "Rows streamed to BigQuery by table and result",
+	},
+	[]string{"table", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(streamedRows)
+}
+
+// StreamEnabled reports whether BIGQUERY_STREAM_<TABLE> enables streaming the table
+func StreamEnabled(table string) bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("BIGQUERY_STREAM_" + strings.ToUpper(table)))
+	return enabled
+}
+
+// Streamer batches rows enqueued for a table and streams them in the background, retrying failed batches
+type Streamer struct {
+	client Client
+	table  string
+	rows   chan Row
+
+	// BatchSize is the most rows streamed at once
+	BatchSize int
+	// FlushInterval is the longest a row waits for its batch to fill
+	FlushInterval time.Duration
+	// MaxRetries is how many times a batch is retried before its rows are dropped
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled for each retry after
+	Backoff time.Duration
+}
+
+// NewStreamer creates a streamer of the table, buffering up to bufferSize rows
+func NewStreamer(client Client, table string, bufferSize int) *Streamer {
+	return &Streamer{
+		client:        client,
+		table:         table,
+		rows:          make(chan Row, bufferSize),
+		BatchSize:     500,
+		FlushInterval: 5 * time.Second,
+		MaxRetries:    5,
+		Backoff:       time.Second,
+	}
+}
+
+// Enqueue a row to be streamed. Streaming is best effort, so when the buffer is full the row is
+// dropped rather than delaying the caller
+func (s *Streamer) Enqueue(row Row) {
+	select {
+	case s.rows <- row:
+	default:
+		streamedRows.With(prometheus.Labels{"table": s.table, "result": "dropped"}).Inc()
+	}
+}
+
+// Run streams enqueued rows until the context is done, flushing the rows already enqueued
+func (s *Streamer) Run(ctx context.Context) {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		ctx, logger = logging.SetupLogger(ctx)
+	}
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Row
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.insert(ctx, batch); err != nil {
+			logger.Error().Err(err).Str("table", s.table).Int("rows", len(batch)).Msg("failed to stream rows to bigquery")
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the rows already enqueued are flushed without the cancelled context
+			for {
+				select {
+				case row := <-s.rows:
+					batch = append(batch, row)
+					if len(batch) >= s.BatchSize {
+						flush(context.Background())
+					}
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		case row := <-s.rows:
+			batch = append(batch, row)
+			if len(batch) >= s.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// insert a batch, retrying failures retrying may resolve. Rejected rows are dropped and the rest of
+// the batch, which is not inserted along with them, retried
+func (s *Streamer) insert(ctx context.Context, rows []Row) error {
+	backoff := s.Backoff
+	for attempt := 0; ; attempt++ {
+		err := s.client.InsertRows(ctx, s.table, rows)
+		if err == nil {
+			streamedRows.With(prometheus.Labels{"table": s.table, "result": "ok"}).Add(float64(len(rows)))
+			return nil
+		}
+
+		var insertErr *InsertRowsError
+		var retryableErr *RetryableError
+		switch {
+		case errors.As(err, &insertErr):
+			rejected := map[int]bool{}
+			for _, ie := range insertErr.Errors {
+				if ie.Invalid() {
+					rejected[ie.Index] = true
+				}
+			}
+			var retry []Row
+			for i, row := range rows {
+				if !rejected[i] {
+					retry = append(retry, row)
+				}
+			}
+			streamedRows.With(prometheus.Labels{"table": s.table, "result": "rejected"}).Add(float64(len(rows) - len(retry)))
+			if len(retry) == 0 || len(retry) == len(rows) {
+				return err
+			}
+			// the remaining rows were valid, so they are retried straight away
+			rows = retry
+			continue
+		case errors.As(err, &retryableErr):
+		default:
+			streamedRows.With(prometheus.Labels{"table": s.table, "result": "error"}).Add(float64(len(rows)))
+			return err
+		}
+
+		if attempt >= s.MaxRetries {
+			streamedRows.With(prometheus.Labels{"table": s.table, "result": "error"}).Add(float64(len(rows)))
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,139 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingClient struct {
+	mu       sync.Mutex
+	attempts int
+	inserted []Row
+	fail     func(attempt int, rows []Row) error
+}
+
+func (c *recordingClient) InsertRows(ctx context.Context, table string, rows []Row) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	if c.fail != nil {
+		if err := c.fail(c.attempts, rows); err != nil {
+			return err
+		}
+	}
+	c.inserted = append(c.inserted, rows...)
+	return nil
+}
+
+func rows(ids ...string) []Row {
+	var r []Row
+	for _, id := range ids {
+		r = append(r, Row{InsertID: id, JSON: map[string]interface{}{"id": id}})
+	}
+	return r
+}
+
+func TestStreamerRetries(t *testing.T) {
+	client := &recordingClient{fail: func(attempt int, rows []Row) error {
+		if attempt < 3 {
+			return &RetryableError{Err: errors.New("unavailable")}
+		}
+		return nil
+	}}
+	s := NewStreamer(client, "transactions", 10)
+	s.Backoff = time.Millisecond
+
+	require.NoError(t, s.insert(context.Background(), rows("a", "b")))
+	assert.Equal(t, 3, client.attempts)
+	assert.Len(t, client.inserted, 2)
+}
+
+func TestStreamerDropsRejectedRows(t *testing.T) {
+	client := &recordingClient{fail: func(attempt int, rows []Row) error {
+		if attempt == 1 {
+			ire := &InsertRowsError{Errors: []InsertError{
+				{Index: 1, Errors: []ErrorProto{{Reason: "invalid", Message: "no such field"}}},
+				{Index: 0, Errors: []ErrorProto{{Reason: "stopped"}}},
+			}}
+			return ire
+		}
+		return nil
+	}}
+	s := NewStreamer(client, "transactions", 10)
+
+	require.NoError(t, s.insert(context.Background(), rows("a", "b")))
+	assert.Equal(t, rows("a"), client.inserted)
+}
+
+func TestStreamerGivesUp(t *testing.T) {
+	client := &recordingClient{fail: func(attempt int, rows []Row) error {
+		return errors.New("bad request")
+	}}
+	s := NewStreamer(client, "transactions", 10)
+
+	assert.Error(t, s.insert(context.Background(), rows("a")))
+	assert.Equal(t, 1, client.attempts, "errors retrying cannot resolve are not retried")
+}
+
+func TestStreamerRunFlushes(t *testing.T) {
+	client := &recordingClient{}
+	s := NewStreamer(client, "transactions", 10)
+	s.BatchSize = 2
+	s.FlushInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	for _, row := range rows("a", "b", "c") {
+		s.Enqueue(row)
+	}
+	cancel()
+	<-done
+
+	assert.Len(t, client.inserted, 3, "rows enqueued before stopping are flushed")
+}
+
+func TestInsertRows(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bigquery/v2/projects/project/datasets/dataset/tables/transactions/insertAll", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("authorization"))
+		var req insertAllRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"insertErrors": [{"index": 0, "errors": [{"reason": "invalid", "message": "bad"}]}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := clients.New(server.URL, "")
+	require.NoError(t, err)
+	bq := &HTTPClient{client: client, project: "project", dataset: "dataset", tokens: &tokenSource{static: "token"}}
+
+	status = http.StatusOK
+	var ire *InsertRowsError
+	assert.True(t, errors.As(bq.InsertRows(context.Background(), "transactions", rows("a")), &ire))
+
+	status = http.StatusServiceUnavailable
+	var re *RetryableError
+	assert.True(t, errors.As(bq.InsertRows(context.Background(), "transactions", rows("a")), &re))
+
+	status = http.StatusBadRequest
+	err = bq.InsertRows(context.Background(), "transactions", rows("a"))
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &re))
+}
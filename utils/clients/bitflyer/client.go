@@ -18,6 +18,7 @@ import (
 	"github.com/brave-intl/bat-go/utils/logging"
 	"github.com/brave-intl/bat-go/utils/requestutils"
 	"github.com/google/go-querystring/query"
+	"github.com/rs/zerolog"
 	"github.com/shopspring/decimal"
 	"github.com/square/go-jose/jwt"
 )
@@ -254,10 +255,14 @@ func (c *HTTPClient) FetchQuote(
 	productCode string,
 	readFromFile bool,
 ) (*Quote, error) {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
 	if readFromFile {
-		read, err := readQuoteFromFile()
+		read, err := readQuoteFromFile(logger)
 		if err != nil {
-			fmt.Println("failed to read quote from file", err)
+			logger.Error().Err(err).Msg("failed to read quote from file")
 			return nil, err
 		}
 		if withinPriceTokenExpiration(read) {
@@ -279,7 +284,7 @@ func (c *HTTPClient) FetchQuote(
 	if err == nil {
 		expiry, err := parseExpiry(body.PriceToken)
 		if err == nil {
-			writeQuoteToFile(SavedQuote{
+			writeQuoteToFile(logger, SavedQuote{
 				Body:   body,
 				Expiry: *expiry,
 			})
@@ -318,10 +323,10 @@ func withinPriceTokenExpiration(savedQuote *SavedQuote) bool {
 	return time.Now().Before(savedQuote.Expiry)
 }
 
-func writeQuoteToFile(quote SavedQuote) {
+func writeQuoteToFile(logger *zerolog.Logger, quote SavedQuote) {
 	data, err := json.Marshal(quote)
 	if err != nil {
-		fmt.Println("marshal error", err)
+		logger.Error().Err(err).Msg("failed to marshal quote")
 		return
 	}
 	_ = ioutil.WriteFile("./fetch-quote.json", data, 0777)
@@ -333,10 +338,10 @@ type SavedQuote struct {
 	Expiry time.Time `json:"expiry"`
 }
 
-func readQuoteFromFile() (*SavedQuote, error) {
+func readQuoteFromFile(logger *zerolog.Logger) (*SavedQuote, error) {
 	dat, err := ioutil.ReadFile("./fetch-quote.json")
 	if err != nil {
-		fmt.Println("read file error", err)
+		logger.Warn().Err(err).Msg("failed to read quote file")
 		return nil, nil
 	}
 	var body SavedQuote
@@ -10,6 +10,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -41,6 +42,7 @@ func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometh
 // CheckPayoutStatus implements Client
 func (_d ClientWithPrometheus) CheckPayoutStatus(ctx context.Context, payload CheckBulkStatusPayload) (wp1 *WithdrawToDepositIDBulkResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CheckPayoutStatus")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -48,6 +50,7 @@ func (_d ClientWithPrometheus) CheckPayoutStatus(ctx context.Context, payload Ch
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "CheckPayoutStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.CheckPayoutStatus(ctx, payload)
 }
@@ -55,6 +58,7 @@ func (_d ClientWithPrometheus) CheckPayoutStatus(ctx context.Context, payload Ch
 // FetchQuote implements Client
 func (_d ClientWithPrometheus) FetchQuote(ctx context.Context, productCode string, readFromFile bool) (qp1 *Quote, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".FetchQuote")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -62,6 +66,7 @@ func (_d ClientWithPrometheus) FetchQuote(ctx context.Context, productCode strin
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "FetchQuote", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.FetchQuote(ctx, productCode, readFromFile)
 }
@@ -69,6 +74,7 @@ func (_d ClientWithPrometheus) FetchQuote(ctx context.Context, productCode strin
 // RefreshToken implements Client
 func (_d ClientWithPrometheus) RefreshToken(ctx context.Context, payload TokenPayload) (tp1 *TokenResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RefreshToken")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -76,6 +82,7 @@ func (_d ClientWithPrometheus) RefreshToken(ctx context.Context, payload TokenPa
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "RefreshToken", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RefreshToken(ctx, payload)
 }
@@ -94,6 +101,7 @@ func (_d ClientWithPrometheus) SetAuthToken(authToken string) {
 // UploadBulkPayout implements Client
 func (_d ClientWithPrometheus) UploadBulkPayout(ctx context.Context, payload WithdrawToDepositIDBulkPayload) (wp1 *WithdrawToDepositIDBulkResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UploadBulkPayout")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -101,6 +109,7 @@ func (_d ClientWithPrometheus) UploadBulkPayout(ctx context.Context, payload Wit
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "UploadBulkPayout", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.UploadBulkPayout(ctx, payload)
 }
@@ -0,0 +1,305 @@
+package cbr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultBreakerFailures is how many calls in a row must fail before the circuit opens
+	defaultBreakerFailures = 5
+	// defaultBreakerCooldown is how long the circuit stays open before a call is let through to try the server
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+var (
+	// defaultRetryPolicies retry the calls which are safe to repeat. Redemptions are not retried, a redemption
+	// which timed out may have been spent and would be rejected as a duplicate
+	defaultRetryPolicies = map[string]RetryPolicy{
+		"CreateIssuer":      {Attempts: 2, Backoff: 250 * time.Millisecond},
+		"GetIssuer":         {Attempts: 3, Backoff: 100 * time.Millisecond},
+		"SignCredentials":   {Attempts: 3, Backoff: 250 * time.Millisecond},
+		"RedeemCredential":  {Attempts: 1},
+		"RedeemCredentials": {Attempts: 1},
+		"RevokeCredentials": {Attempts: 3, Backoff: 250 * time.Millisecond},
+	}
+
+	circuitOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cbr_client_circuit_open",
+			Help: "Whether the circuit to the challenge bypass server is open, failing calls without making them",
+		},
+	)
+	clientRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cbr_client_retries_total",
+			Help: "Calls to the challenge bypass server which were retried, by method",
+		},
+		[]string{"method"},
+	)
+)
+
+// CircuitOpenError is returned without calling the challenge bypass server while the circuit to it is open,
+// after too many calls in a row failed. Callers should report the server as unavailable
+type CircuitOpenError struct {
+	Method string
+	// RetryAfter is how long until the circuit lets a call through again
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("cbr circuit open, %s not attempted", e.Method)
+}
+
+// IsCircuitOpen is whether the error is, or wraps, a CircuitOpenError
+func IsCircuitOpen(err error) bool {
+	var openErr *CircuitOpenError
+	return errors.As(err, &openErr)
+}
+
+// RetryPolicy is how a call to the challenge bypass server is retried. Attempts includes the first call,
+// and Backoff is the delay before the first retry, doubling with each retry after, with jitter
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// delay before the retry following the attempt, jittered between half and all of the backoff so clients
+// retrying together spread out
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.Backoff << uint(attempt-1)
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// ParseRetryPolicies parses policies of the form "SignCredentials=3/250ms,RedeemCredentials=1" over the
+// defaults, the backoff can be left out to keep the default backoff of the method
+func ParseRetryPolicies(raw string) (map[string]RetryPolicy, error) {
+	policies := make(map[string]RetryPolicy, len(defaultRetryPolicies))
+	for method, policy := range defaultRetryPolicies {
+		policies[method] = policy
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		policy, ok := policies[parts[0]]
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid cbr retry policy %q", entry)
+		}
+		values := strings.SplitN(parts[1], "/", 2)
+		attempts, err := strconv.Atoi(values[0])
+		if err != nil || attempts < 1 {
+			return nil, fmt.Errorf("invalid cbr retry attempts %q", entry)
+		}
+		policy.Attempts = attempts
+		if len(values) == 2 {
+			policy.Backoff, err = time.ParseDuration(values[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid cbr retry backoff %q: %w", entry, err)
+			}
+		}
+		policies[parts[0]] = policy
+	}
+	return policies, nil
+}
+
+// retryable is whether a failed call might succeed if retried, and so whether it counts against the circuit.
+// The server rejecting a request as bad will reject it again, and is not a sign the server is failing
+func retryable(err error) bool {
+	var eb *errorutils.ErrorBundle
+	for e := err; errors.As(e, &eb); e = eb.Cause() {
+		if state, ok := eb.Data().(clients.HTTPState); ok {
+			status := state.Status
+			return status < http.StatusBadRequest || status >= http.StatusInternalServerError ||
+				status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+		}
+	}
+	return true
+}
+
+// breaker opens the circuit once enough calls in a row have failed, failing calls without making them until
+// the cooldown has passed. A single call is then let through, closing the circuit if it succeeds and opening
+// it again if it fails
+type breaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	failed    int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow is whether a call may be made, returning how long until one may be made if not
+func (b *breaker) allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed < b.failures {
+		return true, 0
+	}
+	if now.Before(b.openUntil) {
+		return false, b.openUntil.Sub(now)
+	}
+	if b.probing {
+		return false, b.cooldown
+	}
+	b.probing = true
+	return true, 0
+}
+
+// record the result of a call which was allowed
+func (b *breaker) record(failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failed = 0
+		circuitOpen.Set(0)
+		return
+	}
+	b.failed++
+	if b.failed >= b.failures {
+		b.openUntil = now.Add(b.cooldown)
+		circuitOpen.Set(1)
+	}
+}
+
+// ResilientClient retries calls to the challenge bypass server according to the retry policy of each method,
+// behind a circuit breaker so a failing server is not called while it recovers
+type ResilientClient struct {
+	base     Client
+	policies map[string]RetryPolicy
+	breaker  *breaker
+}
+
+// NewResilientClient wraps the client, opening the circuit after failures calls in a row have failed
+func NewResilientClient(base Client, policies map[string]RetryPolicy, failures int, cooldown time.Duration) *ResilientClient {
+	return &ResilientClient{
+		base:     base,
+		policies: policies,
+		breaker:  &breaker{failures: failures, cooldown: cooldown},
+	}
+}
+
+// newResilientClientFromEnv wraps the client with the retry policies of CBR_RETRY_POLICIES and the breaker
+// configured by CBR_BREAKER_FAILURES and CBR_BREAKER_COOLDOWN
+func newResilientClientFromEnv(base Client) (*ResilientClient, error) {
+	policies, err := ParseRetryPolicies(os.Getenv("CBR_RETRY_POLICIES"))
+	if err != nil {
+		return nil, err
+	}
+	failures := defaultBreakerFailures
+	if raw := os.Getenv("CBR_BREAKER_FAILURES"); raw != "" {
+		if failures, err = strconv.Atoi(raw); err != nil || failures < 1 {
+			return nil, fmt.Errorf("invalid CBR_BREAKER_FAILURES %q", raw)
+		}
+	}
+	cooldown := defaultBreakerCooldown
+	if raw := os.Getenv("CBR_BREAKER_COOLDOWN"); raw != "" {
+		if cooldown, err = time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("invalid CBR_BREAKER_COOLDOWN %q: %w", raw, err)
+		}
+	}
+	return NewResilientClient(base, policies, failures, cooldown), nil
+}
+
+// call makes the call through the breaker, retrying it according to the method's policy
+func (c *ResilientClient) call(ctx context.Context, method string, fn func() error) error {
+	policy, ok := c.policies[method]
+	if !ok || policy.Attempts < 1 {
+		policy = RetryPolicy{Attempts: 1}
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		allowed, retryAfter := c.breaker.allow(time.Now())
+		if !allowed {
+			if err != nil {
+				return err
+			}
+			return &CircuitOpenError{Method: method, RetryAfter: retryAfter}
+		}
+
+		err = fn()
+		// a caller giving up is not the server failing
+		failed := err != nil && retryable(err) && ctx.Err() == nil
+		c.breaker.record(failed, time.Now())
+		if !failed || attempt >= policy.Attempts {
+			return err
+		}
+
+		clientRetries.WithLabelValues(method).Inc()
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+}
+
+// CreateIssuer with the provided name and token cap
+func (c *ResilientClient) CreateIssuer(ctx context.Context, issuer string, maxTokens int) error {
+	return c.call(ctx, "CreateIssuer", func() error {
+		return c.base.CreateIssuer(ctx, issuer, maxTokens)
+	})
+}
+
+// GetIssuer by name
+func (c *ResilientClient) GetIssuer(ctx context.Context, issuer string) (*IssuerResponse, error) {
+	var resp *IssuerResponse
+	err := c.call(ctx, "GetIssuer", func() error {
+		var err error
+		resp, err = c.base.GetIssuer(ctx, issuer)
+		return err
+	})
+	return resp, err
+}
+
+// SignCredentials using a particular issuer
+func (c *ResilientClient) SignCredentials(ctx context.Context, issuer string, creds []string) (*CredentialsIssueResponse, error) {
+	var resp *CredentialsIssueResponse
+	err := c.call(ctx, "SignCredentials", func() error {
+		var err error
+		resp, err = c.base.SignCredentials(ctx, issuer, creds)
+		return err
+	})
+	return resp, err
+}
+
+// RedeemCredential that was issued by the specified issuer
+func (c *ResilientClient) RedeemCredential(ctx context.Context, issuer string, preimage string, signature string, payload string) error {
+	return c.call(ctx, "RedeemCredential", func() error {
+		return c.base.RedeemCredential(ctx, issuer, preimage, signature, payload)
+	})
+}
+
+// RedeemCredentials that were issued by the specified issuer
+func (c *ResilientClient) RedeemCredentials(ctx context.Context, credentials []CredentialRedemption, payload string) error {
+	return c.call(ctx, "RedeemCredentials", func() error {
+		return c.base.RedeemCredentials(ctx, credentials, payload)
+	})
+}
+
+// RevokeCredentials signed by the issuer for the blinded tokens
+func (c *ResilientClient) RevokeCredentials(ctx context.Context, issuer string, creds []string) error {
+	return c.call(ctx, "RevokeCredentials", func() error {
+		return c.base.RevokeCredentials(ctx, issuer, creds)
+	})
+}
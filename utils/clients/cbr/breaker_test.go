@@ -0,0 +1,121 @@
+package cbr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingClient fails its calls with err, counting them
+type failingClient struct {
+	Client
+	err   error
+	calls int
+}
+
+func (c *failingClient) SignCredentials(ctx context.Context, issuer string, creds []string) (*CredentialsIssueResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &CredentialsIssueResponse{SignedTokens: creds}, nil
+}
+
+func (c *failingClient) RedeemCredential(ctx context.Context, issuer string, preimage string, signature string, payload string) error {
+	c.calls++
+	return c.err
+}
+
+func TestResilientClientRetries(t *testing.T) {
+	base := &failingClient{err: clients.NewHTTPError(nil, "v1/blindedToken", "unavailable", http.StatusBadGateway, nil)}
+	client := NewResilientClient(base, map[string]RetryPolicy{"SignCredentials": {Attempts: 3}}, 10, time.Minute)
+
+	_, err := client.SignCredentials(context.Background(), "issuer", []string{"a"})
+	assert.Error(t, err)
+	assert.Equal(t, 3, base.calls, "server errors are retried up to the policy's attempts")
+
+	base.calls = 0
+	base.err = clients.NewHTTPError(nil, "v1/blindedToken", "rejected", http.StatusBadRequest, nil)
+	_, err = client.SignCredentials(context.Background(), "issuer", []string{"a"})
+	assert.Error(t, err)
+	assert.Equal(t, 1, base.calls, "rejected requests are not retried")
+
+	base.calls = 0
+	base.err = errors.New("connection reset")
+	assert.Error(t, client.RedeemCredential(context.Background(), "issuer", "t", "sig", "payload"))
+	assert.Equal(t, 1, base.calls, "methods without a policy are not retried")
+}
+
+func TestResilientClientCircuit(t *testing.T) {
+	base := &failingClient{err: errors.New("connection refused")}
+	client := NewResilientClient(base, map[string]RetryPolicy{}, 2, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.SignCredentials(ctx, "issuer", []string{"a"})
+		assert.False(t, IsCircuitOpen(err))
+	}
+
+	_, err := client.SignCredentials(ctx, "issuer", []string{"a"})
+	assert.True(t, IsCircuitOpen(err), "the circuit opens after failures calls in a row have failed")
+	assert.Equal(t, 2, base.calls, "calls are not made while the circuit is open")
+	var openErr *CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.Equal(t, "SignCredentials", openErr.Method)
+	assert.True(t, openErr.RetryAfter > 0)
+
+	// once the cooldown has passed a call is let through, closing the circuit when it succeeds
+	client.breaker.openUntil = time.Now().Add(-time.Second)
+	base.err = nil
+	resp, err := client.SignCredentials(ctx, "issuer", []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, resp.SignedTokens)
+	_, err = client.SignCredentials(ctx, "issuer", []string{"a"})
+	assert.NoError(t, err)
+}
+
+func TestResilientClientCallerCanceled(t *testing.T) {
+	base := &failingClient{err: context.Canceled}
+	client := NewResilientClient(base, map[string]RetryPolicy{"SignCredentials": {Attempts: 3}}, 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.SignCredentials(ctx, "issuer", []string{"a"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, base.calls, "calls the caller gave up on are not retried")
+
+	_, err = client.SignCredentials(context.Background(), "issuer", []string{"a"})
+	assert.False(t, IsCircuitOpen(err), "calls the caller gave up on do not count against the circuit")
+}
+
+func TestParseRetryPolicies(t *testing.T) {
+	policies, err := ParseRetryPolicies("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultRetryPolicies, policies)
+
+	policies, err = ParseRetryPolicies("SignCredentials=5/1s, RedeemCredentials=2")
+	require.NoError(t, err)
+	assert.Equal(t, RetryPolicy{Attempts: 5, Backoff: time.Second}, policies["SignCredentials"])
+	assert.Equal(t, 2, policies["RedeemCredentials"].Attempts)
+	assert.Equal(t, defaultRetryPolicies["GetIssuer"], policies["GetIssuer"])
+
+	for _, raw := range []string{"Unknown=2", "SignCredentials", "SignCredentials=0", "SignCredentials=2/soon"} {
+		_, err := ParseRetryPolicies(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond}
+	for i := 0; i < 20; i++ {
+		delay := policy.delay(2)
+		assert.True(t, delay >= 100*time.Millisecond && delay <= 200*time.Millisecond, delay)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{Attempts: 2}.delay(1))
+}
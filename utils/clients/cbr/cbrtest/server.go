@@ -0,0 +1,253 @@
+// Package cbrtest provides an in-memory challenge bypass server for tests. It signs credentials with real
+// issuer keys, returning batch proofs which verify, and checks the signatures of the credentials redeemed
+// with it, so credentials can be issued and redeemed end to end without a challenge bypass server running.
+package cbrtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+)
+
+var (
+	// ErrIssuerNotFound is returned for issuers which were never created
+	ErrIssuerNotFound = errors.New("cbrtest: issuer not found")
+	// ErrIssuerExists is returned when an issuer is created again
+	ErrIssuerExists = errors.New("cbrtest: issuer already exists")
+	// ErrInvalidCredential is returned for blinded tokens which do not decode and for redemptions whose
+	// signature does not verify
+	ErrInvalidCredential = errors.New("cbrtest: invalid credential")
+	// ErrDuplicateRedemption is returned when a credential is redeemed again
+	ErrDuplicateRedemption = errors.New("cbrtest: credential already redeemed")
+)
+
+// issuer is the key of an issuer and the credentials redeemed and revoked with it
+type issuer struct {
+	key      *ristretto.SigningKey
+	redeemed map[string]bool
+	revoked  []string
+}
+
+// Server is an in-memory challenge bypass server. It is a cbr.Client, to be given to services in place of
+// the client of a real server, and an http.Handler serving the challenge bypass API, so the HTTP client
+// can be pointed at it with httptest.NewServer
+type Server struct {
+	mu      sync.Mutex
+	issuers map[string]*issuer
+}
+
+// NewServer creates a server without issuers
+func NewServer() *Server {
+	return &Server{issuers: map[string]*issuer{}}
+}
+
+// getIssuer must be called with the lock held
+func (s *Server) getIssuer(name string) (*issuer, error) {
+	i, ok := s.issuers[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrIssuerNotFound)
+	}
+	return i, nil
+}
+
+// SigningKey returns the key of an issuer, for tests to sign or verify credentials with directly
+func (s *Server) SigningKey(name string) (*ristretto.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.getIssuer(name)
+	if err != nil {
+		return nil, err
+	}
+	return i.key, nil
+}
+
+// Redeemed returns how many credentials were redeemed with an issuer
+func (s *Server) Redeemed(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.issuers[name]; ok {
+		return len(i.redeemed)
+	}
+	return 0
+}
+
+// Revoked returns the blinded tokens whose credentials were revoked with an issuer. Revocations are only
+// recorded: a blinded token cannot be linked to the credential unblinded from it, so revoked credentials
+// still redeem
+func (s *Server) Revoked(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.issuers[name]; ok {
+		return append([]string{}, i.revoked...)
+	}
+	return nil
+}
+
+// CreateIssuer with a new random key. The token cap is not enforced
+func (s *Server) CreateIssuer(ctx context.Context, name string, maxTokens int) error {
+	key, err := ristretto.NewSigningKey()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.issuers[name]; ok {
+		return fmt.Errorf("%s: %w", name, ErrIssuerExists)
+	}
+	s.issuers[name] = &issuer{key: key, redeemed: map[string]bool{}}
+	return nil
+}
+
+// GetIssuer by name
+func (s *Server) GetIssuer(ctx context.Context, name string) (*cbr.IssuerResponse, error) {
+	key, err := s.SigningKey(name)
+	if err != nil {
+		return nil, err
+	}
+	return &cbr.IssuerResponse{Name: name, PublicKey: key.PublicKey()}, nil
+}
+
+// SignCredentials with the key of the issuer, along with their batch proof
+func (s *Server) SignCredentials(ctx context.Context, name string, creds []string) (*cbr.CredentialsIssueResponse, error) {
+	key, err := s.SigningKey(name)
+	if err != nil {
+		return nil, err
+	}
+	signed, proof, err := key.SignBatch(creds)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, ErrInvalidCredential)
+	}
+	return &cbr.CredentialsIssueResponse{BatchProof: proof, SignedTokens: signed}, nil
+}
+
+// RedeemCredential issued by the issuer toward the payload
+func (s *Server) RedeemCredential(ctx context.Context, name string, preimage string, signature string, payload string) error {
+	return s.RedeemCredentials(ctx, []cbr.CredentialRedemption{{Issuer: name, TokenPreimage: preimage, Signature: signature}}, payload)
+}
+
+// RedeemCredentials toward the payload, either all of them or, when any fails to redeem, none. Errors
+// are classified as the HTTP client classifies the responses of a real server, see cbr.IsDuplicateRedemption
+func (s *Server) RedeemCredentials(ctx context.Context, credentials []cbr.CredentialRedemption, payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, cred := range credentials {
+		i, err := s.getIssuer(cred.Issuer)
+		if err != nil {
+			return err
+		}
+		if err := i.key.Verify(cred.TokenPreimage, cred.Signature, payload); err != nil {
+			return errorutils.New(fmt.Errorf("%s: %w", err, ErrInvalidCredential), "cbr bad request",
+				errorutils.Codified{ErrCode: "cbr_bad_request", Retry: false})
+		}
+		if i.redeemed[cred.TokenPreimage] || seen[cred.Issuer+cred.TokenPreimage] {
+			return errorutils.New(ErrDuplicateRedemption, "cbr duplicate redemption",
+				errorutils.Codified{ErrCode: "cbr_dup_redeem", Retry: false})
+		}
+		seen[cred.Issuer+cred.TokenPreimage] = true
+	}
+	for _, cred := range credentials {
+		s.issuers[cred.Issuer].redeemed[cred.TokenPreimage] = true
+	}
+	return nil
+}
+
+// RevokeCredentials signed for the blinded tokens, see Revoked
+func (s *Server) RevokeCredentials(ctx context.Context, name string, creds []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.getIssuer(name)
+	if err != nil {
+		return err
+	}
+	i.revoked = append(i.revoked, creds...)
+	return nil
+}
+
+// ServeHTTP serves the challenge bypass API the HTTP client calls
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// issuer names carry their sku and version as a query, which the client does not escape
+	path := r.URL.Path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	var (
+		ctx  = r.Context()
+		resp interface{}
+		err  error
+	)
+	switch {
+	case r.Method == http.MethodPost && path == "/v1/issuer/":
+		var req cbr.IssuerCreateRequest
+		if err = decode(r, &req); err == nil {
+			err = s.CreateIssuer(ctx, req.Name, req.MaxTokens)
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v1/issuer/"):
+		resp, err = s.GetIssuer(ctx, strings.TrimPrefix(path, "/v1/issuer/"))
+	case r.Method == http.MethodPost && path == "/v1/blindedToken/bulk/redemption/":
+		var req cbr.CredentialsRedeemRequest
+		if err = decode(r, &req); err == nil {
+			err = s.RedeemCredentials(ctx, req.Credentials, req.Payload)
+		}
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/redemption/"):
+		var req cbr.CredentialRedeemRequest
+		if err = decode(r, &req); err == nil {
+			name := strings.TrimSuffix(strings.TrimPrefix(path, "/v1/blindedToken/"), "/redemption/")
+			err = s.RedeemCredential(ctx, name, req.TokenPreimage, req.Signature, req.Payload)
+		}
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/revocation/"):
+		var req cbr.CredentialsRevokeRequest
+		if err = decode(r, &req); err == nil {
+			name := strings.TrimSuffix(strings.TrimPrefix(path, "/v1/blindedToken/"), "/revocation/")
+			err = s.RevokeCredentials(ctx, name, req.BlindedTokens)
+		}
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v1/blindedToken/"):
+		var req cbr.CredentialsIssueRequest
+		if err = decode(r, &req); err == nil {
+			resp, err = s.SignCredentials(ctx, strings.TrimPrefix(path, "/v1/blindedToken/"), req.BlindedTokens)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if resp == nil {
+		resp = struct{}{}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", err, ErrInvalidCredential)
+	}
+	return nil
+}
+
+// statusOf is the status a real server responds to the error with
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrIssuerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrIssuerExists), errors.Is(err, ErrDuplicateRedemption):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidCredential):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,128 @@
+package cbrtest
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	"github.com/brave-intl/bat-go/utils/faults"
+	"github.com/brave-intl/bat-go/utils/test/ristretto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueCredentials has the issuer sign count new tokens, checking the batch proof, and returns them as
+// redemptions toward the payload
+func issueCredentials(t *testing.T, client cbr.Client, issuer string, count int, payload string) []cbr.CredentialRedemption {
+	ctx := context.Background()
+	resp, err := client.GetIssuer(ctx, issuer)
+	require.NoError(t, err)
+
+	tokens := make([]*ristretto.Token, count)
+	blinded := make([]string, count)
+	for i := range tokens {
+		tokens[i], err = ristretto.NewToken()
+		require.NoError(t, err)
+		blinded[i], err = tokens[i].Blind()
+		require.NoError(t, err)
+	}
+	signed, err := client.SignCredentials(ctx, issuer, blinded)
+	require.NoError(t, err)
+	require.NoError(t, ristretto.VerifyBatchProof(resp.PublicKey, blinded, signed.SignedTokens, signed.BatchProof))
+
+	redemptions := make([]cbr.CredentialRedemption, count)
+	for i, token := range tokens {
+		unblinded, err := token.Unblind(signed.SignedTokens[i])
+		require.NoError(t, err)
+		redemptions[i] = cbr.CredentialRedemption{
+			Issuer:        issuer,
+			TokenPreimage: unblinded.EncodedPreimage(),
+			Signature:     unblinded.Sign(payload),
+		}
+	}
+	return redemptions
+}
+
+func TestServerClient(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	const issuer = "brave.com?sku=anon-card-vote"
+
+	_, err := server.GetIssuer(ctx, issuer)
+	assert.True(t, errors.Is(err, ErrIssuerNotFound))
+	require.NoError(t, server.CreateIssuer(ctx, issuer, 100))
+	assert.True(t, errors.Is(server.CreateIssuer(ctx, issuer, 100), ErrIssuerExists))
+
+	creds := issueCredentials(t, server, issuer, 3, "vote")
+	require.NoError(t, server.RedeemCredentials(ctx, creds[:2], "vote"))
+	assert.Equal(t, 2, server.Redeemed(issuer))
+
+	err = server.RedeemCredentials(ctx, creds, "vote")
+	assert.True(t, cbr.IsDuplicateRedemption(err), "credentials redeem once")
+	assert.Equal(t, 2, server.Redeemed(issuer), "no credential of a failed redemption is redeemed")
+
+	err = server.RedeemCredential(ctx, issuer, creds[2].TokenPreimage, creds[2].Signature, "another payload")
+	assert.True(t, errors.Is(err, ErrInvalidCredential), "credentials are bound to their payload")
+	assert.NoError(t, server.RedeemCredential(ctx, issuer, creds[2].TokenPreimage, creds[2].Signature, "vote"))
+
+	require.NoError(t, server.RevokeCredentials(ctx, issuer, []string{"blinded"}))
+	assert.Equal(t, []string{"blinded"}, server.Revoked(issuer))
+}
+
+func TestServerHTTP(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	require.NoError(t, os.Setenv("CHALLENGE_BYPASS_SERVER", ts.URL))
+	defer func() { _ = os.Unsetenv("CHALLENGE_BYPASS_SERVER") }()
+	client, err := cbr.New()
+	require.NoError(t, err)
+
+	const issuer = "brave.com?sku=brave-vpn&v=2"
+	require.NoError(t, client.CreateIssuer(ctx, issuer, 100))
+	key, err := server.SigningKey(issuer)
+	require.NoError(t, err, "issuer names are not escaped by the client")
+
+	creds := issueCredentials(t, client, issuer, 2, issuer)
+	assert.NoError(t, key.Verify(creds[0].TokenPreimage, creds[0].Signature, issuer))
+	require.NoError(t, client.RedeemCredential(ctx, issuer, creds[0].TokenPreimage, creds[0].Signature, issuer))
+	require.NoError(t, client.RedeemCredentials(ctx, creds[1:], issuer))
+
+	err = client.RedeemCredentials(ctx, creds, issuer)
+	assert.True(t, cbr.IsDuplicateRedemption(err), "conflicts are served as the server does")
+	require.NoError(t, client.RevokeCredentials(ctx, issuer, []string{"blinded"}))
+	assert.Equal(t, []string{"blinded"}, server.Revoked(issuer))
+}
+
+func TestServerFaults(t *testing.T) {
+	server := NewServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	require.NoError(t, os.Setenv("CHALLENGE_BYPASS_SERVER", ts.URL))
+	require.NoError(t, os.Setenv("FAULT_INJECTION", "true"))
+	defer func() {
+		_ = os.Unsetenv("CHALLENGE_BYPASS_SERVER")
+		_ = os.Unsetenv("FAULT_INJECTION")
+	}()
+	client, err := cbr.New()
+	require.NoError(t, err)
+
+	const issuer = "brave.com?sku=anon-card-vote"
+	require.NoError(t, server.CreateIssuer(context.Background(), issuer, 100))
+
+	ctx, err := faults.WithRule(context.Background(), faults.CBR, faults.Rule{ErrorCount: 2})
+	require.NoError(t, err)
+	_, err = client.GetIssuer(ctx, issuer)
+	assert.NoError(t, err, "calls failing fewer times than their retry attempts succeed")
+
+	ctx, err = faults.WithRule(context.Background(), faults.CBR, faults.Rule{ErrorCount: 1})
+	require.NoError(t, err)
+	err = client.RedeemCredentials(ctx, issueCredentials(t, client, issuer, 1, "vote"), "vote")
+	assert.True(t, errors.Is(err, faults.ErrInjected), "redemptions are not retried")
+}
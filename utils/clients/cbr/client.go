@@ -3,11 +3,17 @@ package cbr
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/brave-intl/bat-go/utils/clients"
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/faults"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Client abstracts over the underlying client
@@ -17,25 +23,84 @@ type Client interface {
 	SignCredentials(ctx context.Context, issuer string, creds []string) (*CredentialsIssueResponse, error)
 	RedeemCredential(ctx context.Context, issuer string, preimage string, signature string, payload string) error
 	RedeemCredentials(ctx context.Context, credentials []CredentialRedemption, payload string) error
+	RevokeCredentials(ctx context.Context, issuer string, creds []string) error
+}
+
+func init() {
+	prometheus.MustRegister(circuitOpen, clientRetries)
+
+	// cbr timeouts are retried, so they are reported as a single issue rather than per caller
+	reporting.RegisterFamily(reporting.Family{
+		Name:  "cbr-timeout",
+		Level: sentry.LevelWarning,
+		Match: isTimeout,
+	})
+}
+
+// isTimeout reports whether the error is a timed out request to the cbr server
+func isTimeout(err error) bool {
+	var eb *errorutils.ErrorBundle
+	for e := err; errors.As(e, &eb); e = eb.Cause() {
+		switch data := eb.Data().(type) {
+		case errorutils.Codified:
+			if data.ErrCode == "cbr_timeout" {
+				return true
+			}
+		case clients.HTTPState:
+			server := os.Getenv("CHALLENGE_BYPASS_SERVER")
+			if server == "" || !strings.HasPrefix(data.Path, server) {
+				return false
+			}
+			var netErr net.Error
+			return errors.Is(eb, context.DeadlineExceeded) || (errors.As(eb, &netErr) && netErr.Timeout())
+		}
+	}
+	return false
 }
 
 // HTTPClient wraps http.Client for interacting with the cbr server
 type HTTPClient struct {
 	client *clients.SimpleHTTPClient
+	opts   Options
 }
 
-// New returns a new HTTPClient, retrieving the base URL from the environment
+// New returns a new HTTPClient, retrieving the base URL and options from the environment, see OptionsFromEnv.
+// Calls are retried and pass through a circuit breaker, see ResilientClient
 func New() (Client, error) {
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithOptions(opts)
+}
+
+// NewWithOptions returns a new HTTPClient tuned with the options, retrieving the base URL from the environment
+func NewWithOptions(opts Options) (Client, error) {
 	serverEnvKey := "CHALLENGE_BYPASS_SERVER"
 	serverURL := os.Getenv("CHALLENGE_BYPASS_SERVER")
 	if len(serverURL) == 0 {
 		return nil, errors.New(serverEnvKey + " was empty")
 	}
-	client, err := clients.New(serverURL, os.Getenv("CHALLENGE_BYPASS_TOKEN"))
+	client, err := clients.NewWithHTTPClient(serverURL, os.Getenv("CHALLENGE_BYPASS_TOKEN"), opts.httpClient())
 	if err != nil {
 		return nil, err
 	}
-	return NewClientWithPrometheus(&HTTPClient{client}, "cbr_client"), err
+	if faults.Enabled() {
+		client.WrapTransport(faults.RoundTripper(faults.CBR))
+	}
+	resilient, err := newResilientClientFromEnv(&HTTPClient{client: client, opts: opts})
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithPrometheus(resilient, "cbr_client"), nil
+}
+
+// do the request of a call of the method, limited to the method's timeout
+func (c *HTTPClient) do(ctx context.Context, method string, req *http.Request, v interface{}) error {
+	ctx, cancel := c.opts.withTimeout(ctx, method)
+	defer cancel()
+	_, err := c.client.Do(ctx, req.WithContext(ctx), v)
+	return err
 }
 
 // IssuerCreateRequest is a request to create a new issuer
@@ -57,7 +122,7 @@ func (c *HTTPClient) CreateIssuer(ctx context.Context, issuer string, maxTokens
 		return err
 	}
 
-	_, err = c.client.Do(ctx, req, nil)
+	err = c.do(ctx, "CreateIssuer", req, nil)
 
 	return err
 }
@@ -70,7 +135,7 @@ func (c *HTTPClient) GetIssuer(ctx context.Context, issuer string) (*IssuerRespo
 	}
 
 	var resp IssuerResponse
-	_, err = c.client.Do(ctx, req, &resp)
+	err = c.do(ctx, "GetIssuer", req, &resp)
 
 	return &resp, err
 }
@@ -94,7 +159,7 @@ func (c *HTTPClient) SignCredentials(ctx context.Context, issuer string, creds [
 	}
 
 	var resp CredentialsIssueResponse
-	_, err = c.client.Do(ctx, req, &resp)
+	err = c.do(ctx, "SignCredentials", req, &resp)
 
 	return &resp, err
 }
@@ -165,6 +230,29 @@ func handleRedeemError(err error) error {
 	return err
 }
 
+// IsDuplicateRedemption is whether a redemption failed because a credential was already redeemed
+func IsDuplicateRedemption(err error) bool {
+	var eb *errorutils.ErrorBundle
+	if errors.As(err, &eb) {
+		if data, ok := eb.Data().(errorutils.Codified); ok {
+			return data.ErrCode == "cbr_dup_redeem"
+		}
+	}
+	return false
+}
+
+// IsRejectedRedemption is whether a redemption failed because the server rejected a credential, such as one
+// whose signature does not verify
+func IsRejectedRedemption(err error) bool {
+	var eb *errorutils.ErrorBundle
+	if errors.As(err, &eb) {
+		if data, ok := eb.Data().(errorutils.Codified); ok {
+			return data.ErrCode == "cbr_bad_request"
+		}
+	}
+	return false
+}
+
 // RedeemCredential that was issued by the specified issuer
 func (c *HTTPClient) RedeemCredential(ctx context.Context, issuer string, preimage string, signature string, payload string) error {
 	req, err := c.client.NewRequest(ctx, "POST", "v1/blindedToken/"+issuer+"/redemption/", &CredentialRedeemRequest{TokenPreimage: preimage, Signature: signature, Payload: payload}, nil)
@@ -172,7 +260,7 @@ func (c *HTTPClient) RedeemCredential(ctx context.Context, issuer string, preima
 		return err
 	}
 
-	_, err = c.client.Do(ctx, req, nil)
+	err = c.do(ctx, "RedeemCredential", req, nil)
 	return handleRedeemError(err)
 }
 
@@ -196,6 +284,22 @@ func (c *HTTPClient) RedeemCredentials(ctx context.Context, credentials []Creden
 		return err
 	}
 
-	_, err = c.client.Do(ctx, req, nil)
+	err = c.do(ctx, "RedeemCredentials", req, nil)
 	return handleRedeemError(err)
 }
+
+// CredentialsRevokeRequest is a request to revoke tokens signed for the blinded tokens
+type CredentialsRevokeRequest struct {
+	BlindedTokens []string `json:"blinded_tokens"`
+}
+
+// RevokeCredentials signed by the issuer for the blinded tokens, so they can no longer be redeemed
+func (c *HTTPClient) RevokeCredentials(ctx context.Context, issuer string, creds []string) error {
+	req, err := c.client.NewRequest(ctx, "POST", "v1/blindedToken/"+issuer+"/revocation/", &CredentialsRevokeRequest{BlindedTokens: creds}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(ctx, "RevokeCredentials", req, nil)
+	return err
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -41,6 +42,7 @@ func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometh
 // CreateIssuer implements Client
 func (_d ClientWithPrometheus) CreateIssuer(ctx context.Context, issuer string, maxTokens int) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CreateIssuer")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -48,6 +50,7 @@ func (_d ClientWithPrometheus) CreateIssuer(ctx context.Context, issuer string,
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "CreateIssuer", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.CreateIssuer(ctx, issuer, maxTokens)
 }
@@ -55,6 +58,7 @@ func (_d ClientWithPrometheus) CreateIssuer(ctx context.Context, issuer string,
 // GetIssuer implements Client
 func (_d ClientWithPrometheus) GetIssuer(ctx context.Context, issuer string) (ip1 *IssuerResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetIssuer")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -62,6 +66,7 @@ func (_d ClientWithPrometheus) GetIssuer(ctx context.Context, issuer string) (ip
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "GetIssuer", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetIssuer(ctx, issuer)
 }
@@ -69,6 +74,7 @@ func (_d ClientWithPrometheus) GetIssuer(ctx context.Context, issuer string) (ip
 // RedeemCredential implements Client
 func (_d ClientWithPrometheus) RedeemCredential(ctx context.Context, issuer string, preimage string, signature string, payload string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RedeemCredential")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -76,6 +82,7 @@ func (_d ClientWithPrometheus) RedeemCredential(ctx context.Context, issuer stri
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "RedeemCredential", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RedeemCredential(ctx, issuer, preimage, signature, payload)
 }
@@ -83,6 +90,7 @@ func (_d ClientWithPrometheus) RedeemCredential(ctx context.Context, issuer stri
 // RedeemCredentials implements Client
 func (_d ClientWithPrometheus) RedeemCredentials(ctx context.Context, credentials []CredentialRedemption, payload string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RedeemCredentials")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -90,13 +98,31 @@ func (_d ClientWithPrometheus) RedeemCredentials(ctx context.Context, credential
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "RedeemCredentials", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.RedeemCredentials(ctx, credentials, payload)
 }
 
+// RevokeCredentials implements Client
+func (_d ClientWithPrometheus) RevokeCredentials(ctx context.Context, issuer string, creds []string) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".RevokeCredentials")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "RevokeCredentials", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.RevokeCredentials(ctx, issuer, creds)
+}
+
 // SignCredentials implements Client
 func (_d ClientWithPrometheus) SignCredentials(ctx context.Context, issuer string, creds []string) (cp1 *CredentialsIssueResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".SignCredentials")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -104,6 +130,7 @@ func (_d ClientWithPrometheus) SignCredentials(ctx context.Context, issuer strin
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "SignCredentials", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.SignCredentials(ctx, issuer, creds)
 }
@@ -105,3 +105,17 @@ func (mr *MockClientMockRecorder) RedeemCredentials(ctx, credentials, payload in
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemCredentials", reflect.TypeOf((*MockClient)(nil).RedeemCredentials), ctx, credentials, payload)
 }
+
+// RevokeCredentials mocks base method
+func (m *MockClient) RevokeCredentials(ctx context.Context, issuer string, creds []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeCredentials", ctx, issuer, creds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeCredentials indicates an expected call of RevokeCredentials
+func (mr *MockClientMockRecorder) RevokeCredentials(ctx, issuer, creds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeCredentials", reflect.TypeOf((*MockClient)(nil).RevokeCredentials), ctx, issuer, creds)
+}
@@ -0,0 +1,132 @@
+package cbr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options tune the HTTP client calls to the challenge bypass server are made with
+type Options struct {
+	// Timeout is how long a call may take, unless CallTimeouts has one for its method. Each retry of a
+	// call gets the whole timeout
+	Timeout      time.Duration
+	CallTimeouts map[string]time.Duration
+	// MaxIdleConns and MaxIdleConnsPerHost are how many connections are kept open for reuse, the
+	// default of net/http keeping only 2 per host which churns connections under load
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	// HTTP2 attempts HTTP/2 with servers which support it
+	HTTP2 bool
+}
+
+// DefaultOptions are the options of a client which is not tuned
+func DefaultOptions() Options {
+	return Options{
+		Timeout:             10 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+	}
+}
+
+// OptionsFromEnv overrides the default options with CBR_TIMEOUT, CBR_CALL_TIMEOUTS (as in
+// "SignCredentials=5s,RedeemCredentials=2s"), CBR_MAX_IDLE_CONNS, CBR_MAX_IDLE_CONNS_PER_HOST,
+// CBR_IDLE_CONN_TIMEOUT, CBR_KEEP_ALIVE and CBR_HTTP2
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+	var err error
+	for key, value := range map[string]*time.Duration{
+		"CBR_TIMEOUT":           &opts.Timeout,
+		"CBR_IDLE_CONN_TIMEOUT": &opts.IdleConnTimeout,
+		"CBR_KEEP_ALIVE":        &opts.KeepAlive,
+	} {
+		if raw := os.Getenv(key); raw != "" {
+			if *value, err = time.ParseDuration(raw); err != nil || *value < 0 {
+				return opts, fmt.Errorf("invalid %s %q", key, raw)
+			}
+		}
+	}
+	for key, value := range map[string]*int{
+		"CBR_MAX_IDLE_CONNS":          &opts.MaxIdleConns,
+		"CBR_MAX_IDLE_CONNS_PER_HOST": &opts.MaxIdleConnsPerHost,
+	} {
+		if raw := os.Getenv(key); raw != "" {
+			if *value, err = strconv.Atoi(raw); err != nil || *value < 0 {
+				return opts, fmt.Errorf("invalid %s %q", key, raw)
+			}
+		}
+	}
+	if raw := os.Getenv("CBR_HTTP2"); raw != "" {
+		if opts.HTTP2, err = strconv.ParseBool(raw); err != nil {
+			return opts, fmt.Errorf("invalid CBR_HTTP2 %q", raw)
+		}
+	}
+	opts.CallTimeouts, err = ParseCallTimeouts(os.Getenv("CBR_CALL_TIMEOUTS"))
+	return opts, err
+}
+
+// ParseCallTimeouts parses timeouts by method of the form "SignCredentials=5s,RedeemCredentials=2s"
+func ParseCallTimeouts(raw string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if _, ok := defaultRetryPolicies[parts[0]]; !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid cbr call timeout %q", entry)
+		}
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid cbr call timeout %q", entry)
+		}
+		timeouts[parts[0]] = timeout
+	}
+	return timeouts, nil
+}
+
+// timeout is how long a call of the method may take, zero when it is not limited
+func (opts Options) timeout(method string) time.Duration {
+	if timeout, ok := opts.CallTimeouts[method]; ok {
+		return timeout
+	}
+	return opts.Timeout
+}
+
+// httpClient makes calls over a transport reusing connections as tuned. Timeouts are set per call
+func (opts Options) httpClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: opts.KeepAlive,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     opts.HTTP2,
+			MaxIdleConns:          opts.MaxIdleConns,
+			MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+			IdleConnTimeout:       opts.IdleConnTimeout,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+// withTimeout limits the context of a call of the method to its timeout
+func (opts Options) withTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	if timeout := opts.timeout(method); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
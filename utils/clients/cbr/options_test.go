@@ -0,0 +1,81 @@
+package cbr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	opts, err := OptionsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultOptions().MaxIdleConnsPerHost, opts.MaxIdleConnsPerHost)
+
+	env := map[string]string{
+		"CBR_TIMEOUT":                 "3s",
+		"CBR_CALL_TIMEOUTS":           "SignCredentials=5s, RedeemCredentials=500ms",
+		"CBR_MAX_IDLE_CONNS_PER_HOST": "64",
+		"CBR_KEEP_ALIVE":              "15s",
+		"CBR_HTTP2":                   "true",
+	}
+	for key, value := range env {
+		require.NoError(t, os.Setenv(key, value))
+	}
+	defer func() {
+		for key := range env {
+			_ = os.Unsetenv(key)
+		}
+	}()
+	opts, err = OptionsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, opts.timeout("SignCredentials"))
+	assert.Equal(t, 500*time.Millisecond, opts.timeout("RedeemCredentials"))
+	assert.Equal(t, 3*time.Second, opts.timeout("GetIssuer"))
+	assert.Equal(t, 64, opts.MaxIdleConnsPerHost)
+	assert.Equal(t, 100, opts.MaxIdleConns)
+	assert.Equal(t, 15*time.Second, opts.KeepAlive)
+	assert.True(t, opts.HTTP2)
+
+	transport := opts.httpClient().Transport.(*http.Transport)
+	assert.Equal(t, 64, transport.MaxIdleConnsPerHost)
+	assert.True(t, transport.ForceAttemptHTTP2)
+
+	require.NoError(t, os.Setenv("CBR_CALL_TIMEOUTS", "Sign=5s"))
+	_, err = OptionsFromEnv()
+	assert.Error(t, err, "timeouts are only set for methods of the client")
+	require.NoError(t, os.Setenv("CBR_CALL_TIMEOUTS", ""))
+	require.NoError(t, os.Setenv("CBR_MAX_IDLE_CONNS_PER_HOST", "many"))
+	_, err = OptionsFromEnv()
+	assert.Error(t, err)
+}
+
+func TestCallTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/blindedToken/issuer" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"name":"issuer","public_key":"key"}`))
+	}))
+	defer ts.Close()
+
+	opts := DefaultOptions()
+	opts.CallTimeouts = map[string]time.Duration{"SignCredentials": 50 * time.Millisecond}
+	simple, err := clients.NewWithHTTPClient(ts.URL, "", opts.httpClient())
+	require.NoError(t, err)
+	client := &HTTPClient{client: simple, opts: opts}
+
+	_, err = client.SignCredentials(context.Background(), "issuer", []string{"a"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "the call is limited to its timeout")
+
+	issuer, err := client.GetIssuer(context.Background(), "issuer")
+	require.NoError(t, err, "other calls keep the default timeout")
+	assert.Equal(t, "key", issuer.PublicKey)
+}
@@ -0,0 +1,38 @@
+package cbr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"github.com/brave-intl/bat-go/utils/reporting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutFingerprint(t *testing.T) {
+	server := os.Getenv("CHALLENGE_BYPASS_SERVER")
+	defer func() { _ = os.Setenv("CHALLENGE_BYPASS_SERVER", server) }()
+	assert.NoError(t, os.Setenv("CHALLENGE_BYPASS_SERVER", "http://cbr.local"))
+
+	signErr := clients.NewHTTPError(context.DeadlineExceeded, "http://cbr.local/v1/blindedToken/issuer", "response", 0, nil)
+	assert.Equal(t, []string{"cbr-timeout"}, reporting.Fingerprint(signErr))
+	assert.Equal(t, []string{"cbr-timeout"}, reporting.Fingerprint(handleRedeemError(context.DeadlineExceeded)))
+
+	otherServer := clients.NewHTTPError(context.DeadlineExceeded, "http://other.local/v1", "response", 0, nil)
+	assert.Nil(t, reporting.Fingerprint(otherServer), "timeouts of other services should keep the default grouping")
+	assert.Nil(t, reporting.Fingerprint(errors.New("failed")))
+}
+
+func TestIsDuplicateRedemption(t *testing.T) {
+	conflict := clients.NewHTTPError(nil, "http://cbr.local/v1/blindedToken/bulk/redemption/", "response", http.StatusConflict, nil)
+	assert.True(t, IsDuplicateRedemption(handleRedeemError(conflict)))
+	assert.True(t, IsDuplicateRedemption(fmt.Errorf("failed to redeem: %w", handleRedeemError(conflict))))
+
+	badRequest := clients.NewHTTPError(nil, "http://cbr.local/v1/blindedToken/bulk/redemption/", "response", http.StatusBadRequest, nil)
+	assert.False(t, IsDuplicateRedemption(handleRedeemError(badRequest)))
+	assert.False(t, IsDuplicateRedemption(conflict), "only redemption errors are classified")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/brave-intl/bat-go/utils/closers"
 	"github.com/brave-intl/bat-go/utils/errors"
 	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/getsentry/sentry-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
@@ -66,6 +67,27 @@ func New(serverURL string, authToken string) (*SimpleHTTPClient, error) {
 	}, nil
 }
 
+// NewWithHTTPClient returns a new SimpleHTTPClient making requests with the passed http.Client, so its
+// timeouts and transport can be tuned
+func NewWithHTTPClient(serverURL string, authToken string, client *http.Client) (*SimpleHTTPClient, error) {
+	baseURL, err := url.Parse(serverURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SimpleHTTPClient{
+		BaseURL:   baseURL,
+		AuthToken: authToken,
+		client:    client,
+	}, nil
+}
+
+// WrapTransport wraps the transport requests are made with
+func (c *SimpleHTTPClient) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	c.client.Transport = wrap(c.client.Transport)
+}
+
 // NewWithProxy returns a new SimpleHTTPClient, retrieving the base URL from the environment and adds a proxy
 func NewWithProxy(name string, serverURL string, authToken string, proxyURL string) (*SimpleHTTPClient, error) {
 	baseURL, err := url.Parse(serverURL)
@@ -198,7 +220,19 @@ func (c *SimpleHTTPClient) do(
 	ctx context.Context,
 	req *http.Request,
 	v interface{},
-) (*http.Response, error) {
+) (resp *http.Response, err error) {
+
+	// continue the trace in the service called
+	ctx, span := tracing.StartSpan(ctx, "HTTP "+req.Method, tracing.WithKind(tracing.SpanKindClient))
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	defer func() {
+		if resp != nil {
+			span.SetAttribute("http.status_code", resp.StatusCode)
+		}
+		span.End(err)
+	}()
+	tracing.Inject(ctx, req.Header)
 
 	// concurrent client request instrumentation
 	concurrentClientRequests.With(
@@ -213,7 +247,7 @@ func (c *SimpleHTTPClient) do(
 			}).Dec()
 	}()
 
-	resp, err := c.client.Do(req)
+	resp, err = c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,247 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+	"golang.org/x/crypto/sha3"
+)
+
+// TransferTopic is the topic of the Transfer(address,address,uint256) event of ERC-20 tokens
+var TransferTopic = "0x" + hex.EncodeToString(Keccak256([]byte("Transfer(address,address,uint256)")))
+
+// Client abstracts over the underlying client
+type Client interface {
+	// BlockNumber returns the number of the most recent block
+	BlockNumber(ctx context.Context) (uint64, error)
+	// GetLogs returns the logs matching the filter
+	GetLogs(ctx context.Context, filter LogFilter) ([]Log, error)
+}
+
+// HTTPClient wraps http.Client for calling the JSON-RPC api of an ethereum node
+type HTTPClient struct {
+	client *clients.SimpleHTTPClient
+}
+
+// New returns a new HTTPClient calling the node at ETH_RPC_URL
+func New() (Client, error) {
+	serverURL := os.Getenv("ETH_RPC_URL")
+	if serverURL == "" {
+		return nil, errors.New("ETH_RPC_URL was empty")
+	}
+	client, err := clients.New(serverURL, os.Getenv("ETH_RPC_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithPrometheus(&HTTPClient{client}, "ethereum_client"), nil
+}
+
+// RPCError is an error returned by the node
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("ethereum rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// call calls the method of the node, decoding its result into v
+func (c *HTTPClient) call(ctx context.Context, method string, v interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	req, err := c.client.NewRequest(ctx, "POST", "", rpcRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params}, nil)
+	if err != nil {
+		return err
+	}
+	var resp rpcResponse
+	if _, err := c.client.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// BlockNumber returns the number of the most recent block
+func (c *HTTPClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var number string
+	if err := c.call(ctx, "eth_blockNumber", &number); err != nil {
+		return 0, err
+	}
+	return ParseQuantity(number)
+}
+
+// LogFilter selects the logs of the contract between two blocks, inclusive. Topics are matched by position,
+// a position matching any of its topics and an empty position matching any topic
+type LogFilter struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Address   string
+	Topics    [][]string
+}
+
+// MarshalJSON encodes the filter as eth_getLogs takes it
+func (f LogFilter) MarshalJSON() ([]byte, error) {
+	topics := make([]interface{}, len(f.Topics))
+	for i, position := range f.Topics {
+		if len(position) > 0 {
+			topics[i] = position
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"fromBlock": EncodeQuantity(f.FromBlock),
+		"toBlock":   EncodeQuantity(f.ToBlock),
+		"address":   f.Address,
+		"topics":    topics,
+	})
+}
+
+// Log is an event logged by a contract
+type Log struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+	Removed         bool     `json:"removed"`
+}
+
+// GetLogs returns the logs matching the filter
+func (c *HTTPClient) GetLogs(ctx context.Context, filter LogFilter) ([]Log, error) {
+	var logs []Log
+	if err := c.call(ctx, "eth_getLogs", &logs, filter); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Transfer is an ERC-20 transfer, its value in the token's smallest unit
+type Transfer struct {
+	From            string
+	To              string
+	Value           *big.Int
+	BlockNumber     uint64
+	TransactionHash string
+	LogIndex        uint64
+}
+
+// ParseTransfer decodes the Transfer event of an ERC-20 token from its log
+func ParseTransfer(log Log) (*Transfer, error) {
+	if len(log.Topics) != 3 || log.Topics[0] != TransferTopic {
+		return nil, errors.New("log is not a transfer")
+	}
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(log.Data, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid transfer value %q", log.Data)
+	}
+	blockNumber, err := ParseQuantity(log.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	logIndex, err := ParseQuantity(log.LogIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{
+		From:            topicAddress(log.Topics[1]),
+		To:              topicAddress(log.Topics[2]),
+		Value:           value,
+		BlockNumber:     blockNumber,
+		TransactionHash: log.TransactionHash,
+		LogIndex:        logIndex,
+	}, nil
+}
+
+// AddressTopic is the topic of an indexed address parameter, the address left padded to 32 bytes
+func AddressTopic(address string) string {
+	address = strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if len(address) < 64 {
+		address = strings.Repeat("0", 64-len(address)) + address
+	}
+	return "0x" + address
+}
+
+func topicAddress(topic string) string {
+	topic = strings.TrimPrefix(topic, "0x")
+	if len(topic) < 40 {
+		return ""
+	}
+	return "0x" + strings.ToLower(topic[len(topic)-40:])
+}
+
+// EncodeQuantity encodes a number as a JSON-RPC quantity
+func EncodeQuantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// ParseQuantity parses a JSON-RPC quantity
+func ParseQuantity(quantity string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(quantity, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", quantity, err)
+	}
+	return n, nil
+}
+
+// Keccak256 hashes the data as ethereum does
+func Keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		_, _ = hash.Write(b)
+	}
+	return hash.Sum(nil)
+}
+
+// Create2Address returns the address a factory deploys a contract to with CREATE2, from the salt and the hash
+// of the contract's init code. Addresses are lower case
+func Create2Address(factory string, salt [32]byte, initCodeHash []byte) (string, error) {
+	factoryBytes, err := hex.DecodeString(strings.TrimPrefix(factory, "0x"))
+	if err != nil || len(factoryBytes) != 20 {
+		return "", fmt.Errorf("invalid factory address %q", factory)
+	}
+	if len(initCodeHash) != 32 {
+		return "", errors.New("init code hash must be 32 bytes")
+	}
+	hash := Keccak256([]byte{0xff}, factoryBytes, salt[:], initCodeHash)
+	return "0x" + hex.EncodeToString(hash[12:]), nil
+}
+
+// ChecksumAddress returns the mixed case checksum encoding of an address, see EIP-55
+func ChecksumAddress(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	hash := hex.EncodeToString(Keccak256([]byte(lower)))
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			checksummed[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(checksummed)
+}
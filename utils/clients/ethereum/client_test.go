@@ -0,0 +1,81 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate2Address(t *testing.T) {
+	// example 0 of EIP-1014
+	address, err := Create2Address("0x0000000000000000000000000000000000000000", [32]byte{}, Keccak256([]byte{0x00}))
+	require.NoError(t, err)
+	assert.Equal(t, "0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38", ChecksumAddress(address))
+
+	_, err = Create2Address("0x00", [32]byte{}, Keccak256([]byte{0x00}))
+	assert.Error(t, err)
+}
+
+func TestChecksumAddress(t *testing.T) {
+	assert.Equal(t, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", ChecksumAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"))
+	assert.Equal(t, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", ChecksumAddress("0xFB6916095CA1DF60BB79CE92CE3EA74C37C5D359"))
+}
+
+func TestGetLogs(t *testing.T) {
+	to := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.Method {
+		case "eth_blockNumber":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+		case "eth_getLogs":
+			filter := req.Params[0].(map[string]interface{})
+			assert.Equal(t, "0xa", filter["fromBlock"])
+			assert.Equal(t, []interface{}{[]interface{}{TransferTopic}, nil, []interface{}{AddressTopic(to)}}, filter["topics"])
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": []Log{{
+				Topics:          []string{TransferTopic, AddressTopic("0x01"), AddressTopic(to)},
+				Data:            "0x0de0b6b3a7640000",
+				BlockNumber:     "0xc",
+				TransactionHash: "0xabc",
+				LogIndex:        "0x2",
+			}}})
+		default:
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, os.Setenv("ETH_RPC_URL", server.URL))
+	defer func() { _ = os.Unsetenv("ETH_RPC_URL") }()
+	client, err := New()
+	require.NoError(t, err)
+
+	head, err := client.BlockNumber(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(16), head)
+
+	logs, err := client.GetLogs(context.Background(), LogFilter{
+		FromBlock: 10,
+		ToBlock:   head,
+		Address:   "0x0d8775f648430679a709e98d2b0cb6250d2887ef",
+		Topics:    [][]string{{TransferTopic}, nil, {AddressTopic(to)}},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	transfer, err := ParseTransfer(logs[0])
+	require.NoError(t, err)
+	assert.Equal(t, to, transfer.To)
+	assert.Equal(t, "0x0000000000000000000000000000000000000001", transfer.From)
+	assert.Equal(t, big.NewInt(1e18), transfer.Value)
+	assert.Equal(t, uint64(12), transfer.BlockNumber)
+	assert.Equal(t, uint64(2), transfer.LogIndex)
+}
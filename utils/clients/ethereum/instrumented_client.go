@@ -0,0 +1,72 @@
+package ethereum
+
+// DO NOT EDIT!
+// This code is generated with http://github.com/hexdigest/gowrap tool
+// using ../../../.prom-gowrap.tmpl template
+
+//go:generate gowrap gen -p github.com/brave-intl/bat-go/utils/clients/ethereum -i Client -t ../../../.prom-gowrap.tmpl -o instrumented_client.go
+
+import (
+	"context"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ClientWithPrometheus implements Client interface with all methods wrapped
+// with Prometheus metrics
+type ClientWithPrometheus struct {
+	base         Client
+	instanceName string
+}
+
+var clientDurationSummaryVec = promauto.NewSummaryVec(
+	prometheus.SummaryOpts{
+		Name:       "ethereum_client_duration_seconds",
+		Help:       "client runtime duration and result",
+		MaxAge:     time.Minute,
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	},
+	[]string{"instance_name", "method", "result"})
+
+// NewClientWithPrometheus returns an instance of the Client decorated with prometheus summary metric
+func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometheus {
+	return ClientWithPrometheus{
+		base:         base,
+		instanceName: instanceName,
+	}
+}
+
+// BlockNumber implements Client
+func (_d ClientWithPrometheus) BlockNumber(ctx context.Context) (u1 uint64, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".BlockNumber")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "BlockNumber", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.BlockNumber(ctx)
+}
+
+// GetLogs implements Client
+func (_d ClientWithPrometheus) GetLogs(ctx context.Context, filter LogFilter) (la1 []Log, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetLogs")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "GetLogs", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.GetLogs(ctx, filter)
+}
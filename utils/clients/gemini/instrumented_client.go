@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/brave-intl/bat-go/utils/cryptography"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -42,6 +43,7 @@ func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometh
 // CheckTxStatus implements Client
 func (_d ClientWithPrometheus) CheckTxStatus(ctx context.Context, APIKEY string, clientID string, txRef string) (pp1 *PayoutResult, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".CheckTxStatus")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -49,6 +51,7 @@ func (_d ClientWithPrometheus) CheckTxStatus(ctx context.Context, APIKEY string,
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "CheckTxStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.CheckTxStatus(ctx, APIKEY, clientID, txRef)
 }
@@ -56,6 +59,7 @@ func (_d ClientWithPrometheus) CheckTxStatus(ctx context.Context, APIKEY string,
 // FetchAccountList implements Client
 func (_d ClientWithPrometheus) FetchAccountList(ctx context.Context, APIKey string, signer cryptography.HMACKey, payload string) (aap1 *[]Account, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".FetchAccountList")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -63,6 +67,7 @@ func (_d ClientWithPrometheus) FetchAccountList(ctx context.Context, APIKey stri
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "FetchAccountList", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.FetchAccountList(ctx, APIKey, signer, payload)
 }
@@ -70,6 +75,7 @@ func (_d ClientWithPrometheus) FetchAccountList(ctx context.Context, APIKey stri
 // FetchBalances implements Client
 func (_d ClientWithPrometheus) FetchBalances(ctx context.Context, APIKey string, signer cryptography.HMACKey, payload string) (bap1 *[]Balance, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".FetchBalances")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -77,6 +83,7 @@ func (_d ClientWithPrometheus) FetchBalances(ctx context.Context, APIKey string,
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "FetchBalances", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.FetchBalances(ctx, APIKey, signer, payload)
 }
@@ -84,6 +91,7 @@ func (_d ClientWithPrometheus) FetchBalances(ctx context.Context, APIKey string,
 // UploadBulkPayout implements Client
 func (_d ClientWithPrometheus) UploadBulkPayout(ctx context.Context, APIKey string, signer cryptography.HMACKey, payload string) (pap1 *[]PayoutResult, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UploadBulkPayout")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -91,6 +99,7 @@ func (_d ClientWithPrometheus) UploadBulkPayout(ctx context.Context, APIKey stri
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "UploadBulkPayout", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.UploadBulkPayout(ctx, APIKey, signer, payload)
 }
@@ -98,6 +107,7 @@ func (_d ClientWithPrometheus) UploadBulkPayout(ctx context.Context, APIKey stri
 // ValidateAccount implements Client
 func (_d ClientWithPrometheus) ValidateAccount(ctx context.Context, verificationToken string) (s1 string, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ValidateAccount")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -105,6 +115,7 @@ func (_d ClientWithPrometheus) ValidateAccount(ctx context.Context, verification
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "ValidateAccount", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.ValidateAccount(ctx, verificationToken)
 }
@@ -10,6 +10,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -41,6 +42,7 @@ func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometh
 // FetchRate implements Client
 func (_d ClientWithPrometheus) FetchRate(ctx context.Context, base string, currency string) (rp1 *RateResponse, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".FetchRate")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -48,6 +50,7 @@ func (_d ClientWithPrometheus) FetchRate(ctx context.Context, base string, curre
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "FetchRate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.FetchRate(ctx, base, currency)
 }
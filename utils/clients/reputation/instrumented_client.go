@@ -10,6 +10,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	uuid "github.com/satori/go.uuid"
@@ -42,6 +43,7 @@ func NewClientWithPrometheus(base Client, instanceName string) ClientWithPrometh
 // IsWalletAdsReputable implements Client
 func (_d ClientWithPrometheus) IsWalletAdsReputable(ctx context.Context, id uuid.UUID, platform string) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".IsWalletAdsReputable")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -49,6 +51,7 @@ func (_d ClientWithPrometheus) IsWalletAdsReputable(ctx context.Context, id uuid
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "IsWalletAdsReputable", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.IsWalletAdsReputable(ctx, id, platform)
 }
@@ -56,6 +59,7 @@ func (_d ClientWithPrometheus) IsWalletAdsReputable(ctx context.Context, id uuid
 // IsWalletOnPlatform implements Client
 func (_d ClientWithPrometheus) IsWalletOnPlatform(ctx context.Context, id uuid.UUID, platform string) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".IsWalletOnPlatform")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -63,6 +67,7 @@ func (_d ClientWithPrometheus) IsWalletOnPlatform(ctx context.Context, id uuid.U
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "IsWalletOnPlatform", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.IsWalletOnPlatform(ctx, id, platform)
 }
@@ -70,6 +75,7 @@ func (_d ClientWithPrometheus) IsWalletOnPlatform(ctx context.Context, id uuid.U
 // IsWalletReputable implements Client
 func (_d ClientWithPrometheus) IsWalletReputable(ctx context.Context, id uuid.UUID, platform string) (b1 bool, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".IsWalletReputable")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -77,6 +83,7 @@ func (_d ClientWithPrometheus) IsWalletReputable(ctx context.Context, id uuid.UU
 		}
 
 		clientDurationSummaryVec.WithLabelValues(_d.instanceName, "IsWalletReputable", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.IsWalletReputable(ctx, id, platform)
 }
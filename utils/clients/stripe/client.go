@@ -0,0 +1,200 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/clients"
+)
+
+const (
+	// SignatureHeader carries the signature of a webhook sent by Stripe
+	SignatureHeader = "Stripe-Signature"
+	// DefaultTolerance is how old a webhook's signature may be, older ones are rejected as replays
+	DefaultTolerance = 5 * time.Minute
+
+	defaultServer = "https://api.stripe.com"
+)
+
+var (
+	// ErrInvalidSignature is returned for webhooks whose signature header is malformed or does not match
+	ErrInvalidSignature = errors.New("stripe: invalid webhook signature")
+	// ErrExpiredSignature is returned for webhooks signed longer ago than the tolerance
+	ErrExpiredSignature = errors.New("stripe: webhook signature has expired")
+)
+
+// Client abstracts over the underlying client
+type Client interface {
+	CreateCheckoutSession(ctx context.Context, req *CheckoutSessionRequest) (*CheckoutSession, error)
+}
+
+// HTTPClient wraps http.Client for interacting with the Stripe api
+type HTTPClient struct {
+	client *clients.SimpleHTTPClient
+}
+
+// New returns a new HTTPClient, authenticated with STRIPE_SECRET_KEY. STRIPE_SERVER overrides the api
+// server, for tests
+func New() (Client, error) {
+	secretKey := os.Getenv("STRIPE_SECRET_KEY")
+	if secretKey == "" {
+		return nil, errors.New("STRIPE_SECRET_KEY was empty")
+	}
+	serverURL := os.Getenv("STRIPE_SERVER")
+	if serverURL == "" {
+		serverURL = defaultServer
+	}
+	client, err := clients.New(serverURL, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithPrometheus(&HTTPClient{client}, "stripe_client"), nil
+}
+
+// LineItem is an item of a checkout session, priced in the smallest unit of its currency
+type LineItem struct {
+	Name       string
+	Currency   string
+	UnitAmount int64
+	Quantity   int
+}
+
+// CheckoutSessionRequest is a request to create a checkout session paying for an order
+type CheckoutSessionRequest struct {
+	// ClientReferenceID is the id of the order the session pays for, echoed in the session's webhooks
+	ClientReferenceID string
+	SuccessURL        string
+	CancelURL         string
+	LineItems         []LineItem
+	Metadata          map[string]string
+}
+
+// values form encodes the request the way the Stripe api expects
+func (req *CheckoutSessionRequest) values() url.Values {
+	v := url.Values{}
+	v.Set("mode", "payment")
+	v.Set("client_reference_id", req.ClientReferenceID)
+	v.Set("success_url", req.SuccessURL)
+	v.Set("cancel_url", req.CancelURL)
+	for i, item := range req.LineItems {
+		prefix := "line_items[" + strconv.Itoa(i) + "]"
+		v.Set(prefix+"[price_data][currency]", strings.ToLower(item.Currency))
+		v.Set(prefix+"[price_data][unit_amount]", strconv.FormatInt(item.UnitAmount, 10))
+		v.Set(prefix+"[price_data][product_data][name]", item.Name)
+		v.Set(prefix+"[quantity]", strconv.Itoa(item.Quantity))
+	}
+	for k, value := range req.Metadata {
+		v.Set("metadata["+k+"]", value)
+	}
+	return v
+}
+
+// CheckoutSession is a Stripe hosted payment page, and the payment made on it
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	PaymentStatus     string            `json:"payment_status"`
+	PaymentIntent     string            `json:"payment_intent"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// CreateCheckoutSession creates a checkout session the customer is redirected to, to pay
+func (c *HTTPClient) CreateCheckoutSession(ctx context.Context, req *CheckoutSessionRequest) (*CheckoutSession, error) {
+	r, err := c.client.NewRequest(ctx, "POST", "v1/checkout/sessions", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the api takes form encoded bodies rather than json
+	body := req.values().Encode()
+	r.Body = ioutil.NopCloser(strings.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("content-type", "application/x-www-form-urlencoded")
+	// retried creations return the first session rather than creating another
+	r.Header.Set("Idempotency-Key", "checkout-"+req.ClientReferenceID)
+
+	var session CheckoutSession
+	_, err = c.client.Do(ctx, r, &session)
+	return &session, err
+}
+
+// Event is a webhook sent by Stripe, Data.Object is the object the event is about
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object CheckoutSession `json:"object"`
+	} `json:"data"`
+}
+
+// VerifyWebhookSignature checks the Stripe-Signature header of a webhook payload against the endpoint's
+// signing secret. The header carries the time of signing, t, and one or more v1 signatures, hex HMAC-SHA256
+// of the time and payload, one for each active secret
+func VerifyWebhookSignature(payload []byte, header string, secret string, tolerance time.Duration, now time.Time) error {
+	var (
+		timestamp  string
+		signatures []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		sig, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(sig, expected) {
+			if now.Sub(time.Unix(signedAt, 0)) > tolerance {
+				return ErrExpiredSignature
+			}
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignWebhook returns a Stripe-Signature header for the payload, for tests of webhook endpoints
+func SignWebhook(payload []byte, secret string, signedAt time.Time) string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// IsCheckoutPaid is whether the event reports a checkout session whose payment succeeded
+func (e *Event) IsCheckoutPaid() bool {
+	switch e.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		return e.Data.Object.PaymentStatus == "paid"
+	}
+	return false
+}
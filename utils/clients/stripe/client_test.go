@@ -0,0 +1,70 @@
+package stripe
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := SignWebhook(payload, "whsec_test", signedAt)
+
+	assert.NoError(t, VerifyWebhookSignature(payload, header, "whsec_test", DefaultTolerance, signedAt.Add(time.Minute)))
+	// stripe sends a signature for each active secret while one is rolled
+	assert.NoError(t, VerifyWebhookSignature(payload, header+",v1=00ff", "whsec_test", DefaultTolerance, signedAt))
+
+	assert.Equal(t, ErrInvalidSignature, VerifyWebhookSignature(payload, header, "whsec_other", DefaultTolerance, signedAt))
+	assert.Equal(t, ErrInvalidSignature, VerifyWebhookSignature([]byte(`{}`), header, "whsec_test", DefaultTolerance, signedAt))
+	assert.Equal(t, ErrInvalidSignature, VerifyWebhookSignature(payload, "v1=00ff", "whsec_test", DefaultTolerance, signedAt))
+	assert.Equal(t, ErrExpiredSignature, VerifyWebhookSignature(payload, header, "whsec_test", DefaultTolerance, signedAt.Add(time.Hour)))
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/checkout/sessions", r.URL.Path)
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("authorization"))
+		assert.Equal(t, "checkout-order-1", r.Header.Get("Idempotency-Key"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		form, err = url.ParseQuery(string(body))
+		assert.NoError(t, err)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cs_test_1","url":"https://checkout.stripe.com/c/pay/cs_test_1"}`))
+	}))
+	defer server.Close()
+
+	defer os.Setenv("STRIPE_SECRET_KEY", os.Getenv("STRIPE_SECRET_KEY"))
+	defer os.Setenv("STRIPE_SERVER", os.Getenv("STRIPE_SERVER"))
+	os.Setenv("STRIPE_SECRET_KEY", "sk_test")
+	os.Setenv("STRIPE_SERVER", server.URL)
+
+	client, err := New()
+	require.NoError(t, err)
+	session, err := client.CreateCheckoutSession(context.Background(), &CheckoutSessionRequest{
+		ClientReferenceID: "order-1",
+		SuccessURL:        "https://example.com/success",
+		CancelURL:         "https://example.com/cancel",
+		LineItems:         []LineItem{{Name: "Brave VPN", Currency: "USD", UnitAmount: 999, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cs_test_1", session.ID)
+	assert.Equal(t, "https://checkout.stripe.com/c/pay/cs_test_1", session.URL)
+
+	assert.Equal(t, "payment", form.Get("mode"))
+	assert.Equal(t, "order-1", form.Get("client_reference_id"))
+	assert.Equal(t, "usd", form.Get("line_items[0][price_data][currency]"))
+	assert.Equal(t, "999", form.Get("line_items[0][price_data][unit_amount]"))
+	assert.Equal(t, "Brave VPN", form.Get("line_items[0][price_data][product_data][name]"))
+	assert.Equal(t, "1", form.Get("line_items[0][quantity]"))
+}
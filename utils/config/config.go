@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values of secret fields when a config is dumped
+const redacted = "[redacted]"
+
+// Errors are every problem found loading a config, so they can all be fixed at once
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("invalid configuration:\n%s", strings.Join(msgs, "\n"))
+}
+
+// Validator is implemented by configs with rules spanning several fields, such as mutually exclusive options
+type Validator interface {
+	Validate() []error
+}
+
+// Load fills the struct pointed to by cfg from the environment, as described by the tags of its fields:
+//
+//	env:"NAME"       the environment variable the field is read from
+//	default:"value"  the value used when the variable is unset
+//	required:"true"  the variable must be set
+//	validate:"rule"  url, port, hostport or oneof=a|b|c, applied to each element of slices
+//	secret:"true"    the value is redacted by Redacted
+//
+// Fields may be strings, bools, ints, durations, comma separated string slices, or nested structs whose
+// fields are tagged in turn. Every invalid field is reported, followed by the errors of Validate
+func Load(cfg interface{}) error {
+	var errs Errors
+	load(reflect.ValueOf(cfg).Elem(), &errs)
+	if validator, ok := cfg.(Validator); ok {
+		errs = append(errs, validator.Validate()...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func load(v reflect.Value, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			if value.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+				load(value, errs)
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			if field.Tag.Get("required") == "true" {
+				*errs = append(*errs, fmt.Errorf("%s is required", name))
+				continue
+			}
+			raw = field.Tag.Get("default")
+			if raw == "" {
+				continue
+			}
+		}
+		if err := set(value, raw); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s %w", name, err))
+			continue
+		}
+		if rule := field.Tag.Get("validate"); rule != "" {
+			values := []string{raw}
+			if value.Kind() == reflect.Slice {
+				values = value.Interface().([]string)
+			}
+			for _, s := range values {
+				if err := validate(rule, s); err != nil {
+					*errs = append(*errs, fmt.Errorf("%s %w", name, err))
+					break
+				}
+			}
+		}
+	}
+}
+
+func set(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s, got %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		values := []string{}
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("has unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func validate(rule, value string) error {
+	switch {
+	case rule == "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute url")
+		}
+	case rule == "port":
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("must be a port from 1 to 65535, got %q", value)
+		}
+	case rule == "hostport":
+		host, port, err := net.SplitHostPort(value)
+		if err != nil || host == "" {
+			return fmt.Errorf("must be a host:port, got %q", value)
+		}
+		return validate("port", port)
+	case strings.HasPrefix(rule, "oneof="):
+		options := strings.Split(strings.TrimPrefix(rule, "oneof="), "|")
+		for _, option := range options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(options, ", "), value)
+	default:
+		return fmt.Errorf("has unknown validation %s", rule)
+	}
+	return nil
+}
+
+// Exclusive returns an error when more than one of the options is set, keyed by their variable names
+func Exclusive(options map[string]bool) error {
+	set := []string{}
+	for name, isSet := range options {
+		if isSet {
+			set = append(set, name)
+		}
+	}
+	if len(set) < 2 {
+		return nil
+	}
+	sort.Strings(set)
+	return fmt.Errorf("only one of %s may be set", strings.Join(set, ", "))
+}
+
+// Redacted returns the effective config keyed by variable name, with the values of secret fields that
+// are set replaced, so it can be shown to operators
+func Redacted(cfg interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	redact(reflect.Indirect(reflect.ValueOf(cfg)), out)
+	return out
+}
+
+func redact(v reflect.Value, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			if value.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+				redact(value, out)
+			}
+			continue
+		}
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			out[name] = redacted
+			continue
+		}
+		if d, ok := value.Interface().(time.Duration); ok {
+			out[name] = d.String()
+			continue
+		}
+		out[name] = value.Interface()
+	}
+}
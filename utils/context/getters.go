@@ -3,8 +3,10 @@ package context
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
+	"github.com/brave-intl/bat-go/utils/requestutils"
 	"github.com/rs/zerolog"
 )
 
@@ -89,3 +91,55 @@ func GetLogger(ctx context.Context) (*zerolog.Logger, error) {
 	}
 	return l, nil
 }
+
+// Datastore sets target, a pointer to the datastore interface the caller expects, to the datastore of the
+// context. It errors when the context has no datastore or one of another type
+func Datastore(ctx context.Context, target interface{}) error {
+	return valueAs(ctx, DatastoreCTXKey, target)
+}
+
+// ReadOnlyDatastore sets target, a pointer to the read only datastore interface the caller expects, to
+// the read only datastore of the context. It errors like Datastore
+func ReadOnlyDatastore(ctx context.Context, target interface{}) error {
+	return valueAs(ctx, RODatastoreCTXKey, target)
+}
+
+// WithDatastore attaches a datastore to a context
+func WithDatastore(ctx context.Context, datastore interface{}) context.Context {
+	return context.WithValue(ctx, DatastoreCTXKey, datastore)
+}
+
+// WithReadOnlyDatastore attaches a read only datastore to a context
+func WithReadOnlyDatastore(ctx context.Context, datastore interface{}) context.Context {
+	return context.WithValue(ctx, RODatastoreCTXKey, datastore)
+}
+
+// Logger returns the logger of the context, an error when it only has the disabled logger
+func Logger(ctx context.Context) (*zerolog.Logger, error) {
+	return GetLogger(ctx)
+}
+
+// RequestID returns the id of the request the context was made for, an error outside of requests
+func RequestID(ctx context.Context) (string, error) {
+	if id := requestutils.GetRequestID(ctx); id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("request id not found in context: %w", ErrNotInContext)
+}
+
+// valueAs sets target, which must be a non nil pointer, to the value of the key if the value is assignable
+func valueAs(ctx context.Context, key CTXKey, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		panic("appctx: target must be a non-nil pointer")
+	}
+	v := ctx.Value(key)
+	if v == nil {
+		return fmt.Errorf("%s not found in context: %w", key, ErrNotInContext)
+	}
+	if !reflect.TypeOf(v).AssignableTo(ptr.Elem().Type()) {
+		return fmt.Errorf("%s in context is a %T, not a %s: %w", key, v, ptr.Elem().Type(), ErrValueWrongType)
+	}
+	ptr.Elem().Set(reflect.ValueOf(v))
+	return nil
+}
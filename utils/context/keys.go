@@ -73,6 +73,12 @@ const (
 	ReputationOnDrainCTXKey CTXKey = "reputation_on_drain"
 	// SkipRedeemCredentialsCTXKey - context key for getting the skip redeem credentials
 	SkipRedeemCredentialsCTXKey CTXKey = "skip_redeem_credentials"
+	// RateLimitedMerchantCTXKey - context key for the settings of the merchant a request is rate limited by
+	RateLimitedMerchantCTXKey CTXKey = "rate_limited_merchant"
+	// PrincipalCTXKey - context key for the principal a request was authenticated as
+	PrincipalCTXKey CTXKey = "principal"
+	// FaultRulesCTXKey - context key for the faults injected into the calls made with a context
+	FaultRulesCTXKey CTXKey = "fault_rules"
 )
 
 var (
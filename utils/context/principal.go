@@ -0,0 +1,68 @@
+package context
+
+import (
+	"context"
+)
+
+const (
+	// RoleAdmin is the role of operators administering the services
+	RoleAdmin = "admin"
+	// RoleService is the role of the services and tooling calling one another
+	RoleService = "service"
+	// RoleMerchant is the role of merchants integrating with the services
+	RoleMerchant = "merchant"
+)
+
+// Principal is who a request was authenticated as, with the scopes they were granted
+type Principal struct {
+	// Subject identifies the principal, such as the id of an api key
+	Subject string
+	Role    string
+	// Merchant is the merchant a merchant principal acts for
+	Merchant string
+	Scopes   []string
+	// AllScopes is set for principals granted every scope, such as the holders of the simple tokens
+	AllScopes bool
+}
+
+// HasScope checks if the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	if p.AllScopes {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole checks if the principal has one of the roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a context carrying the principal a request was authenticated as
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, PrincipalCTXKey, principal)
+}
+
+// GetPrincipal returns the principal the request of the context was authenticated as
+func GetPrincipal(ctx context.Context) (*Principal, error) {
+	v := ctx.Value(PrincipalCTXKey)
+	if v == nil {
+		// request not authenticated
+		return nil, ErrNotInContext
+	}
+	if p, ok := v.(*Principal); ok {
+		return p, nil
+	}
+	// value not a principal
+	return nil, ErrValueWrongType
+}
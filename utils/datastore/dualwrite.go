@@ -0,0 +1,134 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DualWriteMode is the stage of a table's migration to a new layout
+type DualWriteMode string
+
+const (
+	// DualWriteOff only uses the old table
+	DualWriteOff DualWriteMode = "off"
+	// DualWriteShadow writes both tables and reads the old one, comparing the reads against the new one
+	DualWriteShadow DualWriteMode = "shadow"
+	// DualWriteReadNew writes both tables and reads the new one
+	DualWriteReadNew DualWriteMode = "read-new"
+)
+
+var dualWriteDivergence = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "datastore_dual_write_divergence_total",
+		Help: "Writes which failed on one table only and reads which differed between the old and new table",
+	},
+	[]string{"table", "operation"},
+)
+
+func init() {
+	prometheus.MustRegister(dualWriteDivergence)
+}
+
+// DualWrite moves a table to a new layout without downtime, writing the old and new table while the
+// new one is backfilled and verified. Its mode is the feature flag DUAL_WRITE_<TABLE>
+type DualWrite struct {
+	Table string
+}
+
+// NewDualWrite creates the dual write of the table
+func NewDualWrite(table string) *DualWrite {
+	return &DualWrite{Table: table}
+}
+
+// Mode returns the mode the feature flag is set to, off when unset or unknown
+func (dw *DualWrite) Mode() DualWriteMode {
+	switch mode := DualWriteMode(os.Getenv("DUAL_WRITE_" + strings.ToUpper(dw.Table))); mode {
+	case DualWriteShadow, DualWriteReadNew:
+		return mode
+	default:
+		return DualWriteOff
+	}
+}
+
+// Enabled reports whether the new table is written
+func (dw *DualWrite) Enabled() bool {
+	return dw.Mode() != DualWriteOff
+}
+
+// Write writes the old table and then, when enabled, the new table. A failed write of the new table
+// only fails the write once reads are served from it
+func (dw *DualWrite) Write(ctx context.Context, operation string, writeOld, writeNew func() error) error {
+	if err := writeOld(); err != nil {
+		return err
+	}
+	mode := dw.Mode()
+	if mode == DualWriteOff {
+		return nil
+	}
+	if err := writeNew(); err != nil {
+		dw.diverged(ctx, operation, err)
+		if mode == DualWriteReadNew {
+			return fmt.Errorf("failed to write %s: %w", dw.Table, err)
+		}
+	}
+	return nil
+}
+
+// WriteTx is Write for writes made within a transaction. The new table is written under a savepoint
+// so its failure does not abort the transaction
+func (dw *DualWrite) WriteTx(ctx context.Context, tx *sqlx.Tx, operation string, writeOld, writeNew func() error) error {
+	return dw.Write(ctx, operation, writeOld, func() error {
+		if _, err := tx.ExecContext(ctx, "savepoint dual_write"); err != nil {
+			return err
+		}
+		if err := writeNew(); err != nil {
+			if _, rollbackErr := tx.ExecContext(ctx, "rollback to savepoint dual_write"); rollbackErr != nil {
+				return rollbackErr
+			}
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "release savepoint dual_write")
+		return err
+	})
+}
+
+// Read reads the table the mode serves reads from. In shadow mode the new table is read as well and
+// any difference from the old table is counted
+func (dw *DualWrite) Read(ctx context.Context, operation string, readOld, readNew func() (interface{}, error)) (interface{}, error) {
+	switch dw.Mode() {
+	case DualWriteReadNew:
+		return readNew()
+	case DualWriteShadow:
+		old, err := readOld()
+		if err != nil {
+			return nil, err
+		}
+		shadow, err := readNew()
+		if err != nil {
+			dw.diverged(ctx, operation, err)
+		} else if !reflect.DeepEqual(old, shadow) {
+			dw.diverged(ctx, operation, fmt.Errorf("%s differs between the old and new table", operation))
+		}
+		return old, nil
+	default:
+		return readOld()
+	}
+}
+
+func (dw *DualWrite) diverged(ctx context.Context, operation string, err error) {
+	dualWriteDivergence.With(prometheus.Labels{"table": dw.Table, "operation": operation}).Inc()
+
+	logger, lerr := appctx.GetLogger(ctx)
+	if lerr != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+	logger.Warn().Err(err).Str("table", dw.Table).Str("operation", operation).Msg("dual write diverged")
+}
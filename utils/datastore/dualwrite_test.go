@@ -0,0 +1,114 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setMode(t *testing.T, table string, mode DualWriteMode) {
+	key := "DUAL_WRITE_" + table
+	require.NoError(t, os.Setenv(key, string(mode)))
+	t.Cleanup(func() { _ = os.Unsetenv(key) })
+}
+
+func TestDualWriteMode(t *testing.T) {
+	dw := NewDualWrite("votes")
+	assert.Equal(t, DualWriteOff, dw.Mode())
+
+	setMode(t, "VOTES", "bogus")
+	assert.Equal(t, DualWriteOff, dw.Mode())
+	assert.False(t, dw.Enabled())
+
+	setMode(t, "VOTES", DualWriteShadow)
+	assert.Equal(t, DualWriteShadow, dw.Mode())
+	assert.True(t, dw.Enabled())
+}
+
+func TestDualWriteWrite(t *testing.T) {
+	ctx := context.Background()
+	dw := NewDualWrite("votes")
+	failNew := errors.New("new table failed")
+
+	var wroteNew bool
+	writeNew := func() error {
+		wroteNew = true
+		return failNew
+	}
+	ok := func() error { return nil }
+
+	// off never touches the new table
+	require.NoError(t, dw.Write(ctx, "insert", ok, writeNew))
+	assert.False(t, wroteNew)
+
+	// a failed old write is always returned, without writing the new table
+	setMode(t, "VOTES", DualWriteShadow)
+	assert.Error(t, dw.Write(ctx, "insert", func() error { return errors.New("old failed") }, writeNew))
+	assert.False(t, wroteNew)
+
+	// in shadow mode the new table failing does not fail the write
+	require.NoError(t, dw.Write(ctx, "insert", ok, writeNew))
+	assert.True(t, wroteNew)
+
+	// once reads are served from the new table it does
+	setMode(t, "VOTES", DualWriteReadNew)
+	assert.True(t, errors.Is(dw.Write(ctx, "insert", ok, writeNew), failNew))
+}
+
+func TestDualWriteWriteTx(t *testing.T) {
+	ctx := context.Background()
+	dw := NewDualWrite("votes")
+	setMode(t, "VOTES", DualWriteShadow)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(mockDB, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("update votes ").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("savepoint dual_write").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("update votes_v2").WillReturnError(errors.New("no such table"))
+	mock.ExpectExec("rollback to savepoint dual_write").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := db.Beginx()
+	require.NoError(t, err)
+	err = dw.WriteTx(ctx, tx, "update", func() error {
+		_, err := tx.ExecContext(ctx, "update votes set processed=true")
+		return err
+	}, func() error {
+		_, err := tx.ExecContext(ctx, "update votes_v2 set processed=true")
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDualWriteRead(t *testing.T) {
+	ctx := context.Background()
+	dw := NewDualWrite("votes")
+	readOld := func() (interface{}, error) { return "old", nil }
+	readNew := func() (interface{}, error) { return "new", nil }
+
+	v, err := dw.Read(ctx, "get", readOld, readNew)
+	require.NoError(t, err)
+	assert.Equal(t, "old", v)
+
+	// shadow mode serves the old table even when the new one differs
+	setMode(t, "VOTES", DualWriteShadow)
+	v, err = dw.Read(ctx, "get", readOld, readNew)
+	require.NoError(t, err)
+	assert.Equal(t, "old", v)
+
+	setMode(t, "VOTES", DualWriteReadNew)
+	v, err = dw.Read(ctx, "get", readOld, readNew)
+	require.NoError(t, err)
+	assert.Equal(t, "new", v)
+}
@@ -0,0 +1,22 @@
+package dbhealth
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// Router exposes the state of the connection pools and the top blocking queries of the window
+func Router(m *Monitor) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/", GetSummary(m))
+	return r
+}
+
+// GetSummary is the handler for summarizing pool health and blocking queries
+func GetSummary(m *Monitor) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return handlers.RenderContent(r.Context(), m.Summary(), w, http.StatusOK)
+	})
+}
@@ -0,0 +1,404 @@
+package dbhealth
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// SeverityWarning is the severity label of a threshold worth looking into
+	SeverityWarning = "warning"
+	// SeverityCritical is the severity label of a threshold worth paging for
+	SeverityCritical = "critical"
+
+	// CheckSaturation compares the connections in use with the pool's maximum
+	CheckSaturation = "pool_saturation"
+	// CheckWait compares the average wait for a connection with its thresholds
+	CheckWait = "pool_wait"
+	// CheckTransaction compares the oldest open transaction with its thresholds
+	CheckTransaction = "transaction_duration"
+
+	// DefaultWindow is how long blocking queries are summarized over
+	DefaultWindow = 15 * time.Minute
+	// topBlockingQueries is how many blocking queries the summary lists
+	topBlockingQueries = 10
+)
+
+var (
+	saturationGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_saturation_ratio",
+			Help: "Connections in use over the maximum open connections of the pool",
+		},
+		[]string{"db_name"},
+	)
+	waitGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_wait_seconds_avg",
+			Help: "Average wait for a connection since the previous sample",
+		},
+		[]string{"db_name"},
+	)
+	waitRateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_pool_waits_per_second",
+			Help: "Connections waited for per second since the previous sample",
+		},
+		[]string{"db_name"},
+	)
+	transactionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_transaction_age_seconds_max",
+			Help: "Age of the oldest open transaction of the pool's connections",
+		},
+		[]string{"db_name"},
+	)
+	alertGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_threshold_breached",
+			Help: "Whether a database check is over its threshold of the severity, 1, or not, 0",
+		},
+		[]string{"db_name", "check", "severity"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(saturationGauge, waitGauge, waitRateGauge, transactionGauge, alertGauge)
+}
+
+// Threshold is the warning and critical levels of a check
+type Threshold struct {
+	Warning  float64 `json:"warning"`
+	Critical float64 `json:"critical"`
+}
+
+// Severity of the value, empty when it is under both levels
+func (t Threshold) Severity(value float64) string {
+	switch {
+	case t.Critical > 0 && value >= t.Critical:
+		return SeverityCritical
+	case t.Warning > 0 && value >= t.Warning:
+		return SeverityWarning
+	}
+	return ""
+}
+
+// Thresholds are the levels the checks alert at
+type Thresholds struct {
+	// Saturation is the ratio of connections in use to the pool's maximum
+	Saturation Threshold `json:"saturation"`
+	// WaitSeconds is the average wait for a connection
+	WaitSeconds Threshold `json:"waitSeconds"`
+	// TransactionSeconds is the age of the oldest open transaction
+	TransactionSeconds Threshold `json:"transactionSeconds"`
+}
+
+// DefaultThresholds alert at a pool 80% in use, waits of 50ms and transactions open for 30s
+var DefaultThresholds = Thresholds{
+	Saturation:         Threshold{Warning: 0.8, Critical: 0.95},
+	WaitSeconds:        Threshold{Warning: 0.05, Critical: 0.25},
+	TransactionSeconds: Threshold{Warning: 30, Critical: 120},
+}
+
+// PoolStatus is the latest derived stats of a pool and the checks it is over the thresholds of
+type PoolStatus struct {
+	Name                  string            `json:"name"`
+	InUse                 int               `json:"inUse"`
+	MaxOpen               int               `json:"maxOpen"`
+	Saturation            float64           `json:"saturation"`
+	WaitSecondsAvg        float64           `json:"waitSecondsAvg"`
+	WaitsPerSecond        float64           `json:"waitsPerSecond"`
+	TransactionAgeSeconds float64           `json:"transactionAgeSeconds"`
+	Alerts                map[string]string `json:"alerts"`
+	SampledAt             time.Time         `json:"sampledAt"`
+}
+
+// BlockingQuery is a query observed blocking others
+type BlockingQuery struct {
+	DBName string `json:"dbName" db:"application_name"`
+	Query  string `json:"query" db:"query"`
+	State  string `json:"state" db:"state"`
+	// Blocked is how many backends were waiting on it when it was observed
+	Blocked int `json:"blocked" db:"blocked"`
+	// QuerySeconds is how long it had been running when it was observed
+	QuerySeconds float64 `json:"querySeconds" db:"query_seconds"`
+}
+
+// BlockingSummary is a blocking query aggregated over the window
+type BlockingSummary struct {
+	DBName          string    `json:"dbName"`
+	Query           string    `json:"query"`
+	Observations    int       `json:"observations"`
+	MaxBlocked      int       `json:"maxBlocked"`
+	MaxQuerySeconds float64   `json:"maxQuerySeconds"`
+	LastSeen        time.Time `json:"lastSeen"`
+}
+
+// Summary is the state of the pools and the top blocking queries of the window
+type Summary struct {
+	Pools      []PoolStatus      `json:"pools"`
+	Blocking   []BlockingSummary `json:"blocking"`
+	Window     string            `json:"window"`
+	Thresholds Thresholds        `json:"thresholds"`
+}
+
+// Activity reads what the database is doing
+type Activity interface {
+	// TransactionAges returns the age in seconds of the oldest open transaction of each application
+	TransactionAges(ctx context.Context) (map[string]float64, error)
+	// BlockingQueries returns the queries blocking other backends
+	BlockingQueries(ctx context.Context) ([]BlockingQuery, error)
+}
+
+// StatsGetter is a connection pool, such as *sqlx.DB
+type StatsGetter interface {
+	Stats() sql.DBStats
+}
+
+type pool struct {
+	stats  StatsGetter
+	last   sql.DBStats
+	lastAt time.Time
+	status PoolStatus
+}
+
+type observation struct {
+	BlockingQuery
+	at time.Time
+}
+
+// Monitor samples connection pools and database activity, deriving alert ready metrics and keeping the
+// blocking queries observed in the window
+type Monitor struct {
+	mu           sync.Mutex
+	activity     Activity
+	thresholds   Thresholds
+	window       time.Duration
+	pools        map[string]*pool
+	observations []observation
+	now          func() time.Time
+}
+
+// NewMonitor creates a monitor reading database activity from the activity, which may be nil
+func NewMonitor(activity Activity, thresholds Thresholds) *Monitor {
+	return &Monitor{
+		activity:   activity,
+		thresholds: thresholds,
+		window:     DefaultWindow,
+		pools:      map[string]*pool{},
+		now:        time.Now,
+	}
+}
+
+// Add a pool to monitor, the name is its db_name label and its connections' application_name
+func (m *Monitor) Add(name string, stats StatsGetter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[name] = &pool{stats: stats, last: stats.Stats(), lastAt: m.now()}
+}
+
+// Jobs samples the pools and database activity every fifteen seconds
+func (m *Monitor) Jobs() []srv.Job {
+	return []srv.Job{
+		{
+			Name:    "db_health",
+			Service: "grant",
+			Func: func(ctx context.Context) (bool, error) {
+				return true, m.Sample(ctx)
+			},
+			Cadence: 15 * time.Second,
+			Workers: 1,
+		},
+	}
+}
+
+// Sample the pools and database activity, updating the metrics
+func (m *Monitor) Sample(ctx context.Context) error {
+	var (
+		ages     map[string]float64
+		blocking []BlockingQuery
+		err      error
+	)
+	if m.activity != nil {
+		if ages, err = m.activity.TransactionAges(ctx); err != nil {
+			return err
+		}
+		if blocking, err = m.activity.BlockingQueries(ctx); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for name, p := range m.pools {
+		stats := p.stats.Stats()
+		status := PoolStatus{
+			Name:                  name,
+			InUse:                 stats.InUse,
+			MaxOpen:               stats.MaxOpenConnections,
+			TransactionAgeSeconds: ages[name],
+			Alerts:                map[string]string{},
+			SampledAt:             now,
+		}
+		if stats.MaxOpenConnections > 0 {
+			status.Saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		}
+		if waits := stats.WaitCount - p.last.WaitCount; waits > 0 {
+			status.WaitSecondsAvg = (stats.WaitDuration - p.last.WaitDuration).Seconds() / float64(waits)
+			if elapsed := now.Sub(p.lastAt).Seconds(); elapsed > 0 {
+				status.WaitsPerSecond = float64(waits) / elapsed
+			}
+		}
+		p.last, p.lastAt, p.status = stats, now, status
+
+		saturationGauge.With(prometheus.Labels{"db_name": name}).Set(status.Saturation)
+		waitGauge.With(prometheus.Labels{"db_name": name}).Set(status.WaitSecondsAvg)
+		waitRateGauge.With(prometheus.Labels{"db_name": name}).Set(status.WaitsPerSecond)
+		transactionGauge.With(prometheus.Labels{"db_name": name}).Set(status.TransactionAgeSeconds)
+		m.check(&status, CheckSaturation, m.thresholds.Saturation, status.Saturation)
+		m.check(&status, CheckWait, m.thresholds.WaitSeconds, status.WaitSecondsAvg)
+		m.check(&status, CheckTransaction, m.thresholds.TransactionSeconds, status.TransactionAgeSeconds)
+	}
+
+	for _, query := range blocking {
+		m.observations = append(m.observations, observation{BlockingQuery: query, at: now})
+	}
+	m.expire(now)
+	return nil
+}
+
+// check sets the alert metric of each severity of the check, recording the breached severity on the status
+func (m *Monitor) check(status *PoolStatus, check string, threshold Threshold, value float64) {
+	severity := threshold.Severity(value)
+	if severity != "" {
+		status.Alerts[check] = severity
+	}
+	for _, s := range []string{SeverityWarning, SeverityCritical} {
+		breached := 0.0
+		if severity == SeverityCritical || severity == s {
+			breached = 1
+		}
+		alertGauge.With(prometheus.Labels{"db_name": status.Name, "check": check, "severity": s}).Set(breached)
+	}
+}
+
+// expire observations older than the window
+func (m *Monitor) expire(now time.Time) {
+	cutoff := now.Add(-m.window)
+	i := 0
+	for i < len(m.observations) && m.observations[i].at.Before(cutoff) {
+		i++
+	}
+	m.observations = m.observations[i:]
+}
+
+// Summary of the pools and the queries which blocked the most over the window
+func (m *Monitor) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(m.now())
+
+	summary := Summary{
+		Pools:      []PoolStatus{},
+		Blocking:   []BlockingSummary{},
+		Window:     m.window.String(),
+		Thresholds: m.thresholds,
+	}
+	for _, p := range m.pools {
+		summary.Pools = append(summary.Pools, p.status)
+	}
+	sort.Slice(summary.Pools, func(i, j int) bool { return summary.Pools[i].Name < summary.Pools[j].Name })
+
+	byQuery := map[string]*BlockingSummary{}
+	for _, o := range m.observations {
+		key := o.DBName + "\x00" + o.Query
+		s, ok := byQuery[key]
+		if !ok {
+			s = &BlockingSummary{DBName: o.DBName, Query: o.Query}
+			byQuery[key] = s
+		}
+		s.Observations++
+		if o.Blocked > s.MaxBlocked {
+			s.MaxBlocked = o.Blocked
+		}
+		if o.QuerySeconds > s.MaxQuerySeconds {
+			s.MaxQuerySeconds = o.QuerySeconds
+		}
+		if o.at.After(s.LastSeen) {
+			s.LastSeen = o.at
+		}
+	}
+	for _, s := range byQuery {
+		summary.Blocking = append(summary.Blocking, *s)
+	}
+	sort.Slice(summary.Blocking, func(i, j int) bool {
+		a, b := summary.Blocking[i], summary.Blocking[j]
+		if a.Observations != b.Observations {
+			return a.Observations > b.Observations
+		}
+		return a.MaxBlocked > b.MaxBlocked
+	})
+	if len(summary.Blocking) > topBlockingQueries {
+		summary.Blocking = summary.Blocking[:topBlockingQueries]
+	}
+	return summary
+}
+
+// PostgresActivity reads activity from pg_stat_activity
+type PostgresActivity struct {
+	db *sqlx.DB
+}
+
+// NewPostgresActivity creates an activity reader of the database
+func NewPostgresActivity(db *sqlx.DB) *PostgresActivity {
+	return &PostgresActivity{db: db}
+}
+
+// TransactionAges returns the age in seconds of the oldest open transaction of each application
+func (a *PostgresActivity) TransactionAges(ctx context.Context) (map[string]float64, error) {
+	var rows []struct {
+		ApplicationName string  `db:"application_name"`
+		Seconds         float64 `db:"seconds"`
+	}
+	err := a.db.SelectContext(ctx, &rows, `
+			SELECT application_name, extract(epoch FROM max(now() - xact_start))::float8 AS seconds
+			FROM pg_stat_activity
+			WHERE datname = current_database() AND xact_start IS NOT NULL AND pid <> pg_backend_pid()
+			GROUP BY application_name
+		`)
+	if err != nil {
+		return nil, err
+	}
+	ages := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		ages[row.ApplicationName] = row.Seconds
+	}
+	return ages, nil
+}
+
+// BlockingQueries returns the queries blocking other backends, truncated to a readable length
+func (a *PostgresActivity) BlockingQueries(ctx context.Context) ([]BlockingQuery, error) {
+	queries := []BlockingQuery{}
+	err := a.db.SelectContext(ctx, &queries, `
+			SELECT blocker.application_name, left(blocker.query, 1000) AS query, coalesce(blocker.state, '') AS state,
+				count(*) AS blocked,
+				coalesce(extract(epoch FROM now() - blocker.query_start), 0)::float8 AS query_seconds
+			FROM pg_stat_activity blocked
+			JOIN LATERAL unnest(pg_blocking_pids(blocked.pid)) AS b(pid) ON true
+			JOIN pg_stat_activity blocker ON blocker.pid = b.pid
+			WHERE blocked.datname = current_database()
+			GROUP BY blocker.pid, blocker.application_name, blocker.query, blocker.state, blocker.query_start
+		`)
+	if err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
@@ -0,0 +1,102 @@
+package dbhealth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePool struct {
+	stats sql.DBStats
+}
+
+func (p *fakePool) Stats() sql.DBStats {
+	return p.stats
+}
+
+type fakeActivity struct {
+	ages     map[string]float64
+	blocking []BlockingQuery
+}
+
+func (a *fakeActivity) TransactionAges(ctx context.Context) (map[string]float64, error) {
+	return a.ages, nil
+}
+
+func (a *fakeActivity) BlockingQueries(ctx context.Context) ([]BlockingQuery, error) {
+	return a.blocking, nil
+}
+
+func TestSample(t *testing.T) {
+	now := time.Now()
+	activity := &fakeActivity{}
+	monitor := NewMonitor(activity, DefaultThresholds)
+	monitor.now = func() time.Time { return now }
+
+	payment := &fakePool{stats: sql.DBStats{MaxOpenConnections: 10}}
+	monitor.Add("payment_db", payment)
+
+	now = now.Add(10 * time.Second)
+	payment.stats = sql.DBStats{MaxOpenConnections: 10, InUse: 9, WaitCount: 20, WaitDuration: 2 * time.Second}
+	activity.ages = map[string]float64{"payment_db": 45}
+	activity.blocking = []BlockingQuery{{DBName: "payment_db", Query: "UPDATE orders SET status = $1", Blocked: 3}}
+	require.NoError(t, monitor.Sample(context.Background()))
+
+	summary := monitor.Summary()
+	require.Len(t, summary.Pools, 1)
+	status := summary.Pools[0]
+	assert.Equal(t, 0.9, status.Saturation)
+	assert.InDelta(t, 0.1, status.WaitSecondsAvg, 1e-9)
+	assert.InDelta(t, 2, status.WaitsPerSecond, 1e-9)
+	assert.Equal(t, map[string]string{
+		CheckSaturation:  SeverityWarning,
+		CheckWait:        SeverityWarning,
+		CheckTransaction: SeverityWarning,
+	}, status.Alerts)
+
+	// waits are derived from the deltas since the previous sample
+	now = now.Add(10 * time.Second)
+	payment.stats = sql.DBStats{MaxOpenConnections: 10, InUse: 10, WaitCount: 21, WaitDuration: 2*time.Second + 300*time.Millisecond}
+	activity.ages = map[string]float64{}
+	require.NoError(t, monitor.Sample(context.Background()))
+
+	status = monitor.Summary().Pools[0]
+	assert.InDelta(t, 0.3, status.WaitSecondsAvg, 1e-9)
+	assert.Equal(t, map[string]string{
+		CheckSaturation: SeverityCritical,
+		CheckWait:       SeverityCritical,
+	}, status.Alerts)
+
+	summary = monitor.Summary()
+	require.Len(t, summary.Blocking, 1)
+	assert.Equal(t, 2, summary.Blocking[0].Observations)
+	assert.Equal(t, 3, summary.Blocking[0].MaxBlocked)
+
+	// observations older than the window are dropped
+	now = now.Add(DefaultWindow + time.Second)
+	assert.Empty(t, monitor.Summary().Blocking)
+}
+
+func TestRouter(t *testing.T) {
+	monitor := NewMonitor(nil, DefaultThresholds)
+	monitor.Add("grant_db", &fakePool{stats: sql.DBStats{MaxOpenConnections: 4, InUse: 1}})
+	require.NoError(t, monitor.Sample(context.Background()))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	Router(monitor).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var summary Summary
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summary))
+	require.Len(t, summary.Pools, 1)
+	assert.Equal(t, 0.25, summary.Pools[0].Saturation)
+	assert.Empty(t, summary.Pools[0].Alerts)
+}
@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ApplicationError is an error clients can branch on: a stable code, the HTTP status it is served with, a
+// message which is safe to show clients and whether retrying the request may succeed
+type ApplicationError struct {
+	Code      string
+	Status    int
+	Message   string
+	Retriable bool
+	cause     error
+}
+
+// NewApplicationError creates an application error, usually declared once as a sentinel
+func NewApplicationError(code string, status int, message string, retriable bool) *ApplicationError {
+	return &ApplicationError{
+		Code:      code,
+		Status:    status,
+		Message:   message,
+		Retriable: retriable,
+	}
+}
+
+// Error is the message along with the cause, if any
+func (e *ApplicationError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.cause)
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *ApplicationError) Unwrap() error {
+	return e.cause
+}
+
+// Is matches application errors by code, so a wrapped sentinel still matches it
+func (e *ApplicationError) Is(target error) bool {
+	t, ok := target.(*ApplicationError)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns the error with a cause, which is logged but never shown to clients
+func (e *ApplicationError) Wrap(cause error) error {
+	wrapped := *e
+	wrapped.cause = cause
+	return &wrapped
+}
+
+// AsApplicationError returns the first application error in the chain of err
+func AsApplicationError(err error) (*ApplicationError, bool) {
+	var appErr *ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
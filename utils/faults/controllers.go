@@ -0,0 +1,63 @@
+package faults
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+)
+
+// Router lets operators inject faults into the service's dependencies to rehearse failure modes
+func Router() chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/", GetRules())
+	r.Method("DELETE", "/", ClearRules())
+	r.Method("PUT", "/{target}", SetRule())
+	r.Method("DELETE", "/{target}", ClearRules())
+	return r
+}
+
+// GetRules is the handler for listing the faults injected into each target
+func GetRules() handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		return handlers.RenderContent(r.Context(), Rules(), w, http.StatusOK)
+	})
+}
+
+// SetRule is the handler for setting the faults injected into a target
+func SetRule() handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		target := Target(chi.URLParam(r, "target"))
+		if !IsTarget(target) {
+			return &handlers.AppError{
+				Message: "Fault target not found",
+				Code:    http.StatusNotFound,
+			}
+		}
+
+		var rule Rule
+		if err := requestutils.ReadJSON(r.Body, &rule); err != nil {
+			return handlers.WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+		if err := Set(target, rule); err != nil {
+			return handlers.ValidationError("request body", map[string]interface{}{
+				"rule": err.Error(),
+			})
+		}
+		return handlers.RenderContent(r.Context(), rule, w, http.StatusOK)
+	})
+}
+
+// ClearRules is the handler for no longer injecting faults into a target, or into any
+func ClearRules() handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		if target := Target(chi.URLParam(r, "target")); target != "" {
+			Clear(target)
+		} else {
+			Clear()
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
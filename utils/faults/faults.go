@@ -0,0 +1,208 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Target is a dependency faults are injected into
+type Target string
+
+const (
+	// Datastore injects faults into postgres queries
+	Datastore Target = "datastore"
+	// CBR injects faults into challenge bypass server requests
+	CBR Target = "cbr"
+	// Kafka injects faults into kafka produce and fetch calls
+	Kafka Target = "kafka"
+)
+
+// Targets are the dependencies faults can be injected into
+var Targets = []Target{Datastore, CBR, Kafka}
+
+var (
+	// ErrInjected is the error an injected fault fails a call with
+	ErrInjected = errors.New("injected fault")
+	// ErrUnknownTarget is the error when configuring a target faults cannot be injected into
+	ErrUnknownTarget = errors.New("unknown fault target")
+)
+
+var injectedFaults = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "injected_faults_total",
+		Help: "Latency and errors injected into calls for resilience testing",
+	},
+	[]string{"target", "fault"},
+)
+
+func init() {
+	prometheus.MustRegister(injectedFaults)
+}
+
+// Rule is the faults injected into the calls to a target
+type Rule struct {
+	// LatencyMs is added to the share of calls given by LatencyPercent
+	LatencyMs      int     `json:"latencyMs"`
+	LatencyPercent float64 `json:"latencyPercent"`
+	// ErrorPercent is the share of calls failed with ErrInjected, after any latency
+	ErrorPercent float64 `json:"errorPercent"`
+	// ErrorCount is how many of the next calls are failed with ErrInjected before ErrorPercent applies, so a
+	// dependency which fails a few times then recovers can be rehearsed deterministically
+	ErrorCount int `json:"errorCount"`
+}
+
+// Validate the rule
+func (r Rule) Validate() error {
+	if r.LatencyMs < 0 {
+		return fmt.Errorf("latencyMs must not be negative")
+	}
+	if r.LatencyPercent < 0 || r.LatencyPercent > 100 {
+		return fmt.Errorf("latencyPercent must be between 0 and 100")
+	}
+	if r.ErrorPercent < 0 || r.ErrorPercent > 100 {
+		return fmt.Errorf("errorPercent must be between 0 and 100")
+	}
+	if r.ErrorCount < 0 {
+		return fmt.Errorf("errorCount must not be negative")
+	}
+	return nil
+}
+
+var (
+	mu    sync.RWMutex
+	rules = map[Target]*Rule{}
+)
+
+// Enabled reports whether fault injection is enabled, which requires FAULT_INJECTION to be set
+// outside of production. The hooks are only installed when enabled, so they cost nothing otherwise
+func Enabled() bool {
+	if os.Getenv("ENV") == "production" {
+		return false
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("FAULT_INJECTION"))
+	return enabled
+}
+
+// IsTarget reports whether faults can be injected into the target
+func IsTarget(target Target) bool {
+	for _, t := range Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Set the rule for a target
+func Set(target Target, rule Rule) error {
+	if !IsTarget(target) {
+		return ErrUnknownTarget
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rules[target] = &rule
+	return nil
+}
+
+// WithRule returns a context whose calls to the target have the faults of the rule injected in place of any
+// rule set for it, so a test can inject faults into its own calls only. Faults are only injected where the
+// target's hooks are installed, see Enabled
+func WithRule(ctx context.Context, target Target, rule Rule) (context.Context, error) {
+	if !IsTarget(target) {
+		return ctx, ErrUnknownTarget
+	}
+	if err := rule.Validate(); err != nil {
+		return ctx, err
+	}
+	scoped := map[Target]*Rule{target: &rule}
+	if parent, ok := ctx.Value(appctx.FaultRulesCTXKey).(map[Target]*Rule); ok {
+		for t, r := range parent {
+			if t != target {
+				scoped[t] = r
+			}
+		}
+	}
+	return context.WithValue(ctx, appctx.FaultRulesCTXKey, scoped), nil
+}
+
+// Clear the rule for a target, or of all targets when none is given
+func Clear(targets ...Target) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(targets) == 0 {
+		rules = map[Target]*Rule{}
+		return
+	}
+	for _, target := range targets {
+		delete(rules, target)
+	}
+}
+
+// Rules returns the rules of all targets
+func Rules() map[Target]Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+	current := make(map[Target]Rule, len(rules))
+	for target, rule := range rules {
+		current[target] = *rule
+	}
+	return current
+}
+
+// next returns the rule of the target for a call made with the context, and whether the call is one of
+// those counted by its ErrorCount
+func next(ctx context.Context, target Target) (Rule, bool, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	rule, ok := rules[target]
+	if scoped, sok := ctx.Value(appctx.FaultRulesCTXKey).(map[Target]*Rule); sok && scoped[target] != nil {
+		rule, ok = scoped[target], true
+	}
+	if !ok {
+		return Rule{}, false, false
+	}
+	counted := rule.ErrorCount > 0
+	if counted {
+		rule.ErrorCount--
+	}
+	return *rule, counted, true
+}
+
+// Inject the faults of the target's rule into a call, sleeping for any latency and returning
+// ErrInjected for any error. The rule of the call's context takes the place of the target's, see WithRule
+func Inject(ctx context.Context, target Target) error {
+	rule, counted, ok := next(ctx, target)
+	if !ok {
+		return nil
+	}
+
+	if rule.LatencyMs > 0 && hit(rule.LatencyPercent) {
+		injectedFaults.With(prometheus.Labels{"target": string(target), "fault": "latency"}).Inc()
+		select {
+		case <-time.After(time.Duration(rule.LatencyMs) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if counted || hit(rule.ErrorPercent) {
+		injectedFaults.With(prometheus.Labels{"target": string(target), "fault": "error"}).Inc()
+		return fmt.Errorf("%s: %w", target, ErrInjected)
+	}
+	return nil
+}
+
+func hit(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
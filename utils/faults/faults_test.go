@@ -0,0 +1,126 @@
+package faults
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnabled(t *testing.T) {
+	defer func() {
+		_ = os.Unsetenv("FAULT_INJECTION")
+		_ = os.Unsetenv("ENV")
+	}()
+
+	assert.False(t, Enabled())
+	require.NoError(t, os.Setenv("FAULT_INJECTION", "true"))
+	require.NoError(t, os.Setenv("ENV", "staging"))
+	assert.True(t, Enabled())
+	require.NoError(t, os.Setenv("ENV", "production"))
+	assert.False(t, Enabled(), "faults are never injected in production")
+}
+
+func TestInject(t *testing.T) {
+	defer Clear()
+	ctx := context.Background()
+
+	assert.NoError(t, Inject(ctx, Datastore), "no rule injects nothing")
+	assert.Equal(t, ErrUnknownTarget, Set("redis", Rule{ErrorPercent: 100}))
+	assert.Error(t, Set(Datastore, Rule{ErrorPercent: 101}))
+
+	require.NoError(t, Set(Datastore, Rule{ErrorPercent: 100}))
+	assert.True(t, errors.Is(Inject(ctx, Datastore), ErrInjected))
+	assert.NoError(t, Inject(ctx, CBR), "rules only apply to their target")
+
+	require.NoError(t, Set(Kafka, Rule{LatencyMs: 20, LatencyPercent: 100}))
+	start := time.Now()
+	assert.NoError(t, Inject(ctx, Kafka))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	Clear(Datastore)
+	assert.NoError(t, Inject(ctx, Datastore))
+	assert.Len(t, Rules(), 1)
+}
+
+func TestInjectErrorCount(t *testing.T) {
+	defer Clear()
+	ctx := context.Background()
+
+	assert.Error(t, Set(CBR, Rule{ErrorCount: -1}))
+	require.NoError(t, Set(CBR, Rule{ErrorCount: 2}))
+	assert.True(t, errors.Is(Inject(ctx, CBR), ErrInjected))
+	assert.Equal(t, 1, Rules()[CBR].ErrorCount, "the calls left to fail are counted down")
+	assert.True(t, errors.Is(Inject(ctx, CBR), ErrInjected))
+	assert.NoError(t, Inject(ctx, CBR), "calls succeed once the count is used up")
+}
+
+func TestWithRule(t *testing.T) {
+	defer Clear()
+	require.NoError(t, Set(Datastore, Rule{ErrorPercent: 100}))
+
+	_, err := WithRule(context.Background(), "redis", Rule{})
+	assert.Equal(t, ErrUnknownTarget, err)
+
+	ctx, err := WithRule(context.Background(), Datastore, Rule{})
+	require.NoError(t, err)
+	assert.NoError(t, Inject(ctx, Datastore), "the rule of the context takes the place of the target's")
+	assert.Error(t, Inject(context.Background(), Datastore))
+
+	ctx, err = WithRule(ctx, Kafka, Rule{ErrorCount: 1})
+	require.NoError(t, err)
+	assert.NoError(t, Inject(ctx, Datastore), "rules of the parent context are kept")
+	assert.True(t, errors.Is(Inject(ctx, Kafka), ErrInjected))
+	assert.NoError(t, Inject(ctx, Kafka))
+	assert.NoError(t, Inject(context.Background(), Kafka), "rules of a context do not apply to other calls")
+}
+
+func TestRoundTripper(t *testing.T) {
+	defer Clear()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: RoundTripper(CBR)(nil)}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.NoError(t, Set(CBR, Rule{ErrorPercent: 100}))
+	_, err = client.Get(server.URL) // nolint:bodyclose
+	assert.True(t, errors.Is(err, ErrInjected))
+}
+
+func TestRouter(t *testing.T) {
+	defer Clear()
+	router := Router()
+
+	body, err := json.Marshal(Rule{LatencyMs: 100, LatencyPercent: 50})
+	require.NoError(t, err)
+	req := httptest.NewRequest("PUT", "/cbr", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, Rule{LatencyMs: 100, LatencyPercent: 50}, Rules()[CBR])
+
+	req = httptest.NewRequest("PUT", "/redis", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req = httptest.NewRequest("DELETE", "/", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, Rules())
+}
@@ -0,0 +1,104 @@
+package faults
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const postgresDriver = "postgres-faults"
+
+var registerDriver sync.Once
+
+// OpenPostgres opens a postgres database whose queries have the faults of the datastore rule
+// injected into them
+func OpenPostgres(databaseURL string) (*sqlx.DB, error) {
+	registerDriver.Do(func() {
+		sql.Register(postgresDriver, &faultDriver{base: &pq.Driver{}})
+	})
+	db, err := sql.Open(postgresDriver, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	// the bind type of the wrapped driver is that of postgres
+	return sqlx.NewDb(db, "postgres"), nil
+}
+
+type faultDriver struct {
+	base driver.Driver
+}
+
+func (d *faultDriver) Open(name string) (driver.Conn, error) {
+	if err := Inject(context.Background(), Datastore); err != nil {
+		return nil, err
+	}
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{base: conn}, nil
+}
+
+// faultConn injects faults before the statements and transactions of a connection, deferring to
+// database/sql's fallbacks where the wrapped connection lacks an interface
+type faultConn struct {
+	base driver.Conn
+}
+
+func (c *faultConn) Prepare(query string) (driver.Stmt, error) {
+	if err := Inject(context.Background(), Datastore); err != nil {
+		return nil, err
+	}
+	return c.base.Prepare(query)
+}
+
+func (c *faultConn) Close() error {
+	return c.base.Close()
+}
+
+func (c *faultConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := Inject(ctx, Datastore); err != nil {
+		return nil, err
+	}
+	if b, ok := c.base.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.base.Begin() //nolint
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.base.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := Inject(ctx, Datastore); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.base.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := Inject(ctx, Datastore); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *faultConn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package faults
+
+import (
+	"net/http"
+)
+
+// RoundTripper returns a wrapper of a transport which injects the faults of the target's rule
+// into its requests, failing them as the network would
+func RoundTripper(target Target) func(http.RoundTripper) http.RoundTripper {
+	return func(base http.RoundTripper) http.RoundTripper {
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := Inject(req.Context(), target); err != nil {
+				return nil, err
+			}
+			return base.RoundTrip(req)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/go-chi/chi"
+)
+
+// batchReferenceRE matches references to the response of an earlier operation, such as
+// {{0.id}} for the id of the order created by the first operation
+var batchReferenceRE = regexp.MustCompile(`\{\{(\d+)((?:\.[A-Za-z0-9_-]+)+)\}\}`)
+
+// batchForwardedHeaders are shared by every operation of a batch, along with the request id
+var batchForwardedHeaders = []string{
+	"Authorization",
+	"User-Agent",
+	"X-Forwarded-For",
+	"X-Real-Ip",
+}
+
+// BatchOperation is a single sub-request of a batch
+type BatchOperation struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchRequest is an ordered list of operations, later operations may reference the
+// responses of earlier ones
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the response to a single operation of a batch
+type BatchResult struct {
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Skipped bool            `json:"skipped,omitempty"`
+}
+
+// BatchResponse holds the result of each operation, in order
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// batchResponseWriter captures the response of an operation
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// resolveBatchReference looks up the value at the path of an earlier operation's response
+func resolveBatchReference(results []BatchResult, index int, path []string) (string, error) {
+	if index >= len(results) {
+		return "", fmt.Errorf("operation %d has not run", index)
+	}
+	var value interface{}
+	if err := json.Unmarshal(results[index].Body, &value); err != nil {
+		return "", fmt.Errorf("operation %d did not respond with json", index)
+	}
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("operation %d response has no %s", index, key)
+			}
+			value = v[i]
+		default:
+			value = nil
+		}
+		if value == nil {
+			return "", fmt.Errorf("operation %d response has no %s", index, key)
+		}
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// expandBatchReferences replaces references to earlier responses in s
+func expandBatchReferences(s string, results []BatchResult) (string, error) {
+	var err error
+	expanded := batchReferenceRE.ReplaceAllStringFunc(s, func(ref string) string {
+		match := batchReferenceRE.FindStringSubmatch(ref)
+		index, _ := strconv.Atoi(match[1])
+		value, rerr := resolveBatchReference(results, index, strings.Split(match[2][1:], "."))
+		if rerr != nil && err == nil {
+			err = rerr
+		}
+		return value
+	})
+	return expanded, err
+}
+
+// BatchHandler executes the operations of a batch in order against the handler, sharing the
+// authorization and request id of the batch request. Execution stops at the first operation
+// which fails, the remaining operations are marked as skipped
+func BatchHandler(h http.Handler, prefix string, maxOperations int) AppHandler {
+	return AppHandler(func(w http.ResponseWriter, r *http.Request) *AppError {
+		var req BatchRequest
+		if err := requestutils.ReadJSON(r.Body, &req); err != nil {
+			return WrapError(err, "Error in request body", http.StatusBadRequest)
+		}
+
+		errs := map[string]interface{}{}
+		if len(req.Operations) == 0 {
+			errs["operations"] = "at least one operation is required"
+		} else if len(req.Operations) > maxOperations {
+			errs["operations"] = fmt.Sprintf("at most %d operations are allowed", maxOperations)
+		}
+		for i, op := range req.Operations {
+			if err := checkBatchPath(op.Path, prefix); err != nil {
+				errs[fmt.Sprintf("operations[%d].path", i)] = err.Error()
+			}
+			switch op.Method {
+			case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				errs[fmt.Sprintf("operations[%d].method", i)] = "unsupported method"
+			}
+		}
+		if len(errs) > 0 {
+			return ValidationError("request body", errs)
+		}
+
+		// operations are routed afresh, without the route of the batch request
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+
+		results := make([]BatchResult, len(req.Operations))
+		failed := false
+		for i, op := range req.Operations {
+			if failed {
+				results[i] = BatchResult{Skipped: true}
+				continue
+			}
+
+			result, err := runBatchOperation(ctx, h, r, prefix, op, results[:i])
+			if err != nil {
+				body, _ := json.Marshal(AppError{Message: err.Error(), Code: http.StatusBadRequest})
+				result = BatchResult{Status: http.StatusBadRequest, Body: body}
+			}
+			results[i] = result
+			failed = result.Status >= http.StatusBadRequest
+		}
+
+		w.Header().Set("content-type", "application/json")
+		return RenderContent(r.Context(), BatchResponse{Results: results}, w, http.StatusOK)
+	})
+}
+
+// checkBatchPath checks the path of an operation is of this host and outside the batch endpoint, once any
+// references it holds are expanded
+func checkBatchPath(p, prefix string) error {
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return errors.New("must be an absolute path")
+	}
+	if strings.HasPrefix(path.Clean(u.Path), prefix) {
+		return errors.New("batches may not be nested")
+	}
+	return nil
+}
+
+// runBatchOperation executes a single operation, expanding references to earlier results
+func runBatchOperation(ctx context.Context, h http.Handler, r *http.Request, prefix string, op BatchOperation, results []BatchResult) (BatchResult, error) {
+	path, err := expandBatchReferences(op.Path, results)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	// references may expand to any path, so the operation is only routed once it is checked again
+	if err := checkBatchPath(path, prefix); err != nil {
+		return BatchResult{}, fmt.Errorf("path %s: %w", path, err)
+	}
+	body, err := expandBatchReferences(string(op.Body), results)
+	if err != nil {
+		return BatchResult{}, err
+	}
+
+	sub, err := http.NewRequestWithContext(ctx, op.Method, path, strings.NewReader(body))
+	if err != nil {
+		return BatchResult{}, err
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Host = r.Host
+	for _, key := range batchForwardedHeaders {
+		if v := r.Header.Get(key); v != "" {
+			sub.Header.Set(key, v)
+		}
+	}
+	requestutils.SetRequestID(r.Context(), sub)
+	for key, v := range op.Headers {
+		sub.Header.Set(key, v)
+	}
+	if len(body) > 0 && sub.Header.Get("Content-Type") == "" {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+
+	rw := &batchResponseWriter{header: http.Header{}}
+	h.ServeHTTP(rw, sub)
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	result := BatchResult{Status: rw.status}
+	if b := bytes.TrimSpace(rw.body.Bytes()); len(b) > 0 {
+		if json.Valid(b) {
+			result.Body = json.RawMessage(b)
+		} else {
+			encoded, _ := json.Marshal(string(b))
+			result.Body = json.RawMessage(encoded)
+		}
+	}
+	return result, nil
+}
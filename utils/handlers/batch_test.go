@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func batchRouter(t *testing.T) *chi.Mux {
+	r := chi.NewRouter()
+	r.Post("/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the authorization of the batch to be shared")
+		}
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"order-1","items":[{"id":"item-1"}]}`))
+	})
+	r.Post("/v1/orders/{orderID}/credentials", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if chi.URLParam(r, "orderID") != "order-1" || body["itemId"] != "item-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Get("/v1/links", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"batch":"v1/batch","dotted":"v1/orders/../batch","remote":"/example.com/v1/orders"}`))
+	})
+	r.Method("POST", "/v1/batch", BatchHandler(r, "/v1/batch", 3))
+	return r
+}
+
+func serveBatch(t *testing.T, body string) (int, BatchResponse) {
+	req := httptest.NewRequest("POST", "/v1/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	batchRouter(t).ServeHTTP(w, req)
+
+	var resp BatchResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode batch response: %v", err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestBatchHandler(t *testing.T) {
+	code, resp := serveBatch(t, `{"operations":[
+		{"method":"POST","path":"/v1/orders","body":{"items":[{"sku":"a"}]}},
+		{"method":"POST","path":"/v1/orders/{{0.id}}/credentials","body":{"itemId":"{{0.items.0.id}}"}}
+	]}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected batch to succeed, got %d", code)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Status != http.StatusCreated || resp.Results[1].Status != http.StatusOK {
+		t.Fatalf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestBatchHandlerStopsAtFailure(t *testing.T) {
+	_, resp := serveBatch(t, `{"operations":[
+		{"method":"POST","path":"/v1/orders/{{0.missing}}/credentials"},
+		{"method":"POST","path":"/v1/orders"}
+	]}`)
+	if len(resp.Results) != 2 || resp.Results[0].Status != http.StatusBadRequest || !resp.Results[1].Skipped {
+		t.Fatalf("expected the first operation to fail and the rest to be skipped, got %+v", resp.Results)
+	}
+}
+
+func TestBatchHandlerChecksExpandedPaths(t *testing.T) {
+	for _, ref := range []string{"/{{0.batch}}", "/{{0.dotted}}", "/{{0.remote}}"} {
+		_, resp := serveBatch(t, `{"operations":[
+			{"method":"GET","path":"/v1/links"},
+			{"method":"POST","path":"`+ref+`","body":{"operations":[]}}
+		]}`)
+		if len(resp.Results) != 2 || resp.Results[1].Status != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %+v", ref, resp.Results)
+		}
+		var appErr AppError
+		if err := json.Unmarshal(resp.Results[1].Body, &appErr); err != nil || !strings.Contains(appErr.Message, "path") {
+			t.Errorf("expected %s to be rejected for its path, got %s", ref, resp.Results[1].Body)
+		}
+	}
+}
+
+func TestBatchHandlerValidation(t *testing.T) {
+	for _, body := range []string{
+		`{"operations":[]}`,
+		`{"operations":[{"method":"POST","path":"/v1/batch"}]}`,
+		`{"operations":[{"method":"POST","path":"/v1/orders/../batch"}]}`,
+		`{"operations":[{"method":"POST","path":"//example.com/v1/orders"}]}`,
+		`{"operations":[{"method":"TRACE","path":"/v1/orders"}]}`,
+		`{"operations":[{"method":"GET","path":"/a"},{"method":"GET","path":"/b"},{"method":"GET","path":"/c"},{"method":"GET","path":"/d"}]}`,
+	} {
+		if code, _ := serveBatch(t, body); code != http.StatusBadRequest {
+			t.Errorf("expected %s to be rejected, got %d", body, code)
+		}
+	}
+}
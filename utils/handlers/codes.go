@@ -0,0 +1,60 @@
+package handlers
+
+import "net/http"
+
+// The stable error codes of error responses, each with a message in the i18n catalog
+const (
+	ErrorCodeBadRequest         = "bad_request"
+	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodeUnauthorized       = "unauthorized"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeMethodNotAllowed   = "method_not_allowed"
+	ErrorCodeConflict           = "conflict"
+	ErrorCodeGone               = "gone"
+	ErrorCodePayloadTooLarge    = "payload_too_large"
+	ErrorCodeUnprocessable      = "unprocessable"
+	ErrorCodeTooManyRequests    = "too_many_requests"
+	ErrorCodeInternal           = "internal_error"
+	ErrorCodeBadGateway         = "bad_gateway"
+	ErrorCodeServiceUnavailable = "service_unavailable"
+	ErrorCodeGatewayTimeout     = "gateway_timeout"
+)
+
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            ErrorCodeBadRequest,
+	http.StatusUnauthorized:          ErrorCodeUnauthorized,
+	http.StatusForbidden:             ErrorCodeForbidden,
+	http.StatusNotFound:              ErrorCodeNotFound,
+	http.StatusMethodNotAllowed:      ErrorCodeMethodNotAllowed,
+	http.StatusConflict:              ErrorCodeConflict,
+	http.StatusGone:                  ErrorCodeGone,
+	http.StatusRequestEntityTooLarge: ErrorCodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   ErrorCodeUnprocessable,
+	http.StatusTooManyRequests:       ErrorCodeTooManyRequests,
+	http.StatusInternalServerError:   ErrorCodeInternal,
+	http.StatusBadGateway:            ErrorCodeBadGateway,
+	http.StatusServiceUnavailable:    ErrorCodeServiceUnavailable,
+	http.StatusGatewayTimeout:        ErrorCodeGatewayTimeout,
+}
+
+// ErrorCodeForStatus returns the error code of an HTTP status, the generic code of its class for the
+// statuses without one of their own
+func ErrorCodeForStatus(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return ErrorCodeInternal
+	}
+	return ErrorCodeBadRequest
+}
+
+// RetriableStatus is whether a request failing with the status may succeed when retried as is
+func RetriableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -9,8 +9,10 @@ import (
 	"net/http"
 
 	"github.com/asaskevich/govalidator"
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
+	"github.com/brave-intl/bat-go/utils/i18n"
+	"github.com/brave-intl/bat-go/utils/reporting"
 	"github.com/brave-intl/bat-go/utils/requestutils"
-	"github.com/getsentry/sentry-go"
 	"github.com/rs/zerolog"
 )
 
@@ -20,6 +22,16 @@ type AppError struct {
 	Message string      `json:"message"`
 	Code    int         `json:"code"`
 	Data    interface{} `json:"data,omitempty"`
+	// RequestID lets a caller quote the failing request when reporting an error
+	RequestID string `json:"requestId,omitempty"`
+	// ErrorCode is the stable code clients branch on, the one of the status unless set
+	ErrorCode string `json:"errorCode,omitempty"`
+	// LocalizedMessage is the message of the error code in the language the client accepts
+	LocalizedMessage string `json:"localizedMessage,omitempty"`
+	// Retriable tells clients whether the same request may succeed later
+	Retriable bool `json:"retriable"`
+	// safe is set when the message is that of an application error, which is served without its cause
+	safe bool
 }
 
 // Error makes app error an error
@@ -31,8 +43,32 @@ func (e AppError) Error() string {
 	return msg
 }
 
+// Unwrap returns the cause of the error
+func (e AppError) Unwrap() error {
+	return e.Cause
+}
+
 // ServeHTTP responds according to the passed AppError
 func (e AppError) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if e.RequestID == "" {
+		e.RequestID = requestutils.GetRequestID(r.Context())
+	}
+	if e.ErrorCode == "" {
+		e.ErrorCode = ErrorCodeForStatus(e.Code)
+	}
+	if e.LocalizedMessage == "" {
+		lang, message, ok := i18n.Default().Localize(r.Header.Get("Accept-Language"), e.ErrorCode)
+		if !ok {
+			// service specific codes without a message of their own share the one of their status
+			lang, message, ok = i18n.Default().Localize(r.Header.Get("Accept-Language"), ErrorCodeForStatus(e.Code))
+		}
+		if ok {
+			e.LocalizedMessage = message
+			w.Header().Set("content-language", lang)
+		}
+	}
+	e.Retriable = e.Retriable || RetriableStatus(e.Code)
+	w.Header().Add("vary", "Accept-Language")
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(e.Code)
 	if err := json.NewEncoder(w).Encode(e); err != nil {
@@ -46,6 +82,9 @@ func WrapError(err error, msg string, passedCode int) *AppError {
 	// appErr, ok := err.(*AppError)
 	var appErr *AppError
 	if !errors.As(err, &appErr) {
+		if typed, ok := errorutils.AsApplicationError(err); ok {
+			return wrapApplicationError(err, typed, msg)
+		}
 		code := passedCode
 		if code == 0 {
 			code = http.StatusBadRequest
@@ -69,7 +108,39 @@ func WrapError(err error, msg string, passedCode int) *AppError {
 		Message: fmt.Sprintf("%s%s", msg, appErr.Message),
 		Code:    code,
 		Data:    appErr.Data,
+		// the code and message clients see are those of the original error
+		ErrorCode:        appErr.ErrorCode,
+		LocalizedMessage: appErr.LocalizedMessage,
+		Retriable:        appErr.Retriable,
+		safe:             appErr.safe,
+	}
+}
+
+// wrapApplicationError serves an application error with its own status and code, the safe message
+// prefixed by msg
+func wrapApplicationError(err error, typed *errorutils.ApplicationError, msg string) *AppError {
+	message := typed.Message
+	if len(msg) != 0 {
+		message = fmt.Sprintf("%s: %s", msg, message)
+	}
+	return &AppError{
+		Cause:     err,
+		Message:   message,
+		Code:      typed.Status,
+		ErrorCode: typed.Code,
+		Retriable: typed.Retriable,
+		safe:      true,
+	}
+}
+
+// RenderError writes any error as the JSON error envelope, those which are not already an AppError or an
+// application error as an internal error
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := WrapError(err, "", http.StatusInternalServerError)
+	if appErr.Message == "" {
+		appErr.Message = http.StatusText(appErr.Code)
 	}
+	appErr.ServeHTTP(w, r)
 }
 
 // RenderContent based on the header
@@ -101,8 +172,9 @@ func WrapValidationError(err error) *AppError {
 // ValidationError creates an error to communicate a bad request was formed
 func ValidationError(message string, validationErrors interface{}) *AppError {
 	return &AppError{
-		Message: "Error validating " + message,
-		Code:    http.StatusBadRequest,
+		Message:   "Error validating " + message,
+		Code:      http.StatusBadRequest,
+		ErrorCode: ErrorCodeValidationFailed,
 		Data: map[string]interface{}{
 			"validationErrors": validationErrors,
 		},
@@ -124,12 +196,7 @@ func (fn AppHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if e := fn(w, r); e != nil {
 		if e.Code >= 500 && e.Code <= 599 {
-			sentry.WithScope(func(scope *sentry.Scope) {
-				scope.SetTags(map[string]string{
-					"reqID": requestutils.GetRequestID(r.Context()),
-				})
-				sentry.CaptureException(e)
-			})
+			reporting.CaptureRequestError(r, e)
 		}
 
 		l := zerolog.Ctx(r.Context())
@@ -137,7 +204,7 @@ func (fn AppHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return c.Err(e)
 		})
 
-		if e.Cause != nil {
+		if e.Cause != nil && !e.safe {
 			// Combine error with message
 			e.Message = fmt.Sprintf("%s: %v", e.Message, e.Cause)
 		}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	errorutils "github.com/brave-intl/bat-go/utils/errors"
 )
 
 func TestWrapError(t *testing.T) {
@@ -66,3 +71,98 @@ func TestWrapError(t *testing.T) {
 		t.Fatalf("AppError.Error() wraps error messages can stand alone got %v, want %v", got, want)
 	}
 }
+
+func TestAppErrorLocalized(t *testing.T) {
+	handler := AppHandler(func(w http.ResponseWriter, r *http.Request) *AppError {
+		return ValidationError("request body", map[string]string{"amount": "required"})
+	})
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Accept-Language", "ja-JP,ja;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var body AppError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := body.ErrorCode, ErrorCodeValidationFailed; got != want {
+		t.Fatalf("AppError keeps its error code got %v, want %v", got, want)
+	}
+	if got, want := body.LocalizedMessage, "リクエストの一部の項目が無効です。"; got != want {
+		t.Fatalf("AppError is localized to the accepted language got %v, want %v", got, want)
+	}
+	if got, want := w.Header().Get("content-language"), "ja"; got != want {
+		t.Fatalf("AppError sets the content language got %v, want %v", got, want)
+	}
+	if got, want := body.Message, "Error validating request body"; got != want {
+		t.Fatalf("AppError.Message is left untranslated got %v, want %v", got, want)
+	}
+
+	err := WrapError(&AppError{Code: http.StatusTeapot}, "wrapped", 0)
+	w = httptest.NewRecorder()
+	err.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := body.ErrorCode, ErrorCodeBadRequest; got != want {
+		t.Fatalf("AppError falls back to the code of the status class got %v, want %v", got, want)
+	}
+	if got, want := body.LocalizedMessage, "The request is invalid."; got != want {
+		t.Fatalf("AppError falls back to the default language got %v, want %v", got, want)
+	}
+}
+
+func TestApplicationError(t *testing.T) {
+	errLimited := errorutils.NewApplicationError("limited", http.StatusTooManyRequests, "slow down", false)
+	cause := errors.New("password=hunter2")
+	handler := AppHandler(func(w http.ResponseWriter, r *http.Request) *AppError {
+		return WrapError(fmt.Errorf("limiting: %w", errLimited.Wrap(cause)), "Error creating", http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if got, want := w.Code, http.StatusTooManyRequests; got != want {
+		t.Fatalf("application errors are served with their status got %v, want %v", got, want)
+	}
+	var body AppError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := body.ErrorCode, "limited"; got != want {
+		t.Fatalf("application errors are served with their code got %v, want %v", got, want)
+	}
+	if got, want := body.Message, "Error creating: slow down"; got != want {
+		t.Fatalf("application errors are served with their safe message, without the cause got %v, want %v", got, want)
+	}
+	if !body.Retriable {
+		t.Fatalf("errors of retriable statuses are retriable")
+	}
+	if got, want := body.LocalizedMessage, "Too many requests, please try again later."; got != want {
+		t.Fatalf("codes without a message are localized with the code of their status got %v, want %v", got, want)
+	}
+	if !errors.Is(errLimited.Wrap(cause), errLimited) || !errors.Is(errLimited.Wrap(cause), cause) {
+		t.Fatalf("wrapped application errors match their sentinel and their cause")
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	w := httptest.NewRecorder()
+	RenderError(w, httptest.NewRequest("GET", "/", nil), errors.New("connection refused"))
+	if got, want := w.Code, http.StatusInternalServerError; got != want {
+		t.Fatalf("unknown errors are internal errors got %v, want %v", got, want)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := body["message"], http.StatusText(http.StatusInternalServerError); got != want {
+		t.Fatalf("unknown errors are rendered without their cause got %v, want %v", got, want)
+	}
+	if got, want := body["errorCode"], ErrorCodeInternal; got != want {
+		t.Fatalf("unknown errors have the internal code got %v, want %v", got, want)
+	}
+	if got, want := body["retriable"], false; got != want {
+		t.Fatalf("the envelope always says whether the request is retriable got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// DependencyOK is the status of a healthy dependency, or of a ready service
+	DependencyOK = "ok"
+	// DependencyDegraded is the status of a service with only non critical dependencies failing
+	DependencyDegraded = "degraded"
+	// DependencyUnavailable is the status of a failing dependency, or of a service which is not ready
+	DependencyUnavailable = "unavailable"
+)
+
+// DependencyCheck checks that a dependency of the service is reachable
+type DependencyCheck struct {
+	Name string
+	// Critical dependencies failing make the service not ready, others only degrade it
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+// DependencyStatus is the result of a dependency check
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReadinessResponse - response structure for readiness checks
+type ReadinessResponse struct {
+	Status       string                      `json:"status"`
+	Degraded     bool                        `json:"degraded"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// CheckDependencies runs the checks concurrently, each bounded by the timeout
+func CheckDependencies(ctx context.Context, timeout time.Duration, checks ...DependencyCheck) ReadinessResponse {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		resp = ReadinessResponse{
+			Status:       DependencyOK,
+			Dependencies: make(map[string]DependencyStatus, len(checks)),
+		}
+	)
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check DependencyCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			status := DependencyStatus{
+				Status:    DependencyOK,
+				Critical:  check.Critical,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				status.Status = DependencyUnavailable
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Dependencies[check.Name] = status
+			if err != nil {
+				if check.Critical {
+					resp.Status = DependencyUnavailable
+				} else if resp.Status == DependencyOK {
+					resp.Status = DependencyDegraded
+				}
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	resp.Degraded = resp.Status == DependencyDegraded
+	return resp
+}
+
+// ReadinessHandler - function which generates a readiness check http.HandlerFunc, responding
+// with 503 when a critical dependency is unavailable
+func ReadinessHandler(timeout time.Duration, checks ...DependencyCheck) http.HandlerFunc {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var ctx = r.Context()
+			logger, err := appctx.GetLogger(ctx)
+			if err != nil {
+				ctx, logger = logging.SetupLogger(ctx)
+			}
+
+			resp := CheckDependencies(ctx, timeout, checks...)
+			status := http.StatusOK
+			if resp.Status == DependencyUnavailable {
+				status = http.StatusServiceUnavailable
+				logger.Warn().Interface("dependencies", resp.Dependencies).Msg("service is not ready")
+			}
+
+			w.Header().Set("content-type", "application/json")
+			w.WriteHeader(status)
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				logger.Error().Err(err).Msg("failed to write response to writer")
+			}
+		})
+}
+
+// Pinger is a dependency which can be pinged, such as a *sql.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck - check a dependency by pinging it
+func PingCheck(name string, critical bool, pinger Pinger) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check:    pinger.PingContext,
+	}
+}
+
+// HTTPCheck - check an http dependency is reachable, any response other than a server error counts
+func HTTPCheck(name string, critical bool, url string) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode >= 500 {
+				return fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// DialCheck - check a tcp dependency, such as a set of kafka brokers, accepts connections on at least one address
+func DialCheck(name string, critical bool, addrs ...string) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check: func(ctx context.Context) error {
+			var (
+				dialer  net.Dialer
+				lastErr = fmt.Errorf("no addresses configured")
+			)
+			for _, addr := range addrs {
+				conn, err := dialer.DialContext(ctx, "tcp", addr)
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				return conn.Close()
+			}
+			return lastErr
+		},
+	}
+}
+
+// RedisCheck - check a redis server responds to a ping
+func RedisCheck(name string, critical bool, url string) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check: func(ctx context.Context) error {
+			opts := []redis.DialOption{}
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout := time.Until(deadline)
+				opts = append(opts,
+					redis.DialConnectTimeout(timeout),
+					redis.DialReadTimeout(timeout),
+					redis.DialWriteTimeout(timeout))
+			}
+			conn, err := redis.DialURL(url, opts...)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = conn.Close() }()
+			_, err = conn.Do("PING")
+			return err
+		},
+	}
+}
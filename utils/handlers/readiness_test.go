@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func check(name string, critical bool, err error) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check:    func(ctx context.Context) error { return err },
+	}
+}
+
+func serveReadiness(t *testing.T, checks ...DependencyCheck) (int, ReadinessResponse) {
+	w := httptest.NewRecorder()
+	ReadinessHandler(time.Second, checks...).ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	var resp ReadinessResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode readiness response: %v", err)
+	}
+	return w.Code, resp
+}
+
+func TestReadinessHandler(t *testing.T) {
+	code, resp := serveReadiness(t, check("db", true, nil), check("redis", false, nil))
+	if code != http.StatusOK || resp.Status != DependencyOK || resp.Degraded {
+		t.Fatalf("expected ready, got %d %+v", code, resp)
+	}
+	if len(resp.Dependencies) != 2 {
+		t.Fatalf("expected every dependency to be reported, got %+v", resp.Dependencies)
+	}
+
+	code, resp = serveReadiness(t, check("db", true, nil), check("redis", false, errors.New("refused")))
+	if code != http.StatusOK || resp.Status != DependencyDegraded || !resp.Degraded {
+		t.Fatalf("expected degraded, got %d %+v", code, resp)
+	}
+	if got := resp.Dependencies["redis"]; got.Status != DependencyUnavailable || got.Error != "refused" {
+		t.Fatalf("expected redis to be unavailable, got %+v", got)
+	}
+
+	code, resp = serveReadiness(t, check("db", true, errors.New("refused")), check("redis", false, errors.New("refused")))
+	if code != http.StatusServiceUnavailable || resp.Status != DependencyUnavailable || resp.Degraded {
+		t.Fatalf("expected unavailable, got %d %+v", code, resp)
+	}
+}
+
+func TestReadinessHandlerTimeout(t *testing.T) {
+	slow := DependencyCheck{
+		Name:     "kafka",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	resp := CheckDependencies(context.Background(), 10*time.Millisecond, slow)
+	if resp.Status != DependencyUnavailable {
+		t.Fatalf("expected a timed out check to be unavailable, got %+v", resp)
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	status := http.StatusNotFound
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	if err := HTTPCheck("cbr", false, ts.URL).Check(context.Background()); err != nil {
+		t.Fatalf("expected a reachable server to pass: %v", err)
+	}
+	status = http.StatusBadGateway
+	if err := HTTPCheck("cbr", false, ts.URL).Check(context.Background()); err == nil {
+		t.Fatal("expected a server error to fail")
+	}
+}
@@ -0,0 +1,151 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language messages fall back to, every code has a message in it
+const DefaultLanguage = "en"
+
+// Catalog holds the messages of each error code, by language
+type Catalog struct {
+	languages []string
+	messages  map[string]map[string]string
+	matcher   language.Matcher
+}
+
+// NewCatalog creates a catalog of the messages, keyed by language tag then code. The default language
+// is required, the remaining languages may leave codes out to fall back to it
+func NewCatalog(messages map[string]map[string]string) (*Catalog, error) {
+	if _, ok := messages[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("catalog is missing the %s messages", DefaultLanguage)
+	}
+
+	// the first language is the one the matcher falls back to
+	languages := []string{DefaultLanguage}
+	tags := []language.Tag{language.MustParse(DefaultLanguage)}
+	for lang := range messages {
+		if lang == DefaultLanguage {
+			continue
+		}
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("catalog language %q is invalid: %w", lang, err)
+		}
+		languages = append(languages, lang)
+		tags = append(tags, tag)
+	}
+
+	return &Catalog{
+		languages: languages,
+		messages:  messages,
+		matcher:   language.NewMatcher(tags),
+	}, nil
+}
+
+// Load creates a catalog of the built in messages, overridden and extended by the <language>.json files
+// of the directory, each an object of messages keyed by code
+func Load(dir string) (*Catalog, error) {
+	messages := map[string]map[string]string{}
+	for lang, m := range builtin {
+		messages[lang] = map[string]string{}
+		for code, message := range m {
+			messages[lang][code] = message
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list message files: %w", err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message file: %w", err)
+		}
+		m := map[string]string{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode message file %s: %w", filepath.Base(file), err)
+		}
+		lang := strings.TrimSuffix(filepath.Base(file), ".json")
+		if messages[lang] == nil {
+			messages[lang] = map[string]string{}
+		}
+		for code, message := range m {
+			messages[lang][code] = message
+		}
+	}
+	return NewCatalog(messages)
+}
+
+// Languages returns the languages of the catalog, the default language first
+func (c *Catalog) Languages() []string {
+	return append([]string{}, c.languages...)
+}
+
+// Match returns the catalog language best matching an Accept-Language header, the default language when
+// none do
+func (c *Catalog) Match(acceptLanguage string) string {
+	// an invalid header is treated as no preference
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := c.matcher.Match(tags...)
+	return c.languages[index]
+}
+
+// Message returns the message of the code in the language, falling back to the default language. It is
+// false when the code has no message at all
+func (c *Catalog) Message(lang, code string) (string, bool) {
+	if message, ok := c.messages[lang][code]; ok {
+		return message, true
+	}
+	message, ok := c.messages[DefaultLanguage][code]
+	return message, ok
+}
+
+// Localize returns the language matching the Accept-Language header and the message of the code in it
+func (c *Catalog) Localize(acceptLanguage, code string) (string, string, bool) {
+	lang := c.Match(acceptLanguage)
+	message, ok := c.Message(lang, code)
+	if !ok {
+		return DefaultLanguage, "", false
+	}
+	if _, translated := c.messages[lang][code]; !translated {
+		lang = DefaultLanguage
+	}
+	return lang, message, true
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultCatalog *Catalog
+)
+
+func init() {
+	catalog, err := NewCatalog(builtin)
+	if err != nil {
+		panic(err)
+	}
+	defaultCatalog = catalog
+}
+
+// Default returns the catalog error responses are localized with, the built in messages until another
+// is set at startup
+func Default() *Catalog {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCatalog
+}
+
+// SetDefault sets the catalog error responses are localized with
+func SetDefault(catalog *Catalog) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultCatalog = catalog
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	catalog := Default()
+	assert.Equal(t, "en", catalog.Match(""))
+	assert.Equal(t, "en", catalog.Match("not a header;;"))
+	assert.Equal(t, "en", catalog.Match("de-DE"))
+	assert.Equal(t, "ja", catalog.Match("ja-JP,ja;q=0.9,en;q=0.8"))
+	assert.Equal(t, "pt-BR", catalog.Match("pt"))
+	assert.Equal(t, "pt-BR", catalog.Match("fr;q=0.9, pt-BR;q=0.8"))
+	assert.Equal(t, "en", catalog.Match("en-US,ja;q=0.5"))
+}
+
+func TestBuiltinComplete(t *testing.T) {
+	for lang, messages := range builtin {
+		for code := range builtin[DefaultLanguage] {
+			assert.NotEmpty(t, messages[code], "%s is missing %s", lang, code)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "i18n")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ja.json"), []byte(`{"not_found": "見つかりません。"}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "es.json"), []byte(`{"not_found": "No encontrado."}`), 0600))
+
+	catalog, err := Load(dir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"en", "ja", "pt-BR", "es"}, catalog.Languages())
+	assert.Equal(t, "en", catalog.Languages()[0])
+
+	lang, message, ok := catalog.Localize("ja", "not_found")
+	require.True(t, ok)
+	assert.Equal(t, "ja", lang)
+	assert.Equal(t, "見つかりません。", message)
+
+	lang, message, ok = catalog.Localize("es-MX", "conflict")
+	require.True(t, ok)
+	assert.Equal(t, "en", lang, "untranslated codes fall back to the default language")
+	assert.Equal(t, builtin["en"]["conflict"], message)
+
+	_, _, ok = catalog.Localize("es", "no_such_code")
+	assert.False(t, ok)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "xx-!.json"), []byte(`{}`), 0600))
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
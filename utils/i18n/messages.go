@@ -0,0 +1,56 @@
+package i18n
+
+// builtin are the messages of the error codes shared by every service
+var builtin = map[string]map[string]string{
+	"en": {
+		"bad_request":         "The request is invalid.",
+		"validation_failed":   "Some of the request's fields are invalid.",
+		"unauthorized":        "Authentication is required.",
+		"forbidden":           "You are not allowed to do this.",
+		"not_found":           "The requested resource was not found.",
+		"method_not_allowed":  "This method is not supported.",
+		"conflict":            "The request conflicts with the current state of the resource.",
+		"gone":                "The requested resource is no longer available.",
+		"payload_too_large":   "The request is too large.",
+		"unprocessable":       "The request could not be processed.",
+		"too_many_requests":   "Too many requests, please try again later.",
+		"internal_error":      "Something went wrong, please try again later.",
+		"bad_gateway":         "An upstream service failed, please try again later.",
+		"service_unavailable": "The service is temporarily unavailable, please try again later.",
+		"gateway_timeout":     "An upstream service timed out, please try again later.",
+	},
+	"ja": {
+		"bad_request":         "リクエストが無効です。",
+		"validation_failed":   "リクエストの一部の項目が無効です。",
+		"unauthorized":        "認証が必要です。",
+		"forbidden":           "この操作は許可されていません。",
+		"not_found":           "要求されたリソースが見つかりません。",
+		"method_not_allowed":  "このメソッドはサポートされていません。",
+		"conflict":            "リクエストがリソースの現在の状態と競合しています。",
+		"gone":                "要求されたリソースは利用できなくなりました。",
+		"payload_too_large":   "リクエストが大きすぎます。",
+		"unprocessable":       "リクエストを処理できませんでした。",
+		"too_many_requests":   "リクエストが多すぎます。しばらくしてから再度お試しください。",
+		"internal_error":      "問題が発生しました。しばらくしてから再度お試しください。",
+		"bad_gateway":         "上流のサービスでエラーが発生しました。しばらくしてから再度お試しください。",
+		"service_unavailable": "サービスは一時的に利用できません。しばらくしてから再度お試しください。",
+		"gateway_timeout":     "上流のサービスがタイムアウトしました。しばらくしてから再度お試しください。",
+	},
+	"pt-BR": {
+		"bad_request":         "A solicitação é inválida.",
+		"validation_failed":   "Alguns campos da solicitação são inválidos.",
+		"unauthorized":        "É necessário autenticar-se.",
+		"forbidden":           "Você não tem permissão para fazer isso.",
+		"not_found":           "O recurso solicitado não foi encontrado.",
+		"method_not_allowed":  "Este método não é suportado.",
+		"conflict":            "A solicitação está em conflito com o estado atual do recurso.",
+		"gone":                "O recurso solicitado não está mais disponível.",
+		"payload_too_large":   "A solicitação é grande demais.",
+		"unprocessable":       "Não foi possível processar a solicitação.",
+		"too_many_requests":   "Muitas solicitações, tente novamente mais tarde.",
+		"internal_error":      "Algo deu errado, tente novamente mais tarde.",
+		"bad_gateway":         "Um serviço externo falhou, tente novamente mais tarde.",
+		"service_unavailable": "O serviço está temporariamente indisponível, tente novamente mais tarde.",
+		"gateway_timeout":     "Um serviço externo não respondeu a tempo, tente novamente mais tarde.",
+	},
+}
@@ -0,0 +1,152 @@
+package jsonutils
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errTextArray is returned when scanning a value which is not a one dimensional text[] without nulls
+var errTextArray = errors.New("invalid text array")
+
+// TextArray is a string array stored as a native Postgres text[], which is cheaper to write and read
+// than the JSON text of JSONStringArray. It marshals to JSON as an array of strings
+type TextArray []string
+
+// Scan the src sql type into the passed TextArray, columns still holding a JSON array are scanned too
+// so the array can be read while a column is migrated from json to text[]
+func (arr *TextArray) Scan(src interface{}) error {
+	var data string
+	switch v := src.(type) {
+	case nil:
+		*arr = nil
+		return nil
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into TextArray", src)
+	}
+
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, "[") {
+		return json.Unmarshal([]byte(data), (*[]string)(arr))
+	}
+	parsed, err := parseTextArray(data)
+	if err != nil {
+		return err
+	}
+	*arr = parsed
+	return nil
+}
+
+// parseTextArray parses the text representation of a one dimensional array, unquoted elements are
+// sliced from the data rather than copied
+func parseTextArray(data string) (TextArray, error) {
+	if len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}' {
+		return nil, errTextArray
+	}
+	body := data[1 : len(data)-1]
+	if body == "" {
+		return TextArray{}, nil
+	}
+
+	arr := make(TextArray, 0, strings.Count(body, ",")+1)
+	for i := 0; ; i++ {
+		var elem string
+		if body[i] == '"' {
+			end := strings.IndexByte(body[i+1:], '"')
+			if end < 0 {
+				return nil, errTextArray
+			}
+			if quoted := body[i+1 : i+1+end]; !strings.Contains(quoted, `\`) {
+				elem, i = quoted, i+end+2
+			} else {
+				var b strings.Builder
+				for i++; i < len(body) && body[i] != '"'; i++ {
+					if body[i] == '\\' {
+						i++
+						if i == len(body) {
+							return nil, errTextArray
+						}
+					}
+					b.WriteByte(body[i])
+				}
+				if i == len(body) {
+					return nil, errTextArray
+				}
+				i++
+				elem = b.String()
+			}
+		} else {
+			end := strings.IndexByte(body[i:], ',')
+			if end < 0 {
+				end = len(body) - i
+			}
+			elem = body[i : i+end]
+			if elem == "" || strings.EqualFold(elem, "NULL") || strings.ContainsAny(elem, `{}"\`) {
+				return nil, errTextArray
+			}
+			i += end
+		}
+		arr = append(arr, elem)
+
+		if i == len(body) {
+			return arr, nil
+		}
+		if body[i] != ',' || i+1 == len(body) {
+			return nil, errTextArray
+		}
+	}
+}
+
+// Value the driver.Value representation, the text[] literal of the array. A nil array is empty, use a
+// nil *TextArray for NULL
+func (arr TextArray) Value() (driver.Value, error) {
+	n := 2
+	for _, s := range arr {
+		n += len(s) + 3
+	}
+	var b bytes.Buffer
+	b.Grow(n)
+	b.WriteByte('{')
+	for i, s := range arr {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if !textArrayQuoted(s) {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[j])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	// strings are sent as text, where []byte would be sent as bytea
+	return b.String(), nil
+}
+
+// textArrayQuoted is whether an element has to be quoted in the text of an array, as Postgres quotes them
+func textArrayQuoted(s string) bool {
+	return s == "" || strings.EqualFold(s, "NULL") || strings.ContainsAny(s, "{},\"\\ \t\n\r\v\f")
+}
+
+// MarshalJSON returns the JSON representation
+func (arr TextArray) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string(arr))
+}
+
+// UnmarshalJSON sets the passed TextArray to the value deserialized from JSON
+func (arr *TextArray) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*[]string)(arr))
+}
@@ -0,0 +1,115 @@
+package jsonutils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCreds are as many base64 encoded credentials as a large order submits
+func testCreds(n int) []string {
+	creds := make([]string, n)
+	for i := range creds {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		creds[i] = base64.StdEncoding.EncodeToString(b)
+	}
+	return creds
+}
+
+func TestTextArray(t *testing.T) {
+	creds := TextArray(testCreds(3))
+
+	value, err := creds.Value()
+	require.NoError(t, err)
+	var scanned TextArray
+	require.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, creds, scanned)
+
+	quoted := TextArray{"", "null", "a b", "a,b", `"{}"`, `\`}
+	value, err = quoted.Value()
+	require.NoError(t, err)
+	require.NoError(t, scanned.Scan(value))
+	assert.Equal(t, quoted, scanned, "elements are quoted as postgres quotes them")
+	require.NoError(t, scanned.Scan(`{abc=,"d e",f+/}`))
+	assert.Equal(t, TextArray{"abc=", "d e", "f+/"}, scanned)
+	for _, invalid := range []string{"abc", "{a,NULL}", "{a,}", `{"a}`, "{{a}}"} {
+		assert.Error(t, scanned.Scan(invalid), invalid)
+	}
+
+	// columns not yet migrated from json still scan
+	data, err := json.Marshal(creds)
+	require.NoError(t, err)
+	var fromJSON TextArray
+	require.NoError(t, fromJSON.Scan(data))
+	assert.Equal(t, creds, fromJSON)
+
+	var null TextArray
+	require.NoError(t, null.Scan(nil))
+	assert.Nil(t, null)
+
+	empty, err := TextArray(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{}", empty, "a nil array is empty rather than NULL")
+
+	var fromNullPointer *TextArray
+	marshaled, err := json.Marshal(struct {
+		Creds  TextArray  `json:"creds"`
+		Signed *TextArray `json:"signed"`
+	}{Creds: TextArray{"a"}, Signed: fromNullPointer})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"creds":["a"],"signed":null}`, string(marshaled))
+}
+
+func BenchmarkJSONStringArrayValue(b *testing.B) {
+	creds := JSONStringArray(testCreds(4000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := creds.Value(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextArrayValue(b *testing.B) {
+	creds := TextArray(testCreds(4000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := creds.Value(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONStringArrayScan(b *testing.B) {
+	src, err := json.Marshal(testCreds(4000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var creds JSONStringArray
+		if err := creds.Scan(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextArrayScan(b *testing.B) {
+	value, err := TextArray(testCreds(4000)).Value()
+	if err != nil {
+		b.Fatal(err)
+	}
+	src := []byte(value.(string))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var creds TextArray
+		if err := creds.Scan(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+const (
+	// DeadLetterSuffix is appended to a topic to name its dead letter topic
+	DeadLetterSuffix = ".dlq"
+
+	// HeaderOriginalTopic is the topic a dead letter was consumed from
+	HeaderOriginalTopic = "dlq-original-topic"
+	// HeaderOriginalPartition is the partition a dead letter was consumed from
+	HeaderOriginalPartition = "dlq-original-partition"
+	// HeaderOriginalOffset is the offset a dead letter was consumed from
+	HeaderOriginalOffset = "dlq-original-offset"
+	// HeaderError is the error the message failed with
+	HeaderError = "dlq-error"
+	// HeaderFailedAt is when the message failed, in RFC3339
+	HeaderFailedAt = "dlq-failed-at"
+	// HeaderReplayedFrom is set on replayed messages to the partition and offset of the dead letter
+	HeaderReplayedFrom = "dlq-replayed-from"
+)
+
+var (
+	// ErrUnknownTopic - the topic has not been registered
+	ErrUnknownTopic = errors.New("unknown topic")
+
+	topicsMu sync.RWMutex
+	topics   = map[string]*goavro.Codec{}
+)
+
+// RegisterTopic registers the avro schema of the messages on a topic, so its dead letters can be decoded
+func RegisterTopic(topic string, schema string) error {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return fmt.Errorf("failed to generate codec for %s: %w", topic, err)
+	}
+	topicsMu.Lock()
+	defer topicsMu.Unlock()
+	topics[topic] = codec
+	return nil
+}
+
+// Topics returns the names of the registered topics
+func Topics() []string {
+	topicsMu.RLock()
+	defer topicsMu.RUnlock()
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	return names
+}
+
+// DeadLetterTopic returns the name of the dead letter topic of a topic
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterSuffix
+}
+
+// DeadLetterMessage quarantines a message which could not be handled, to be written to the dead
+// letter topic of the topic it was consumed from
+func DeadLetterMessage(msg kafka.Message, cause error) kafka.Message {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+	return kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// DeadLetter is a message read from a dead letter topic
+type DeadLetter struct {
+	Partition         int            `json:"partition"`
+	Offset            int64          `json:"offset"`
+	OriginalTopic     string         `json:"originalTopic"`
+	OriginalPartition int            `json:"originalPartition"`
+	OriginalOffset    int64          `json:"originalOffset"`
+	Error             string         `json:"error"`
+	FailedAt          time.Time      `json:"failedAt"`
+	Key               []byte         `json:"key"`
+	Value             []byte         `json:"-"`
+	Headers           []kafka.Header `json:"-"`
+}
+
+// ParseDeadLetter reads the quarantine headers of a message from a dead letter topic
+func ParseDeadLetter(msg kafka.Message) DeadLetter {
+	dl := DeadLetter{
+		Partition:     msg.Partition,
+		Offset:        msg.Offset,
+		OriginalTopic: strings.TrimSuffix(msg.Topic, DeadLetterSuffix),
+		Key:           msg.Key,
+		Value:         msg.Value,
+	}
+	for _, h := range msg.Headers {
+		value := string(h.Value)
+		switch h.Key {
+		case HeaderOriginalTopic:
+			dl.OriginalTopic = value
+		case HeaderOriginalPartition:
+			dl.OriginalPartition, _ = strconv.Atoi(value)
+		case HeaderOriginalOffset:
+			dl.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderError:
+			dl.Error = value
+		case HeaderFailedAt:
+			dl.FailedAt, _ = time.Parse(time.RFC3339, value)
+		default:
+			dl.Headers = append(dl.Headers, h)
+		}
+	}
+	return dl
+}
+
+// Decode decodes the dead letter with the avro codec of its original topic, returning it as json
+func (dl DeadLetter) Decode() (string, error) {
+	return DecodeMessage(dl.OriginalTopic, dl.Value)
+}
+
+// DecodeMessage decodes a message with the avro codec registered for its topic, returning it as json
+func DecodeMessage(topic string, value []byte) (string, error) {
+	topicsMu.RLock()
+	codec, ok := topics[topic]
+	topicsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("failed to decode message from %s: %w", topic, ErrUnknownTopic)
+	}
+	native, _, err := codec.NativeFromBinary(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode message: %w", err)
+	}
+	textual, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode message as json: %w", err)
+	}
+	return string(textual), nil
+}
+
+// Replay returns the message to publish to the original topic, where it is handled again by the
+// topic's consumers. The quarantine headers are replaced with a reference to the dead letter
+func (dl DeadLetter) Replay() kafka.Message {
+	headers := append([]kafka.Header{}, dl.Headers...)
+	headers = append(headers, kafka.Header{
+		Key:   HeaderReplayedFrom,
+		Value: []byte(fmt.Sprintf("%d/%d", dl.Partition, dl.Offset)),
+	})
+	return kafka.Message{
+		Key:     dl.Key,
+		Value:   dl.Value,
+		Headers: headers,
+	}
+}
+
+// ReadDeadLetters reads the dead letters of a topic partition, from the offset to the end of the
+// partition or until limit are read. A negative offset starts at the first retained message
+func ReadDeadLetters(
+	ctx context.Context,
+	dialer *kafka.Dialer,
+	broker string,
+	topic string,
+	partition int,
+	offset int64,
+	limit int,
+) ([]DeadLetter, error) {
+	msgs, err := readPartition(ctx, dialer, broker, DeadLetterTopic(topic), partition, offset, limit)
+	letters := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		letters = append(letters, ParseDeadLetter(msg))
+	}
+	if err != nil {
+		return letters, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// DeadLetterPartitions returns the partitions of the dead letter topic of a topic
+func DeadLetterPartitions(ctx context.Context, dialer *kafka.Dialer, broker string, topic string) ([]int, error) {
+	return Partitions(ctx, dialer, broker, DeadLetterTopic(topic))
+}
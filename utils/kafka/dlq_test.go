@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/linkedin/goavro"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "record",
+  "name": "Test",
+  "fields": [{ "name": "id", "type": "string" }]
+}`
+
+func TestDeadLetter(t *testing.T) {
+	require.NoError(t, RegisterTopic("test.dlq.topic", testSchema))
+	codec, err := goavro.NewCodec(testSchema)
+	require.NoError(t, err)
+	value, err := codec.BinaryFromNative(nil, map[string]interface{}{"id": "abc"})
+	require.NoError(t, err)
+
+	consumed := kafka.Message{
+		Topic:     "test.dlq.topic",
+		Partition: 2,
+		Offset:    41,
+		Key:       []byte("key"),
+		Value:     value,
+		Headers:   []kafka.Header{{Key: requestutils.RequestIDHeaderKey, Value: []byte("req")}},
+	}
+	quarantined := DeadLetterMessage(consumed, errors.New("handler failed"))
+	// as read back from the dead letter topic
+	quarantined.Topic = DeadLetterTopic(consumed.Topic)
+	quarantined.Partition = 0
+	quarantined.Offset = 7
+
+	letter := ParseDeadLetter(quarantined)
+	assert.Equal(t, "test.dlq.topic", letter.OriginalTopic)
+	assert.Equal(t, 2, letter.OriginalPartition)
+	assert.Equal(t, int64(41), letter.OriginalOffset)
+	assert.Equal(t, "handler failed", letter.Error)
+	assert.False(t, letter.FailedAt.IsZero())
+	assert.Equal(t, []kafka.Header{{Key: requestutils.RequestIDHeaderKey, Value: []byte("req")}}, letter.Headers,
+		"only the original headers should be kept")
+
+	decoded, err := letter.Decode()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "abc"}`, decoded)
+
+	replay := letter.Replay()
+	assert.Equal(t, consumed.Key, replay.Key)
+	assert.Equal(t, consumed.Value, replay.Value)
+	assert.Equal(t, []kafka.Header{
+		{Key: requestutils.RequestIDHeaderKey, Value: []byte("req")},
+		{Key: HeaderReplayedFrom, Value: []byte("0/7")},
+	}, replay.Headers)
+
+	letter.OriginalTopic = "unregistered"
+	_, err = letter.Decode()
+	assert.True(t, errors.Is(err, ErrUnknownTopic))
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/faults"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// RequestIDHeaders - create the message headers which carry the request id from the context,
+// so a produced message can be correlated with the request that caused it
+func RequestIDHeaders(ctx context.Context) []kafka.Header {
+	reqID := requestutils.GetRequestID(ctx)
+	if reqID == "" {
+		return nil
+	}
+	return []kafka.Header{
+		{Key: requestutils.RequestIDHeaderKey, Value: []byte(reqID)},
+	}
+}
+
+// TraceHeaders - create the message headers which carry the trace context, so the consumer of a
+// message continues the trace that produced it
+func TraceHeaders(ctx context.Context) []kafka.Header {
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	if traceparent := header.Get(tracing.TraceparentHeader); traceparent != "" {
+		return []kafka.Header{
+			{Key: tracing.TraceparentHeader, Value: []byte(traceparent)},
+		}
+	}
+	return nil
+}
+
+// WriteMessages - write the messages within a producer span, adding the request id and trace
+// context to the headers of each
+func WriteMessages(ctx context.Context, writer *kafka.Writer, msgs ...kafka.Message) error {
+	ctx, span := tracing.StartSpan(ctx, "kafka.produce "+writer.Topic, tracing.WithKind(tracing.SpanKindProducer))
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.destination", writer.Topic)
+
+	headers := append(RequestIDHeaders(ctx), TraceHeaders(ctx)...)
+	for i := range msgs {
+		msgs[i].Headers = append(msgs[i].Headers, headers...)
+	}
+	err := faults.Inject(ctx, faults.Kafka)
+	if err == nil {
+		err = writer.WriteMessages(ctx, msgs...)
+	}
+	span.End(err)
+	return err
+}
+
+// StartConsumerSpan - start a consumer span for a message, continuing the trace in its headers
+func StartConsumerSpan(ctx context.Context, msg kafka.Message) (context.Context, *tracing.Span) {
+	for _, h := range msg.Headers {
+		if h.Key != tracing.TraceparentHeader {
+			continue
+		}
+		if sc, err := tracing.ParseTraceparent(string(h.Value)); err == nil {
+			ctx = tracing.ContextWithRemoteParent(ctx, sc)
+		}
+	}
+	ctx, span := tracing.StartSpan(ctx, "kafka.consume "+msg.Topic, tracing.WithKind(tracing.SpanKindConsumer))
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.destination", msg.Topic)
+	span.SetAttribute("messaging.kafka.partition", msg.Partition)
+	span.SetAttribute("messaging.kafka.offset", msg.Offset)
+	return ctx, span
+}
@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// HeaderReplayOf is set on replayed messages to the topic, partition and offset they were read from
+const HeaderReplayOf = "replay-of"
+
+// ReadTopic reads the messages of a topic partition, from the offset, or the first message at or after
+// since when it is set, to the end of the partition or until limit are read. A negative offset starts at
+// the first retained message
+func ReadTopic(
+	ctx context.Context,
+	dialer *kafka.Dialer,
+	broker string,
+	topic string,
+	partition int,
+	offset int64,
+	since time.Time,
+	limit int,
+) ([]kafka.Message, error) {
+	if !since.IsZero() {
+		conn, err := dialer.DialLeader(ctx, "tcp", broker, topic, partition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to topic: %w", err)
+		}
+		offset, err = conn.ReadOffset(since)
+		_ = conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read offset at %s: %w", since.Format(time.RFC3339), err)
+		}
+		if offset < 0 {
+			// no message was published since
+			return nil, nil
+		}
+	}
+	return readPartition(ctx, dialer, broker, topic, partition, offset, limit)
+}
+
+// ReplayMessage returns the message to publish again to be handled by the topic's consumers. Its headers
+// are kept so the replay continues the trace of the original, with a reference to where it was read from
+func ReplayMessage(msg kafka.Message) kafka.Message {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers, kafka.Header{
+		Key:   HeaderReplayOf,
+		Value: []byte(fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)),
+	})
+	return kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// Partitions returns the partitions of a topic
+func Partitions(ctx context.Context, dialer *kafka.Dialer, broker string, topic string) ([]int, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+	ids := make([]int, 0, len(partitions))
+	for _, p := range partitions {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+// readPartition reads the messages of a topic partition from the offset to the end of the partition or
+// until limit are read
+func readPartition(
+	ctx context.Context,
+	dialer *kafka.Dialer,
+	broker string,
+	topic string,
+	partition int,
+	offset int64,
+	limit int,
+) ([]kafka.Message, error) {
+	conn, err := dialer.DialLeader(ctx, "tcp", broker, topic, partition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to topic: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offsets: %w", err)
+	}
+	if offset < first {
+		offset = first
+	}
+	if offset >= last {
+		return nil, nil
+	}
+	if _, err := conn.Seek(offset, kafka.SeekAbsolute); err != nil {
+		return nil, fmt.Errorf("failed to seek topic: %w", err)
+	}
+
+	var msgs []kafka.Message
+	for offset < last && (limit <= 0 || len(msgs) < limit) {
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetReadDeadline(deadline)
+		}
+		msg, err := conn.ReadMessage(10e6)
+		if err != nil {
+			return msgs, fmt.Errorf("failed to read message at %d: %w", offset, err)
+		}
+		msg.Topic = topic
+		msg.Partition = partition
+		msgs = append(msgs, msg)
+		offset = msg.Offset + 1
+	}
+	return msgs, nil
+}
@@ -0,0 +1,30 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayMessage(t *testing.T) {
+	msg := kafka.Message{
+		Topic:     "test.replay.topic",
+		Partition: 1,
+		Offset:    12,
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Headers:   []kafka.Header{{Key: requestutils.RequestIDHeaderKey, Value: []byte("req")}},
+	}
+
+	replay := ReplayMessage(msg)
+	assert.Empty(t, replay.Topic, "the writer publishes to its destination")
+	assert.Equal(t, msg.Key, replay.Key)
+	assert.Equal(t, msg.Value, replay.Value)
+	assert.Equal(t, []kafka.Header{
+		{Key: requestutils.RequestIDHeaderKey, Value: []byte("req")},
+		{Key: HeaderReplayOf, Value: []byte("test.replay.topic/1/12")},
+	}, replay.Headers)
+	assert.Len(t, msg.Headers, 1, "the original headers are not changed")
+}
@@ -0,0 +1,181 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// FamilySweeps are the jobs cleaning up after other work, such as delivery sweeps and retention purges
+	FamilySweeps = "sweeps"
+	// FamilyExports are the jobs copying data out to the warehouse
+	FamilyExports = "exports"
+
+	// DefaultTTL is how long a lease lasts without being renewed, the longest a family goes without a
+	// leader after its leader dies
+	DefaultTTL = 30 * time.Second
+)
+
+var leaderGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "leader_elected",
+		Help: "Whether this instance is the leader of the job family, 1, or not, 0",
+	},
+	[]string{"family"},
+)
+
+func init() {
+	prometheus.MustRegister(leaderGauge)
+}
+
+// Store holds a lease per family, which one holder at a time may take until it expires
+type Store interface {
+	// Acquire takes the family's lease for the holder, or extends it if the holder already has it,
+	// returning false while another holder's lease has not expired
+	Acquire(ctx context.Context, family, holder string, ttl time.Duration) (bool, error)
+}
+
+// Elector campaigns for the leadership of job families, so only one instance runs the jobs of each
+type Elector struct {
+	store    Store
+	holder   string
+	ttl      time.Duration
+	families []string
+	mu       sync.RWMutex
+	// leading is when the lease of each family led expires, as far as this instance knows
+	leading map[string]time.Time
+	now     func() time.Time
+}
+
+// NewElector creates an elector campaigning for the families under the holder's name
+func NewElector(store Store, holder string, ttl time.Duration, families ...string) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	sort.Strings(families)
+	for _, family := range families {
+		leaderGauge.WithLabelValues(family).Set(0)
+	}
+	return &Elector{
+		store:    store,
+		holder:   holder,
+		ttl:      ttl,
+		families: families,
+		leading:  map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// Holder names this instance by its hostname, which is the task or pod, and a random suffix so that
+// restarts with the same hostname do not inherit the previous process's leases
+func Holder() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%s", host, uuid.New().String()[:8])
+}
+
+// IsLeader returns whether this instance leads the family and its lease has not expired
+func (e *Elector) IsLeader(family string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	expiry, ok := e.leading[family]
+	return ok && e.now().Before(expiry)
+}
+
+// Leading returns the families this instance currently leads, in name order
+func (e *Elector) Leading() []string {
+	leading := []string{}
+	for _, family := range e.families {
+		if e.IsLeader(family) {
+			leading = append(leading, family)
+		}
+	}
+	return leading
+}
+
+// Campaign takes or renews the lease of every family, it implements service.JobFunc so campaigns
+// can be run by a job worker. A family whose lease fails to renew is led until the lease expires
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	var result error
+	for _, family := range e.families {
+		// the local expiry is measured from before the request so it never outlasts the stored lease
+		expiry := e.now().Add(e.ttl)
+		acquired, err := e.store.Acquire(ctx, family, e.holder, e.ttl)
+		if err != nil {
+			if result == nil {
+				result = fmt.Errorf("failed to campaign for %s: %w", family, err)
+			}
+			e.set(ctx, family, e.IsLeader(family), time.Time{})
+			continue
+		}
+		e.set(ctx, family, acquired, expiry)
+	}
+	return true, result
+}
+
+// set records whether the family is led, logging changes of leadership. A zero expiry keeps the
+// current one
+func (e *Elector) set(ctx context.Context, family string, leading bool, expiry time.Time) {
+	e.mu.Lock()
+	_, wasLeading := e.leading[family]
+	if !leading {
+		delete(e.leading, family)
+	} else if !expiry.IsZero() {
+		e.leading[family] = expiry
+	}
+	e.mu.Unlock()
+
+	if leading {
+		leaderGauge.WithLabelValues(family).Set(1)
+	} else {
+		leaderGauge.WithLabelValues(family).Set(0)
+	}
+	if leading == wasLeading {
+		return
+	}
+
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+	if leading {
+		logger.Info().Str("family", family).Str("holder", e.holder).Msg("elected leader of job family")
+	} else {
+		logger.Warn().Str("family", family).Str("holder", e.holder).Msg("lost leadership of job family")
+	}
+}
+
+// Guard only runs the job while this instance leads the family, other instances skip their runs
+func (e *Elector) Guard(family string, fn srv.JobFunc) srv.JobFunc {
+	return func(ctx context.Context) (bool, error) {
+		if !e.IsLeader(family) {
+			return false, nil
+		}
+		return fn(ctx)
+	}
+}
+
+// Jobs renews the leases three times per lease, so a single failed renewal does not lose leadership
+func (e *Elector) Jobs() []srv.Job {
+	return []srv.Job{
+		{
+			Name:    "leader_election",
+			Service: "grant",
+			Func:    e.Campaign,
+			Cadence: e.ttl / 3,
+			Workers: 1,
+		},
+	}
+}
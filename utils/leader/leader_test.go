@@ -0,0 +1,100 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Acquire(ctx context.Context, family, holder string, ttl time.Duration) (bool, error) {
+	return false, errors.New("database unavailable")
+}
+
+func TestElectorFailover(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	store := NewMemoryStore()
+	store.now = clock
+	first := NewElector(store, "first", 30*time.Second, FamilySweeps, FamilyExports)
+	first.now = clock
+	second := NewElector(store, "second", 30*time.Second, FamilySweeps, FamilyExports)
+	second.now = clock
+
+	_, err := first.Campaign(ctx)
+	require.NoError(t, err)
+	_, err = second.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FamilyExports, FamilySweeps}, first.Leading())
+	assert.Empty(t, second.Leading(), "only one instance leads each family")
+
+	// the leader renews its lease before it expires
+	now = now.Add(20 * time.Second)
+	_, err = first.Campaign(ctx)
+	require.NoError(t, err)
+	now = now.Add(20 * time.Second)
+	_, err = second.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, first.IsLeader(FamilySweeps))
+	assert.False(t, second.IsLeader(FamilySweeps))
+
+	// once the leader dies its lease expires and the standby takes over
+	now = now.Add(31 * time.Second)
+	assert.False(t, first.IsLeader(FamilySweeps), "an unrenewed lease expires locally as well")
+	_, err = second.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, second.IsLeader(FamilySweeps))
+	_, err = first.Campaign(ctx)
+	require.NoError(t, err)
+	assert.False(t, first.IsLeader(FamilySweeps))
+}
+
+func TestElectorFailedRenewal(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)
+	e := NewElector(NewMemoryStore(), "leader", 30*time.Second, FamilySweeps)
+	e.now = func() time.Time { return now }
+	_, err := e.Campaign(ctx)
+	require.NoError(t, err)
+
+	// a failed renewal keeps leadership until the lease it held expires
+	e.store = failingStore{}
+	now = now.Add(10 * time.Second)
+	_, err = e.Campaign(ctx)
+	assert.Error(t, err)
+	assert.True(t, e.IsLeader(FamilySweeps))
+
+	now = now.Add(25 * time.Second)
+	assert.False(t, e.IsLeader(FamilySweeps))
+	_, err = e.Campaign(ctx)
+	assert.Error(t, err)
+	assert.Empty(t, e.Leading())
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	e := NewElector(NewMemoryStore(), "leader", time.Minute, FamilyExports)
+	runs := 0
+	job := e.Guard(FamilyExports, func(ctx context.Context) (bool, error) {
+		runs++
+		return true, nil
+	})
+
+	attempted, err := job(ctx)
+	require.NoError(t, err)
+	assert.False(t, attempted, "followers skip the job")
+
+	_, err = e.Campaign(ctx)
+	require.NoError(t, err)
+	attempted, err = job(ctx)
+	require.NoError(t, err)
+	assert.True(t, attempted)
+	assert.Equal(t, 1, runs)
+}
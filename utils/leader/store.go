@@ -0,0 +1,74 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore keeps the leases in the leader_leases table, leases are timed by the database clock
+// so the instances' clocks do not need to agree
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Acquire takes the lease if it is free, expired or already held by the holder
+func (s *PostgresStore) Acquire(ctx context.Context, family, holder string, ttl time.Duration) (bool, error) {
+	var leader string
+	err := s.db.GetContext(ctx, &leader, `
+			INSERT INTO leader_leases (family, holder, acquired_at, expires_at)
+			VALUES ($1, $2, current_timestamp, current_timestamp + $3 * interval '1 millisecond')
+			ON CONFLICT (family) DO UPDATE SET
+				holder = $2,
+				acquired_at = CASE WHEN leader_leases.holder = $2 THEN leader_leases.acquired_at ELSE current_timestamp END,
+				expires_at = current_timestamp + $3 * interval '1 millisecond'
+			WHERE leader_leases.holder = $2 OR leader_leases.expires_at < current_timestamp
+			RETURNING holder
+		`, family, holder, ttl.Milliseconds())
+
+	if err == sql.ErrNoRows {
+		// the update was skipped, another holder's lease is current
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+	return leader == holder, nil
+}
+
+type lease struct {
+	holder  string
+	expires time.Time
+}
+
+// MemoryStore keeps the leases in memory, it does not coordinate across instances
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]lease
+	now    func() time.Time
+}
+
+// NewMemoryStore creates an empty in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{leases: map[string]lease{}, now: time.Now}
+}
+
+// Acquire takes the lease if it is free, expired or already held by the holder
+func (s *MemoryStore) Acquire(ctx context.Context, family, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if current, ok := s.leases[family]; ok && current.holder != holder && now.Before(current.expires) {
+		return false, nil
+	}
+	s.leases[family] = lease{holder: holder, expires: now.Add(ttl)}
+	return true, nil
+}
@@ -2,6 +2,8 @@ package logging
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"os"
 	"strconv"
@@ -14,6 +16,13 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+const (
+	// WalletHashField is the log field holding the hashed wallet id of a request
+	WalletHashField = "wallet_hash"
+	// MerchantHashField is the log field holding the hashed merchant id of a request
+	MerchantHashField = "merchant_hash"
+)
+
 var (
 	// we are not promising to get every log message in the log
 	// anymore, when it comes down to it, we would rather the service
@@ -92,14 +101,25 @@ func SetupLogger(ctx context.Context) (context.Context, *zerolog.Logger) {
 	return l.WithContext(ctx), &l
 }
 
-// AddWalletIDToContext adds wallet id to context
+// HashIdentifier hashes a wallet or merchant identifier for logging, so log lines about the same
+// wallet can be correlated without the logs holding the identifier itself
+func HashIdentifier(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AddWalletIDToContext adds the hashed wallet id to the logger in the context
 func AddWalletIDToContext(ctx context.Context, walletID uuid.UUID) {
-	l := zerolog.Ctx(ctx)
-	if e := l.Debug(); e.Enabled() {
-		l.UpdateContext(func(c zerolog.Context) zerolog.Context {
-			return c.Str("walletID", walletID.String())
-		})
-	}
+	zerolog.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str(WalletHashField, HashIdentifier(walletID.String()))
+	})
+}
+
+// AddMerchantIDToContext adds the hashed merchant id to the logger in the context
+func AddMerchantIDToContext(ctx context.Context, merchantID string) {
+	zerolog.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str(MerchantHashField, HashIdentifier(merchantID))
+	})
 }
 
 // Progress - type to store the incremental progress of a task
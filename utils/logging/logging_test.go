@@ -13,7 +13,7 @@ import (
 
 func TestAddWalletIDToContext(t *testing.T) {
 	type logLine struct {
-		WalletID uuid.UUID `json:"walletID"`
+		WalletHash string `json:"wallet_hash"`
 	}
 
 	var b bytes.Buffer
@@ -39,7 +39,10 @@ func TestAddWalletIDToContext(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !uuid.Equal(line.WalletID, walletID) {
-		t.Fatal("WalletID must be included")
+	if line.WalletHash != HashIdentifier(walletID.String()) {
+		t.Fatal("the hashed wallet id must be included")
+	}
+	if bytes.Contains(b.Bytes(), []byte(walletID.String())) {
+		t.Fatal("the wallet id itself must not be logged")
 	}
 }
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaReaderCollector exports the stats of kafka consumers. Reader stats are reset each time they are
+// read, so the collector keeps the running totals
+type KafkaReaderCollector struct {
+	mu      sync.Mutex
+	readers []*kafka.Reader
+	totals  map[*kafka.Reader]*kafka.ReaderStats
+
+	messagesDesc *prometheus.Desc
+	bytesDesc    *prometheus.Desc
+	errorsDesc   *prometheus.Desc
+	lagDesc      *prometheus.Desc
+}
+
+// NewKafkaReaderCollector creates a collector for the consumers of the service
+func NewKafkaReaderCollector(service string, readers ...*kafka.Reader) *KafkaReaderCollector {
+	labels := []string{"topic", "partition"}
+	constLabels := prometheus.Labels{"service": service}
+	c := &KafkaReaderCollector{
+		totals:       map[*kafka.Reader]*kafka.ReaderStats{},
+		messagesDesc: prometheus.NewDesc("kafka_consumer_messages_total", "The number of messages consumed.", labels, constLabels),
+		bytesDesc:    prometheus.NewDesc("kafka_consumer_message_bytes_total", "The bytes of messages consumed.", labels, constLabels),
+		errorsDesc:   prometheus.NewDesc("kafka_consumer_errors_total", "The number of errors consuming messages.", labels, constLabels),
+		lagDesc:      prometheus.NewDesc("kafka_consumer_lag", "The number of messages the consumer is behind.", labels, constLabels),
+	}
+	for _, reader := range readers {
+		c.AddReader(reader)
+	}
+	return c
+}
+
+// AddReader adds a consumer to the collector
+func (c *KafkaReaderCollector) AddReader(reader *kafka.Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readers = append(c.readers, reader)
+	c.totals[reader] = &kafka.ReaderStats{}
+}
+
+// Describe implements the prometheus.Collector interface
+func (c *KafkaReaderCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messagesDesc
+	ch <- c.bytesDesc
+	ch <- c.errorsDesc
+	ch <- c.lagDesc
+}
+
+// Collect implements the prometheus.Collector interface
+func (c *KafkaReaderCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, reader := range c.readers {
+		stats := reader.Stats()
+		totals := c.totals[reader]
+		totals.Messages += stats.Messages
+		totals.Bytes += stats.Bytes
+		totals.Errors += stats.Errors
+
+		ch <- prometheus.MustNewConstMetric(c.messagesDesc, prometheus.CounterValue, float64(totals.Messages), stats.Topic, stats.Partition)
+		ch <- prometheus.MustNewConstMetric(c.bytesDesc, prometheus.CounterValue, float64(totals.Bytes), stats.Topic, stats.Partition)
+		ch <- prometheus.MustNewConstMetric(c.errorsDesc, prometheus.CounterValue, float64(totals.Errors), stats.Topic, stats.Partition)
+		ch <- prometheus.MustNewConstMetric(c.lagDesc, prometheus.GaugeValue, float64(stats.Lag), stats.Topic, stats.Partition)
+	}
+}
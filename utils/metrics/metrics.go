@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultAddr is where the metrics listener serves unless METRICS_ADDRESS is set
+const DefaultAddr = ":9090"
+
+var (
+	// HTTPRequestDuration is the latency of requests served, by service, route pattern, method and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "A histogram of latencies for requests served, by service, route, method and status.",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"service", "route", "method", "status"},
+	)
+
+	// JobDuration is the latency of background job runs, by service, job and whether the run failed
+	JobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "job_duration_seconds",
+			Help:    "A histogram of latencies for background job runs, by service, job and status.",
+			Buckets: []float64{.01, .1, .5, 1, 5, 30, 60, 300},
+		},
+		[]string{"service", "job", "status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequestDuration, JobDuration)
+}
+
+// Addr returns the address the metrics listener should serve on
+func Addr() string {
+	if addr := os.Getenv("METRICS_ADDRESS"); addr != "" {
+		return addr
+	}
+	return DefaultAddr
+}
+
+// NewServer creates the dedicated metrics listener, kept apart from the api so it is never exposed publicly
+func NewServer() *http.Server {
+	return &http.Server{
+		Addr:         Addr(),
+		Handler:      promhttp.Handler(),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket handlers take over the connection through the recorder
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if sr.status == 0 {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// HTTPServer is a middleware recording the latency of requests under the service label. Routes are labelled
+// with their chi pattern rather than the path, so ids do not create new series
+func HTTPServer(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			HTTPRequestDuration.WithLabelValues(service, route, r.Method, strconv.Itoa(status)).
+				Observe(time.Since(started).Seconds())
+		})
+	}
+}
+
+// ObserveJob records a background job run which started at the time
+func ObserveJob(service string, job string, started time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	JobDuration.WithLabelValues(service, job, status).Observe(time.Since(started).Seconds())
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestHTTPServerLabelsRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.With(HTTPServer("test")).Get("/v1/orders/{orderID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	before := testutil.CollectAndCount(HTTPRequestDuration)
+	for _, id := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest("GET", "/v1/orders/"+id, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// every id is served by the same route, so only one series is added
+	if after := testutil.CollectAndCount(HTTPRequestDuration); after != before+1 {
+		t.Errorf("expected one new series, got %d", after-before)
+	}
+
+	histogram, err := HTTPRequestDuration.GetMetricWithLabelValues("test", "/v1/orders/{orderID}", "GET", "404")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m dto.Metric
+	if err := histogram.(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if count := m.GetHistogram().GetSampleCount(); count != 3 {
+		t.Errorf("expected 3 requests labelled with the route pattern and status, got %d", count)
+	}
+}
+
+func TestObserveJob(t *testing.T) {
+	before := testutil.CollectAndCount(JobDuration)
+	ObserveJob("test", "job", time.Now(), nil)
+	ObserveJob("test", "job", time.Now(), errors.New("failed"))
+	ObserveJob("test", "job", time.Now(), nil)
+
+	// runs are split by whether they failed
+	if after := testutil.CollectAndCount(JobDuration); after != before+2 {
+		t.Errorf("expected two new series, got %d", after-before)
+	}
+}
@@ -0,0 +1,315 @@
+// Package msgpack encodes and decodes MessagePack by way of the json representation of a
+// value, so the json struct tags already on request and response types apply unchanged
+package msgpack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// ContentType is the media type of MessagePack payloads
+	ContentType = "application/msgpack"
+	// maxDepth bounds the nesting of decoded payloads
+	maxDepth = 64
+)
+
+var (
+	// ErrTruncated is returned when a payload ends part way through a value
+	ErrTruncated = errors.New("msgpack: truncated payload")
+)
+
+// Marshal encodes v as MessagePack, as it would be encoded as json
+func Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(b)
+}
+
+// Unmarshal decodes a MessagePack payload into v, as if it were json
+func Unmarshal(data []byte, v interface{}) error {
+	b, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// FromJSON converts a json document to MessagePack
+func FromJSON(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ToJSON converts a MessagePack payload to json, binary values become base64 strings
+func ToJSON(data []byte) ([]byte, error) {
+	d := &decoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("msgpack: trailing data after payload")
+	}
+	return json.Marshal(v)
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		return encodeNumber(buf, v)
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		encodeLength(buf, len(v), 0x90, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		encodeLength(buf, len(v), 0x80, 0xde, 0xdf)
+		// keys are sorted so equal values always encode to the same bytes
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encodeString(buf, key)
+			if err := encode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+func encodeNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		switch {
+		case i >= 0 && i <= 0x7f:
+			buf.WriteByte(byte(i))
+		case i < 0 && i >= -32:
+			buf.WriteByte(byte(int8(i)))
+		default:
+			buf.WriteByte(0xd3)
+			_ = binary.Write(buf, binary.BigEndian, i)
+		}
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		_ = binary.Write(buf, binary.BigEndian, u)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(0xcb)
+	_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	return nil
+}
+
+func encodeString(buf *bytes.Buffer, s string) {
+	if len(s) <= 31 {
+		buf.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		encodeLength(buf, len(s), 0, 0xda, 0xdb)
+	}
+	buf.WriteString(s)
+}
+
+// encodeLength writes the header of a collection, using the fixed form when one is
+// given and the length allows it, otherwise the 16 or 32 bit form
+func encodeLength(buf *bytes.Buffer, n int, fixed, b16, b32 byte) {
+	switch {
+	case fixed != 0 && n <= 15:
+		buf.WriteByte(fixed | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, ErrTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("msgpack: payload nested too deeply")
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend from the encoded size
+		shift := uint(64 - 8*size)
+		return int64(u<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n), depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%x", c)
+}
+
+func (d *decoder) decodeString(n int) (string, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *decoder) decodeArray(n int, depth int) ([]interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *decoder) decodeMap(n int, depth int) (map[string]interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case string:
+			m[k] = value
+		case int64, uint64:
+			m[fmt.Sprint(k)] = value
+		default:
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,66 @@
+package msgpack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type credential struct {
+	ID          string   `json:"id"`
+	SignedCreds []string `json:"signedCreds"`
+	Count       int      `json:"count"`
+	Negative    int64    `json:"negative"`
+	Ratio       float64  `json:"ratio"`
+	Revoked     bool     `json:"revoked"`
+	Proof       *string  `json:"batchProof"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := credential{
+		ID:          "9c8e0a5a-0f7a-4b4f-9b8e-3e1f2a6b7c8d",
+		SignedCreds: []string{"a", strings.Repeat("b", 300)},
+		Count:       70000,
+		Negative:    -5000000000,
+		Ratio:       0.25,
+		Revoked:     true,
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out credential
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || len(out.SignedCreds) != 2 || out.SignedCreds[1] != in.SignedCreds[1] ||
+		out.Count != in.Count || out.Negative != in.Negative || out.Ratio != in.Ratio || !out.Revoked || out.Proof != nil {
+		t.Fatalf("round trip changed the value: %+v", out)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	b, err := FromJSON([]byte(`{"a":[1,-1,true,null,"x"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x81, 0xa1, 'a', 0x95, 0x01, 0xff, 0xc3, 0xc0, 0xa1, 'x'}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("unexpected encoding %x", b)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		{},
+		{0x92, 0x01},
+		{0xdd, 0xff, 0xff, 0xff, 0xff},
+		{0xc1},
+		{0x01, 0x02},
+	} {
+		if _, err := ToJSON(payload); err == nil {
+			t.Errorf("expected %x to be rejected", payload)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/requestutils"
+	"github.com/brave-intl/bat-go/utils/tracing"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi"
+	"github.com/lib/pq"
+)
+
+// Family is a group of errors sharing a known cause. Errors in a family are reported as a single issue
+// at the family's level, rather than one issue per call site
+type Family struct {
+	// Name is the fingerprint of the issue the family is grouped into
+	Name string
+	// Level is the severity errors in the family are reported at
+	Level sentry.Level
+	// Match reports whether the error belongs to the family
+	Match func(error) bool
+	// Subgroup optionally splits the family into an issue per returned value
+	Subgroup func(error) string
+}
+
+var (
+	familiesMu sync.RWMutex
+	families   []Family
+)
+
+// levels orders the sentry levels by severity
+var levels = map[sentry.Level]int{
+	sentry.LevelDebug:   0,
+	sentry.LevelInfo:    1,
+	sentry.LevelWarning: 2,
+	sentry.LevelError:   3,
+	sentry.LevelFatal:   4,
+}
+
+// headers which are never sent to sentry
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Signature", "Digest"}
+
+func init() {
+	// unique violations, serialization failures and deadlocks are expected under concurrent writes
+	RegisterFamily(Family{
+		Name:  "db-conflict",
+		Level: sentry.LevelWarning,
+		Match: func(err error) bool {
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) {
+				return false
+			}
+			switch pqErr.Code {
+			case "23505", "40001", "40P01":
+				return true
+			}
+			return false
+		},
+		Subgroup: func(err error) string {
+			var pqErr *pq.Error
+			errors.As(err, &pqErr)
+			return pqErr.Code.Name()
+		},
+	})
+}
+
+// RegisterFamily adds a family of errors to fingerprint. Families are matched in the order registered
+func RegisterFamily(family Family) {
+	familiesMu.Lock()
+	defer familiesMu.Unlock()
+	families = append(families, family)
+}
+
+// FamilyOf returns the family the error belongs to, if any
+func FamilyOf(err error) (*Family, bool) {
+	familiesMu.RLock()
+	defer familiesMu.RUnlock()
+	for i := range families {
+		if families[i].Match(err) {
+			family := families[i]
+			return &family, true
+		}
+	}
+	return nil, false
+}
+
+// Fingerprint returns the sentry fingerprint for the error, or nil to use sentry's default grouping
+func Fingerprint(err error) []string {
+	family, ok := FamilyOf(err)
+	if !ok {
+		return nil
+	}
+	fingerprint := []string{family.Name}
+	if family.Subgroup != nil {
+		fingerprint = append(fingerprint, family.Subgroup(err))
+	}
+	return fingerprint
+}
+
+// Init configures sentry from SENTRY_DSN, tagging events with the service and release. Events below
+// SENTRY_MIN_LEVEL, warning unless set, are dropped. The returned func flushes queued events
+func Init(ctx context.Context, service string) (func(), error) {
+	dsn := os.Getenv("SENTRY_DSN")
+	if dsn == "" {
+		return func() {}, nil
+	}
+
+	minLevel := sentry.LevelWarning
+	if level := sentry.Level(strings.ToLower(os.Getenv("SENTRY_MIN_LEVEL"))); level != "" {
+		if _, ok := levels[level]; !ok {
+			return func() {}, fmt.Errorf("unknown sentry level: %s", level)
+		}
+		minLevel = level
+	}
+
+	buildTime, _ := appctx.GetStringFromContext(ctx, appctx.BuildTimeCTXKey)
+	commit, _ := appctx.GetStringFromContext(ctx, appctx.CommitCTXKey)
+	env, _ := appctx.GetStringFromContext(ctx, appctx.EnvironmentCTXKey)
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         dsn,
+		Release:     fmt.Sprintf("bat-go@%s-%s", commit, buildTime),
+		Environment: env,
+		BeforeSend:  beforeSend(minLevel),
+	})
+	if err != nil {
+		return func() {}, err
+	}
+	sentry.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("service", service)
+	})
+
+	return func() { sentry.Flush(2 * time.Second) }, nil
+}
+
+// beforeSend groups events for known error families and drops those below the minimum level
+func beforeSend(minLevel sentry.Level) func(*sentry.Event, *sentry.EventHint) *sentry.Event {
+	return func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if hint != nil && hint.OriginalException != nil {
+			if family, ok := FamilyOf(hint.OriginalException); ok {
+				event.Fingerprint = Fingerprint(hint.OriginalException)
+				event.Level = family.Level
+			}
+		}
+		level := event.Level
+		if level == "" {
+			level = sentry.LevelError
+		}
+		if levels[level] < levels[minLevel] {
+			return nil
+		}
+		return event
+	}
+}
+
+// scopeFromContext tags the scope with the request and trace the context belongs to
+func scopeFromContext(ctx context.Context, scope *sentry.Scope) {
+	if reqID := requestutils.GetRequestID(ctx); reqID != "" {
+		scope.SetTag("reqID", reqID)
+	}
+	if sc := tracing.SpanFromContext(ctx).Context(); sc.IsValid() {
+		scope.SetTag("trace_id", sc.TraceID.String())
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+		scope.SetTag("route", rctx.RoutePattern())
+	}
+}
+
+// setRequest describes the request on events from the scope, without its credentials or body
+func setRequest(scope *sentry.Scope, r *http.Request) {
+	req := sentry.NewRequest(r)
+	req.Cookies = ""
+	for _, header := range sensitiveHeaders {
+		delete(req.Headers, header)
+	}
+	scope.AddEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		event.Request = req
+		return event
+	})
+}
+
+// CaptureError reports an error with the request and trace in the context
+func CaptureError(ctx context.Context, err error) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scopeFromContext(ctx, scope)
+		sentry.CaptureException(err)
+	})
+}
+
+// CaptureRequestError reports an error which failed the request
+func CaptureRequestError(r *http.Request, err error) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scopeFromContext(r.Context(), scope)
+		setRequest(scope, r)
+		sentry.CaptureException(err)
+	})
+}
+
+// CapturePanic reports a recovered panic while serving the request
+func CapturePanic(r *http.Request, message string) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scopeFromContext(r.Context(), scope)
+		setRequest(scope, r)
+		event := sentry.NewEvent()
+		event.Level = sentry.LevelFatal
+		event.Message = message
+		sentry.CaptureEvent(event)
+	})
+}
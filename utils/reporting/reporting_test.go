@@ -0,0 +1,63 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	conflict := fmt.Errorf("failed to insert order: %w", &pq.Error{Code: "23505"})
+	assert.Equal(t, []string{"db-conflict", "unique_violation"}, Fingerprint(conflict))
+
+	deadlock := fmt.Errorf("failed to update order: %w", &pq.Error{Code: "40P01"})
+	assert.Equal(t, []string{"db-conflict", "deadlock_detected"}, Fingerprint(deadlock))
+
+	assert.Nil(t, Fingerprint(fmt.Errorf("failed: %w", &pq.Error{Code: "23503"})),
+		"other database errors should keep the default grouping")
+	assert.Nil(t, Fingerprint(errors.New("unknown")))
+}
+
+func TestBeforeSend(t *testing.T) {
+	send := beforeSend(sentry.LevelWarning)
+
+	conflict := &pq.Error{Code: "40001"}
+	event := send(&sentry.Event{Level: sentry.LevelError}, &sentry.EventHint{OriginalException: conflict})
+	if assert.NotNil(t, event) {
+		assert.Equal(t, sentry.LevelWarning, event.Level)
+		assert.Equal(t, []string{"db-conflict", "serialization_failure"}, event.Fingerprint)
+	}
+
+	assert.NotNil(t, send(&sentry.Event{}, nil), "events without a level are errors")
+	assert.Nil(t, send(&sentry.Event{Level: sentry.LevelInfo}, nil), "events below the threshold should be dropped")
+
+	send = beforeSend(sentry.LevelError)
+	assert.Nil(t, send(&sentry.Event{Level: sentry.LevelError}, &sentry.EventHint{OriginalException: conflict}),
+		"families reported below the threshold should be dropped")
+}
+
+func TestSetRequestStripsCredentials(t *testing.T) {
+	r := httptest.NewRequest("POST", "/v1/orders", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "session=secret")
+	r.Header.Set("Signature", "keyId=\"abc\"")
+	r.Header.Set("User-Agent", "test")
+
+	scope := sentry.NewScope()
+	setRequest(scope, r)
+	event := scope.ApplyToEvent(sentry.NewEvent(), nil)
+
+	if assert.NotNil(t, event.Request) {
+		assert.Equal(t, "POST", event.Request.Method)
+		assert.Equal(t, "test", event.Request.Headers["User-Agent"])
+		assert.Empty(t, event.Request.Cookies)
+		for _, header := range sensitiveHeaders {
+			assert.NotContains(t, event.Request.Headers, header)
+		}
+	}
+}
@@ -57,6 +57,11 @@ func SetRequestID(ctx context.Context, r *http.Request) {
 	}
 }
 
+// WithRequestID attaches a request id to a context
+func WithRequestID(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, RequestID, reqID)
+}
+
 // GetRequestID gets the request id
 func GetRequestID(ctx context.Context) string {
 	if reqID, ok := ctx.Value(RequestID).(string); ok {
@@ -0,0 +1,377 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/closers"
+)
+
+const (
+	amzDateFormat = "20060102T150405Z"
+	service       = "s3"
+)
+
+// ErrInvalidURI - the uri is not of the form s3://bucket/key
+var ErrInvalidURI = errors.New("invalid s3 uri")
+
+// Credentials used to sign requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Client puts and gets objects using the S3 REST api
+type Client struct {
+	// Endpoint overrides the regional endpoint, requests are made path style when set
+	Endpoint    string
+	Region      string
+	Credentials Credentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// New creates a client configured from the standard AWS_* environment variables. AWS_S3_ENDPOINT
+// optionally points the client at an s3 compatible service
+func New() (*Client, error) {
+	region, creds, err := EnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		Endpoint:    os.Getenv("AWS_S3_ENDPOINT"),
+		Region:      region,
+		Credentials: creds,
+		client:      &http.Client{Timeout: time.Minute},
+		now:         time.Now,
+	}, nil
+}
+
+// EnvCredentials returns the region and credentials from the standard AWS_* environment variables
+func EnvCredentials() (string, Credentials, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", Credentials{}, errors.New("AWS_REGION must be set")
+	}
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", Credentials{}, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return region, creds, nil
+}
+
+// ParseURI splits an s3://bucket/key uri into its bucket and key
+func ParseURI(uri string) (string, string, error) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return "", "", ErrInvalidURI
+	}
+	parts := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidURI
+	}
+	return parts[0], parts[1], nil
+}
+
+// IsURI reports whether the location is an s3 uri rather than a local path
+func IsURI(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// PutObject uploads the body to the key in the bucket
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	_, err = c.do(req, body)
+	if err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// GetObject downloads the key in the bucket
+func (c *Client) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	return body, nil
+}
+
+// Object is an object listed in a bucket
+type Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+type listBucketResult struct {
+	Contents              []Object `xml:"Contents"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+}
+
+// ListObjects lists every object in the bucket whose key starts with the prefix
+func (c *Client) ListObjects(ctx context.Context, bucket, prefix string) ([]Object, error) {
+	objects := []Object{}
+	token := ""
+	for {
+		req, err := c.newRequest(ctx, http.MethodGet, bucket, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req.URL.RawQuery = query.Encode()
+
+		body, err := c.do(req, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse listing of s3://%s/%s: %w", bucket, prefix, err)
+		}
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// DownloadObject streams the key in the bucket to w, for objects too large to hold in memory. It is not
+// subject to the client's timeout, only to the context's
+func (c *Client) DownloadObject(ctx context.Context, bucket, key string, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	c.sign(req, nil)
+	resp, err := (&http.Client{Transport: c.client.Transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer closers.Panic(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to get s3://%s/%s: unexpected status %d: %s", bucket, key, resp.StatusCode, string(data))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, body []byte) (*http.Request, error) {
+	var u string
+	if c.Endpoint != "" {
+		u = strings.TrimSuffix(c.Endpoint, "/") + "/" + bucket + "/" + escapePath(key)
+	} else {
+		u = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, c.Region, escapePath(key))
+	}
+	return http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+}
+
+func (c *Client) do(req *http.Request, body []byte) ([]byte, error) {
+	c.sign(req, body)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closers.Panic(resp.Body)
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+func (c *Client) sign(req *http.Request, body []byte) {
+	Sign(req, body, c.Credentials, c.Region, service, c.now())
+}
+
+// Sign adds an AWS signature version 4 authorization header for the service in the region to the request
+func Sign(req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(amzDateFormat)
+	date := amzDate[:8]
+	payloadHash := hashHex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(req.Header)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+	// the host header is sent from the request url
+	req.Header.Del("Host")
+}
+
+// canonicalizeHeaders returns the signed header names and the canonical header block
+func canonicalizeHeaders(header http.Header) (string, string) {
+	names := make([]string, 0, len(header))
+	values := make(map[string]string, len(header))
+	for name, vs := range header {
+		lower := strings.ToLower(name)
+		if lower != "host" && lower != "content-type" && lower != "range" && !strings.HasPrefix(lower, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, len(vs))
+		for i, v := range vs {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		names = append(names, lower)
+		values[lower] = strings.Join(trimmed, ",")
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := query[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes each segment of the key, keeping the separators
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i := range segments {
+		segments[i] = escape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent encodes everything but the unreserved characters, as sigv4 requires
+func escape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// ReadLocation reads an s3 uri, or a local path
+func ReadLocation(ctx context.Context, location string) ([]byte, error) {
+	if !IsURI(location) {
+		return ioutil.ReadFile(location)
+	}
+	bucket, key, err := ParseURI(location)
+	if err != nil {
+		return nil, err
+	}
+	client, err := New()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetObject(ctx, bucket, key)
+}
+
+// WriteLocation writes to an s3 uri, or a local path, creating its directory
+func WriteLocation(ctx context.Context, location string, data []byte, contentType string) error {
+	if !IsURI(location) {
+		if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(location, data, 0600)
+	}
+	bucket, key, err := ParseURI(location)
+	if err != nil {
+		return err
+	}
+	client, err := New()
+	if err != nil {
+		return err
+	}
+	return client.PutObject(ctx, bucket, key, data, contentType)
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// Router exposes the status of scheduled jobs and lets operators trigger them
+func Router(s *Scheduler) chi.Router {
+	r := chi.NewRouter()
+	r.Method("GET", "/", GetJobStatus(s))
+	r.Method("POST", "/{name}/run", TriggerJob(s))
+	return r
+}
+
+// GetJobStatus is the handler for listing scheduled jobs with their last and next runs
+func GetJobStatus(s *Scheduler) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		statuses, err := s.Status(r.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error getting scheduled jobs", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), statuses, w, http.StatusOK)
+	})
+}
+
+// TriggerJob is the handler for running a scheduled job outside of its schedule
+func TriggerJob(s *Scheduler) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, r *http.Request) *handlers.AppError {
+		name := chi.URLParam(r, "name")
+		if err := s.Trigger(name); err != nil {
+			if err == ErrUnknownJob {
+				return &handlers.AppError{
+					Message: "Job not found",
+					Code:    http.StatusNotFound,
+				}
+			}
+			return handlers.WrapError(err, "Error triggering job", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(r.Context(), map[string]string{"name": name, "status": "triggered"}, w, http.StatusAccepted)
+	})
+}
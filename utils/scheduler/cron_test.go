@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	start := time.Date(2021, time.March, 31, 10, 17, 30, 0, time.UTC)
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2021, time.March, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, time.March, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2021, time.April, 1, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, time.March, 31, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		// wednesday the 31st, the next monday is april 5th
+		{"30 9 * * 1", time.Date(2021, time.April, 5, 9, 30, 0, 0, time.UTC)},
+		{"0 0 30 * *", time.Date(2021, time.April, 30, 0, 0, 0, 0, time.UTC)},
+		// either restricted day field may match
+		{"0 0 15 * 5", time.Date(2021, time.April, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1-7 2,6 *", time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)},
+		{"@every 90s", start.Add(90 * time.Second)},
+	}
+	for _, c := range cases {
+		schedule, err := Parse(c.expr)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, c.next, schedule.Next(start), c.expr)
+	}
+
+	impossible, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, impossible.Next(start).IsZero(), "schedules which never match have no next run")
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 1ms", "@never"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
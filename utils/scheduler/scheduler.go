@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/metrics"
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrUnknownJob is returned when triggering a job which was not registered
+var ErrUnknownJob = errors.New("unknown job")
+
+// JobFunc is the work done by a scheduled job
+type JobFunc func(context.Context) error
+
+// Job is a unit of work run on a cron schedule
+type Job struct {
+	// Name identifies the job, it is also the key of its lock and status
+	Name string
+	// Schedule is a cron expression, see Parse
+	Schedule string
+	// Jitter delays each run by up to this long, spreading out jobs scheduled at the same time
+	Jitter time.Duration
+	// Family is the singleton job family the job belongs to, only the family's leader runs it
+	Family string
+	Func   JobFunc
+}
+
+// JobService - interface defining what can have scheduled jobs
+type JobService interface {
+	ScheduledJobs() []Job
+}
+
+// JobStatus is the run history of a job
+type JobStatus struct {
+	Name           string     `json:"name" db:"name"`
+	Schedule       string     `json:"schedule" db:"schedule"`
+	Family         string     `json:"family,omitempty" db:"-"`
+	LastRunAt      *time.Time `json:"lastRunAt" db:"last_run_at"`
+	LastDurationMS *int64     `json:"lastDurationMs" db:"last_duration_ms"`
+	LastError      *string    `json:"lastError" db:"last_error"`
+	NextRunAt      *time.Time `json:"nextRunAt" db:"next_run_at"`
+	Running        bool       `json:"running" db:"-"`
+}
+
+// Store locks jobs so only one instance runs each, and keeps their status
+type Store interface {
+	// TryLock takes the job's lock, returning false if another instance holds it. The returned
+	// func releases the lock
+	TryLock(ctx context.Context, name string) (func(), bool, error)
+	// GetStatus returns the status of a job, or nil if it has not been recorded
+	GetStatus(ctx context.Context, name string) (*JobStatus, error)
+	// SaveStatus records the status of a job
+	SaveStatus(ctx context.Context, status *JobStatus) error
+}
+
+// Leadership reports which job families this instance leads
+type Leadership interface {
+	IsLeader(family string) bool
+}
+
+type scheduledJob struct {
+	Job
+	schedule  Schedule
+	next      time.Time
+	triggered bool
+	running   bool
+}
+
+// Scheduler runs registered jobs when they are due
+type Scheduler struct {
+	store      Store
+	leadership Leadership
+	mu         sync.Mutex
+	jobs       map[string]*scheduledJob
+}
+
+// New creates a scheduler keeping job locks and status in the store
+func New(store Store) *Scheduler {
+	return &Scheduler{store: store, jobs: map[string]*scheduledJob{}}
+}
+
+// UseLeadership only runs jobs belonging to a family on the instance leading it, jobs without a
+// family are still run by whichever instance takes their lock first
+func (s *Scheduler) UseLeadership(leadership Leadership) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leadership = leadership
+}
+
+// Register adds jobs to the scheduler, whose first run is the next time their schedule matches
+func (s *Scheduler) Register(jobs ...Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		schedule, err := Parse(job.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule for %s: %w", job.Name, err)
+		}
+		if _, ok := s.jobs[job.Name]; ok {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+		sj := &scheduledJob{Job: job, schedule: schedule}
+		sj.next = sj.nextRun(time.Now())
+		s.jobs[job.Name] = sj
+	}
+	return nil
+}
+
+// nextRun returns when the job should next wake, including the jitter
+func (sj *scheduledJob) nextRun(now time.Time) time.Time {
+	next := sj.schedule.Next(now)
+	if sj.Jitter > 0 && !next.IsZero() {
+		next = next.Add(time.Duration(rand.Int63n(int64(sj.Jitter))))
+	}
+	return next
+}
+
+// Trigger runs the job on the next check regardless of its schedule
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	job.triggered = true
+	return nil
+}
+
+// due returns the jobs which should run now, in name order, marking them as running
+func (s *Scheduler) due(now time.Time) []*scheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []*scheduledJob{}
+	for _, job := range s.jobs {
+		if job.running {
+			continue
+		}
+		// followers leave a triggered job pending, to be run if they are elected
+		if job.Family != "" && s.leadership != nil && !s.leadership.IsLeader(job.Family) {
+			continue
+		}
+		if job.triggered || (!job.next.IsZero() && !now.Before(job.next)) {
+			job.running = true
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Name < due[j].Name })
+	return due
+}
+
+// RunDue runs each job which is due, it implements service.JobFunc so the scheduler can be driven by a job worker
+func (s *Scheduler) RunDue(ctx context.Context) (bool, error) {
+	var (
+		attempted bool
+		result    error
+	)
+	for _, job := range s.due(time.Now()) {
+		ran, err := s.run(ctx, job)
+		attempted = attempted || ran
+		if err != nil && result == nil {
+			result = err
+		}
+	}
+	return attempted, result
+}
+
+// run runs a due job while holding its lock, skipping it if another instance already ran it
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob) (bool, error) {
+	s.mu.Lock()
+	manual := job.triggered
+	job.triggered = false
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		job.running = false
+		job.next = job.nextRun(time.Now())
+		s.mu.Unlock()
+	}()
+
+	unlock, acquired, err := s.store.TryLock(ctx, job.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock job %s: %w", job.Name, err)
+	}
+	if !acquired {
+		// another instance is running the job
+		return false, nil
+	}
+	defer unlock()
+
+	status, err := s.store.GetStatus(ctx, job.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get status of job %s: %w", job.Name, err)
+	}
+	if status == nil {
+		status = &JobStatus{Name: job.Name}
+	}
+	// instances wake at slightly different times, the first to take the lock runs the scheduled slot
+	if !manual && status.LastRunAt != nil && job.schedule.Next(*status.LastRunAt).After(time.Now()) {
+		return false, nil
+	}
+
+	logger, lerr := appctx.GetLogger(ctx)
+	if lerr != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	started := time.Now()
+	jobErr := job.Func(ctx)
+	metrics.ObserveJob("scheduler", job.Name, started, jobErr)
+	duration := time.Since(started).Milliseconds()
+
+	status.Schedule = job.Schedule
+	status.LastRunAt = &started
+	status.LastDurationMS = &duration
+	status.LastError = nil
+	if jobErr != nil {
+		msg := jobErr.Error()
+		status.LastError = &msg
+		logger.Error().Err(jobErr).Str("job", job.Name).Msg("scheduled job failed")
+		sentry.CaptureException(jobErr)
+	}
+	if next := job.schedule.Next(time.Now()); !next.IsZero() {
+		status.NextRunAt = &next
+	}
+	if err := s.store.SaveStatus(ctx, status); err != nil {
+		return true, fmt.Errorf("failed to save status of job %s: %w", job.Name, err)
+	}
+	return true, nil
+}
+
+// Status returns the status of every registered job, in name order
+func (s *Scheduler) Status(ctx context.Context) ([]JobStatus, error) {
+	s.mu.Lock()
+	jobs := make([]scheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	s.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+	statuses := []JobStatus{}
+	for _, job := range jobs {
+		status, err := s.store.GetStatus(ctx, job.Name)
+		if err != nil {
+			return nil, err
+		}
+		if status == nil {
+			status = &JobStatus{Name: job.Name}
+		}
+		status.Schedule = job.Schedule
+		status.Family = job.Family
+		status.Running = job.running
+		if !job.next.IsZero() {
+			next := job.next
+			status.NextRunAt = &next
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
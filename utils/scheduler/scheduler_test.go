@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerRunDue(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	runs := 0
+	s := New(store)
+	require.NoError(t, s.Register(Job{
+		Name:     "sweep",
+		Schedule: "@every 1h",
+		Func: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+	}))
+	assert.Error(t, s.Register(Job{Name: "sweep", Schedule: "@daily"}), "names are unique")
+	assert.Error(t, s.Register(Job{Name: "invalid", Schedule: "daily"}))
+
+	// the job is not due until its schedule matches
+	attempted, err := s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.False(t, attempted)
+
+	assert.Equal(t, ErrUnknownJob, s.Trigger("missing"))
+	require.NoError(t, s.Trigger("sweep"))
+	attempted, err = s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.True(t, attempted)
+	assert.Equal(t, 1, runs)
+
+	status, err := store.GetStatus(ctx, "sweep")
+	require.NoError(t, err)
+	require.NotNil(t, status.LastRunAt)
+	assert.Nil(t, status.LastError)
+
+	// another instance waking for the same slot skips the run
+	other := New(store)
+	require.NoError(t, other.Register(Job{Name: "sweep", Schedule: "@every 1h", Func: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}))
+	other.jobs["sweep"].next = time.Now()
+	attempted, err = other.RunDue(ctx)
+	require.NoError(t, err)
+	assert.False(t, attempted)
+	assert.Equal(t, 1, runs)
+
+	// as does an instance which cannot take the lock
+	unlock, acquired, err := store.TryLock(ctx, "sweep")
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, s.Trigger("sweep"))
+	attempted, err = s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.False(t, attempted)
+	unlock()
+}
+
+func TestSchedulerRecordsFailures(t *testing.T) {
+	ctx := context.Background()
+	s := New(NewMemoryStore())
+	require.NoError(t, s.Register(Job{Name: "reconcile", Schedule: "@daily", Func: func(ctx context.Context) error {
+		return errors.New("upstream unavailable")
+	}}))
+	require.NoError(t, s.Trigger("reconcile"))
+	_, err := s.RunDue(ctx)
+	require.NoError(t, err, "job failures are recorded rather than returned")
+
+	statuses, err := s.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.NotNil(t, statuses[0].LastError)
+	assert.Equal(t, "upstream unavailable", *statuses[0].LastError)
+	assert.NotNil(t, statuses[0].NextRunAt)
+}
+
+type leadership map[string]bool
+
+func (l leadership) IsLeader(family string) bool {
+	return l[family]
+}
+
+func TestSchedulerLeadership(t *testing.T) {
+	ctx := context.Background()
+	leading := leadership{}
+	runs := 0
+	s := New(NewMemoryStore())
+	s.UseLeadership(leading)
+	require.NoError(t, s.Register(Job{Name: "export", Schedule: "@daily", Family: "exports", Func: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}))
+
+	// followers leave the job pending
+	require.NoError(t, s.Trigger("export"))
+	attempted, err := s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.False(t, attempted)
+	assert.True(t, s.jobs["export"].triggered)
+
+	// and run it once elected
+	leading["exports"] = true
+	attempted, err = s.RunDue(ctx)
+	require.NoError(t, err)
+	assert.True(t, attempted)
+	assert.Equal(t, 1, runs)
+
+	statuses, err := s.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "exports", statuses[0].Family)
+}
+
+func TestRouter(t *testing.T) {
+	s := New(NewMemoryStore())
+	require.NoError(t, s.Register(Job{Name: "sweep", Schedule: "0 3 * * *", Func: func(ctx context.Context) error { return nil }}))
+	router := Router(s)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"schedule":"0 3 * * *"`)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/sweep/run", nil))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.True(t, s.jobs["sweep"].triggered)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/missing/run", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore locks jobs with postgres advisory locks, which are released if the instance holding them dies,
+// and keeps their status in the scheduled_jobs table
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// TryLock takes the advisory lock for the job on a dedicated connection, as advisory locks are held by sessions
+func (s *PostgresStore) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('scheduled_job:' || $1))`, name).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	return func() {
+		// the lock is released with the session if unlocking fails
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('scheduled_job:' || $1))`, name)
+		_ = conn.Close()
+	}, true, nil
+}
+
+// GetStatus returns the status of a job, or nil if it has not been recorded
+func (s *PostgresStore) GetStatus(ctx context.Context, name string) (*JobStatus, error) {
+	var status JobStatus
+	err := s.db.GetContext(ctx, &status, `
+			SELECT name, schedule, last_run_at, last_duration_ms, last_error, next_run_at
+			FROM scheduled_jobs
+			WHERE name = $1
+		`, name)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled job status: %w", err)
+	}
+	return &status, nil
+}
+
+// SaveStatus records the status of a job
+func (s *PostgresStore) SaveStatus(ctx context.Context, status *JobStatus) error {
+	_, err := s.db.ExecContext(ctx, `
+			INSERT INTO scheduled_jobs (name, schedule, last_run_at, last_duration_ms, last_error, next_run_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (name) DO UPDATE SET
+				schedule = $2, last_run_at = $3, last_duration_ms = $4, last_error = $5, next_run_at = $6,
+				updated_at = current_timestamp
+		`, status.Name, status.Schedule, status.LastRunAt, status.LastDurationMS, status.LastError, status.NextRunAt)
+	if err != nil {
+		return fmt.Errorf("failed to save scheduled job status: %w", err)
+	}
+	return nil
+}
+
+// MemoryStore keeps job locks and status in memory, it does not coordinate across instances
+type MemoryStore struct {
+	mu       sync.Mutex
+	locked   map[string]bool
+	statuses map[string]JobStatus
+}
+
+// NewMemoryStore creates an empty in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{locked: map[string]bool{}, statuses: map[string]JobStatus{}}
+}
+
+// TryLock takes the job's lock unless it is already held
+func (s *MemoryStore) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked[name] {
+		return nil, false, nil
+	}
+	s.locked[name] = true
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.locked, name)
+	}, true, nil
+}
+
+// GetStatus returns the status of a job, or nil if it has not been recorded
+func (s *MemoryStore) GetStatus(ctx context.Context, name string) (*JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[name]
+	if !ok {
+		return nil, nil
+	}
+	return &status, nil
+}
+
+// SaveStatus records the status of a job
+func (s *MemoryStore) SaveStatus(ctx context.Context, status *JobStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[status.Name] = *status
+	return nil
+}
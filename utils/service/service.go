@@ -10,6 +10,9 @@ type JobFunc func(context.Context) (bool, error)
 
 // Job - Structure defining what a common job meta-information
 type Job struct {
+	// Name and Service label the job's metrics
+	Name    string
+	Service string
 	Func    JobFunc
 	Workers int
 	Cadence time.Duration
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout is how long in flight work is given to finish during shutdown
+const ShutdownTimeout = 25 * time.Second
+
+// WithShutdownSignals returns a context which is cancelled once the process is asked
+// to stop with SIGINT or SIGTERM
+func WithShutdownSignals(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// detachedContext carries the values of its parent but is never done
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// Detach returns a context with the values of ctx which is not cancelled along with it, so work started
+// before shutdown can finish once the shutdown signals cancel ctx
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// ListenAndServe runs the server until it is shut down, a closed server is not an error
+func ListenAndServe(server *http.Server) error {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the servers in order, each stops accepting new connections and
+// drains its in flight requests until the context is done
+func Shutdown(ctx context.Context, servers ...*http.Server) error {
+	var result error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// Wait waits for the group to finish until the context is done, returning false if it did not
+func Wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	})}
+	go func() { _ = server.Serve(listener) }()
+
+	statuses := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			statuses <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		statuses <- resp.StatusCode
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Shutdown(ctx, server); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+	if status := <-statuses; status != http.StatusCreated {
+		t.Fatalf("expected the in flight request to complete, got %d", status)
+	}
+	if err := ListenAndServe(server); err != nil {
+		t.Fatalf("a closed server should not be an error: %v", err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if Wait(ctx, &wg) {
+		t.Fatal("expected wait to give up at the deadline")
+	}
+
+	wg.Done()
+	if !Wait(context.Background(), &wg) {
+		t.Fatal("expected wait to return once the group finished")
+	}
+}
+
+type shutdownTestKey struct{}
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), shutdownTestKey{}, "value"))
+	detached := Detach(ctx)
+	cancel()
+
+	if detached.Err() != nil {
+		t.Fatal("expected the detached context not to be cancelled with its parent")
+	}
+	if detached.Value(shutdownTestKey{}) != "value" {
+		t.Fatal("expected the detached context to carry the values of its parent")
+	}
+}
@@ -0,0 +1,129 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+)
+
+// expireAfter is when containers are removed by docker should the tests die before stopping them
+const expireAfter = 600
+
+// Options are the dependencies to start
+type Options struct {
+	Postgres bool
+	Kafka    bool
+}
+
+// Environment is the dependencies the tests run against, either started in containers or, when already
+// configured by DATABASE_URL and KAFKA_BROKERS, those of the environment such as docker-compose's
+type Environment struct {
+	DatabaseURL  string
+	KafkaBrokers string
+
+	pool      *dockertest.Pool
+	network   *dockertest.Network
+	resources []*dockertest.Resource
+}
+
+// Root returns the root of the repository, where the migrations and test secrets are
+func Root() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..")
+}
+
+// Path returns the path of a fixture file bundled with the package
+func Path(name string) string {
+	return filepath.Join(Root(), "utils", "test", "fixtures", "data", name)
+}
+
+// Start the dependencies which are not configured by the environment, migrating the database it starts,
+// and set the environment variables the services read to connect to them
+func Start(ctx context.Context, opts Options) (*Environment, error) {
+	env := &Environment{
+		DatabaseURL:  os.Getenv("DATABASE_URL"),
+		KafkaBrokers: os.Getenv("KAFKA_BROKERS"),
+	}
+	if os.Getenv("DATABASE_MIGRATIONS_URL") == "" {
+		if err := os.Setenv("DATABASE_MIGRATIONS_URL", "file://"+filepath.Join(Root(), "migrations")); err != nil {
+			return nil, err
+		}
+	}
+
+	startPostgres := opts.Postgres && env.DatabaseURL == ""
+	startKafka := opts.Kafka && env.KafkaBrokers == ""
+	if !startPostgres && !startKafka {
+		return env, nil
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to docker: %w", err)
+	}
+	env.pool = pool
+
+	if startPostgres {
+		if err := env.startPostgres(ctx); err != nil {
+			_ = env.Close()
+			return nil, err
+		}
+	}
+	if startKafka {
+		if err := env.startKafka(ctx); err != nil {
+			_ = env.Close()
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// Require starts the dependencies for the test, failing it when they cannot be started, and stops them
+// once the test completes. Suites call it from SetupSuite with suite.T()
+func Require(t *testing.T, opts Options) *Environment {
+	env, err := Start(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("failed to start test dependencies: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Close(); err != nil {
+			t.Logf("failed to stop test dependencies: %v", err)
+		}
+	})
+	return env
+}
+
+// run a container, removed by Close
+func (env *Environment) run(opts *dockertest.RunOptions) (*dockertest.Resource, error) {
+	resource, err := env.pool.RunWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", opts.Repository, err)
+	}
+	env.resources = append(env.resources, resource)
+	if err := resource.Expire(expireAfter); err != nil {
+		return nil, fmt.Errorf("failed to expire %s: %w", opts.Repository, err)
+	}
+	return resource, nil
+}
+
+// Close stops the containers which were started
+func (env *Environment) Close() error {
+	var failed error
+	for i := len(env.resources) - 1; i >= 0; i-- {
+		if err := env.pool.Purge(env.resources[i]); err != nil && failed == nil {
+			failed = fmt.Errorf("failed to remove container: %w", err)
+		}
+	}
+	env.resources = nil
+	if env.network != nil {
+		if err := env.network.Close(); err != nil && failed == nil {
+			failed = fmt.Errorf("failed to remove network: %w", err)
+		}
+		env.network = nil
+	}
+	return failed
+}
@@ -0,0 +1,113 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	kafkautils "github.com/brave-intl/bat-go/utils/kafka"
+	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
+	uuid "github.com/satori/go.uuid"
+)
+
+// freePort returns a port of the host nothing listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startKafka starts the same zookeeper and kafka over TLS docker-compose runs, with the test secrets
+func (env *Environment) startKafka(ctx context.Context) error {
+	network, err := env.pool.CreateNetwork("bat-go-fixtures-" + uuid.NewV4().String())
+	if err != nil {
+		return fmt.Errorf("failed to create network: %w", err)
+	}
+	env.network = network
+
+	zookeeper, err := env.run(&dockertest.RunOptions{
+		Repository: "confluentinc/cp-zookeeper",
+		Tag:        "5.2.2",
+		Env:        []string{"ZOOKEEPER_CLIENT_PORT=2181"},
+		Networks:   []*dockertest.Network{network},
+	})
+	if err != nil {
+		return err
+	}
+
+	// kafka advertises the address clients connect to, so the host port is picked before it starts
+	port, err := freePort()
+	if err != nil {
+		return fmt.Errorf("failed to find a free port: %w", err)
+	}
+	secrets := filepath.Join(Root(), "test", "secrets")
+	listener := dc.Port(strconv.Itoa(port) + "/tcp")
+	_, err = env.run(&dockertest.RunOptions{
+		Repository: "confluentinc/cp-kafka",
+		Tag:        "5.2.2",
+		Hostname:   "kafka",
+		Env: []string{
+			"KAFKA_ZOOKEEPER_CONNECT=" + zookeeper.GetIPInNetwork(network) + ":2181",
+			"KAFKA_ADVERTISED_LISTENERS=SSL://kafka:19092,SSL2://localhost:" + strconv.Itoa(port),
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP=SSL:SSL,SSL2:SSL",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=1",
+			"KAFKA_SSL_KEYSTORE_FILENAME=kafka.broker1.keystore.jks",
+			"KAFKA_SSL_KEYSTORE_CREDENTIALS=broker1_keystore_creds",
+			"KAFKA_SSL_KEY_CREDENTIALS=broker1_sslkey_creds",
+			"KAFKA_SSL_TRUSTSTORE_FILENAME=kafka.broker1.truststore.jks",
+			"KAFKA_SSL_TRUSTSTORE_CREDENTIALS=broker1_truststore_creds",
+			"KAFKA_SSL_ENDPOINT_IDENTIFICATION_ALGORITHM= ",
+			"KAFKA_SSL_CLIENT_AUTH=requested",
+			"KAFKA_SECURITY_INTER_BROKER_PROTOCOL=SSL",
+		},
+		Mounts:       []string{secrets + ":/etc/kafka/secrets"},
+		Networks:     []*dockertest.Network{network},
+		ExposedPorts: []string{string(listener)},
+		PortBindings: map[dc.Port][]dc.PortBinding{
+			listener: {{HostIP: "127.0.0.1", HostPort: strconv.Itoa(port)}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	brokers := "localhost:" + strconv.Itoa(port)
+	for key, value := range map[string]string{
+		"KAFKA_BROKERS":                  brokers,
+		"KAFKA_SSL_CA_LOCATION":          filepath.Join(secrets, "snakeoil-ca-1.crt"),
+		"KAFKA_SSL_CERTIFICATE_LOCATION": filepath.Join(secrets, "consumer-ca1-signed.pem"),
+		"KAFKA_SSL_KEY_LOCATION":         filepath.Join(secrets, "consumer.client.key"),
+		"KAFKA_SSL_KEY_PASSWORD":         "confluent",
+		"KAFKA_REQUIRED_ACKS":            "1",
+	} {
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	dialer, _, err := kafkautils.TLSDialer()
+	if err != nil {
+		return fmt.Errorf("failed to create kafka dialer: %w", err)
+	}
+	err = env.pool.Retry(func() error {
+		conn, err := dialer.DialContext(ctx, "tcp", brokers)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.Brokers()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("kafka did not become ready: %w", err)
+	}
+	env.KafkaBrokers = brokers
+	return nil
+}
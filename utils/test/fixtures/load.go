@@ -0,0 +1,154 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v2"
+)
+
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Load inserts the rows of the fixture files in a single transaction. Each file maps table names to
+// their rows, each row mapping columns to values, and tables are inserted in the order of the file so
+// rows can reference those of earlier tables. Maps and lists are inserted as json, postgres arrays are
+// written as their '{a,b}' literal
+func Load(ctx context.Context, db *sqlx.DB, files ...string) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin fixture transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture: %w", err)
+		}
+		tables := yaml.MapSlice{}
+		if err := yaml.Unmarshal(data, &tables); err != nil {
+			return fmt.Errorf("failed to decode fixture %s: %w", filepath.Base(file), err)
+		}
+		for _, table := range tables {
+			if err := insert(ctx, tx, table); err != nil {
+				return fmt.Errorf("failed to load fixture %s: %w", filepath.Base(file), err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fixtures: %w", err)
+	}
+	return nil
+}
+
+// insert the rows of a table
+func insert(ctx context.Context, tx *sqlx.Tx, table yaml.MapItem) error {
+	name, ok := table.Key.(string)
+	if !ok || !identifier.MatchString(name) {
+		return fmt.Errorf("invalid table name %v", table.Key)
+	}
+	rows, ok := table.Value.([]interface{})
+	if !ok {
+		return fmt.Errorf("rows of %s must be a list", name)
+	}
+
+	for i, r := range rows {
+		items, ok := r.(yaml.MapSlice)
+		if !ok {
+			return fmt.Errorf("row %d of %s must be a map", i, name)
+		}
+		// columns are sorted so each row's statement is the same whatever the order of its keys
+		row := make(map[string]interface{}, len(items))
+		columns := make([]string, 0, len(items))
+		for _, item := range items {
+			column, ok := item.Key.(string)
+			if !ok || !identifier.MatchString(column) {
+				return fmt.Errorf("invalid column name %v of %s", item.Key, name)
+			}
+			row[column] = item.Value
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		quoted := make([]string, len(columns))
+		placeholders := make([]string, len(columns))
+		values := make([]interface{}, len(columns))
+		for j, column := range columns {
+			value, err := columnValue(row[column])
+			if err != nil {
+				return fmt.Errorf("invalid value of %s.%s: %w", name, column, err)
+			}
+			quoted[j] = pq.QuoteIdentifier(column)
+			placeholders[j] = fmt.Sprintf("$%d", j+1)
+			values[j] = value
+		}
+
+		statement := fmt.Sprintf("insert into %s (%s) values (%s)",
+			pq.QuoteIdentifier(name), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+		if _, err := tx.ExecContext(ctx, statement, values...); err != nil {
+			return fmt.Errorf("failed to insert row %d of %s: %w", i, name, err)
+		}
+	}
+	return nil
+}
+
+// columnValue converts a decoded yaml value to one the driver accepts
+func columnValue(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case yaml.MapSlice, []interface{}:
+		return json.Marshal(jsonValue(value))
+	}
+	return value, nil
+}
+
+// jsonValue converts the maps yaml decodes into ones json encodes
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			m[fmt.Sprint(item.Key)] = jsonValue(item.Value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = jsonValue(item)
+		}
+		return s
+	}
+	return value
+}
+
+// Truncate empties the tables, and those referencing them, between tests
+func Truncate(ctx context.Context, db *sqlx.DB, tables ...string) error {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		if !identifier.MatchString(table) {
+			return fmt.Errorf("invalid table name %s", table)
+		}
+		quoted[i] = pq.QuoteIdentifier(table)
+	}
+	if _, err := db.ExecContext(ctx, "truncate "+strings.Join(quoted, ", ")+" cascade"); err != nil {
+		return fmt.Errorf("failed to truncate tables: %w", err)
+	}
+	return nil
+}
+
+// Load inserts the fixture files into the environment's database
+func (env *Environment) Load(ctx context.Context, files ...string) error {
+	db, err := env.DB()
+	if err != nil {
+		return err
+	}
+	return Load(ctx, db, files...)
+}
@@ -0,0 +1,64 @@
+package fixtures
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(mockDB, "postgres")
+
+	mock.ExpectBegin()
+	for _, table := range []string{"promotions", "promotions", "issuers"} {
+		mock.ExpectExec(`insert into "` + table + `"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	for _, table := range []string{"merchants", "order_cred_issuers", "orders", "orders", "order_items", "order_items", "transactions"} {
+		mock.ExpectExec(`insert into "` + table + `"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	require.NoError(t, Load(context.Background(), db, Path("promotions.yml"), Path("orders.yml")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "events.yml")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`
+order_events:
+  - sequence: 1
+    order_id: 9b4c2e18-3a7d-4f0b-8e6c-5d1a2b3c4d01
+    data: {status: paid, items: [a, b]}
+`), 0600))
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(mockDB, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`insert into "order_events" ("data", "order_id", "sequence") values ($1, $2, $3)`)).
+		WithArgs([]byte(`{"items":["a","b"],"status":"paid"}`), "9b4c2e18-3a7d-4f0b-8e6c-5d1a2b3c4d01", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, Load(context.Background(), db, file))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	require.NoError(t, ioutil.WriteFile(file, []byte(`"orders; drop table orders": [{id: 1}]`), 0600))
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	assert.Error(t, Load(context.Background(), db, file), "table names are not taken from fixtures as is")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,66 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/dockertest/v3"
+
+	// the postgres driver
+	_ "github.com/lib/pq"
+)
+
+// startPostgres starts the same postgres docker-compose runs and migrates it
+func (env *Environment) startPostgres(ctx context.Context) error {
+	resource, err := env.run(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "10.4",
+		Env:        []string{"POSTGRES_USER=grants", "POSTGRES_PASSWORD=password"},
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("postgres://grants:password@%s/grants?sslmode=disable", resource.GetHostPort("5432/tcp"))
+	err = env.pool.Retry(func() error {
+		db, err := sqlx.Open("postgres", url)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.PingContext(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("postgres did not become ready: %w", err)
+	}
+
+	env.DatabaseURL = url
+	if err := os.Setenv("DATABASE_URL", url); err != nil {
+		return err
+	}
+	return env.Migrate()
+}
+
+// Migrate the database to the current migration
+func (env *Environment) Migrate() error {
+	pg, err := grantserver.NewPostgres(env.DatabaseURL, false, "")
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion); err != nil {
+		return fmt.Errorf("failed to migrate postgres: %w", err)
+	}
+	return nil
+}
+
+// DB connects to the database
+func (env *Environment) DB() (*sqlx.DB, error) {
+	pg, err := grantserver.NewPostgres(env.DatabaseURL, false, "")
+	if err != nil {
+		return nil, err
+	}
+	return pg.RawDB(), nil
+}
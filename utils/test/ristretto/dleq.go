@@ -0,0 +1,151 @@
+package ristretto
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// ErrInvalidBatchProof is returned when a batch proof does not prove the signed tokens were signed with
+// the issuer's key
+var ErrInvalidBatchProof = errors.New("ristretto: invalid batch proof")
+
+// dleqProof proves that log_G(Y) == log_P(Q), that the key the public key Y is derived from signed P as Q
+type dleqProof struct {
+	c, s *Scalar
+}
+
+func (proof *dleqProof) encode() string {
+	return base64.StdEncoding.EncodeToString(append(proof.c.Encode(), proof.s.Encode()...))
+}
+
+func decodeDLEQProof(encoded string) (*dleqProof, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(b) != 64 {
+		return nil, ErrInvalidBatchProof
+	}
+	c, err := DecodeScalar(b[:32])
+	if err != nil {
+		return nil, ErrInvalidBatchProof
+	}
+	s, err := DecodeScalar(b[32:])
+	if err != nil {
+		return nil, ErrInvalidBatchProof
+	}
+	return &dleqProof{c: c, s: s}, nil
+}
+
+// dleqChallenge hashes the statement and commitments of a proof to its challenge
+func dleqChallenge(points ...*Point) *Scalar {
+	h := sha512.New()
+	for _, p := range points {
+		_, _ = h.Write(p.Encode())
+	}
+	return ScalarFromUniformBytes(h.Sum(nil))
+}
+
+func (proof *dleqProof) verify(y, p, q *Point) bool {
+	a := Basepoint().Mul(proof.s).Add(y.Mul(proof.c))
+	b := p.Mul(proof.s).Add(q.Mul(proof.c))
+	return dleqChallenge(Basepoint(), y, p, q, a, b).Equal(proof.c)
+}
+
+// batchComposites combines the blinded and signed tokens into the single pair the batch proof is over,
+// weighted by scalars drawn from a ChaCha20 stream seeded with a hash of the whole batch
+func batchComposites(y *Point, blinded, signed []*Point) (*Point, *Point, error) {
+	if len(blinded) != len(signed) {
+		return nil, nil, fmt.Errorf("%d tokens were signed for %d blinded tokens: %w", len(signed), len(blinded), ErrInvalidBatchProof)
+	}
+	h := sha512.New()
+	_, _ = h.Write(Basepoint().Encode())
+	_, _ = h.Write(y.Encode())
+	for i := range blinded {
+		_, _ = h.Write(blinded[i].Encode())
+		_, _ = h.Write(signed[i].Encode())
+	}
+	stream, err := chacha20.NewUnauthenticatedCipher(h.Sum(nil)[:32], make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	weights := make([]*Scalar, len(blinded))
+	for i := range weights {
+		b := make([]byte, 64)
+		stream.XORKeyStream(b, b)
+		weights[i] = ScalarFromUniformBytes(b)
+	}
+	return MultiScalarMul(weights, blinded), MultiScalarMul(weights, signed), nil
+}
+
+// VerifyBatchProof checks the batch proof returned by the challenge bypass server with the signed tokens,
+// that each was signed from its blinded token with the key of the base64 encoded public key
+func VerifyBatchProof(publicKey string, blindedTokens []string, signedTokens []string, batchProof string) error {
+	y, err := decodePoint(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	proof, err := decodeDLEQProof(batchProof)
+	if err != nil {
+		return err
+	}
+	blinded, err := decodePoints(blindedTokens)
+	if err != nil {
+		return fmt.Errorf("invalid blinded token: %w", err)
+	}
+	signed, err := decodePoints(signedTokens)
+	if err != nil {
+		return fmt.Errorf("invalid signed token: %w", err)
+	}
+
+	m, z, err := batchComposites(y, blinded, signed)
+	if err != nil {
+		return err
+	}
+	if !proof.verify(y, m, z) {
+		return ErrInvalidBatchProof
+	}
+	return nil
+}
+
+// SignBatch signs base64 encoded blinded tokens, returning the signed tokens and their batch proof as the
+// challenge bypass server does
+func (key *SigningKey) SignBatch(blindedTokens []string) ([]string, string, error) {
+	blinded, err := decodePoints(blindedTokens)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid blinded token: %w", err)
+	}
+	signed := make([]*Point, len(blinded))
+	encoded := make([]string, len(blinded))
+	for i, p := range blinded {
+		signed[i] = p.Mul(key.k)
+		encoded[i] = base64.StdEncoding.EncodeToString(signed[i].Encode())
+	}
+
+	y := Basepoint().Mul(key.k)
+	m, z, err := batchComposites(y, blinded, signed)
+	if err != nil {
+		return nil, "", err
+	}
+	t, err := RandomScalar()
+	if err != nil {
+		return nil, "", err
+	}
+	c := dleqChallenge(Basepoint(), y, m, z, Basepoint().Mul(t), m.Mul(t))
+	proof := &dleqProof{c: c, s: t.Sub(c.Mul(key.k))}
+	return encoded, proof.encode(), nil
+}
+
+func decodePoints(encoded []string) ([]*Point, error) {
+	points := make([]*Point, len(encoded))
+	for i, e := range encoded {
+		p, err := decodePoint(e)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return points, nil
+}
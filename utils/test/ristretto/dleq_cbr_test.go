@@ -0,0 +1,44 @@
+// +build integration
+
+package ristretto
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brave-intl/bat-go/utils/clients/cbr"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVerifyBatchProofCBR checks proofs of batches signed by the challenge bypass server at
+// CHALLENGE_BYPASS_SERVER, whose composites the single token proof of TestVerifyBatchProof does not cover
+func TestVerifyBatchProofCBR(t *testing.T) {
+	ctx := context.Background()
+	client, err := cbr.New()
+	require.NoError(t, err)
+
+	issuer := "brave.com?sku=batch-proof-" + uuid.NewV4().String()
+	require.NoError(t, client.CreateIssuer(ctx, issuer, 100))
+	issuerResp, err := client.GetIssuer(ctx, issuer)
+	require.NoError(t, err)
+
+	blinded := make([]string, 5)
+	for i := range blinded {
+		token, err := NewToken()
+		require.NoError(t, err)
+		blinded[i], err = token.Blind()
+		require.NoError(t, err)
+	}
+	resp, err := client.SignCredentials(ctx, issuer, blinded)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyBatchProof(issuerResp.PublicKey, blinded, resp.SignedTokens, resp.BatchProof))
+
+	// the proof binds the order of the tokens
+	swapped := append([]string{}, resp.SignedTokens...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	err = VerifyBatchProof(issuerResp.PublicKey, blinded, swapped, resp.BatchProof)
+	assert.True(t, errors.Is(err, ErrInvalidBatchProof))
+}
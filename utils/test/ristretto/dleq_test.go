@@ -0,0 +1,67 @@
+package ristretto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBatchProof(t *testing.T) {
+	// signed by the challenge bypass server
+	publicKey := "dHuiBIasUO0khhXsWgygqpVasZhtQraDSZxzJW2FKQ4="
+	blinded := []string{"XhBPMjh4vMw+yoNjE7C5OtoTz2rCtfuOXO/Vk7UwWzY="}
+	signed := []string{"NJnOyyL6YAKMYo6kSAuvtG+/04zK1VNaD9KdKwuzAjU="}
+	proof := "IiKqfk10e7SJ54Ud/8FnCf+sLYQzS4WiVtYAM5+RVgApY6B9x4CVbMEngkDifEBRD6szEqnNlc3KA8wokGV5Cw=="
+	assert.NoError(t, VerifyBatchProof(publicKey, blinded, signed, proof))
+
+	key, err := NewSigningKey()
+	require.NoError(t, err)
+	assert.True(t, errors.Is(VerifyBatchProof(key.PublicKey(), blinded, signed, proof), ErrInvalidBatchProof),
+		"the proof is for the issuer's key")
+	assert.True(t, errors.Is(VerifyBatchProof(publicKey, blinded, signed, "bm90IGEgcHJvb2Y="), ErrInvalidBatchProof))
+}
+
+func TestSignBatch(t *testing.T) {
+	key, err := NewSigningKey()
+	require.NoError(t, err)
+	blinded := make([]string, 3)
+	for i := range blinded {
+		token, err := NewToken()
+		require.NoError(t, err)
+		blinded[i], err = token.Blind()
+		require.NoError(t, err)
+	}
+
+	signed, proof, err := key.SignBatch(blinded)
+	require.NoError(t, err)
+	require.Len(t, signed, 3)
+	assert.NoError(t, VerifyBatchProof(key.PublicKey(), blinded, signed, proof))
+
+	tampered := append([]string{}, signed...)
+	tampered[0], tampered[1] = signed[1], signed[0]
+	assert.True(t, errors.Is(VerifyBatchProof(key.PublicKey(), blinded, tampered, proof), ErrInvalidBatchProof),
+		"every token is bound to its blinded token")
+	tampered[0], tampered[1] = signed[0], blinded[1]
+	assert.True(t, errors.Is(VerifyBatchProof(key.PublicKey(), blinded, tampered, proof), ErrInvalidBatchProof))
+	assert.True(t, errors.Is(VerifyBatchProof(key.PublicKey(), blinded, signed[:2], proof), ErrInvalidBatchProof))
+	assert.Error(t, VerifyBatchProof(key.PublicKey(), blinded, []string{"bm90IGEgcG9pbnQ=", signed[1], signed[2]}, proof))
+}
+
+func TestMultiScalarMul(t *testing.T) {
+	scalars := make([]*Scalar, 5)
+	points := make([]*Point, 5)
+	expected := Identity()
+	for i := range scalars {
+		var err error
+		scalars[i], err = RandomScalar()
+		require.NoError(t, err)
+		k, err := RandomScalar()
+		require.NoError(t, err)
+		points[i] = Basepoint().Mul(k)
+		expected = expected.Add(points[i].Mul(scalars[i]))
+	}
+	assert.True(t, MultiScalarMul(scalars, points).Equal(expected))
+	assert.True(t, MultiScalarMul(nil, nil).Equal(Identity()))
+}
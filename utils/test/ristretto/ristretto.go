@@ -0,0 +1,335 @@
+// Package ristretto implements the ristretto255 prime order group and the privacy pass tokens the
+// challenge bypass server signs. It favours clarity over speed and is only meant for tests and developer
+// tooling, production code should use the challenge bypass ristretto library.
+package ristretto
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrInvalidEncoding is returned when decoding bytes which are not a canonical point encoding
+	ErrInvalidEncoding = errors.New("invalid ristretto255 encoding")
+
+	one   = big.NewInt(1)
+	two   = big.NewInt(2)
+	prime = new(big.Int).Sub(new(big.Int).Lsh(one, 255), big.NewInt(19))
+	// order is the order of the group, the scalar field modulus
+	order, _ = new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+
+	curveD = feMul(big.NewInt(-121665), feInv(big.NewInt(121666)))
+	sqrtM1 = new(big.Int).Exp(two, new(big.Int).Rsh(new(big.Int).Sub(prime, one), 2), prime)
+	// the specification fixes the negative root here, unlike the other constants
+	sqrtADMinusOne = feNeg(mustSqrt(feSub(feNeg(curveD), one)))
+	invSqrtAMinusD = feInv(mustSqrt(feSub(feNeg(one), curveD)))
+	oneMinusDSq    = feSub(one, feMul(curveD, curveD))
+	dMinusOneSq    = feMul(feSub(curveD, one), feSub(curveD, one))
+
+	basepoint = newBasepoint()
+)
+
+func fe(x *big.Int) *big.Int       { return x.Mod(x, prime) }
+func feAdd(a, b *big.Int) *big.Int { return fe(new(big.Int).Add(a, b)) }
+func feSub(a, b *big.Int) *big.Int { return fe(new(big.Int).Sub(a, b)) }
+func feMul(a, b *big.Int) *big.Int { return fe(new(big.Int).Mul(a, b)) }
+func feNeg(a *big.Int) *big.Int    { return fe(new(big.Int).Neg(a)) }
+func feInv(a *big.Int) *big.Int {
+	return new(big.Int).Exp(fe(new(big.Int).Set(a)), new(big.Int).Sub(prime, two), prime)
+}
+func feEqual(a, b *big.Int) bool   { return fe(new(big.Int).Set(a)).Cmp(fe(new(big.Int).Set(b))) == 0 }
+func isNegative(a *big.Int) bool   { return fe(new(big.Int).Set(a)).Bit(0) == 1 }
+func feAbs(a *big.Int) *big.Int    { return feCondNeg(a, isNegative(a)) }
+func feSquare(a *big.Int) *big.Int { return feMul(a, a) }
+func feCondNeg(a *big.Int, neg bool) *big.Int {
+	if neg {
+		return feNeg(a)
+	}
+	return fe(new(big.Int).Set(a))
+}
+
+// sqrtRatioM1 computes the non-negative square root of u/v or of i*u/v, reporting whether u/v is square
+func sqrtRatioM1(u, v *big.Int) (bool, *big.Int) {
+	v3 := feMul(feSquare(v), v)
+	v7 := feMul(feSquare(v3), v)
+	exp := new(big.Int).Rsh(new(big.Int).Sub(prime, big.NewInt(5)), 3)
+	r := feMul(feMul(u, v3), new(big.Int).Exp(feMul(u, v7), exp, prime))
+
+	check := feMul(v, feSquare(r))
+	correct := feEqual(check, u)
+	flipped := feEqual(check, feNeg(u))
+	flippedI := feEqual(check, feMul(feNeg(u), sqrtM1))
+	if flipped || flippedI {
+		r = feMul(r, sqrtM1)
+	}
+	return correct || flipped, feAbs(r)
+}
+
+func mustSqrt(x *big.Int) *big.Int {
+	ok, r := sqrtRatioM1(x, one)
+	if !ok {
+		panic("ristretto: constant is not square")
+	}
+	return r
+}
+
+// Point is an element of the group, held in extended edwards coordinates
+type Point struct {
+	x, y, z, t *big.Int
+}
+
+func newBasepoint() *Point {
+	y := feMul(big.NewInt(4), feInv(big.NewInt(5)))
+	yy := feSquare(y)
+	_, x := sqrtRatioM1(feSub(yy, one), feAdd(feMul(curveD, yy), one))
+	return &Point{x: x, y: y, z: big.NewInt(1), t: feMul(x, y)}
+}
+
+// Identity returns the identity element
+func Identity() *Point {
+	return &Point{x: big.NewInt(0), y: big.NewInt(1), z: big.NewInt(1), t: big.NewInt(0)}
+}
+
+// Basepoint returns the generator of the group
+func Basepoint() *Point {
+	return &Point{x: basepoint.x, y: basepoint.y, z: basepoint.z, t: basepoint.t}
+}
+
+// Add returns p + q
+func (p *Point) Add(q *Point) *Point {
+	a := feMul(feSub(p.y, p.x), feSub(q.y, q.x))
+	b := feMul(feAdd(p.y, p.x), feAdd(q.y, q.x))
+	c := feMul(feMul(p.t, feAdd(curveD, curveD)), q.t)
+	d := feMul(feAdd(p.z, p.z), q.z)
+	e, f, g, h := feSub(b, a), feSub(d, c), feAdd(d, c), feAdd(b, a)
+	return &Point{x: feMul(e, f), y: feMul(g, h), z: feMul(f, g), t: feMul(e, h)}
+}
+
+// Mul returns s * p
+func (p *Point) Mul(s *Scalar) *Point {
+	result := Identity()
+	for i := s.n.BitLen() - 1; i >= 0; i-- {
+		result = result.Add(result)
+		if s.n.Bit(i) == 1 {
+			result = result.Add(p)
+		}
+	}
+	return result
+}
+
+// MultiScalarMul returns the sum of scalars[i] * points[i], sharing the doublings between the terms
+func MultiScalarMul(scalars []*Scalar, points []*Point) *Point {
+	// bucket the terms by each window of the scalars, most significant first
+	const window = 4
+	bits := 0
+	for _, s := range scalars {
+		if s.n.BitLen() > bits {
+			bits = s.n.BitLen()
+		}
+	}
+	result := Identity()
+	for w := (bits + window - 1) / window; w >= 0; w-- {
+		for i := 0; i < window; i++ {
+			result = result.Add(result)
+		}
+		var buckets [1 << window]*Point
+		for i, s := range scalars {
+			digit := 0
+			for b := window - 1; b >= 0; b-- {
+				digit = digit<<1 | int(s.n.Bit(w*window+b))
+			}
+			if digit == 0 {
+				continue
+			}
+			if buckets[digit] == nil {
+				buckets[digit] = points[i]
+			} else {
+				buckets[digit] = buckets[digit].Add(points[i])
+			}
+		}
+		// sum of digit * bucket, as the running sum of the buckets from the highest digit down
+		running, sum := Identity(), Identity()
+		for digit := len(buckets) - 1; digit > 0; digit-- {
+			if buckets[digit] != nil {
+				running = running.Add(buckets[digit])
+			}
+			sum = sum.Add(running)
+		}
+		result = result.Add(sum)
+	}
+	return result
+}
+
+// Equal reports whether p and q are the same group element
+func (p *Point) Equal(q *Point) bool {
+	return feEqual(feMul(p.x, q.y), feMul(p.y, q.x)) || feEqual(feMul(p.y, q.y), feMul(p.x, q.x))
+}
+
+// Encode returns the canonical 32 byte encoding of the point
+func (p *Point) Encode() []byte {
+	u1 := feMul(feAdd(p.z, p.y), feSub(p.z, p.y))
+	u2 := feMul(p.x, p.y)
+	_, invsqrt := sqrtRatioM1(one, feMul(u1, feSquare(u2)))
+	den1 := feMul(invsqrt, u1)
+	den2 := feMul(invsqrt, u2)
+	zInv := feMul(feMul(den1, den2), p.t)
+
+	x, y, denInv := p.x, p.y, den2
+	if isNegative(feMul(p.t, zInv)) {
+		x, y, denInv = feMul(p.y, sqrtM1), feMul(p.x, sqrtM1), feMul(den1, invSqrtAMinusD)
+	}
+	y = feCondNeg(y, isNegative(feMul(x, zInv)))
+	return encodeFieldElement(feAbs(feMul(denInv, feSub(p.z, y))))
+}
+
+// Decode parses a canonical 32 byte point encoding
+func Decode(b []byte) (*Point, error) {
+	if len(b) != 32 {
+		return nil, ErrInvalidEncoding
+	}
+	s := decodeFieldElement(b)
+	if s.Cmp(prime) >= 0 || isNegative(s) {
+		return nil, ErrInvalidEncoding
+	}
+
+	ss := feSquare(s)
+	u1 := feSub(one, ss)
+	u2 := feAdd(one, ss)
+	u2Sq := feSquare(u2)
+	v := feSub(feNeg(feMul(curveD, feSquare(u1))), u2Sq)
+	wasSquare, invsqrt := sqrtRatioM1(one, feMul(v, u2Sq))
+	denX := feMul(invsqrt, u2)
+	denY := feMul(feMul(invsqrt, denX), v)
+
+	x := feAbs(feMul(feAdd(s, s), denX))
+	y := feMul(u1, denY)
+	t := feMul(x, y)
+	if !wasSquare || isNegative(t) || y.Sign() == 0 {
+		return nil, ErrInvalidEncoding
+	}
+	return &Point{x: x, y: y, z: big.NewInt(1), t: t}, nil
+}
+
+// FromUniformBytes maps 64 uniformly random bytes to a point
+func FromUniformBytes(b []byte) (*Point, error) {
+	if len(b) != 64 {
+		return nil, errors.New("ristretto: 64 bytes are required")
+	}
+	return elligator(b[:32]).Add(elligator(b[32:])), nil
+}
+
+// elligator maps a field element, the low 255 bits of b, to a point
+func elligator(b []byte) *Point {
+	masked := append([]byte{}, b...)
+	masked[31] &= 0x7f
+	t := decodeFieldElement(masked)
+
+	r := feMul(sqrtM1, feSquare(t))
+	u := feMul(feAdd(r, one), oneMinusDSq)
+	v := feMul(feSub(feNeg(one), feMul(r, curveD)), feAdd(r, curveD))
+	wasSquare, s := sqrtRatioM1(u, v)
+	c := feNeg(one)
+	if !wasSquare {
+		s = feNeg(feAbs(feMul(s, t)))
+		c = r
+	}
+	n := feSub(feMul(feMul(c, feSub(r, one)), dMinusOneSq), v)
+
+	w0 := feMul(feAdd(s, s), v)
+	w1 := feMul(n, sqrtADMinusOne)
+	w2 := feSub(one, feSquare(s))
+	w3 := feAdd(one, feSquare(s))
+	return &Point{x: feMul(w0, w3), y: feMul(w2, w1), z: feMul(w1, w3), t: feMul(w0, w2)}
+}
+
+// Scalar is an integer modulo the group order
+type Scalar struct {
+	n *big.Int
+}
+
+// RandomScalar returns a uniformly random non-zero scalar
+func RandomScalar() (*Scalar, error) {
+	for {
+		b := make([]byte, 64)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := ScalarFromUniformBytes(b)
+		if s.n.Sign() != 0 {
+			return s, nil
+		}
+	}
+}
+
+// ScalarFromUniformBytes reduces little endian bytes modulo the group order
+func ScalarFromUniformBytes(b []byte) *Scalar {
+	n := decodeLittleEndian(b)
+	return &Scalar{n: n.Mod(n, order)}
+}
+
+// DecodeScalar parses a canonical 32 byte little endian scalar
+func DecodeScalar(b []byte) (*Scalar, error) {
+	if len(b) != 32 {
+		return nil, errors.New("ristretto: invalid scalar length")
+	}
+	n := decodeLittleEndian(b)
+	if n.Cmp(order) >= 0 {
+		return nil, errors.New("ristretto: non canonical scalar")
+	}
+	return &Scalar{n: n}, nil
+}
+
+// Encode returns the 32 byte little endian encoding of the scalar
+func (s *Scalar) Encode() []byte {
+	return encodeFieldElement(s.n)
+}
+
+// Invert returns the multiplicative inverse of the scalar
+func (s *Scalar) Invert() *Scalar {
+	return &Scalar{n: new(big.Int).ModInverse(s.n, order)}
+}
+
+// Add returns s + t
+func (s *Scalar) Add(t *Scalar) *Scalar {
+	n := new(big.Int).Add(s.n, t.n)
+	return &Scalar{n: n.Mod(n, order)}
+}
+
+// Sub returns s - t
+func (s *Scalar) Sub(t *Scalar) *Scalar {
+	n := new(big.Int).Sub(s.n, t.n)
+	return &Scalar{n: n.Mod(n, order)}
+}
+
+// Mul returns s * t
+func (s *Scalar) Mul(t *Scalar) *Scalar {
+	n := new(big.Int).Mul(s.n, t.n)
+	return &Scalar{n: n.Mod(n, order)}
+}
+
+// Equal reports whether s and t are the same scalar
+func (s *Scalar) Equal(t *Scalar) bool {
+	return s.n.Cmp(t.n) == 0
+}
+
+func decodeLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func decodeFieldElement(b []byte) *big.Int {
+	return decodeLittleEndian(b)
+}
+
+func encodeFieldElement(x *big.Int) []byte {
+	be := x.Bytes()
+	out := make([]byte, 32)
+	for i := range be {
+		out[i] = be[len(be)-1-i]
+	}
+	return out
+}
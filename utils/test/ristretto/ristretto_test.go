@@ -0,0 +1,56 @@
+package ristretto
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasepointMultiples(t *testing.T) {
+	// from the ristretto255 test vectors, the encodings of 0, B, 2B and 3B
+	vectors := []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"e2f2ae0a6abc4e71a884a961c500515f58e30b6aa582dd8db6a65945e08d2d76",
+		"6a493210f7499cd17fecb510ae0cea23a110e8d5b901f8acadd3095c73a3b919",
+		"94741f5d5d52755ece4f23f044ee27d5d1ea1e2bd196b462166b16152a9d0259",
+	}
+	point := Identity()
+	for i, vector := range vectors {
+		assert.Equal(t, vector, hex.EncodeToString(point.Encode()), "encoding of %dB", i)
+
+		decoded, err := Decode(point.Encode())
+		require.NoError(t, err)
+		assert.True(t, decoded.Equal(point))
+		point = point.Add(Basepoint())
+	}
+
+	_, err := Decode(make([]byte, 31))
+	assert.Equal(t, ErrInvalidEncoding, err)
+	// the encoding of B with the sign bit set is not canonical
+	invalid, _ := hex.DecodeString("e2f2ae0a6abc4e71a884a961c500515f58e30b6aa582dd8db6a65945e08d2df6")
+	_, err = Decode(invalid)
+	assert.Equal(t, ErrInvalidEncoding, err)
+}
+
+func TestFromUniformBytes(t *testing.T) {
+	// from the ristretto255 hash to group test vectors
+	hash := sha512.Sum512([]byte("Ristretto is traditionally a short shot of espresso coffee"))
+	point, err := FromUniformBytes(hash[:])
+	require.NoError(t, err)
+	assert.Equal(t, "3066f82a1a747d45120d1740f14358531a8f04bbffe6a819f86dfe50f44a0a46", hex.EncodeToString(point.Encode()))
+}
+
+func TestScalarMul(t *testing.T) {
+	s, err := RandomScalar()
+	require.NoError(t, err)
+
+	p := Basepoint().Mul(s)
+	assert.True(t, p.Mul(s.Invert()).Equal(Basepoint()))
+
+	decoded, err := DecodeScalar(s.Encode())
+	require.NoError(t, err)
+	assert.True(t, Basepoint().Mul(decoded).Equal(p))
+}
@@ -0,0 +1,132 @@
+package ristretto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// TokenPreimageLength is the length of the random preimage a token is derived from
+const TokenPreimageLength = 64
+
+// Token is a random token preimage and the scalar it is blinded with before being sent to be signed
+type Token struct {
+	Preimage []byte
+	blind    *Scalar
+}
+
+// NewToken creates a token with a random preimage and blind
+func NewToken() (*Token, error) {
+	preimage := make([]byte, TokenPreimageLength)
+	if _, err := rand.Read(preimage); err != nil {
+		return nil, err
+	}
+	blind, err := RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return &Token{Preimage: preimage, blind: blind}, nil
+}
+
+// tokenPoint maps a token preimage to the point which is signed
+func tokenPoint(preimage []byte) (*Point, error) {
+	return FromUniformBytes(preimage)
+}
+
+// Blind returns the blinded token, base64 encoded as the challenge bypass server expects
+func (token *Token) Blind() (string, error) {
+	t, err := tokenPoint(token.Preimage)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(t.Mul(token.blind).Encode()), nil
+}
+
+// Unblind removes the blind from the signed token returned by the challenge bypass server. The
+// batch proof returned with the signed tokens is verified separately, see VerifyBatchProof
+func (token *Token) Unblind(signedToken string) (*UnblindedToken, error) {
+	signed, err := decodePoint(signedToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signed token: %w", err)
+	}
+	return &UnblindedToken{Preimage: token.Preimage, point: signed.Mul(token.blind.Invert())}, nil
+}
+
+// UnblindedToken is a token preimage and its signature, from which redemption signatures are derived
+type UnblindedToken struct {
+	Preimage []byte
+	point    *Point
+}
+
+// EncodedPreimage returns the base64 encoded token preimage
+func (token *UnblindedToken) EncodedPreimage() string {
+	return base64.StdEncoding.EncodeToString(token.Preimage)
+}
+
+// Sign returns the base64 encoded signature binding the token to the payload being redeemed
+func (token *UnblindedToken) Sign(payload string) string {
+	key := sha512.New()
+	_, _ = key.Write([]byte("hash_derive_key"))
+	_, _ = key.Write(token.Preimage)
+	_, _ = key.Write(token.point.Encode())
+
+	mac := hmac.New(sha512.New, key.Sum(nil))
+	_, _ = mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SigningKey is an issuer's secret key, standing in for the challenge bypass server in tests
+type SigningKey struct {
+	k *Scalar
+}
+
+// NewSigningKey creates a random signing key
+func NewSigningKey() (*SigningKey, error) {
+	k, err := RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{k: k}, nil
+}
+
+// PublicKey returns the base64 encoded public key of the issuer
+func (key *SigningKey) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(Basepoint().Mul(key.k).Encode())
+}
+
+// Sign signs a base64 encoded blinded token
+func (key *SigningKey) Sign(blindedToken string) (string, error) {
+	blinded, err := decodePoint(blindedToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid blinded token: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(blinded.Mul(key.k).Encode()), nil
+}
+
+// Verify checks the redemption signature of a base64 encoded token preimage over the payload
+func (key *SigningKey) Verify(preimage string, signature string, payload string) error {
+	decoded, err := base64.StdEncoding.DecodeString(preimage)
+	if err != nil || len(decoded) != TokenPreimageLength {
+		return errors.New("invalid token preimage")
+	}
+	t, err := tokenPoint(decoded)
+	if err != nil {
+		return err
+	}
+	expected := (&UnblindedToken{Preimage: decoded, point: t.Mul(key.k)}).Sign(payload)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("invalid redemption signature")
+	}
+	return nil
+}
+
+func decodePoint(encoded string) (*Point, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
@@ -0,0 +1,30 @@
+package ristretto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRedemption(t *testing.T) {
+	key, err := NewSigningKey()
+	require.NoError(t, err)
+	token, err := NewToken()
+	require.NoError(t, err)
+
+	blinded, err := token.Blind()
+	require.NoError(t, err)
+	signed, err := key.Sign(blinded)
+	require.NoError(t, err)
+	unblinded, err := token.Unblind(signed)
+	require.NoError(t, err)
+
+	signature := unblinded.Sign("payload")
+	assert.NoError(t, key.Verify(unblinded.EncodedPreimage(), signature, "payload"))
+	assert.Error(t, key.Verify(unblinded.EncodedPreimage(), signature, "other payload"))
+
+	other, err := NewSigningKey()
+	require.NoError(t, err)
+	assert.Error(t, other.Verify(unblinded.EncodedPreimage(), signature, "payload"))
+}
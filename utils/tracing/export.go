@@ -0,0 +1,279 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// queueSize bounds the spans waiting for export, spans are dropped rather than blocking requests
+	queueSize = 2048
+	// batchSize is the most spans sent in one export
+	batchSize = 512
+	// flushInterval is how long ended spans wait before being exported
+	flushInterval = 5 * time.Second
+)
+
+// exporter sends ended spans to a collector
+type exporter interface {
+	export(ctx context.Context, spans []*Span) error
+}
+
+// processor batches ended spans and exports them in the background
+type processor struct {
+	exporter exporter
+	queue    chan *Span
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	activeMu sync.RWMutex
+	active   *processor
+)
+
+func exporting() bool {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return active != nil
+}
+
+func enqueue(span *Span) {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	if active == nil {
+		return
+	}
+	select {
+	case active.queue <- span:
+	default:
+		// the collector is not keeping up, drop the span
+	}
+}
+
+// Init starts exporting spans over otlp/http to the collector in OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, or
+// OTEL_EXPORTER_OTLP_ENDPOINT. Without an endpoint trace context is still propagated but nothing is exported.
+// The returned func flushes the remaining spans and stops the exporter
+func Init(service string) func(context.Context) error {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		service = name
+	}
+
+	return start(&otlpExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	})
+}
+
+// start replaces the active exporter
+func start(e exporter) func(context.Context) error {
+	p := &processor{
+		exporter: e,
+		queue:    make(chan *Span, queueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	activeMu.Lock()
+	active = p
+	activeMu.Unlock()
+
+	go p.run()
+
+	return func(ctx context.Context) error {
+		activeMu.Lock()
+		if active == p {
+			active = nil
+		}
+		activeMu.Unlock()
+
+		close(p.stop)
+		select {
+		case <-p.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *processor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// a failed export is not retried, tracing must never hold up the service
+		_ = p.exporter.export(ctx, batch)
+		batch = make([]*Span, 0, batchSize)
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// otlpExporter posts spans to a collector using the otlp/http json encoding
+type otlpExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func attributes(values map[string]string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(values))
+	for k, v := range values {
+		attrs = append(attrs, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+// encode converts ended spans to an otlp export request
+func (e *otlpExporter) encode(spans []*Span) otlpRequest {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = "github.com/brave-intl/bat-go/utils/tracing"
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           span.context.TraceID.String(),
+			SpanID:            span.context.SpanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        attributes(span.attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if span.parent.IsValid() {
+			s.ParentSpanID = span.parent.String()
+		}
+		if span.err != nil {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.err.Error()}
+		}
+		span.mu.Unlock()
+		scope.Spans = append(scope.Spans, s)
+	}
+
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = attributes(map[string]string{"service.name": e.service})
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{resource}}
+}
+
+func (e *otlpExporter) export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to export spans: collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace across every service it passes through
+type TraceID [16]byte
+
+// String returns the hex encoding of the id
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns false for the all zero id
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the hex encoding of the id
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns false for the all zero id
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the part of a span which is propagated to other services
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns true if both ids are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship of a span to its parent, the values match otlp
+type SpanKind int
+
+const (
+	// SpanKindInternal is work within a service
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the handling of a request from another service
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is a request made to another service
+	SpanKindClient SpanKind = 3
+	// SpanKindProducer is a message sent to a queue
+	SpanKindProducer SpanKind = 4
+	// SpanKindConsumer is the handling of a message from a queue
+	SpanKindConsumer SpanKind = 5
+)
+
+// Span is a timed operation within a trace. A nil span is valid and does nothing, so callers never
+// need to check whether tracing is enabled
+type Span struct {
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	context    SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        error
+}
+
+// SpanOption configures a span when it is started
+type SpanOption func(*Span)
+
+// WithKind sets the kind of the span, spans are internal by default
+func WithKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.kind = kind
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// SpanFromContext returns the span in progress, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent sets the span context received from another service as the parent of
+// the next span started
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// parentFromContext returns the span context of the local span in progress, or else the remote parent
+func parentFromContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.context, true
+	}
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok
+}
+
+// StartSpan starts a span as a child of the span in the context, or as the root of a new trace if
+// there is none. The returned context carries the new span
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	span := &Span{
+		name:       name,
+		kind:       SpanKindInternal,
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(span)
+	}
+
+	if parent, ok := parentFromContext(ctx); ok {
+		span.context.TraceID = parent.TraceID
+		span.context.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.context.TraceID[:])
+		// new traces are only sampled when there is somewhere to export them
+		span.context.Sampled = exporting()
+	}
+	_, _ = rand.Read(span.context.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Context returns the span context to propagate to other services
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetName renames the span, for when the operation is only known after it has started
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttribute records a key value pair describing the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = fmt.Sprint(value)
+}
+
+// End finishes the span, marking it as failed if err is not nil, and queues it for export.
+// Ending a span more than once has no effect
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		enqueue(s)
+	}
+}
@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *memoryExporter) export(ctx context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	_, span := StartSpan(context.Background(), "test")
+	sc := span.Context()
+	sc.Sampled = true
+
+	parsed, err := ParseTraceparent(sc.Traceparent())
+	require.NoError(t, err)
+	assert.Equal(t, sc, parsed)
+
+	for _, invalid := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"00-zzf7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, invalid)
+	}
+
+	// later versions may carry more fields
+	_, err = ParseTraceparent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra")
+	assert.NoError(t, err)
+}
+
+func TestStartSpanContinuesTrace(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	ctx := Extract(context.Background(), header)
+	ctx, parent := StartSpan(ctx, "parent")
+	_, child := StartSpan(ctx, "child")
+
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", parent.Context().TraceID.String())
+	assert.Equal(t, "b7ad6b7169203331", parent.parent.String())
+	assert.True(t, parent.Context().Sampled, "the caller's sampling decision should be kept")
+
+	assert.Equal(t, parent.Context().TraceID, child.Context().TraceID)
+	assert.Equal(t, parent.Context().SpanID, child.parent)
+	assert.NotEqual(t, parent.Context().SpanID, child.Context().SpanID)
+
+	out := http.Header{}
+	Inject(ctx, out)
+	assert.Equal(t, parent.Context().Traceparent(), out.Get(TraceparentHeader))
+}
+
+func TestNilSpan(t *testing.T) {
+	var span *Span
+	span.SetName("name")
+	span.SetAttribute("key", "value")
+	span.End(nil)
+	assert.False(t, span.Context().IsValid())
+}
+
+func TestExport(t *testing.T) {
+	exporter := &memoryExporter{}
+	shutdown := start(exporter)
+
+	ctx, parent := StartSpan(context.Background(), "parent", WithKind(SpanKindServer))
+	assert.True(t, parent.Context().Sampled, "new traces should be sampled while exporting")
+	_, child := StartSpan(ctx, "child")
+	child.SetAttribute("count", 3)
+	child.End(errors.New("failed"))
+	child.End(nil)
+	parent.End(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, shutdown(ctx))
+
+	require.Len(t, exporter.spans, 2, "spans should be exported once when flushed on shutdown")
+
+	_, unsampled := StartSpan(context.Background(), "unsampled")
+	assert.False(t, unsampled.Context().Sampled, "new traces should not be sampled without an exporter")
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("content-type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer server.Close()
+
+	ctx, parent := StartSpan(context.Background(), "parent")
+	_, child := StartSpan(ctx, "child", WithKind(SpanKindClient))
+	child.SetAttribute("http.method", "GET")
+	child.End(errors.New("failed"))
+
+	exporter := &otlpExporter{endpoint: server.URL + "/v1/traces", service: "test", client: server.Client()}
+	require.NoError(t, exporter.export(context.Background(), []*Span{child}))
+
+	require.Len(t, body.ResourceSpans, 1)
+	assert.Equal(t, []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: "test"}}},
+		body.ResourceSpans[0].Resource.Attributes)
+	require.Len(t, body.ResourceSpans[0].ScopeSpans[0].Spans, 1)
+
+	span := body.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, child.Context().TraceID.String(), span.TraceID)
+	assert.Equal(t, parent.Context().SpanID.String(), span.ParentSpanID)
+	assert.Equal(t, SpanKindClient, span.Kind)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "failed"}, span.Status)
+	assert.Equal(t, []otlpAttribute{{Key: "http.method", Value: otlpValue{StringValue: "GET"}}}, span.Attributes)
+}
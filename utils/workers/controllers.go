@@ -0,0 +1,26 @@
+package workers
+
+import (
+	"net/http"
+
+	"github.com/brave-intl/bat-go/utils/handlers"
+	"github.com/go-chi/chi"
+)
+
+// Router lists the job workers of every instance with the job each is working on
+func Router(r *Registry) chi.Router {
+	router := chi.NewRouter()
+	router.Method("GET", "/", GetWorkers(r))
+	return router
+}
+
+// GetWorkers is the handler for listing workers, with their progress and whether they are stale
+func GetWorkers(r *Registry) handlers.AppHandler {
+	return handlers.AppHandler(func(w http.ResponseWriter, req *http.Request) *handlers.AppError {
+		statuses, err := r.List(req.Context())
+		if err != nil {
+			return handlers.WrapError(err, "Error listing workers", http.StatusInternalServerError)
+		}
+		return handlers.RenderContent(req.Context(), statuses, w, http.StatusOK)
+	})
+}
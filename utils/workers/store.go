@@ -0,0 +1,155 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresStore keeps the heartbeats in the workers table, shared by every instance
+type PostgresStore struct {
+	db *sqlx.DB
+}
+
+// NewPostgresStore creates a store backed by the database
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Heartbeat records the status of the worker
+func (s *PostgresStore) Heartbeat(ctx context.Context, status Status) error {
+	_, err := s.db.ExecContext(ctx, `
+			INSERT INTO workers (id, service, job, host, current_job, progress, claimed_at, backend_pid, started_at, heartbeat_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (id) DO UPDATE SET
+				current_job = $5, progress = $6, claimed_at = $7, backend_pid = $8, heartbeat_at = $10
+		`, status.ID, status.Service, status.Job, status.Host, status.CurrentJob, status.Progress,
+		status.ClaimedAt, status.BackendPID, status.StartedAt, status.HeartbeatAt)
+	if err != nil {
+		return fmt.Errorf("failed to record worker heartbeat: %w", err)
+	}
+	return nil
+}
+
+// Remove forgets a worker which stopped
+func (s *PostgresStore) Remove(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM workers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove worker: %w", err)
+	}
+	return nil
+}
+
+// List returns every worker, oldest first
+func (s *PostgresStore) List(ctx context.Context) ([]Status, error) {
+	statuses := []Status{}
+	err := s.db.SelectContext(ctx, &statuses, `
+			SELECT id, service, job, host, current_job, progress, claimed_at, backend_pid, started_at, heartbeat_at
+			FROM workers
+			ORDER BY started_at, id
+		`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	return statuses, nil
+}
+
+// Requeue forgets the worker and ends the session holding its job's lock, which rolls back the job's
+// transaction so its rows are picked up again. The session is only ended if it is still in the
+// transaction which took the job, as the worker may have finished since
+func (s *PostgresStore) Requeue(ctx context.Context, status Status) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current Status
+	err = tx.GetContext(ctx, &current, `
+			DELETE FROM workers WHERE id = $1 AND heartbeat_at = $2
+			RETURNING id, service, job, host, current_job, progress, claimed_at, backend_pid, started_at, heartbeat_at
+		`, status.ID, status.HeartbeatAt)
+	if err == sql.ErrNoRows {
+		// the worker heartbeat since, or another watchdog requeued it
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to remove stale worker: %w", err)
+	}
+
+	requeued := false
+	if current.BackendPID != nil && current.ClaimedAt != nil {
+		err = tx.GetContext(ctx, &requeued, `
+				SELECT coalesce(bool_or(pg_terminate_backend(pid)), false)
+				FROM pg_stat_activity
+				WHERE pid = $1 AND xact_start <= $2
+			`, *current.BackendPID, *current.ClaimedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to end the session of stale worker: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return requeued, nil
+}
+
+// MemoryStore keeps the heartbeats in memory, it does not coordinate across instances. Requeuing
+// only forgets the worker
+type MemoryStore struct {
+	mu       sync.Mutex
+	statuses map[string]Status
+}
+
+// NewMemoryStore creates an empty in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{statuses: map[string]Status{}}
+}
+
+// Heartbeat records the status of the worker
+func (s *MemoryStore) Heartbeat(ctx context.Context, status Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[status.ID] = status
+	return nil
+}
+
+// Remove forgets a worker which stopped
+func (s *MemoryStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.statuses, id)
+	return nil
+}
+
+// List returns every worker, oldest first
+func (s *MemoryStore) List(ctx context.Context) ([]Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := []Status{}
+	for _, status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].StartedAt.Equal(statuses[j].StartedAt) {
+			return statuses[i].ID < statuses[j].ID
+		}
+		return statuses[i].StartedAt.Before(statuses[j].StartedAt)
+	})
+	return statuses, nil
+}
+
+// Requeue forgets the worker, reporting its job as requeued if it had one
+func (s *MemoryStore) Requeue(ctx context.Context, status Status) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.statuses[status.ID]
+	if !ok || !current.HeartbeatAt.Equal(status.HeartbeatAt) {
+		return false, nil
+	}
+	delete(s.statuses, status.ID)
+	return current.CurrentJob != nil, nil
+}
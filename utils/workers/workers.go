@@ -0,0 +1,273 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	srv "github.com/brave-intl/bat-go/utils/service"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// HeartbeatInterval is how often a worker records its heartbeat, a worker taking or finishing a
+	// job records it straight away
+	HeartbeatInterval = 15 * time.Second
+	// DefaultStaleAfter is how long a worker goes without a heartbeat before its job is requeued, it is
+	// longer than the cadence of every job worker
+	DefaultStaleAfter = 2 * time.Minute
+)
+
+var staleCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "worker_stale_total",
+		Help: "Workers whose heartbeat went stale, by service, job and whether their job was requeued",
+	},
+	[]string{"service", "job", "requeued"},
+)
+
+func init() {
+	prometheus.MustRegister(staleCounter)
+}
+
+// Status is the heartbeat of a worker and the job it is working on
+type Status struct {
+	ID          string     `json:"id" db:"id"`
+	Service     string     `json:"service" db:"service"`
+	Job         string     `json:"job" db:"job"`
+	Host        string     `json:"host" db:"host"`
+	CurrentJob  *string    `json:"currentJob" db:"current_job"`
+	Progress    *string    `json:"progress" db:"progress"`
+	ClaimedAt   *time.Time `json:"claimedAt" db:"claimed_at"`
+	BackendPID  *int       `json:"-" db:"backend_pid"`
+	StartedAt   time.Time  `json:"startedAt" db:"started_at"`
+	HeartbeatAt time.Time  `json:"heartbeatAt" db:"heartbeat_at"`
+	Stale       bool       `json:"stale" db:"-"`
+}
+
+// Store keeps the heartbeats of every instance's workers
+type Store interface {
+	// Heartbeat records the status of the worker
+	Heartbeat(ctx context.Context, status Status) error
+	// Remove forgets a worker which stopped
+	Remove(ctx context.Context, id string) error
+	// List returns every worker, oldest first
+	List(ctx context.Context) ([]Status, error)
+	// Requeue releases the job of a stale worker so another worker picks it up, and forgets the worker.
+	// It returns whether the job was released
+	Requeue(ctx context.Context, status Status) (bool, error)
+}
+
+// Registry registers the job workers of this instance and watches for workers of any instance which
+// stopped heartbeating
+type Registry struct {
+	store      Store
+	host       string
+	staleAfter time.Duration
+	now        func() time.Time
+}
+
+// New creates a registry naming this instance's workers after the host
+func New(store Store, host string, staleAfter time.Duration) *Registry {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	return &Registry{store: store, host: host, staleAfter: staleAfter, now: time.Now}
+}
+
+// Worker is a job worker goroutine, it records its heartbeat and the job it is working on
+type Worker struct {
+	registry *Registry
+	mu       sync.Mutex
+	status   Status
+	lastBeat time.Time
+}
+
+// Register a worker of the service's job
+func (r *Registry) Register(service, job string) *Worker {
+	now := r.now()
+	return &Worker{
+		registry: r,
+		status: Status{
+			ID:        fmt.Sprintf("%s/%s/%s", r.host, job, uuid.New().String()[:8]),
+			Service:   service,
+			Job:       job,
+			Host:      r.host,
+			StartedAt: now,
+		},
+	}
+}
+
+// Beat records the worker's heartbeat if it has not recorded one for a while
+func (w *Worker) Beat(ctx context.Context) error {
+	w.mu.Lock()
+	due := w.registry.now().Sub(w.lastBeat) >= HeartbeatInterval
+	w.mu.Unlock()
+	if !due {
+		return nil
+	}
+	return w.beat(ctx)
+}
+
+func (w *Worker) beat(ctx context.Context) error {
+	w.mu.Lock()
+	now := w.registry.now()
+	w.status.HeartbeatAt = now
+	w.lastBeat = now
+	status := w.status
+	w.mu.Unlock()
+	return w.registry.store.Heartbeat(ctx, status)
+}
+
+// claim records the job the worker took, and the database session holding the job's lock if any
+func (w *Worker) claim(ctx context.Context, job string, pid *int) error {
+	w.mu.Lock()
+	now := w.registry.now()
+	w.status.CurrentJob = &job
+	w.status.Progress = nil
+	w.status.ClaimedAt = &now
+	w.status.BackendPID = pid
+	w.mu.Unlock()
+	return w.beat(ctx)
+}
+
+// progress records how far the worker is through its job, with the next heartbeat
+func (w *Worker) progress(ctx context.Context, progress string) error {
+	w.mu.Lock()
+	w.status.Progress = &progress
+	w.mu.Unlock()
+	return w.Beat(ctx)
+}
+
+// Release clears the worker's job once it is done, the job's lock is no longer held so the
+// worker's session must not be terminated
+func (w *Worker) Release(ctx context.Context) error {
+	w.mu.Lock()
+	if w.status.CurrentJob == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	w.status.CurrentJob = nil
+	w.status.Progress = nil
+	w.status.ClaimedAt = nil
+	w.status.BackendPID = nil
+	w.mu.Unlock()
+	return w.beat(ctx)
+}
+
+// Stop forgets the worker, once it has stopped running jobs
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.registry.store.Remove(ctx, w.status.ID)
+}
+
+type workerKey struct{}
+
+// WithWorker returns a context whose jobs report to the worker
+func WithWorker(ctx context.Context, w *Worker) context.Context {
+	return context.WithValue(ctx, workerKey{}, w)
+}
+
+func fromContext(ctx context.Context) *Worker {
+	w, _ := ctx.Value(workerKey{}).(*Worker)
+	return w
+}
+
+// Claim records the job taken by the context's worker. When the job is locked by a transaction, passing
+// it lets the watchdog requeue the job by ending the session should the worker get stuck. It does
+// nothing outside of a worker
+func Claim(ctx context.Context, tx sqlx.QueryerContext, job string) error {
+	w := fromContext(ctx)
+	if w == nil {
+		return nil
+	}
+	var pid *int
+	if tx != nil {
+		var backendPID int
+		if err := sqlx.GetContext(ctx, tx, &backendPID, `SELECT pg_backend_pid()`); err != nil {
+			return fmt.Errorf("failed to get backend of worker: %w", err)
+		}
+		pid = &backendPID
+	}
+	return w.claim(ctx, job, pid)
+}
+
+// Progress records how far the context's worker is through its job, which also keeps its heartbeat
+// fresh during long jobs. It does nothing outside of a worker
+func Progress(ctx context.Context, format string, args ...interface{}) error {
+	w := fromContext(ctx)
+	if w == nil {
+		return nil
+	}
+	return w.progress(ctx, fmt.Sprintf(format, args...))
+}
+
+// List returns every worker, marking those whose heartbeat is stale
+func (r *Registry) List(ctx context.Context) ([]Status, error) {
+	statuses, err := r.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := r.now().Add(-r.staleAfter)
+	for i := range statuses {
+		statuses[i].Stale = statuses[i].HeartbeatAt.Before(cutoff)
+	}
+	return statuses, nil
+}
+
+// Watch requeues the jobs of workers whose heartbeat is stale, it implements service.JobFunc so
+// the watchdog can be run by a job worker
+func (r *Registry) Watch(ctx context.Context) (bool, error) {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	statuses, err := r.List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list workers: %w", err)
+	}
+	attempted := false
+	for _, status := range statuses {
+		if !status.Stale {
+			continue
+		}
+		attempted = true
+		requeued, err := r.store.Requeue(ctx, status)
+		if err != nil {
+			return attempted, fmt.Errorf("failed to requeue the job of worker %s: %w", status.ID, err)
+		}
+		staleCounter.WithLabelValues(status.Service, status.Job, fmt.Sprint(requeued)).Inc()
+
+		log := logger.Warn().
+			Str("worker", status.ID).
+			Time("heartbeat_at", status.HeartbeatAt).
+			Bool("requeued", requeued)
+		if status.CurrentJob != nil {
+			log = log.Str("current_job", *status.CurrentJob)
+		}
+		log.Msg("worker heartbeat is stale")
+		if status.CurrentJob != nil {
+			sentry.CaptureMessage(fmt.Sprintf("worker %s stuck on %s", status.ID, *status.CurrentJob))
+		}
+	}
+	return attempted, nil
+}
+
+// Jobs checks for stale workers every thirty seconds
+func (r *Registry) Jobs() []srv.Job {
+	return []srv.Job{
+		{
+			Name:    "worker_watchdog",
+			Service: "grant",
+			Func:    r.Watch,
+			Cadence: 30 * time.Second,
+			Workers: 1,
+		},
+	}
+}
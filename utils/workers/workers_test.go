@@ -0,0 +1,116 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerHeartbeat(t *testing.T) {
+	now := time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	registry := New(store, "host", time.Minute)
+	registry.now = func() time.Time { return now }
+
+	worker := registry.Register("payment", "order")
+	ctx := WithWorker(context.Background(), worker)
+	require.NoError(t, worker.Beat(ctx))
+
+	// idle heartbeats are only recorded every interval
+	now = now.Add(time.Second)
+	require.NoError(t, worker.Beat(ctx))
+	statuses, err := registry.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, now.Add(-time.Second), statuses[0].HeartbeatAt)
+	assert.Nil(t, statuses[0].CurrentJob)
+
+	// taking a job is recorded straight away
+	require.NoError(t, Claim(ctx, nil, "order 1"))
+	statuses, err = registry.List(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, statuses[0].CurrentJob)
+	assert.Equal(t, "order 1", *statuses[0].CurrentJob)
+	assert.Equal(t, now, statuses[0].HeartbeatAt)
+
+	// while progress is recorded with the next heartbeat
+	require.NoError(t, Progress(ctx, "%d/%d", 1, 2))
+	now = now.Add(HeartbeatInterval)
+	require.NoError(t, Progress(ctx, "%d/%d", 2, 2))
+	statuses, err = registry.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2/2", *statuses[0].Progress)
+	assert.Equal(t, now, statuses[0].HeartbeatAt)
+
+	require.NoError(t, worker.Release(ctx))
+	statuses, err = registry.List(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, statuses[0].CurrentJob)
+
+	require.NoError(t, worker.Stop(ctx))
+	statuses, err = registry.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+
+	// jobs outside of a worker do not report
+	assert.NoError(t, Claim(context.Background(), nil, "order 2"))
+}
+
+func TestWatch(t *testing.T) {
+	now := time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	registry := New(store, "host", time.Minute)
+	registry.now = func() time.Time { return now }
+
+	stuck := registry.Register("promotion", "drain")
+	require.NoError(t, Claim(WithWorker(context.Background(), stuck), nil, "claim drain 1"))
+	idle := registry.Register("payment", "vote_drain")
+	require.NoError(t, idle.Beat(context.Background()))
+
+	attempted, err := registry.Watch(context.Background())
+	require.NoError(t, err)
+	assert.False(t, attempted, "fresh heartbeats are left alone")
+
+	now = now.Add(45 * time.Second)
+	require.NoError(t, idle.Beat(context.Background()))
+	now = now.Add(30 * time.Second)
+
+	statuses, err := registry.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Stale)
+	assert.False(t, statuses[1].Stale)
+
+	attempted, err = registry.Watch(context.Background())
+	require.NoError(t, err)
+	assert.True(t, attempted)
+	statuses, err = registry.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1, "the stuck worker is forgotten once its job is requeued")
+	assert.Equal(t, idle.status.ID, statuses[0].ID)
+}
+
+func TestRouter(t *testing.T) {
+	registry := New(NewMemoryStore(), "host", time.Minute)
+	worker := registry.Register("payment", "order")
+	require.NoError(t, Claim(WithWorker(context.Background(), worker), nil, "order 1"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	Router(registry).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var statuses []Status
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "order", statuses[0].Job)
+	assert.Equal(t, "order 1", *statuses[0].CurrentJob)
+	assert.NotContains(t, rr.Body.String(), "backend")
+}
@@ -0,0 +1,225 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brave-intl/bat-go/utils/altcurrency"
+	appctx "github.com/brave-intl/bat-go/utils/context"
+	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/s3"
+	"github.com/brave-intl/bat-go/utils/scheduler"
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
+	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+)
+
+const balanceAuditPageSize = 500
+
+var (
+	balanceAuditDiscrepancies = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "wallet_balance_audit_discrepancies",
+			Help:        "Number of wallets whose custodian balance differs from the ledger in the last audit",
+			ConstLabels: prometheus.Labels{"service": "wallet"},
+		})
+)
+
+func init() {
+	prometheus.MustRegister(balanceAuditDiscrepancies)
+}
+
+// LedgerBalances are the balances, in BAT, the ledger expects wallets to hold, keyed by payment id
+type LedgerBalances map[string]decimal.Decimal
+
+// ParseLedgerBalances parses a ledger balance export, a list of payment ids and balances
+func ParseLedgerBalances(data []byte) (LedgerBalances, error) {
+	var entries []struct {
+		PaymentID string          `json:"paymentId"`
+		Balance   decimal.Decimal `json:"balance"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger balances: %w", err)
+	}
+	ledger := make(LedgerBalances, len(entries))
+	for _, entry := range entries {
+		ledger[entry.PaymentID] = ledger[entry.PaymentID].Add(entry.Balance)
+	}
+	return ledger, nil
+}
+
+// BalanceAuditOptions controls which wallets are audited
+type BalanceAuditOptions struct {
+	// Sample audits a random sample of this many wallets, all wallets are audited when zero
+	Sample int
+	// Tolerance is the difference, in BAT, below which balances are considered matching
+	Tolerance decimal.Decimal
+	// Ledger holds the expected balances, wallets missing from it are expected to be empty
+	Ledger LedgerBalances
+}
+
+// BalanceDiscrepancy is a wallet whose custodian balance differs from the ledger
+type BalanceDiscrepancy struct {
+	PaymentID        string          `json:"paymentId"`
+	ProviderID       string          `json:"providerId"`
+	CustodianBalance decimal.Decimal `json:"custodianBalance"`
+	LedgerBalance    decimal.Decimal `json:"ledgerBalance"`
+	Difference       decimal.Decimal `json:"difference"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// BalanceAuditReport lists the discrepancies found by a balance audit
+type BalanceAuditReport struct {
+	StartedAt     time.Time            `json:"startedAt"`
+	CompletedAt   time.Time            `json:"completedAt"`
+	Audited       int                  `json:"audited"`
+	Failed        int                  `json:"failed"`
+	Discrepancies []BalanceDiscrepancy `json:"discrepancies"`
+}
+
+// custodianBalanceFunc fetches the balance, in BAT, the custodian holds for the wallet
+type custodianBalanceFunc func(ctx context.Context, info walletutils.Info) (decimal.Decimal, error)
+
+// upholdBalance fetches the balance of the wallet's uphold card
+func upholdBalance(ctx context.Context, info walletutils.Info) (decimal.Decimal, error) {
+	uwallet := uphold.Wallet{Info: info}
+	balance, err := uwallet.GetBalance(true)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return altcurrency.BAT.FromProbi(balance.TotalProbi), nil
+}
+
+// AuditBalances compares the custodian balance of uphold wallets against the ledger
+func (service *Service) AuditBalances(ctx context.Context, options BalanceAuditOptions) (*BalanceAuditReport, error) {
+	report := &BalanceAuditReport{
+		StartedAt:     time.Now().UTC(),
+		Discrepancies: []BalanceDiscrepancy{},
+	}
+	datastore := service.ReadableDatastore()
+
+	if options.Sample > 0 {
+		wallets, err := datastore.SampleUpholdWallets(ctx, options.Sample)
+		if err != nil {
+			return nil, err
+		}
+		auditBalances(ctx, report, wallets, options, upholdBalance)
+	} else {
+		after := uuid.Nil
+		for {
+			wallets, err := datastore.ListUpholdWallets(ctx, after, balanceAuditPageSize)
+			if err != nil {
+				return nil, err
+			}
+			auditBalances(ctx, report, wallets, options, upholdBalance)
+			if len(wallets) < balanceAuditPageSize {
+				break
+			}
+			after = uuid.Must(uuid.FromString(wallets[len(wallets)-1].ID))
+		}
+	}
+
+	report.CompletedAt = time.Now().UTC()
+	balanceAuditDiscrepancies.Set(float64(len(report.Discrepancies)))
+	return report, nil
+}
+
+// auditBalances adds the wallets whose custodian balance differs from the ledger to the report
+func auditBalances(
+	ctx context.Context,
+	report *BalanceAuditReport,
+	wallets []walletutils.Info,
+	options BalanceAuditOptions,
+	fetch custodianBalanceFunc,
+) {
+	logger, err := appctx.GetLogger(ctx)
+	if err != nil {
+		_, logger = logging.SetupLogger(ctx)
+	}
+
+	for _, info := range wallets {
+		if ctx.Err() != nil {
+			return
+		}
+		report.Audited++
+		expected := options.Ledger[info.ID]
+
+		balance, err := fetch(ctx, info)
+		if err != nil {
+			logger.Warn().Err(err).Str("id", info.ID).Msg("failed to fetch custodian balance")
+			report.Failed++
+			report.Discrepancies = append(report.Discrepancies, BalanceDiscrepancy{
+				PaymentID:     info.ID,
+				ProviderID:    info.ProviderID,
+				LedgerBalance: expected,
+				Error:         err.Error(),
+			})
+			continue
+		}
+
+		difference := balance.Sub(expected)
+		if difference.Abs().GreaterThan(options.Tolerance) {
+			report.Discrepancies = append(report.Discrepancies, BalanceDiscrepancy{
+				PaymentID:        info.ID,
+				ProviderID:       info.ProviderID,
+				CustodianBalance: balance,
+				LedgerBalance:    expected,
+				Difference:       difference,
+			})
+		}
+	}
+}
+
+// ScheduledJobs - Implement scheduler.JobService interface. The balance audit is scheduled when
+// WALLET_BALANCE_AUDIT_SCHEDULE is set
+func (service *Service) ScheduledJobs() []scheduler.Job {
+	schedule := os.Getenv("WALLET_BALANCE_AUDIT_SCHEDULE")
+	if schedule == "" {
+		return nil
+	}
+	return []scheduler.Job{
+		{
+			Name:     "wallet-balance-audit",
+			Schedule: schedule,
+			Jitter:   10 * time.Minute,
+			Func:     service.RunScheduledBalanceAudit,
+		},
+	}
+}
+
+// RunScheduledBalanceAudit audits against the ledger export at WALLET_BALANCE_AUDIT_LEDGER, writing
+// the report under the WALLET_BALANCE_AUDIT_REPORT prefix. Both may be s3 uris
+func (service *Service) RunScheduledBalanceAudit(ctx context.Context) error {
+	data, err := s3.ReadLocation(ctx, os.Getenv("WALLET_BALANCE_AUDIT_LEDGER"))
+	if err != nil {
+		return fmt.Errorf("failed to read ledger balances: %w", err)
+	}
+	ledger, err := ParseLedgerBalances(data)
+	if err != nil {
+		return err
+	}
+
+	options := BalanceAuditOptions{Ledger: ledger}
+	if sample := os.Getenv("WALLET_BALANCE_AUDIT_SAMPLE"); sample != "" {
+		if options.Sample, err = strconv.Atoi(sample); err != nil {
+			return fmt.Errorf("invalid WALLET_BALANCE_AUDIT_SAMPLE: %w", err)
+		}
+	}
+
+	report, err := service.AuditBalances(ctx, options)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal balance audit report: %w", err)
+	}
+	location := os.Getenv("WALLET_BALANCE_AUDIT_REPORT") + report.StartedAt.Format("2006-01-02T15-04-05") + ".json"
+	return s3.WriteLocation(ctx, location, out, "application/json")
+}
@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	walletutils "github.com/brave-intl/bat-go/utils/wallet"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLedgerBalances(t *testing.T) {
+	ledger, err := ParseLedgerBalances([]byte(`[
+		{"paymentId": "a", "balance": "1.5"},
+		{"paymentId": "b", "balance": "2"},
+		{"paymentId": "a", "balance": "0.5"}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, "2", ledger["a"].String(), "entries for the same wallet should be summed")
+	assert.Equal(t, "2", ledger["b"].String())
+
+	_, err = ParseLedgerBalances([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestAuditBalances(t *testing.T) {
+	custodian := map[string]decimal.Decimal{
+		"matching":   decimal.NewFromFloat(10),
+		"within":     decimal.NewFromFloat(5.001),
+		"short":      decimal.NewFromFloat(3),
+		"unledgered": decimal.NewFromFloat(1),
+	}
+	fetch := func(ctx context.Context, info walletutils.Info) (decimal.Decimal, error) {
+		balance, ok := custodian[info.ID]
+		if !ok {
+			return decimal.Zero, errors.New("card not found")
+		}
+		return balance, nil
+	}
+	wallets := []walletutils.Info{{ID: "matching"}, {ID: "within"}, {ID: "short"}, {ID: "unledgered"}, {ID: "missing"}}
+	options := BalanceAuditOptions{
+		Tolerance: decimal.NewFromFloat(0.01),
+		Ledger: LedgerBalances{
+			"matching": decimal.NewFromFloat(10),
+			"within":   decimal.NewFromFloat(5),
+			"short":    decimal.NewFromFloat(4),
+		},
+	}
+
+	report := &BalanceAuditReport{}
+	auditBalances(context.Background(), report, wallets, options, fetch)
+
+	assert.Equal(t, 5, report.Audited)
+	assert.Equal(t, 1, report.Failed)
+	require.Len(t, report.Discrepancies, 3)
+
+	assert.Equal(t, "short", report.Discrepancies[0].PaymentID)
+	assert.Equal(t, "-1", report.Discrepancies[0].Difference.String())
+	assert.Equal(t, "unledgered", report.Discrepancies[1].PaymentID, "wallets missing from the ledger should be empty")
+	assert.Equal(t, "1", report.Discrepancies[1].Difference.String())
+	assert.Equal(t, "missing", report.Discrepancies[2].PaymentID)
+	assert.Equal(t, "card not found", report.Discrepancies[2].Error)
+}
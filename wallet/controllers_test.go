@@ -14,6 +14,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/altcurrency"
 	appctx "github.com/brave-intl/bat-go/utils/context"
@@ -51,7 +52,7 @@ func (suite *WalletControllersTestSuite) SetupSuite() {
 		suite.Require().NoError(m.Down(), "Failed to migrate down cleanly")
 	}
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 }
 
 func (suite *WalletControllersTestSuite) SetupTest() {
@@ -54,14 +54,10 @@ func CreateUpholdWalletV3(w http.ResponseWriter, r *http.Request) *handlers.AppE
 			"failed to create wallet", http.StatusBadRequest)
 	}
 
-	var (
-		db Datastore
-		ok bool
-	)
-
 	// get datastore from context
-	if db, ok = ctx.Value(appctx.DatastoreCTXKey).(Datastore); !ok {
-		logger.Error().Msg("unable to get datastore from context")
+	var db Datastore
+	if err := appctx.Datastore(ctx, &db); err != nil {
+		logger.Error().Err(err).Msg("unable to get datastore from context")
 		return handlers.WrapError(err, "misconfigured datastore", http.StatusServiceUnavailable)
 	}
 
@@ -118,14 +114,10 @@ func CreateBraveWalletV3(w http.ResponseWriter, r *http.Request) *handlers.AppEr
 		return bcr.HandleErrors(err)
 	}
 
-	var (
-		db Datastore
-		ok bool
-	)
-
 	// get datastore from context
-	if db, ok = ctx.Value(appctx.DatastoreCTXKey).(Datastore); !ok {
-		logger.Error().Msg("unable to get datastore from context")
+	var db Datastore
+	if err := appctx.Datastore(ctx, &db); err != nil {
+		logger.Error().Err(err).Msg("unable to get datastore from context")
 		return handlers.WrapError(err, "misconfigured datastore", http.StatusServiceUnavailable)
 	}
 
@@ -363,14 +355,11 @@ func GetWalletV3(w http.ResponseWriter, r *http.Request) *handlers.AppError {
 		)
 	}
 
-	var (
-		roDB ReadOnlyDatastore
-		ok   bool
-	)
-
 	// get datastore from context
-	if roDB, ok = ctx.Value(appctx.RODatastoreCTXKey).(ReadOnlyDatastore); !ok {
-		logger.Error().Msg("unable to get read only datastore from context")
+	var roDB ReadOnlyDatastore
+	if err := appctx.ReadOnlyDatastore(ctx, &roDB); err != nil {
+		logger.Error().Err(err).Msg("unable to get read only datastore from context")
+		return handlers.WrapError(err, "misconfigured datastore", http.StatusServiceUnavailable)
 	}
 
 	// get wallet from datastore
@@ -413,14 +402,11 @@ func RecoverWalletV3(w http.ResponseWriter, r *http.Request) *handlers.AppError
 		)
 	}
 
-	var (
-		roDB ReadOnlyDatastore
-		ok   bool
-	)
-
 	// get datastore from context
-	if roDB, ok = ctx.Value(appctx.RODatastoreCTXKey).(ReadOnlyDatastore); !ok {
-		logger.Error().Msg("unable to get read only datastore from context")
+	var roDB ReadOnlyDatastore
+	if err := appctx.ReadOnlyDatastore(ctx, &roDB); err != nil {
+		logger.Error().Err(err).Msg("unable to get read only datastore from context")
+		return handlers.WrapError(err, "misconfigured datastore", http.StatusServiceUnavailable)
 	}
 
 	// get wallet from datastore
@@ -463,14 +449,11 @@ func GetUpholdWalletBalanceV3(w http.ResponseWriter, r *http.Request) *handlers.
 		)
 	}
 
-	var (
-		roDB ReadOnlyDatastore
-		ok   bool
-	)
-
 	// get datastore from context
-	if roDB, ok = ctx.Value(appctx.RODatastoreCTXKey).(ReadOnlyDatastore); !ok {
-		logger.Error().Msg("unable to get read only datastore from context")
+	var roDB ReadOnlyDatastore
+	if err := appctx.ReadOnlyDatastore(ctx, &roDB); err != nil {
+		logger.Error().Err(err).Msg("unable to get read only datastore from context")
+		return handlers.WrapError(err, "misconfigured datastore", http.StatusServiceUnavailable)
 	}
 
 	// get wallet from datastore
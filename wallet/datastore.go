@@ -67,6 +67,10 @@ type Datastore interface {
 	GetCustodianLinkByWalletID(ctx context.Context, ID uuid.UUID) (*CustodianLink, error)
 	// GetCustodianLinkCount - get the wallet custodian link count across all wallets
 	GetCustodianLinkCount(ctx context.Context, linkingID uuid.UUID) (int, int, error)
+	// ListUpholdWallets - list uphold wallets holding a card, in id order, starting after the given id
+	ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) ([]walletutils.Info, error)
+	// SampleUpholdWallets - get a random sample of uphold wallets holding a card
+	SampleUpholdWallets(ctx context.Context, limit int) ([]walletutils.Info, error)
 }
 
 // ReadOnlyDatastore includes all database methods that can be made with a read only db connection
@@ -82,6 +86,10 @@ type ReadOnlyDatastore interface {
 	GetCustodianLinkByWalletID(ctx context.Context, ID uuid.UUID) (*CustodianLink, error)
 	// GetCustodianLinkCount - get the wallet custodian link count across all wallets
 	GetCustodianLinkCount(ctx context.Context, linkingID uuid.UUID) (int, int, error)
+	// ListUpholdWallets - list uphold wallets holding a card, in id order, starting after the given id
+	ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) ([]walletutils.Info, error)
+	// SampleUpholdWallets - get a random sample of uphold wallets holding a card
+	SampleUpholdWallets(ctx context.Context, limit int) ([]walletutils.Info, error)
 }
 
 // Postgres is a Datastore wrapper around a postgres database
@@ -222,6 +230,42 @@ func (pg *Postgres) GetByProviderLinkingID(ctx context.Context, providerLinkingI
 	return &wallets, err
 }
 
+// ListUpholdWallets lists uphold wallets holding a card, in id order, starting after the given id
+func (pg *Postgres) ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) ([]walletutils.Info, error) {
+	statement := `
+	select
+		id, provider, provider_id, public_key, provider_linking_id, anonymous_address,
+		user_deposit_account_provider, user_deposit_destination
+	from
+		wallets
+	where provider = 'uphold' and provider_id != '' and id > $1
+	order by id
+	limit $2`
+	wallets := []walletutils.Info{}
+	if err := pg.RawDB().SelectContext(ctx, &wallets, statement, after, limit); err != nil {
+		return nil, fmt.Errorf("failed to list uphold wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// SampleUpholdWallets returns a random sample of uphold wallets holding a card
+func (pg *Postgres) SampleUpholdWallets(ctx context.Context, limit int) ([]walletutils.Info, error) {
+	statement := `
+	select
+		id, provider, provider_id, public_key, provider_linking_id, anonymous_address,
+		user_deposit_account_provider, user_deposit_destination
+	from
+		wallets
+	where provider = 'uphold' and provider_id != ''
+	order by random()
+	limit $1`
+	wallets := []walletutils.Info{}
+	if err := pg.RawDB().SelectContext(ctx, &wallets, statement, limit); err != nil {
+		return nil, fmt.Errorf("failed to sample uphold wallets: %w", err)
+	}
+	return wallets, nil
+}
+
 // InsertBitFlyerRequestID - attempts to insert a request id
 func (pg *Postgres) InsertBitFlyerRequestID(ctx context.Context, requestID string) error {
 	statement := `insert into bf_req_ids(id) values ($1)`
@@ -284,10 +328,10 @@ func bitFlyerRequestIDSpent(ctx context.Context, requestID string) bool {
 		ctx, logger = logging.SetupLogger(ctx)
 	}
 	// get pg from context
-	db, ok := ctx.Value(appctx.DatastoreCTXKey).(Datastore)
-	if !ok {
+	var db Datastore
+	if err := appctx.Datastore(ctx, &db); err != nil {
 		// if we cant check the db consider "spent"
-		logger.Error().Msg("bitFlyerRequestIDSpent: unable to get datastore from context")
+		logger.Error().Err(err).Msg("bitFlyerRequestIDSpent: unable to get datastore from context")
 		return true
 	}
 
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/utils/altcurrency"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	uuid "github.com/satori/go.uuid"
@@ -37,7 +38,7 @@ func (suite *WalletPostgresTestSuite) SetupSuite() {
 		suite.Require().NoError(m.Down(), "Failed to migrate down cleanly")
 	}
 
-	suite.Require().NoError(pg.Migrate(), "Failed to fully migrate")
+	suite.Require().NoError(pg.Migrate(context.Background(), grantserver.CurrentMigrationVersion), "Failed to fully migrate")
 }
 
 func (suite *WalletPostgresTestSuite) SetupTest() {
@@ -161,3 +162,30 @@ func (suite *WalletPostgresTestSuite) TestCustodianLink() {
 	cl, err = pg.GetCustodianLinkByWalletID(ctx, id)
 	suite.Require().True(errors.Is(err, sql.ErrNoRows), "should be no rows found error")
 }
+
+func (suite *WalletPostgresTestSuite) TestListUpholdWallets() {
+	ctx := context.Background()
+
+	pg, _, err := NewPostgres()
+	suite.Require().NoError(err)
+
+	for i := 0; i < 3; i++ {
+		wallet := &walletutils.Info{ID: uuid.NewV4().String(), Provider: "uphold", ProviderID: uuid.NewV4().String(), PublicKey: "hBrtClwIppLmu/qZ8EhGM1TQZUwDUosbOrVu3jMwryY="}
+		suite.Require().NoError(pg.InsertWallet(ctx, wallet), "Save wallet should succeed")
+	}
+	brave := &walletutils.Info{ID: uuid.NewV4().String(), Provider: "brave", PublicKey: "hBrtClwIppLmu/qZ8EhGM1TQZUwDUosbOrVu3jMwryY="}
+	suite.Require().NoError(pg.InsertWallet(ctx, brave), "Save wallet should succeed")
+
+	page, err := pg.ListUpholdWallets(ctx, uuid.Nil, 2)
+	suite.Require().NoError(err)
+	suite.Require().Len(page, 2)
+	suite.Assert().True(page[0].ID < page[1].ID, "wallets should be listed in id order")
+
+	rest, err := pg.ListUpholdWallets(ctx, uuid.Must(uuid.FromString(page[1].ID)), 2)
+	suite.Require().NoError(err)
+	suite.Require().Len(rest, 1, "wallets without a card should not be listed")
+
+	sample, err := pg.SampleUpholdWallets(ctx, 2)
+	suite.Require().NoError(err)
+	suite.Assert().Len(sample, 2)
+}
@@ -10,6 +10,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
@@ -45,6 +47,7 @@ func NewDatastoreWithPrometheus(base Datastore, instanceName string) DatastoreWi
 // ConnectCustodialWallet implements Datastore
 func (_d DatastoreWithPrometheus) ConnectCustodialWallet(ctx context.Context, cl *CustodianLink, depositDest string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ConnectCustodialWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -52,6 +55,7 @@ func (_d DatastoreWithPrometheus) ConnectCustodialWallet(ctx context.Context, cl
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ConnectCustodialWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.ConnectCustodialWallet(ctx, cl, depositDest)
 }
@@ -59,6 +63,7 @@ func (_d DatastoreWithPrometheus) ConnectCustodialWallet(ctx context.Context, cl
 // DisconnectCustodialWallet implements Datastore
 func (_d DatastoreWithPrometheus) DisconnectCustodialWallet(ctx context.Context, walletID uuid.UUID) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".DisconnectCustodialWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -66,6 +71,7 @@ func (_d DatastoreWithPrometheus) DisconnectCustodialWallet(ctx context.Context,
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "DisconnectCustodialWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.DisconnectCustodialWallet(ctx, walletID)
 }
@@ -73,6 +79,7 @@ func (_d DatastoreWithPrometheus) DisconnectCustodialWallet(ctx context.Context,
 // GetByProviderLinkingID implements Datastore
 func (_d DatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Context, providerLinkingID uuid.UUID) (iap1 *[]walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetByProviderLinkingID")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -80,6 +87,7 @@ func (_d DatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Context, pr
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetByProviderLinkingID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetByProviderLinkingID(ctx, providerLinkingID)
 }
@@ -87,6 +95,7 @@ func (_d DatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Context, pr
 // GetCustodianLinkByWalletID implements Datastore
 func (_d DatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context.Context, ID uuid.UUID) (cp1 *CustodianLink, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCustodianLinkByWalletID")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -94,6 +103,7 @@ func (_d DatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context.Context
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCustodianLinkByWalletID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetCustodianLinkByWalletID(ctx, ID)
 }
@@ -101,6 +111,7 @@ func (_d DatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context.Context
 // GetCustodianLinkCount implements Datastore
 func (_d DatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Context, linkingID uuid.UUID) (i1 int, i2 int, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCustodianLinkCount")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -108,6 +119,7 @@ func (_d DatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Context, lin
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCustodianLinkCount", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetCustodianLinkCount(ctx, linkingID)
 }
@@ -115,6 +127,7 @@ func (_d DatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Context, lin
 // GetLinkingLimitInfo implements Datastore
 func (_d DatastoreWithPrometheus) GetLinkingLimitInfo(ctx context.Context, providerLinkingID string) (l1 LinkingInfo, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetLinkingLimitInfo")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -122,6 +135,7 @@ func (_d DatastoreWithPrometheus) GetLinkingLimitInfo(ctx context.Context, provi
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetLinkingLimitInfo", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetLinkingLimitInfo(ctx, providerLinkingID)
 }
@@ -129,6 +143,7 @@ func (_d DatastoreWithPrometheus) GetLinkingLimitInfo(ctx context.Context, provi
 // GetWallet implements Datastore
 func (_d DatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid.UUID) (ip1 *walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -136,6 +151,7 @@ func (_d DatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid.UUID) (
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetWallet(ctx, ID)
 }
@@ -143,6 +159,7 @@ func (_d DatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid.UUID) (
 // GetWalletByPublicKey implements Datastore
 func (_d DatastoreWithPrometheus) GetWalletByPublicKey(ctx context.Context, s1 string) (ip1 *walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWalletByPublicKey")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -150,6 +167,7 @@ func (_d DatastoreWithPrometheus) GetWalletByPublicKey(ctx context.Context, s1 s
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWalletByPublicKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetWalletByPublicKey(ctx, s1)
 }
@@ -157,6 +175,7 @@ func (_d DatastoreWithPrometheus) GetWalletByPublicKey(ctx context.Context, s1 s
 // IncreaseLinkingLimit implements Datastore
 func (_d DatastoreWithPrometheus) IncreaseLinkingLimit(ctx context.Context, providerLinkingID uuid.UUID) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".IncreaseLinkingLimit")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -164,6 +183,7 @@ func (_d DatastoreWithPrometheus) IncreaseLinkingLimit(ctx context.Context, prov
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "IncreaseLinkingLimit", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.IncreaseLinkingLimit(ctx, providerLinkingID)
 }
@@ -171,6 +191,7 @@ func (_d DatastoreWithPrometheus) IncreaseLinkingLimit(ctx context.Context, prov
 // InsertBitFlyerRequestID implements Datastore
 func (_d DatastoreWithPrometheus) InsertBitFlyerRequestID(ctx context.Context, requestID string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertBitFlyerRequestID")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -178,6 +199,7 @@ func (_d DatastoreWithPrometheus) InsertBitFlyerRequestID(ctx context.Context, r
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertBitFlyerRequestID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.InsertBitFlyerRequestID(ctx, requestID)
 }
@@ -185,6 +207,7 @@ func (_d DatastoreWithPrometheus) InsertBitFlyerRequestID(ctx context.Context, r
 // InsertWallet implements Datastore
 func (_d DatastoreWithPrometheus) InsertWallet(ctx context.Context, wallet *walletutils.Info) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".InsertWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -192,6 +215,7 @@ func (_d DatastoreWithPrometheus) InsertWallet(ctx context.Context, wallet *wall
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "InsertWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.InsertWallet(ctx, wallet)
 }
@@ -199,6 +223,7 @@ func (_d DatastoreWithPrometheus) InsertWallet(ctx context.Context, wallet *wall
 // LinkWallet implements Datastore
 func (_d DatastoreWithPrometheus) LinkWallet(ctx context.Context, ID string, providerID string, providerLinkingID uuid.UUID, anonymousAddress *uuid.UUID, depositProvider string) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".LinkWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -206,13 +231,31 @@ func (_d DatastoreWithPrometheus) LinkWallet(ctx context.Context, ID string, pro
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "LinkWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.LinkWallet(ctx, ID, providerID, providerLinkingID, anonymousAddress, depositProvider)
 }
 
+// ListUpholdWallets implements Datastore
+func (_d DatastoreWithPrometheus) ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) (ia1 []walletutils.Info, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ListUpholdWallets")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ListUpholdWallets", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.ListUpholdWallets(ctx, after, limit)
+}
+
 // Migrate implements Datastore
-func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d DatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -220,8 +263,25 @@ func (_d DatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements Datastore
+func (_d DatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements Datastore
@@ -273,9 +333,26 @@ func (_d DatastoreWithPrometheus) RollbackTxAndHandle(tx *sqlx.Tx) (err error) {
 	return _d.base.RollbackTxAndHandle(tx)
 }
 
+// SampleUpholdWallets implements Datastore
+func (_d DatastoreWithPrometheus) SampleUpholdWallets(ctx context.Context, limit int) (ia1 []walletutils.Info, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".SampleUpholdWallets")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "SampleUpholdWallets", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.SampleUpholdWallets(ctx, limit)
+}
+
 // UpsertWallet implements Datastore
 func (_d DatastoreWithPrometheus) UpsertWallet(ctx context.Context, wallet *walletutils.Info) (err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".UpsertWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -283,6 +360,7 @@ func (_d DatastoreWithPrometheus) UpsertWallet(ctx context.Context, wallet *wall
 		}
 
 		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "UpsertWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.UpsertWallet(ctx, wallet)
 }
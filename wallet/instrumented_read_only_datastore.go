@@ -10,6 +10,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
+	"github.com/brave-intl/bat-go/utils/tracing"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/jmoiron/sqlx"
@@ -45,6 +47,7 @@ func NewReadOnlyDatastoreWithPrometheus(base ReadOnlyDatastore, instanceName str
 // GetByProviderLinkingID implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Context, providerLinkingID uuid.UUID) (iap1 *[]walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetByProviderLinkingID")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -52,6 +55,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Con
 		}
 
 		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetByProviderLinkingID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetByProviderLinkingID(ctx, providerLinkingID)
 }
@@ -59,6 +63,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetByProviderLinkingID(ctx context.Con
 // GetCustodianLinkByWalletID implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context.Context, ID uuid.UUID) (cp1 *CustodianLink, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCustodianLinkByWalletID")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -66,6 +71,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context
 		}
 
 		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCustodianLinkByWalletID", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetCustodianLinkByWalletID(ctx, ID)
 }
@@ -73,6 +79,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkByWalletID(ctx context
 // GetCustodianLinkCount implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Context, linkingID uuid.UUID) (i1 int, i2 int, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetCustodianLinkCount")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -80,6 +87,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Cont
 		}
 
 		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetCustodianLinkCount", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetCustodianLinkCount(ctx, linkingID)
 }
@@ -87,6 +95,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetCustodianLinkCount(ctx context.Cont
 // GetWallet implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid.UUID) (ip1 *walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWallet")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -94,6 +103,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid
 		}
 
 		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWallet", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetWallet(ctx, ID)
 }
@@ -101,6 +111,7 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetWallet(ctx context.Context, ID uuid
 // GetWalletByPublicKey implements ReadOnlyDatastore
 func (_d ReadOnlyDatastoreWithPrometheus) GetWalletByPublicKey(ctx context.Context, s1 string) (ip1 *walletutils.Info, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".GetWalletByPublicKey")
 	defer func() {
 		result := "ok"
 		if err != nil {
@@ -108,22 +119,57 @@ func (_d ReadOnlyDatastoreWithPrometheus) GetWalletByPublicKey(ctx context.Conte
 		}
 
 		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "GetWalletByPublicKey", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
 	return _d.base.GetWalletByPublicKey(ctx, s1)
 }
 
+// ListUpholdWallets implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) ListUpholdWallets(ctx context.Context, after uuid.UUID, limit int) (ia1 []walletutils.Info, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".ListUpholdWallets")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "ListUpholdWallets", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.ListUpholdWallets(ctx, after, limit)
+}
+
 // Migrate implements ReadOnlyDatastore
-func (_d ReadOnlyDatastoreWithPrometheus) Migrate(p1 ...uint) (err error) {
+func (_d ReadOnlyDatastoreWithPrometheus) Migrate(ctx context.Context, targetVersion uint) (err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".Migrate")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.Migrate(ctx, targetVersion)
+}
+
+// MigrationStatus implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) MigrationStatus(ctx context.Context) (mp1 *grantserver.MigrationStatus, err error) {
 	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".MigrationStatus")
 	defer func() {
 		result := "ok"
 		if err != nil {
 			result = "error"
 		}
 
-		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "Migrate", result).Observe(time.Since(_since).Seconds())
+		datastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "MigrationStatus", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
 	}()
-	return _d.base.Migrate(p1...)
+	return _d.base.MigrationStatus(ctx)
 }
 
 // NewMigrate implements ReadOnlyDatastore
@@ -174,3 +220,19 @@ func (_d ReadOnlyDatastoreWithPrometheus) RollbackTxAndHandle(tx *sqlx.Tx) (err
 	}()
 	return _d.base.RollbackTxAndHandle(tx)
 }
+
+// SampleUpholdWallets implements ReadOnlyDatastore
+func (_d ReadOnlyDatastoreWithPrometheus) SampleUpholdWallets(ctx context.Context, limit int) (ia1 []walletutils.Info, err error) {
+	_since := time.Now()
+	ctx, _span := tracing.StartSpan(ctx, _d.instanceName+".SampleUpholdWallets")
+	defer func() {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+
+		readonlydatastoreDurationSummaryVec.WithLabelValues(_d.instanceName, "SampleUpholdWallets", result).Observe(time.Since(_since).Seconds())
+		_span.End(err)
+	}()
+	return _d.base.SampleUpholdWallets(ctx, limit)
+}
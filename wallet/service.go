@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/brave-intl/bat-go/datastore/grantserver"
 	"github.com/brave-intl/bat-go/middleware"
 	"github.com/brave-intl/bat-go/utils/clients/gemini"
 	"github.com/brave-intl/bat-go/utils/clients/reputation"
@@ -15,6 +17,7 @@ import (
 	errorutils "github.com/brave-intl/bat-go/utils/errors"
 	"github.com/brave-intl/bat-go/utils/handlers"
 	"github.com/brave-intl/bat-go/utils/logging"
+	"github.com/brave-intl/bat-go/utils/metrics"
 	walletutils "github.com/brave-intl/bat-go/utils/wallet"
 	"github.com/brave-intl/bat-go/utils/wallet/provider"
 	"github.com/brave-intl/bat-go/utils/wallet/provider/uphold"
@@ -24,6 +27,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// signedRequestTTL is how far the date of a signed claim may be from now before it is rejected as stale
+const signedRequestTTL = 5 * time.Minute
+
 var (
 	// WalletClaimNamespace uuidv5 namespace for provider linking - exported for tests
 	WalletClaimNamespace = uuid.Must(uuid.FromString("c39b298b-b625-42e9-a463-69c7726e5ddc"))
@@ -35,6 +41,7 @@ type Service struct {
 	RoDatastore  ReadOnlyDatastore
 	repClient    reputation.Client
 	geminiClient gemini.Client
+	nonces       middleware.NonceStore
 }
 
 // InitService creates a service using the passed datastore and clients configured from the environment
@@ -261,9 +268,15 @@ func SetupService(ctx context.Context, r *chi.Mux) (*chi.Mux, context.Context, *
 	if err != nil {
 		logger.Panic().Err(err).Msg("unable connect to wallet db")
 	}
+	// with migrations run ahead of deploys rather than at boot, the schema is checked before serving
+	if viper.GetBool("verify-schema") {
+		if err := grantserver.VerifySchemaVersion(ctx, db, grantserver.CurrentMigrationVersion); err != nil {
+			logger.Panic().Err(err).Msg("wallet db schema is not at the expected version")
+		}
+	}
 
-	ctx = context.WithValue(ctx, appctx.RODatastoreCTXKey, roDB)
-	ctx = context.WithValue(ctx, appctx.DatastoreCTXKey, db)
+	ctx = appctx.WithReadOnlyDatastore(ctx, roDB)
+	ctx = appctx.WithDatastore(ctx, db)
 
 	// add our command line params to context
 	ctx = context.WithValue(ctx, appctx.EnvironmentCTXKey, viper.Get("environment"))
@@ -279,6 +292,10 @@ func SetupService(ctx context.Context, r *chi.Mux) (*chi.Mux, context.Context, *
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to initialize wallet service")
 	}
+	s.nonces, err = middleware.NewNonceStore(ctx, "wallet_nonce:")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize wallet nonce store")
+	}
 
 	// setup reputation client
 	s.repClient, err = reputation.New()
@@ -299,6 +316,8 @@ func SetupService(ctx context.Context, r *chi.Mux) (*chi.Mux, context.Context, *
 
 	// setup our wallet routes
 	r.Route("/v3/wallet", func(r chi.Router) {
+		r.Use(metrics.HTTPServer("wallet"))
+		r.Use(middleware.NewAppCtx(db, logger))
 		// rate limited to 2 per minute...
 		// create wallet routes for our wallet providers
 		r.Post("/uphold", middleware.RateLimiter(ctx, 2)(middleware.InstrumentHandlerFunc(
@@ -311,14 +330,14 @@ func SetupService(ctx context.Context, r *chi.Mux) (*chi.Mux, context.Context, *
 			// create wallet claim routes for our wallet providers
 			r.Post("/uphold/{paymentID}/claim", middleware.InstrumentHandlerFunc(
 				"LinkUpholdDepositAccount", LinkUpholdDepositAccountV3(s)))
-			r.Post("/bitflyer/{paymentID}/claim", middleware.HTTPSignedOnly(s)(middleware.InstrumentHandlerFunc(
+			r.Post("/bitflyer/{paymentID}/claim", middleware.HTTPSignedOnly(s, middleware.RequireNonceOn("LinkBitFlyerDepositAccount", s.nonces, signedRequestTTL))(middleware.InstrumentHandlerFunc(
 				"LinkBitFlyerDepositAccount", LinkBitFlyerDepositAccountV3(s))).ServeHTTP)
-			r.Post("/brave/{paymentID}/claim", middleware.HTTPSignedOnly(s)(middleware.InstrumentHandlerFunc(
+			r.Post("/brave/{paymentID}/claim", middleware.HTTPSignedOnly(s, middleware.RequireNonceOn("LinkBraveDepositAccount", s.nonces, signedRequestTTL))(middleware.InstrumentHandlerFunc(
 				"LinkBraveDepositAccount", LinkBraveDepositAccountV3(s))).ServeHTTP)
-			r.Post("/gemini/{paymentID}/claim", middleware.HTTPSignedOnly(s)(middleware.InstrumentHandlerFunc(
+			r.Post("/gemini/{paymentID}/claim", middleware.HTTPSignedOnly(s, middleware.RequireNonceOn("LinkGeminiDepositAccount", s.nonces, signedRequestTTL))(middleware.InstrumentHandlerFunc(
 				"LinkGeminiDepositAccount", LinkGeminiDepositAccountV3(s))).ServeHTTP)
 			// disconnect verified custodial wallet
-			r.Delete("/{custodian}/{paymentID}/claim", middleware.HTTPSignedOnly(s)(middleware.InstrumentHandlerFunc(
+			r.Delete("/{custodian}/{paymentID}/claim", middleware.HTTPSignedOnly(s, middleware.RequireNonceOn("DisconnectCustodianLinkV3", s.nonces, signedRequestTTL))(middleware.InstrumentHandlerFunc(
 				"DisconnectCustodianLinkV3", DisconnectCustodianLinkV3(s))).ServeHTTP)
 		}
 		// support only APIs to assist in linking limit issues
@@ -340,8 +359,8 @@ func SetupService(ctx context.Context, r *chi.Mux) (*chi.Mux, context.Context, *
 			"RecoverWallet", RecoverWalletV3))
 
 		// get wallet balance routes
-		r.Get("/uphold/{paymentID}", middleware.InstrumentHandlerFunc(
-			"GetUpholdWalletBalance", GetUpholdWalletBalanceV3))
+		r.Get("/uphold/{paymentID}", middleware.InstrumentHandler(
+			"GetUpholdWalletBalance", middleware.MessagePack(handlers.AppHandler(GetUpholdWalletBalanceV3))).ServeHTTP)
 	})
 	return r, ctx, s
 }